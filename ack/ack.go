@@ -0,0 +1,294 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package ack implements acknowledgements of alerts. Repeated notifications
+// are not sent for acknowledged alerts until the acknowledgement expires.
+// Acknowledgements are shared with other Alertmanager instances through
+// the mesh network.
+package ack
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/prometheus/common/log"
+	"github.com/prometheus/common/model"
+	"github.com/satori/go.uuid"
+	"github.com/weaveworks/mesh"
+
+	"github.com/prometheus/alertmanager/gossipstate"
+)
+
+// ErrNotFound is returned if an acknowledgement was not found.
+var ErrNotFound = errors.New("acknowledgement not found")
+
+// Ack acknowledges the alerts that have all of its labels and started
+// firing before the acknowledgement was created.
+type Ack struct {
+	ID string `json:"id"`
+	// The labels of the acknowledged alert or group.
+	Labels    model.LabelSet `json:"labels"`
+	CreatedBy string         `json:"createdBy"`
+	Comment   string         `json:"comment,omitempty"`
+	CreatedAt time.Time      `json:"createdAt"`
+	// ExpiresAt is nil if the acknowledgement lasts until its alerts
+	// resolved.
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+	UpdatedAt time.Time  `json:"updatedAt"`
+}
+
+// Active returns whether the acknowledgement did not expire at the given time.
+func (a *Ack) Active(now time.Time) bool {
+	return a.ExpiresAt == nil || a.ExpiresAt.After(now)
+}
+
+// Applies returns whether the acknowledgement applies to an alert with the
+// given labels that started at the given time.
+func (a *Ack) Applies(lset model.LabelSet, startsAt, now time.Time) bool {
+	if !a.Active(now) || startsAt.After(a.CreatedAt) {
+		return false
+	}
+	for ln, lv := range a.Labels {
+		if lset[ln] != lv {
+			return false
+		}
+	}
+	return true
+}
+
+func validateAck(a *Ack) error {
+	if a.ID == "" {
+		return errors.New("ID missing")
+	}
+	if len(a.Labels) == 0 {
+		return errors.New("at least one label required")
+	}
+	if err := a.Labels.Validate(); err != nil {
+		return err
+	}
+	if a.CreatedBy == "" {
+		return errors.New("creator missing")
+	}
+	if a.CreatedAt.IsZero() || a.UpdatedAt.IsZero() {
+		return errors.New("timestamps missing")
+	}
+	return nil
+}
+
+// Acks holds acknowledgements of alerts.
+type Acks struct {
+	retention time.Duration
+	now       func() time.Time
+	st        *gossipstate.State
+}
+
+// Options configures a new Acks object.
+type Options struct {
+	// A snapshot file from which the initial state is loaded.
+	SnapshotFile string
+
+	// Acknowledgements may be garbage collected the given duration after
+	// they expired. Acknowledgements without expiry are garbage collected
+	// the given duration after they were created.
+	Retention time.Duration
+
+	// A function creating a mesh.Gossip on being called with a mesh.Gossiper.
+	Gossip func(g mesh.Gossiper) mesh.Gossip
+
+	// A logger used by background processing.
+	Logger log.Logger
+}
+
+// New returns a new Acks object with the given configuration.
+func New(o Options) (*Acks, error) {
+	st, err := gossipstate.New(ackType{}, gossipstate.Options{
+		SnapshotFile: o.SnapshotFile,
+		Gossip:       o.Gossip,
+		Logger:       o.Logger,
+	})
+	if st == nil {
+		return nil, err
+	}
+	return &Acks{
+		retention: o.Retention,
+		now:       gossipstate.UTCNow,
+		st:        st,
+	}, err
+}
+
+// Maintenance garbage collects the acknowledgements at the given interval.
+// If the snapshot file is set, a snapshot is written to it afterwards.
+// Terminates on receiving from stopc.
+func (a *Acks) Maintenance(interval time.Duration, snapf string, stopc <-chan struct{}) {
+	a.st.Maintenance(interval, snapf, stopc, a.GC)
+}
+
+// GC removes acknowledgements that expired longer than the retention time
+// ago. It returns the number of removed acknowledgements.
+func (a *Acks) GC() (int, error) {
+	now := a.now()
+
+	return a.st.GC(func(e interface{}) bool {
+		ack := e.(*Ack)
+		end := ack.CreatedAt
+		if ack.ExpiresAt != nil {
+			end = *ack.ExpiresAt
+		}
+		return !end.Add(a.retention).After(now)
+	}), nil
+}
+
+// Create adds a new acknowledgement and returns its ID.
+func (a *Acks) Create(ack *Ack) (string, error) {
+	now := a.now()
+
+	ack.ID = uuid.NewV4().String()
+	ack.CreatedAt = now
+	ack.UpdatedAt = now
+
+	if err := validateAck(ack); err != nil {
+		return "", fmt.Errorf("invalid acknowledgement: %s", err)
+	}
+	if ack.ExpiresAt != nil && !ack.ExpiresAt.After(now) {
+		return "", errors.New("invalid acknowledgement: expiry must be in the future")
+	}
+
+	a.st.Lock()
+	defer a.st.Unlock()
+
+	a.st.Set(ack)
+	return ack.ID, nil
+}
+
+// Expire the acknowledgement with the given ID immediately.
+func (a *Acks) Expire(id string) error {
+	now := a.now()
+
+	a.st.Lock()
+	defer a.st.Unlock()
+
+	e, ok := a.st.Get(id)
+	if !ok {
+		return ErrNotFound
+	}
+	ack := e.(*Ack)
+	if !ack.Active(now) {
+		return errors.New("acknowledgement already expired")
+	}
+	c := *ack
+	c.ExpiresAt = &now
+	c.UpdatedAt = now
+
+	a.st.Set(&c)
+	return nil
+}
+
+// Get returns the acknowledgement with the given ID.
+func (a *Acks) Get(id string) (*Ack, error) {
+	a.st.RLock()
+	defer a.st.RUnlock()
+
+	e, ok := a.st.Get(id)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	c := *e.(*Ack)
+	return &c, nil
+}
+
+type ackSlice []*Ack
+
+func (s ackSlice) Len() int           { return len(s) }
+func (s ackSlice) Less(i, j int) bool { return s[i].CreatedAt.Before(s[j].CreatedAt) }
+func (s ackSlice) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+// List returns all active acknowledgements ordered by their creation time.
+func (a *Acks) List() []*Ack {
+	now := a.now()
+
+	a.st.RLock()
+	defer a.st.RUnlock()
+
+	res := ackSlice{}
+	a.st.Range(func(e interface{}) {
+		if ack := e.(*Ack); ack.Active(now) {
+			c := *ack
+			res = append(res, &c)
+		}
+	})
+	sort.Sort(res)
+	return res
+}
+
+// Acked returns the oldest acknowledgement that applies to an alert with
+// the given labels that started at the given time. It returns nil if there
+// is none. It is safe to call on a nil Acks.
+func (a *Acks) Acked(lset model.LabelSet, startsAt time.Time) *Ack {
+	if a == nil {
+		return nil
+	}
+	now := a.now()
+
+	a.st.RLock()
+	defer a.st.RUnlock()
+
+	var res *Ack
+	a.st.Range(func(e interface{}) {
+		ack := e.(*Ack)
+		if !ack.Applies(lset, startsAt, now) {
+			return
+		}
+		if res == nil || ack.CreatedAt.Before(res.CreatedAt) {
+			res = ack
+		}
+	})
+	if res == nil {
+		return nil
+	}
+	c := *res
+	return &c
+}
+
+// ackType implements the handling of acknowledgements by the state.
+type ackType struct{}
+
+func (ackType) Key(e interface{}) string {
+	return e.(*Ack).ID
+}
+
+func (ackType) Decode(b []byte) (interface{}, error) {
+	var ack Ack
+	if err := json.Unmarshal(b, &ack); err != nil {
+		return nil, err
+	}
+	if err := validateAck(&ack); err != nil {
+		return nil, fmt.Errorf("invalid acknowledgement: %s", err)
+	}
+	return &ack, nil
+}
+
+func (ackType) Size(e interface{}) int {
+	ack := e.(*Ack)
+	return len(ack.Comment) + 64*(len(ack.Labels)+1)
+}
+
+// Merge keeps the more recently updated acknowledgement.
+func (ackType) Merge(prev, e interface{}) interface{} {
+	if !prev.(*Ack).UpdatedAt.Before(e.(*Ack).UpdatedAt) {
+		return nil
+	}
+	return e
+}
@@ -0,0 +1,104 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ack
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+
+	"github.com/prometheus/alertmanager/gossipstate"
+)
+
+func TestAcksCreateExpire(t *testing.T) {
+	a, err := New(Options{Retention: time.Hour})
+	require.NoError(t, err)
+
+	now := gossipstate.UTCNow()
+	a.now = func() time.Time { return now }
+
+	_, err = a.Create(&Ack{Labels: model.LabelSet{"job": "api"}})
+	require.Error(t, err, "creator missing")
+
+	past := now.Add(-time.Minute)
+	_, err = a.Create(&Ack{Labels: model.LabelSet{"job": "api"}, CreatedBy: "me", ExpiresAt: &past})
+	require.Error(t, err, "expiry in the past")
+
+	id, err := a.Create(&Ack{Labels: model.LabelSet{"job": "api"}, CreatedBy: "me"})
+	require.NoError(t, err)
+
+	alert := model.LabelSet{"alertname": "HighLatency", "job": "api"}
+
+	require.Equal(t, id, a.Acked(alert, now.Add(-time.Minute)).ID)
+	require.Nil(t, a.Acked(alert, now.Add(time.Second)), "alerts starting after the ack are not acknowledged")
+	require.Nil(t, a.Acked(model.LabelSet{"job": "db"}, now.Add(-time.Minute)))
+	require.Len(t, a.List(), 1)
+
+	now = now.Add(time.Minute)
+	require.NoError(t, a.Expire(id))
+	require.Error(t, a.Expire(id))
+	require.Equal(t, ErrNotFound, a.Expire("unknown"))
+	require.Nil(t, a.Acked(alert, now.Add(-time.Hour)))
+	require.Len(t, a.List(), 0)
+
+	ack, err := a.Get(id)
+	require.NoError(t, err)
+	require.Equal(t, now, *ack.ExpiresAt)
+
+	n, err := a.GC()
+	require.NoError(t, err)
+	require.Equal(t, 0, n)
+
+	now = now.Add(time.Hour)
+	n, err = a.GC()
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+
+	var nilAcks *Acks
+	require.Nil(t, nilAcks.Acked(alert, now))
+}
+
+func TestAckTypeMerge(t *testing.T) {
+	now := gossipstate.UTCNow()
+
+	newAck := func(updated time.Time) *Ack {
+		return &Ack{
+			ID:        "a",
+			Labels:    model.LabelSet{"job": "api"},
+			CreatedBy: "me",
+			CreatedAt: now,
+			UpdatedAt: updated,
+		}
+	}
+	prev := newAck(now)
+
+	for _, c := range []struct {
+		name   string
+		e      *Ack
+		merged bool
+	}{
+		{name: "newer", e: newAck(now.Add(time.Minute)), merged: true},
+		{name: "same update", e: newAck(now)},
+		{name: "older", e: newAck(now.Add(-time.Minute))},
+	} {
+		res := ackType{}.Merge(prev, c.e)
+		if !c.merged {
+			require.Nil(t, res, c.name)
+			continue
+		}
+		require.Equal(t, c.e, res, c.name)
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/common/route"
+
+	"github.com/prometheus/alertmanager/ack"
+	"github.com/prometheus/alertmanager/provider"
+)
+
+// ackRequest is the body of a request acknowledging an alert or group.
+// Either the fingerprint of an alert or the labels of an alert or group
+// must be set.
+type ackRequest struct {
+	Fingerprint string         `json:"fingerprint,omitempty"`
+	Labels      model.LabelSet `json:"labels,omitempty"`
+	CreatedBy   string         `json:"createdBy"`
+	Comment     string         `json:"comment,omitempty"`
+	ExpiresAt   *time.Time     `json:"expiresAt,omitempty"`
+}
+
+// ack returns the acknowledgement described by the request.
+func (req *ackRequest) ack(alerts provider.Alerts) (*ack.Ack, error) {
+	a := &ack.Ack{
+		Labels:    req.Labels,
+		CreatedBy: req.CreatedBy,
+		Comment:   req.Comment,
+		ExpiresAt: req.ExpiresAt,
+	}
+	if req.Fingerprint == "" {
+		return a, nil
+	}
+	if len(req.Labels) > 0 {
+		return nil, errors.New("only one of fingerprint and labels must be set")
+	}
+	fp, err := model.ParseFingerprint(req.Fingerprint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid fingerprint %q: %s", req.Fingerprint, err)
+	}
+	alert, err := alerts.Get(fp)
+	if err != nil {
+		return nil, fmt.Errorf("alert %s: %s", fp, err)
+	}
+	a.Labels = alert.Labels
+	return a, nil
+}
+
+func (api *API) addAck(w http.ResponseWriter, r *http.Request) {
+	var req ackRequest
+	if err := receive(r, &req); err != nil {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+	api.setUser(r, &req.CreatedBy)
+	a, err := req.ack(api.alerts)
+	if err != nil {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+	id, err := api.acks.Create(a)
+	if err != nil {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+	respond(w, struct {
+		AckID string `json:"ackId"`
+	}{
+		AckID: id,
+	})
+}
+
+func (api *API) listAcks(w http.ResponseWriter, r *http.Request) {
+	respond(w, api.acks.List())
+}
+
+func (api *API) getAck(w http.ResponseWriter, r *http.Request) {
+	id := route.Param(api.context(r), "aid")
+
+	a, err := api.acks.Get(id)
+	if err != nil {
+		http.Error(w, fmt.Sprint("Error getting acknowledgement: ", err), http.StatusNotFound)
+		return
+	}
+	respond(w, a)
+}
+
+func (api *API) delAck(w http.ResponseWriter, r *http.Request) {
+	id := route.Param(api.context(r), "aid")
+
+	if err := api.acks.Expire(id); err != nil {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+	respond(w, nil)
+}
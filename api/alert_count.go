@@ -0,0 +1,121 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/prometheus/common/model"
+
+	"github.com/prometheus/alertmanager/types"
+)
+
+// alertCount is the number of active alerts sharing the values of the
+// labels counted by.
+type alertCount struct {
+	// Labels holds the values of the labels counted by. Labels missing from
+	// the alerts are omitted.
+	Labels model.LabelSet `json:"labels"`
+	Count  int            `json:"count"`
+}
+
+// alertCounts is the result of counting alerts.
+type alertCounts struct {
+	By     []model.LabelName `json:"by"`
+	Total  int               `json:"total"`
+	Counts []*alertCount     `json:"counts"`
+}
+
+// countAlerts returns the number of active alerts per combination of the
+// values of the labels given by the "by" query parameters, e.g. per team
+// and severity. The alerts can be filtered with the parameters of
+// listAlerts. The counts are sorted in descending order.
+func (api *API) countAlerts(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	var by []model.LabelName
+	for _, s := range q["by"] {
+		ln := model.LabelName(s)
+		if !ln.IsValid() {
+			respondError(w, apiError{
+				typ: errorBadData,
+				err: fmt.Errorf("invalid label name %q", s),
+			}, nil)
+			return
+		}
+		by = append(by, ln)
+	}
+
+	f, err := parseAlertFilter(q)
+	if err != nil {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+
+	alerts, err := api.queryAlerts(f)
+	if err != nil {
+		respondError(w, apiError{
+			typ: errorInternal,
+			err: err,
+		}, nil)
+		return
+	}
+
+	respond(w, countAlertsBy(alerts, by))
+}
+
+// countAlertsBy counts the alerts that are not resolved by the values of
+// the given labels.
+func countAlertsBy(alerts []*types.Alert, by []model.LabelName) *alertCounts {
+	if by == nil {
+		by = []model.LabelName{}
+	}
+	var (
+		res    = &alertCounts{By: by, Counts: []*alertCount{}}
+		counts = map[model.Fingerprint]*alertCount{}
+	)
+	for _, a := range alerts {
+		if a.Resolved() {
+			continue
+		}
+		lset := model.LabelSet{}
+		for _, ln := range by {
+			if lv, ok := a.Labels[ln]; ok {
+				lset[ln] = lv
+			}
+		}
+		fp := lset.Fingerprint()
+		c, ok := counts[fp]
+		if !ok {
+			c = &alertCount{Labels: lset}
+			counts[fp] = c
+			res.Counts = append(res.Counts, c)
+		}
+		c.Count++
+		res.Total++
+	}
+
+	sort.Slice(res.Counts, func(i, j int) bool {
+		if res.Counts[i].Count != res.Counts[j].Count {
+			return res.Counts[i].Count > res.Counts[j].Count
+		}
+		return res.Counts[i].Labels.Before(res.Counts[j].Labels)
+	})
+	return res
+}
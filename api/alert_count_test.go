@@ -0,0 +1,68 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+
+	"github.com/prometheus/alertmanager/types"
+)
+
+func TestCountAlertsBy(t *testing.T) {
+	now := time.Now()
+	alert := func(lset model.LabelSet, endsAt time.Time) *types.Alert {
+		return &types.Alert{Alert: model.Alert{Labels: lset, StartsAt: now.Add(-time.Hour), EndsAt: endsAt}}
+	}
+	firing := now.Add(time.Hour)
+
+	alerts := []*types.Alert{
+		alert(model.LabelSet{"alertname": "a", "team": "db", "severity": "critical"}, firing),
+		alert(model.LabelSet{"alertname": "b", "team": "db", "severity": "critical"}, firing),
+		alert(model.LabelSet{"alertname": "c", "team": "db", "severity": "warning"}, firing),
+		alert(model.LabelSet{"alertname": "d", "team": "web", "severity": "critical"}, firing),
+		alert(model.LabelSet{"alertname": "e", "severity": "critical"}, firing),
+		alert(model.LabelSet{"alertname": "f", "team": "web", "severity": "critical"}, now.Add(-time.Minute)),
+	}
+
+	require.Equal(t, &alertCounts{
+		By:    []model.LabelName{"team", "severity"},
+		Total: 5,
+		Counts: []*alertCount{
+			{Labels: model.LabelSet{"team": "db", "severity": "critical"}, Count: 2},
+			{Labels: model.LabelSet{"severity": "critical"}, Count: 1},
+			{Labels: model.LabelSet{"team": "web", "severity": "critical"}, Count: 1},
+			{Labels: model.LabelSet{"team": "db", "severity": "warning"}, Count: 1},
+		},
+	}, countAlertsBy(alerts, []model.LabelName{"team", "severity"}))
+
+	require.Equal(t, &alertCounts{
+		By:     []model.LabelName{},
+		Total:  5,
+		Counts: []*alertCount{{Labels: model.LabelSet{}, Count: 5}},
+	}, countAlertsBy(alerts, nil))
+}
+
+func TestCountAlertsInvalidLabel(t *testing.T) {
+	api := New(nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+
+	rec := httptest.NewRecorder()
+	api.countAlerts(rec, httptest.NewRequest("GET", "/alerts/counts?by=not-a-label", nil))
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+}
@@ -0,0 +1,217 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/prometheus/common/model"
+
+	"github.com/prometheus/alertmanager/dispatch"
+	"github.com/prometheus/alertmanager/types"
+)
+
+type alertState string
+
+// Possible states of an alert as seen by the API.
+const (
+	// The alert has not been routed by the dispatcher yet.
+	alertStateUnprocessed alertState = "unprocessed"
+	// The alert is routed and neither silenced nor inhibited.
+	alertStateActive alertState = "active"
+	// The alert is routed but silenced or inhibited.
+	alertStateSuppressed alertState = "suppressed"
+)
+
+// alertFilter holds the parameters by which alert listings are filtered
+// and paginated.
+type alertFilter struct {
+	matchers  types.Matchers
+	states    []alertState
+	receivers []string
+	// If set, only alerts assigned to the person are selected.
+	assignee *string
+
+	startsAfter, startsBefore time.Time
+	endsAfter, endsBefore     time.Time
+
+	order         sortOrder
+	offset, limit int
+}
+
+// parseAlertFilter reads an alertFilter from the query parameters of
+// a request. Supported parameters are:
+//
+//	matcher        <name>=<value> or <name>=~<regex>, the alert must match
+//	               (may be repeated). Names prefixed with "annotations."
+//	               are matched against the annotations of the alert.
+//	state          unprocessed, active or suppressed (may be repeated)
+//	receiver       receiver the alert is routed to (may be repeated)
+//	assignee       person the alert is assigned to
+//	startsAfter    RFC3339 timestamp
+//	startsBefore   RFC3339 timestamp
+//	endsAfter      RFC3339 timestamp
+//	endsBefore     RFC3339 timestamp
+//	sort           startsAt (default), endsAt, severity or labels, prefixed
+//	               with "-" for descending order
+//	offset         number of alerts to skip
+//	limit          maximum number of alerts to return
+func parseAlertFilter(q url.Values) (*alertFilter, error) {
+	f := &alertFilter{receivers: q["receiver"]}
+	if v, ok := q["assignee"]; ok {
+		f.assignee = &v[0]
+	}
+
+	for _, s := range q["matcher"] {
+		annotation := strings.HasPrefix(s, "annotations.")
+		m, err := parseMatcher(strings.TrimPrefix(s, "annotations."))
+		if err != nil {
+			return nil, err
+		}
+		m.IsAnnotation = annotation
+		if err := m.Init(); err != nil {
+			return nil, err
+		}
+		f.matchers = append(f.matchers, m)
+	}
+	for _, s := range q["state"] {
+		switch st := alertState(s); st {
+		case alertStateUnprocessed, alertStateActive, alertStateSuppressed:
+			f.states = append(f.states, st)
+		default:
+			return nil, fmt.Errorf("invalid alert state %q", s)
+		}
+	}
+
+	order, err := parseSortOrder(q.Get("sort"), alertSortKeys)
+	if err != nil {
+		return nil, err
+	}
+	if f.order = order; f.order.key == "" {
+		f.order.key = "startsAt"
+	}
+
+	err = parseTimeParams(q, map[string]*time.Time{
+		"startsAfter":  &f.startsAfter,
+		"startsBefore": &f.startsBefore,
+		"endsAfter":    &f.endsAfter,
+		"endsBefore":   &f.endsBefore,
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = parseIntParams(q, map[string]*int{
+		"offset": &f.offset,
+		"limit":  &f.limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// alertStates returns the state of every alert routed by the dispatcher.
+// Alerts missing from the result are unprocessed.
+func alertStates(groups dispatch.AlertOverview) map[model.Fingerprint]alertState {
+	states := map[model.Fingerprint]alertState{}
+	for _, g := range groups {
+		for _, b := range g.Blocks {
+			for _, a := range b.Alerts {
+				st := alertStateActive
+				if a.Inhibited || a.Silenced != "" {
+					st = alertStateSuppressed
+				}
+				// An alert suppressed for one route is suppressed for all.
+				if states[a.Fingerprint()] != alertStateSuppressed {
+					states[a.Fingerprint()] = st
+				}
+			}
+		}
+	}
+	return states
+}
+
+// match returns true iff the alert passes the filter. The state map is
+// only consulted if the filter has states, the route only if it has
+// receivers.
+func (f *alertFilter) match(a *types.Alert, states map[model.Fingerprint]alertState, route *dispatch.Route) bool {
+	if !f.matchers.MatchAlert(a.Labels, a.Annotations) {
+		return false
+	}
+	if len(f.states) > 0 {
+		st, ok := states[a.Fingerprint()]
+		if !ok {
+			st = alertStateUnprocessed
+		}
+		found := false
+		for _, s := range f.states {
+			if s == st {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(f.receivers) > 0 {
+		if route == nil {
+			return false
+		}
+		found := false
+	outer:
+		for _, r := range route.Match(a.Labels) {
+			for _, name := range f.receivers {
+				if r.RouteOpts.Receiver == name {
+					found = true
+					break outer
+				}
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if !f.startsAfter.IsZero() && a.StartsAt.Before(f.startsAfter) {
+		return false
+	}
+	if !f.startsBefore.IsZero() && a.StartsAt.After(f.startsBefore) {
+		return false
+	}
+	if !f.endsAfter.IsZero() && !a.EndsAt.IsZero() && a.EndsAt.Before(f.endsAfter) {
+		return false
+	}
+	if !f.endsBefore.IsZero() && (a.EndsAt.IsZero() || a.EndsAt.After(f.endsBefore)) {
+		return false
+	}
+	return true
+}
+
+// paginate sorts the alerts by the filter's order and returns the
+// requested page.
+func (f *alertFilter) paginate(alerts []*types.Alert, sev *severityRanking) []*types.Alert {
+	sortAlerts(alerts, f.order, sev)
+
+	if f.offset >= len(alerts) {
+		return nil
+	}
+	alerts = alerts[f.offset:]
+	if f.limit > 0 && f.limit < len(alerts) {
+		alerts = alerts[:f.limit]
+	}
+	return alerts
+}
@@ -0,0 +1,135 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+
+	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/dispatch"
+	"github.com/prometheus/alertmanager/types"
+)
+
+func TestParseAlertFilter(t *testing.T) {
+	for _, q := range []string{
+		"state=silenced",
+		"matcher=job",
+		"matcher=job=~(",
+		"startsAfter=yesterday",
+		"limit=-1",
+	} {
+		v, err := url.ParseQuery(q)
+		require.NoError(t, err)
+
+		_, err = parseAlertFilter(v)
+		require.Error(t, err, q)
+	}
+
+	v, err := url.ParseQuery("state=active&matcher=annotations.summary=~disk.*&receiver=team-X&offset=5")
+	require.NoError(t, err)
+
+	f, err := parseAlertFilter(v)
+	require.NoError(t, err)
+	require.Equal(t, []alertState{alertStateActive}, f.states)
+	require.Len(t, f.matchers, 1)
+	require.True(t, f.matchers[0].IsAnnotation)
+	require.Equal(t, "summary", f.matchers[0].Name)
+	require.Equal(t, []string{"team-X"}, f.receivers)
+	require.Equal(t, 5, f.offset)
+}
+
+func TestAlertFilterMatch(t *testing.T) {
+	now := time.Now().UTC()
+
+	newAlert := func(job string) *types.Alert {
+		return &types.Alert{Alert: model.Alert{
+			Labels:      model.LabelSet{"job": model.LabelValue(job)},
+			Annotations: model.LabelSet{"summary": "disk full"},
+			StartsAt:    now.Add(-time.Hour),
+		}}
+	}
+	active, suppressed, unprocessed := newAlert("a"), newAlert("b"), newAlert("c")
+
+	states := alertStates(dispatch.AlertOverview{{
+		Blocks: []*dispatch.AlertBlock{{
+			Alerts: []*dispatch.APIAlert{
+				{Alert: &active.Alert},
+				{Alert: &suppressed.Alert, Silenced: "sil"},
+			},
+		}},
+	}})
+
+	route := dispatch.NewRoute(&config.Route{
+		Receiver: "default",
+		Routes: []*config.Route{{
+			Receiver: "team-X",
+			Match:    map[string]string{"job": "a"},
+		}},
+	}, nil)
+
+	for _, c := range []struct {
+		query string
+		match []*types.Alert
+	}{
+		{"", []*types.Alert{active, suppressed, unprocessed}},
+		{"matcher=job=~a|b", []*types.Alert{active, suppressed}},
+		{"matcher=annotations.summary=disk full", []*types.Alert{active, suppressed, unprocessed}},
+		{"matcher=annotations.summary=cpu", nil},
+		{"state=active", []*types.Alert{active}},
+		{"state=suppressed&state=unprocessed", []*types.Alert{suppressed, unprocessed}},
+		{"receiver=team-X", []*types.Alert{active}},
+		{"receiver=default", []*types.Alert{suppressed, unprocessed}},
+		{"startsAfter=" + now.Format(time.RFC3339), nil},
+		{"endsBefore=" + now.Format(time.RFC3339), nil},
+		{"endsAfter=" + now.Format(time.RFC3339), []*types.Alert{active, suppressed, unprocessed}},
+	} {
+		v, err := url.ParseQuery(c.query)
+		require.NoError(t, err)
+		f, err := parseAlertFilter(v)
+		require.NoError(t, err)
+
+		var res []*types.Alert
+		for _, a := range []*types.Alert{active, suppressed, unprocessed} {
+			if f.match(a, states, route) {
+				res = append(res, a)
+			}
+		}
+		require.Equal(t, c.match, res, c.query)
+	}
+}
+
+func TestAlertFilterPaginate(t *testing.T) {
+	now := time.Now()
+
+	var alerts []*types.Alert
+	for i := 0; i < 5; i++ {
+		alerts = append(alerts, &types.Alert{Alert: model.Alert{
+			Labels:   model.LabelSet{"i": model.LabelValue(strconv.Itoa(i))},
+			StartsAt: now.Add(-time.Duration(i) * time.Minute),
+		}})
+	}
+
+	f := &alertFilter{order: sortOrder{key: "startsAt"}, offset: 1, limit: 2}
+	res := f.paginate(append([]*types.Alert(nil), alerts...), newSeverityRanking(nil))
+	require.Equal(t, []*types.Alert{alerts[3], alerts[2]}, res)
+
+	f = &alertFilter{offset: 5}
+	require.Len(t, f.paginate(alerts, newSeverityRanking(nil)), 0)
+}
@@ -14,9 +14,13 @@
 package api
 
 import (
+	"crypto/sha256"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
+	"strconv"
 	"sync"
 	"time"
 
@@ -25,14 +29,28 @@ import (
 	"github.com/prometheus/common/log"
 	"github.com/prometheus/common/model"
 	"github.com/prometheus/common/route"
-	"github.com/prometheus/common/version"
 	"golang.org/x/net/context"
 
+	"github.com/prometheus/alertmanager/ack"
+	"github.com/prometheus/alertmanager/apikey"
+	"github.com/prometheus/alertmanager/assignment"
+	"github.com/prometheus/alertmanager/audit"
+	"github.com/prometheus/alertmanager/auth"
+	"github.com/prometheus/alertmanager/cluster"
+	"github.com/prometheus/alertmanager/comment"
 	"github.com/prometheus/alertmanager/config"
 	"github.com/prometheus/alertmanager/dispatch"
+	"github.com/prometheus/alertmanager/history"
+	"github.com/prometheus/alertmanager/inhibit"
+	"github.com/prometheus/alertmanager/nflog"
+	"github.com/prometheus/alertmanager/notify"
+	"github.com/prometheus/alertmanager/pause"
 	"github.com/prometheus/alertmanager/provider"
 	"github.com/prometheus/alertmanager/silence"
 	"github.com/prometheus/alertmanager/silence/silencepb"
+	"github.com/prometheus/alertmanager/snooze"
+	"github.com/prometheus/alertmanager/template"
+	"github.com/prometheus/alertmanager/tracing"
 	"github.com/prometheus/alertmanager/types"
 )
 
@@ -55,30 +73,41 @@ func init() {
 	prometheus.Register(numInvalidAlerts)
 }
 
-var corsHeaders = map[string]string{
-	"Access-Control-Allow-Headers":  "Accept, Authorization, Content-Type, Origin",
-	"Access-Control-Allow-Methods":  "GET, OPTIONS",
-	"Access-Control-Allow-Origin":   "*",
-	"Access-Control-Expose-Headers": "Date",
-}
-
-// Enables cross-site script calls.
-func setCORS(w http.ResponseWriter) {
-	for h, v := range corsHeaders {
-		w.Header().Set(h, v)
-	}
-}
-
 // API provides registration of handlers for API routes.
 type API struct {
 	alerts         provider.Alerts
 	silences       *silence.Silences
+	acks           *ack.Acks
+	comments       *comment.Comments
+	assignments    *assignment.Assignments
+	snoozes        *snooze.Snoozes
+	pauses         *pause.Pauses
+	apiKeys        *apikey.Keys
+	nflog          nflog.Log
+	history        *history.History
+	audit          *audit.Log
 	config         string
 	configJSON     config.Config
+	configHash     string
+	configLoadedAt time.Time
 	resolveTimeout time.Duration
+	route          *dispatch.Route
+	severities     *severityRanking
+	rateLimiters   map[string]*rateLimiter
+	receiverConfs  []*config.Receiver
+	tmpl           *template.Template
+	captures       *notify.Captures
 	uptime         time.Time
+	cluster        *cluster.Tracker
+	readOnly       bool
+
+	// How long resolved alerts are kept and how often they are removed.
+	alertRetention  time.Duration
+	alertGCInterval time.Duration
 
 	groups func() dispatch.AlertOverview
+	// inhibitions explains the inhibition of a label set.
+	inhibitions func(model.LabelSet) []*inhibit.Inhibition
 
 	// context is an indirection for testing.
 	context func(r *http.Request) context.Context
@@ -86,13 +115,40 @@ type API struct {
 }
 
 // New returns a new API.
-func New(alerts provider.Alerts, silences *silence.Silences, gf func() dispatch.AlertOverview) *API {
+func New(
+	alerts provider.Alerts,
+	silences *silence.Silences,
+	acks *ack.Acks,
+	comments *comment.Comments,
+	assignments *assignment.Assignments,
+	snoozes *snooze.Snoozes,
+	pauses *pause.Pauses,
+	apiKeys *apikey.Keys,
+	nlog nflog.Log,
+	hist *history.History,
+	auditLog *audit.Log,
+	gf func() dispatch.AlertOverview,
+	inf func(model.LabelSet) []*inhibit.Inhibition,
+	cl *cluster.Tracker,
+) *API {
 	return &API{
-		context:  route.Context,
-		alerts:   alerts,
-		silences: silences,
-		groups:   gf,
-		uptime:   time.Now(),
+		context:     route.Context,
+		alerts:      alerts,
+		silences:    silences,
+		acks:        acks,
+		comments:    comments,
+		assignments: assignments,
+		snoozes:     snoozes,
+		pauses:      pauses,
+		apiKeys:     apiKeys,
+		nflog:       nlog,
+		history:     hist,
+		audit:       auditLog,
+		groups:      gf,
+		inhibitions: inf,
+		cluster:     cl,
+		severities:  newSeverityRanking(nil),
+		uptime:      time.Now(),
 	}
 }
 
@@ -100,8 +156,9 @@ func New(alerts provider.Alerts, silences *silence.Silences, gf func() dispatch.
 // in the given router.
 func (api *API) Register(r *route.Router) {
 	ihf := func(name string, f http.HandlerFunc) http.HandlerFunc {
+		f = api.writable(name, f)
 		return prometheus.InstrumentHandlerFunc(name, func(w http.ResponseWriter, r *http.Request) {
-			setCORS(w)
+			api.setCORS(w, r)
 			f(w, r)
 		})
 	}
@@ -109,21 +166,71 @@ func (api *API) Register(r *route.Router) {
 	r.Options("/*path", ihf("options", func(w http.ResponseWriter, r *http.Request) {}))
 
 	// Register legacy forwarder for alert pushing.
-	r.Post("/alerts", ihf("legacy_add_alerts", api.legacyAddAlerts))
+	r.Post("/alerts", ihf("legacy_add_alerts", api.rateLimited("alerts", api.legacyAddAlerts)))
+
+	api.registerV2(r.WithPrefix("/v2"), ihf)
 
 	// Register actual API.
 	r = r.WithPrefix("/v1")
 
 	r.Get("/status", ihf("status", api.status))
 	r.Get("/alerts/groups", ihf("alert_groups", api.alertGroups))
+	r.Get("/alerts/counts", ihf("alert_counts", api.countAlerts))
+	r.Get("/alerts/inhibitions", ihf("alert_inhibitions", api.explainInhibition))
+	r.Get("/alert/:fingerprint/explain", ihf("explain_alert", api.explainAlert))
 
 	r.Get("/alerts", ihf("list_alerts", api.listAlerts))
-	r.Post("/alerts", ihf("add_alerts", api.addAlerts))
+	r.Post("/alerts", ihf("add_alerts", api.rateLimited("alerts", api.addAlerts)))
+	r.Post("/alerts/bulk", ihf("add_alerts_bulk", api.rateLimited("alerts", api.addAlertsBulk)))
+	r.Post("/ingest/:name", ihf("ingest_alerts", api.rateLimited("alerts", api.ingestAlerts)))
 
 	r.Get("/silences", ihf("list_silences", api.listSilences))
-	r.Post("/silences", ihf("add_silence", api.addSilence))
+	r.Post("/silences", ihf("add_silence", api.rateLimited("silences", api.addSilence)))
+	r.Get("/silences/link", ihf("silence_link", api.silenceLink))
+	r.Post("/silences/link", ihf("confirm_silence_link", api.rateLimited("silences", api.confirmSilenceLink)))
 	r.Get("/silence/:sid", ihf("get_silence", api.getSilence))
-	r.Del("/silence/:sid", ihf("del_silence", api.delSilence))
+	r.Del("/silence/:sid", ihf("del_silence", api.rateLimited("silences", api.delSilence)))
+	r.Post("/silence/:sid/approve", ihf("approve_silence", api.rateLimited("silences", api.approveSilence)))
+	r.Post("/silence/:sid/extend", ihf("extend_silence", api.rateLimited("silences", api.extendSilence)))
+
+	r.Get("/acks", ihf("list_acks", api.listAcks))
+	r.Post("/acks", ihf("add_ack", api.unscoped(api.addAck)))
+	r.Get("/ack/:aid", ihf("get_ack", api.getAck))
+	r.Del("/ack/:aid", ihf("del_ack", api.unscoped(api.delAck)))
+
+	r.Get("/comments", ihf("list_comments", api.listComments))
+	r.Post("/comments", ihf("add_comment", api.unscoped(api.addComment)))
+
+	r.Get("/assignments", ihf("list_assignments", api.listAssignments))
+	r.Post("/assignments", ihf("set_assignment", api.unscoped(api.setAssignment)))
+	r.Del("/assignment/:id", ihf("del_assignment", api.unscoped(api.delAssignment)))
+
+	r.Get("/snoozes", ihf("list_snoozes", api.listSnoozes))
+	r.Post("/snoozes", ihf("add_snooze", api.unscoped(api.addSnooze)))
+	r.Del("/snooze/:fingerprint", ihf("del_snooze", api.unscoped(api.delSnooze)))
+
+	r.Get("/pauses", ihf("list_pauses", api.listPauses))
+	r.Post("/pauses", ihf("add_pause", api.unscoped(api.addPause)))
+	r.Del("/pause/:receiver", ihf("del_pause", api.unscoped(api.delPause)))
+
+	r.Get("/history", ihf("list_history", api.listHistory))
+	r.Get("/audit", ihf("list_audit", api.listAudit))
+
+	r.Get("/apikeys", ihf("list_apikeys", api.withoutAPIKey(api.listAPIKeys)))
+	r.Post("/apikeys", ihf("create_apikey", api.withoutAPIKey(api.createAPIKey)))
+	r.Del("/apikey/:id", ihf("revoke_apikey", api.withoutAPIKey(api.revokeAPIKey)))
+	r.Post("/apikey/:id/rotate", ihf("rotate_apikey", api.withoutAPIKey(api.rotateAPIKey)))
+
+	r.Get("/receivers", ihf("receivers", api.receivers))
+	r.Post("/receivers/:name/test", ihf("test_receiver", api.unscoped(api.testReceiver)))
+	r.Get("/receivers/:name/capture", ihf("receiver_capture", api.unscoped(api.getCapture)))
+	r.Post("/receivers/:name/capture", ihf("enable_receiver_capture", api.unscoped(api.enableCapture)))
+	r.Del("/receivers/:name/capture", ihf("disable_receiver_capture", api.unscoped(api.disableCapture)))
+
+	r.Post("/templates/render", ihf("render_template", api.renderTemplate))
+
+	r.Get("/snapshot", ihf("snapshot", api.snapshot))
+	r.Post("/snapshot", ihf("restore_snapshot", api.unscoped(api.restoreSnapshot)))
 }
 
 // Update sets the configuration string to a new value.
@@ -141,17 +248,67 @@ func (api *API) Update(cfg string, resolveTimeout time.Duration) error {
 	}
 
 	api.configJSON = *configJSON
+	api.configHash = fmt.Sprintf("%x", sha256.Sum256([]byte(cfg)))
+	api.configLoadedAt = time.Now()
+	api.route = dispatch.NewRoute(configJSON.Route, nil)
+	api.severities = newSeverityRanking(configJSON.NotificationPriority)
+
+	var alertsLimit, silencesLimit *config.RateLimit
+	if rl := configJSON.RateLimits; rl != nil {
+		alertsLimit, silencesLimit = rl.Alerts, rl.Silences
+	}
+	api.rateLimiters = map[string]*rateLimiter{
+		"alerts":   updateRateLimiter(api.rateLimiters["alerts"], alertsLimit),
+		"silences": updateRateLimiter(api.rateLimiters["silences"], silencesLimit),
+	}
 	return nil
 }
 
+// SetAlertRetention sets how long resolved alerts are kept and the interval
+// at which they are garbage collected, which are reported by the status.
+func (api *API) SetAlertRetention(retention, gcInterval time.Duration) {
+	api.mtx.Lock()
+	defer api.mtx.Unlock()
+
+	api.alertRetention = retention
+	api.alertGCInterval = gcInterval
+}
+
+// SetNotifiers sets the receivers test notifications are sent to and the
+// template used to render them and template previews. Unlike the receivers
+// of the configuration passed to Update, the secrets of the receivers must
+// not be hidden.
+func (api *API) SetNotifiers(receivers []*config.Receiver, tmpl *template.Template) {
+	api.mtx.Lock()
+	defer api.mtx.Unlock()
+
+	api.receiverConfs = receivers
+	api.tmpl = tmpl
+}
+
 type errorType string
 
 const (
-	errorNone     errorType = ""
-	errorInternal           = "server_error"
-	errorBadData            = "bad_data"
+	errorNone            errorType = ""
+	errorInternal                  = "server_error"
+	errorBadData                   = "bad_data"
+	errorQuotaExceeded             = "quota_exceeded"
+	errorConflict                  = "conflict"
+	errorForbidden                 = "forbidden"
+	errorRateLimited               = "rate_limited"
+	errorPayloadTooLarge           = "payload_too_large"
+	errorUnavailable               = "unavailable"
 )
 
+// silenceErrorType returns the type of an error changing a silence, which
+// is typ unless the change was not replicated to a quorum of peers.
+func silenceErrorType(err error, typ errorType) errorType {
+	if err == silence.ErrNoQuorum {
+		return errorUnavailable
+	}
+	return typ
+}
+
 type apiError struct {
 	typ errorType
 	err error
@@ -161,53 +318,73 @@ func (e *apiError) Error() string {
 	return fmt.Sprintf("%s: %s", e.typ, e.err)
 }
 
-func (api *API) status(w http.ResponseWriter, req *http.Request) {
-	api.mtx.RLock()
+// alertGroups returns the aggregation groups. If the "assignee" query
+// parameter is set, only alerts assigned to the given person are returned.
+// The "sort" parameter orders the groups by labels (default), size,
+// startsAt or severity, prefixed with "-" for descending order.
+func (api *API) alertGroups(w http.ResponseWriter, req *http.Request) {
+	assignee, filter := req.URL.Query()["assignee"]
 
-	var status = struct {
-		Config      string            `json:"config"`
-		ConfigJSON  config.Config     `json:"configJSON"`
-		VersionInfo map[string]string `json:"versionInfo"`
-		Uptime      time.Time         `json:"uptime"`
-	}{
-		Config:     api.config,
-		ConfigJSON: api.configJSON,
-		VersionInfo: map[string]string{
-			"version":   version.Version,
-			"revision":  version.Revision,
-			"branch":    version.Branch,
-			"buildUser": version.BuildUser,
-			"buildDate": version.BuildDate,
-			"goVersion": version.GoVersion,
-		},
-		Uptime: api.uptime,
+	order, err := parseSortOrder(req.URL.Query().Get("sort"), groupSortKeys)
+	if err != nil {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
 	}
 
-	api.mtx.RUnlock()
+	groups := api.groups()
+	res := groups[:0]
+	for _, g := range groups {
+		blocks := g.Blocks[:0]
+		for _, b := range g.Blocks {
+			alerts := b.Alerts[:0]
+			for _, a := range b.Alerts {
+				a.Ack = api.acks.Acked(a.Labels, a.StartsAt)
+				a.Comments = api.comments.Query(a.Labels, a.StartsAt)
+				a.AssignedTo = api.assignments.Assignee(a.Labels, a.StartsAt)
+				a.Snooze = api.snoozes.Snoozed(a.Fingerprint())
+
+				if filter && a.AssignedTo != assignee[0] {
+					continue
+				}
+				alerts = append(alerts, a)
+			}
+			b.Pause = api.pauses.Paused(b.RouteOpts.Receiver, g.GroupKey)
+
+			if b.Alerts = alerts; len(alerts) > 0 || !filter {
+				blocks = append(blocks, b)
+			}
+		}
+		if g.Blocks = blocks; len(blocks) > 0 || !filter {
+			res = append(res, g)
+		}
+	}
 
-	respond(w, status)
-}
+	if order.key != "" {
+		api.mtx.RLock()
+		sev := api.severities
+		api.mtx.RUnlock()
 
-func (api *API) alertGroups(w http.ResponseWriter, req *http.Request) {
-	respond(w, api.groups())
+		sortAlertGroups(res, order, sev)
+	}
+	respond(w, res)
 }
 
+// listAlerts returns the alerts. They may be filtered and paginated with
+// the query parameters described at parseAlertFilter.
 func (api *API) listAlerts(w http.ResponseWriter, r *http.Request) {
-	alerts := api.alerts.GetPending()
-	defer alerts.Close()
-
-	var (
-		err error
-		res []*types.Alert
-	)
-	// TODO(fabxc): enforce a sensible timeout.
-	for a := range alerts.Next() {
-		if err = alerts.Err(); err != nil {
-			break
-		}
-		res = append(res, a)
+	f, err := parseAlertFilter(r.URL.Query())
+	if err != nil {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
 	}
 
+	res, err := api.queryAlerts(f)
 	if err != nil {
 		respondError(w, apiError{
 			typ: errorInternal,
@@ -215,7 +392,66 @@ func (api *API) listAlerts(w http.ResponseWriter, r *http.Request) {
 		}, nil)
 		return
 	}
-	respond(w, types.Alerts(res...))
+
+	api.mtx.RLock()
+	sev := api.severities
+	api.mtx.RUnlock()
+
+	type apiAlert struct {
+		*model.Alert
+		Ack        *ack.Ack             `json:"ack,omitempty"`
+		Comments   []*types.Comment     `json:"comments,omitempty"`
+		AssignedTo string               `json:"assignedTo,omitempty"`
+		Snooze     *snooze.Snooze       `json:"snooze,omitempty"`
+		Sources    []*types.AlertSource `json:"sources,omitempty"`
+	}
+
+	w.Header().Set("X-Total-Count", strconv.Itoa(len(res)))
+	res = f.paginate(res, sev)
+
+	apiAlerts := make([]*apiAlert, 0, len(res))
+	for _, ta := range res {
+		a := types.Alerts(ta)[0]
+		apiAlerts = append(apiAlerts, &apiAlert{
+			Alert:      a,
+			Ack:        api.acks.Acked(a.Labels, a.StartsAt),
+			Comments:   api.comments.Query(a.Labels, a.StartsAt),
+			AssignedTo: api.assignments.Assignee(a.Labels, a.StartsAt),
+			Snooze:     api.snoozes.Snoozed(a.Fingerprint()),
+			Sources:    ta.Sources,
+		})
+	}
+	respond(w, apiAlerts)
+}
+
+// queryAlerts returns all alerts passing the filter without paginating them.
+func (api *API) queryAlerts(f *alertFilter) ([]*types.Alert, error) {
+	var states map[model.Fingerprint]alertState
+	if len(f.states) > 0 {
+		states = alertStates(api.groups())
+	}
+	api.mtx.RLock()
+	route := api.route
+	api.mtx.RUnlock()
+
+	alerts := api.alerts.GetPending()
+	defer alerts.Close()
+
+	var res []*types.Alert
+	// TODO(fabxc): enforce a sensible timeout.
+	for a := range alerts.Next() {
+		if err := alerts.Err(); err != nil {
+			return nil, err
+		}
+		if !f.match(a, states, route) {
+			continue
+		}
+		if f.assignee != nil && api.assignments.Assignee(a.Labels, a.StartsAt) != *f.assignee {
+			continue
+		}
+		res = append(res, a)
+	}
+	return res, alerts.Err()
 }
 
 func (api *API) legacyAddAlerts(w http.ResponseWriter, r *http.Request) {
@@ -226,8 +462,7 @@ func (api *API) legacyAddAlerts(w http.ResponseWriter, r *http.Request) {
 		Labels      model.LabelSet   `json:"labels"`
 		Payload     model.LabelSet   `json:"payload"`
 	}{}
-	if err := receive(r, &legacyAlerts); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	if !api.receiveAlerts(w, r, &legacyAlerts) {
 		return
 	}
 
@@ -254,22 +489,117 @@ func (api *API) legacyAddAlerts(w http.ResponseWriter, r *http.Request) {
 
 func (api *API) addAlerts(w http.ResponseWriter, r *http.Request) {
 	var alerts []*types.Alert
-	if err := receive(r, &alerts); err != nil {
+	if !api.receiveAlerts(w, r, &alerts) {
+		return
+	}
+
+	api.insertAlerts(w, r, alerts...)
+}
+
+// alertSource returns the name of the client that sent the alert. If the
+// alert has the source label, the label is removed from it and its value is
+// returned. Otherwise the host of the alert's generator URL is returned.
+func alertSource(a *types.Alert, label model.LabelName) string {
+	if v, ok := a.Labels[label]; ok && label != "" {
+		lset := make(model.LabelSet, len(a.Labels)-1)
+		for ln, lv := range a.Labels {
+			if ln != label {
+				lset[ln] = lv
+			}
+		}
+		a.Labels = lset
+		return string(v)
+	}
+	u, err := url.Parse(a.GeneratorURL)
+	if err != nil {
+		return ""
+	}
+	return u.Host
+}
+
+func (api *API) insertAlerts(w http.ResponseWriter, r *http.Request, alerts ...*types.Alert) {
+	span := traceIngest(r)
+	defer span.End()
+	span.SetAttribute("alerts", len(alerts))
+	setTraceParent(span, alerts)
+
+	limits := api.ingestLimits()
+	if n := limits.MaxAlertsPerRequest; n > 0 && len(alerts) > n {
 		respondError(w, apiError{
 			typ: errorBadData,
+			err: fmt.Errorf("request has %d alerts, more than the limit of %d", len(alerts), n),
+		}, nil)
+		return
+	}
+	if err := api.checkAlertsScope(r, alerts); err != nil {
+		respondError(w, apiError{
+			typ: errorForbidden,
 			err: err,
 		}, nil)
 		return
 	}
 
-	api.insertAlerts(w, r, alerts...)
+	alertErrs, err := api.putAlerts(limits, 0, alerts)
+	span.SetError(err)
+	if err != nil {
+		respondError(w, apiError{
+			typ: errorInternal,
+			err: err,
+		}, nil)
+		return
+	}
+
+	if len(alertErrs) > 0 {
+		validationErrs := &types.MultiError{}
+		for _, e := range alertErrs {
+			validationErrs.Add(errors.New(e.Error))
+		}
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: validationErrs,
+		}, struct {
+			Errors []*alertError `json:"errors"`
+		}{
+			Errors: alertErrs,
+		})
+		return
+	}
+
+	respond(w, nil)
 }
 
-func (api *API) insertAlerts(w http.ResponseWriter, r *http.Request, alerts ...*types.Alert) {
+// traceIngest starts the span of a request sending alerts, continuing the
+// trace of the client if any.
+func traceIngest(r *http.Request) *tracing.Span {
+	_, span := tracing.Start(tracing.Extract(r.Context(), r.Header), "ingest", tracing.KindServer)
+	span.SetAttribute("http.path", r.URL.Path)
+	return span
+}
+
+// setTraceParent links the alerts to the span of the request sending them,
+// so the spans of their notifications can refer to it.
+func setTraceParent(span *tracing.Span, alerts []*types.Alert) {
+	if sc := span.Context(); sc.Sampled {
+		for _, a := range alerts {
+			a.TraceParent = sc.TraceParent()
+		}
+	}
+}
+
+// putAlerts completes the received alerts and inserts those that are valid.
+// It returns the errors of the invalid alerts, whose indices are counted
+// from offset.
+func (api *API) putAlerts(limits *config.IngestLimits, offset int, alerts []*types.Alert) ([]*alertError, error) {
 	now := time.Now()
 
+	api.mtx.RLock()
+	route, resolveTimeout := api.route, api.resolveTimeout
+	sourceLabel := api.configJSON.Global.SourceLabel
+	api.mtx.RUnlock()
+
 	for _, alert := range alerts {
 		alert.UpdatedAt = now
+		source := alertSource(alert, sourceLabel)
 
 		// Ensure StartsAt is set.
 		if alert.StartsAt.IsZero() {
@@ -279,69 +609,190 @@ func (api *API) insertAlerts(w http.ResponseWriter, r *http.Request, alerts ...*
 		// is marked resolved if it is not updated.
 		if alert.EndsAt.IsZero() {
 			alert.Timeout = true
-			alert.EndsAt = now.Add(api.resolveTimeout)
+			if route != nil {
+				alert.EndsAt = now.Add(route.ResolveTimeout(alert.Labels, resolveTimeout))
+			} else {
+				alert.EndsAt = now.Add(resolveTimeout)
+			}
 
 			numReceivedAlerts.WithLabelValues("firing").Inc()
 		} else {
 			numReceivedAlerts.WithLabelValues("resolved").Inc()
 		}
+
+		if source != "" {
+			alert.Sources = []*types.AlertSource{{
+				Name:      source,
+				EndsAt:    alert.EndsAt,
+				Timeout:   alert.Timeout,
+				UpdatedAt: now,
+			}}
+		}
 	}
 
 	// Make a best effort to insert all alerts that are valid.
 	var (
-		validAlerts    = make([]*types.Alert, 0, len(alerts))
-		validationErrs = &types.MultiError{}
+		validAlerts = make([]*types.Alert, 0, len(alerts))
+		alertErrs   []*alertError
 	)
-	for _, a := range alerts {
-		if err := a.Validate(); err != nil {
-			validationErrs.Add(err)
+	for i, a := range alerts {
+		err := a.Validate()
+		if err == nil {
+			err = checkAlertLimits(limits, a)
+		}
+		if err != nil {
+			alertErrs = append(alertErrs, &alertError{Index: offset + i, Labels: a.Labels, Error: err.Error()})
 			numInvalidAlerts.Inc()
 			continue
 		}
 		validAlerts = append(validAlerts, a)
 	}
-	if err := api.alerts.Put(validAlerts...); err != nil {
+	return alertErrs, api.alerts.Put(validAlerts...)
+}
+
+// PutAlerts inserts alerts as if they were received by the API, completing
+// their start and end times and sources. Invalid alerts are skipped and
+// their errors returned.
+func (api *API) PutAlerts(alerts ...*types.Alert) error {
+	alertErrs, err := api.putAlerts(api.ingestLimits(), 0, alerts)
+	if err != nil {
+		return err
+	}
+	if len(alertErrs) > 0 {
+		errs := &types.MultiError{}
+		for _, e := range alertErrs {
+			errs.Add(errors.New(e.Error))
+		}
+		return errs
+	}
+	return nil
+}
+
+func (api *API) addSilence(w http.ResponseWriter, r *http.Request) {
+	var sil types.Silence
+	if err := receive(r, &sil); err != nil {
 		respondError(w, apiError{
-			typ: errorInternal,
+			typ: errorBadData,
 			err: err,
 		}, nil)
 		return
 	}
+	api.createSilence(w, r, &sil)
+}
 
-	if validationErrs.Len() > 0 {
+// createSilence creates or updates the silence on behalf of the user of the
+// request after checking it against the user's scope, the silence policy
+// and the existing silences, and responds with its ID.
+func (api *API) createSilence(w http.ResponseWriter, r *http.Request, sil *types.Silence) {
+	api.setUser(r, &sil.CreatedBy)
+	if err := api.checkSilenceScope(r, sil); err != nil {
+		respondError(w, apiError{
+			typ: errorForbidden,
+			err: err,
+		}, nil)
+		return
+	}
+	if sil.ID != "" {
+		if err := api.checkSilenceIDScope(r, sil.ID); err != nil {
+			respondError(w, apiError{
+				typ: errorForbidden,
+				err: err,
+			}, nil)
+			return
+		}
+	}
+	psil, err := silenceToProto(sil)
+	if err != nil {
 		respondError(w, apiError{
 			typ: errorBadData,
-			err: validationErrs,
+			err: err,
 		}, nil)
 		return
 	}
+	// Drop start time for new silences so we default to now.
+	if sil.ID == "" && sil.StartsAt.Before(time.Now()) {
+		psil.StartsAt = nil
+	}
 
-	respond(w, nil)
-}
+	api.mtx.RLock()
+	policy := api.configJSON.SilencePolicy
+	api.mtx.RUnlock()
 
-func (api *API) addSilence(w http.ResponseWriter, r *http.Request) {
-	var sil types.Silence
-	if err := receive(r, &sil); err != nil {
+	if err := checkSilencePolicy(policy, sil, time.Now()); err != nil {
 		respondError(w, apiError{
 			typ: errorBadData,
 			err: err,
 		}, nil)
 		return
 	}
-	psil, err := silenceToProto(&sil)
+	if policy != nil {
+		if err := checkSilenceComment(policy.Comment, sil.Comment); err != nil {
+			respondError(w, apiError{
+				typ: errorBadData,
+				err: err,
+			}, nil)
+			return
+		}
+	}
+	active, err := api.activeSilences(sil.CreatedBy)
 	if err != nil {
 		respondError(w, apiError{
-			typ: errorBadData,
+			typ: errorInternal,
 			err: err,
 		}, nil)
 		return
 	}
-	// Drop start time for new silences so we default to now.
-	if sil.ID == "" && sil.StartsAt.Before(time.Now()) {
-		psil.StartsAt = nil
+	if err := checkSilenceQuota(policy, sil.CreatedBy, active); err != nil {
+		respondError(w, apiError{
+			typ: errorQuotaExceeded,
+			err: err,
+		}, nil)
+		return
+	}
+
+	overlaps, err := api.overlappingSilences(sil)
+	if err != nil {
+		respondError(w, apiError{
+			typ: errorInternal,
+			err: err,
+		}, nil)
+		return
+	}
+	if len(overlaps) > 0 && r.URL.Query().Get("override") != "true" {
+		respondError(w, apiError{
+			typ: errorConflict,
+			err: fmt.Errorf("%d existing silence(s) already mute all alerts of the silence, set override=true to create it anyway", len(overlaps)),
+		}, struct {
+			Conflicts []*types.Silence `json:"conflicts"`
+		}{
+			Conflicts: overlaps,
+		})
+		return
+	}
+
+	psil.ApprovalRequired, err = approvalRequired(policy, sil, func() (int, error) {
+		return api.affectedAlerts(sil)
+	})
+	if err != nil {
+		respondError(w, apiError{
+			typ: errorInternal,
+			err: err,
+		}, nil)
+		return
 	}
 
 	sid, err := api.silences.Create(psil)
+	if err == silence.ErrNoQuorum {
+		respondError(w, apiError{
+			typ: errorUnavailable,
+			err: err,
+		}, struct {
+			SilenceID string `json:"silenceId"`
+		}{
+			SilenceID: sid,
+		})
+		return
+	}
 	if err != nil {
 		respondError(w, apiError{
 			typ: errorInternal,
@@ -351,15 +802,212 @@ func (api *API) addSilence(w http.ResponseWriter, r *http.Request) {
 	}
 
 	respond(w, struct {
-		SilenceID string `json:"silenceId"`
+		SilenceID string           `json:"silenceId"`
+		Conflicts []*types.Silence `json:"conflicts,omitempty"`
 	}{
 		SilenceID: sid,
+		Conflicts: overlaps,
 	})
 }
 
+// overlappingSilences returns the active and pending silences that make the
+// given silence redundant.
+func (api *API) overlappingSilences(sil *types.Silence) ([]*types.Silence, error) {
+	psils, err := api.silences.Query(silence.QState(silence.StateActive, silence.StatePending))
+	if err != nil {
+		return nil, err
+	}
+	sils := make([]*types.Silence, 0, len(psils))
+	for _, ps := range psils {
+		s, err := silenceFromProto(ps)
+		if err != nil {
+			return nil, err
+		}
+		sils = append(sils, s)
+	}
+	return overlappingSilences(sil, sils, time.Now()), nil
+}
+
+// activeSilences returns the number of active and pending silences of the creator.
+func (api *API) activeSilences(creator string) (int, error) {
+	sils, err := api.silences.Query(silence.QState(silence.StateActive, silence.StatePending))
+	if err != nil {
+		return 0, err
+	}
+	var n int
+	for _, s := range sils {
+		if len(s.Comments) > 0 && s.Comments[0].Author == creator {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// affectedAlerts returns the number of firing alerts matched by the silence.
+func (api *API) affectedAlerts(sil *types.Silence) (int, error) {
+	ms := make(types.Matchers, 0, len(sil.Matchers))
+	for _, m := range sil.Matchers {
+		m := *m
+		if err := m.Init(); err != nil {
+			return 0, err
+		}
+		ms = append(ms, &m)
+	}
+
+	// Silences of an aggregation group only affect the alerts in it.
+	if sil.GroupKey != "" {
+		var n int
+		for _, ag := range api.groups() {
+			if strconv.FormatUint(ag.GroupKey, 10) != sil.GroupKey {
+				continue
+			}
+			for _, b := range ag.Blocks {
+				for _, a := range b.Alerts {
+					if !a.Resolved() && ms.MatchAlert(a.Labels, a.Annotations) {
+						n++
+					}
+				}
+			}
+		}
+		return n, nil
+	}
+
+	it := api.alerts.GetPending()
+	defer it.Close()
+
+	var n int
+	for a := range it.Next() {
+		if err := it.Err(); err != nil {
+			return 0, err
+		}
+		if !a.Resolved() && ms.MatchAlert(a.Labels, a.Annotations) {
+			n++
+		}
+	}
+	return n, it.Err()
+}
+
+func (api *API) approveSilence(w http.ResponseWriter, r *http.Request) {
+	sid := route.Param(api.context(r), "sid")
+
+	var req struct {
+		ApprovedBy string `json:"approvedBy"`
+	}
+	if err := receive(r, &req); err != nil {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+	api.setUser(r, &req.ApprovedBy)
+	if err := api.checkSilenceIDScope(r, sid); err != nil {
+		respondError(w, apiError{
+			typ: errorForbidden,
+			err: err,
+		}, nil)
+		return
+	}
+	if err := api.silences.Approve(sid, req.ApprovedBy); err != nil {
+		respondError(w, apiError{
+			typ: silenceErrorType(err, errorBadData),
+			err: err,
+		}, nil)
+		return
+	}
+	respond(w, nil)
+}
+
+func (api *API) extendSilence(w http.ResponseWriter, r *http.Request) {
+	sid := route.Param(api.context(r), "sid")
+
+	var req struct {
+		EndsAt     time.Time `json:"endsAt"`
+		Duration   string    `json:"duration"`
+		ExtendedBy string    `json:"extendedBy"`
+		Comment    string    `json:"comment"`
+	}
+	if err := receive(r, &req); err != nil {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+	api.setUser(r, &req.ExtendedBy)
+	if req.EndsAt.IsZero() == (req.Duration == "") {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: fmt.Errorf("either endsAt or duration must be set"),
+		}, nil)
+		return
+	}
+
+	sils, err := api.silences.Query(silence.QIDs(sid))
+	if err != nil || len(sils) == 0 {
+		http.Error(w, fmt.Sprint("Error getting silence: ", err), http.StatusNotFound)
+		return
+	}
+	sil, err := silenceFromProto(sils[0])
+	if err != nil {
+		respondError(w, apiError{
+			typ: errorInternal,
+			err: err,
+		}, nil)
+		return
+	}
+
+	if req.Duration != "" {
+		d, err := time.ParseDuration(req.Duration)
+		if err != nil {
+			respondError(w, apiError{
+				typ: errorBadData,
+				err: err,
+			}, nil)
+			return
+		}
+		req.EndsAt = sil.EndsAt.Add(d)
+	}
+	sil.EndsAt = req.EndsAt
+
+	if err := api.checkSilenceScope(r, sil); err != nil {
+		respondError(w, apiError{
+			typ: errorForbidden,
+			err: err,
+		}, nil)
+		return
+	}
+
+	api.mtx.RLock()
+	policy := api.configJSON.SilencePolicy
+	api.mtx.RUnlock()
+
+	if err := checkSilencePolicy(policy, sil, time.Now()); err != nil {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+
+	if err := api.silences.Extend(sid, req.EndsAt, req.ExtendedBy, req.Comment); err != nil {
+		respondError(w, apiError{
+			typ: silenceErrorType(err, errorBadData),
+			err: err,
+		}, nil)
+		return
+	}
+	respond(w, sil)
+}
+
 func (api *API) getSilence(w http.ResponseWriter, r *http.Request) {
 	sid := route.Param(api.context(r), "sid")
 
+	// Repair the silence from other peers if quorum writes are enabled.
+	if err := api.silences.Sync(sid); err != nil {
+		log.Warnf("Syncing silence %s failed: %s", sid, err)
+	}
+
 	sils, err := api.silences.Query(silence.QIDs(sid))
 	if err != nil || len(sils) == 0 {
 		http.Error(w, fmt.Sprint("Error getting silence: ", err), http.StatusNotFound)
@@ -373,6 +1021,7 @@ func (api *API) getSilence(w http.ResponseWriter, r *http.Request) {
 		}, nil)
 		return
 	}
+	api.setUsage(sil)
 
 	respond(w, sil)
 }
@@ -380,9 +1029,16 @@ func (api *API) getSilence(w http.ResponseWriter, r *http.Request) {
 func (api *API) delSilence(w http.ResponseWriter, r *http.Request) {
 	sid := route.Param(api.context(r), "sid")
 
+	if err := api.checkSilenceIDScope(r, sid); err != nil {
+		respondError(w, apiError{
+			typ: errorForbidden,
+			err: err,
+		}, nil)
+		return
+	}
 	if err := api.silences.Expire(sid); err != nil {
 		respondError(w, apiError{
-			typ: errorBadData,
+			typ: silenceErrorType(err, errorBadData),
 			err: err,
 		}, nil)
 		return
@@ -391,7 +1047,16 @@ func (api *API) delSilence(w http.ResponseWriter, r *http.Request) {
 }
 
 func (api *API) listSilences(w http.ResponseWriter, r *http.Request) {
-	psils, err := api.silences.Query()
+	filter, err := parseSilenceFilter(r.URL.Query())
+	if err != nil {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+
+	sils, err := api.querySilences(filter)
 	if err != nil {
 		respondError(w, apiError{
 			typ: errorInternal,
@@ -400,20 +1065,43 @@ func (api *API) listSilences(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	w.Header().Set("X-Total-Count", strconv.Itoa(len(sils)))
+	respond(w, filter.paginate(sils))
+}
+
+// querySilences returns all silences passing the filter without paginating
+// them.
+func (api *API) querySilences(f *silenceFilter) ([]*types.Silence, error) {
+	psils, err := api.silences.Query(f.queryParams()...)
+	if err != nil {
+		return nil, err
+	}
+
 	var sils []*types.Silence
 	for _, ps := range psils {
 		s, err := silenceFromProto(ps)
 		if err != nil {
-			respondError(w, apiError{
-				typ: errorInternal,
-				err: err,
-			}, nil)
-			return
+			return nil, err
+		}
+		if f.match(ps, s) {
+			api.setUsage(s)
+			sils = append(sils, s)
 		}
-		sils = append(sils, s)
 	}
+	return sils, nil
+}
 
-	respond(w, sils)
+// setUsage sets the recorded usage of the silence.
+func (api *API) setUsage(s *types.Silence) {
+	u, ok := api.silences.Usage(s.ID)
+	if !ok {
+		return
+	}
+	s.MutedAlerts = u.MutedAlerts
+	if !u.LastMatched.IsZero() {
+		lastMatched := u.LastMatched
+		s.LastMatchedAt = &lastMatched
+	}
 }
 
 func silenceToProto(s *types.Silence) (*silencepb.Silence, error) {
@@ -434,12 +1122,15 @@ func silenceToProto(s *types.Silence) (*silencepb.Silence, error) {
 		StartsAt:  startsAt,
 		EndsAt:    endsAt,
 		UpdatedAt: updatedAt,
+		GroupKey:  s.GroupKey,
+		Soft:      s.Soft,
 	}
 	for _, m := range s.Matchers {
 		matcher := &silencepb.Matcher{
-			Name:    m.Name,
-			Pattern: m.Value,
-			Type:    silencepb.Matcher_EQUAL,
+			Name:       m.Name,
+			Pattern:    m.Value,
+			Type:       silencepb.Matcher_EQUAL,
+			Annotation: m.IsAnnotation,
 		}
 		if m.IsRegex {
 			matcher.Type = silencepb.Matcher_REGEXP
@@ -472,11 +1163,17 @@ func silenceFromProto(s *silencepb.Silence) (*types.Silence, error) {
 		StartsAt:  startsAt,
 		EndsAt:    endsAt,
 		UpdatedAt: updatedAt,
+		GroupKey:  s.GroupKey,
+
+		ApprovalRequired: s.ApprovalRequired,
+		ApprovedBy:       s.ApprovedBy,
+		Soft:             s.Soft,
 	}
 	for _, m := range s.Matchers {
 		matcher := &types.Matcher{
-			Name:  m.Name,
-			Value: m.Pattern,
+			Name:         m.Name,
+			Value:        m.Pattern,
+			IsAnnotation: m.Annotation,
 		}
 		switch m.Type {
 		case silencepb.Matcher_EQUAL:
@@ -532,8 +1229,20 @@ func respondError(w http.ResponseWriter, apiErr apiError, data interface{}) {
 		w.WriteHeader(http.StatusBadRequest)
 	case errorInternal:
 		w.WriteHeader(http.StatusInternalServerError)
+	case errorQuotaExceeded:
+		w.WriteHeader(http.StatusForbidden)
+	case errorConflict:
+		w.WriteHeader(http.StatusConflict)
+	case errorForbidden:
+		w.WriteHeader(http.StatusForbidden)
+	case errorRateLimited:
+		w.WriteHeader(http.StatusTooManyRequests)
+	case errorPayloadTooLarge:
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+	case errorUnavailable:
+		w.WriteHeader(http.StatusServiceUnavailable)
 	default:
-		panic(fmt.Sprintf("unknown error type %q", apiErr))
+		panic(fmt.Sprintf("unknown error type %q", apiErr.typ))
 	}
 
 	b, err := json.Marshal(&response{
@@ -550,6 +1259,15 @@ func respondError(w http.ResponseWriter, apiErr apiError, data interface{}) {
 	w.Write(b)
 }
 
+// setUser sets the user name given in a request to the name of the
+// authenticated user. Names given in requests are only used if
+// authentication is disabled.
+func (api *API) setUser(r *http.Request, name *string) {
+	if u, ok := auth.User(api.context(r)); ok {
+		*name = u
+	}
+}
+
 func receive(r *http.Request, v interface{}) error {
 	dec := json.NewDecoder(r.Body)
 	defer r.Body.Close()
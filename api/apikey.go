@@ -0,0 +1,250 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/common/route"
+
+	"github.com/prometheus/alertmanager/apikey"
+	"github.com/prometheus/alertmanager/auth"
+	"github.com/prometheus/alertmanager/silence"
+	"github.com/prometheus/alertmanager/types"
+)
+
+// apiKey is the representation of an API key in the API. The hashes of its
+// secrets are never returned.
+type apiKey struct {
+	ID         string         `json:"id"`
+	Name       string         `json:"name"`
+	Matchers   types.Matchers `json:"matchers"`
+	CreatedBy  string         `json:"createdBy"`
+	CreatedAt  time.Time      `json:"createdAt"`
+	ExpiresAt  *time.Time     `json:"expiresAt,omitempty"`
+	RotatedAt  *time.Time     `json:"rotatedAt,omitempty"`
+	RevokedAt  *time.Time     `json:"revokedAt,omitempty"`
+	LastUsedAt *time.Time     `json:"lastUsedAt,omitempty"`
+	Active     bool           `json:"active"`
+}
+
+// apiKeyToken is returned on creation and rotation of a key. It is the only
+// time the token is revealed.
+type apiKeyToken struct {
+	Key   *apiKey `json:"key"`
+	Token string  `json:"token"`
+}
+
+func newAPIKey(k *apikey.Key, now time.Time) *apiKey {
+	ts := func(t time.Time) *time.Time {
+		if t.IsZero() {
+			return nil
+		}
+		return &t
+	}
+	return &apiKey{
+		ID:         k.ID,
+		Name:       k.Name,
+		Matchers:   k.Matchers,
+		CreatedBy:  k.CreatedBy,
+		CreatedAt:  k.CreatedAt,
+		ExpiresAt:  ts(k.ExpiresAt),
+		RotatedAt:  ts(k.RotatedAt),
+		RevokedAt:  ts(k.RevokedAt),
+		LastUsedAt: ts(k.LastUsedAt),
+		Active:     k.Active(now),
+	}
+}
+
+func (api *API) listAPIKeys(w http.ResponseWriter, r *http.Request) {
+	now := time.Now()
+
+	res := []*apiKey{}
+	for _, k := range api.apiKeys.List() {
+		res = append(res, newAPIKey(k, now))
+	}
+	respond(w, res)
+}
+
+func (api *API) createAPIKey(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name      string         `json:"name"`
+		Matchers  types.Matchers `json:"matchers"`
+		CreatedBy string         `json:"createdBy"`
+		ExpiresAt time.Time      `json:"expiresAt"`
+	}
+	if err := receive(r, &req); err != nil {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+	api.setUser(r, &req.CreatedBy)
+
+	k, token, err := api.apiKeys.Create(req.Name, req.Matchers, req.CreatedBy, req.ExpiresAt)
+	if err != nil {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+	respond(w, &apiKeyToken{Key: newAPIKey(k, time.Now()), Token: token})
+}
+
+// rotateAPIKey replaces the secret of a key. The previous token remains
+// valid for the duration given by the "gracePeriod" query parameter.
+func (api *API) rotateAPIKey(w http.ResponseWriter, r *http.Request) {
+	id := route.Param(api.context(r), "id")
+
+	var grace time.Duration
+	if s := r.URL.Query().Get("gracePeriod"); s != "" {
+		var err error
+		if grace, err = time.ParseDuration(s); err != nil {
+			respondError(w, apiError{
+				typ: errorBadData,
+				err: fmt.Errorf("invalid grace period %q: %s", s, err),
+			}, nil)
+			return
+		}
+	}
+	k, token, err := api.apiKeys.Rotate(id, grace)
+	if err != nil {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+	respond(w, &apiKeyToken{Key: newAPIKey(k, time.Now()), Token: token})
+}
+
+func (api *API) revokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	id := route.Param(api.context(r), "id")
+
+	if err := api.apiKeys.Revoke(id); err != nil {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+	respond(w, nil)
+}
+
+// withoutAPIKey only serves requests that were not authenticated with an
+// API key. Keys must not be able to manage keys.
+func (api *API) withoutAPIKey(f http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := auth.Scope(api.context(r)); ok {
+			respondError(w, apiError{
+				typ: errorForbidden,
+				err: errors.New("API keys cannot be managed with API keys"),
+			}, nil)
+			return
+		}
+		f(w, r)
+	}
+}
+
+// unscoped only serves requests that were not authenticated with an API key
+// restricted to matchers. It protects changes that cannot be checked against
+// the matchers.
+func (api *API) unscoped(f http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if scope, _ := auth.Scope(api.context(r)); len(scope) > 0 {
+			respondError(w, apiError{
+				typ: errorForbidden,
+				err: fmt.Errorf("API key restricted to %s", scopeString(scope)),
+			}, nil)
+			return
+		}
+		f(w, r)
+	}
+}
+
+// checkAlertsScope returns an error unless all alerts match the matchers the
+// request's API key is restricted to.
+func (api *API) checkAlertsScope(r *http.Request, alerts []*types.Alert) error {
+	scope, _ := auth.Scope(api.context(r))
+	if len(scope) == 0 {
+		return nil
+	}
+	for _, a := range alerts {
+		if !scope.Match(a.Labels) {
+			return fmt.Errorf("alert %s is outside of the API key's scope %s", a.Labels, scopeString(scope))
+		}
+	}
+	return nil
+}
+
+// checkSilenceScope returns an error unless the silence only mutes alerts
+// matching the matchers the request's API key is restricted to. This is the
+// case if the silence contains all of those matchers.
+func (api *API) checkSilenceScope(r *http.Request, sil *types.Silence) error {
+	scope, _ := auth.Scope(api.context(r))
+	if len(scope) == 0 {
+		return nil
+	}
+Outer:
+	for _, sm := range scope {
+		for _, m := range sil.Matchers {
+			if m.Name == sm.Name && m.Value == sm.Value && m.IsRegex == sm.IsRegex && !m.IsAnnotation {
+				continue Outer
+			}
+		}
+		return fmt.Errorf("silence must contain the matchers of the API key's scope %s", scopeString(scope))
+	}
+	return nil
+}
+
+// checkSilenceIDScope behaves like checkSilenceScope for the existing
+// silence with the given ID.
+func (api *API) checkSilenceIDScope(r *http.Request, sid string) error {
+	if scope, _ := auth.Scope(api.context(r)); len(scope) == 0 {
+		return nil
+	}
+	sils, err := api.silences.Query(silence.QIDs(sid))
+	if err != nil {
+		return err
+	}
+	if len(sils) == 0 {
+		return silence.ErrNotFound
+	}
+	sil, err := silenceFromProto(sils[0])
+	if err != nil {
+		return err
+	}
+	return api.checkSilenceScope(r, sil)
+}
+
+// scopeString formats the matchers of a scope like a label selector.
+func scopeString(scope types.Matchers) string {
+	s := "{"
+	for i, m := range scope {
+		if i > 0 {
+			s += ", "
+		}
+		op := "="
+		if m.IsRegex {
+			op = "=~"
+		}
+		s += fmt.Sprintf("%s%s%q", m.Name, op, m.Value)
+	}
+	return s + "}"
+}
@@ -0,0 +1,167 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/common/route"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+
+	"github.com/prometheus/alertmanager/apikey"
+	"github.com/prometheus/alertmanager/auth"
+	"github.com/prometheus/alertmanager/provider/mem"
+	"github.com/prometheus/alertmanager/silence"
+	"github.com/prometheus/alertmanager/silence/silencepb"
+	"github.com/prometheus/alertmanager/types"
+)
+
+func TestAPIKeys(t *testing.T) {
+	keys, err := apikey.New(apikey.Options{})
+	require.NoError(t, err)
+
+	api := New(nil, nil, nil, nil, nil, nil, nil, keys, nil, nil, nil, nil, nil, nil)
+
+	var ctx context.Context
+	serve := func(h http.HandlerFunc, method, url, body string) *httptest.ResponseRecorder {
+		api.context = func(*http.Request) context.Context { return ctx }
+		rec := httptest.NewRecorder()
+		h(rec, httptest.NewRequest(method, url, strings.NewReader(body)))
+		return rec
+	}
+	var res struct {
+		Data apiKeyToken `json:"data"`
+	}
+
+	ctx = auth.WithUser(context.Background(), "admin")
+	rec := serve(api.withoutAPIKey(api.createAPIKey), "POST", "/apikeys", `{"name":"ci","matchers":[{"name":"team","value":"payments"}]}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&res))
+	require.Equal(t, "admin", res.Data.Key.CreatedBy)
+	require.True(t, res.Data.Key.Active)
+	require.Nil(t, res.Data.Key.LastUsedAt)
+
+	k, err := keys.Authenticate(res.Data.Token)
+	require.NoError(t, err)
+	require.Equal(t, "ci", k.Name)
+
+	rec = serve(api.withoutAPIKey(api.listAPIKeys), "GET", "/apikeys", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NotContains(t, rec.Body.String(), k.Hash)
+	require.Contains(t, rec.Body.String(), `"lastUsedAt"`)
+
+	// Keys cannot manage keys.
+	ctx = auth.WithScope(ctx, nil)
+	rec = serve(api.withoutAPIKey(api.createAPIKey), "POST", "/apikeys", `{"name":"other"}`)
+	require.Equal(t, http.StatusForbidden, rec.Code)
+
+	ctx = route.WithParam(auth.WithUser(context.Background(), "admin"), "id", k.ID)
+	rec = serve(api.rotateAPIKey, "POST", "/apikey/"+k.ID+"/rotate?gracePeriod=x", "")
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	rec = serve(api.rotateAPIKey, "POST", "/apikey/"+k.ID+"/rotate?gracePeriod=1h", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&res))
+	require.NotNil(t, res.Data.Key.RotatedAt)
+
+	_, err = keys.Authenticate(res.Data.Token)
+	require.NoError(t, err)
+
+	rec = serve(api.revokeAPIKey, "DELETE", "/apikey/"+k.ID, "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	rec = serve(api.revokeAPIKey, "DELETE", "/apikey/"+k.ID, "")
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+
+	_, err = keys.Authenticate(res.Data.Token)
+	require.Equal(t, apikey.ErrInvalid, err)
+}
+
+func TestAPIKeyScope(t *testing.T) {
+	alerts, err := mem.NewAlerts("")
+	require.NoError(t, err)
+	defer alerts.Close()
+
+	sils, err := silence.New(silence.Options{})
+	require.NoError(t, err)
+
+	endsAt, err := ptypes.TimestampProto(time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	otherSid, err := sils.Create(&silencepb.Silence{
+		Matchers: []*silencepb.Matcher{{Name: "team", Pattern: "billing"}},
+		EndsAt:   endsAt,
+		Comments: []*silencepb.Comment{{Author: "me", Comment: "deploy"}},
+	})
+	require.NoError(t, err)
+
+	api := New(alerts, sils, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+	require.NoError(t, api.Update(`
+route:
+  receiver: default
+receivers:
+- name: default
+`, time.Minute))
+
+	scope := types.Matchers{types.NewMatcher("team", "payments")}
+	ctx := auth.WithScope(auth.WithUser(context.Background(), "apikey:ci"), scope)
+	serve := func(h http.HandlerFunc, method, url, body string, params ...string) int {
+		api.context = func(*http.Request) context.Context {
+			c := ctx
+			for i := 0; i+1 < len(params); i += 2 {
+				c = route.WithParam(c, params[i], params[i+1])
+			}
+			return c
+		}
+		rec := httptest.NewRecorder()
+		h(rec, httptest.NewRequest(method, url, strings.NewReader(body)))
+		return rec.Code
+	}
+	silenceBody := func(matchers string) string {
+		return fmt.Sprintf(`{"matchers":[%s],"endsAt":%q,"comment":"deploy"}`, matchers, time.Now().Add(time.Hour).Format(time.RFC3339))
+	}
+
+	// Silences must contain the matchers of the scope.
+	require.Equal(t, http.StatusForbidden, serve(api.addSilence, "POST", "/silences", silenceBody(`{"name":"team","value":"billing"}`)))
+	require.Equal(t, http.StatusForbidden, serve(api.addSilence, "POST", "/silences", silenceBody(`{"name":"team","value":"pay.*","isRegex":true}`)))
+	require.Equal(t, http.StatusOK, serve(api.addSilence, "POST", "/silences", silenceBody(`{"name":"team","value":"payments"},{"name":"job","value":"web"}`)))
+
+	psils, err := sils.Query(silence.QMatches(model.LabelSet{"team": "payments", "job": "web"}))
+	require.NoError(t, err)
+	require.Len(t, psils, 1)
+	require.Equal(t, "apikey:ci", psils[0].Comments[0].Author)
+
+	// Silences outside of the scope cannot be changed.
+	require.Equal(t, http.StatusForbidden, serve(api.delSilence, "DELETE", "/silence/"+otherSid, "", "sid", otherSid))
+	require.Equal(t, http.StatusForbidden, serve(api.extendSilence, "POST", "/silence/"+otherSid+"/extend", `{"duration":"1h"}`, "sid", otherSid))
+	require.Equal(t, http.StatusOK, serve(api.delSilence, "DELETE", "/silence/"+psils[0].Id, "", "sid", psils[0].Id))
+
+	// Alerts must match the scope.
+	require.Equal(t, http.StatusForbidden, serve(api.addAlerts, "POST", "/alerts", `[{"labels":{"alertname":"a","team":"billing"}}]`))
+	require.Equal(t, http.StatusOK, serve(api.addAlerts, "POST", "/alerts", `[{"labels":{"alertname":"a","team":"payments"}}]`))
+
+	// Other changes are forbidden.
+	require.Equal(t, http.StatusForbidden, serve(api.unscoped(api.addPause), "POST", "/pauses", `{}`))
+
+	// Keys without matchers are not restricted.
+	ctx = auth.WithScope(auth.WithUser(context.Background(), "apikey:admin"), nil)
+	require.Equal(t, http.StatusOK, serve(api.addAlerts, "POST", "/alerts", `[{"labels":{"alertname":"a","team":"billing"}}]`))
+	require.Equal(t, http.StatusBadRequest, serve(api.unscoped(api.addPause), "POST", "/pauses", `{}`))
+}
@@ -0,0 +1,106 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/common/route"
+
+	"github.com/prometheus/alertmanager/provider"
+)
+
+// assignmentRequest is the body of a request assigning an alert or group.
+// Either the fingerprint of an alert or the labels of an alert or group
+// must be set. An empty assignee unassigns the alerts.
+type assignmentRequest struct {
+	Fingerprint string         `json:"fingerprint,omitempty"`
+	Labels      model.LabelSet `json:"labels,omitempty"`
+	Assignee    string         `json:"assignee"`
+	AssignedBy  string         `json:"assignedBy"`
+}
+
+// labels returns the labels of the alerts the request refers to.
+func (req *assignmentRequest) labels(alerts provider.Alerts) (model.LabelSet, error) {
+	if req.Fingerprint == "" {
+		return req.Labels, nil
+	}
+	if len(req.Labels) > 0 {
+		return nil, errors.New("only one of fingerprint and labels must be set")
+	}
+	fp, err := model.ParseFingerprint(req.Fingerprint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid fingerprint %q: %s", req.Fingerprint, err)
+	}
+	a, err := alerts.Get(fp)
+	if err != nil {
+		return nil, fmt.Errorf("alert %s: %s", fp, err)
+	}
+	return a.Labels, nil
+}
+
+func (api *API) setAssignment(w http.ResponseWriter, r *http.Request) {
+	var req assignmentRequest
+	if err := receive(r, &req); err != nil {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+	api.setUser(r, &req.AssignedBy)
+	lset, err := req.labels(api.alerts)
+	if err != nil {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+	id, err := api.assignments.Set(lset, req.Assignee, req.AssignedBy)
+	if err != nil {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+	respond(w, struct {
+		AssignmentID string `json:"assignmentId"`
+	}{
+		AssignmentID: id,
+	})
+}
+
+func (api *API) listAssignments(w http.ResponseWriter, r *http.Request) {
+	respond(w, api.assignments.List())
+}
+
+// delAssignment unassigns the alerts of an assignment. The user removing the
+// assignment may be given in the "by" query parameter.
+func (api *API) delAssignment(w http.ResponseWriter, r *http.Request) {
+	id := route.Param(api.context(r), "id")
+
+	if err := api.assignments.Unassign(id, r.URL.Query().Get("by")); err != nil {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+	respond(w, nil)
+}
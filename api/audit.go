@@ -0,0 +1,70 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// defaultAuditLimit is the number of audit log entries returned if no limit
+// is given.
+const defaultAuditLimit = 1000
+
+// listAudit returns the entries of the audit log, most recent first. They
+// may be filtered with the following query parameters:
+//
+//	user     authenticated user that made the request
+//	since    RFC3339 timestamp
+//	limit    maximum number of entries to return, 1000 by default
+func (api *API) listAudit(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	var (
+		since time.Time
+		limit = defaultAuditLimit
+		err   error
+	)
+	if s := q.Get("since"); s != "" {
+		if since, err = time.Parse(time.RFC3339, s); err != nil {
+			respondError(w, apiError{
+				typ: errorBadData,
+				err: fmt.Errorf("invalid since %q: %s", s, err),
+			}, nil)
+			return
+		}
+	}
+	if s := q.Get("limit"); s != "" {
+		if limit, err = strconv.Atoi(s); err != nil || limit <= 0 {
+			respondError(w, apiError{
+				typ: errorBadData,
+				err: fmt.Errorf("invalid limit %q", s),
+			}, nil)
+			return
+		}
+	}
+
+	entries, total, err := api.audit.Query(since, q.Get("user"), limit)
+	if err != nil {
+		respondError(w, apiError{
+			typ: errorInternal,
+			err: err,
+		}, nil)
+		return
+	}
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
+	respond(w, entries)
+}
@@ -0,0 +1,110 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/prometheus/common/route"
+
+	"github.com/prometheus/alertmanager/notify"
+)
+
+// captureResult holds whether requests to a receiver are captured and the
+// captured requests.
+type captureResult struct {
+	Receiver string                    `json:"receiver"`
+	Enabled  bool                      `json:"enabled"`
+	Requests []*notify.CapturedRequest `json:"requests"`
+}
+
+// SetCaptures sets the captures of requests to receivers, which are
+// disabled if it is nil.
+func (api *API) SetCaptures(c *notify.Captures) {
+	api.mtx.Lock()
+	defer api.mtx.Unlock()
+
+	api.captures = c
+}
+
+// getCapture returns the captured requests sent to a receiver, most recent
+// first.
+func (api *API) getCapture(w http.ResponseWriter, r *http.Request) {
+	name, c := api.captureReceiver(w, r)
+	if c == nil {
+		return
+	}
+	respond(w, &captureResult{
+		Receiver: name,
+		Enabled:  c.Enabled(name),
+		Requests: c.Requests(name),
+	})
+}
+
+// enableCapture starts capturing the requests sent to a receiver.
+func (api *API) enableCapture(w http.ResponseWriter, r *http.Request) {
+	api.setCaptureEnabled(w, r, true)
+}
+
+// disableCapture stops capturing the requests sent to a receiver. The
+// captured requests are kept.
+func (api *API) disableCapture(w http.ResponseWriter, r *http.Request) {
+	api.setCaptureEnabled(w, r, false)
+}
+
+func (api *API) setCaptureEnabled(w http.ResponseWriter, r *http.Request, enabled bool) {
+	name, c := api.captureReceiver(w, r)
+	if c == nil {
+		return
+	}
+	c.SetEnabled(name, enabled)
+	respond(w, &captureResult{
+		Receiver: name,
+		Enabled:  enabled,
+		Requests: c.Requests(name),
+	})
+}
+
+// captureReceiver returns the receiver of the request and the captures. It
+// responds with an error and returns nil captures if capturing is disabled
+// or the receiver does not exist.
+func (api *API) captureReceiver(w http.ResponseWriter, r *http.Request) (string, *notify.Captures) {
+	name := route.Param(api.context(r), "name")
+
+	api.mtx.RLock()
+	c := api.captures
+	found := false
+	for _, rc := range api.receiverConfs {
+		if rc.Name == name {
+			found = true
+			break
+		}
+	}
+	api.mtx.RUnlock()
+
+	if c == nil {
+		respondError(w, apiError{
+			typ: errorUnavailable,
+			err: errors.New("capturing requests is disabled"),
+		}, nil)
+		return "", nil
+	}
+	if !found {
+		http.Error(w, fmt.Sprintf("receiver %q not found", name), http.StatusNotFound)
+		return "", nil
+	}
+	return name, c
+}
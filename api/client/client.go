@@ -0,0 +1,311 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package client provides a client for the v2 API of the Alertmanager.
+// The client is written by hand after the OpenAPI definition served at
+// /api/v2/openapi.yaml; a test of package api checks that its requests
+// match the definition.
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"golang.org/x/net/context"
+	"golang.org/x/net/context/ctxhttp"
+
+	"github.com/prometheus/alertmanager/dispatch"
+	"github.com/prometheus/alertmanager/snooze"
+	"github.com/prometheus/alertmanager/types"
+)
+
+// Client is a client for the v2 API of an Alertmanager.
+type Client struct {
+	url  *url.URL
+	http *http.Client
+}
+
+// New returns a client for the Alertmanager at the given address, e.g.
+// "http://localhost:9093". If c is nil, http.DefaultClient is used.
+func New(address string, c *http.Client) (*Client, error) {
+	u, err := url.Parse(address)
+	if err != nil {
+		return nil, err
+	}
+	if c == nil {
+		c = http.DefaultClient
+	}
+	u.Path = strings.TrimRight(u.Path, "/") + "/api/v2"
+
+	return &Client{url: u, http: c}, nil
+}
+
+// Error is returned for requests the Alertmanager did not answer
+// successfully.
+type Error struct {
+	StatusCode int             `json:"-"`
+	Type       string          `json:"errorType"`
+	Message    string          `json:"error"`
+	Data       json.RawMessage `json:"data"`
+}
+
+func (e *Error) Error() string {
+	if e.Type == "" {
+		return fmt.Sprintf("%d: %s", e.StatusCode, e.Message)
+	}
+	return fmt.Sprintf("%d %s: %s", e.StatusCode, e.Type, e.Message)
+}
+
+// Status is the status of an Alertmanager.
+type Status struct {
+	Config            string                `json:"config"`
+	ConfigJSON        json.RawMessage       `json:"configJSON"`
+	ConfigHash        string                `json:"configHash"`
+	ConfigLoadedAt    time.Time             `json:"configLoadedAt"`
+	VersionInfo       map[string]string     `json:"versionInfo"`
+	Uptime            time.Time             `json:"uptime"`
+	Peers             []Peer                `json:"peers"`
+	Alerts            map[string]int        `json:"alerts"`
+	Silences          map[string]int        `json:"silences"`
+	LastNotifications map[string]*time.Time `json:"lastNotifications"`
+}
+
+// Peer is a peer of the Alertmanager mesh.
+type Peer struct {
+	Name     string `json:"name"`
+	NickName string `json:"nickName"`
+	Address  string `json:"address,omitempty"`
+	Self     bool   `json:"self"`
+	Healthy  bool   `json:"healthy"`
+}
+
+// Receiver is a configured receiver.
+type Receiver struct {
+	Name         string        `json:"name"`
+	DryRun       bool          `json:"dryRun,omitempty"`
+	Integrations []Integration `json:"integrations"`
+}
+
+// Integration is an integration of a receiver through which notifications
+// are sent. Secrets in its settings are hidden.
+type Integration struct {
+	Type         string                 `json:"type"`
+	Index        int                    `json:"index"`
+	SendResolved bool                   `json:"sendResolved"`
+	Settings     map[string]interface{} `json:"settings"`
+}
+
+// TestResult is the outcome of a test notification sent via a receiver.
+type TestResult struct {
+	Receiver     string              `json:"receiver"`
+	Alert        *model.Alert        `json:"alert"`
+	Integrations []IntegrationResult `json:"integrations"`
+}
+
+// IntegrationResult is the outcome of a test notification sent via a single
+// integration.
+type IntegrationResult struct {
+	Type    string `json:"type"`
+	Index   int    `json:"index"`
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Status returns the status of the Alertmanager.
+func (c *Client) Status(ctx context.Context) (*Status, error) {
+	var s Status
+	if err := c.do(ctx, "GET", "/status", nil, nil, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// Receivers returns the configured receivers and their integrations.
+func (c *Client) Receivers(ctx context.Context) ([]Receiver, error) {
+	var rs []Receiver
+	err := c.do(ctx, "GET", "/receivers", nil, nil, &rs)
+	return rs, err
+}
+
+// TestReceiver sends a test notification via all integrations of the named
+// receiver. The labels and annotations, which may be nil, are added to those
+// of the synthetic alert. A zero timeout uses the server's default.
+func (c *Client) TestReceiver(ctx context.Context, name string, labels, annotations model.LabelSet, timeout time.Duration) (*TestResult, error) {
+	var q url.Values
+	if timeout > 0 {
+		q = url.Values{"timeout": {timeout.String()}}
+	}
+	in := struct {
+		Labels      model.LabelSet `json:"labels,omitempty"`
+		Annotations model.LabelSet `json:"annotations,omitempty"`
+	}{labels, annotations}
+
+	var res TestResult
+	if err := c.do(ctx, "POST", "/receivers/"+url.PathEscape(name)+"/test", q, in, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// Alerts returns the alerts matching the given filter parameters, e.g.
+// url.Values{"matcher": {"job=foo"}, "limit": {"10"}}. The filter may be nil.
+func (c *Client) Alerts(ctx context.Context, filter url.Values) ([]*dispatch.APIAlert, error) {
+	var as []*dispatch.APIAlert
+	err := c.do(ctx, "GET", "/alerts", filter, nil, &as)
+	return as, err
+}
+
+// PostAlerts creates or updates the given alerts.
+func (c *Client) PostAlerts(ctx context.Context, alerts ...*model.Alert) error {
+	return c.do(ctx, "POST", "/alerts", nil, alerts, nil)
+}
+
+// AlertGroups returns the aggregation groups of alerts. The filter may
+// hold the "assignee" and "sort" parameters, or be nil.
+func (c *Client) AlertGroups(ctx context.Context, filter url.Values) ([]*dispatch.AlertGroup, error) {
+	var gs []*dispatch.AlertGroup
+	err := c.do(ctx, "GET", "/alerts/groups", filter, nil, &gs)
+	return gs, err
+}
+
+// Snooze suppresses notifications for the alert with the given fingerprint
+// until endsAt, or if it is zero, for the duration d.
+func (c *Client) Snooze(ctx context.Context, fp model.Fingerprint, endsAt time.Time, d time.Duration, comment string) (*snooze.Snooze, error) {
+	req := struct {
+		Fingerprint string     `json:"fingerprint"`
+		EndsAt      *time.Time `json:"endsAt,omitempty"`
+		Duration    string     `json:"duration,omitempty"`
+		Comment     string     `json:"comment,omitempty"`
+	}{Fingerprint: fp.String(), Comment: comment}
+	if endsAt.IsZero() {
+		req.Duration = d.String()
+	} else {
+		req.EndsAt = &endsAt
+	}
+	var sn snooze.Snooze
+	if err := c.do(ctx, "POST", "/snoozes", nil, req, &sn); err != nil {
+		return nil, err
+	}
+	return &sn, nil
+}
+
+// Silences returns the silences matching the given filter parameters,
+// e.g. url.Values{"state": {"active"}}. The filter may be nil.
+func (c *Client) Silences(ctx context.Context, filter url.Values) ([]*types.Silence, error) {
+	var ss []*types.Silence
+	err := c.do(ctx, "GET", "/silences", filter, nil, &ss)
+	return ss, err
+}
+
+// Silence returns the silence with the given ID.
+func (c *Client) Silence(ctx context.Context, id string) (*types.Silence, error) {
+	var s types.Silence
+	if err := c.do(ctx, "GET", "/silence/"+url.PathEscape(id), nil, nil, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// SetSilence creates the given silence, or updates it if its ID is set,
+// and returns its ID. Unless override is set, the request fails with a
+// conflict if existing silences already mute all alerts the silence would.
+func (c *Client) SetSilence(ctx context.Context, s *types.Silence, override bool) (string, error) {
+	var q url.Values
+	if override {
+		q = url.Values{"override": {"true"}}
+	}
+	var res struct {
+		SilenceID string `json:"silenceId"`
+	}
+	err := c.do(ctx, "POST", "/silences", q, s, &res)
+	return res.SilenceID, err
+}
+
+// ExpireSilence expires the silence with the given ID.
+func (c *Client) ExpireSilence(ctx context.Context, id string) error {
+	return c.do(ctx, "DELETE", "/silence/"+url.PathEscape(id), nil, nil, nil)
+}
+
+// ExtendSilence moves the end of the silence with the given ID to endsAt,
+// or if it is zero, by the duration d, and returns the extended silence.
+func (c *Client) ExtendSilence(ctx context.Context, id string, endsAt time.Time, d time.Duration, comment string) (*types.Silence, error) {
+	req := struct {
+		EndsAt   *time.Time `json:"endsAt,omitempty"`
+		Duration string     `json:"duration,omitempty"`
+		Comment  string     `json:"comment,omitempty"`
+	}{Comment: comment}
+	if endsAt.IsZero() {
+		req.Duration = d.String()
+	} else {
+		req.EndsAt = &endsAt
+	}
+	var s types.Silence
+	if err := c.do(ctx, "POST", "/silence/"+url.PathEscape(id)+"/extend", nil, req, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// do sends a request with the JSON encoding of in as its body and decodes
+// the response into out. Both in and out may be nil.
+func (c *Client) do(ctx context.Context, method, path string, q url.Values, in, out interface{}) error {
+	u := *c.url
+	u.Path += path
+	u.RawQuery = q.Encode()
+
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(b)
+	}
+	req, err := http.NewRequest(method, u.String(), body)
+	if err != nil {
+		return err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := ctxhttp.Do(ctx, c.http, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode/100 != 2 {
+		e := &Error{StatusCode: resp.StatusCode}
+		if err := json.Unmarshal(b, e); err != nil {
+			e.Message = strings.TrimSpace(string(b))
+		}
+		return e
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(b, out)
+}
@@ -0,0 +1,91 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+
+	"github.com/prometheus/alertmanager/types"
+)
+
+func TestClient(t *testing.T) {
+	var (
+		method, path, query string
+		body                []byte
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method, path, query = r.Method, r.URL.Path, r.URL.RawQuery
+
+		switch path {
+		case "/prefix/api/v2/silence/foo":
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":"silence not found"}`))
+		case "/prefix/api/v2/silences":
+			if r.Method == "POST" {
+				var s types.Silence
+				require.NoError(t, json.NewDecoder(r.Body).Decode(&s))
+				body, _ = json.Marshal(&s)
+				w.Write([]byte(`{"silenceId":"bar"}`))
+				return
+			}
+			w.Write([]byte(`[{"id":"bar","createdBy":"me"}]`))
+		case "/prefix/api/v2/receivers":
+			w.Write([]byte(`[{"name":"team-X"}]`))
+		case "/prefix/api/v2/receivers/team-X/test":
+			w.Write([]byte(`{"receiver":"team-X","integrations":[{"type":"email","index":0,"success":false,"error":"timeout"}]}`))
+		}
+	}))
+	defer srv.Close()
+
+	c, err := New(srv.URL+"/prefix/", nil)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	rs, err := c.Receivers(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []Receiver{{Name: "team-X"}}, rs)
+
+	tr, err := c.TestReceiver(ctx, "team-X", nil, nil, 10*time.Second)
+	require.NoError(t, err)
+	require.Equal(t, "POST", method)
+	require.Equal(t, "timeout=10s", query)
+	require.Equal(t, []IntegrationResult{{Type: "email", Error: "timeout"}}, tr.Integrations)
+
+	ss, err := c.Silences(ctx, url.Values{"state": {"active"}})
+	require.NoError(t, err)
+	require.Equal(t, "GET", method)
+	require.Equal(t, "state=active", query)
+	require.Len(t, ss, 1)
+	require.Equal(t, "bar", ss[0].ID)
+	require.Equal(t, "me", ss[0].CreatedBy)
+
+	id, err := c.SetSilence(ctx, &types.Silence{CreatedBy: "me"}, true)
+	require.NoError(t, err)
+	require.Equal(t, "bar", id)
+	require.Equal(t, "POST", method)
+	require.Equal(t, "override=true", query)
+	require.Contains(t, string(body), `"createdBy":"me"`)
+
+	_, err = c.Silence(ctx, "foo")
+	require.Equal(t, &Error{StatusCode: http.StatusNotFound, Message: "silence not found"}, err)
+	require.Equal(t, "/prefix/api/v2/silence/foo", path)
+}
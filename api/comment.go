@@ -0,0 +1,120 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/prometheus/common/model"
+
+	"github.com/prometheus/alertmanager/provider"
+	"github.com/prometheus/alertmanager/types"
+)
+
+// commentRequest is the body of a request adding a comment to an alert or
+// group. Either the fingerprint of an alert or the labels of an alert or
+// group must be set.
+type commentRequest struct {
+	Fingerprint string         `json:"fingerprint,omitempty"`
+	Labels      model.LabelSet `json:"labels,omitempty"`
+	Author      string         `json:"author"`
+	Comment     string         `json:"comment"`
+}
+
+// comment returns the comment described by the request.
+func (req *commentRequest) comment(alerts provider.Alerts) (*types.Comment, error) {
+	c := &types.Comment{
+		Labels:  req.Labels,
+		Author:  req.Author,
+		Comment: req.Comment,
+	}
+	if req.Fingerprint == "" {
+		return c, nil
+	}
+	if len(req.Labels) > 0 {
+		return nil, errors.New("only one of fingerprint and labels must be set")
+	}
+	fp, err := model.ParseFingerprint(req.Fingerprint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid fingerprint %q: %s", req.Fingerprint, err)
+	}
+	a, err := alerts.Get(fp)
+	if err != nil {
+		return nil, fmt.Errorf("alert %s: %s", fp, err)
+	}
+	c.Labels = a.Labels
+	return c, nil
+}
+
+func (api *API) addComment(w http.ResponseWriter, r *http.Request) {
+	var req commentRequest
+	if err := receive(r, &req); err != nil {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+	api.setUser(r, &req.Author)
+	c, err := req.comment(api.alerts)
+	if err != nil {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+	id, err := api.comments.Add(c)
+	if err != nil {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+	respond(w, struct {
+		CommentID string `json:"commentId"`
+	}{
+		CommentID: id,
+	})
+}
+
+// listComments returns all comments or, if the fingerprint of an alert is
+// given, the comments about the alert.
+func (api *API) listComments(w http.ResponseWriter, r *http.Request) {
+	s := r.URL.Query().Get("fingerprint")
+	if s == "" {
+		respond(w, api.comments.List())
+		return
+	}
+	fp, err := model.ParseFingerprint(s)
+	if err != nil {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: fmt.Errorf("invalid fingerprint %q: %s", s, err),
+		}, nil)
+		return
+	}
+	a, err := api.alerts.Get(fp)
+	if err != nil {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: fmt.Errorf("alert %s: %s", fp, err),
+		}, nil)
+		return
+	}
+	respond(w, api.comments.Query(a.Labels, a.StartsAt))
+}
@@ -0,0 +1,70 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var corsHeaders = map[string]string{
+	"Access-Control-Allow-Headers":  "Accept, Authorization, Content-Type, Origin",
+	"Access-Control-Allow-Methods":  "GET, OPTIONS",
+	"Access-Control-Allow-Origin":   "*",
+	"Access-Control-Expose-Headers": "Date",
+}
+
+// setCORS enables cross-site script calls. Without a CORS configuration,
+// all origins may read from the API.
+func (api *API) setCORS(w http.ResponseWriter, r *http.Request) {
+	api.mtx.RLock()
+	c := api.configJSON.CORS
+	api.mtx.RUnlock()
+
+	if c == nil {
+		for h, v := range corsHeaders {
+			w.Header().Set(h, v)
+		}
+		return
+	}
+	w.Header().Add("Vary", "Origin")
+
+	origin := r.Header.Get("Origin")
+	allowed := ""
+	for _, o := range c.AllowedOrigins {
+		if o == "*" {
+			allowed = "*"
+			break
+		}
+		if strings.TrimSuffix(o, "/") == origin {
+			allowed = origin
+			break
+		}
+	}
+	if origin == "" || allowed == "" {
+		return
+	}
+	w.Header().Set("Access-Control-Allow-Origin", allowed)
+	w.Header().Set("Access-Control-Allow-Methods", strings.Join(c.AllowedMethods, ", "))
+	w.Header().Set("Access-Control-Allow-Headers", strings.Join(c.AllowedHeaders, ", "))
+	w.Header().Set("Access-Control-Expose-Headers", corsHeaders["Access-Control-Expose-Headers"])
+	if c.AllowCredentials {
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+	}
+	if c.MaxAge > 0 {
+		w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(time.Duration(c.MaxAge).Seconds())))
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetCORS(t *testing.T) {
+	api := New(nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+
+	cors := func(origin string) http.Header {
+		req := httptest.NewRequest("OPTIONS", "/api/v1/silences", nil)
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		rec := httptest.NewRecorder()
+		api.setCORS(rec, req)
+		return rec.Header()
+	}
+
+	// Without configuration, all origins may read.
+	h := cors("https://dash.example.com")
+	require.Equal(t, "*", h.Get("Access-Control-Allow-Origin"))
+	require.Equal(t, "GET, OPTIONS", h.Get("Access-Control-Allow-Methods"))
+
+	require.NoError(t, api.Update(`
+route:
+  receiver: default
+receivers:
+- name: default
+cors:
+  allowed_origins:
+  - https://dash.example.com
+  allowed_headers: [Content-Type]
+  allow_credentials: true
+  max_age: 10m
+`, time.Minute))
+
+	h = cors("https://dash.example.com")
+	require.Equal(t, "https://dash.example.com", h.Get("Access-Control-Allow-Origin"))
+	require.Equal(t, "GET, POST, DELETE, OPTIONS", h.Get("Access-Control-Allow-Methods"))
+	require.Equal(t, "Content-Type", h.Get("Access-Control-Allow-Headers"))
+	require.Equal(t, "true", h.Get("Access-Control-Allow-Credentials"))
+	require.Equal(t, "600", h.Get("Access-Control-Max-Age"))
+	require.Equal(t, "Origin", h.Get("Vary"))
+
+	for _, origin := range []string{"", "https://evil.example.com", "http://dash.example.com"} {
+		h = cors(origin)
+		require.Equal(t, "", h.Get("Access-Control-Allow-Origin"), origin)
+		require.Equal(t, "", h.Get("Access-Control-Allow-Methods"), origin)
+	}
+
+	require.NoError(t, api.Update(`
+route:
+  receiver: default
+receivers:
+- name: default
+cors:
+  allowed_origins: ['*']
+`, time.Minute))
+	require.Equal(t, "*", cors("https://other.example.com").Get("Access-Control-Allow-Origin"))
+}
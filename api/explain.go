@@ -0,0 +1,197 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/common/route"
+
+	"github.com/prometheus/alertmanager/ack"
+	"github.com/prometheus/alertmanager/dispatch"
+	"github.com/prometheus/alertmanager/history"
+	"github.com/prometheus/alertmanager/inhibit"
+	"github.com/prometheus/alertmanager/pause"
+	"github.com/prometheus/alertmanager/silence"
+	"github.com/prometheus/alertmanager/snooze"
+	"github.com/prometheus/alertmanager/types"
+)
+
+// alertExplanation describes how an alert passes through the notification
+// pipeline.
+type alertExplanation struct {
+	Fingerprint string            `json:"fingerprint"`
+	Labels      model.LabelSet    `json:"labels"`
+	Status      model.AlertStatus `json:"status"`
+	State       alertState        `json:"state"`
+	// Routes are the routes the alert matches, each notifying a receiver.
+	Routes []*explainedRoute `json:"routes"`
+	// Silences are the active silences matching the alert.
+	Silences    []*types.Silence      `json:"silences"`
+	Inhibitions []*inhibit.Inhibition `json:"inhibitions"`
+	Ack         *ack.Ack              `json:"ack,omitempty"`
+	Snooze      *snooze.Snooze        `json:"snooze,omitempty"`
+}
+
+// explainedRoute is a route matched by an alert.
+type explainedRoute struct {
+	Receiver string            `json:"receiver"`
+	GroupBy  []model.LabelName `json:"groupBy"`
+	// Path holds the routes from the root of the routing tree to the
+	// matched route.
+	Path []*routeStep `json:"path"`
+	// Group is the aggregation group the alert is in. It is nil if the
+	// alert was not processed by the dispatcher yet.
+	Group *explainedGroup `json:"group,omitempty"`
+	// Pause is the pause of the group's notifications, if any.
+	Pause *pause.Pause `json:"pause,omitempty"`
+	// LastNotification is the most recent notification of the receiver
+	// including the alert.
+	LastNotification *history.Entry `json:"lastNotification,omitempty"`
+}
+
+type routeStep struct {
+	Receiver string         `json:"receiver"`
+	Matchers types.Matchers `json:"matchers"`
+	Continue bool           `json:"continue"`
+}
+
+type explainedGroup struct {
+	Labels   model.LabelSet `json:"labels"`
+	GroupKey uint64         `json:"groupKey"`
+}
+
+// explainAlert returns how the alert with the given fingerprint passes
+// through the notification pipeline: the routes it matches, the groups it
+// is in, the silences and inhibitions affecting it and the last
+// notification of each receiver.
+func (api *API) explainAlert(w http.ResponseWriter, r *http.Request) {
+	s := route.Param(api.context(r), "fingerprint")
+	fp, err := model.ParseFingerprint(s)
+	if err != nil {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: fmt.Errorf("invalid fingerprint %q: %s", s, err),
+		}, nil)
+		return
+	}
+	a, err := api.alerts.Get(fp)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("alert %s: %s", fp, err), http.StatusNotFound)
+		return
+	}
+
+	sils, err := api.silences.Query(
+		silence.QState(silence.StateActive),
+		silence.QMatchesAlert(a.Labels, a.Annotations),
+	)
+	if err != nil {
+		respondError(w, apiError{
+			typ: errorInternal,
+			err: err,
+		}, nil)
+		return
+	}
+
+	api.mtx.RLock()
+	rt := api.route
+	api.mtx.RUnlock()
+
+	groups := api.groups()
+	state, ok := alertStates(groups)[fp]
+	if !ok {
+		state = alertStateUnprocessed
+	}
+
+	res := &alertExplanation{
+		Fingerprint: fp.String(),
+		Labels:      a.Labels,
+		Status:      a.Status(),
+		State:       state,
+		Routes:      []*explainedRoute{},
+		Silences:    make([]*types.Silence, 0, len(sils)),
+		Inhibitions: api.inhibitions(a.Labels),
+		Ack:         api.acks.Acked(a.Labels, a.StartsAt),
+		Snooze:      api.snoozes.Snoozed(fp),
+	}
+	for _, ps := range sils {
+		sil, err := silenceFromProto(ps)
+		if err != nil {
+			respondError(w, apiError{
+				typ: errorInternal,
+				err: err,
+			}, nil)
+			return
+		}
+		res.Silences = append(res.Silences, sil)
+	}
+	if rt != nil {
+		for _, mr := range rt.Match(a.Labels) {
+			res.Routes = append(res.Routes, api.explainRoute(mr, fp, groups))
+		}
+	}
+
+	respond(w, res)
+}
+
+// explainRoute describes the route matched by the alert with the given
+// fingerprint.
+func (api *API) explainRoute(r *dispatch.Route, fp model.Fingerprint, groups dispatch.AlertOverview) *explainedRoute {
+	er := &explainedRoute{
+		Receiver: r.RouteOpts.Receiver,
+		GroupBy:  make([]model.LabelName, 0, len(r.RouteOpts.GroupBy)),
+	}
+	for ln := range r.RouteOpts.GroupBy {
+		er.GroupBy = append(er.GroupBy, ln)
+	}
+	sort.Slice(er.GroupBy, func(i, j int) bool { return er.GroupBy[i] < er.GroupBy[j] })
+
+	for _, pr := range r.Path() {
+		er.Path = append(er.Path, &routeStep{
+			Receiver: pr.RouteOpts.Receiver,
+			Matchers: pr.Matchers,
+			Continue: pr.Continue,
+		})
+	}
+
+	// The dispatcher has its own routing tree, so routes are identified by
+	// their receiver and grouping.
+outer:
+	for _, g := range groups {
+		for _, b := range g.Blocks {
+			if b.RouteOpts.Receiver != er.Receiver || !reflect.DeepEqual(b.RouteOpts.GroupBy, r.RouteOpts.GroupBy) {
+				continue
+			}
+			for _, ba := range b.Alerts {
+				if ba.Fingerprint() == fp {
+					er.Group = &explainedGroup{Labels: g.Labels, GroupKey: g.GroupKey}
+					er.Pause = api.pauses.Paused(er.Receiver, g.GroupKey)
+					break outer
+				}
+			}
+		}
+	}
+
+	if entries := api.history.Query(
+		history.QReceiver(er.Receiver),
+		history.QFingerprint(fp),
+	); len(entries) > 0 {
+		er.LastNotification = entries[0]
+	}
+	return er
+}
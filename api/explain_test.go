@@ -0,0 +1,133 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/common/route"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+
+	"github.com/prometheus/alertmanager/dispatch"
+	"github.com/prometheus/alertmanager/history"
+	"github.com/prometheus/alertmanager/inhibit"
+	"github.com/prometheus/alertmanager/provider/mem"
+	"github.com/prometheus/alertmanager/silence"
+	"github.com/prometheus/alertmanager/silence/silencepb"
+	"github.com/prometheus/alertmanager/types"
+)
+
+func TestExplainAlert(t *testing.T) {
+	alerts, err := mem.NewAlerts("")
+	require.NoError(t, err)
+	defer alerts.Close()
+
+	sils, err := silence.New(silence.Options{})
+	require.NoError(t, err)
+	endsAt, err := ptypes.TimestampProto(time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	sid, err := sils.Create(&silencepb.Silence{
+		Matchers: []*silencepb.Matcher{{Name: "job", Pattern: "web"}},
+		EndsAt:   endsAt,
+	})
+	require.NoError(t, err)
+
+	hist, err := history.New(history.Options{})
+	require.NoError(t, err)
+
+	a := &types.Alert{Alert: model.Alert{
+		Labels:   model.LabelSet{"alertname": "HighLatency", "job": "web", "team": "frontend"},
+		StartsAt: time.Now(),
+	}, UpdatedAt: time.Now()}
+	require.NoError(t, alerts.Put(a))
+	fp := a.Fingerprint()
+
+	hist.Record("frontend", "slack", 42, []model.Fingerprint{fp}, errors.New("unexpected status code 500"), "status_5xx")
+
+	api := New(alerts, sils, nil, nil, nil, nil, nil, nil, nil, hist, nil, func() dispatch.AlertOverview {
+		return dispatch.AlertOverview{{
+			Labels:   model.LabelSet{"alertname": "HighLatency"},
+			GroupKey: 42,
+			Blocks: []*dispatch.AlertBlock{{
+				RouteOpts: &dispatch.RouteOpts{
+					Receiver: "frontend",
+					GroupBy:  map[model.LabelName]struct{}{"alertname": {}},
+				},
+				Alerts: []*dispatch.APIAlert{{Alert: &a.Alert, Silenced: sid}},
+			}},
+		}}
+	}, func(model.LabelSet) []*inhibit.Inhibition { return nil }, nil)
+	require.NoError(t, api.Update(`
+route:
+  receiver: default
+  group_by: [alertname]
+  routes:
+  - match:
+      team: frontend
+    receiver: frontend
+receivers:
+- name: default
+- name: frontend
+`, time.Minute))
+
+	var param string
+	api.context = func(r *http.Request) context.Context {
+		return route.WithParam(context.Background(), "fingerprint", param)
+	}
+
+	param = fp.String()
+	rec := httptest.NewRecorder()
+	api.explainAlert(rec, httptest.NewRequest("GET", "/alert/"+param+"/explain", nil))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var res struct {
+		Data *alertExplanation `json:"data"`
+	}
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&res))
+
+	ex := res.Data
+	require.Equal(t, alertStateSuppressed, ex.State)
+	require.Equal(t, model.AlertFiring, ex.Status)
+	require.Len(t, ex.Silences, 1)
+	require.Equal(t, sid, ex.Silences[0].ID)
+
+	require.Len(t, ex.Routes, 1)
+	er := ex.Routes[0]
+	require.Equal(t, "frontend", er.Receiver)
+	require.Equal(t, []model.LabelName{"alertname"}, er.GroupBy)
+	require.Len(t, er.Path, 2)
+	require.Equal(t, "default", er.Path[0].Receiver)
+	require.Equal(t, "team", er.Path[1].Matchers[0].Name)
+	require.Equal(t, uint64(42), er.Group.GroupKey)
+	require.Equal(t, "slack", er.LastNotification.Integration)
+	require.False(t, er.LastNotification.Success())
+
+	param = "0000000000000001"
+	rec = httptest.NewRecorder()
+	api.explainAlert(rec, httptest.NewRequest("GET", "/alert/"+param+"/explain", nil))
+	require.Equal(t, http.StatusNotFound, rec.Code)
+
+	param = "nope"
+	rec = httptest.NewRecorder()
+	api.explainAlert(rec, httptest.NewRequest("GET", "/alert/"+param+"/explain", nil))
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+}
@@ -0,0 +1,401 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/common/route"
+	"github.com/prometheus/common/version"
+
+	"github.com/prometheus/alertmanager/dispatch"
+	"github.com/prometheus/alertmanager/graphql"
+	"github.com/prometheus/alertmanager/silence"
+	"github.com/prometheus/alertmanager/types"
+)
+
+// RegisterGraphQL registers the GraphQL endpoint under /graphql in the
+// given router. Queries are answered over alerts, silences, receivers and
+// the status of the Alertmanager:
+//
+//	type Query {
+//	  alerts(matcher: [String], state: [String], receiver: [String],
+//	         assignee: String, startsAfter: String, startsBefore: String,
+//	         endsAfter: String, endsBefore: String, sort: String,
+//	         offset: Int, limit: Int): [Alert]
+//	  silences(state: [String], matcher: [String], createdBy: String,
+//	           createdAfter: String, createdBefore: String, endsAfter: String,
+//	           endsBefore: String, offset: Int, limit: Int): [Silence]
+//	  silence(id: String!): Silence
+//	  receivers: [Receiver]
+//	  status: Status
+//	}
+//	type Alert {
+//	  fingerprint: String, labels: LabelSet, annotations: LabelSet,
+//	  startsAt: Time, endsAt: Time, generatorURL: String, state: String,
+//	  inhibited: Boolean, silence: Silence, receivers: [Receiver],
+//	  assignedTo: String
+//	}
+//	type Silence {
+//	  id: String, matchers: [Matcher], startsAt: Time, endsAt: Time,
+//	  updatedAt: Time, createdBy: String, comment: String, state: String,
+//	  mutedAlerts: Int
+//	}
+//	type Matcher { name: String, value: String, isRegex: Boolean, isAnnotation: Boolean }
+//	type Receiver { name: String, integrations: [Integration] }
+//	type Integration { type: String, index: Int, sendResolved: Boolean, settings: Map }
+//	type Status {
+//	  config: String, configHash: String, configLoadedAt: Time,
+//	  versionInfo: Map, uptime: Time
+//	}
+//
+// The arguments of alerts and silences are the query parameters of the
+// respective v1 endpoints.
+func (api *API) RegisterGraphQL(r *route.Router) {
+	h := prometheus.InstrumentHandlerFunc("graphql", func(w http.ResponseWriter, r *http.Request) {
+		api.setCORS(w, r)
+		api.serveGraphQL(w, r)
+	})
+	r.Get("/graphql", h)
+	r.Post("/graphql", h)
+}
+
+// maxGraphQLRequestSize is the maximum size in bytes of GraphQL request
+// bodies and query strings.
+const maxGraphQLRequestSize = 1 << 20
+
+func (api *API) serveGraphQL(w http.ResponseWriter, r *http.Request) {
+	var req graphql.Request
+	if r.Method == "POST" {
+		r.Body = http.MaxBytesReader(w, r.Body, maxGraphQLRequestSize)
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, fmt.Sprintf("invalid request: %s", err), http.StatusBadRequest)
+			return
+		}
+	} else {
+		if len(r.URL.RawQuery) > maxGraphQLRequestSize {
+			http.Error(w, fmt.Sprintf("request exceeds the limit of %d bytes", maxGraphQLRequestSize), http.StatusRequestEntityTooLarge)
+			return
+		}
+		q := r.URL.Query()
+		req.Query = q.Get("query")
+		req.OperationName = q.Get("operationName")
+		if s := q.Get("variables"); s != "" {
+			if err := json.Unmarshal([]byte(s), &req.Variables); err != nil {
+				http.Error(w, fmt.Sprintf("invalid variables: %s", err), http.StatusBadRequest)
+				return
+			}
+		}
+	}
+
+	resp := graphql.Execute(api.graphQLRoot(), &req)
+
+	w.Header().Set("Content-Type", "application/json")
+	if resp.Data == nil {
+		w.WriteHeader(http.StatusBadRequest)
+	}
+	b, err := json.Marshal(resp)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Write(b)
+}
+
+// graphQLQuery converts the given field arguments into query parameters as
+// understood by the filters of the REST API.
+func graphQLQuery(args graphql.Args, names ...string) (url.Values, error) {
+	q := url.Values{}
+	for _, n := range names {
+		switch args[n].(type) {
+		case nil:
+		case int, float64:
+			v, err := args.Int(n)
+			if err != nil {
+				return nil, err
+			}
+			q.Set(n, strconv.Itoa(v))
+		default:
+			vs, err := args.Strings(n)
+			if err != nil {
+				return nil, err
+			}
+			q[n] = vs
+		}
+	}
+	for n := range args {
+		if _, ok := q[n]; !ok && args[n] != nil {
+			return nil, fmt.Errorf("unknown argument %q", n)
+		}
+	}
+	return q, nil
+}
+
+// graphQLRoot returns the root query object. The state of alerts in the
+// dispatcher is retrieved at most once per query.
+func (api *API) graphQLRoot() *graphql.Object {
+	api.mtx.RLock()
+	route, sev := api.route, api.severities
+	api.mtx.RUnlock()
+
+	var grouped map[model.Fingerprint]*dispatch.APIAlert
+	groupedAlert := func(fp model.Fingerprint) *dispatch.APIAlert {
+		if grouped == nil {
+			grouped = map[model.Fingerprint]*dispatch.APIAlert{}
+			for _, g := range api.groups() {
+				for _, b := range g.Blocks {
+					for _, a := range b.Alerts {
+						grouped[a.Fingerprint()] = a
+					}
+				}
+			}
+		}
+		return grouped[fp]
+	}
+
+	alertObject := func(a *types.Alert) *graphql.Object {
+		return &graphql.Object{Type: "Alert", Fields: map[string]graphql.FieldFunc{
+			"fingerprint":  constant(a.Fingerprint().String()),
+			"labels":       constant(a.Labels),
+			"annotations":  constant(a.Annotations),
+			"startsAt":     constant(a.StartsAt),
+			"endsAt":       constantTime(a.EndsAt),
+			"generatorURL": constant(a.GeneratorURL),
+			"state": func(graphql.Args) (interface{}, error) {
+				ga := groupedAlert(a.Fingerprint())
+				switch {
+				case ga == nil:
+					return alertStateUnprocessed, nil
+				case ga.Inhibited || ga.Silenced != "":
+					return alertStateSuppressed, nil
+				}
+				return alertStateActive, nil
+			},
+			"inhibited": func(graphql.Args) (interface{}, error) {
+				ga := groupedAlert(a.Fingerprint())
+				return ga != nil && ga.Inhibited, nil
+			},
+			"silence": func(graphql.Args) (interface{}, error) {
+				ga := groupedAlert(a.Fingerprint())
+				if ga == nil || ga.Silenced == "" {
+					return nil, nil
+				}
+				return api.graphQLSilence(ga.Silenced)
+			},
+			"receivers": func(graphql.Args) (interface{}, error) {
+				var res []*graphql.Object
+				if route == nil {
+					return res, nil
+				}
+				seen := map[string]bool{}
+				for _, r := range route.Match(a.Labels) {
+					if name := r.RouteOpts.Receiver; !seen[name] {
+						seen[name] = true
+						res = append(res, api.graphQLReceiver(name))
+					}
+				}
+				return res, nil
+			},
+			"assignedTo": func(graphql.Args) (interface{}, error) {
+				if s := api.assignments.Assignee(a.Labels, a.StartsAt); s != "" {
+					return s, nil
+				}
+				return nil, nil
+			},
+		}}
+	}
+
+	return &graphql.Object{Type: "Query", Fields: map[string]graphql.FieldFunc{
+		"alerts": func(args graphql.Args) (interface{}, error) {
+			q, err := graphQLQuery(args, "matcher", "state", "receiver", "assignee",
+				"startsAfter", "startsBefore", "endsAfter", "endsBefore", "sort", "offset", "limit")
+			if err != nil {
+				return nil, err
+			}
+			f, err := parseAlertFilter(q)
+			if err != nil {
+				return nil, err
+			}
+			alerts, err := api.queryAlerts(f)
+			if err != nil {
+				return nil, err
+			}
+			var res []*graphql.Object
+			for _, a := range f.paginate(alerts, sev) {
+				res = append(res, alertObject(a))
+			}
+			return res, nil
+		},
+		"silences": func(args graphql.Args) (interface{}, error) {
+			q, err := graphQLQuery(args, "state", "matcher", "createdBy",
+				"createdAfter", "createdBefore", "endsAfter", "endsBefore", "offset", "limit")
+			if err != nil {
+				return nil, err
+			}
+			f, err := parseSilenceFilter(q)
+			if err != nil {
+				return nil, err
+			}
+			sils, err := api.querySilences(f)
+			if err != nil {
+				return nil, err
+			}
+			var res []*graphql.Object
+			for _, s := range f.paginate(sils) {
+				res = append(res, silenceObject(s))
+			}
+			return res, nil
+		},
+		"silence": func(args graphql.Args) (interface{}, error) {
+			id, err := args.String("id")
+			if err != nil {
+				return nil, err
+			}
+			if id == "" {
+				return nil, fmt.Errorf("argument \"id\" is required")
+			}
+			return api.graphQLSilence(id)
+		},
+		"receivers": func(graphql.Args) (interface{}, error) {
+			api.mtx.RLock()
+			defer api.mtx.RUnlock()
+
+			var res []*graphql.Object
+			for _, rc := range api.configJSON.Receivers {
+				res = append(res, api.graphQLReceiver(rc.Name))
+			}
+			return res, nil
+		},
+		"status": func(graphql.Args) (interface{}, error) {
+			api.mtx.RLock()
+			defer api.mtx.RUnlock()
+
+			return &graphql.Object{Type: "Status", Fields: map[string]graphql.FieldFunc{
+				"config":         constant(api.config),
+				"configHash":     constant(api.configHash),
+				"configLoadedAt": constant(api.configLoadedAt),
+				"versionInfo": constant(map[string]string{
+					"version":   version.Version,
+					"revision":  version.Revision,
+					"branch":    version.Branch,
+					"buildUser": version.BuildUser,
+					"buildDate": version.BuildDate,
+					"goVersion": version.GoVersion,
+				}),
+				"uptime": constant(api.uptime),
+			}}, nil
+		},
+	}}
+}
+
+// graphQLSilence returns the object of the silence with the given ID or
+// nil if it does not exist.
+func (api *API) graphQLSilence(id string) (interface{}, error) {
+	sils, err := api.silences.Query(silence.QIDs(id))
+	if err != nil || len(sils) == 0 {
+		return nil, err
+	}
+	s, err := silenceFromProto(sils[0])
+	if err != nil {
+		return nil, err
+	}
+	api.setUsage(s)
+	return silenceObject(s), nil
+}
+
+func silenceObject(s *types.Silence) *graphql.Object {
+	var matchers []*graphql.Object
+	for _, m := range s.Matchers {
+		matchers = append(matchers, &graphql.Object{Type: "Matcher", Fields: map[string]graphql.FieldFunc{
+			"name":         constant(m.Name),
+			"value":        constant(m.Value),
+			"isRegex":      constant(m.IsRegex),
+			"isAnnotation": constant(m.IsAnnotation),
+		}})
+	}
+	return &graphql.Object{Type: "Silence", Fields: map[string]graphql.FieldFunc{
+		"id":        constant(s.ID),
+		"matchers":  constant(matchers),
+		"startsAt":  constant(s.StartsAt),
+		"endsAt":    constant(s.EndsAt),
+		"updatedAt": constant(s.UpdatedAt),
+		"createdBy": constant(s.CreatedBy),
+		"comment":   constant(s.Comment),
+		"state": func(graphql.Args) (interface{}, error) {
+			now := time.Now()
+			switch {
+			case now.Before(s.StartsAt):
+				return silence.StatePending, nil
+			case now.Before(s.EndsAt):
+				return silence.StateActive, nil
+			}
+			return silence.StateExpired, nil
+		},
+		"mutedAlerts": constant(s.MutedAlerts),
+	}}
+}
+
+// graphQLReceiver returns the object of the receiver with the given name.
+func (api *API) graphQLReceiver(name string) *graphql.Object {
+	return &graphql.Object{Type: "Receiver", Fields: map[string]graphql.FieldFunc{
+		"name": constant(name),
+		"integrations": func(graphql.Args) (interface{}, error) {
+			api.mtx.RLock()
+			defer api.mtx.RUnlock()
+
+			var res []*graphql.Object
+			for _, rc := range api.configJSON.Receivers {
+				if rc.Name != name {
+					continue
+				}
+				info, err := newReceiverInfo(rc)
+				if err != nil {
+					return nil, err
+				}
+				for _, i := range info.Integrations {
+					res = append(res, &graphql.Object{Type: "Integration", Fields: map[string]graphql.FieldFunc{
+						"type":         constant(i.Type),
+						"index":        constant(i.Index),
+						"sendResolved": constant(i.SendResolved),
+						"settings":     constant(i.Settings),
+					}})
+				}
+			}
+			return res, nil
+		},
+	}}
+}
+
+// constant returns a field function resolving to v.
+func constant(v interface{}) graphql.FieldFunc {
+	return func(graphql.Args) (interface{}, error) {
+		return v, nil
+	}
+}
+
+// constantTime returns a field function resolving to t or to null if t is
+// the zero time.
+func constantTime(t time.Time) graphql.FieldFunc {
+	if t.IsZero() {
+		return constant(nil)
+	}
+	return constant(t)
+}
@@ -0,0 +1,140 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+
+	"github.com/prometheus/alertmanager/dispatch"
+	"github.com/prometheus/alertmanager/graphql"
+	"github.com/prometheus/alertmanager/provider/mem"
+	"github.com/prometheus/alertmanager/silence"
+	"github.com/prometheus/alertmanager/silence/silencepb"
+	"github.com/prometheus/alertmanager/types"
+)
+
+func TestGraphQLQuery(t *testing.T) {
+	q, err := graphQLQuery(graphql.Args{
+		"state":   []interface{}{graphql.Enum("active")},
+		"matcher": "job=web",
+		"limit":   10,
+	}, "state", "matcher", "limit", "offset")
+	require.NoError(t, err)
+	require.Equal(t, url.Values{
+		"state":   {"active"},
+		"matcher": {"job=web"},
+		"limit":   {"10"},
+	}, q)
+
+	_, err = graphQLQuery(graphql.Args{"unknown": "x"}, "state")
+	require.Error(t, err)
+}
+
+func TestServeGraphQL(t *testing.T) {
+	alerts, err := mem.NewAlerts("")
+	require.NoError(t, err)
+	defer alerts.Close()
+
+	sils, err := silence.New(silence.Options{})
+	require.NoError(t, err)
+	endsAt, err := ptypes.TimestampProto(time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	sid, err := sils.Create(&silencepb.Silence{
+		Matchers: []*silencepb.Matcher{{Name: "job", Pattern: "web"}},
+		EndsAt:   endsAt,
+		Comments: []*silencepb.Comment{{Author: "me", Comment: "deploy"}},
+	})
+	require.NoError(t, err)
+
+	silenced := &types.Alert{Alert: model.Alert{
+		Labels:   model.LabelSet{"alertname": "a", "job": "web"},
+		StartsAt: time.Now(),
+	}, UpdatedAt: time.Now()}
+	unprocessed := &types.Alert{Alert: model.Alert{
+		Labels:   model.LabelSet{"alertname": "b", "job": "db"},
+		StartsAt: time.Now(),
+	}, UpdatedAt: time.Now()}
+	require.NoError(t, alerts.Put(silenced, unprocessed))
+
+	api := New(alerts, sils, nil, nil, nil, nil, nil, nil, nil, nil, nil, func() dispatch.AlertOverview {
+		return dispatch.AlertOverview{{Blocks: []*dispatch.AlertBlock{{
+			Alerts: []*dispatch.APIAlert{{Alert: &silenced.Alert, Silenced: sid}},
+		}}}}
+	}, nil, nil)
+	require.NoError(t, api.Update(`
+route:
+  receiver: default
+  routes:
+  - receiver: web
+    match:
+      job: web
+receivers:
+- name: default
+- name: web
+`, time.Minute))
+
+	query := `{
+		alerts(state: suppressed) {
+			labels
+			state
+			silence { createdBy comment state matchers { name value } }
+			receivers { name }
+		}
+		other: alerts(matcher: "job=db") { state receivers { name } }
+		receivers { name }
+	}`
+	rec := httptest.NewRecorder()
+	api.serveGraphQL(rec, httptest.NewRequest("POST", "/graphql", strings.NewReader(`{"query":`+strconv.Quote(query)+`}`)))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.JSONEq(t, `{"data":{
+		"alerts":[{
+			"labels":{"alertname":"a","job":"web"},
+			"state":"suppressed",
+			"silence":{"createdBy":"me","comment":"deploy","state":"active","matchers":[{"name":"job","value":"web"}]},
+			"receivers":[{"name":"web"}]
+		}],
+		"other":[{"state":"unprocessed","receivers":[{"name":"default"}]}],
+		"receivers":[{"name":"default"},{"name":"web"}]
+	}}`, rec.Body.String())
+
+	// Queries are also accepted as URL parameters.
+	rec = httptest.NewRecorder()
+	api.serveGraphQL(rec, httptest.NewRequest("GET", "/graphql?query="+url.QueryEscape(`{ silence(id: "`+sid+`") { id } }`), nil))
+	require.JSONEq(t, `{"data":{"silence":{"id":"`+sid+`"}}}`, rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	api.serveGraphQL(rec, httptest.NewRequest("GET", "/graphql?query=%7B", nil))
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+
+	// Oversized requests are rejected.
+	big := strings.Repeat("a", maxGraphQLRequestSize+1)
+	rec = httptest.NewRecorder()
+	api.serveGraphQL(rec, httptest.NewRequest("GET", "/graphql?query="+big, nil))
+	require.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+
+	rec = httptest.NewRecorder()
+	api.serveGraphQL(rec, httptest.NewRequest("POST", "/graphql", strings.NewReader(`{"query":"`+big+`"}`)))
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+}
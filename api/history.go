@@ -0,0 +1,119 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/common/model"
+
+	"github.com/prometheus/alertmanager/history"
+)
+
+// listHistory returns the recorded notifications, most recent first. They
+// may be filtered with the query parameters described at
+// parseHistoryParams.
+func (api *API) listHistory(w http.ResponseWriter, r *http.Request) {
+	params, limit, err := parseHistoryParams(r.URL.Query())
+	if err != nil {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+
+	entries := api.history.Query(params...)
+	w.Header().Set("X-Total-Count", strconv.Itoa(len(entries)))
+	if limit > 0 && limit < len(entries) {
+		entries = entries[:limit]
+	}
+	respond(w, entries)
+}
+
+// parseHistoryParams reads the history query parameters and the maximum
+// number of entries to return from the query parameters of a request.
+// Supported parameters are:
+//
+//	receiver       receiver that was notified
+//	integration    integration through which it was notified, e.g. email
+//	groupKey       key of the notified aggregation group
+//	fingerprint    fingerprint of an alert in the notification
+//	outcome        success or failure
+//	reason         reason of failed notifications, e.g. timeout
+//	since          RFC3339 timestamp
+//	until          RFC3339 timestamp
+//	limit          maximum number of entries to return
+func parseHistoryParams(q url.Values) ([]history.QueryParam, int, error) {
+	var params []history.QueryParam
+
+	if s := q.Get("receiver"); s != "" {
+		params = append(params, history.QReceiver(s))
+	}
+	if s := q.Get("integration"); s != "" {
+		params = append(params, history.QIntegration(s))
+	}
+	if s := q.Get("groupKey"); s != "" {
+		gkey, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return nil, 0, fmt.Errorf("invalid group key %q: %s", s, err)
+		}
+		params = append(params, history.QGroupKey(gkey))
+	}
+	if s := q.Get("fingerprint"); s != "" {
+		fp, err := model.FingerprintFromString(s)
+		if err != nil {
+			return nil, 0, fmt.Errorf("invalid fingerprint %q: %s", s, err)
+		}
+		params = append(params, history.QFingerprint(fp))
+	}
+	switch s := q.Get("outcome"); s {
+	case "":
+	case "success":
+		params = append(params, history.QFailed(false))
+	case "failure":
+		params = append(params, history.QFailed(true))
+	default:
+		return nil, 0, fmt.Errorf("invalid outcome %q", s)
+	}
+	if s := q.Get("reason"); s != "" {
+		params = append(params, history.QReason(s))
+	}
+
+	var (
+		since, until time.Time
+		limit        int
+	)
+	err := parseTimeParams(q, map[string]*time.Time{
+		"since": &since,
+		"until": &until,
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	if !since.IsZero() {
+		params = append(params, history.QSince(since))
+	}
+	if !until.IsZero() {
+		params = append(params, history.QUntil(until))
+	}
+	if err := parseIntParams(q, map[string]*int{"limit": &limit}); err != nil {
+		return nil, 0, err
+	}
+	return params, limit, nil
+}
@@ -0,0 +1,309 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/common/route"
+
+	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/ingest"
+	"github.com/prometheus/alertmanager/types"
+)
+
+// alertError is the error of a single alert of a request posting alerts.
+type alertError struct {
+	// Index is the position of the alert in the request.
+	Index  int            `json:"index"`
+	Labels model.LabelSet `json:"labels"`
+	Error  string         `json:"error"`
+}
+
+// ingestLimits returns the configured limits on posted alerts. It never
+// returns nil.
+func (api *API) ingestLimits() *config.IngestLimits {
+	api.mtx.RLock()
+	defer api.mtx.RUnlock()
+
+	if l := api.configJSON.IngestLimits; l != nil {
+		return l
+	}
+	return &config.IngestLimits{}
+}
+
+// receiveAlerts decodes the body of a request posting alerts into v. It
+// responds with an error and returns false if the body is invalid or exceeds
+// the maximum body size.
+func (api *API) receiveAlerts(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	body, ok := api.openAlertsBody(w, r)
+	if !ok {
+		return false
+	}
+	if err := receive(r, v); err != nil {
+		respondBodyError(w, body, err, nil)
+		return false
+	}
+	return true
+}
+
+// maxDecompressedBodySize is the maximum size in bytes of decompressed
+// request bodies if no maximum body size is configured, which protects
+// against small bodies decompressing to huge ones.
+var maxDecompressedBodySize int64 = 64 << 20
+
+// openAlertsBody replaces the body of a request posting alerts by a reader
+// that decompresses gzip-encoded bodies and fails beyond the maximum body
+// size. The size limit applies to the decompressed body, which is always
+// limited. The returned limitedBody is nil if there is no limit. It responds
+// with an error and returns false if the body cannot be read.
+func (api *API) openAlertsBody(w http.ResponseWriter, r *http.Request) (*limitedBody, bool) {
+	max := api.ingestLimits().MaxBodySize
+
+	switch enc := r.Header.Get("Content-Encoding"); enc {
+	case "", "identity":
+	case "gzip":
+		gz, err := gzip.NewReader(r.Body)
+		if err != nil {
+			respondError(w, apiError{
+				typ: errorBadData,
+				err: fmt.Errorf("invalid gzip body: %s", err),
+			}, nil)
+			return nil, false
+		}
+		r.Body = gz
+		if max <= 0 {
+			max = maxDecompressedBodySize
+		}
+	default:
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: fmt.Errorf("unsupported content encoding %q", enc),
+		}, nil)
+		return nil, false
+	}
+
+	var body *limitedBody
+	if max > 0 {
+		body = &limitedBody{ReadCloser: r.Body, max: max}
+		r.Body = body
+	}
+	return body, true
+}
+
+// respondBodyError responds with the error of reading a body opened by
+// openAlertsBody.
+func respondBodyError(w http.ResponseWriter, body *limitedBody, err error, data interface{}) {
+	if body != nil && body.exceeded {
+		respondError(w, apiError{
+			typ: errorPayloadTooLarge,
+			err: fmt.Errorf("request body exceeds the limit of %d bytes", body.max),
+		}, data)
+		return
+	}
+	respondError(w, apiError{
+		typ: errorBadData,
+		err: err,
+	}, data)
+}
+
+// bulkBatchSize is the number of alerts of a bulk request that are inserted
+// at once.
+var bulkBatchSize = 1000
+
+// bulkResult is the result of a bulk request posting alerts.
+type bulkResult struct {
+	// Accepted is the number of inserted alerts.
+	Accepted int           `json:"accepted"`
+	Errors   []*alertError `json:"errors,omitempty"`
+}
+
+// addAlertsBulk inserts the alerts of a JSON array while decoding it so that
+// large batches do not have to be held in memory at once. Unlike addAlerts,
+// it is not subject to the maximum number of alerts per request. If the
+// request fails midway, the alerts of the preceding batches remain inserted
+// and the response reports how many were accepted.
+func (api *API) addAlertsBulk(w http.ResponseWriter, r *http.Request) {
+	body, ok := api.openAlertsBody(w, r)
+	if !ok {
+		return
+	}
+	defer r.Body.Close()
+
+	span := traceIngest(r)
+	defer span.End()
+
+	var (
+		limits = api.ingestLimits()
+		dec    = json.NewDecoder(r.Body)
+		res    = &bulkResult{}
+		batch  = make([]*types.Alert, 0, bulkBatchSize)
+		n      int
+	)
+	flush := func() *apiError {
+		if err := api.checkAlertsScope(r, batch); err != nil {
+			return &apiError{typ: errorForbidden, err: err}
+		}
+		setTraceParent(span, batch)
+		alertErrs, err := api.putAlerts(limits, n-len(batch), batch)
+		if err != nil {
+			span.SetError(err)
+			return &apiError{typ: errorInternal, err: err}
+		}
+		res.Accepted += len(batch) - len(alertErrs)
+		res.Errors = append(res.Errors, alertErrs...)
+		batch = batch[:0]
+		return nil
+	}
+
+	if err := expectDelim(dec, '['); err != nil {
+		respondBodyError(w, body, err, res)
+		return
+	}
+	for dec.More() {
+		var a types.Alert
+		if err := dec.Decode(&a); err != nil {
+			respondBodyError(w, body, fmt.Errorf("decoding alert %d: %s", n, err), res)
+			return
+		}
+		batch = append(batch, &a)
+		n++
+
+		if len(batch) == bulkBatchSize {
+			if apiErr := flush(); apiErr != nil {
+				respondError(w, *apiErr, res)
+				return
+			}
+		}
+	}
+	if err := expectDelim(dec, ']'); err != nil {
+		respondBodyError(w, body, err, res)
+		return
+	}
+	if apiErr := flush(); apiErr != nil {
+		respondError(w, *apiErr, res)
+		return
+	}
+
+	if len(res.Errors) > 0 {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: fmt.Errorf("%d of %d alerts are invalid", len(res.Errors), n),
+		}, res)
+		return
+	}
+	respond(w, res)
+}
+
+// expectDelim reads the next token of dec and returns an error unless it is
+// the given delimiter.
+func expectDelim(dec *json.Decoder, d json.Delim) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok != d {
+		return fmt.Errorf("expected %q but got %v", d, tok)
+	}
+	return nil
+}
+
+// checkAlertLimits returns an error if the alert exceeds one of the limits.
+func checkAlertLimits(l *config.IngestLimits, a *types.Alert) error {
+	if l.MaxLabelsPerAlert > 0 && len(a.Labels) > l.MaxLabelsPerAlert {
+		return fmt.Errorf("alert has %d labels, more than the limit of %d", len(a.Labels), l.MaxLabelsPerAlert)
+	}
+	if l.MaxAnnotationsPerAlert > 0 && len(a.Annotations) > l.MaxAnnotationsPerAlert {
+		return fmt.Errorf("alert has %d annotations, more than the limit of %d", len(a.Annotations), l.MaxAnnotationsPerAlert)
+	}
+	if l.MaxValueLength > 0 {
+		for ln, lv := range a.Labels {
+			if len(lv) > l.MaxValueLength {
+				return fmt.Errorf("value of label %q is longer than the limit of %d bytes", ln, l.MaxValueLength)
+			}
+		}
+		for an, av := range a.Annotations {
+			if len(av) > l.MaxValueLength {
+				return fmt.Errorf("value of annotation %q is longer than the limit of %d bytes", an, l.MaxValueLength)
+			}
+		}
+	}
+	return nil
+}
+
+// limitedBody fails reading a request body beyond max bytes and records
+// that the limit was exceeded.
+type limitedBody struct {
+	io.ReadCloser
+	max      int64
+	n        int64
+	exceeded bool
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.n >= b.max {
+		// The body may end exactly at the limit.
+		var buf [1]byte
+		if n, err := b.ReadCloser.Read(buf[:]); n == 0 {
+			return 0, err
+		}
+		b.exceeded = true
+		return 0, fmt.Errorf("request body too large")
+	}
+	if int64(len(p)) > b.max-b.n {
+		p = p[:b.max-b.n]
+	}
+	n, err := b.ReadCloser.Read(p)
+	b.n += int64(n)
+	return n, err
+}
+
+// ingestAlerts translates the alerts of a third-party format posted to the
+// ingest adapter of the given name and inserts them like posted alerts.
+func (api *API) ingestAlerts(w http.ResponseWriter, r *http.Request) {
+	name := route.Param(api.context(r), "name")
+
+	api.mtx.RLock()
+	var c *config.IngestAdapter
+	for _, ia := range api.configJSON.IngestAdapters {
+		if ia.Name == name {
+			c = ia
+			break
+		}
+	}
+	api.mtx.RUnlock()
+
+	if c == nil {
+		http.Error(w, fmt.Sprintf("ingest adapter %q not found", name), http.StatusNotFound)
+		return
+	}
+
+	body, ok := api.openAlertsBody(w, r)
+	if !ok {
+		return
+	}
+	defer r.Body.Close()
+
+	alerts, err := ingest.Decode(c, r.Body)
+	if err != nil {
+		respondBodyError(w, body, err, nil)
+		return
+	}
+	api.insertAlerts(w, r, alerts...)
+}
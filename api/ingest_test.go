@@ -0,0 +1,246 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/common/route"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+
+	"github.com/prometheus/alertmanager/provider/mem"
+)
+
+func TestIngestLimits(t *testing.T) {
+	alerts, err := mem.NewAlerts("")
+	require.NoError(t, err)
+	defer alerts.Close()
+
+	api := New(alerts, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+	require.NoError(t, api.Update(`
+route:
+  receiver: default
+receivers:
+- name: default
+ingest_limits:
+  max_alerts_per_request: 4
+  max_labels_per_alert: 2
+  max_annotations_per_alert: 1
+  max_value_length: 10
+  max_body_size: 300
+`, time.Minute))
+
+	post := func(body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		api.addAlerts(rec, httptest.NewRequest("POST", "/alerts", strings.NewReader(body)))
+		return rec
+	}
+
+	rec := post(`[{"labels":{"alertname":"a"}},{"labels":{"alertname":"b"}},{"labels":{"alertname":"c"}},{"labels":{"alertname":"d"}},{"labels":{"alertname":"e"}}]`)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	require.Contains(t, rec.Body.String(), "more than the limit of 4")
+
+	rec = post(`[{"labels":{"alertname":"` + strings.Repeat("x", 300) + `"}}]`)
+	require.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+
+	// Valid alerts are accepted and each violation is reported.
+	rec = post(`[{"labels":{"alertname":"a","job":"web","env":"prod"}},` +
+		`{"labels":{"alertname":"ok"}},` +
+		`{"labels":{"alertname":"b"},"annotations":{"summary":"x","runbook":"y"}},` +
+		`{"labels":{"alertname":"waytoolongvalue"}}]`)
+	require.Equal(t, http.StatusBadRequest, rec.Code, rec.Body.String())
+
+	var res struct {
+		Data struct {
+			Errors []*alertError `json:"errors"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&res))
+	require.Len(t, res.Data.Errors, 3)
+	require.Equal(t, 0, res.Data.Errors[0].Index)
+	require.Contains(t, res.Data.Errors[0].Error, "3 labels")
+	require.Equal(t, 2, res.Data.Errors[1].Index)
+	require.Contains(t, res.Data.Errors[1].Error, "2 annotations")
+	require.Equal(t, 3, res.Data.Errors[2].Index)
+	require.Equal(t, model.LabelSet{"alertname": "waytoolongvalue"}, res.Data.Errors[2].Labels)
+
+	_, err = alerts.Get(model.LabelSet{"alertname": "ok"}.Fingerprint())
+	require.NoError(t, err)
+	_, err = alerts.Get(model.LabelSet{"alertname": "b"}.Fingerprint())
+	require.Error(t, err)
+
+	// Bodies up to the maximum size are accepted.
+	body := `[{"labels":{"alertname":"ok"}}]`
+	require.Equal(t, http.StatusOK, post(body+strings.Repeat(" ", 300-len(body))).Code)
+}
+
+func TestAddAlertsGzip(t *testing.T) {
+	alerts, err := mem.NewAlerts("")
+	require.NoError(t, err)
+	defer alerts.Close()
+
+	api := New(alerts, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+	require.NoError(t, api.Update(`
+route:
+  receiver: default
+receivers:
+- name: default
+ingest_limits:
+  max_body_size: 100
+`, time.Minute))
+
+	post := func(body string) *httptest.ResponseRecorder {
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		gz.Write([]byte(body))
+		gz.Close()
+
+		req := httptest.NewRequest("POST", "/alerts", &buf)
+		req.Header.Set("Content-Encoding", "gzip")
+		rec := httptest.NewRecorder()
+		api.addAlerts(rec, req)
+		return rec
+	}
+
+	require.Equal(t, http.StatusOK, post(`[{"labels":{"alertname":"a"}}]`).Code)
+	_, err = alerts.Get(model.LabelSet{"alertname": "a"}.Fingerprint())
+	require.NoError(t, err)
+
+	// The size limit applies to the decompressed body.
+	rec := post(`[{"labels":{"alertname":"` + strings.Repeat("x", 1000) + `"}}]`)
+	require.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+
+	// Decompressed bodies are limited without a configured limit.
+	require.NoError(t, api.Update(`
+route:
+  receiver: default
+receivers:
+- name: default
+`, time.Minute))
+	defer func(n int64) { maxDecompressedBodySize = n }(maxDecompressedBodySize)
+	maxDecompressedBodySize = 500
+
+	rec = post(`[{"labels":{"alertname":"` + strings.Repeat("x", 1000) + `"}}]`)
+	require.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+	require.Equal(t, http.StatusOK, post(`[{"labels":{"alertname":"b"}}]`).Code)
+
+	req := httptest.NewRequest("POST", "/alerts", strings.NewReader(`[]`))
+	req.Header.Set("Content-Encoding", "gzip")
+	rec = httptest.NewRecorder()
+	api.addAlerts(rec, req)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestAddAlertsBulk(t *testing.T) {
+	alerts, err := mem.NewAlerts("")
+	require.NoError(t, err)
+	defer alerts.Close()
+
+	api := New(alerts, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+	require.NoError(t, api.Update(`
+route:
+  receiver: default
+receivers:
+- name: default
+ingest_limits:
+  max_alerts_per_request: 2
+`, time.Minute))
+
+	defer func(n int) { bulkBatchSize = n }(bulkBatchSize)
+	bulkBatchSize = 2
+
+	post := func(body string) (int, *bulkResult) {
+		rec := httptest.NewRecorder()
+		api.addAlertsBulk(rec, httptest.NewRequest("POST", "/alerts/bulk", strings.NewReader(body)))
+
+		var res struct {
+			Data *bulkResult `json:"data"`
+		}
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&res))
+		return rec.Code, res.Data
+	}
+
+	code, res := post(`[{"labels":{"alertname":"a"}},{"labels":{"alertname":"b"}},{"labels":{}},{"labels":{"alertname":"c"}},{"labels":{"alertname":"d"}}]`)
+	require.Equal(t, http.StatusBadRequest, code)
+	require.Equal(t, 4, res.Accepted)
+	require.Len(t, res.Errors, 1)
+	require.Equal(t, 2, res.Errors[0].Index)
+
+	for _, name := range []model.LabelValue{"a", "b", "c", "d"} {
+		_, err = alerts.Get(model.LabelSet{"alertname": name}.Fingerprint())
+		require.NoError(t, err)
+	}
+
+	code, res = post(`[{"labels":{"alertname":"e"}}]`)
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, 1, res.Accepted)
+
+	// Batches preceding a malformed alert remain inserted.
+	code, res = post(`[{"labels":{"alertname":"f"}},{"labels":{"alertname":"g"}},{"labels":42}]`)
+	require.Equal(t, http.StatusBadRequest, code)
+	require.Equal(t, 2, res.Accepted)
+
+	code, _ = post(`{"labels":{"alertname":"h"}}`)
+	require.Equal(t, http.StatusBadRequest, code)
+}
+
+func TestIngestAlerts(t *testing.T) {
+	alerts, err := mem.NewAlerts("")
+	require.NoError(t, err)
+	defer alerts.Close()
+
+	api := New(alerts, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+	require.NoError(t, api.Update(`
+route:
+  receiver: default
+receivers:
+- name: default
+ingest_adapters:
+- name: grafana
+  type: grafana
+  labels:
+    source: grafana
+`, time.Minute))
+
+	var name string
+	api.context = func(r *http.Request) context.Context {
+		return route.WithParam(context.Background(), "name", name)
+	}
+
+	post := func(body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		api.ingestAlerts(rec, httptest.NewRequest("POST", "/ingest/"+name, strings.NewReader(body)))
+		return rec
+	}
+
+	name = "grafana"
+	rec := post(`{"alerts":[{"status":"firing","labels":{"alertname":"HighCPU"}}]}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	_, err = alerts.Get(model.LabelSet{"alertname": "HighCPU", "source": "grafana"}.Fingerprint())
+	require.NoError(t, err)
+
+	require.Equal(t, http.StatusBadRequest, post(`{"title":"no rule"}`).Code)
+
+	name = "cloudwatch"
+	require.Equal(t, http.StatusNotFound, post(`{}`).Code)
+}
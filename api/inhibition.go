@@ -0,0 +1,88 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/prometheus/common/model"
+
+	"github.com/prometheus/alertmanager/inhibit"
+	"github.com/prometheus/alertmanager/provider"
+)
+
+// inhibitionLabels returns the label set to explain the inhibition of. It is
+// either the label set of the alert with the fingerprint given in the query
+// or the label set given by label=<name>=<value> parameters.
+func inhibitionLabels(q url.Values, alerts provider.Alerts) (model.LabelSet, error) {
+	if s := q.Get("fingerprint"); s != "" {
+		if len(q["label"]) > 0 {
+			return nil, errors.New("only one of fingerprint and label must be set")
+		}
+		fp, err := model.ParseFingerprint(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid fingerprint %q: %s", s, err)
+		}
+		a, err := alerts.Get(fp)
+		if err != nil {
+			return nil, fmt.Errorf("alert %s: %s", fp, err)
+		}
+		return a.Labels, nil
+	}
+
+	lset := model.LabelSet{}
+	for _, l := range q["label"] {
+		parts := strings.SplitN(l, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid label %q, expected <name>=<value>", l)
+		}
+		ln := model.LabelName(parts[0])
+		if !ln.IsValid() {
+			return nil, fmt.Errorf("invalid label name %q", parts[0])
+		}
+		lset[ln] = model.LabelValue(parts[1])
+	}
+	if len(lset) == 0 {
+		return nil, errors.New("fingerprint or at least one label required")
+	}
+	return lset, nil
+}
+
+// explainInhibition reports whether alerts with a label set are inhibited and
+// by which rules and source alerts.
+func (api *API) explainInhibition(w http.ResponseWriter, r *http.Request) {
+	lset, err := inhibitionLabels(r.URL.Query(), api.alerts)
+	if err != nil {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+	inhibitions := api.inhibitions(lset)
+
+	respond(w, struct {
+		Labels      model.LabelSet        `json:"labels"`
+		Inhibited   bool                  `json:"inhibited"`
+		Inhibitions []*inhibit.Inhibition `json:"inhibitions"`
+	}{
+		Labels:      lset,
+		Inhibited:   len(inhibitions) > 0,
+		Inhibitions: inhibitions,
+	})
+}
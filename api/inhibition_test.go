@@ -0,0 +1,85 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+
+	"github.com/prometheus/alertmanager/provider/mem"
+	"github.com/prometheus/alertmanager/types"
+)
+
+func TestInhibitionLabels(t *testing.T) {
+	alerts, err := mem.NewAlerts("")
+	require.NoError(t, err)
+	defer alerts.Close()
+
+	a := &types.Alert{
+		Alert: model.Alert{
+			Labels:   model.LabelSet{"alertname": "a", "job": "api"},
+			StartsAt: time.Now(),
+		},
+		UpdatedAt: time.Now(),
+	}
+	require.NoError(t, alerts.Put(a))
+
+	cases := []struct {
+		query  url.Values
+		labels model.LabelSet
+		err    bool
+	}{
+		{
+			query:  url.Values{"fingerprint": {a.Fingerprint().String()}},
+			labels: a.Labels,
+		},
+		{
+			query:  url.Values{"label": {"alertname=b", "job=web=1"}},
+			labels: model.LabelSet{"alertname": "b", "job": "web=1"},
+		},
+		{
+			query: url.Values{},
+			err:   true,
+		},
+		{
+			query: url.Values{"fingerprint": {"0000000000000001"}},
+			err:   true,
+		},
+		{
+			query: url.Values{"fingerprint": {"xyz"}},
+			err:   true,
+		},
+		{
+			query: url.Values{"label": {"alertname"}},
+			err:   true,
+		},
+		{
+			query: url.Values{"fingerprint": {a.Fingerprint().String()}, "label": {"a=b"}},
+			err:   true,
+		},
+	}
+	for i, c := range cases {
+		lset, err := inhibitionLabels(c.query, alerts)
+		if c.err {
+			require.Error(t, err, "case %d", i)
+			continue
+		}
+		require.NoError(t, err, "case %d", i)
+		require.Equal(t, c.labels, lset, "case %d", i)
+	}
+}
@@ -0,0 +1,1212 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+// openAPISpec is the OpenAPI definition of the v2 API.
+const openAPISpec = `openapi: 3.0.0
+info:
+  title: Alertmanager API
+  version: 2.0.0
+  description: >
+    API of the Prometheus Alertmanager. Responses are plain JSON documents.
+    Failed requests are answered with an error status code and an Error
+    object.
+servers:
+  - url: /api/v2
+paths:
+  /status:
+    get:
+      operationId: getStatus
+      summary: Get the status and configuration of the Alertmanager.
+      responses:
+        '200':
+          description: The status of the Alertmanager.
+          content:
+            application/json:
+              schema:
+                $ref: '#/components/schemas/Status'
+  /receivers:
+    get:
+      operationId: getReceivers
+      summary: List the configured receivers and their integrations.
+      responses:
+        '200':
+          description: The receivers.
+          content:
+            application/json:
+              schema:
+                type: array
+                items:
+                  $ref: '#/components/schemas/Receiver'
+  /receivers/{name}/test:
+    post:
+      operationId: testReceiver
+      summary: Send a test notification via all integrations of a receiver.
+      description: >
+        A synthetic firing alert is sent via every integration of the
+        receiver. Failed attempts are retried until the timeout. Test
+        notifications are not deduplicated and not recorded in the
+        notification log.
+      parameters:
+        - name: name
+          in: path
+          required: true
+          schema:
+            type: string
+        - name: timeout
+          in: query
+          description: Duration after which sending is given up, e.g. 10s. Defaults to 30s.
+          schema:
+            type: string
+      requestBody:
+        required: false
+        content:
+          application/json:
+            schema:
+              $ref: '#/components/schemas/TestNotification'
+      responses:
+        '200':
+          description: The outcome per integration.
+          content:
+            application/json:
+              schema:
+                $ref: '#/components/schemas/TestNotificationResult'
+        '400':
+          $ref: '#/components/responses/Error'
+        '404':
+          $ref: '#/components/responses/Error'
+  /receivers/{name}/capture:
+    parameters:
+      - name: name
+        in: path
+        required: true
+        schema:
+          type: string
+    get:
+      operationId: getReceiverCapture
+      summary: Get the captured requests sent to a receiver, most recent first.
+      responses:
+        '200':
+          description: The captured requests.
+          content:
+            application/json:
+              schema:
+                $ref: '#/components/schemas/Capture'
+        '404':
+          $ref: '#/components/responses/Error'
+        '503':
+          $ref: '#/components/responses/Error'
+    post:
+      operationId: enableReceiverCapture
+      summary: Start capturing the requests sent to a receiver.
+      description: >
+        The HTTP requests notifiers send to the receiver and the responses
+        are kept in a ring buffer, with the secrets of the receiver's
+        configuration redacted. Capturing is enabled on this instance only
+        and not persisted.
+      responses:
+        '200':
+          description: The captured requests.
+          content:
+            application/json:
+              schema:
+                $ref: '#/components/schemas/Capture'
+        '404':
+          $ref: '#/components/responses/Error'
+        '503':
+          $ref: '#/components/responses/Error'
+    delete:
+      operationId: disableReceiverCapture
+      summary: Stop capturing the requests sent to a receiver.
+      responses:
+        '200':
+          description: The captured requests, which are kept.
+          content:
+            application/json:
+              schema:
+                $ref: '#/components/schemas/Capture'
+        '404':
+          $ref: '#/components/responses/Error'
+        '503':
+          $ref: '#/components/responses/Error'
+  /alerts:
+    get:
+      operationId: getAlerts
+      summary: List the alerts.
+      parameters:
+        - name: matcher
+          in: query
+          description: >
+            Only return alerts matching all given matchers, e.g. job="foo"
+            or job=~"foo.*". Names prefixed with "annotations." are matched
+            against the annotations of the alert.
+          style: form
+          explode: true
+          schema:
+            type: array
+            items:
+              type: string
+        - name: state
+          in: query
+          description: Only return alerts in one of the given states.
+          style: form
+          explode: true
+          schema:
+            type: array
+            items:
+              type: string
+              enum: [unprocessed, active, suppressed]
+        - name: receiver
+          in: query
+          description: Only return alerts routed to one of the given receivers.
+          style: form
+          explode: true
+          schema:
+            type: array
+            items:
+              type: string
+        - name: assignee
+          in: query
+          description: Only return alerts assigned to the given person.
+          schema:
+            type: string
+        - name: sort
+          in: query
+          description: >
+            The key to sort the alerts by, prefixed with "-" for descending
+            order. Severity is ranked by the notification priority
+            configuration, or else by the values critical, error, warning
+            and info of the severity label.
+          schema:
+            type: string
+            enum: [startsAt, -startsAt, endsAt, -endsAt, severity, -severity, labels, -labels]
+            default: startsAt
+        - name: startsAfter
+          in: query
+          schema:
+            type: string
+            format: date-time
+        - name: startsBefore
+          in: query
+          schema:
+            type: string
+            format: date-time
+        - name: endsAfter
+          in: query
+          schema:
+            type: string
+            format: date-time
+        - name: endsBefore
+          in: query
+          schema:
+            type: string
+            format: date-time
+        - name: offset
+          in: query
+          schema:
+            type: integer
+        - name: limit
+          in: query
+          schema:
+            type: integer
+      responses:
+        '200':
+          description: >
+            The alerts, ordered by start time. The X-Total-Count header holds
+            their total number before pagination.
+          content:
+            application/json:
+              schema:
+                type: array
+                items:
+                  $ref: '#/components/schemas/Alert'
+        '400':
+          $ref: '#/components/responses/Error'
+        '500':
+          $ref: '#/components/responses/Error'
+    post:
+      operationId: postAlerts
+      summary: Create or update alerts.
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema:
+              type: array
+              items:
+                $ref: '#/components/schemas/PostableAlert'
+      responses:
+        '200':
+          description: All alerts were accepted.
+        '400':
+          $ref: '#/components/responses/Error'
+        '413':
+          $ref: '#/components/responses/Error'
+        '429':
+          $ref: '#/components/responses/Error'
+        '500':
+          $ref: '#/components/responses/Error'
+  /alerts/bulk:
+    post:
+      operationId: postAlertsBulk
+      summary: Create or update a large batch of alerts.
+      description: >
+        The alerts are decoded and inserted in batches while the request is
+        read. They are not subject to the maximum number of alerts per
+        request. Request bodies of this endpoint and of postAlerts may be
+        compressed with Content-Encoding gzip.
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema:
+              type: array
+              items:
+                $ref: '#/components/schemas/PostableAlert'
+      responses:
+        '200':
+          description: All alerts were accepted.
+          content:
+            application/json:
+              schema:
+                $ref: '#/components/schemas/BulkResult'
+        '400':
+          description: >
+            Some alerts were invalid or the request failed midway. The data
+            of the error is a BulkResult.
+          content:
+            application/json:
+              schema:
+                $ref: '#/components/schemas/Error'
+        '403':
+          $ref: '#/components/responses/Error'
+        '413':
+          $ref: '#/components/responses/Error'
+        '429':
+          $ref: '#/components/responses/Error'
+        '500':
+          $ref: '#/components/responses/Error'
+  /alerts/groups:
+    get:
+      operationId: getAlertGroups
+      summary: List the aggregation groups of alerts.
+      parameters:
+        - name: sort
+          in: query
+          description: >
+            The key to sort the groups by, prefixed with "-" for descending
+            order. The alerts of each group are sorted by the key as well,
+            unless it is size.
+          schema:
+            type: string
+            enum: [labels, -labels, size, -size, startsAt, -startsAt, severity, -severity]
+            default: labels
+        - name: assignee
+          in: query
+          description: Only return alerts assigned to the given person.
+          schema:
+            type: string
+      responses:
+        '200':
+          description: The aggregation groups.
+          content:
+            application/json:
+              schema:
+                type: array
+                items:
+                  $ref: '#/components/schemas/AlertGroup'
+        '400':
+          $ref: '#/components/responses/Error'
+  /alerts/counts:
+    get:
+      operationId: getAlertCounts
+      summary: Count the active alerts by the values of labels.
+      description: >
+        Resolved alerts are not counted. The alerts can be filtered with the
+        matcher, state, receiver and assignee parameters of getAlerts.
+      parameters:
+        - name: by
+          in: query
+          description: >
+            The labels to count by, e.g. team and severity. Without labels,
+            all alerts are counted together.
+          style: form
+          explode: true
+          schema:
+            type: array
+            items:
+              type: string
+        - name: matcher
+          in: query
+          style: form
+          explode: true
+          schema:
+            type: array
+            items:
+              type: string
+        - name: state
+          in: query
+          style: form
+          explode: true
+          schema:
+            type: array
+            items:
+              type: string
+              enum: [unprocessed, active, suppressed]
+        - name: receiver
+          in: query
+          style: form
+          explode: true
+          schema:
+            type: array
+            items:
+              type: string
+        - name: assignee
+          in: query
+          schema:
+            type: string
+      responses:
+        '200':
+          description: The counts, in descending order.
+          content:
+            application/json:
+              schema:
+                $ref: '#/components/schemas/AlertCounts'
+        '400':
+          $ref: '#/components/responses/Error'
+        '500':
+          $ref: '#/components/responses/Error'
+  /alert/{fingerprint}/explain:
+    get:
+      operationId: explainAlert
+      summary: Explain how an alert passes through the notification pipeline.
+      parameters:
+        - name: fingerprint
+          in: path
+          required: true
+          schema:
+            type: string
+      responses:
+        '200':
+          description: The routes, groups, silences, inhibitions and notifications of the alert.
+          content:
+            application/json:
+              schema:
+                $ref: '#/components/schemas/AlertExplanation'
+        '400':
+          $ref: '#/components/responses/Error'
+        '404':
+          $ref: '#/components/responses/Error'
+  /snoozes:
+    get:
+      operationId: getSnoozes
+      summary: List the snoozes of alerts.
+      responses:
+        '200':
+          description: The active snoozes.
+          content:
+            application/json:
+              schema:
+                type: array
+                items:
+                  $ref: '#/components/schemas/Snooze'
+    post:
+      operationId: postSnooze
+      summary: Suppress notifications for a single alert until the snooze ends.
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema:
+              type: object
+              required: [fingerprint]
+              description: Exactly one of endsAt and duration must be set.
+              properties:
+                fingerprint:
+                  type: string
+                endsAt:
+                  type: string
+                  format: date-time
+                duration:
+                  type: string
+                  description: Duration of the snooze from now, e.g. 2h.
+                createdBy:
+                  type: string
+                comment:
+                  type: string
+      responses:
+        '200':
+          description: The snooze.
+          content:
+            application/json:
+              schema:
+                $ref: '#/components/schemas/Snooze'
+        '400':
+          $ref: '#/components/responses/Error'
+  /snooze/{fingerprint}:
+    delete:
+      operationId: deleteSnooze
+      summary: End the snooze of an alert.
+      parameters:
+        - name: fingerprint
+          in: path
+          required: true
+          schema:
+            type: string
+      responses:
+        '200':
+          description: The snooze was ended.
+        '400':
+          $ref: '#/components/responses/Error'
+  /silences:
+    get:
+      operationId: getSilences
+      summary: List the silences.
+      parameters:
+        - name: state
+          in: query
+          description: Only return silences in one of the given states.
+          style: form
+          explode: true
+          schema:
+            type: array
+            items:
+              type: string
+              enum: [active, pending, expired]
+        - name: matcher
+          in: query
+          description: Only return silences with an identical matcher, e.g. job="foo".
+          style: form
+          explode: true
+          schema:
+            type: array
+            items:
+              type: string
+        - name: createdBy
+          in: query
+          schema:
+            type: string
+        - name: createdAfter
+          in: query
+          schema:
+            type: string
+            format: date-time
+        - name: createdBefore
+          in: query
+          schema:
+            type: string
+            format: date-time
+        - name: endsAfter
+          in: query
+          schema:
+            type: string
+            format: date-time
+        - name: endsBefore
+          in: query
+          schema:
+            type: string
+            format: date-time
+        - name: offset
+          in: query
+          schema:
+            type: integer
+        - name: limit
+          in: query
+          schema:
+            type: integer
+      responses:
+        '200':
+          description: The silences. The X-Total-Count header holds their total number.
+          content:
+            application/json:
+              schema:
+                type: array
+                items:
+                  $ref: '#/components/schemas/Silence'
+        '400':
+          $ref: '#/components/responses/Error'
+        '500':
+          $ref: '#/components/responses/Error'
+    post:
+      operationId: postSilences
+      summary: Create or update a silence.
+      parameters:
+        - name: override
+          in: query
+          description: Create the silence even if existing silences already mute all of its alerts.
+          schema:
+            type: boolean
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema:
+              $ref: '#/components/schemas/Silence'
+      responses:
+        '200':
+          description: The silence was created.
+          content:
+            application/json:
+              schema:
+                type: object
+                properties:
+                  silenceId:
+                    type: string
+                  conflicts:
+                    type: array
+                    items:
+                      $ref: '#/components/schemas/Silence'
+        '400':
+          $ref: '#/components/responses/Error'
+        '403':
+          $ref: '#/components/responses/Error'
+        '409':
+          $ref: '#/components/responses/Error'
+        '429':
+          $ref: '#/components/responses/Error'
+        '500':
+          $ref: '#/components/responses/Error'
+        '503':
+          description: >
+            The silence was created but not replicated to a quorum of peers
+            in time. The data holds its silenceId.
+          content:
+            application/json:
+              schema:
+                $ref: '#/components/schemas/Error'
+  /silence/{silenceID}:
+    parameters:
+      - name: silenceID
+        in: path
+        required: true
+        schema:
+          type: string
+    get:
+      operationId: getSilence
+      summary: Get a silence by its ID.
+      responses:
+        '200':
+          description: The silence.
+          content:
+            application/json:
+              schema:
+                $ref: '#/components/schemas/Silence'
+        '404':
+          $ref: '#/components/responses/Error'
+    delete:
+      operationId: deleteSilence
+      summary: Expire a silence.
+      responses:
+        '200':
+          description: The silence was expired.
+        '400':
+          $ref: '#/components/responses/Error'
+        '429':
+          $ref: '#/components/responses/Error'
+  /silence/{silenceID}/extend:
+    parameters:
+      - name: silenceID
+        in: path
+        required: true
+        schema:
+          type: string
+    post:
+      operationId: extendSilence
+      summary: Move the end of a silence without recreating it.
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema:
+              type: object
+              description: Exactly one of endsAt and duration must be set.
+              properties:
+                endsAt:
+                  type: string
+                  format: date-time
+                duration:
+                  type: string
+                  description: Duration added to the current end, e.g. 2h.
+                extendedBy:
+                  type: string
+                comment:
+                  type: string
+      responses:
+        '200':
+          description: The extended silence.
+          content:
+            application/json:
+              schema:
+                $ref: '#/components/schemas/Silence'
+        '400':
+          $ref: '#/components/responses/Error'
+        '403':
+          $ref: '#/components/responses/Error'
+        '404':
+          description: The silence does not exist.
+        '429':
+          $ref: '#/components/responses/Error'
+components:
+  responses:
+    Error:
+      description: The request failed.
+      content:
+        application/json:
+          schema:
+            $ref: '#/components/schemas/Error'
+  schemas:
+    Error:
+      type: object
+      required: [error]
+      properties:
+        error:
+          type: string
+        errorType:
+          type: string
+          enum: [server_error, bad_data, quota_exceeded, conflict, forbidden, rate_limited, payload_too_large, unavailable]
+        data:
+          description: Additional information about the error.
+    AlertCounts:
+      type: object
+      properties:
+        by:
+          type: array
+          items:
+            type: string
+        total:
+          type: integer
+        counts:
+          type: array
+          items:
+            type: object
+            properties:
+              labels:
+                $ref: '#/components/schemas/LabelSet'
+              count:
+                type: integer
+    BulkResult:
+      type: object
+      properties:
+        accepted:
+          type: integer
+          description: The number of alerts that were inserted.
+        errors:
+          type: array
+          items:
+            type: object
+            properties:
+              index:
+                type: integer
+              labels:
+                $ref: '#/components/schemas/LabelSet'
+              error:
+                type: string
+    LabelSet:
+      type: object
+      additionalProperties:
+        type: string
+    PostableAlert:
+      type: object
+      required: [labels]
+      properties:
+        labels:
+          $ref: '#/components/schemas/LabelSet'
+        annotations:
+          $ref: '#/components/schemas/LabelSet'
+        startsAt:
+          type: string
+          format: date-time
+        endsAt:
+          type: string
+          format: date-time
+        generatorURL:
+          type: string
+    Alert:
+      allOf:
+        - $ref: '#/components/schemas/PostableAlert'
+        - type: object
+          properties:
+            ack:
+              $ref: '#/components/schemas/Ack'
+            comments:
+              type: array
+              items:
+                $ref: '#/components/schemas/Comment'
+            assignedTo:
+              type: string
+            snooze:
+              $ref: '#/components/schemas/Snooze'
+            sources:
+              type: array
+              items:
+                $ref: '#/components/schemas/AlertSource'
+    GroupedAlert:
+      allOf:
+        - $ref: '#/components/schemas/Alert'
+        - type: object
+          properties:
+            inhibited:
+              type: boolean
+            silenced:
+              type: string
+              description: The ID of the silence muting the alert.
+            flapping:
+              type: boolean
+    AlertGroup:
+      type: object
+      properties:
+        labels:
+          $ref: '#/components/schemas/LabelSet'
+        groupKey:
+          type: integer
+          format: uint64
+        blocks:
+          type: array
+          items:
+            type: object
+            properties:
+              routeOpts:
+                $ref: '#/components/schemas/RouteOpts'
+              alerts:
+                type: array
+                items:
+                  $ref: '#/components/schemas/GroupedAlert'
+              pause:
+                $ref: '#/components/schemas/Pause'
+    AlertExplanation:
+      type: object
+      properties:
+        fingerprint:
+          type: string
+        labels:
+          $ref: '#/components/schemas/LabelSet'
+        status:
+          type: string
+          enum: [firing, resolved]
+        state:
+          type: string
+          enum: [unprocessed, active, suppressed]
+        routes:
+          type: array
+          items:
+            type: object
+            properties:
+              receiver:
+                type: string
+              groupBy:
+                type: array
+                items:
+                  type: string
+              path:
+                type: array
+                description: The routes from the root of the routing tree to the matched route.
+                items:
+                  type: object
+                  properties:
+                    receiver:
+                      type: string
+                    matchers:
+                      type: array
+                      items:
+                        $ref: '#/components/schemas/Matcher'
+                    continue:
+                      type: boolean
+              group:
+                type: object
+                description: The aggregation group of the alert, unless it was not processed yet.
+                properties:
+                  labels:
+                    $ref: '#/components/schemas/LabelSet'
+                  groupKey:
+                    type: integer
+                    format: uint64
+              pause:
+                $ref: '#/components/schemas/Pause'
+              lastNotification:
+                type: object
+                description: The most recent notification of the receiver including the alert.
+                properties:
+                  integration:
+                    type: string
+                  time:
+                    type: string
+                    format: date-time
+                  success:
+                    type: boolean
+                  error:
+                    type: string
+        silences:
+          type: array
+          description: The active silences matching the alert.
+          items:
+            $ref: '#/components/schemas/Silence'
+        inhibitions:
+          type: array
+          items:
+            type: object
+        ack:
+          $ref: '#/components/schemas/Ack'
+        snooze:
+          $ref: '#/components/schemas/Snooze'
+    RouteOpts:
+      type: object
+      properties:
+        receiver:
+          type: string
+        groupBy:
+          type: array
+          items:
+            type: string
+        groupWait:
+          type: integer
+          description: Duration in nanoseconds.
+        groupInterval:
+          type: integer
+          description: Duration in nanoseconds.
+        repeatInterval:
+          type: integer
+          description: Duration in nanoseconds.
+    AlertSource:
+      type: object
+      properties:
+        name:
+          type: string
+        endsAt:
+          type: string
+          format: date-time
+        timeout:
+          type: boolean
+        updatedAt:
+          type: string
+          format: date-time
+    Ack:
+      type: object
+      properties:
+        id:
+          type: string
+        labels:
+          $ref: '#/components/schemas/LabelSet'
+        createdBy:
+          type: string
+        comment:
+          type: string
+        createdAt:
+          type: string
+          format: date-time
+        expiresAt:
+          type: string
+          format: date-time
+        updatedAt:
+          type: string
+          format: date-time
+    Comment:
+      type: object
+      properties:
+        id:
+          type: string
+        labels:
+          $ref: '#/components/schemas/LabelSet'
+        author:
+          type: string
+        comment:
+          type: string
+        createdAt:
+          type: string
+          format: date-time
+    Snooze:
+      type: object
+      properties:
+        fingerprint:
+          type: string
+        createdBy:
+          type: string
+        comment:
+          type: string
+        endsAt:
+          type: string
+          format: date-time
+        updatedAt:
+          type: string
+          format: date-time
+    Pause:
+      type: object
+      properties:
+        receiver:
+          type: string
+        groupKey:
+          type: integer
+          format: uint64
+        createdBy:
+          type: string
+        comment:
+          type: string
+        endsAt:
+          type: string
+          format: date-time
+        updatedAt:
+          type: string
+          format: date-time
+    Matcher:
+      type: object
+      required: [name, value]
+      properties:
+        name:
+          type: string
+        value:
+          type: string
+        isRegex:
+          type: boolean
+        isAnnotation:
+          type: boolean
+    Silence:
+      type: object
+      required: [matchers, endsAt, createdBy]
+      properties:
+        id:
+          type: string
+          description: Set to update an existing silence.
+        matchers:
+          type: array
+          items:
+            $ref: '#/components/schemas/Matcher'
+        groupKey:
+          type: string
+        startsAt:
+          type: string
+          format: date-time
+        endsAt:
+          type: string
+          format: date-time
+        updatedAt:
+          type: string
+          format: date-time
+          readOnly: true
+        createdBy:
+          type: string
+        comment:
+          type: string
+        approvalRequired:
+          type: boolean
+          readOnly: true
+        approvedBy:
+          type: string
+          readOnly: true
+        soft:
+          type: boolean
+        mutedAlerts:
+          type: integer
+          readOnly: true
+        lastMatchedAt:
+          type: string
+          format: date-time
+          readOnly: true
+    Receiver:
+      type: object
+      properties:
+        name:
+          type: string
+        dryRun:
+          type: boolean
+        integrations:
+          type: array
+          items:
+            $ref: '#/components/schemas/Integration'
+    Integration:
+      type: object
+      properties:
+        type:
+          type: string
+          enum: [webhook, email, pagerduty, opsgenie, slack, hipchat, victorops, pushover]
+        index:
+          type: integer
+          description: The position among the receiver's integrations of the same type.
+        sendResolved:
+          type: boolean
+        settings:
+          type: object
+          description: >
+            The settings of the configuration file. Secrets and passwords in
+            URLs are replaced by "<hidden>".
+    TestNotification:
+      type: object
+      properties:
+        labels:
+          $ref: '#/components/schemas/LabelSet'
+        annotations:
+          $ref: '#/components/schemas/LabelSet'
+    TestNotificationResult:
+      type: object
+      properties:
+        receiver:
+          type: string
+        alert:
+          $ref: '#/components/schemas/PostableAlert'
+        integrations:
+          type: array
+          items:
+            type: object
+            properties:
+              type:
+                type: string
+              index:
+                type: integer
+              success:
+                type: boolean
+              error:
+                type: string
+              reason:
+                type: string
+                description: >
+                  Class of the error: timeout, connection, tls, template,
+                  rate_limited, status_4xx, status_5xx or other.
+    Capture:
+      type: object
+      properties:
+        receiver:
+          type: string
+        enabled:
+          type: boolean
+        requests:
+          type: array
+          items:
+            type: object
+            properties:
+              receiver:
+                type: string
+              integration:
+                type: string
+              index:
+                type: integer
+              time:
+                type: string
+                format: date-time
+              duration:
+                type: number
+                description: Duration of the request in seconds.
+              request:
+                $ref: '#/components/schemas/CapturedMessage'
+              response:
+                $ref: '#/components/schemas/CapturedMessage'
+              error:
+                type: string
+    CapturedMessage:
+      type: object
+      properties:
+        method:
+          type: string
+        url:
+          type: string
+        statusCode:
+          type: integer
+        header:
+          type: object
+          additionalProperties:
+            type: array
+            items:
+              type: string
+        body:
+          type: string
+        truncated:
+          type: boolean
+    Status:
+      type: object
+      properties:
+        config:
+          type: string
+          description: The configuration file with secrets hidden.
+        configJSON:
+          type: object
+        configHash:
+          type: string
+          description: SHA-256 hash of the configuration file.
+        configLoadedAt:
+          type: string
+          format: date-time
+        versionInfo:
+          type: object
+          additionalProperties:
+            type: string
+        uptime:
+          type: string
+          format: date-time
+        peers:
+          type: array
+          items:
+            $ref: '#/components/schemas/Peer'
+        channels:
+          type: array
+          items:
+            $ref: '#/components/schemas/GossipChannel'
+        alerts:
+          type: object
+          description: Number of alerts by state.
+          additionalProperties:
+            type: integer
+        silences:
+          type: object
+          description: Number of silences by state.
+          additionalProperties:
+            type: integer
+        lastNotifications:
+          type: object
+          description: >
+            Time of the last successful notification by receiver, null if
+            the receiver was not notified yet.
+          additionalProperties:
+            type: string
+            format: date-time
+            nullable: true
+        alertRetention:
+          type: object
+          description: How long resolved alerts are kept.
+          properties:
+            resolved:
+              type: string
+              description: Time resolved alerts are kept after they ended, e.g. 24h0m0s.
+            gcInterval:
+              type: string
+              description: Interval at which resolved alerts past their retention are removed.
+    Peer:
+      type: object
+      properties:
+        name:
+          type: string
+        nickName:
+          type: string
+        address:
+          type: string
+        self:
+          type: boolean
+        healthy:
+          type: boolean
+          description: True if the peer is this Alertmanager or connected to it.
+        region:
+          type: string
+        lastGossip:
+          type: string
+          format: date-time
+          nullable: true
+          description: Time of the last gossip message broadcast by the peer.
+    GossipChannel:
+      type: object
+      properties:
+        name:
+          type: string
+        lastGossip:
+          type: string
+          format: date-time
+        lag:
+          type: number
+          description: Seconds since gossip was last received on the channel.
+`
@@ -0,0 +1,122 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/common/route"
+)
+
+func (api *API) addPause(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Receiver  string    `json:"receiver"`
+		GroupKey  uint64    `json:"groupKey"`
+		EndsAt    time.Time `json:"endsAt"`
+		Duration  string    `json:"duration"`
+		CreatedBy string    `json:"createdBy"`
+		Comment   string    `json:"comment"`
+	}
+	if err := receive(r, &req); err != nil {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+	api.setUser(r, &req.CreatedBy)
+	if req.EndsAt.IsZero() == (req.Duration == "") {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: fmt.Errorf("either endsAt or duration must be set"),
+		}, nil)
+		return
+	}
+	if !api.hasReceiver(req.Receiver) {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: fmt.Errorf("unknown receiver %q", req.Receiver),
+		}, nil)
+		return
+	}
+
+	if req.Duration != "" {
+		d, err := time.ParseDuration(req.Duration)
+		if err != nil {
+			respondError(w, apiError{
+				typ: errorBadData,
+				err: err,
+			}, nil)
+			return
+		}
+		req.EndsAt = time.Now().Add(d)
+	}
+
+	p, err := api.pauses.Pause(req.Receiver, req.GroupKey, req.EndsAt, req.CreatedBy, req.Comment)
+	if err != nil {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+	respond(w, p)
+}
+
+// hasReceiver returns whether a receiver with the given name is configured.
+func (api *API) hasReceiver(name string) bool {
+	api.mtx.RLock()
+	defer api.mtx.RUnlock()
+
+	for _, rc := range api.configJSON.Receivers {
+		if rc.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (api *API) listPauses(w http.ResponseWriter, r *http.Request) {
+	respond(w, api.pauses.List())
+}
+
+// delPause resumes notifications to a receiver. If the "groupKey" query
+// parameter is set, the pause of the aggregation group with that key is
+// ended instead.
+func (api *API) delPause(w http.ResponseWriter, r *http.Request) {
+	receiver := route.Param(api.context(r), "receiver")
+
+	var gkey uint64
+	if s := r.URL.Query().Get("groupKey"); s != "" {
+		var err error
+		if gkey, err = strconv.ParseUint(s, 10, 64); err != nil {
+			respondError(w, apiError{
+				typ: errorBadData,
+				err: fmt.Errorf("invalid group key %q: %s", s, err),
+			}, nil)
+			return
+		}
+	}
+	if err := api.pauses.Resume(receiver, gkey); err != nil {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+	respond(w, nil)
+}
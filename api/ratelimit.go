@@ -0,0 +1,160 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/prometheus/alertmanager/auth"
+	"github.com/prometheus/alertmanager/config"
+)
+
+var numRateLimited = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "alertmanager",
+	Name:      "api_requests_rate_limited_total",
+	Help:      "The total number of API requests rejected by rate limits.",
+}, []string{"limit"})
+
+func init() {
+	prometheus.Register(numRateLimited)
+}
+
+// rateLimiter holds a token bucket per client.
+type rateLimiter struct {
+	rate  float64
+	burst float64
+	now   func() time.Time
+
+	mtx     sync.Mutex
+	buckets map[string]*bucket
+	lastGC  time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(c *config.RateLimit) *rateLimiter {
+	return &rateLimiter{
+		rate:    c.Rate,
+		burst:   float64(c.Burst),
+		now:     time.Now,
+		buckets: map[string]*bucket{},
+	}
+}
+
+// updateRateLimiter returns a limiter for the configuration. The given
+// limiter is kept if its configuration did not change so that clients are
+// not reset by configuration reloads. It returns nil if c is nil.
+func updateRateLimiter(l *rateLimiter, c *config.RateLimit) *rateLimiter {
+	if c == nil {
+		return nil
+	}
+	if l != nil && l.rate == c.Rate && l.burst == float64(c.Burst) {
+		return l
+	}
+	return newRateLimiter(c)
+}
+
+// allow takes a token from the client's bucket. If the bucket is empty, it
+// returns false and the time until a token is available.
+func (l *rateLimiter) allow(client string) (bool, time.Duration) {
+	now := l.now()
+
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	l.gc(now)
+
+	b, ok := l.buckets[client]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[client] = b
+	}
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+	}
+	b.tokens--
+	return true, 0
+}
+
+// gc removes the buckets that are full again at most once per minute.
+// The caller must hold the lock.
+func (l *rateLimiter) gc(now time.Time) {
+	if now.Sub(l.lastGC) < time.Minute {
+		return
+	}
+	l.lastGC = now
+
+	refill := time.Duration(l.burst / l.rate * float64(time.Second))
+	for c, b := range l.buckets {
+		if now.Sub(b.last) >= refill {
+			delete(l.buckets, c)
+		}
+	}
+}
+
+// rateLimited serves requests with the given handler unless the client
+// exceeded the rate limit of the given name.
+func (api *API) rateLimited(name string, f http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		api.mtx.RLock()
+		l := api.rateLimiters[name]
+		api.mtx.RUnlock()
+
+		if l == nil {
+			f(w, r)
+			return
+		}
+		client := api.rateLimitClient(r)
+		if ok, wait := l.allow(client); !ok {
+			numRateLimited.WithLabelValues(name).Inc()
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			respondError(w, apiError{
+				typ: errorRateLimited,
+				err: fmt.Errorf("rate limit for %s exceeded by %s", name, client),
+			}, nil)
+			return
+		}
+		f(w, r)
+	}
+}
+
+// rateLimitClient identifies the client of a request by its API key or its
+// IP address.
+func (api *API) rateLimitClient(r *http.Request) string {
+	ctx := api.context(r)
+	if _, ok := auth.Scope(ctx); ok {
+		if user, ok := auth.User(ctx); ok {
+			return user
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
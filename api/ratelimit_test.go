@@ -0,0 +1,108 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+
+	"github.com/prometheus/alertmanager/auth"
+	"github.com/prometheus/alertmanager/config"
+)
+
+func TestRateLimiter(t *testing.T) {
+	l := newRateLimiter(&config.RateLimit{Rate: 2, Burst: 3})
+	now := time.Now()
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		ok, _ := l.allow("a")
+		require.True(t, ok)
+	}
+	ok, wait := l.allow("a")
+	require.False(t, ok)
+	require.Equal(t, 500*time.Millisecond, wait)
+
+	// Clients have separate buckets.
+	ok, _ = l.allow("b")
+	require.True(t, ok)
+
+	now = now.Add(500 * time.Millisecond)
+	ok, _ = l.allow("a")
+	require.True(t, ok)
+	ok, _ = l.allow("a")
+	require.False(t, ok)
+
+	// Full buckets are garbage collected.
+	now = now.Add(time.Minute)
+	l.allow("c")
+	require.Len(t, l.buckets, 1)
+
+	require.True(t, l == updateRateLimiter(l, &config.RateLimit{Rate: 2, Burst: 3}))
+	require.False(t, l == updateRateLimiter(l, &config.RateLimit{Rate: 1, Burst: 3}))
+	require.Nil(t, updateRateLimiter(l, nil))
+}
+
+func TestRateLimited(t *testing.T) {
+	api := New(nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+	require.NoError(t, api.Update(`
+route:
+  receiver: default
+receivers:
+- name: default
+rate_limits:
+  alerts:
+    rate: 0.001
+    burst: 1
+`, time.Minute))
+
+	ctx := context.Background()
+	api.context = func(*http.Request) context.Context { return ctx }
+	h := api.rateLimited("alerts", func(w http.ResponseWriter, r *http.Request) {})
+
+	serve := func(remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/alerts", nil)
+		req.RemoteAddr = remoteAddr
+		rec := httptest.NewRecorder()
+		h(rec, req)
+		return rec
+	}
+
+	require.Equal(t, http.StatusOK, serve("10.0.0.1:1234").Code)
+	rec := serve("10.0.0.1:4321")
+	require.Equal(t, http.StatusTooManyRequests, rec.Code)
+	require.Equal(t, "1000", rec.Header().Get("Retry-After"))
+	require.Equal(t, http.StatusOK, serve("10.0.0.2:1234").Code)
+
+	// Requests with API keys are limited per key.
+	ctx = auth.WithScope(auth.WithUser(ctx, "apikey:ci"), nil)
+	require.Equal(t, http.StatusOK, serve("10.0.0.1:1234").Code)
+	require.Equal(t, http.StatusTooManyRequests, serve("10.0.0.2:1234").Code)
+
+	// Limits without configuration do not apply.
+	h = api.rateLimited("silences", func(w http.ResponseWriter, r *http.Request) {})
+	for i := 0; i < 10; i++ {
+		require.Equal(t, http.StatusOK, serve("10.0.0.1:1234").Code)
+	}
+
+	// Reloading an unchanged configuration keeps the state of clients.
+	require.NoError(t, api.Update(api.config, time.Minute))
+	h = api.rateLimited("alerts", func(w http.ResponseWriter, r *http.Request) {})
+	require.Equal(t, http.StatusTooManyRequests, serve("10.0.0.3:1234").Code)
+}
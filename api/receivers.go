@@ -0,0 +1,295 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/common/route"
+	"golang.org/x/net/context"
+
+	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/notify"
+	"github.com/prometheus/alertmanager/types"
+)
+
+// defaultTestTimeout is the time after which sending a test notification is
+// given up if the request does not specify a timeout.
+const defaultTestTimeout = 30 * time.Second
+
+// receiverInfo describes a configured receiver.
+type receiverInfo struct {
+	Name         string             `json:"name"`
+	DryRun       bool               `json:"dryRun,omitempty"`
+	Integrations []*integrationInfo `json:"integrations"`
+}
+
+// integrationInfo describes an integration of a receiver. Its settings are
+// those of the configuration file with secrets and URL passwords hidden.
+type integrationInfo struct {
+	Type         string                 `json:"type"`
+	Index        int                    `json:"index"`
+	SendResolved bool                   `json:"sendResolved"`
+	Settings     map[string]interface{} `json:"settings"`
+}
+
+// receivers returns the configured receivers and their integrations.
+func (api *API) receivers(w http.ResponseWriter, r *http.Request) {
+	api.mtx.RLock()
+	defer api.mtx.RUnlock()
+
+	res := make([]*receiverInfo, 0, len(api.configJSON.Receivers))
+	for _, rc := range api.configJSON.Receivers {
+		info, err := newReceiverInfo(rc)
+		if err != nil {
+			respondError(w, apiError{
+				typ: errorInternal,
+				err: err,
+			}, nil)
+			return
+		}
+		res = append(res, info)
+	}
+	respond(w, res)
+}
+
+func newReceiverInfo(rc *config.Receiver) (*receiverInfo, error) {
+	type integrationConfig struct {
+		typ  string
+		idx  int
+		conf interface {
+			SendResolved() bool
+		}
+	}
+	// Integrations are listed in the same order as they are built for
+	// notifications.
+	var ics []integrationConfig
+	for i, c := range rc.WebhookConfigs {
+		ics = append(ics, integrationConfig{"webhook", i, c})
+	}
+	for i, c := range rc.EmailConfigs {
+		ics = append(ics, integrationConfig{"email", i, c})
+	}
+	for i, c := range rc.PagerdutyConfigs {
+		ics = append(ics, integrationConfig{"pagerduty", i, c})
+	}
+	for i, c := range rc.OpsGenieConfigs {
+		ics = append(ics, integrationConfig{"opsgenie", i, c})
+	}
+	for i, c := range rc.SlackConfigs {
+		ics = append(ics, integrationConfig{"slack", i, c})
+	}
+	for i, c := range rc.HipchatConfigs {
+		ics = append(ics, integrationConfig{"hipchat", i, c})
+	}
+	for i, c := range rc.VictorOpsConfigs {
+		ics = append(ics, integrationConfig{"victorops", i, c})
+	}
+	for i, c := range rc.PushoverConfigs {
+		ics = append(ics, integrationConfig{"pushover", i, c})
+	}
+
+	info := &receiverInfo{
+		Name:         rc.Name,
+		DryRun:       rc.DryRun,
+		Integrations: make([]*integrationInfo, 0, len(ics)),
+	}
+	for _, ic := range ics {
+		settings, err := sanitizedSettings(ic.conf)
+		if err != nil {
+			return nil, err
+		}
+		info.Integrations = append(info.Integrations, &integrationInfo{
+			Type:         ic.typ,
+			Index:        ic.idx,
+			SendResolved: ic.conf.SendResolved(),
+			Settings:     settings,
+		})
+	}
+	return info, nil
+}
+
+// sanitizedSettings returns the JSON representation of an integration
+// configuration as a map. Secrets are hidden by their JSON encoding,
+// passwords in URLs are hidden here.
+func sanitizedSettings(c interface{}) (map[string]interface{}, error) {
+	b, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	var settings map[string]interface{}
+	if err := json.Unmarshal(b, &settings); err != nil {
+		return nil, err
+	}
+	delete(settings, "send_resolved")
+
+	for k, v := range settings {
+		s, ok := v.(string)
+		if !ok {
+			continue
+		}
+		u, err := url.Parse(s)
+		if err != nil || u.User == nil {
+			continue
+		}
+		pw, ok := u.User.Password()
+		if !ok {
+			continue
+		}
+		if strings.Contains(s, ":"+pw+"@") {
+			settings[k] = strings.Replace(s, ":"+pw+"@", ":<hidden>@", 1)
+		} else {
+			// The password is escaped in the URL.
+			u.User = url.UserPassword(u.User.Username(), "hidden")
+			settings[k] = u.String()
+		}
+	}
+	return settings, nil
+}
+
+// testNotificationRequest optionally customizes the synthetic alert of a test
+// notification. The labels and annotations are added to the default ones.
+type testNotificationRequest struct {
+	Labels      model.LabelSet `json:"labels"`
+	Annotations model.LabelSet `json:"annotations"`
+}
+
+// testNotificationResult is the outcome of a test notification.
+type testNotificationResult struct {
+	Receiver     string               `json:"receiver"`
+	Alert        *model.Alert         `json:"alert"`
+	Integrations []*integrationResult `json:"integrations"`
+}
+
+// integrationResult is the outcome of a test notification sent via a single
+// integration.
+type integrationResult struct {
+	Type    string `json:"type"`
+	Index   int    `json:"index"`
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+	// Reason classifies the error, e.g. timeout or status_5xx.
+	Reason string `json:"reason,omitempty"`
+}
+
+// testReceiver sends a synthetic alert via all integrations of a receiver.
+func (api *API) testReceiver(w http.ResponseWriter, r *http.Request) {
+	name := route.Param(api.context(r), "name")
+
+	var req testNotificationRequest
+	if err := receive(r, &req); err != nil && err != io.EOF {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+
+	timeout := defaultTestTimeout
+	if s := r.URL.Query().Get("timeout"); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil || d <= 0 {
+			respondError(w, apiError{
+				typ: errorBadData,
+				err: fmt.Errorf("invalid timeout %q", s),
+			}, nil)
+			return
+		}
+		timeout = d
+	}
+
+	api.mtx.RLock()
+	var rc *config.Receiver
+	for _, c := range api.receiverConfs {
+		if c.Name == name {
+			rc = c
+			break
+		}
+	}
+	tmpl := api.tmpl
+	api.mtx.RUnlock()
+
+	if rc == nil {
+		http.Error(w, fmt.Sprintf("receiver %q not found", name), http.StatusNotFound)
+		return
+	}
+	if tmpl == nil {
+		respondError(w, apiError{
+			typ: errorInternal,
+			err: fmt.Errorf("notification templates not loaded"),
+		}, nil)
+		return
+	}
+
+	alert := newTestAlert(name, req, time.Now())
+	if err := alert.Validate(); err != nil {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	res := &testNotificationResult{
+		Receiver:     name,
+		Alert:        alert,
+		Integrations: []*integrationResult{},
+	}
+	for _, ir := range notify.SendTestNotification(ctx, rc, tmpl, &types.Alert{Alert: *alert, UpdatedAt: alert.StartsAt}) {
+		info := &integrationResult{
+			Type:    ir.Integration,
+			Index:   ir.Index,
+			Success: ir.Error == nil,
+		}
+		if ir.Error != nil {
+			info.Error = ir.Error.Error()
+			info.Reason = notify.FailureReason(ir.Error)
+		}
+		res.Integrations = append(res.Integrations, info)
+	}
+	respond(w, res)
+}
+
+// newTestAlert returns the firing alert sent as a test notification to the
+// receiver.
+func newTestAlert(receiver string, req testNotificationRequest, now time.Time) *model.Alert {
+	a := &model.Alert{
+		Labels: model.LabelSet{
+			model.AlertNameLabel: "TestNotification",
+			"receiver":           model.LabelValue(receiver),
+		},
+		Annotations: model.LabelSet{
+			"summary":     "Test notification",
+			"description": model.LabelValue(fmt.Sprintf("Test notification sent to receiver %s. It can be ignored.", receiver)),
+		},
+		StartsAt: now,
+	}
+	for ln, lv := range req.Labels {
+		a.Labels[ln] = lv
+	}
+	for ln, lv := range req.Annotations {
+		a.Annotations[ln] = lv
+	}
+	return a
+}
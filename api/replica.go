@@ -0,0 +1,74 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"errors"
+	"net/http"
+)
+
+// queryHandlers are the handlers of POST requests that do not change state
+// and are served by read replicas.
+var queryHandlers = map[string]bool{
+	"render_template": true,
+}
+
+// ingestHandlers are the handlers receiving alerts. Read replicas accept
+// alerts like any other instance, as alerts are not replicated through the
+// mesh.
+var ingestHandlers = map[string]bool{
+	"legacy_add_alerts":  true,
+	"add_alerts":         true,
+	"add_alerts_bulk":    true,
+	"ingest_alerts":      true,
+	"v2_add_alerts":      true,
+	"v2_add_alerts_bulk": true,
+}
+
+// SetReadOnly sets whether the API rejects requests changing state, as it
+// does on read replicas. Their state is replicated from other instances, to
+// which changes have to be sent.
+func (api *API) SetReadOnly(readOnly bool) {
+	api.mtx.Lock()
+	defer api.mtx.Unlock()
+
+	api.readOnly = readOnly
+}
+
+// writable rejects requests that may change state if the API is read-only.
+// Alerts are accepted nonetheless.
+func (api *API) writable(name string, f http.HandlerFunc) http.HandlerFunc {
+	if queryHandlers[name] || ingestHandlers[name] {
+		return f
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET", "HEAD", "OPTIONS":
+			f(w, r)
+			return
+		}
+		api.mtx.RLock()
+		readOnly := api.readOnly
+		api.mtx.RUnlock()
+
+		if readOnly {
+			respondError(w, apiError{
+				typ: errorForbidden,
+				err: errors.New("read replicas do not accept changes, send them to a primary instance"),
+			}, nil)
+			return
+		}
+		f(w, r)
+	}
+}
@@ -0,0 +1,50 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadOnly(t *testing.T) {
+	api := &API{}
+	ok := func(w http.ResponseWriter, r *http.Request) {}
+
+	for _, c := range []struct {
+		name, method string
+		readOnly     bool
+		code         int
+	}{
+		{"add_silence", "POST", false, http.StatusOK},
+		{"add_silence", "POST", true, http.StatusForbidden},
+		{"del_silence", "DELETE", true, http.StatusForbidden},
+		{"list_silences", "GET", true, http.StatusOK},
+		{"render_template", "POST", true, http.StatusOK},
+		{"add_alerts", "POST", true, http.StatusOK},
+		{"v2_add_alerts", "POST", true, http.StatusOK},
+		{"ingest_alerts", "POST", true, http.StatusOK},
+		{"silence_link", "GET", true, http.StatusOK},
+		{"confirm_silence_link", "POST", true, http.StatusForbidden},
+	} {
+		api.SetReadOnly(c.readOnly)
+
+		w := httptest.NewRecorder()
+		api.writable(c.name, ok)(w, httptest.NewRequest(c.method, "/", nil))
+		require.Equal(t, c.code, w.Code, "%s %s", c.method, c.name)
+	}
+}
@@ -0,0 +1,220 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+
+	"github.com/prometheus/alertmanager/silence"
+	"github.com/prometheus/alertmanager/silence/silencepb"
+	"github.com/prometheus/alertmanager/types"
+)
+
+// silenceFilter holds the parameters by which silence listings are
+// filtered and paginated.
+type silenceFilter struct {
+	states    []silence.SilenceState
+	createdBy string
+	matchers  []*types.Matcher
+
+	createdAfter, createdBefore time.Time
+	endsAfter, endsBefore       time.Time
+
+	offset, limit int
+}
+
+// parseSilenceFilter reads a silenceFilter from the query parameters of
+// a request. Supported parameters are:
+//
+//	state          active, pending or expired (may be repeated)
+//	createdBy      creator of the silence
+//	matcher        <name>=<value> or <name>=~<regex>, the silence must have
+//	               an identical matcher (may be repeated)
+//	createdAfter   RFC3339 timestamp
+//	createdBefore  RFC3339 timestamp
+//	endsAfter      RFC3339 timestamp
+//	endsBefore     RFC3339 timestamp
+//	offset         number of silences to skip
+//	limit          maximum number of silences to return
+func parseSilenceFilter(q url.Values) (*silenceFilter, error) {
+	f := &silenceFilter{createdBy: q.Get("createdBy")}
+
+	for _, s := range q["state"] {
+		switch st := silence.SilenceState(s); st {
+		case silence.StateActive, silence.StatePending, silence.StateExpired:
+			f.states = append(f.states, st)
+		default:
+			return nil, fmt.Errorf("invalid silence state %q", s)
+		}
+	}
+	for _, s := range q["matcher"] {
+		m, err := parseMatcher(s)
+		if err != nil {
+			return nil, err
+		}
+		f.matchers = append(f.matchers, m)
+	}
+
+	err := parseTimeParams(q, map[string]*time.Time{
+		"createdAfter":  &f.createdAfter,
+		"createdBefore": &f.createdBefore,
+		"endsAfter":     &f.endsAfter,
+		"endsBefore":    &f.endsBefore,
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = parseIntParams(q, map[string]*int{
+		"offset": &f.offset,
+		"limit":  &f.limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// parseTimeParams sets the given times from the RFC3339 timestamps in the
+// query parameters of the same name. Missing parameters are skipped.
+func parseTimeParams(q url.Values, ts map[string]*time.Time) error {
+	for name, t := range ts {
+		s := q.Get(name)
+		if s == "" {
+			continue
+		}
+		v, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %s", name, err)
+		}
+		*t = v
+	}
+	return nil
+}
+
+// parseIntParams sets the given integers from the non-negative numbers in
+// the query parameters of the same name. Missing parameters are skipped.
+func parseIntParams(q url.Values, ns map[string]*int) error {
+	for name, n := range ns {
+		s := q.Get(name)
+		if s == "" {
+			continue
+		}
+		v, err := strconv.Atoi(s)
+		if err != nil || v < 0 {
+			return fmt.Errorf("invalid %s %q", name, s)
+		}
+		*n = v
+	}
+	return nil
+}
+
+// parseMatcher parses a matcher of the form <name>=<value> or <name>=~<regex>.
+func parseMatcher(s string) (*types.Matcher, error) {
+	i := strings.Index(s, "=")
+	if i < 0 {
+		return nil, fmt.Errorf("invalid matcher %q", s)
+	}
+	m := &types.Matcher{Name: s[:i], Value: s[i+1:]}
+	if strings.HasPrefix(m.Value, "~") {
+		m.Value = m.Value[1:]
+		m.IsRegex = true
+	}
+	if err := m.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid matcher %q: %s", s, err)
+	}
+	return m, nil
+}
+
+// queryParams returns the silence query parameters for the filter.
+func (f *silenceFilter) queryParams() []silence.QueryParam {
+	if len(f.states) == 0 {
+		return nil
+	}
+	return []silence.QueryParam{silence.QState(f.states...)}
+}
+
+// match returns true iff the silence passes the filter.
+func (f *silenceFilter) match(ps *silencepb.Silence, s *types.Silence) bool {
+	if f.createdBy != "" && s.CreatedBy != f.createdBy {
+		return false
+	}
+	for _, m := range f.matchers {
+		found := false
+		for _, sm := range s.Matchers {
+			if sm.Name == m.Name && sm.Value == m.Value && sm.IsRegex == m.IsRegex {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if !f.createdAfter.IsZero() || !f.createdBefore.IsZero() {
+		if len(ps.Comments) == 0 {
+			return false
+		}
+		created, err := ptypes.Timestamp(ps.Comments[0].Timestamp)
+		if err != nil {
+			return false
+		}
+		if !f.createdAfter.IsZero() && created.Before(f.createdAfter) {
+			return false
+		}
+		if !f.createdBefore.IsZero() && created.After(f.createdBefore) {
+			return false
+		}
+	}
+	if !f.endsAfter.IsZero() && s.EndsAt.Before(f.endsAfter) {
+		return false
+	}
+	if !f.endsBefore.IsZero() && s.EndsAt.After(f.endsBefore) {
+		return false
+	}
+	return true
+}
+
+// paginate sorts the silences by start time and ID and returns the
+// requested page.
+func (f *silenceFilter) paginate(sils []*types.Silence) []*types.Silence {
+	sort.Sort(silencesByStart(sils))
+
+	if f.offset >= len(sils) {
+		return nil
+	}
+	sils = sils[f.offset:]
+	if f.limit > 0 && f.limit < len(sils) {
+		sils = sils[:f.limit]
+	}
+	return sils
+}
+
+type silencesByStart []*types.Silence
+
+func (s silencesByStart) Len() int      { return len(s) }
+func (s silencesByStart) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+
+func (s silencesByStart) Less(i, j int) bool {
+	if !s[i].StartsAt.Equal(s[j].StartsAt) {
+		return s[i].StartsAt.Before(s[j].StartsAt)
+	}
+	return s[i].ID < s[j].ID
+}
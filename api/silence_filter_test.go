@@ -0,0 +1,104 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/stretchr/testify/require"
+
+	"github.com/prometheus/alertmanager/silence/silencepb"
+	"github.com/prometheus/alertmanager/types"
+)
+
+func TestParseSilenceFilter(t *testing.T) {
+	for _, q := range []string{
+		"state=unknown",
+		"matcher=job",
+		"matcher=job=~(",
+		"createdAfter=yesterday",
+		"limit=-1",
+		"offset=abc",
+	} {
+		v, err := url.ParseQuery(q)
+		require.NoError(t, err)
+
+		_, err = parseSilenceFilter(v)
+		require.Error(t, err, q)
+	}
+
+	v, err := url.ParseQuery("state=active&state=pending&matcher=job=~web.*&limit=10")
+	require.NoError(t, err)
+
+	f, err := parseSilenceFilter(v)
+	require.NoError(t, err)
+	require.Len(t, f.states, 2)
+	require.Equal(t, []*types.Matcher{{Name: "job", Value: "web.*", IsRegex: true}}, f.matchers)
+	require.Equal(t, 10, f.limit)
+}
+
+func TestSilenceFilterMatch(t *testing.T) {
+	now := time.Now().UTC()
+
+	created, err := ptypes.TimestampProto(now.Add(-time.Hour))
+	require.NoError(t, err)
+
+	ps := &silencepb.Silence{
+		Comments: []*silencepb.Comment{{Timestamp: created}},
+	}
+	s := &types.Silence{
+		Matchers:  types.Matchers{{Name: "job", Value: "web"}},
+		EndsAt:    now.Add(time.Hour),
+		CreatedBy: "alice",
+	}
+
+	cases := []struct {
+		filter silenceFilter
+		match  bool
+	}{
+		{filter: silenceFilter{}, match: true},
+		{filter: silenceFilter{createdBy: "alice"}, match: true},
+		{filter: silenceFilter{createdBy: "bob"}, match: false},
+		{filter: silenceFilter{matchers: []*types.Matcher{{Name: "job", Value: "web"}}}, match: true},
+		{filter: silenceFilter{matchers: []*types.Matcher{{Name: "job", Value: "web", IsRegex: true}}}, match: false},
+		{filter: silenceFilter{createdAfter: now.Add(-2 * time.Hour)}, match: true},
+		{filter: silenceFilter{createdBefore: now.Add(-2 * time.Hour)}, match: false},
+		{filter: silenceFilter{endsAfter: now, endsBefore: now.Add(2 * time.Hour)}, match: true},
+		{filter: silenceFilter{endsBefore: now}, match: false},
+	}
+	for i, c := range cases {
+		require.Equal(t, c.match, c.filter.match(ps, s), "case %d", i)
+	}
+}
+
+func TestSilenceFilterPaginate(t *testing.T) {
+	now := time.Now()
+
+	var sils []*types.Silence
+	for _, id := range []string{"c", "b", "a", "d"} {
+		sils = append(sils, &types.Silence{ID: id, StartsAt: now})
+	}
+
+	f := &silenceFilter{offset: 1, limit: 2}
+	res := f.paginate(sils)
+	require.Len(t, res, 2)
+	require.Equal(t, "b", res[0].ID)
+	require.Equal(t, "c", res[1].ID)
+
+	f = &silenceFilter{offset: 4}
+	require.Len(t, f.paginate(sils), 0)
+}
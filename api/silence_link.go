@@ -0,0 +1,151 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/prometheus/common/model"
+
+	"github.com/prometheus/alertmanager/silence/link"
+	"github.com/prometheus/alertmanager/types"
+)
+
+// silenceLinkAuthor is the creator of silences created through links.
+const silenceLinkAuthor = "silence-link"
+
+// silenceFromLink returns the silence described by a verified link.
+func silenceFromLink(l *link.Link, now time.Time) *types.Silence {
+	sil := &types.Silence{
+		StartsAt:  now,
+		EndsAt:    now.Add(l.Duration),
+		CreatedBy: silenceLinkAuthor,
+		Comment:   "Created through a link in a notification",
+	}
+	for ln, lv := range l.Labels {
+		sil.Matchers = append(sil.Matchers, types.NewMatcher(ln, string(lv)))
+	}
+	sort.Sort(sil.Matchers)
+	return sil
+}
+
+// silenceLinkPage asks to confirm the silence of a link. Visiting a link
+// must not create the silence by itself, as links are followed by mail
+// scanners and link previews, so the page posts the link back to create it.
+var silenceLinkPage = htmltemplate.Must(htmltemplate.New("link").Parse(`<!DOCTYPE html>
+<html>
+<head><title>Create silence</title></head>
+<body>
+<h1>Create silence</h1>
+<p>Silence all alerts matching the following labels for {{ .Duration }}?</p>
+<ul>
+{{ range .Matchers }}<li>{{ .Name }}="{{ .Value }}"</li>
+{{ end }}</ul>
+<form method="post">
+<button type="submit">Create silence</button>
+</form>
+</body>
+</html>
+`))
+
+// silenceLink renders a page confirming the silence described by a signed
+// link. The silence is created by posting the link with confirmSilenceLink.
+func (api *API) silenceLink(w http.ResponseWriter, r *http.Request) {
+	l, ok := api.verifySilenceLink(w, r)
+	if !ok {
+		return
+	}
+	sil := silenceFromLink(l, time.Now())
+
+	var buf bytes.Buffer
+	if err := silenceLinkPage.Execute(&buf, struct {
+		Duration model.Duration
+		Matchers types.Matchers
+	}{
+		Duration: model.Duration(l.Duration),
+		Matchers: sil.Matchers,
+	}); err != nil {
+		respondError(w, apiError{
+			typ: errorInternal,
+			err: err,
+		}, nil)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(buf.Bytes())
+}
+
+// confirmSilenceLink creates the silence described by a signed link like
+// any other silence. If an existing silence already mutes all alerts of the
+// link, e.g. because the link was confirmed before, its ID is returned
+// instead.
+func (api *API) confirmSilenceLink(w http.ResponseWriter, r *http.Request) {
+	l, ok := api.verifySilenceLink(w, r)
+	if !ok {
+		return
+	}
+	sil := silenceFromLink(l, time.Now())
+
+	overlaps, err := api.overlappingSilences(sil)
+	if err != nil {
+		respondError(w, apiError{
+			typ: errorInternal,
+			err: err,
+		}, nil)
+		return
+	}
+	if len(overlaps) > 0 {
+		respond(w, struct {
+			SilenceID string `json:"silenceId"`
+		}{
+			SilenceID: overlaps[0].ID,
+		})
+		return
+	}
+	api.createSilence(w, r, sil)
+}
+
+// verifySilenceLink returns the link described by the query of the request.
+// It responds with an error if silence links are not configured or the link
+// is invalid.
+func (api *API) verifySilenceLink(w http.ResponseWriter, r *http.Request) (*link.Link, bool) {
+	api.mtx.RLock()
+	conf := api.configJSON.SilenceLinks
+	api.mtx.RUnlock()
+
+	if conf == nil {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: errors.New("silence links are not configured"),
+		}, nil)
+		return nil, false
+	}
+	signer := link.NewSigner(string(conf.Secret), time.Duration(conf.Duration), time.Duration(conf.Validity))
+
+	l, err := signer.Verify(r.URL.Query())
+	if err != nil {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: fmt.Errorf("invalid silence link: %s", err),
+		}, nil)
+		return nil, false
+	}
+	return l, true
+}
@@ -0,0 +1,113 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+
+	"github.com/prometheus/alertmanager/auth"
+	"github.com/prometheus/alertmanager/provider/mem"
+	"github.com/prometheus/alertmanager/silence"
+	"github.com/prometheus/alertmanager/silence/link"
+)
+
+func TestSilenceLink(t *testing.T) {
+	alerts, err := mem.NewAlerts("")
+	require.NoError(t, err)
+	defer alerts.Close()
+
+	sils, err := silence.New(silence.Options{})
+	require.NoError(t, err)
+
+	api := New(alerts, sils, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+	api.context = func(*http.Request) context.Context {
+		return auth.WithUser(context.Background(), "alice")
+	}
+	conf := `
+route:
+  receiver: default
+receivers:
+- name: default
+silence_links:
+  secret: secret
+`
+	require.NoError(t, api.Update(conf, time.Minute))
+
+	u, err := link.NewSigner("secret", time.Hour, time.Hour).URL(&url.URL{Scheme: "http", Host: "am"}, model.LabelSet{"alertname": "<script>"})
+	require.NoError(t, err)
+
+	serve := func(h http.HandlerFunc, method, url string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h(rec, httptest.NewRequest(method, url, nil))
+		return rec
+	}
+	count := func() int {
+		psils, err := sils.Query()
+		require.NoError(t, err)
+		return len(psils)
+	}
+
+	// Visiting the link only asks for confirmation.
+	rec := serve(api.silenceLink, "GET", u)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.Contains(t, rec.Header().Get("Content-Type"), "text/html")
+	require.Contains(t, rec.Body.String(), `<form method="post">`)
+	require.Contains(t, rec.Body.String(), "&lt;script&gt;")
+	require.Equal(t, 0, count())
+
+	rec = serve(api.silenceLink, "GET", u+"x")
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+
+	var res struct {
+		Data struct {
+			SilenceID string `json:"silenceId"`
+		} `json:"data"`
+	}
+	rec = serve(api.confirmSilenceLink, "POST", u)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&res))
+	sid := res.Data.SilenceID
+
+	psils, err := sils.Query(silence.QIDs(sid))
+	require.NoError(t, err)
+	require.Len(t, psils, 1)
+	require.Equal(t, "alice", psils[0].Comments[0].Author)
+
+	// Confirming the link again returns the existing silence.
+	rec = serve(api.confirmSilenceLink, "POST", u)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&res))
+	require.Equal(t, sid, res.Data.SilenceID)
+	require.Equal(t, 1, count())
+
+	// Silences created through links are subject to the silence policy.
+	require.NoError(t, sils.Expire(sid))
+	require.NoError(t, api.Update(conf+`
+silence_policy:
+  comment:
+    regex: '.*OPS-[0-9]+.*'
+`, time.Minute))
+	rec = serve(api.confirmSilenceLink, "POST", u)
+	require.Equal(t, http.StatusBadRequest, rec.Code, rec.Body.String())
+	require.Equal(t, 1, count())
+}
@@ -0,0 +1,82 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"regexp"
+	"time"
+
+	"github.com/prometheus/alertmanager/types"
+)
+
+// overlappingSilences returns the silences that overlap in time with sil and
+// mute every alert that sil mutes, i.e. that make sil redundant.
+func overlappingSilences(sil *types.Silence, existing []*types.Silence, now time.Time) []*types.Silence {
+	start := sil.StartsAt
+	if start.Before(now) {
+		start = now
+	}
+	var res []*types.Silence
+	for _, s := range existing {
+		if s.ID == sil.ID && s.ID != "" {
+			continue
+		}
+		if !s.StartsAt.Before(sil.EndsAt) || !start.Before(s.EndsAt) {
+			continue
+		}
+		if subsumes(s, sil) {
+			res = append(res, s)
+		}
+	}
+	return res
+}
+
+// subsumes returns true if the silence a mutes every alert that b mutes.
+// It is conservative and may return false for some silences that do.
+func subsumes(a, b *types.Silence) bool {
+	// Soft silences do not mute, they cannot make a regular silence redundant.
+	if a.Soft && !b.Soft {
+		return false
+	}
+	if a.GroupKey != "" && a.GroupKey != b.GroupKey {
+		return false
+	}
+	for _, m := range a.Matchers {
+		if !matcherSubsumes(m, b.Matchers) {
+			return false
+		}
+	}
+	return true
+}
+
+// matcherSubsumes returns true if m matches every label set that is matched
+// by all matchers of ms.
+func matcherSubsumes(m *types.Matcher, ms types.Matchers) bool {
+	for _, o := range ms {
+		if o.Name != m.Name || o.IsAnnotation != m.IsAnnotation {
+			continue
+		}
+		if o.IsRegex == m.IsRegex && o.Value == m.Value {
+			return true
+		}
+		// A regular expression subsumes an equality matcher on a value it matches.
+		if m.IsRegex && !o.IsRegex {
+			re, err := regexp.Compile(m.Value)
+			if err == nil && re.MatchString(o.Value) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,143 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/prometheus/alertmanager/types"
+)
+
+func TestOverlappingSilences(t *testing.T) {
+	now := time.Now()
+
+	sil := &types.Silence{
+		Matchers: types.Matchers{
+			{Name: "alertname", Value: "HighLatency"},
+			{Name: "job", Value: "api"},
+		},
+		StartsAt: now,
+		EndsAt:   now.Add(time.Hour),
+	}
+
+	cases := []struct {
+		existing *types.Silence
+		overlaps bool
+	}{
+		{
+			// Duplicate.
+			existing: &types.Silence{
+				Matchers: sil.Matchers,
+				StartsAt: now.Add(-time.Hour),
+				EndsAt:   now.Add(2 * time.Hour),
+			},
+			overlaps: true,
+		},
+		{
+			// Subset of the matchers mutes more alerts.
+			existing: &types.Silence{
+				Matchers: types.Matchers{{Name: "job", Value: "api"}},
+				StartsAt: now,
+				EndsAt:   now.Add(time.Minute),
+			},
+			overlaps: true,
+		},
+		{
+			existing: &types.Silence{
+				Matchers: types.Matchers{{Name: "job", Value: "api|web", IsRegex: true}},
+				StartsAt: now,
+				EndsAt:   now.Add(time.Hour),
+			},
+			overlaps: true,
+		},
+		{
+			existing: &types.Silence{
+				Matchers: types.Matchers{{Name: "job", Value: "web"}},
+				StartsAt: now,
+				EndsAt:   now.Add(time.Hour),
+			},
+			overlaps: false,
+		},
+		{
+			// More specific silence.
+			existing: &types.Silence{
+				Matchers: append(types.Matchers{{Name: "instance", Value: "a"}}, sil.Matchers...),
+				StartsAt: now,
+				EndsAt:   now.Add(time.Hour),
+			},
+			overlaps: false,
+		},
+		{
+			// Annotation matchers do not subsume label matchers.
+			existing: &types.Silence{
+				Matchers: types.Matchers{{Name: "job", Value: "api", IsAnnotation: true}},
+				StartsAt: now,
+				EndsAt:   now.Add(time.Hour),
+			},
+			overlaps: false,
+		},
+		{
+			// Ends before the new silence starts.
+			existing: &types.Silence{
+				Matchers: sil.Matchers,
+				StartsAt: now.Add(-2 * time.Hour),
+				EndsAt:   now.Add(-time.Hour),
+			},
+			overlaps: false,
+		},
+		{
+			existing: &types.Silence{
+				Matchers: sil.Matchers,
+				StartsAt: now.Add(time.Hour),
+				EndsAt:   now.Add(2 * time.Hour),
+			},
+			overlaps: false,
+		},
+		{
+			existing: &types.Silence{
+				Matchers: sil.Matchers,
+				GroupKey: "123",
+				StartsAt: now,
+				EndsAt:   now.Add(time.Hour),
+			},
+			overlaps: false,
+		},
+		{
+			existing: &types.Silence{
+				Matchers: sil.Matchers,
+				Soft:     true,
+				StartsAt: now,
+				EndsAt:   now.Add(time.Hour),
+			},
+			overlaps: false,
+		},
+	}
+
+	for i, c := range cases {
+		res := overlappingSilences(sil, []*types.Silence{c.existing}, now)
+		if c.overlaps {
+			require.Equal(t, []*types.Silence{c.existing}, res, "case %d", i)
+		} else {
+			require.Empty(t, res, "case %d", i)
+		}
+	}
+
+	// An updated silence does not conflict with its previous version.
+	sil.ID = "abc"
+	res := overlappingSilences(sil, []*types.Silence{{ID: "abc", Matchers: sil.Matchers, EndsAt: sil.EndsAt}}, now)
+	require.Empty(t, res)
+}
@@ -0,0 +1,130 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/types"
+)
+
+// checkSilencePolicy returns an error if the silence violates the given policy.
+func checkSilencePolicy(p *config.SilencePolicy, sil *types.Silence, now time.Time) error {
+	if p == nil {
+		return nil
+	}
+	start := sil.StartsAt
+	if start.Before(now) {
+		start = now
+	}
+	max := time.Duration(p.MaxDuration)
+	for _, e := range p.MaxDurationExceptions {
+		if exceptionApplies(e, sil.Matchers) {
+			max = time.Duration(e.MaxDuration)
+			break
+		}
+	}
+	if d := sil.EndsAt.Sub(start); max > 0 && d > max {
+		return fmt.Errorf("silence duration of %s exceeds the maximum of %s", d, max)
+	}
+	return nil
+}
+
+// checkSilenceComment returns an error if the comment violates the policy.
+func checkSilenceComment(p *config.SilenceCommentPolicy, comment string) error {
+	if p == nil {
+		return nil
+	}
+	if n := utf8.RuneCountInString(strings.TrimSpace(comment)); n < p.MinLength {
+		return fmt.Errorf("silence comment must be at least %d characters long", p.MinLength)
+	}
+	if p.Regex != nil && p.Regex.Regexp != nil && !p.Regex.MatchString(comment) {
+		if p.Message != "" {
+			return fmt.Errorf("invalid silence comment: %s", p.Message)
+		}
+		return fmt.Errorf("silence comment must match %q", p.Regex.String())
+	}
+	return nil
+}
+
+// exceptionApplies returns true iff the matchers only select alerts that have
+// the label values required by the exception.
+func exceptionApplies(e *config.SilenceDurationException, ms types.Matchers) bool {
+	equal := map[string]string{}
+	for _, m := range ms {
+		if !m.IsRegex && !m.IsAnnotation {
+			equal[m.Name] = m.Value
+		}
+	}
+	for ln, lv := range e.Match {
+		if v, ok := equal[ln]; !ok || v != lv {
+			return false
+		}
+	}
+	for ln, re := range e.MatchRE {
+		if v, ok := equal[ln]; !ok || !re.MatchString(v) {
+			return false
+		}
+	}
+	return true
+}
+
+// approvalRequired returns true iff the silence has to be approved by a second
+// user according to the policy. The affected function returns the number of
+// firing alerts matched by the silence.
+func approvalRequired(p *config.SilencePolicy, sil *types.Silence, affected func() (int, error)) (bool, error) {
+	if p == nil || p.Approval == nil {
+		return false, nil
+	}
+	if p.Approval.RegexOnly && len(sil.Matchers) > 0 {
+		regexOnly := true
+		for _, m := range sil.Matchers {
+			if !m.IsRegex {
+				regexOnly = false
+				break
+			}
+		}
+		if regexOnly {
+			return true, nil
+		}
+	}
+	if p.Approval.MaxAffectedAlerts == 0 {
+		return false, nil
+	}
+	n, err := affected()
+	if err != nil {
+		return false, err
+	}
+	return n > p.Approval.MaxAffectedAlerts, nil
+}
+
+// checkSilenceQuota returns an error if the creator may not create another
+// silence while having the given number of active and pending silences.
+func checkSilenceQuota(p *config.SilencePolicy, creator string, active int) error {
+	if p == nil {
+		return nil
+	}
+	max := p.MaxActivePerCreator
+	if n, ok := p.CreatorLimits[creator]; ok {
+		max = n
+	}
+	if max > 0 && active >= max {
+		return fmt.Errorf("creator %q has reached the limit of %d active silences", creator, max)
+	}
+	return nil
+}
@@ -0,0 +1,225 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+
+	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/types"
+)
+
+func TestCheckSilencePolicy(t *testing.T) {
+	now := time.Now()
+
+	policy := &config.SilencePolicy{
+		MaxDuration: model.Duration(24 * time.Hour),
+		MaxDurationExceptions: []*config.SilenceDurationException{
+			{
+				Match:       map[string]string{"team": "infra"},
+				MaxDuration: model.Duration(7 * 24 * time.Hour),
+			},
+			{
+				MatchRE:     map[string]config.Regexp{"job": {Regexp: regexp.MustCompile("^(?:batch-.*)$")}},
+				MaxDuration: 0,
+			},
+		},
+	}
+
+	cases := []struct {
+		matchers types.Matchers
+		start    time.Time
+		end      time.Time
+		policy   *config.SilencePolicy
+		err      bool
+	}{
+		{
+			matchers: types.Matchers{types.NewMatcher("team", "frontend")},
+			end:      now.Add(48 * time.Hour),
+			policy:   nil,
+		}, {
+			matchers: types.Matchers{types.NewMatcher("team", "frontend")},
+			end:      now.Add(12 * time.Hour),
+			policy:   policy,
+		}, {
+			matchers: types.Matchers{types.NewMatcher("team", "frontend")},
+			end:      now.Add(48 * time.Hour),
+			policy:   policy,
+			err:      true,
+		}, {
+			// Start times in the past are reset to now.
+			matchers: types.Matchers{types.NewMatcher("team", "frontend")},
+			start:    now.Add(-48 * time.Hour),
+			end:      now.Add(12 * time.Hour),
+			policy:   policy,
+		}, {
+			matchers: types.Matchers{types.NewMatcher("team", "infra")},
+			end:      now.Add(48 * time.Hour),
+			policy:   policy,
+		}, {
+			matchers: types.Matchers{types.NewMatcher("team", "infra")},
+			end:      now.Add(30 * 24 * time.Hour),
+			policy:   policy,
+			err:      true,
+		}, {
+			// A regex matcher does not satisfy an exception.
+			matchers: types.Matchers{types.NewRegexMatcher("team", regexp.MustCompile("infra"))},
+			end:      now.Add(48 * time.Hour),
+			policy:   policy,
+			err:      true,
+		}, {
+			matchers: types.Matchers{types.NewMatcher("job", "batch-nightly")},
+			end:      now.Add(365 * 24 * time.Hour),
+			policy:   policy,
+		},
+	}
+
+	for i, c := range cases {
+		sil := &types.Silence{
+			Matchers: c.matchers,
+			StartsAt: c.start,
+			EndsAt:   c.end,
+		}
+		err := checkSilencePolicy(c.policy, sil, now)
+		if c.err && err == nil {
+			t.Errorf("case %d: expected error but got none", i)
+		}
+		if !c.err && err != nil {
+			t.Errorf("case %d: unexpected error: %s", i, err)
+		}
+	}
+}
+
+func TestApprovalRequired(t *testing.T) {
+	policy := &config.SilencePolicy{
+		Approval: &config.SilenceApprovalPolicy{
+			MaxAffectedAlerts: 10,
+			RegexOnly:         true,
+		},
+	}
+	cases := []struct {
+		policy   *config.SilencePolicy
+		matchers types.Matchers
+		affected int
+		required bool
+	}{
+		{
+			policy:   nil,
+			matchers: types.Matchers{{Name: "job", Value: ".*", IsRegex: true}},
+			affected: 100,
+			required: false,
+		},
+		{
+			policy:   policy,
+			matchers: types.Matchers{{Name: "job", Value: ".*", IsRegex: true}},
+			affected: 1,
+			required: true,
+		},
+		{
+			policy:   policy,
+			matchers: types.Matchers{{Name: "job", Value: "web"}, {Name: "instance", Value: ".*", IsRegex: true}},
+			affected: 10,
+			required: false,
+		},
+		{
+			policy:   policy,
+			matchers: types.Matchers{{Name: "job", Value: "web"}},
+			affected: 11,
+			required: true,
+		},
+	}
+	for i, c := range cases {
+		sil := &types.Silence{Matchers: c.matchers}
+		required, err := approvalRequired(c.policy, sil, func() (int, error) {
+			return c.affected, nil
+		})
+		if err != nil {
+			t.Fatalf("%d: unexpected error: %s", i, err)
+		}
+		if required != c.required {
+			t.Errorf("%d: expected approval required to be %v but got %v", i, c.required, required)
+		}
+	}
+}
+
+func TestCheckSilenceQuota(t *testing.T) {
+	policy := &config.SilencePolicy{
+		MaxActivePerCreator: 2,
+		CreatorLimits:       map[string]int{"automation": 100, "unlimited": 0},
+	}
+	cases := []struct {
+		policy  *config.SilencePolicy
+		creator string
+		active  int
+		err     bool
+	}{
+		{policy: nil, creator: "alice", active: 1000},
+		{policy: policy, creator: "alice", active: 1},
+		{policy: policy, creator: "alice", active: 2, err: true},
+		{policy: policy, creator: "automation", active: 99},
+		{policy: policy, creator: "automation", active: 100, err: true},
+		{policy: policy, creator: "unlimited", active: 1000},
+	}
+	for i, c := range cases {
+		err := checkSilenceQuota(c.policy, c.creator, c.active)
+		if err != nil && !c.err {
+			t.Errorf("%d: unexpected error: %s", i, err)
+		}
+		if err == nil && c.err {
+			t.Errorf("%d: expected error but got none", i)
+		}
+	}
+}
+
+func TestCheckSilenceComment(t *testing.T) {
+	policy := &config.SilenceCommentPolicy{
+		MinLength: 10,
+		Regex:     &config.Regexp{Regexp: regexp.MustCompile(`^(?:.*OPS-[0-9]+.*)$`)},
+	}
+	cases := []struct {
+		policy  *config.SilenceCommentPolicy
+		comment string
+		err     string
+	}{
+		{policy: nil, comment: ""},
+		{policy: policy, comment: "Upgrade, see OPS-123"},
+		{policy: policy, comment: "OPS-1", err: "silence comment must be at least 10 characters long"},
+		{policy: policy, comment: "      OPS-1     ", err: "silence comment must be at least 10 characters long"},
+		{policy: policy, comment: "Upgrade of the database", err: `silence comment must match "^(?:.*OPS-[0-9]+.*)$"`},
+		{
+			policy: &config.SilenceCommentPolicy{
+				Regex:   policy.Regex,
+				Message: "reference a ticket",
+			},
+			comment: "Upgrade of the database",
+			err:     "invalid silence comment: reference a ticket",
+		},
+	}
+	for i, c := range cases {
+		err := checkSilenceComment(c.policy, c.comment)
+		if c.err == "" {
+			if err != nil {
+				t.Errorf("%d: unexpected error: %s", i, err)
+			}
+			continue
+		}
+		if err == nil || err.Error() != c.err {
+			t.Errorf("%d: expected error %q but got %v", i, c.err, err)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/common/log"
+
+	"github.com/prometheus/alertmanager/nflog"
+	"github.com/prometheus/alertmanager/silence"
+)
+
+// The names of the archive entries. They match the snapshot file names in
+// the data directory so that an extracted archive can be used as such.
+const (
+	snapshotSilences = "silences"
+	snapshotNflog    = "nflog"
+)
+
+// maxSnapshotSize limits the size of an uploaded snapshot archive.
+const maxSnapshotSize = 256 << 20
+
+// writeSnapshotArchive writes a gzipped tar archive holding a snapshot of the
+// silences and the notification log into w.
+func writeSnapshotArchive(w io.Writer, sils *silence.Silences, nlog nflog.Log, now time.Time) error {
+	snapshots := []struct {
+		name string
+		f    func(io.Writer) (int, error)
+	}{
+		{snapshotSilences, sils.Snapshot},
+		{snapshotNflog, nlog.Snapshot},
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	for _, s := range snapshots {
+		var buf bytes.Buffer
+		if _, err := s.f(&buf); err != nil {
+			return fmt.Errorf("creating %s snapshot: %s", s.name, err)
+		}
+		hdr := &tar.Header{
+			Name:    s.name,
+			Mode:    0644,
+			Size:    int64(buf.Len()),
+			ModTime: now,
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := tw.Write(buf.Bytes()); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// readSnapshotArchive reads an archive written by writeSnapshotArchive and
+// returns the contained snapshots by name.
+func readSnapshotArchive(r io.Reader) (map[string][]byte, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+
+	res := map[string][]byte{}
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch hdr.Name {
+		case snapshotSilences, snapshotNflog:
+		default:
+			return nil, fmt.Errorf("unexpected archive entry %q", hdr.Name)
+		}
+		if _, ok := res[hdr.Name]; ok {
+			return nil, fmt.Errorf("duplicate archive entry %q", hdr.Name)
+		}
+		b, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		res[hdr.Name] = b
+	}
+	return res, nil
+}
+
+// snapshot responds with an archive of the current silences and notification
+// log state.
+func (api *API) snapshot(w http.ResponseWriter, r *http.Request) {
+	now := time.Now().UTC()
+
+	var buf bytes.Buffer
+	if err := writeSnapshotArchive(&buf, api.silences, api.nflog, now); err != nil {
+		respondError(w, apiError{
+			typ: errorInternal,
+			err: err,
+		}, nil)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(
+		"attachment; filename=\"alertmanager-snapshot-%s.tar.gz\"", now.Format("20060102T150405Z"),
+	))
+	w.WriteHeader(http.StatusOK)
+	if _, err := buf.WriteTo(w); err != nil {
+		log.Errorf("error writing snapshot: %v", err)
+	}
+}
+
+// restoreSnapshot merges the state of an uploaded snapshot archive into
+// the silences and notification log.
+func (api *API) restoreSnapshot(w http.ResponseWriter, r *http.Request) {
+	snaps, err := readSnapshotArchive(http.MaxBytesReader(w, r.Body, maxSnapshotSize))
+	if err != nil {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: fmt.Errorf("invalid snapshot archive: %s", err),
+		}, nil)
+		return
+	}
+
+	var res struct {
+		Silences int `json:"silences"`
+		Nflog    int `json:"nflog"`
+	}
+	if b, ok := snaps[snapshotSilences]; ok {
+		if res.Silences, err = api.silences.Restore(bytes.NewReader(b)); err != nil {
+			respondError(w, apiError{
+				typ: errorBadData,
+				err: fmt.Errorf("restoring silences: %s", err),
+			}, nil)
+			return
+		}
+	}
+	if b, ok := snaps[snapshotNflog]; ok {
+		if res.Nflog, err = api.nflog.Restore(bytes.NewReader(b)); err != nil {
+			respondError(w, apiError{
+				typ: errorBadData,
+				err: fmt.Errorf("restoring notification log: %s", err),
+			}, nil)
+			return
+		}
+	}
+	respond(w, res)
+}
@@ -0,0 +1,75 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/stretchr/testify/require"
+
+	"github.com/prometheus/alertmanager/nflog"
+	"github.com/prometheus/alertmanager/silence"
+	"github.com/prometheus/alertmanager/silence/silencepb"
+)
+
+func TestSnapshotArchive(t *testing.T) {
+	now := time.Now().UTC()
+
+	sils, err := silence.New(silence.Options{})
+	require.NoError(t, err)
+	endsAt, err := ptypes.TimestampProto(now.Add(time.Hour))
+	require.NoError(t, err)
+	_, err = sils.Create(&silencepb.Silence{
+		Matchers: []*silencepb.Matcher{{Name: "a", Pattern: "b"}},
+		EndsAt:   endsAt,
+	})
+	require.NoError(t, err)
+
+	nlog, err := nflog.New()
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, writeSnapshotArchive(&buf, sils, nlog, now))
+
+	snaps, err := readSnapshotArchive(&buf)
+	require.NoError(t, err)
+	require.Len(t, snaps, 2)
+	require.Contains(t, snaps, snapshotNflog)
+
+	restored, err := silence.New(silence.Options{})
+	require.NoError(t, err)
+	n, err := restored.Restore(bytes.NewReader(snaps[snapshotSilences]))
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+}
+
+func TestReadSnapshotArchiveUnexpectedEntry(t *testing.T) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "alerts", Mode: 0644}))
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+
+	_, err := readSnapshotArchive(&buf)
+	require.Error(t, err)
+
+	_, err = readSnapshotArchive(bytes.NewBufferString("not an archive"))
+	require.Error(t, err)
+}
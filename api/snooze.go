@@ -0,0 +1,110 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/common/route"
+)
+
+func (api *API) addSnooze(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Fingerprint string    `json:"fingerprint"`
+		EndsAt      time.Time `json:"endsAt"`
+		Duration    string    `json:"duration"`
+		CreatedBy   string    `json:"createdBy"`
+		Comment     string    `json:"comment"`
+	}
+	if err := receive(r, &req); err != nil {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+	api.setUser(r, &req.CreatedBy)
+	if req.EndsAt.IsZero() == (req.Duration == "") {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: fmt.Errorf("either endsAt or duration must be set"),
+		}, nil)
+		return
+	}
+	fp, err := model.ParseFingerprint(req.Fingerprint)
+	if err != nil {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: fmt.Errorf("invalid fingerprint %q: %s", req.Fingerprint, err),
+		}, nil)
+		return
+	}
+	if _, err := api.alerts.Get(fp); err != nil {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: fmt.Errorf("alert %s: %s", fp, err),
+		}, nil)
+		return
+	}
+
+	if req.Duration != "" {
+		d, err := time.ParseDuration(req.Duration)
+		if err != nil {
+			respondError(w, apiError{
+				typ: errorBadData,
+				err: err,
+			}, nil)
+			return
+		}
+		req.EndsAt = time.Now().Add(d)
+	}
+
+	sn, err := api.snoozes.Snooze(fp, req.EndsAt, req.CreatedBy, req.Comment)
+	if err != nil {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+	respond(w, sn)
+}
+
+func (api *API) listSnoozes(w http.ResponseWriter, r *http.Request) {
+	respond(w, api.snoozes.List())
+}
+
+func (api *API) delSnooze(w http.ResponseWriter, r *http.Request) {
+	s := route.Param(api.context(r), "fingerprint")
+
+	fp, err := model.ParseFingerprint(s)
+	if err != nil {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: fmt.Errorf("invalid fingerprint %q: %s", s, err),
+		}, nil)
+		return
+	}
+	if err := api.snoozes.Unsnooze(fp); err != nil {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+	respond(w, nil)
+}
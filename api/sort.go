@@ -0,0 +1,222 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/prometheus/common/model"
+
+	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/dispatch"
+	"github.com/prometheus/alertmanager/types"
+)
+
+// Keys by which alerts and alert groups can be sorted.
+var (
+	alertSortKeys = []string{"startsAt", "endsAt", "severity", "labels"}
+	groupSortKeys = []string{"labels", "size", "startsAt", "severity"}
+)
+
+// sortOrder is a sort key as given in the "sort" query parameter. A key
+// prefixed with "-" sorts in descending order.
+type sortOrder struct {
+	key  string
+	desc bool
+}
+
+// parseSortOrder parses a sort order and checks that its key is one of the
+// given ones. An empty string yields the zero sortOrder.
+func parseSortOrder(s string, keys []string) (sortOrder, error) {
+	if s == "" {
+		return sortOrder{}, nil
+	}
+	o := sortOrder{key: strings.TrimPrefix(s, "-"), desc: strings.HasPrefix(s, "-")}
+	for _, k := range keys {
+		if k == o.key {
+			return o, nil
+		}
+	}
+	return sortOrder{}, fmt.Errorf("invalid sort key %q, must be one of %s", o.key, strings.Join(keys, ", "))
+}
+
+// apply returns the sign of c adjusted to the direction of the order.
+func (o sortOrder) apply(c int) int {
+	if o.desc {
+		return -c
+	}
+	return c
+}
+
+// severityRanking ranks alerts by the value of their severity label.
+// Lower ranks are more severe.
+type severityRanking struct {
+	label  model.LabelName
+	values map[model.LabelValue]int
+}
+
+// newSeverityRanking returns the ranking of the notification priority
+// configuration. Without one, the usual values of the "severity" label
+// are ranked.
+func newSeverityRanking(c *config.NotificationPriorityConfig) *severityRanking {
+	label, values := model.LabelName("severity"), []model.LabelValue{"critical", "error", "warning", "info"}
+	if c != nil {
+		label, values = c.Label, c.Values
+	}
+
+	r := &severityRanking{label: label, values: map[model.LabelValue]int{}}
+	for i, v := range values {
+		if _, ok := r.values[v]; !ok {
+			r.values[v] = i
+		}
+	}
+	return r
+}
+
+// rank returns the rank of the given labels. Unknown severities rank
+// after all known ones.
+func (r *severityRanking) rank(lset model.LabelSet) int {
+	if i, ok := r.values[lset[r.label]]; ok {
+		return i
+	}
+	return len(r.values)
+}
+
+// compareAlerts compares two alerts by the given sort key. Alerts
+// that have not ended sort after all ended ones by endsAt.
+func compareAlerts(a, b *model.Alert, key string, sev *severityRanking) int {
+	switch key {
+	case "startsAt":
+		return compareTimes(a.StartsAt, b.StartsAt)
+	case "endsAt":
+		switch {
+		case a.EndsAt.IsZero() && b.EndsAt.IsZero():
+			return 0
+		case a.EndsAt.IsZero():
+			return 1
+		case b.EndsAt.IsZero():
+			return -1
+		}
+		return compareTimes(a.EndsAt, b.EndsAt)
+	case "severity":
+		return sev.rank(a.Labels) - sev.rank(b.Labels)
+	case "labels":
+		return compareLabels(a.Labels, b.Labels)
+	}
+	return 0
+}
+
+func compareTimes(a, b time.Time) int {
+	switch {
+	case a.Before(b):
+		return -1
+	case a.After(b):
+		return 1
+	}
+	return 0
+}
+
+func compareLabels(a, b model.LabelSet) int {
+	switch {
+	case a.Before(b):
+		return -1
+	case b.Before(a):
+		return 1
+	}
+	return 0
+}
+
+// sortAlerts sorts the alerts by the given order, ties are broken by
+// fingerprint.
+func sortAlerts(alerts []*types.Alert, o sortOrder, sev *severityRanking) {
+	sort.Slice(alerts, func(i, j int) bool {
+		if c := o.apply(compareAlerts(&alerts[i].Alert, &alerts[j].Alert, o.key, sev)); c != 0 {
+			return c < 0
+		}
+		return alerts[i].Fingerprint() < alerts[j].Fingerprint()
+	})
+}
+
+// sortAlertGroups sorts the groups by the given order, ties are broken by
+// their labels. If the order's key applies to alerts too, the alerts of
+// each group are sorted by it as well.
+func sortAlertGroups(groups dispatch.AlertOverview, o sortOrder, sev *severityRanking) {
+	for _, g := range groups {
+		for _, b := range g.Blocks {
+			if o.key == "size" {
+				continue
+			}
+			alerts := b.Alerts
+			sort.SliceStable(alerts, func(i, j int) bool {
+				return o.apply(compareAlerts(alerts[i].Alert, alerts[j].Alert, o.key, sev)) < 0
+			})
+		}
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		var c int
+		switch o.key {
+		case "labels":
+			c = compareLabels(groups[i].Labels, groups[j].Labels)
+		case "size":
+			c = groupSize(groups[i]) - groupSize(groups[j])
+		case "startsAt":
+			c = compareTimes(groupStart(groups[i]), groupStart(groups[j]))
+		case "severity":
+			c = groupSeverity(groups[i], sev) - groupSeverity(groups[j], sev)
+		}
+		if c = o.apply(c); c != 0 {
+			return c < 0
+		}
+		return groups[i].Labels.Before(groups[j].Labels)
+	})
+}
+
+// groupSize returns the number of alerts in the group.
+func groupSize(g *dispatch.AlertGroup) int {
+	n := 0
+	for _, b := range g.Blocks {
+		n += len(b.Alerts)
+	}
+	return n
+}
+
+// groupStart returns the earliest start time of the group's alerts.
+func groupStart(g *dispatch.AlertGroup) time.Time {
+	var start time.Time
+	for _, b := range g.Blocks {
+		for _, a := range b.Alerts {
+			if start.IsZero() || a.StartsAt.Before(start) {
+				start = a.StartsAt
+			}
+		}
+	}
+	return start
+}
+
+// groupSeverity returns the rank of the most severe alert of the group.
+func groupSeverity(g *dispatch.AlertGroup, sev *severityRanking) int {
+	res := len(sev.values)
+	for _, b := range g.Blocks {
+		for _, a := range b.Alerts {
+			if r := sev.rank(a.Labels); r < res {
+				res = r
+			}
+		}
+	}
+	return res
+}
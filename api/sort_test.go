@@ -0,0 +1,127 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+
+	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/dispatch"
+	"github.com/prometheus/alertmanager/types"
+)
+
+func TestParseSortOrder(t *testing.T) {
+	o, err := parseSortOrder("", alertSortKeys)
+	require.NoError(t, err)
+	require.Equal(t, sortOrder{}, o)
+
+	o, err = parseSortOrder("-severity", alertSortKeys)
+	require.NoError(t, err)
+	require.Equal(t, sortOrder{key: "severity", desc: true}, o)
+
+	_, err = parseSortOrder("size", alertSortKeys)
+	require.Error(t, err)
+}
+
+func TestSortAlerts(t *testing.T) {
+	now := time.Now()
+
+	newAlert := func(name, severity string, start, end time.Duration) *types.Alert {
+		a := &types.Alert{Alert: model.Alert{
+			Labels:   model.LabelSet{"alertname": model.LabelValue(name), "severity": model.LabelValue(severity)},
+			StartsAt: now.Add(start),
+		}}
+		if end != 0 {
+			a.EndsAt = now.Add(end)
+		}
+		return a
+	}
+	a := newAlert("a", "info", -time.Hour, 0)
+	b := newAlert("b", "critical", -time.Minute, time.Minute)
+	c := newAlert("c", "page", -2*time.Hour, time.Hour)
+
+	sev := newSeverityRanking(nil)
+	for _, tc := range []struct {
+		order string
+		exp   []*types.Alert
+	}{
+		{"startsAt", []*types.Alert{c, a, b}},
+		{"-startsAt", []*types.Alert{b, a, c}},
+		{"endsAt", []*types.Alert{b, c, a}},
+		{"severity", []*types.Alert{b, a, c}},
+		{"-labels", []*types.Alert{c, b, a}},
+	} {
+		o, err := parseSortOrder(tc.order, alertSortKeys)
+		require.NoError(t, err)
+
+		alerts := []*types.Alert{a, b, c}
+		sortAlerts(alerts, o, sev)
+		require.Equal(t, tc.exp, alerts, tc.order)
+	}
+
+	// The notification priority configuration overrides the default ranking.
+	alerts := []*types.Alert{a, b, c}
+	sortAlerts(alerts, sortOrder{key: "severity"}, newSeverityRanking(&config.NotificationPriorityConfig{
+		Label:  "severity",
+		Values: []model.LabelValue{"page", "info"},
+	}))
+	require.Equal(t, []*types.Alert{c, a, b}, alerts)
+}
+
+func TestSortAlertGroups(t *testing.T) {
+	now := time.Now()
+
+	newGroup := func(name string, alerts ...*model.Alert) *dispatch.AlertGroup {
+		var apiAlerts []*dispatch.APIAlert
+		for _, a := range alerts {
+			apiAlerts = append(apiAlerts, &dispatch.APIAlert{Alert: a})
+		}
+		return &dispatch.AlertGroup{
+			Labels: model.LabelSet{"group": model.LabelValue(name)},
+			Blocks: []*dispatch.AlertBlock{{Alerts: apiAlerts}},
+		}
+	}
+	warning := &model.Alert{Labels: model.LabelSet{"severity": "warning"}, StartsAt: now}
+	critical := &model.Alert{Labels: model.LabelSet{"severity": "critical"}, StartsAt: now.Add(-time.Hour)}
+
+	a := newGroup("a", warning, warning)
+	b := newGroup("b", warning, critical)
+	c := newGroup("c", warning)
+
+	sev := newSeverityRanking(nil)
+	for _, tc := range []struct {
+		order string
+		exp   dispatch.AlertOverview
+	}{
+		{"-labels", dispatch.AlertOverview{c, b, a}},
+		{"-size", dispatch.AlertOverview{a, b, c}},
+		{"size", dispatch.AlertOverview{c, a, b}},
+		{"startsAt", dispatch.AlertOverview{b, a, c}},
+		{"severity", dispatch.AlertOverview{b, a, c}},
+	} {
+		o, err := parseSortOrder(tc.order, groupSortKeys)
+		require.NoError(t, err)
+
+		groups := dispatch.AlertOverview{a, b, c}
+		sortAlertGroups(groups, o, sev)
+		require.Equal(t, tc.exp, groups, tc.order)
+	}
+
+	// The alerts of the groups were sorted by severity last.
+	require.Equal(t, critical, b.Blocks[0].Alerts[0].Alert)
+}
@@ -0,0 +1,167 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/common/version"
+
+	"github.com/prometheus/alertmanager/cluster"
+	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/nflog"
+	"github.com/prometheus/alertmanager/silence"
+)
+
+func (api *API) status(w http.ResponseWriter, req *http.Request) {
+	// The counts are gathered before locking as they lock on their own.
+	var (
+		alerts   = api.alertCounts()
+		silences = api.silenceCounts()
+		notified = api.lastNotifications()
+		peers    = api.cluster.Peers()
+		channels = api.cluster.Channels()
+	)
+
+	api.mtx.RLock()
+
+	var status = struct {
+		Config            string                `json:"config"`
+		ConfigJSON        config.Config         `json:"configJSON"`
+		ConfigHash        string                `json:"configHash"`
+		ConfigLoadedAt    time.Time             `json:"configLoadedAt"`
+		VersionInfo       map[string]string     `json:"versionInfo"`
+		Uptime            time.Time             `json:"uptime"`
+		Peers             []*cluster.Peer       `json:"peers"`
+		Channels          []*cluster.Channel    `json:"channels"`
+		Alerts            map[string]int        `json:"alerts"`
+		Silences          map[string]int        `json:"silences"`
+		LastNotifications map[string]*time.Time `json:"lastNotifications"`
+		AlertRetention    alertRetention        `json:"alertRetention"`
+		ReadReplica       bool                  `json:"readReplica"`
+	}{
+		Config:         api.config,
+		ConfigJSON:     api.configJSON,
+		ConfigHash:     api.configHash,
+		ConfigLoadedAt: api.configLoadedAt,
+		VersionInfo: map[string]string{
+			"version":   version.Version,
+			"revision":  version.Revision,
+			"branch":    version.Branch,
+			"buildUser": version.BuildUser,
+			"buildDate": version.BuildDate,
+			"goVersion": version.GoVersion,
+		},
+		Uptime:            api.uptime,
+		Peers:             peers,
+		Channels:          channels,
+		Alerts:            alerts,
+		Silences:          silences,
+		LastNotifications: map[string]*time.Time{},
+		ReadReplica:       api.readOnly,
+		AlertRetention: alertRetention{
+			Resolved:   api.alertRetention.String(),
+			GCInterval: api.alertGCInterval.String(),
+		},
+	}
+	// Every configured receiver is listed, with a null timestamp if it has
+	// not been notified yet.
+	for _, rc := range api.configJSON.Receivers {
+		status.LastNotifications[rc.Name] = notified[rc.Name]
+	}
+
+	api.mtx.RUnlock()
+
+	respond(w, status)
+}
+
+// alertRetention describes how long resolved alerts are kept.
+type alertRetention struct {
+	Resolved   string `json:"resolved"`
+	GCInterval string `json:"gcInterval"`
+}
+
+// alertCounts returns the number of alerts by state.
+func (api *API) alertCounts() map[string]int {
+	counts := map[string]int{
+		string(alertStateActive):      0,
+		string(alertStateSuppressed):  0,
+		string(alertStateUnprocessed): 0,
+	}
+	if api.alerts == nil {
+		return counts
+	}
+	var states map[model.Fingerprint]alertState
+	if api.groups != nil {
+		states = alertStates(api.groups())
+	}
+
+	alerts := api.alerts.GetPending()
+	defer alerts.Close()
+
+	for a := range alerts.Next() {
+		st, ok := states[a.Fingerprint()]
+		if !ok {
+			st = alertStateUnprocessed
+		}
+		counts[string(st)]++
+	}
+	return counts
+}
+
+// silenceCounts returns the number of silences by state.
+func (api *API) silenceCounts() map[string]int {
+	counts := map[string]int{}
+	for _, st := range []silence.SilenceState{silence.StateActive, silence.StatePending, silence.StateExpired} {
+		counts[string(st)] = 0
+		if api.silences == nil {
+			continue
+		}
+		sils, err := api.silences.Query(silence.QState(st))
+		if err != nil {
+			continue
+		}
+		counts[string(st)] = len(sils)
+	}
+	return counts
+}
+
+// lastNotifications returns the time of the last successful notification
+// by receiver according to the notification log.
+func (api *API) lastNotifications() map[string]*time.Time {
+	res := map[string]*time.Time{}
+	if api.nflog == nil {
+		return res
+	}
+	entries, err := api.nflog.Query(nflog.QSince(time.Time{}))
+	if err != nil {
+		return res
+	}
+	for _, e := range entries {
+		if e.Receiver == nil {
+			continue
+		}
+		ts, err := ptypes.Timestamp(e.Timestamp)
+		if err != nil {
+			continue
+		}
+		if last, ok := res[e.Receiver.GroupName]; !ok || ts.After(*last) {
+			res[e.Receiver.GroupName] = &ts
+		}
+	}
+	return res
+}
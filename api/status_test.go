@@ -0,0 +1,129 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+
+	"github.com/prometheus/alertmanager/cluster"
+	"github.com/prometheus/alertmanager/dispatch"
+	"github.com/prometheus/alertmanager/nflog"
+	"github.com/prometheus/alertmanager/nflog/nflogpb"
+	"github.com/prometheus/alertmanager/provider/mem"
+	"github.com/prometheus/alertmanager/silence"
+	"github.com/prometheus/alertmanager/silence/silencepb"
+	"github.com/prometheus/alertmanager/types"
+)
+
+type testNflog struct {
+	nflog.Log
+	entries []*nflogpb.Entry
+}
+
+func (l *testNflog) Query(p ...nflog.QueryParam) ([]*nflogpb.Entry, error) {
+	return l.entries, nil
+}
+
+func TestStatus(t *testing.T) {
+	alerts, err := mem.NewAlerts("")
+	require.NoError(t, err)
+	defer alerts.Close()
+
+	active := &types.Alert{Alert: model.Alert{
+		Labels:   model.LabelSet{"alertname": "a"},
+		StartsAt: time.Now(),
+	}, UpdatedAt: time.Now()}
+	unprocessed := &types.Alert{Alert: model.Alert{
+		Labels:   model.LabelSet{"alertname": "b"},
+		StartsAt: time.Now(),
+	}, UpdatedAt: time.Now()}
+	require.NoError(t, alerts.Put(active, unprocessed))
+
+	sils, err := silence.New(silence.Options{})
+	require.NoError(t, err)
+	startsAt, err := ptypes.TimestampProto(time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	endsAt, err := ptypes.TimestampProto(time.Now().Add(2 * time.Hour))
+	require.NoError(t, err)
+	_, err = sils.Create(&silencepb.Silence{
+		Matchers: []*silencepb.Matcher{{Name: "job", Pattern: "web"}},
+		StartsAt: startsAt,
+		EndsAt:   endsAt,
+	})
+	require.NoError(t, err)
+
+	notified, err := ptypes.TimestampProto(time.Now())
+	require.NoError(t, err)
+	nlog := &testNflog{entries: []*nflogpb.Entry{
+		{Receiver: &nflogpb.Receiver{GroupName: "team", Integration: "email"}, Timestamp: notified},
+	}}
+
+	api := New(alerts, sils, nil, nil, nil, nil, nil, nil, nlog, nil, nil, func() dispatch.AlertOverview {
+		return dispatch.AlertOverview{{Blocks: []*dispatch.AlertBlock{{
+			Alerts: []*dispatch.APIAlert{{Alert: &active.Alert}},
+		}}}}
+	}, nil, nil)
+	cfg := `
+route:
+  receiver: team
+receivers:
+- name: team
+- name: other
+`
+	require.NoError(t, api.Update(cfg, time.Minute))
+	api.SetAlertRetention(24*time.Hour, 30*time.Minute)
+
+	rec := httptest.NewRecorder()
+	api.status(rec, httptest.NewRequest("GET", "/status", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var res struct {
+		Data struct {
+			ConfigHash        string                `json:"configHash"`
+			ConfigLoadedAt    time.Time             `json:"configLoadedAt"`
+			Peers             []*cluster.Peer       `json:"peers"`
+			Channels          []*cluster.Channel    `json:"channels"`
+			Alerts            map[string]int        `json:"alerts"`
+			Silences          map[string]int        `json:"silences"`
+			LastNotifications map[string]*time.Time `json:"lastNotifications"`
+			AlertRetention    alertRetention        `json:"alertRetention"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&res))
+
+	s := res.Data
+	require.Len(t, s.ConfigHash, 64)
+	require.False(t, s.ConfigLoadedAt.IsZero())
+	require.Equal(t, []*cluster.Peer{}, s.Peers)
+	require.Equal(t, []*cluster.Channel{}, s.Channels)
+	require.Equal(t, map[string]int{"active": 1, "suppressed": 0, "unprocessed": 1}, s.Alerts)
+	require.Equal(t, map[string]int{"active": 0, "pending": 1, "expired": 0}, s.Silences)
+	require.Len(t, s.LastNotifications, 2)
+	require.NotNil(t, s.LastNotifications["team"])
+	require.Nil(t, s.LastNotifications["other"])
+	require.Equal(t, alertRetention{Resolved: "24h0m0s", GCInterval: "30m0s"}, s.AlertRetention)
+
+	// The hash changes with the configuration.
+	hash := s.ConfigHash
+	require.NoError(t, api.Update(cfg+"- name: third\n", time.Minute))
+	require.NotEqual(t, hash, api.configHash)
+}
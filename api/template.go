@@ -0,0 +1,158 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/common/model"
+
+	"github.com/prometheus/alertmanager/template"
+	"github.com/prometheus/alertmanager/types"
+)
+
+// renderRequest is a template to render for a sample alert group. Either a
+// template name or an inline template must be set.
+type renderRequest struct {
+	// Template is the name of a template defined by the template files.
+	Template string `json:"template"`
+	// Text is an inline template which may use the defined templates.
+	Text string `json:"text"`
+	// HTML renders the template with HTML escaping as done for emails.
+	HTML bool `json:"html"`
+
+	Receiver    string         `json:"receiver"`
+	GroupLabels model.LabelSet `json:"groupLabels"`
+	Alerts      []*model.Alert `json:"alerts"`
+}
+
+// renderResult is the output of a rendered template. The output is partial
+// if an error occurred while executing the template.
+type renderResult struct {
+	Output string       `json:"output"`
+	Error  *renderError `json:"error,omitempty"`
+}
+
+// renderError is an error of parsing or executing a template. The position
+// is unset if the error does not reference one.
+type renderError struct {
+	Message  string `json:"message"`
+	Template string `json:"template,omitempty"`
+	Line     int    `json:"line,omitempty"`
+	Column   int    `json:"column,omitempty"`
+}
+
+// renderTemplate renders a template for a sample alert group so that template
+// changes can be previewed without sending notifications.
+func (api *API) renderTemplate(w http.ResponseWriter, r *http.Request) {
+	var req renderRequest
+	if err := receive(r, &req); err != nil {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+
+	api.mtx.RLock()
+	tmpl := api.tmpl
+	api.mtx.RUnlock()
+
+	if tmpl == nil {
+		respondError(w, apiError{
+			typ: errorInternal,
+			err: fmt.Errorf("notification templates not loaded"),
+		}, nil)
+		return
+	}
+
+	text, alerts, err := renderInput(tmpl, &req, time.Now())
+	if err != nil {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+
+	data := tmpl.Data(req.Receiver, req.GroupLabels, alerts...)
+	var out string
+	if req.HTML {
+		out, err = tmpl.ExecuteHTMLString(text, data)
+	} else {
+		out, err = tmpl.ExecuteTextString(text, data)
+	}
+	res := &renderResult{Output: out}
+	if err != nil {
+		res.Error = newRenderError(err)
+	}
+	respond(w, res)
+}
+
+// renderInput validates the request and returns the template text to render
+// and the alerts of the sample group.
+func renderInput(tmpl *template.Template, req *renderRequest, now time.Time) (string, []*types.Alert, error) {
+	var text string
+	switch {
+	case req.Template != "" && req.Text != "":
+		return "", nil, fmt.Errorf("only one of template and text may be set")
+	case req.Template != "":
+		if !tmpl.Defined(req.Template) {
+			return "", nil, fmt.Errorf("template %q not defined", req.Template)
+		}
+		text = fmt.Sprintf("{{ template %q . }}", req.Template)
+	case req.Text != "":
+		text = req.Text
+	default:
+		return "", nil, fmt.Errorf("either template or text must be set")
+	}
+
+	if err := req.GroupLabels.Validate(); err != nil {
+		return "", nil, fmt.Errorf("invalid group labels: %s", err)
+	}
+	alerts := make([]*types.Alert, 0, len(req.Alerts))
+	for _, a := range req.Alerts {
+		if a.StartsAt.IsZero() {
+			a.StartsAt = now
+		}
+		if err := a.Validate(); err != nil {
+			return "", nil, err
+		}
+		alerts = append(alerts, &types.Alert{Alert: *a, UpdatedAt: now})
+	}
+	return text, alerts, nil
+}
+
+// templateErrorRe matches the errors of the text and html template packages,
+// e.g. `template: slack.tmpl:3:14: executing "slack.title" at <.Foo>: ...`.
+var templateErrorRe = regexp.MustCompile(`^(?:html/)?template: ([^:]*):(\d+):(?:(\d+):)? (.*)$`)
+
+func newRenderError(err error) *renderError {
+	m := templateErrorRe.FindStringSubmatch(err.Error())
+	if m == nil {
+		return &renderError{Message: err.Error()}
+	}
+	line, _ := strconv.Atoi(m[2])
+	col, _ := strconv.Atoi(m[3])
+	return &renderError{
+		Message:  m[4],
+		Template: m[1],
+		Line:     line,
+		Column:   col,
+	}
+}
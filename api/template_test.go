@@ -0,0 +1,83 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/prometheus/alertmanager/template"
+)
+
+func TestRenderTemplate(t *testing.T) {
+	api := New(nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+
+	render := func(body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		api.renderTemplate(rec, httptest.NewRequest("POST", "/templates/render", strings.NewReader(body)))
+		return rec
+	}
+	result := func(body string) renderResult {
+		rec := render(body)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		var res struct {
+			Data renderResult `json:"data"`
+		}
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&res))
+		return res.Data
+	}
+
+	require.Equal(t, http.StatusInternalServerError, render(`{"text":"x"}`).Code)
+
+	tmpl, err := template.FromGlobs()
+	require.NoError(t, err)
+	tmpl.ExternalURL, _ = url.Parse("http://localhost")
+	api.SetNotifiers(nil, tmpl)
+
+	for _, body := range []string{
+		`{}`,
+		`{"template":"slack.default.title","text":"x"}`,
+		`{"template":"unknown"}`,
+		`{"text":"x","alerts":[{"labels":{}}]}`,
+	} {
+		require.Equal(t, http.StatusBadRequest, render(body).Code, body)
+	}
+
+	alerts := `"receiver":"team","groupLabels":{"job":"web"},"alerts":[{"labels":{"alertname":"a","job":"web"}},{"labels":{"alertname":"b","job":"web"}}]`
+
+	res := result(`{"template":"slack.default.title",` + alerts + `}`)
+	require.Nil(t, res.Error)
+	require.Equal(t, "[FIRING:2] web ", res.Output)
+
+	res = result(`{"text":"{{ len .Alerts }} <b>","html":true,` + alerts + `}`)
+	require.Nil(t, res.Error)
+	require.Equal(t, "2 <b>", res.Output)
+
+	res = result(`{"text":"ok\n{{ .Receiver }}\n{{ end }}"}`)
+	require.Equal(t, "", res.Output)
+	require.Equal(t, &renderError{Message: "unexpected {{end}}", Line: 3}, res.Error)
+
+	res = result(`{"text":"{{ .Receiver }}\n{{ index .Alerts 5 }}",` + alerts + `}`)
+	require.Equal(t, "team\n", res.Output)
+	require.NotNil(t, res.Error)
+	require.Equal(t, 2, res.Error.Line)
+	require.Equal(t, 3, res.Error.Column)
+	require.Contains(t, res.Error.Message, "index out of range")
+}
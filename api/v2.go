@@ -0,0 +1,131 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/prometheus/common/route"
+)
+
+// registerV2 registers the handlers of the v2 API, which is described by
+// the OpenAPI definition served at /v2/openapi.yaml.
+//
+// The v2 API shares its handlers with v1 but responds with plain JSON
+// documents instead of wrapping them in a status envelope. Errors are
+// reported through the HTTP status code and an object holding the error.
+func (api *API) registerV2(r *route.Router, ihf func(string, http.HandlerFunc) http.HandlerFunc) {
+	r.Get("/openapi.yaml", ihf("v2_openapi", serveOpenAPI))
+
+	r.Get("/status", ihf("v2_status", unwrap(api.status)))
+	r.Get("/receivers", ihf("v2_receivers", unwrap(api.receivers)))
+	r.Post("/receivers/:name/test", ihf("v2_test_receiver", unwrap(api.unscoped(api.testReceiver))))
+	r.Get("/receivers/:name/capture", ihf("v2_receiver_capture", unwrap(api.unscoped(api.getCapture))))
+	r.Post("/receivers/:name/capture", ihf("v2_enable_receiver_capture", unwrap(api.unscoped(api.enableCapture))))
+	r.Del("/receivers/:name/capture", ihf("v2_disable_receiver_capture", unwrap(api.unscoped(api.disableCapture))))
+
+	r.Get("/alerts", ihf("v2_list_alerts", unwrap(api.listAlerts)))
+	r.Post("/alerts", ihf("v2_add_alerts", unwrap(api.rateLimited("alerts", api.addAlerts))))
+	r.Post("/alerts/bulk", ihf("v2_add_alerts_bulk", unwrap(api.rateLimited("alerts", api.addAlertsBulk))))
+	r.Get("/alerts/groups", ihf("v2_alert_groups", unwrap(api.alertGroups)))
+	r.Get("/alerts/counts", ihf("v2_alert_counts", unwrap(api.countAlerts)))
+	r.Get("/alert/:fingerprint/explain", ihf("v2_explain_alert", unwrap(api.explainAlert)))
+
+	r.Get("/snoozes", ihf("v2_list_snoozes", unwrap(api.listSnoozes)))
+	r.Post("/snoozes", ihf("v2_add_snooze", unwrap(api.unscoped(api.addSnooze))))
+	r.Del("/snooze/:fingerprint", ihf("v2_del_snooze", unwrap(api.unscoped(api.delSnooze))))
+
+	r.Get("/silences", ihf("v2_list_silences", unwrap(api.listSilences)))
+	r.Post("/silences", ihf("v2_add_silence", unwrap(api.rateLimited("silences", api.addSilence))))
+	r.Get("/silence/:sid", ihf("v2_get_silence", unwrap(api.getSilence)))
+	r.Del("/silence/:sid", ihf("v2_del_silence", unwrap(api.rateLimited("silences", api.delSilence))))
+	r.Post("/silence/:sid/extend", ihf("v2_extend_silence", unwrap(api.rateLimited("silences", api.extendSilence))))
+}
+
+func serveOpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/yaml")
+	w.Write([]byte(openAPISpec))
+}
+
+// v2Error is the body of v2 error responses.
+type v2Error struct {
+	Error     string          `json:"error"`
+	ErrorType errorType       `json:"errorType,omitempty"`
+	Data      json.RawMessage `json:"data,omitempty"`
+}
+
+// unwrap turns a v1 handler into a v2 handler by removing the status
+// envelope from its responses.
+func unwrap(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		rec := &responseRecorder{header: w.Header(), code: http.StatusOK}
+		h(rec, r)
+
+		var resp struct {
+			Status    status          `json:"status"`
+			Data      json.RawMessage `json:"data"`
+			ErrorType errorType       `json:"errorType"`
+			Error     string          `json:"error"`
+		}
+		if err := json.Unmarshal(rec.body.Bytes(), &resp); err != nil || resp.Status == "" {
+			// Handlers report some errors as plain text.
+			resp.Status = statusError
+			resp.Error = strings.TrimSpace(rec.body.String())
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(rec.code)
+
+		var b []byte
+		if resp.Status == statusSuccess {
+			b = resp.Data
+		} else {
+			if string(resp.Data) == "null" {
+				resp.Data = nil
+			}
+			b, _ = json.Marshal(&v2Error{
+				Error:     resp.Error,
+				ErrorType: resp.ErrorType,
+				Data:      resp.Data,
+			})
+		}
+		if len(b) == 0 {
+			b = []byte("null")
+		}
+		w.Write(b)
+	}
+}
+
+// responseRecorder captures the status code and body written by a handler.
+// Headers are written to the underlying response directly.
+type responseRecorder struct {
+	header http.Header
+	code   int
+	body   bytes.Buffer
+}
+
+func (r *responseRecorder) Header() http.Header {
+	return r.header
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	return r.body.Write(b)
+}
+
+func (r *responseRecorder) WriteHeader(code int) {
+	r.code = code
+}
@@ -0,0 +1,177 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+	"gopkg.in/yaml.v2"
+
+	"github.com/prometheus/alertmanager/api/client"
+	"github.com/prometheus/alertmanager/types"
+)
+
+func TestOpenAPISpec(t *testing.T) {
+	var spec struct {
+		OpenAPI string                            `yaml:"openapi"`
+		Paths   map[string]map[string]interface{} `yaml:"paths"`
+		Comps   map[string]map[string]interface{} `yaml:"components"`
+	}
+	require.NoError(t, yaml.Unmarshal([]byte(openAPISpec), &spec))
+	require.Equal(t, "3.0.0", spec.OpenAPI)
+
+	// All data routes registered in registerV2 must be documented.
+	for path, methods := range map[string][]string{
+		"/status":                      {"get"},
+		"/receivers":                   {"get"},
+		"/receivers/{name}/capture":    {"get", "post", "delete"},
+		"/alerts":                      {"get", "post"},
+		"/alerts/bulk":                 {"post"},
+		"/alerts/groups":               {"get"},
+		"/alerts/counts":               {"get"},
+		"/alert/{fingerprint}/explain": {"get"},
+		"/snoozes":                     {"get", "post"},
+		"/snooze/{fingerprint}":        {"delete"},
+		"/silences":                    {"get", "post"},
+		"/silence/{silenceID}":         {"get", "delete"},
+		"/silence/{silenceID}/extend":  {"post"},
+	} {
+		require.Contains(t, spec.Paths, path)
+		for _, m := range methods {
+			require.Contains(t, spec.Paths[path], m, path)
+		}
+	}
+
+	// All referenced schemas and responses must be defined.
+	for _, ref := range strings.Split(openAPISpec, "$ref: '")[1:] {
+		ref = ref[:strings.Index(ref, "'")]
+		parts := strings.Split(ref, "/")
+		require.Len(t, parts, 4, ref)
+		require.Contains(t, spec.Comps[parts[2]], parts[3], ref)
+	}
+}
+
+func TestUnwrap(t *testing.T) {
+	cases := []struct {
+		handler http.HandlerFunc
+		code    int
+		body    string
+	}{
+		{
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				respond(w, []string{"a"})
+			},
+			code: http.StatusOK,
+			body: `["a"]`,
+		},
+		{
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				respond(w, nil)
+			},
+			code: http.StatusOK,
+			body: `null`,
+		},
+		{
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				respondError(w, apiError{typ: errorBadData, err: errors.New("bad")}, nil)
+			},
+			code: http.StatusBadRequest,
+			body: `{"error":"bad","errorType":"bad_data"}`,
+		},
+		{
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				respondError(w, apiError{typ: errorConflict, err: errors.New("conflict")}, map[string]int{"n": 1})
+			},
+			code: http.StatusConflict,
+			body: `{"error":"conflict","errorType":"conflict","data":{"n":1}}`,
+		},
+		{
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, "not found", http.StatusNotFound)
+			},
+			code: http.StatusNotFound,
+			body: `{"error":"not found"}`,
+		},
+	}
+
+	for _, c := range cases {
+		rec := httptest.NewRecorder()
+		unwrap(c.handler)(rec, httptest.NewRequest("GET", "/", nil))
+
+		require.Equal(t, c.code, rec.Code)
+		require.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+		require.JSONEq(t, c.body, rec.Body.String())
+	}
+}
+
+// TestClientRoutes checks that every request of the hand-written client
+// targets a path and method of the OpenAPI definition.
+func TestClientRoutes(t *testing.T) {
+	var spec struct {
+		Paths map[string]map[string]interface{} `yaml:"paths"`
+	}
+	require.NoError(t, yaml.Unmarshal([]byte(openAPISpec), &spec))
+
+	var requests []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, strings.ToLower(r.Method)+" "+strings.TrimPrefix(r.URL.Path, "/api/v2"))
+		w.Write([]byte("null"))
+	}))
+	defer srv.Close()
+
+	c, err := client.New(srv.URL, nil)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	calls := []func() error{
+		func() error { _, err := c.Status(ctx); return err },
+		func() error { _, err := c.Receivers(ctx); return err },
+		func() error { _, err := c.TestReceiver(ctx, "team", nil, nil, 0); return err },
+		func() error { _, err := c.Alerts(ctx, nil); return err },
+		func() error { return c.PostAlerts(ctx, &model.Alert{}) },
+		func() error { _, err := c.AlertGroups(ctx, nil); return err },
+		func() error { _, err := c.Snooze(ctx, 1, time.Time{}, time.Hour, ""); return err },
+		func() error { _, err := c.Silences(ctx, nil); return err },
+		func() error { _, err := c.Silence(ctx, "id"); return err },
+		func() error { _, err := c.SetSilence(ctx, &types.Silence{}, false); return err },
+		func() error { return c.ExpireSilence(ctx, "id") },
+		func() error { _, err := c.ExtendSilence(ctx, "id", time.Time{}, time.Hour, ""); return err },
+	}
+	for _, call := range calls {
+		require.NoError(t, call())
+	}
+	require.Len(t, requests, len(calls))
+
+	param := regexp.MustCompile(`\{[^}]+\}`)
+	for _, req := range requests {
+		found := false
+		for path, methods := range spec.Paths {
+			re := regexp.MustCompile("^" + param.ReplaceAllString(path, "[^/]+") + "$")
+			if _, ok := methods[strings.Fields(req)[0]]; ok && re.MatchString(strings.Fields(req)[1]) {
+				found = true
+				break
+			}
+		}
+		require.True(t, found, "client request %q is not part of the OpenAPI definition", req)
+	}
+}
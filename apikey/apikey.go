@@ -0,0 +1,419 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package apikey implements API keys for automation accounts. A key may be
+// scoped by matchers, restricting it to silences and alerts matching them.
+// Only a hash of each key's secret is stored. Keys are shared with other
+// Alertmanager instances through the mesh network.
+package apikey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/prometheus/common/log"
+	"github.com/weaveworks/mesh"
+
+	"github.com/prometheus/alertmanager/gossipstate"
+	"github.com/prometheus/alertmanager/types"
+)
+
+// Prefix is the prefix of all API key tokens.
+const Prefix = "amk_"
+
+// lastUsedInterval is the minimum interval between gossiped updates of the
+// last use of a key.
+const lastUsedInterval = time.Minute
+
+var (
+	// ErrNotFound is returned if a key was not found.
+	ErrNotFound = errors.New("API key not found")
+
+	// ErrInvalid is returned if a token does not belong to a valid key.
+	ErrInvalid = errors.New("invalid API key")
+)
+
+// Key is an API key. The token authenticating with the key is only returned
+// on creation and rotation.
+type Key struct {
+	ID        string         `json:"id"`
+	Name      string         `json:"name"`
+	Matchers  types.Matchers `json:"matchers"`
+	CreatedBy string         `json:"createdBy"`
+	CreatedAt time.Time      `json:"createdAt"`
+	ExpiresAt time.Time      `json:"expiresAt,omitempty"`
+	RotatedAt time.Time      `json:"rotatedAt,omitempty"`
+	RevokedAt time.Time      `json:"revokedAt,omitempty"`
+	// LastUsedAt is only updated once per minute.
+	LastUsedAt time.Time `json:"lastUsedAt,omitempty"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+
+	// Hash is the SHA-256 hash of the key's secret. After a rotation, the
+	// previous secret remains valid until PreviousValidUntil.
+	Hash               string    `json:"hash"`
+	PreviousHash       string    `json:"previousHash,omitempty"`
+	PreviousValidUntil time.Time `json:"previousValidUntil,omitempty"`
+}
+
+// Active returns whether the key is neither revoked nor expired at the
+// given time.
+func (k *Key) Active(now time.Time) bool {
+	if !k.RevokedAt.IsZero() && !k.RevokedAt.After(now) {
+		return false
+	}
+	return k.ExpiresAt.IsZero() || k.ExpiresAt.After(now)
+}
+
+// endedAt returns the time at which the key was revoked or expired. It is
+// zero for active keys without expiry.
+func (k *Key) endedAt() time.Time {
+	t := k.ExpiresAt
+	if !k.RevokedAt.IsZero() && (t.IsZero() || k.RevokedAt.Before(t)) {
+		t = k.RevokedAt
+	}
+	return t
+}
+
+func validateKey(k *Key) error {
+	if k.ID == "" {
+		return errors.New("ID missing")
+	}
+	if strings.Contains(k.ID, "_") {
+		return errors.New("ID must not contain underscores")
+	}
+	if k.Name == "" {
+		return errors.New("name missing")
+	}
+	if k.CreatedBy == "" {
+		return errors.New("creator missing")
+	}
+	if k.Hash == "" {
+		return errors.New("hash missing")
+	}
+	if k.CreatedAt.IsZero() || k.UpdatedAt.IsZero() {
+		return errors.New("timestamps missing")
+	}
+	for _, m := range k.Matchers {
+		if m.IsAnnotation {
+			return errors.New("annotation matchers cannot scope keys")
+		}
+		if err := m.Validate(); err != nil {
+			return fmt.Errorf("invalid matcher: %s", err)
+		}
+		if err := m.Init(); err != nil {
+			return fmt.Errorf("invalid matcher: %s", err)
+		}
+	}
+	return nil
+}
+
+// Keys holds the API keys.
+type Keys struct {
+	retention time.Duration
+	now       func() time.Time
+	st        *gossipstate.State
+}
+
+// Options configures a new Keys object.
+type Options struct {
+	// A snapshot file from which the initial state is loaded.
+	SnapshotFile string
+
+	// Keys may be garbage collected the given duration after they were
+	// revoked or expired.
+	Retention time.Duration
+
+	// A function creating a mesh.Gossip on being called with a mesh.Gossiper.
+	Gossip func(g mesh.Gossiper) mesh.Gossip
+
+	// A logger used by background processing.
+	Logger log.Logger
+}
+
+// New returns a new Keys object with the given configuration.
+func New(o Options) (*Keys, error) {
+	st, err := gossipstate.New(keyType{}, gossipstate.Options{
+		SnapshotFile: o.SnapshotFile,
+		Gossip:       o.Gossip,
+		Logger:       o.Logger,
+	})
+	if st == nil {
+		return nil, err
+	}
+	return &Keys{
+		retention: o.Retention,
+		now:       gossipstate.UTCNow,
+		st:        st,
+	}, err
+}
+
+// Maintenance garbage collects the keys at the given interval. If the
+// snapshot file is set, a snapshot is written to it afterwards.
+// Terminates on receiving from stopc.
+func (s *Keys) Maintenance(interval time.Duration, snapf string, stopc <-chan struct{}) {
+	s.st.Maintenance(interval, snapf, stopc, s.GC)
+}
+
+// GC removes keys that were revoked or expired longer than the retention
+// time ago. It returns the number of removed keys.
+func (s *Keys) GC() (int, error) {
+	now := s.now()
+
+	return s.st.GC(func(e interface{}) bool {
+		end := e.(*Key).endedAt()
+		return !end.IsZero() && !end.Add(s.retention).After(now)
+	}), nil
+}
+
+// Create creates a key restricted to the given matchers. A zero expiresAt
+// creates a key that never expires. It returns the key and the token to
+// authenticate with.
+func (s *Keys) Create(name string, matchers types.Matchers, createdBy string, expiresAt time.Time) (*Key, string, error) {
+	now := s.now()
+
+	if !expiresAt.IsZero() && !expiresAt.After(now) {
+		return nil, "", errors.New("invalid API key: expiry must be in the future")
+	}
+	secret, hash := newSecret()
+	k := &Key{
+		ID:        randomHex(8),
+		Name:      name,
+		Matchers:  types.NewMatchers(matchers...),
+		CreatedBy: createdBy,
+		CreatedAt: now,
+		ExpiresAt: expiresAt,
+		UpdatedAt: now,
+		Hash:      hash,
+	}
+	if err := validateKey(k); err != nil {
+		return nil, "", fmt.Errorf("invalid API key: %s", err)
+	}
+
+	s.st.Lock()
+	defer s.st.Unlock()
+
+	s.st.Set(k)
+	return k.clone(), token(k.ID, secret), nil
+}
+
+// Rotate replaces the secret of the key. The previous token remains valid
+// for the given grace period so that clients can be updated without
+// downtime. It returns the key and the new token.
+func (s *Keys) Rotate(id string, grace time.Duration) (*Key, string, error) {
+	now := s.now()
+
+	if grace < 0 {
+		return nil, "", errors.New("grace period must not be negative")
+	}
+
+	s.st.Lock()
+	defer s.st.Unlock()
+
+	e, ok := s.st.Get(id)
+	if !ok || !e.(*Key).Active(now) {
+		return nil, "", ErrNotFound
+	}
+	secret, hash := newSecret()
+
+	k := e.(*Key).clone()
+	k.PreviousHash = k.Hash
+	k.PreviousValidUntil = now.Add(grace)
+	k.Hash = hash
+	k.RotatedAt = now
+	k.UpdatedAt = now
+
+	s.st.Set(k)
+	return k.clone(), token(k.ID, secret), nil
+}
+
+// Revoke invalidates the key immediately.
+func (s *Keys) Revoke(id string) error {
+	now := s.now()
+
+	s.st.Lock()
+	defer s.st.Unlock()
+
+	e, ok := s.st.Get(id)
+	if !ok || !e.(*Key).Active(now) {
+		return ErrNotFound
+	}
+	k := e.(*Key).clone()
+	k.RevokedAt = now
+	k.UpdatedAt = now
+
+	s.st.Set(k)
+	return nil
+}
+
+type keySlice []*Key
+
+func (s keySlice) Len() int           { return len(s) }
+func (s keySlice) Less(i, j int) bool { return s[i].CreatedAt.Before(s[j].CreatedAt) }
+func (s keySlice) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+// List returns all keys ordered by their creation time, including revoked
+// and expired keys that were not yet garbage collected.
+func (s *Keys) List() []*Key {
+	s.st.RLock()
+	defer s.st.RUnlock()
+
+	res := keySlice{}
+	s.st.Range(func(e interface{}) {
+		res = append(res, e.(*Key).clone())
+	})
+	sort.Sort(res)
+	return res
+}
+
+// Authenticate returns the active key the token belongs to and records its
+// use. It returns ErrInvalid if there is no such key.
+func (s *Keys) Authenticate(tok string) (*Key, error) {
+	now := s.now()
+
+	id, secret, ok := parseToken(tok)
+	if !ok {
+		return nil, ErrInvalid
+	}
+	h := hashSecret(secret)
+
+	s.st.Lock()
+	defer s.st.Unlock()
+
+	e, ok := s.st.Get(id)
+	if !ok || !e.(*Key).Active(now) {
+		return nil, ErrInvalid
+	}
+	k := e.(*Key)
+	valid := subtle.ConstantTimeCompare([]byte(h), []byte(k.Hash)) == 1
+	if !valid && k.PreviousHash != "" && k.PreviousValidUntil.After(now) {
+		valid = subtle.ConstantTimeCompare([]byte(h), []byte(k.PreviousHash)) == 1
+	}
+	if !valid {
+		return nil, ErrInvalid
+	}
+	if now.Sub(k.LastUsedAt) >= lastUsedInterval {
+		k = k.clone()
+		k.LastUsedAt = now
+		s.st.Set(k)
+	}
+	return k.clone(), nil
+}
+
+// keyType implements the handling of keys by the state.
+type keyType struct{}
+
+func (keyType) Key(e interface{}) string {
+	return e.(*Key).ID
+}
+
+func (keyType) Decode(b []byte) (interface{}, error) {
+	var k Key
+	if err := json.Unmarshal(b, &k); err != nil {
+		return nil, err
+	}
+	if err := validateKey(&k); err != nil {
+		return nil, fmt.Errorf("invalid API key: %s", err)
+	}
+	return &k, nil
+}
+
+func (keyType) Size(e interface{}) int {
+	k := e.(*Key)
+	return len(k.Name) + len(k.CreatedBy) + 64*len(k.Matchers) + 512
+}
+
+// Merge merges the states of the key with mergeKeys.
+func (keyType) Merge(prev, e interface{}) interface{} {
+	m := mergeKeys(prev.(*Key), e.(*Key))
+	if m.equal(prev.(*Key)) {
+		return nil
+	}
+	return m
+}
+
+// mergeKeys merges two states of the same key. The more recently updated
+// state wins, but a revocation and the latest use are never lost to a
+// concurrent update.
+func mergeKeys(a, b *Key) *Key {
+	newer, older := a, b
+	if b.UpdatedAt.After(a.UpdatedAt) {
+		newer, older = b, a
+	}
+	m := newer.clone()
+	if !older.RevokedAt.IsZero() && (m.RevokedAt.IsZero() || older.RevokedAt.Before(m.RevokedAt)) {
+		m.RevokedAt = older.RevokedAt
+	}
+	if older.LastUsedAt.After(m.LastUsedAt) {
+		m.LastUsedAt = older.LastUsedAt
+	}
+	return m
+}
+
+func (k *Key) clone() *Key {
+	c := *k
+	c.Matchers = make(types.Matchers, 0, len(k.Matchers))
+	for _, m := range k.Matchers {
+		mc := *m
+		c.Matchers = append(c.Matchers, &mc)
+	}
+	return &c
+}
+
+func (k *Key) equal(o *Key) bool {
+	return k.Hash == o.Hash &&
+		k.UpdatedAt.Equal(o.UpdatedAt) &&
+		k.RevokedAt.Equal(o.RevokedAt) &&
+		k.LastUsedAt.Equal(o.LastUsedAt)
+}
+
+func token(id, secret string) string {
+	return Prefix + id + "_" + secret
+}
+
+func parseToken(tok string) (id, secret string, ok bool) {
+	if !strings.HasPrefix(tok, Prefix) {
+		return "", "", false
+	}
+	parts := strings.SplitN(strings.TrimPrefix(tok, Prefix), "_", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", false
+	}
+	return parts[0], parts[1], true
+}
+
+// newSecret returns a new random secret and its hash.
+func newSecret() (string, string) {
+	secret := randomHex(32)
+	return secret, hashSecret(secret)
+}
+
+func hashSecret(secret string) string {
+	h := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(h[:])
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
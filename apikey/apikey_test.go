@@ -0,0 +1,177 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apikey
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/prometheus/alertmanager/gossipstate"
+	"github.com/prometheus/alertmanager/types"
+)
+
+func TestKeysLifecycle(t *testing.T) {
+	s, err := New(Options{Retention: time.Hour})
+	require.NoError(t, err)
+
+	now := gossipstate.UTCNow()
+	s.now = func() time.Time { return now }
+
+	scope := types.Matchers{types.NewMatcher("team", "payments")}
+
+	_, _, err = s.Create("", scope, "me", time.Time{})
+	require.Error(t, err, "name missing")
+	_, _, err = s.Create("ci", scope, "", time.Time{})
+	require.Error(t, err, "creator missing")
+	_, _, err = s.Create("ci", scope, "me", now)
+	require.Error(t, err, "expiry must be in the future")
+	_, _, err = s.Create("ci", types.Matchers{{Name: "team", Value: "x", IsAnnotation: true}}, "me", time.Time{})
+	require.Error(t, err, "annotation matchers")
+
+	k, tok, err := s.Create("ci", scope, "me", time.Time{})
+	require.NoError(t, err)
+	require.Contains(t, tok, Prefix+k.ID+"_")
+	require.NotContains(t, tok, k.Hash)
+
+	got, err := s.Authenticate(tok)
+	require.NoError(t, err)
+	require.Equal(t, "ci", got.Name)
+	require.True(t, got.Matchers.Equal(scope))
+	require.Equal(t, now, got.LastUsedAt)
+
+	for _, bad := range []string{"", "amk_", "amk_" + k.ID, "amk_" + k.ID + "_wrong", tok + "x", "other_" + k.ID} {
+		_, err := s.Authenticate(bad)
+		require.Equal(t, ErrInvalid, err, bad)
+	}
+
+	// The last use is only recorded once per minute.
+	used := now
+	now = now.Add(30 * time.Second)
+	got, err = s.Authenticate(tok)
+	require.NoError(t, err)
+	require.Equal(t, used, got.LastUsedAt)
+	now = now.Add(30 * time.Second)
+	got, err = s.Authenticate(tok)
+	require.NoError(t, err)
+	require.Equal(t, now, got.LastUsedAt)
+
+	// The previous token remains valid during the grace period.
+	_, _, err = s.Rotate(k.ID, -time.Second)
+	require.Error(t, err)
+	_, newTok, err := s.Rotate(k.ID, 10*time.Minute)
+	require.NoError(t, err)
+	require.NotEqual(t, tok, newTok)
+
+	_, err = s.Authenticate(tok)
+	require.NoError(t, err)
+	_, err = s.Authenticate(newTok)
+	require.NoError(t, err)
+
+	now = now.Add(10 * time.Minute)
+	_, err = s.Authenticate(tok)
+	require.Equal(t, ErrInvalid, err)
+	_, err = s.Authenticate(newTok)
+	require.NoError(t, err)
+
+	// Revoked and expired keys are rejected and eventually removed.
+	require.NoError(t, s.Revoke(k.ID))
+	require.Equal(t, ErrNotFound, s.Revoke(k.ID))
+	_, _, err = s.Rotate(k.ID, 0)
+	require.Equal(t, ErrNotFound, err)
+	_, err = s.Authenticate(newTok)
+	require.Equal(t, ErrInvalid, err)
+
+	_, expTok, err := s.Create("tmp", nil, "me", now.Add(time.Minute))
+	require.NoError(t, err)
+	now = now.Add(time.Minute)
+	_, err = s.Authenticate(expTok)
+	require.Equal(t, ErrInvalid, err)
+	require.Len(t, s.List(), 2)
+
+	now = now.Add(59 * time.Minute)
+	n, err := s.GC()
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+
+	now = now.Add(time.Minute)
+	n, err = s.GC()
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	require.Len(t, s.List(), 0)
+}
+
+func TestKeyTypeMerge(t *testing.T) {
+	now := gossipstate.UTCNow()
+
+	newKey := func(hash string, updated, revoked, used time.Time) *Key {
+		return &Key{
+			ID:         "a",
+			Name:       "ci",
+			Matchers:   types.Matchers{types.NewMatcher("team", "payments")},
+			CreatedBy:  "me",
+			CreatedAt:  now,
+			UpdatedAt:  updated,
+			Hash:       hash,
+			RevokedAt:  revoked,
+			LastUsedAt: used,
+		}
+	}
+	var zero time.Time
+	prev := newKey("h1", now, zero, now)
+
+	for _, c := range []struct {
+		name string
+		e    *Key
+		// exp is nil if the known key is kept.
+		exp *Key
+	}{
+		{
+			name: "same state",
+			e:    newKey("h1", now, zero, now),
+		},
+		{
+			name: "older update",
+			e:    newKey("h0", now.Add(-time.Minute), zero, now.Add(-time.Minute)),
+		},
+		{
+			name: "newer rotation",
+			e:    newKey("h2", now.Add(time.Minute), zero, now),
+			exp:  newKey("h2", now.Add(time.Minute), zero, now),
+		},
+		{
+			name: "revocation is not lost to a newer update",
+			e:    newKey("h1", now.Add(-time.Minute), now.Add(-time.Minute), zero),
+			exp:  newKey("h1", now, now.Add(-time.Minute), now),
+		},
+		{
+			name: "latest use is not lost to a newer update",
+			e:    newKey("h2", now.Add(time.Minute), zero, now.Add(-time.Hour)),
+			exp:  newKey("h2", now.Add(time.Minute), zero, now),
+		},
+		{
+			name: "later use of an older state",
+			e:    newKey("h1", now.Add(-time.Minute), zero, now.Add(time.Hour)),
+			exp:  newKey("h1", now, zero, now.Add(time.Hour)),
+		},
+	} {
+		res := keyType{}.Merge(prev, c.e)
+		if c.exp == nil {
+			require.Nil(t, res, c.name)
+			continue
+		}
+		require.Equal(t, c.exp, res, c.name)
+	}
+}
@@ -0,0 +1,238 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package assignment stores who alerts and groups are assigned to.
+// Assignments are shared with other Alertmanager instances through the mesh
+// network.
+package assignment
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/prometheus/common/log"
+	"github.com/prometheus/common/model"
+	"github.com/weaveworks/mesh"
+
+	"github.com/prometheus/alertmanager/gossipstate"
+	"github.com/prometheus/alertmanager/types"
+)
+
+// ErrNotFound is returned if an assignment was not found.
+var ErrNotFound = errors.New("assignment not found")
+
+func validateAssignment(a *types.Assignment) error {
+	if len(a.Labels) == 0 {
+		return errors.New("at least one label required")
+	}
+	if err := a.Labels.Validate(); err != nil {
+		return err
+	}
+	if a.ID != a.Labels.Fingerprint().String() {
+		return errors.New("ID does not match labels")
+	}
+	if a.AssignedBy == "" {
+		return errors.New("assigning user missing")
+	}
+	if a.UpdatedAt.IsZero() {
+		return errors.New("update time missing")
+	}
+	return nil
+}
+
+// Assignments holds the assignments of alerts.
+type Assignments struct {
+	retention time.Duration
+	now       func() time.Time
+	st        *gossipstate.State
+}
+
+// Options configures a new Assignments object.
+type Options struct {
+	// A snapshot file from which the initial state is loaded.
+	SnapshotFile string
+
+	// Assignments may be garbage collected the given duration after they
+	// were last updated.
+	Retention time.Duration
+
+	// A function creating a mesh.Gossip on being called with a mesh.Gossiper.
+	Gossip func(g mesh.Gossiper) mesh.Gossip
+
+	// A logger used by background processing.
+	Logger log.Logger
+}
+
+// New returns a new Assignments object with the given configuration.
+func New(o Options) (*Assignments, error) {
+	st, err := gossipstate.New(assignmentType{}, gossipstate.Options{
+		SnapshotFile: o.SnapshotFile,
+		Gossip:       o.Gossip,
+		Logger:       o.Logger,
+	})
+	if st == nil {
+		return nil, err
+	}
+	return &Assignments{
+		retention: o.Retention,
+		now:       gossipstate.UTCNow,
+		st:        st,
+	}, err
+}
+
+// Maintenance garbage collects the assignments at the given interval. If the
+// snapshot file is set, a snapshot is written to it afterwards.
+// Terminates on receiving from stopc.
+func (a *Assignments) Maintenance(interval time.Duration, snapf string, stopc <-chan struct{}) {
+	a.st.Maintenance(interval, snapf, stopc, a.GC)
+}
+
+// GC removes assignments that were last updated longer than the retention
+// time ago. It returns the number of removed assignments.
+func (a *Assignments) GC() (int, error) {
+	now := a.now()
+
+	return a.st.GC(func(e interface{}) bool {
+		return !e.(*types.Assignment).UpdatedAt.Add(a.retention).After(now)
+	}), nil
+}
+
+// Set assigns the alerts with the given labels to the assignee, replacing
+// a previous assignment of the labels. An empty assignee unassigns them.
+// It returns the ID of the assignment.
+func (a *Assignments) Set(lset model.LabelSet, assignee, assignedBy string) (string, error) {
+	as := &types.Assignment{
+		ID:         lset.Fingerprint().String(),
+		Labels:     lset,
+		Assignee:   assignee,
+		AssignedBy: assignedBy,
+		UpdatedAt:  a.now(),
+	}
+	if err := validateAssignment(as); err != nil {
+		return "", fmt.Errorf("invalid assignment: %s", err)
+	}
+
+	a.st.Lock()
+	defer a.st.Unlock()
+
+	if prev, ok := a.st.Get(as.ID); ok && prev.(*types.Assignment).Assignee == "" && assignee == "" {
+		return "", errors.New("alerts are not assigned")
+	}
+	a.st.Set(as)
+
+	return as.ID, nil
+}
+
+// Unassign removes the assignment with the given ID. If by is empty, the
+// user who made the assignment is recorded as removing it.
+func (a *Assignments) Unassign(id, by string) error {
+	a.st.RLock()
+	e, ok := a.st.Get(id)
+	a.st.RUnlock()
+
+	if !ok {
+		return ErrNotFound
+	}
+	as := e.(*types.Assignment)
+	if by == "" {
+		by = as.AssignedBy
+	}
+	_, err := a.Set(as.Labels, "", by)
+	return err
+}
+
+type assignmentSlice []*types.Assignment
+
+func (s assignmentSlice) Len() int           { return len(s) }
+func (s assignmentSlice) Less(i, j int) bool { return s[i].UpdatedAt.Before(s[j].UpdatedAt) }
+func (s assignmentSlice) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+// List returns all assignments that were not removed, ordered by their
+// update time.
+func (a *Assignments) List() []*types.Assignment {
+	a.st.RLock()
+	defer a.st.RUnlock()
+
+	res := assignmentSlice{}
+	a.st.Range(func(e interface{}) {
+		if as := e.(*types.Assignment); as.Assignee != "" {
+			res = append(res, as)
+		}
+	})
+	sort.Sort(res)
+	return res
+}
+
+// Assignee returns who an alert with the given labels that started firing at
+// the given time is assigned to. If several assignments apply, the one with
+// the most labels wins and, among those, the most recent one. It returns an
+// empty string if the alert is not assigned. It is safe to call on a nil
+// Assignments.
+func (a *Assignments) Assignee(lset model.LabelSet, startsAt time.Time) string {
+	if a == nil {
+		return ""
+	}
+	a.st.RLock()
+	defer a.st.RUnlock()
+
+	var res *types.Assignment
+	a.st.Range(func(e interface{}) {
+		as := e.(*types.Assignment)
+		if !as.Applies(lset, startsAt) {
+			return
+		}
+		if res == nil ||
+			len(as.Labels) > len(res.Labels) ||
+			len(as.Labels) == len(res.Labels) && as.UpdatedAt.After(res.UpdatedAt) {
+			res = as
+		}
+	})
+	if res == nil {
+		return ""
+	}
+	return res.Assignee
+}
+
+// assignmentType implements the handling of assignments by the state.
+type assignmentType struct{}
+
+func (assignmentType) Key(e interface{}) string {
+	return e.(*types.Assignment).ID
+}
+
+func (assignmentType) Decode(b []byte) (interface{}, error) {
+	var as types.Assignment
+	if err := json.Unmarshal(b, &as); err != nil {
+		return nil, err
+	}
+	if err := validateAssignment(&as); err != nil {
+		return nil, fmt.Errorf("invalid assignment: %s", err)
+	}
+	return &as, nil
+}
+
+func (assignmentType) Size(e interface{}) int {
+	as := e.(*types.Assignment)
+	return 64 * (len(as.Labels) + 2)
+}
+
+// Merge keeps the more recently updated assignment.
+func (assignmentType) Merge(prev, e interface{}) interface{} {
+	if !prev.(*types.Assignment).UpdatedAt.Before(e.(*types.Assignment).UpdatedAt) {
+		return nil
+	}
+	return e
+}
@@ -0,0 +1,106 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package assignment
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+
+	"github.com/prometheus/alertmanager/gossipstate"
+	"github.com/prometheus/alertmanager/types"
+)
+
+func TestAssignmentsSetAssignee(t *testing.T) {
+	a, err := New(Options{Retention: time.Hour})
+	require.NoError(t, err)
+
+	now := gossipstate.UTCNow()
+	a.now = func() time.Time { return now }
+
+	_, err = a.Set(model.LabelSet{"job": "api"}, "alice", "")
+	require.Error(t, err, "assigning user missing")
+
+	_, err = a.Set(model.LabelSet{"job": "api"}, "", "bob")
+	require.NoError(t, err)
+	_, err = a.Set(model.LabelSet{"job": "api"}, "", "bob")
+	require.Error(t, err, "alerts are not assigned")
+
+	group, err := a.Set(model.LabelSet{"job": "api"}, "alice", "bob")
+	require.NoError(t, err)
+
+	alert := model.LabelSet{"alertname": "HighLatency", "job": "api", "instance": "a"}
+	require.Equal(t, "alice", a.Assignee(alert, now.Add(-time.Hour)))
+
+	// Assignments made before the alert started firing do not apply.
+	require.Equal(t, "", a.Assignee(alert, now.Add(time.Second)))
+
+	// The assignment with the most labels wins, regardless of its age.
+	now = now.Add(time.Minute)
+	_, err = a.Set(model.LabelSet{"alertname": "HighLatency", "job": "api"}, "carol", "bob")
+	require.NoError(t, err)
+	now = now.Add(time.Minute)
+	_, err = a.Set(model.LabelSet{"job": "api"}, "dave", "bob")
+	require.NoError(t, err)
+	require.Equal(t, "carol", a.Assignee(alert, now.Add(-time.Hour)))
+	require.Equal(t, "dave", a.Assignee(model.LabelSet{"job": "api"}, now.Add(-time.Hour)))
+	require.Len(t, a.List(), 2)
+
+	require.NoError(t, a.Unassign(group, ""))
+	require.Equal(t, "", a.Assignee(model.LabelSet{"job": "api"}, now.Add(-time.Hour)))
+	require.Len(t, a.List(), 1)
+	require.Equal(t, ErrNotFound, a.Unassign("unknown", "bob"))
+
+	var nilAssignments *Assignments
+	require.Equal(t, "", nilAssignments.Assignee(alert, now))
+
+	now = now.Add(59*time.Minute + 30*time.Second)
+	n, err := a.GC()
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+}
+
+func TestAssignmentTypeMerge(t *testing.T) {
+	now := gossipstate.UTCNow()
+
+	newAssignment := func(assignee string, updated time.Time) *types.Assignment {
+		return &types.Assignment{
+			ID:         "a",
+			Labels:     model.LabelSet{"job": "api"},
+			Assignee:   assignee,
+			AssignedBy: "bob",
+			UpdatedAt:  updated,
+		}
+	}
+	prev := newAssignment("alice", now)
+
+	for _, c := range []struct {
+		name   string
+		e      *types.Assignment
+		merged bool
+	}{
+		{name: "newer", e: newAssignment("carol", now.Add(time.Minute)), merged: true},
+		{name: "same update", e: newAssignment("carol", now)},
+		{name: "older", e: newAssignment("carol", now.Add(-time.Minute))},
+	} {
+		res := assignmentType{}.Merge(prev, c.e)
+		if !c.merged {
+			require.Nil(t, res, c.name)
+			continue
+		}
+		require.Equal(t, c.e, res, c.name)
+	}
+}
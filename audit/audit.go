@@ -0,0 +1,342 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package audit records mutating HTTP requests in an append-only log.
+//
+// Each entry holds the authenticated user, the remote address, a summary of
+// the request and its result. Entries are appended to a file as JSON lines,
+// which is rotated once it exceeds a maximum size, and may additionally be
+// shipped to syslog.
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/prometheus/common/log"
+
+	"github.com/prometheus/alertmanager/auth"
+)
+
+// maxSummary is the maximum number of bytes of a request body and of an
+// error response recorded in an entry.
+const maxSummary = 512
+
+// Entry is a recorded request.
+type Entry struct {
+	Time       time.Time `json:"time"`
+	User       string    `json:"user,omitempty"`
+	RemoteAddr string    `json:"remoteAddr"`
+	Method     string    `json:"method"`
+	Path       string    `json:"path"`
+	Summary    string    `json:"summary,omitempty"`
+	Status     int       `json:"status"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// Log is an append-only audit log.
+type Log struct {
+	logger log.Logger
+	now    func() time.Time
+
+	mtx     sync.Mutex
+	file    *os.File
+	size    int64
+	maxSize int64
+	syslog  io.WriteCloser
+}
+
+// Options configures a new Log.
+type Options struct {
+	// The file entries are appended to.
+	File string
+
+	// The size in bytes from which on the file is rotated. The previous
+	// entries are kept in the file with the suffix ".1", replacing those
+	// rotated before. Zero disables rotation.
+	MaxSize int64
+
+	// The syslog server entries are shipped to. It is either "local" for
+	// the local syslog daemon or an address like "udp://host:514". Entries
+	// are not shipped if it is empty.
+	Syslog string
+
+	// A logger used to report failures to record entries.
+	Logger log.Logger
+}
+
+// New opens the audit log with the given configuration.
+func New(o Options) (*Log, error) {
+	l := &Log{
+		logger:  log.NewNopLogger(),
+		now:     time.Now,
+		maxSize: o.MaxSize,
+	}
+	if err := l.open(o.File); err != nil {
+		return nil, err
+	}
+	if o.Logger != nil {
+		l.logger = o.Logger
+	}
+	if o.Syslog != "" {
+		var err error
+		if l.syslog, err = dialSyslog(o.Syslog); err != nil {
+			l.file.Close()
+			return nil, err
+		}
+	}
+	return l, nil
+}
+
+// open opens the file entries are appended to.
+func (l *Log) open(name string) error {
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	l.file, l.size = f, fi.Size()
+	return nil
+}
+
+// rotate moves the entries to the rotated file and starts a new file. It
+// must be called with the lock held.
+func (l *Log) rotate() error {
+	name := l.file.Name()
+	if err := l.file.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(name, name+".1"); err != nil {
+		return err
+	}
+	return l.open(name)
+}
+
+// Close closes the log.
+func (l *Log) Close() error {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	if l.syslog != nil {
+		l.syslog.Close()
+	}
+	return l.file.Close()
+}
+
+// Record appends the entry to the log. The entry is written to disk before
+// Record returns. Failing to ship the entry to syslog is not an error.
+func (l *Log) Record(e *Entry) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	n, err := l.file.Write(append(b, '\n'))
+	l.size += int64(n)
+	if err != nil {
+		return err
+	}
+	if err := l.file.Sync(); err != nil {
+		return err
+	}
+	if l.maxSize > 0 && l.size >= l.maxSize {
+		if err := l.rotate(); err != nil {
+			l.logger.With("err", err).Error("rotating audit log failed")
+		}
+	}
+	if l.syslog != nil {
+		if _, err := l.syslog.Write(b); err != nil {
+			l.logger.With("err", err).Error("shipping audit log entry to syslog failed")
+		}
+	}
+	return nil
+}
+
+// Query returns the most recent entries since the given time, newest first,
+// and the total number of such entries. If user is not empty, only the
+// entries of that user are returned. If limit is positive, at most limit
+// entries are returned. The rotated entries are included.
+func (l *Log) Query(since time.Time, user string, limit int) ([]*Entry, int, error) {
+	l.mtx.Lock()
+	name := l.file.Name()
+	l.mtx.Unlock()
+
+	var (
+		res   []*Entry
+		total int
+	)
+	for _, fn := range []string{name + ".1", name} {
+		f, err := os.Open(fn)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		sc := bufio.NewScanner(f)
+		sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+		for sc.Scan() {
+			var e Entry
+			if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+				// A crash may leave a partially written last line.
+				continue
+			}
+			if e.Time.Before(since) || (user != "" && e.User != user) {
+				continue
+			}
+			// Only the last limit entries are kept in a ring buffer.
+			if limit > 0 && len(res) == limit {
+				res[total%limit] = &e
+			} else {
+				res = append(res, &e)
+			}
+			total++
+		}
+		err = sc.Err()
+		f.Close()
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+	if limit > 0 && total > limit {
+		k := total % limit
+		res = append(append([]*Entry{}, res[k:]...), res[:k]...)
+	}
+	for i, j := 0, len(res)-1; i < j; i, j = i+1, j-1 {
+		res[i], res[j] = res[j], res[i]
+	}
+	return res, total, nil
+}
+
+// Handler returns a handler that serves requests with the given handler and
+// records all requests that are not GET, HEAD or OPTIONS requests. Requests
+// to the given excluded paths, or below them if they end with a slash, are
+// not recorded either. The user is taken from the request's context.
+func (l *Log) Handler(next http.Handler, excluded ...string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" || r.Method == "HEAD" || r.Method == "OPTIONS" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		for _, p := range excluded {
+			if r.URL.Path == p || (strings.HasSuffix(p, "/") && strings.HasPrefix(r.URL.Path, p)) {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+
+		e := &Entry{
+			Time:       l.now(),
+			RemoteAddr: r.RemoteAddr,
+			Method:     r.Method,
+			Path:       r.URL.Path,
+			Summary:    summarize(r),
+		}
+		e.User, _ = auth.User(r.Context())
+
+		rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		e.Status = rec.status
+		if rec.status >= 400 {
+			e.Error = responseError(rec.body.Bytes())
+		}
+		if err := l.Record(e); err != nil {
+			l.logger.With("err", err).With("path", e.Path).Error("recording audit log entry failed")
+		}
+	})
+}
+
+// summarize returns the query and the beginning of the body of a request.
+// The body remains readable by the handler.
+func summarize(r *http.Request) string {
+	var s []string
+	if r.URL.RawQuery != "" {
+		s = append(s, "?"+r.URL.RawQuery)
+	}
+	if r.Body != nil {
+		buf := make([]byte, maxSummary+1)
+		n, _ := io.ReadFull(r.Body, buf)
+		r.Body = readCloser{
+			Reader: io.MultiReader(bytes.NewReader(buf[:n]), r.Body),
+			Closer: r.Body,
+		}
+		switch {
+		case n == 0:
+		case !utf8.Valid(buf[:n]):
+			s = append(s, "<binary body>")
+		default:
+			s = append(s, truncate(string(buf[:n])))
+		}
+	}
+	return strings.Join(s, " ")
+}
+
+// responseError returns the error message of an error response. API errors
+// are JSON objects holding the message in their error field.
+func responseError(b []byte) string {
+	var resp struct {
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(b, &resp); err == nil && resp.Error != "" {
+		return truncate(resp.Error)
+	}
+	return truncate(strings.TrimSpace(string(b)))
+}
+
+func truncate(s string) string {
+	if len(s) > maxSummary {
+		return s[:maxSummary] + "..."
+	}
+	return s
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// responseRecorder records the status code and the beginning of the body
+// of error responses.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(code int) {
+	r.status = code
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	if r.status >= 400 && r.body.Len() <= maxSummary {
+		r.body.Write(b)
+	}
+	return r.ResponseWriter.Write(b)
+}
@@ -0,0 +1,146 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/prometheus/alertmanager/auth"
+)
+
+func TestHandler(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	l, err := New(Options{File: filepath.Join(dir, "audit.log")})
+	require.NoError(t, err)
+	defer func() { l.Close() }()
+
+	now := time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC)
+	l.now = func() time.Time { return now }
+
+	var body string
+	h := l.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		body = string(b)
+
+		if strings.HasSuffix(r.URL.Path, "/fail") {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"status":"error","errorType":"bad_data","error":"invalid silence"}`))
+		}
+	}), "/api/graphql", "/api/v1/ingest/")
+
+	serve := func(method, path, user, reqBody string) {
+		req := httptest.NewRequest(method, path, strings.NewReader(reqBody))
+		req.RemoteAddr = "10.0.0.1:1234"
+		if user != "" {
+			req = req.WithContext(auth.WithUser(req.Context(), user))
+		}
+		h.ServeHTTP(httptest.NewRecorder(), req)
+		require.Equal(t, reqBody, body, "handler must receive the whole body")
+	}
+
+	serve("GET", "/api/v1/silences", "alice", "")
+	serve("POST", "/api/graphql", "alice", `{"query":"{ status }"}`)
+	serve("POST", "/api/v1/ingest/grafana", "alice", `{}`)
+
+	serve("POST", "/api/v1/silences?override=true", "alice", `{"comment":"deploy"}`)
+	now = now.Add(time.Minute)
+	serve("DELETE", "/api/v1/silence/fail", "bob", "")
+	now = now.Add(time.Minute)
+	long := strings.Repeat("x", 2*maxSummary)
+	serve("POST", "/-/reload", "", long)
+
+	entries, total, err := l.Query(time.Time{}, "", 0)
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	require.Equal(t, 3, total)
+
+	require.Equal(t, &Entry{
+		Time:       now.Add(-2 * time.Minute),
+		User:       "alice",
+		RemoteAddr: "10.0.0.1:1234",
+		Method:     "POST",
+		Path:       "/api/v1/silences",
+		Summary:    `?override=true {"comment":"deploy"}`,
+		Status:     http.StatusOK,
+	}, entries[2])
+	require.Equal(t, "invalid silence", entries[1].Error)
+	require.Equal(t, http.StatusBadRequest, entries[1].Status)
+	require.Equal(t, long[:maxSummary]+"...", entries[0].Summary)
+
+	entries, _, err = l.Query(now.Add(-time.Minute), "bob", 0)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, "/api/v1/silence/fail", entries[0].Path)
+
+	entries, total, err = l.Query(time.Time{}, "", 2)
+	require.NoError(t, err)
+	require.Equal(t, 3, total)
+	require.Len(t, entries, 2)
+	require.Equal(t, "/-/reload", entries[0].Path)
+	require.Equal(t, "/api/v1/silence/fail", entries[1].Path)
+
+	// Entries survive reopening the log.
+	require.NoError(t, l.Close())
+	l, err = New(Options{File: filepath.Join(dir, "audit.log")})
+	require.NoError(t, err)
+	entries, _, err = l.Query(time.Time{}, "alice", 0)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+}
+
+func TestRotate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "audit.log")
+	l, err := New(Options{File: file, MaxSize: 300})
+	require.NoError(t, err)
+	defer func() { l.Close() }()
+
+	for i := 0; i < 10; i++ {
+		require.NoError(t, l.Record(&Entry{
+			Time:   time.Date(2016, 1, 1, 0, i, 0, 0, time.UTC),
+			Method: "POST",
+			Path:   fmt.Sprintf("/api/v1/silences/%d", i),
+		}))
+	}
+	fi, err := os.Stat(file)
+	require.NoError(t, err)
+	require.True(t, fi.Size() < 300)
+	_, err = os.Stat(file + ".1")
+	require.NoError(t, err)
+
+	// Queries span the rotated file, while older entries are dropped.
+	entries, total, err := l.Query(time.Time{}, "", 3)
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	require.True(t, total > 3 && total < 10, "total %d", total)
+	require.Equal(t, "/api/v1/silences/9", entries[0].Path)
+	require.Equal(t, "/api/v1/silences/7", entries[2].Path)
+}
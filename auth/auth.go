@@ -0,0 +1,447 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package auth authenticates users of the web interface and the API against
+// an OpenID Connect provider.
+//
+// Browsers are redirected to the provider to log in and then hold a signed
+// session cookie. Other clients authenticate with an ID token issued to the
+// Alertmanager's client as bearer token.
+//
+// Automation accounts authenticate with API keys as bearer token instead.
+// API keys are accepted whether or not OpenID Connect is configured and only
+// grant access to the API.
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/common/log"
+	"golang.org/x/net/context"
+
+	"github.com/prometheus/alertmanager/apikey"
+	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/types"
+)
+
+const (
+	sessionCookie = "alertmanager_session"
+	stateCookie   = "alertmanager_oidc_state"
+
+	// loginTimeout is how long a login at the provider may take.
+	loginTimeout = 10 * time.Minute
+)
+
+type contextKey int
+
+const (
+	keyUser contextKey = iota
+	keyScope
+)
+
+// WithUser returns a context holding the name of the authenticated user.
+func WithUser(ctx context.Context, user string) context.Context {
+	return context.WithValue(ctx, keyUser, user)
+}
+
+// User returns the name of the authenticated user held by the context. Iff
+// there is none, the second argument is false.
+func User(ctx context.Context) (string, bool) {
+	if ctx == nil {
+		return "", false
+	}
+	u, ok := ctx.Value(keyUser).(string)
+	return u, ok
+}
+
+// WithScope returns a context holding the matchers an API key is restricted
+// to.
+func WithScope(ctx context.Context, scope types.Matchers) context.Context {
+	return context.WithValue(ctx, keyScope, scope)
+}
+
+// Scope returns the matchers the API key of the request is restricted to.
+// Iff the request was not authenticated with an API key, the second
+// argument is false. An API key without matchers is not restricted.
+func Scope(ctx context.Context) (types.Matchers, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+	s, ok := ctx.Value(keyScope).(types.Matchers)
+	return s, ok
+}
+
+// Authenticator protects an HTTP handler by requiring authentication
+// against an OpenID Connect provider.
+type Authenticator struct {
+	externalURL *url.URL
+	client      *http.Client
+	keys        *apikey.Keys
+	now         func() time.Time
+
+	mtx      sync.RWMutex
+	conf     *config.OIDCConfig
+	provider *provider
+	key      []byte
+}
+
+// New returns an Authenticator for the Alertmanager reachable under the
+// external URL. It lets all requests pass until it is configured, except
+// those presenting an invalid API key. If keys is nil, API keys are not
+// accepted.
+func New(externalURL *url.URL, keys *apikey.Keys) *Authenticator {
+	return &Authenticator{
+		externalURL: externalURL,
+		client:      &http.Client{Timeout: 30 * time.Second},
+		keys:        keys,
+		now:         time.Now,
+	}
+}
+
+// ApplyConfig sets the OIDC configuration. A nil configuration disables
+// authentication.
+func (a *Authenticator) ApplyConfig(c *config.OIDCConfig) {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	if c == nil {
+		a.conf, a.provider, a.key = nil, nil, nil
+		return
+	}
+	if a.provider == nil || a.conf.Issuer != c.Issuer {
+		a.provider = newProvider(c.Issuer, a.client)
+	}
+	a.conf = c
+	// Sessions are signed with a key derived from the client secret so that
+	// all replicas sharing the configuration accept them.
+	mac := hmac.New(sha256.New, []byte(c.ClientSecret))
+	mac.Write([]byte("alertmanager session"))
+	a.key = mac.Sum(nil)
+}
+
+func (a *Authenticator) state() (*config.OIDCConfig, *provider, []byte) {
+	a.mtx.RLock()
+	defer a.mtx.RUnlock()
+
+	return a.conf, a.provider, a.key
+}
+
+// Handler returns a handler that serves requests of authenticated users
+// with the given handler. The name of the user is added to the context of
+// the request. The health endpoints, metrics and CORS preflight requests are
+// served without authentication.
+func (a *Authenticator) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if a.keys != nil && strings.HasPrefix(r.Header.Get("Authorization"), "Bearer "+apikey.Prefix) {
+			a.serveAPIKey(w, r, next)
+			return
+		}
+		conf, prov, key := a.state()
+		// Browsers send preflight requests without credentials.
+		if conf == nil || r.Method == "OPTIONS" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		switch r.URL.Path {
+		case a.path("/-/healthy"), a.path("/-/ready"), a.path("/metrics"):
+			next.ServeHTTP(w, r)
+			return
+		case a.path("/-/oidc/callback"):
+			a.callback(w, r, conf, prov, key)
+			return
+		case a.path("/-/oidc/logout"):
+			a.setCookie(w, sessionCookie, "", a.path("/"), -1)
+			http.Redirect(w, r, a.path("/"), http.StatusFound)
+			return
+		}
+
+		user, err := a.authenticate(r, conf, prov, key)
+		if err != nil {
+			log.With("path", r.URL.Path).Debugf("Authentication failed: %s", err)
+			if r.Method == "GET" && r.Header.Get("Authorization") == "" && !strings.HasPrefix(r.URL.Path, a.path("/api")) {
+				a.login(w, r, conf, prov, key)
+				return
+			}
+			w.Header().Set("WWW-Authenticate", `Bearer realm="alertmanager"`)
+			http.Error(w, "Unauthorized: "+err.Error(), http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(WithUser(r.Context(), user)))
+	})
+}
+
+// serveAPIKey serves a request authenticated with an API key. The key's
+// name prefixed with "apikey:" is used as user name.
+func (a *Authenticator) serveAPIKey(w http.ResponseWriter, r *http.Request, next http.Handler) {
+	k, err := a.keys.Authenticate(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "))
+	if err != nil {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="alertmanager"`)
+		http.Error(w, "Unauthorized: "+err.Error(), http.StatusUnauthorized)
+		return
+	}
+	if !strings.HasPrefix(r.URL.Path, a.path("/api")+"/") {
+		http.Error(w, "Forbidden: API keys only grant access to the API", http.StatusForbidden)
+		return
+	}
+	ctx := WithScope(WithUser(r.Context(), "apikey:"+k.Name), k.Matchers)
+	next.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// path returns the path of an endpoint under the external URL.
+func (a *Authenticator) path(p string) string {
+	return path.Join("/", a.externalURL.Path, p)
+}
+
+// authenticate returns the user of the request's bearer token or session.
+func (a *Authenticator) authenticate(r *http.Request, conf *config.OIDCConfig, prov *provider, key []byte) (string, error) {
+	if h := r.Header.Get("Authorization"); h != "" {
+		if !strings.HasPrefix(h, "Bearer ") {
+			return "", errors.New("unsupported authorization scheme")
+		}
+		return a.verifyToken(r.Context(), strings.TrimPrefix(h, "Bearer "), "", conf, prov)
+	}
+	c, err := r.Cookie(sessionCookie)
+	if err != nil {
+		return "", errors.New("not logged in")
+	}
+	var s session
+	if err := a.decode(c.Value, key, &s); err != nil {
+		return "", err
+	}
+	return s.User, nil
+}
+
+// verifyToken verifies an ID token and returns its user. An empty nonce is
+// not checked.
+func (a *Authenticator) verifyToken(ctx context.Context, token, nonce string, conf *config.OIDCConfig, prov *provider) (string, error) {
+	if err := prov.discover(ctx); err != nil {
+		return "", err
+	}
+	c, err := parseJWT(token, func(kid string) (*rsa.PublicKey, error) {
+		return prov.key(ctx, kid)
+	})
+	if err != nil {
+		return "", err
+	}
+	if err := c.verify(prov.issuer, conf.ClientID, a.now()); err != nil {
+		return "", err
+	}
+	if nonce != "" && c.Nonce != nonce {
+		return "", errors.New("token nonce mismatch")
+	}
+	user := c.stringClaim(conf.UsernameClaim)
+	if user == "" {
+		return "", fmt.Errorf("token lacks the %q claim", conf.UsernameClaim)
+	}
+	if len(conf.AllowedGroups) > 0 && !intersects(c.stringsClaim(conf.GroupsClaim), conf.AllowedGroups) {
+		return "", fmt.Errorf("user %q is not a member of an allowed group", user)
+	}
+	return user, nil
+}
+
+// session is the content of the session cookie.
+type session struct {
+	User    string `json:"user"`
+	Expires int64  `json:"exp"`
+}
+
+// loginState is the content of the cookie that ties the provider's callback
+// to the login it was started by.
+type loginState struct {
+	State    string `json:"state"`
+	Nonce    string `json:"nonce"`
+	Redirect string `json:"redirect"`
+	Expires  int64  `json:"exp"`
+}
+
+// login redirects to the provider to authenticate the user.
+func (a *Authenticator) login(w http.ResponseWriter, r *http.Request, conf *config.OIDCConfig, prov *provider, key []byte) {
+	if err := prov.discover(r.Context()); err != nil {
+		log.Errorf("Cannot log in: %s", err)
+		http.Error(w, "Login unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	st := loginState{
+		State:    randomString(),
+		Nonce:    randomString(),
+		Redirect: r.URL.RequestURI(),
+		Expires:  a.now().Add(loginTimeout).Unix(),
+	}
+	v, err := a.encode(st, key)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	a.setCookie(w, stateCookie, v, a.path("/-/oidc/callback"), int(loginTimeout.Seconds()))
+
+	q := url.Values{
+		"response_type": {"code"},
+		"client_id":     {conf.ClientID},
+		"redirect_uri":  {a.callbackURL()},
+		"scope":         {strings.Join(append([]string{"openid"}, conf.Scopes...), " ")},
+		"state":         {st.State},
+		"nonce":         {st.Nonce},
+	}
+	u := prov.authURL
+	if strings.Contains(u, "?") {
+		u += "&" + q.Encode()
+	} else {
+		u += "?" + q.Encode()
+	}
+	http.Redirect(w, r, u, http.StatusFound)
+}
+
+// callback completes a login when the provider redirects back.
+func (a *Authenticator) callback(w http.ResponseWriter, r *http.Request, conf *config.OIDCConfig, prov *provider, key []byte) {
+	q := r.URL.Query()
+	if e := q.Get("error"); e != "" {
+		http.Error(w, fmt.Sprintf("Login failed: %s %s", e, q.Get("error_description")), http.StatusUnauthorized)
+		return
+	}
+	c, err := r.Cookie(stateCookie)
+	if err != nil {
+		http.Error(w, "Login failed: missing login state", http.StatusBadRequest)
+		return
+	}
+	var st loginState
+	if err := a.decode(c.Value, key, &st); err != nil || st.State != q.Get("state") {
+		http.Error(w, "Login failed: invalid login state", http.StatusBadRequest)
+		return
+	}
+	a.setCookie(w, stateCookie, "", a.path("/-/oidc/callback"), -1)
+
+	if err := prov.discover(r.Context()); err != nil {
+		http.Error(w, "Login failed: "+err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	token, err := prov.exchange(r.Context(), conf.ClientID, string(conf.ClientSecret), q.Get("code"), a.callbackURL())
+	if err != nil {
+		http.Error(w, "Login failed: "+err.Error(), http.StatusUnauthorized)
+		return
+	}
+	user, err := a.verifyToken(r.Context(), token, st.Nonce, conf, prov)
+	if err != nil {
+		http.Error(w, "Login failed: "+err.Error(), http.StatusForbidden)
+		return
+	}
+
+	s := session{
+		User:    user,
+		Expires: a.now().Add(time.Duration(conf.SessionDuration)).Unix(),
+	}
+	v, err := a.encode(s, key)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	a.setCookie(w, sessionCookie, v, a.path("/"), int(time.Duration(conf.SessionDuration).Seconds()))
+	log.With("user", user).Info("User logged in")
+
+	// Only redirect to local paths.
+	redirect := st.Redirect
+	if !strings.HasPrefix(redirect, "/") || strings.HasPrefix(redirect, "//") {
+		redirect = a.path("/")
+	}
+	http.Redirect(w, r, redirect, http.StatusFound)
+}
+
+func (a *Authenticator) callbackURL() string {
+	u := *a.externalURL
+	u.Path = a.path("/-/oidc/callback")
+	u.RawQuery = ""
+	return u.String()
+}
+
+// setCookie sets an HTTP-only cookie. A negative maxAge deletes it.
+func (a *Authenticator) setCookie(w http.ResponseWriter, name, value, path string, maxAge int) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     path,
+		MaxAge:   maxAge,
+		Secure:   a.externalURL.Scheme == "https",
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// encode returns the signed JSON encoding of v.
+func (a *Authenticator) encode(v interface{}, key []byte) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	payload := base64.RawURLEncoding.EncodeToString(b)
+	return payload + "." + sign(payload, key), nil
+}
+
+// decode verifies the signature and expiry of an encoded value.
+func (a *Authenticator) decode(s string, key []byte, v interface{}) error {
+	i := strings.LastIndex(s, ".")
+	if i < 0 || !hmac.Equal([]byte(s[i+1:]), []byte(sign(s[:i], key))) {
+		return errors.New("invalid signature")
+	}
+	b, err := base64.RawURLEncoding.DecodeString(s[:i])
+	if err != nil {
+		return err
+	}
+	var exp struct {
+		Expires int64 `json:"exp"`
+	}
+	if err := json.Unmarshal(b, &exp); err != nil {
+		return err
+	}
+	if a.now().After(time.Unix(exp.Expires, 0)) {
+		return errors.New("session expired")
+	}
+	return json.Unmarshal(b, v)
+}
+
+func sign(payload string, key []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func randomString() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+func intersects(a, b []string) bool {
+	for _, x := range a {
+		for _, y := range b {
+			if x == y {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,268 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+
+	"github.com/prometheus/alertmanager/apikey"
+	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/types"
+)
+
+// testProvider is an OpenID Connect provider issuing tokens for the code
+// "code" with the nonce of the last authorization request.
+type testProvider struct {
+	*httptest.Server
+	key   *rsa.PrivateKey
+	nonce string
+}
+
+func newTestProvider(t *testing.T) *testProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err)
+
+	p := &testProvider{key: key}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 p.URL,
+			"authorization_endpoint": p.URL + "/auth",
+			"token_endpoint":         p.URL + "/token",
+			"jwks_uri":               p.URL + "/keys",
+		})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": "k1",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		id, secret, _ := r.BasicAuth()
+		if id != "am" || secret != "s3cret" || r.FormValue("code") != "code" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"invalid_grant"}`))
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{
+			"id_token": p.token(t, map[string]interface{}{"nonce": p.nonce}),
+		})
+	})
+	p.Server = httptest.NewServer(mux)
+	return p
+}
+
+// token returns a signed ID token for the user alice in the group ops.
+// The given claims override the defaults.
+func (p *testProvider) token(t *testing.T, override map[string]interface{}) string {
+	c := map[string]interface{}{
+		"iss":    p.URL,
+		"aud":    "am",
+		"exp":    time.Now().Add(time.Hour).Unix(),
+		"email":  "alice@example.com",
+		"groups": []string{"ops"},
+	}
+	for k, v := range override {
+		c[k] = v
+	}
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "kid": "k1"})
+	require.NoError(t, err)
+	claims, err := json.Marshal(c)
+	require.NoError(t, err)
+
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	h := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, p.key, crypto.SHA256, h[:])
+	require.NoError(t, err)
+
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func TestHandler(t *testing.T) {
+	p := newTestProvider(t)
+	defer p.Close()
+
+	extURL, _ := url.Parse("https://am.example.com/am")
+	a := New(extURL, nil)
+
+	var user string
+	h := a.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, _ = User(r.Context())
+	}))
+	serve := func(method, path string, header http.Header) *httptest.ResponseRecorder {
+		user = ""
+		req := httptest.NewRequest(method, path, nil)
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+	bearer := func(token string) http.Header {
+		return http.Header{"Authorization": {"Bearer " + token}}
+	}
+
+	// Without configuration, all requests pass.
+	require.Equal(t, http.StatusOK, serve("GET", "/am/api/v1/status", nil).Code)
+
+	a.ApplyConfig(&config.OIDCConfig{
+		Issuer:          p.URL,
+		ClientID:        "am",
+		ClientSecret:    "s3cret",
+		UsernameClaim:   "email",
+		GroupsClaim:     "groups",
+		AllowedGroups:   []string{"ops", "dev"},
+		SessionDuration: model.Duration(time.Hour),
+	})
+
+	require.Equal(t, http.StatusOK, serve("GET", "/am/-/ready", nil).Code)
+	require.Equal(t, http.StatusOK, serve("OPTIONS", "/am/api/v1/silences", nil).Code)
+	require.Equal(t, http.StatusUnauthorized, serve("GET", "/am/api/v1/status", nil).Code)
+
+	rec := serve("POST", "/am/api/v1/silences", bearer(p.token(t, nil)))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "alice@example.com", user)
+
+	for _, override := range []map[string]interface{}{
+		{"aud": "other"},
+		{"iss": "https://evil.example.com"},
+		{"exp": time.Now().Add(-time.Hour).Unix()},
+		{"groups": []string{"finance"}},
+		{"email": nil},
+	} {
+		rec := serve("GET", "/am/api/v1/status", bearer(p.token(t, override)))
+		require.Equal(t, http.StatusUnauthorized, rec.Code, "%v", override)
+		require.Equal(t, "", user)
+	}
+	tampered := p.token(t, nil)
+	require.Equal(t, http.StatusUnauthorized, serve("GET", "/am/api/v1/status", bearer(tampered[:len(tampered)-4]+"AAAA")).Code)
+
+	// Browsers are redirected to the provider and back.
+	rec = serve("GET", "/am/?view=silences", nil)
+	require.Equal(t, http.StatusFound, rec.Code)
+	loc, err := url.Parse(rec.Header().Get("Location"))
+	require.NoError(t, err)
+	require.Equal(t, p.URL+"/auth", loc.Scheme+"://"+loc.Host+loc.Path)
+	require.Equal(t, "am", loc.Query().Get("client_id"))
+	require.Equal(t, "https://am.example.com/am/-/oidc/callback", loc.Query().Get("redirect_uri"))
+	require.Equal(t, "openid", loc.Query().Get("scope"))
+	p.nonce = loc.Query().Get("nonce")
+
+	stateCookie := rec.Result().Cookies()[0]
+	cookie := func(c *http.Cookie) http.Header {
+		return http.Header{"Cookie": {c.Name + "=" + c.Value}}
+	}
+	callback := "/am/-/oidc/callback?code=code&state=" + loc.Query().Get("state")
+
+	require.Equal(t, http.StatusBadRequest, serve("GET", callback, nil).Code)
+	require.Equal(t, http.StatusBadRequest, serve("GET", "/am/-/oidc/callback?code=code&state=other", cookie(stateCookie)).Code)
+
+	rec = serve("GET", callback, cookie(stateCookie))
+	require.Equal(t, http.StatusFound, rec.Code)
+	require.Equal(t, "/am/?view=silences", rec.Header().Get("Location"))
+
+	var session *http.Cookie
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == sessionCookie {
+			session = c
+		}
+	}
+	require.NotNil(t, session)
+	require.True(t, session.HttpOnly)
+	require.True(t, session.Secure)
+
+	rec = serve("GET", "/am/api/v1/status", cookie(session))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "alice@example.com", user)
+
+	// Sessions expire.
+	a.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	require.Equal(t, http.StatusUnauthorized, serve("GET", "/am/api/v1/status", cookie(session)).Code)
+	a.now = time.Now
+
+	// Sessions are invalidated by a new client secret.
+	a.ApplyConfig(&config.OIDCConfig{
+		Issuer:          p.URL,
+		ClientID:        "am",
+		ClientSecret:    "rotated",
+		UsernameClaim:   "email",
+		SessionDuration: model.Duration(time.Hour),
+	})
+	require.Equal(t, http.StatusUnauthorized, serve("GET", "/am/api/v1/status", cookie(session)).Code)
+}
+
+func TestHandlerAPIKey(t *testing.T) {
+	keys, err := apikey.New(apikey.Options{})
+	require.NoError(t, err)
+	scope := types.Matchers{types.NewMatcher("team", "payments")}
+	_, tok, err := keys.Create("ci", scope, "me", time.Time{})
+	require.NoError(t, err)
+
+	extURL, _ := url.Parse("http://am.example.com/")
+	a := New(extURL, keys)
+
+	var (
+		user     string
+		gotScope types.Matchers
+		scoped   bool
+	)
+	h := a.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, _ = User(r.Context())
+		gotScope, scoped = Scope(r.Context())
+	}))
+	serve := func(path, token string) int {
+		user, gotScope, scoped = "", nil, false
+		req := httptest.NewRequest("POST", path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// API keys are verified even without OIDC configuration.
+	require.Equal(t, http.StatusOK, serve("/api/v1/silences", ""))
+	require.False(t, scoped)
+	require.Equal(t, http.StatusUnauthorized, serve("/api/v1/silences", tok+"x"))
+
+	require.Equal(t, http.StatusOK, serve("/api/v1/silences", tok))
+	require.Equal(t, "apikey:ci", user)
+	require.True(t, scoped)
+	require.True(t, gotScope.Equal(scope))
+
+	require.Equal(t, http.StatusForbidden, serve("/-/reload", tok))
+	require.Equal(t, http.StatusForbidden, serve("/apikeys", tok))
+}
@@ -0,0 +1,190 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	// Register the hash functions of the supported signing algorithms.
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+)
+
+// signingHashes maps the supported JWS algorithms to their hash functions.
+var signingHashes = map[string]crypto.Hash{
+	"RS256": crypto.SHA256,
+	"RS384": crypto.SHA384,
+	"RS512": crypto.SHA512,
+}
+
+// clockSkew is the tolerated difference between our clock and the
+// provider's when checking the expiry of tokens.
+const clockSkew = time.Minute
+
+// jsonWebKey is an RSA key of a JSON web key set.
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// publicKey returns the RSA public key or nil if the key is not an RSA
+// signing key.
+func (k *jsonWebKey) publicKey() (*rsa.PublicKey, error) {
+	if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") {
+		return nil, nil
+	}
+	n, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil {
+		return nil, fmt.Errorf("invalid modulus of key %q: %s", k.Kid, err)
+	}
+	e, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil {
+		return nil, fmt.Errorf("invalid exponent of key %q: %s", k.Kid, err)
+	}
+	return &rsa.PublicKey{
+		N: new(big.Int).SetBytes(n),
+		E: int(new(big.Int).SetBytes(e).Int64()),
+	}, nil
+}
+
+// claims are the claims of an ID token. Other claims, such as the user name
+// and groups, are read from the raw claims.
+type claims struct {
+	Issuer   string   `json:"iss"`
+	Audience audience `json:"aud"`
+	Expiry   int64    `json:"exp"`
+	Nonce    string   `json:"nonce"`
+
+	raw map[string]interface{}
+}
+
+// audience is the aud claim, which is either a string or a list of strings.
+type audience []string
+
+func (a *audience) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*a = audience{s}
+		return nil
+	}
+	return json.Unmarshal(b, (*[]string)(a))
+}
+
+func (a audience) contains(s string) bool {
+	for _, x := range a {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
+
+// stringClaim returns the claim if it is a string.
+func (c *claims) stringClaim(name string) string {
+	s, _ := c.raw[name].(string)
+	return s
+}
+
+// stringsClaim returns the claim if it is a string or a list of strings.
+func (c *claims) stringsClaim(name string) []string {
+	switch v := c.raw[name].(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		var res []string
+		for _, x := range v {
+			if s, ok := x.(string); ok {
+				res = append(res, s)
+			}
+		}
+		return res
+	}
+	return nil
+}
+
+// parseJWT verifies the signature of a compact serialized JWT and returns
+// its claims. The key function returns the key with the given ID.
+func parseJWT(token string, key func(kid string) (*rsa.PublicKey, error)) (*claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed token")
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("invalid token header: %s", err)
+	}
+	hash, ok := signingHashes[header.Alg]
+	if !ok {
+		return nil, fmt.Errorf("unsupported signing algorithm %q", header.Alg)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("invalid token signature: %s", err)
+	}
+	pub, err := key(header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	h := hash.New()
+	h.Write([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(pub, hash, h.Sum(nil), sig); err != nil {
+		return nil, errors.New("invalid token signature")
+	}
+
+	var c claims
+	if err := decodeSegment(parts[1], &c); err != nil {
+		return nil, fmt.Errorf("invalid token claims: %s", err)
+	}
+	if err := decodeSegment(parts[1], &c.raw); err != nil {
+		return nil, fmt.Errorf("invalid token claims: %s", err)
+	}
+	return &c, nil
+}
+
+// verify checks that the claims were issued by the issuer for the client
+// and have not expired.
+func (c *claims) verify(issuer, clientID string, now time.Time) error {
+	if c.Issuer != issuer {
+		return fmt.Errorf("token issued by %q instead of %q", c.Issuer, issuer)
+	}
+	if !c.Audience.contains(clientID) {
+		return errors.New("token not issued for this client")
+	}
+	if now.Add(-clockSkew).After(time.Unix(c.Expiry, 0)) {
+		return errors.New("token expired")
+	}
+	return nil
+}
+
+func decodeSegment(s string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
@@ -0,0 +1,185 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+	"golang.org/x/net/context/ctxhttp"
+)
+
+// minKeyRefresh is the minimum interval between fetches of the provider's
+// keys triggered by tokens signed with unknown keys.
+const minKeyRefresh = time.Minute
+
+// provider is an OpenID Connect provider. Its endpoints are discovered on
+// first use.
+type provider struct {
+	issuer string
+	client *http.Client
+
+	mtx        sync.Mutex
+	discovered bool
+	authURL    string
+	tokenURL   string
+	jwksURL    string
+	keys       map[string]*rsa.PublicKey
+	keysAt     time.Time
+}
+
+func newProvider(issuer string, client *http.Client) *provider {
+	return &provider{
+		issuer: strings.TrimSuffix(issuer, "/"),
+		client: client,
+	}
+}
+
+// discover fetches the discovery document of the provider unless it was
+// already fetched.
+func (p *provider) discover(ctx context.Context) error {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	if p.discovered {
+		return nil
+	}
+	var doc struct {
+		Issuer   string `json:"issuer"`
+		AuthURL  string `json:"authorization_endpoint"`
+		TokenURL string `json:"token_endpoint"`
+		JWKSURL  string `json:"jwks_uri"`
+	}
+	if err := p.get(ctx, p.issuer+"/.well-known/openid-configuration", &doc); err != nil {
+		return fmt.Errorf("OIDC discovery failed: %s", err)
+	}
+	if strings.TrimSuffix(doc.Issuer, "/") != p.issuer {
+		return fmt.Errorf("OIDC discovery returned issuer %q instead of %q", doc.Issuer, p.issuer)
+	}
+	if doc.AuthURL == "" || doc.TokenURL == "" || doc.JWKSURL == "" {
+		return fmt.Errorf("OIDC discovery document of %q lacks endpoints", p.issuer)
+	}
+	p.authURL, p.tokenURL, p.jwksURL = doc.AuthURL, doc.TokenURL, doc.JWKSURL
+	p.discovered = true
+
+	return nil
+}
+
+// key returns the signing key with the given ID. The keys are fetched again
+// if the ID is unknown, at most once per minKeyRefresh.
+func (p *provider) key(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	if k := p.lookupKey(kid); k != nil {
+		return k, nil
+	}
+	if time.Since(p.keysAt) < minKeyRefresh {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	var set struct {
+		Keys []*jsonWebKey `json:"keys"`
+	}
+	if err := p.get(ctx, p.jwksURL, &set); err != nil {
+		return nil, fmt.Errorf("fetching signing keys failed: %s", err)
+	}
+	keys := map[string]*rsa.PublicKey{}
+	for _, jwk := range set.Keys {
+		k, err := jwk.publicKey()
+		if err != nil {
+			return nil, err
+		}
+		if k != nil {
+			keys[jwk.Kid] = k
+		}
+	}
+	p.keys, p.keysAt = keys, time.Now()
+
+	if k := p.lookupKey(kid); k != nil {
+		return k, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// lookupKey returns the key with the given ID. If the token does not name
+// its key, the only key of the provider is used.
+func (p *provider) lookupKey(kid string) *rsa.PublicKey {
+	if kid == "" && len(p.keys) == 1 {
+		for _, k := range p.keys {
+			return k
+		}
+	}
+	return p.keys[kid]
+}
+
+// exchange exchanges an authorization code for an ID token.
+func (p *provider) exchange(ctx context.Context, clientID, clientSecret, code, redirectURL string) (string, error) {
+	form := url.Values{
+		"grant_type":   {"authorization_code"},
+		"code":         {code},
+		"redirect_uri": {redirectURL},
+	}
+	req, err := http.NewRequest("POST", p.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(clientID), url.QueryEscape(clientSecret))
+
+	resp, err := ctxhttp.Do(ctx, p.client, req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var res struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return "", fmt.Errorf("invalid token response: %s", err)
+	}
+	if res.Error != "" {
+		return "", fmt.Errorf("token request failed: %s %s", res.Error, res.ErrorDescription)
+	}
+	if resp.StatusCode/100 != 2 {
+		return "", fmt.Errorf("token request failed with status %d", resp.StatusCode)
+	}
+	if res.IDToken == "" {
+		return "", fmt.Errorf("token response lacks an ID token")
+	}
+	return res.IDToken, nil
+}
+
+func (p *provider) get(ctx context.Context, u string, v interface{}) error {
+	resp, err := ctxhttp.Get(ctx, p.client, u)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("unexpected status code %d from %s", resp.StatusCode, u)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
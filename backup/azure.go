@@ -0,0 +1,134 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backup
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// azure is a Bucket using a container of Azure Blob Storage, authorized by a
+// shared access signature.
+type azure struct {
+	url    string
+	prefix string
+	sas    string
+	client *http.Client
+}
+
+func (a *azure) do(method, blob string, query url.Values, body []byte) (*http.Response, error) {
+	req, err := http.NewRequest(method, a.url+blob, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	// The signature's parameters are appended as they are.
+	req.URL.RawQuery = query.Encode()
+	if a.sas != "" {
+		if req.URL.RawQuery != "" {
+			req.URL.RawQuery += "&"
+		}
+		req.URL.RawQuery += a.sas
+	}
+	req.Header.Set("X-Ms-Version", "2017-04-17")
+	if method == "PUT" {
+		req.Header.Set("X-Ms-Blob-Type", "BlockBlob")
+	}
+	return a.client.Do(req)
+}
+
+// Put implements the Bucket interface.
+func (a *azure) Put(key string, data []byte) error {
+	resp, err := a.do("PUT", a.prefix+key, nil, data)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return checkStatus(resp)
+}
+
+// Get implements the Bucket interface.
+func (a *azure) Get(key string) ([]byte, error) {
+	resp, err := a.do("GET", a.prefix+key, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if err := checkStatus(resp); err != nil {
+		return nil, err
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+type azureListResult struct {
+	Blobs struct {
+		Blob []struct {
+			Name string
+		}
+	}
+	NextMarker string
+}
+
+// List implements the Bucket interface.
+func (a *azure) List(prefix string) ([]string, error) {
+	var (
+		keys   []string
+		marker string
+	)
+	for {
+		q := url.Values{
+			"restype": {"container"},
+			"comp":    {"list"},
+			"prefix":  {a.prefix + prefix},
+		}
+		if marker != "" {
+			q.Set("marker", marker)
+		}
+		resp, err := a.do("GET", "", q, nil)
+		if err != nil {
+			return nil, err
+		}
+		var res azureListResult
+		err = checkStatus(resp)
+		if err == nil {
+			err = xml.NewDecoder(io.LimitReader(resp.Body, 16<<20)).Decode(&res)
+		}
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, b := range res.Blobs.Blob {
+			keys = append(keys, strings.TrimPrefix(b.Name, a.prefix))
+		}
+		if marker = res.NextMarker; marker == "" {
+			return keys, nil
+		}
+	}
+}
+
+// Delete implements the Bucket interface.
+func (a *azure) Delete(key string) error {
+	resp, err := a.do("DELETE", a.prefix+key, nil, nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return checkStatus(resp)
+}
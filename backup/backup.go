@@ -0,0 +1,233 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package backup uploads the state files of the storage path to object
+// storage such as Amazon S3, Google Cloud Storage or Azure Blob Storage and
+// restores them.
+//
+// Each backup is stored below a key named after the time it was taken, e.g.
+// 20170102T150405Z/silences, so backups sort by their age.
+package backup
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+)
+
+// nameFormat is the time format of backup names.
+const nameFormat = "20060102T150405Z"
+
+// Backup uploads state files to a bucket.
+type Backup struct {
+	bucket    Bucket
+	dir       string
+	files     []string
+	retention time.Duration
+	logger    log.Logger
+	now       func() time.Time
+
+	lastSuccess prometheus.Gauge
+	failures    prometheus.Counter
+}
+
+// Options configures a new Backup.
+type Options struct {
+	// The bucket backups are uploaded to.
+	Bucket Bucket
+	// The directory holding the files and the names of the files that are
+	// backed up. Missing files are skipped.
+	Dir   string
+	Files []string
+	// How long backups are kept. The latest backup is always kept.
+	Retention time.Duration
+
+	Logger  log.Logger
+	Metrics prometheus.Registerer
+}
+
+// New returns a new Backup.
+func New(o Options) *Backup {
+	b := &Backup{
+		bucket:    o.Bucket,
+		dir:       o.Dir,
+		files:     o.Files,
+		retention: o.Retention,
+		logger:    log.NewNopLogger(),
+		now:       time.Now,
+		lastSuccess: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "alertmanager_backup_last_success_timestamp_seconds",
+			Help: "Timestamp of the last successful backup of the state files.",
+		}),
+		failures: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "alertmanager_backup_failures_total",
+			Help: "Number of failed backups of the state files.",
+		}),
+	}
+	if o.Logger != nil {
+		b.logger = o.Logger
+	}
+	if o.Metrics != nil {
+		o.Metrics.MustRegister(b.lastSuccess, b.failures)
+	}
+	return b
+}
+
+// Run takes a backup at the given interval until the stop channel is closed.
+func (b *Backup) Run(interval time.Duration, stopc <-chan struct{}) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-stopc:
+			return
+		case <-t.C:
+			name, err := b.Upload()
+			if err != nil {
+				b.failures.Inc()
+				b.logger.With("err", err).Error("backup failed")
+				continue
+			}
+			b.lastSuccess.Set(float64(b.now().Unix()))
+			b.logger.With("backup", name).Info("backup done")
+
+			if err := b.expire(); err != nil {
+				b.logger.With("err", err).Error("deleting expired backups failed")
+			}
+		}
+	}
+}
+
+// Upload takes a backup of the files and returns its name.
+func (b *Backup) Upload() (string, error) {
+	name := b.now().UTC().Format(nameFormat)
+
+	for _, f := range b.files {
+		data, err := ioutil.ReadFile(filepath.Join(b.dir, f))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		if err := b.bucket.Put(name+"/"+f, data); err != nil {
+			return "", fmt.Errorf("uploading %s: %s", f, err)
+		}
+	}
+	return name, nil
+}
+
+// backups returns the keys of all backups by their names, oldest first.
+func (b *Backup) backups() ([]string, map[string][]string, error) {
+	keys, err := b.bucket.List("")
+	if err != nil {
+		return nil, nil, err
+	}
+	backups := map[string][]string{}
+	for _, k := range keys {
+		i := strings.Index(k, "/")
+		if i < 0 {
+			continue
+		}
+		if _, err := time.Parse(nameFormat, k[:i]); err != nil {
+			continue
+		}
+		backups[k[:i]] = append(backups[k[:i]], k)
+	}
+	names := make([]string, 0, len(backups))
+	for n := range backups {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names, backups, nil
+}
+
+// expire deletes all backups older than the retention but the latest one.
+func (b *Backup) expire() error {
+	names, backups, err := b.backups()
+	if err != nil {
+		return err
+	}
+	if len(names) > 0 {
+		names = names[:len(names)-1]
+	}
+	for _, n := range names {
+		t, _ := time.Parse(nameFormat, n)
+		if b.now().Sub(t) < b.retention {
+			continue
+		}
+		for _, k := range backups[n] {
+			if err := b.bucket.Delete(k); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Empty returns whether none of the backed up files exist in the directory.
+func (b *Backup) Empty() bool {
+	for _, f := range b.files {
+		if _, err := os.Stat(filepath.Join(b.dir, f)); err == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// Restore downloads the files of the latest backup into the directory. The
+// files replace existing ones, files missing in the backup are removed. It
+// returns the name of the restored backup, which is empty if there is none.
+func (b *Backup) Restore() (string, error) {
+	names, backups, err := b.backups()
+	if err != nil || len(names) == 0 {
+		return "", err
+	}
+	name := names[len(names)-1]
+
+	keys := map[string]struct{}{}
+	for _, k := range backups[name] {
+		keys[k] = struct{}{}
+	}
+	for _, f := range b.files {
+		path := filepath.Join(b.dir, f)
+
+		if _, ok := keys[name+"/"+f]; !ok {
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				return "", err
+			}
+			continue
+		}
+		data, err := b.bucket.Get(name + "/" + f)
+		if err != nil {
+			return "", fmt.Errorf("downloading %s: %s", f, err)
+		}
+		// Write to a temporary file first so files are not left
+		// partially written.
+		if err := ioutil.WriteFile(path+".restore", data, 0666); err != nil {
+			return "", err
+		}
+		if err := os.Rename(path+".restore", path); err != nil {
+			return "", err
+		}
+	}
+	return name, nil
+}
@@ -0,0 +1,118 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backup
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ErrNotFound is returned by buckets for missing objects.
+var ErrNotFound = errors.New("object not found")
+
+// Bucket is an object storage bucket. Keys are relative to the prefix the
+// bucket was created with.
+type Bucket interface {
+	// Put stores the data at the key.
+	Put(key string, data []byte) error
+	// Get returns the data stored at the key.
+	Get(key string) ([]byte, error)
+	// List returns all keys starting with the prefix.
+	List(prefix string) ([]string, error)
+	// Delete removes the key.
+	Delete(key string) error
+}
+
+// BucketOptions configures a new Bucket.
+type BucketOptions struct {
+	// The bucket's URL, which is s3://<bucket>/<prefix> for Amazon S3,
+	// gs://<bucket>/<prefix> for Google Cloud Storage and
+	// azure://<account>/<container>/<prefix> for Azure Blob Storage.
+	URL string
+
+	// The endpoint and region of S3. The endpoint defaults to the one of
+	// Amazon for the region, which defaults to us-east-1.
+	S3Endpoint string
+	S3Region   string
+	// The HMAC credentials for S3 and Google Cloud Storage.
+	AccessKeyID     string
+	SecretAccessKey string
+
+	// The shared access signature for Azure Blob Storage.
+	SASToken string
+}
+
+// NewBucket returns the bucket described by the options.
+func NewBucket(o BucketOptions) (Bucket, error) {
+	u, err := url.Parse(o.URL)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		client = &http.Client{Timeout: time.Minute}
+		prefix = strings.TrimPrefix(u.Path, "/")
+	)
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("bucket missing in %q", o.URL)
+	}
+
+	switch u.Scheme {
+	case "s3":
+		region := o.S3Region
+		if region == "" {
+			region = "us-east-1"
+		}
+		endpoint := o.S3Endpoint
+		if endpoint == "" {
+			endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", region)
+		}
+		return newS3(endpoint, region, u.Host, prefix, o.AccessKeyID, o.SecretAccessKey, client), nil
+	case "gs":
+		// Cloud Storage is accessed through its S3 compatible XML API.
+		return newS3("https://storage.googleapis.com", "auto", u.Host, prefix, o.AccessKeyID, o.SecretAccessKey, client), nil
+	case "azure":
+		parts := strings.SplitN(prefix, "/", 2)
+		if parts[0] == "" {
+			return nil, fmt.Errorf("container missing in %q", o.URL)
+		}
+		prefix = ""
+		if len(parts) == 2 {
+			prefix = parts[1]
+		}
+		return &azure{
+			url:    fmt.Sprintf("https://%s.blob.core.windows.net/%s/", u.Host, parts[0]),
+			prefix: prefix,
+			sas:    strings.TrimPrefix(o.SASToken, "?"),
+			client: client,
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown bucket scheme %q", u.Scheme)
+}
+
+func checkStatus(resp *http.Response) error {
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("unexpected status code %v from %s %s", resp.StatusCode, resp.Request.Method, resp.Request.URL.Path)
+	}
+	return nil
+}
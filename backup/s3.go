@@ -0,0 +1,239 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backup
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// s3 is a Bucket using the S3 REST API with path-style requests signed with
+// AWS signature version 4.
+type s3 struct {
+	endpoint  string
+	region    string
+	bucket    string
+	prefix    string
+	keyID     string
+	secretKey string
+	client    *http.Client
+	now       func() time.Time
+}
+
+func newS3(endpoint, region, bucket, prefix, keyID, secretKey string, client *http.Client) *s3 {
+	return &s3{
+		endpoint:  strings.TrimRight(endpoint, "/"),
+		region:    region,
+		bucket:    bucket,
+		prefix:    prefix,
+		keyID:     keyID,
+		secretKey: secretKey,
+		client:    client,
+		now:       time.Now,
+	}
+}
+
+func (s *s3) do(method, key string, query url.Values, body []byte) (*http.Response, error) {
+	u := s.endpoint + "/" + s.bucket + "/" + key
+	req, err := http.NewRequest(method, u, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.URL.RawQuery = query.Encode()
+	s.sign(req, body)
+	return s.client.Do(req)
+}
+
+// sign adds an AWS signature version 4 for the request and the payload to
+// the request. All headers set on the request are signed.
+func (s *s3) sign(req *http.Request, payload []byte) {
+	now := s.now().UTC()
+	date := now.Format("20060102")
+	payloadHash := sha256.Sum256(payload)
+
+	req.Header.Set("X-Amz-Date", now.Format("20060102T150405Z"))
+	req.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(payloadHash[:]))
+
+	headers := map[string]string{"host": req.URL.Host}
+	for k, v := range req.Header {
+		headers[strings.ToLower(k)] = strings.TrimSpace(strings.Join(v, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	var canonHeaders bytes.Buffer
+	for _, k := range names {
+		fmt.Fprintf(&canonHeaders, "%s:%s\n", k, headers[k])
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonRequest := strings.Join([]string{
+		req.Method,
+		awsEscape(req.URL.Path, false),
+		canonicalQuery(req.URL.Query()),
+		canonHeaders.String(),
+		signedHeaders,
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+
+	scope := strings.Join([]string{date, s.region, "s3", "aws4_request"}, "/")
+	canonHash := sha256.Sum256([]byte(canonRequest))
+	toSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		now.Format("20060102T150405Z"),
+		scope,
+		hex.EncodeToString(canonHash[:]),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.secretKey), date)
+	key = hmacSHA256(key, s.region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	sig := hex.EncodeToString(hmacSHA256(key, toSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.keyID, scope, signedHeaders, sig,
+	))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// awsEscape escapes all but the unreserved characters as required by AWS
+// signatures, optionally including slashes.
+func awsEscape(s string, slash bool) string {
+	var b bytes.Buffer
+	for _, c := range []byte(s) {
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~', c == '/' && !slash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func canonicalQuery(q url.Values) string {
+	keys := make([]string, 0, len(q))
+	for k := range q {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var parts []string
+	for _, k := range keys {
+		vals := append([]string(nil), q[k]...)
+		sort.Strings(vals)
+		for _, v := range vals {
+			parts = append(parts, awsEscape(k, true)+"="+awsEscape(v, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// Put implements the Bucket interface.
+func (s *s3) Put(key string, data []byte) error {
+	resp, err := s.do("PUT", s.prefix+key, nil, data)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return checkStatus(resp)
+}
+
+// Get implements the Bucket interface.
+func (s *s3) Get(key string) ([]byte, error) {
+	resp, err := s.do("GET", s.prefix+key, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if err := checkStatus(resp); err != nil {
+		return nil, err
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+type s3ListResult struct {
+	IsTruncated bool
+	Contents    []struct {
+		Key string
+	}
+}
+
+// List implements the Bucket interface.
+func (s *s3) List(prefix string) ([]string, error) {
+	var (
+		keys   []string
+		marker string
+	)
+	for {
+		q := url.Values{"prefix": {s.prefix + prefix}}
+		if marker != "" {
+			q.Set("marker", marker)
+		}
+		resp, err := s.do("GET", "", q, nil)
+		if err != nil {
+			return nil, err
+		}
+		var res s3ListResult
+		err = checkStatus(resp)
+		if err == nil {
+			err = xml.NewDecoder(io.LimitReader(resp.Body, 16<<20)).Decode(&res)
+		}
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, c := range res.Contents {
+			keys = append(keys, strings.TrimPrefix(c.Key, s.prefix))
+			marker = c.Key
+		}
+		if !res.IsTruncated || len(res.Contents) == 0 {
+			return keys, nil
+		}
+	}
+}
+
+// Delete implements the Bucket interface.
+func (s *s3) Delete(key string) error {
+	resp, err := s.do("DELETE", s.prefix+key, nil, nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return checkStatus(resp)
+}
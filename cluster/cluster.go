@@ -0,0 +1,355 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package cluster keeps track of the peers of the mesh and of the gossip
+// received from them, so that a split cluster can be detected before it
+// causes duplicate notifications.
+package cluster
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/weaveworks/mesh"
+)
+
+// Options configure a Tracker.
+type Options struct {
+	// Region is the region this instance runs in. It is gossiped to the
+	// other peers, which order the sending of notifications by region.
+	Region string
+	// Compress gossip sent to other peers. All peers must run a version
+	// that understands compressed gossip before it is enabled.
+	Compress bool
+}
+
+// Tracker records the gossip received on the channels of a mesh router and
+// reports the state of the cluster. A nil Tracker reports an empty cluster.
+type Tracker struct {
+	router   *mesh.Router
+	now      func() time.Time
+	compress bool
+
+	mtx sync.RWMutex
+	// peers holds the time of the last gossip by peer name. Only
+	// broadcasts and unicasts carry their source, the periodic exchange
+	// of the complete state does not.
+	peers map[string]time.Time
+	// channels holds the time of the last gossip by channel.
+	channels map[string]time.Time
+	// received holds the number of gossip messages by channel.
+	received map[string]float64
+	// regions holds the regions of the peers by peer name.
+	regions regionData
+
+	peersDesc        *prometheus.Desc
+	healthyPeersDesc *prometheus.Desc
+	peerHealthyDesc  *prometheus.Desc
+	peerGossipDesc   *prometheus.Desc
+	channelDesc      *prometheus.Desc
+	receivedDesc     *prometheus.Desc
+}
+
+// NewTracker returns a tracker of the cluster formed by the router. It
+// registers a gossip channel on the router to exchange the regions of the
+// peers.
+func NewTracker(r *mesh.Router, o Options) *Tracker {
+	t := &Tracker{
+		router:   r,
+		now:      time.Now,
+		compress: o.Compress,
+		peers:    map[string]time.Time{},
+		channels: map[string]time.Time{},
+		received: map[string]float64{},
+		regions:  regionData{},
+
+		peersDesc: prometheus.NewDesc(
+			"alertmanager_cluster_peers",
+			"Number of peers known to this instance, including itself.",
+			nil, nil,
+		),
+		healthyPeersDesc: prometheus.NewDesc(
+			"alertmanager_cluster_healthy_peers",
+			"Number of peers with an established connection to this instance, including itself.",
+			nil, nil,
+		),
+		peerHealthyDesc: prometheus.NewDesc(
+			"alertmanager_cluster_peer_healthy",
+			"Whether this instance has an established connection to the peer.",
+			[]string{"peer"}, nil,
+		),
+		peerGossipDesc: prometheus.NewDesc(
+			"alertmanager_cluster_peer_last_gossip_timestamp_seconds",
+			"Timestamp of the last gossip message broadcast by the peer.",
+			[]string{"peer"}, nil,
+		),
+		channelDesc: prometheus.NewDesc(
+			"alertmanager_cluster_channel_last_gossip_timestamp_seconds",
+			"Timestamp of the last gossip message received on the channel.",
+			[]string{"channel"}, nil,
+		),
+		receivedDesc: prometheus.NewDesc(
+			"alertmanager_cluster_gossip_messages_received_total",
+			"Total number of gossip messages received on the channel.",
+			[]string{"channel"}, nil,
+		),
+	}
+	if r != nil {
+		t.regions[r.Ourself.Name.String()] = regionEntry{Region: o.Region, Since: t.now()}
+		r.NewGossip("regions", t.Gossiper("regions", regionGossiper{t: t}))
+	}
+	return t
+}
+
+// Gossiper wraps the gossiper of a channel to record the gossip it receives
+// and to compress the gossip it sends if enabled. Compressed gossip is
+// accepted regardless.
+func (t *Tracker) Gossiper(channel string, g mesh.Gossiper) mesh.Gossiper {
+	return &gossiper{Gossiper: g, channel: channel, tracker: t}
+}
+
+func (t *Tracker) record(channel string, src *mesh.PeerName) {
+	now := t.now()
+
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	t.channels[channel] = now
+	t.received[channel]++
+	if src != nil {
+		t.peers[src.String()] = now
+	}
+}
+
+type gossiper struct {
+	mesh.Gossiper
+	channel string
+	tracker *Tracker
+}
+
+func (g *gossiper) OnGossipUnicast(src mesh.PeerName, msg []byte) error {
+	g.tracker.record(g.channel, &src)
+	msg, err := decompress(msg)
+	if err != nil {
+		return err
+	}
+	return g.Gossiper.OnGossipUnicast(src, msg)
+}
+
+func (g *gossiper) OnGossipBroadcast(src mesh.PeerName, msg []byte) (mesh.GossipData, error) {
+	g.tracker.record(g.channel, &src)
+	msg, err := decompress(msg)
+	if err != nil {
+		return nil, err
+	}
+	return g.wrap(g.Gossiper.OnGossipBroadcast(src, msg))
+}
+
+func (g *gossiper) OnGossip(msg []byte) (mesh.GossipData, error) {
+	g.tracker.record(g.channel, nil)
+	msg, err := decompress(msg)
+	if err != nil {
+		return nil, err
+	}
+	return g.wrap(g.Gossiper.OnGossip(msg))
+}
+
+func (g *gossiper) Gossip() mesh.GossipData {
+	d, _ := g.wrap(g.Gossiper.Gossip(), nil)
+	return d
+}
+
+// wrap compresses the gossip data if compression is enabled.
+func (g *gossiper) wrap(d mesh.GossipData, err error) (mesh.GossipData, error) {
+	if d == nil || err != nil || !g.tracker.compress {
+		return d, err
+	}
+	return compressedData{d}, nil
+}
+
+// compressedData gzips the encoded gossip data.
+type compressedData struct {
+	mesh.GossipData
+}
+
+func (d compressedData) Encode() [][]byte {
+	var res [][]byte
+	for _, b := range d.GossipData.Encode() {
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		w.Write(b)
+		w.Close()
+		res = append(res, buf.Bytes())
+	}
+	return res
+}
+
+func (d compressedData) Merge(other mesh.GossipData) mesh.GossipData {
+	if c, ok := other.(compressedData); ok {
+		other = c.GossipData
+	}
+	return compressedData{d.GossipData.Merge(other)}
+}
+
+// decompress returns the message unchanged unless it is gzipped. Gossip
+// messages are protocol buffers or JSON, which never start with the gzip
+// magic bytes.
+func decompress(msg []byte) ([]byte, error) {
+	if len(msg) < 2 || msg[0] != 0x1f || msg[1] != 0x8b {
+		return msg, nil
+	}
+	r, err := gzip.NewReader(bytes.NewReader(msg))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return ioutil.ReadAll(r)
+}
+
+// Peer describes a peer of the mesh. A peer is healthy if this instance has
+// an established connection to it.
+type Peer struct {
+	Name       string     `json:"name"`
+	NickName   string     `json:"nickName"`
+	Address    string     `json:"address,omitempty"`
+	Self       bool       `json:"self"`
+	Healthy    bool       `json:"healthy"`
+	Region     string     `json:"region,omitempty"`
+	LastGossip *time.Time `json:"lastGossip"`
+}
+
+// Channel describes the state synchronization of a gossip channel. The lag
+// is the time in seconds since gossip was last received on it.
+type Channel struct {
+	Name       string     `json:"name"`
+	LastGossip *time.Time `json:"lastGossip"`
+	Lag        float64    `json:"lag"`
+}
+
+// Peers returns the known peers of the mesh sorted by name.
+func (t *Tracker) Peers() []*Peer {
+	res := []*Peer{}
+	if t == nil || t.router == nil {
+		return res
+	}
+	st := mesh.NewStatus(t.router)
+
+	// The connections of our own peer tell which peers are reachable.
+	type conn struct {
+		address     string
+		established bool
+	}
+	conns := map[string]conn{}
+	for _, p := range st.Peers {
+		if p.Name != st.Name {
+			continue
+		}
+		for _, c := range p.Connections {
+			conns[c.Name] = conn{address: c.Address, established: c.Established}
+		}
+	}
+
+	t.mtx.RLock()
+	defer t.mtx.RUnlock()
+
+	for _, p := range st.Peers {
+		self := p.Name == st.Name
+		c := conns[p.Name]
+		peer := &Peer{
+			Name:     p.Name,
+			NickName: p.NickName,
+			Address:  c.address,
+			Self:     self,
+			Healthy:  self || c.established,
+			Region:   t.regions[p.Name].Region,
+		}
+		if ts, ok := t.peers[p.Name]; ok {
+			peer.LastGossip = &ts
+		}
+		res = append(res, peer)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+
+	return res
+}
+
+// Channels returns the gossip channels on which gossip was received, sorted
+// by name.
+func (t *Tracker) Channels() []*Channel {
+	res := []*Channel{}
+	if t == nil {
+		return res
+	}
+	now := t.now()
+
+	t.mtx.RLock()
+	defer t.mtx.RUnlock()
+
+	for name, ts := range t.channels {
+		ts := ts
+		res = append(res, &Channel{
+			Name:       name,
+			LastGossip: &ts,
+			Lag:        now.Sub(ts).Seconds(),
+		})
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+
+	return res
+}
+
+// Describe implements prometheus.Collector.
+func (t *Tracker) Describe(ch chan<- *prometheus.Desc) {
+	ch <- t.peersDesc
+	ch <- t.healthyPeersDesc
+	ch <- t.peerHealthyDesc
+	ch <- t.peerGossipDesc
+	ch <- t.channelDesc
+	ch <- t.receivedDesc
+}
+
+// Collect implements prometheus.Collector.
+func (t *Tracker) Collect(ch chan<- prometheus.Metric) {
+	peers := t.Peers()
+
+	healthy := 0
+	for _, p := range peers {
+		v := 0.0
+		if p.Healthy {
+			healthy++
+			v = 1
+		}
+		if p.Self {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(t.peerHealthyDesc, prometheus.GaugeValue, v, p.Name)
+		if p.LastGossip != nil {
+			ch <- prometheus.MustNewConstMetric(t.peerGossipDesc, prometheus.GaugeValue, float64(p.LastGossip.UnixNano())/1e9, p.Name)
+		}
+	}
+	ch <- prometheus.MustNewConstMetric(t.peersDesc, prometheus.GaugeValue, float64(len(peers)))
+	ch <- prometheus.MustNewConstMetric(t.healthyPeersDesc, prometheus.GaugeValue, float64(healthy))
+
+	t.mtx.RLock()
+	defer t.mtx.RUnlock()
+
+	for name, ts := range t.channels {
+		ch <- prometheus.MustNewConstMetric(t.channelDesc, prometheus.GaugeValue, float64(ts.UnixNano())/1e9, name)
+		ch <- prometheus.MustNewConstMetric(t.receivedDesc, prometheus.CounterValue, t.received[name], name)
+	}
+}
@@ -0,0 +1,168 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"io/ioutil"
+	"log"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/weaveworks/mesh"
+)
+
+type nopGossiper struct{}
+
+func (nopGossiper) OnGossipUnicast(mesh.PeerName, []byte) error { return nil }
+func (nopGossiper) OnGossipBroadcast(mesh.PeerName, []byte) (mesh.GossipData, error) {
+	return nil, nil
+}
+func (nopGossiper) Gossip() mesh.GossipData                  { return nil }
+func (nopGossiper) OnGossip([]byte) (mesh.GossipData, error) { return nil, nil }
+
+func TestTracker(t *testing.T) {
+	name, err := mesh.PeerNameFromString("00:00:00:00:00:01")
+	require.NoError(t, err)
+	r := mesh.NewRouter(mesh.Config{
+		Port:               0,
+		ProtocolMinVersion: mesh.ProtocolMinVersion,
+		TrustedSubnets:     []*net.IPNet{},
+	}, name, "am-1", mesh.NullOverlay{}, log.New(ioutil.Discard, "", 0))
+
+	now := time.Unix(1000, 0)
+	tr := NewTracker(r, Options{Region: "eu"})
+	tr.now = func() time.Time { return now }
+
+	peers := tr.Peers()
+	require.Len(t, peers, 1)
+	require.True(t, peers[0].Self)
+	require.True(t, peers[0].Healthy)
+	require.Equal(t, "eu", peers[0].Region)
+	require.Nil(t, peers[0].LastGossip)
+	require.Equal(t, []*Channel{}, tr.Channels())
+
+	other, err := mesh.PeerNameFromString("00:00:00:00:00:02")
+	require.NoError(t, err)
+
+	g := tr.Gossiper("silences", nopGossiper{})
+	_, err = g.OnGossipBroadcast(other, nil)
+	require.NoError(t, err)
+
+	now = now.Add(10 * time.Second)
+	_, err = tr.Gossiper("nflog", nopGossiper{}).OnGossip(nil)
+	require.NoError(t, err)
+
+	now = now.Add(5 * time.Second)
+	ts1, ts2 := time.Unix(1000, 0), time.Unix(1010, 0)
+	require.Equal(t, []*Channel{
+		{Name: "nflog", LastGossip: &ts2, Lag: 5},
+		{Name: "silences", LastGossip: &ts1, Lag: 15},
+	}, tr.Channels())
+	require.Equal(t, ts1, tr.peers[other.String()])
+
+	var nilTracker *Tracker
+	require.Equal(t, []*Peer{}, nilTracker.Peers())
+	require.Equal(t, []*Channel{}, nilTracker.Channels())
+}
+
+type recordingGossiper struct {
+	nopGossiper
+	msgs [][]byte
+}
+
+func (g *recordingGossiper) OnGossip(msg []byte) (mesh.GossipData, error) {
+	g.msgs = append(g.msgs, msg)
+	return regionData{"a": {Region: "eu"}}, nil
+}
+
+func TestCompression(t *testing.T) {
+	rg := &recordingGossiper{}
+	g := NewTracker(nil, Options{Compress: true}).Gossiper("test", rg)
+
+	d, err := g.OnGossip([]byte(`{"plain":true}`))
+	require.NoError(t, err)
+
+	// Gossip sent on is compressed and accepted as such.
+	enc := d.Encode()
+	require.Len(t, enc, 1)
+	require.Equal(t, []byte{0x1f, 0x8b}, enc[0][:2])
+
+	_, err = g.OnGossip(enc[0])
+	require.NoError(t, err)
+	require.Equal(t, [][]byte{[]byte(`{"plain":true}`), []byte(`{"a":{"region":"eu","since":"0001-01-01T00:00:00Z"}}`)}, rg.msgs)
+
+	// Merging unwraps compressed data.
+	m := d.Merge(compressedData{regionData{"b": {Region: "us"}}})
+	require.Equal(t, regionData{"a": {Region: "eu"}, "b": {Region: "us"}}, m.(compressedData).GossipData)
+}
+
+func TestRegions(t *testing.T) {
+	name, err := mesh.PeerNameFromString("00:00:00:00:00:01")
+	require.NoError(t, err)
+	other, err := mesh.PeerNameFromString("00:00:00:00:00:02")
+	require.NoError(t, err)
+
+	tr := &Tracker{regions: regionData{name.String(): {Region: "eu", Since: time.Unix(100, 0)}}}
+	g := regionGossiper{t: tr}
+
+	msg := regionData{
+		name.String():  {Region: "us", Since: time.Unix(50, 0)},
+		other.String(): {Region: "us", Since: time.Unix(100, 0)},
+	}.Encode()[0]
+
+	// Only the unknown peer is new.
+	delta, err := g.OnGossipBroadcast(other, msg)
+	require.NoError(t, err)
+	require.Len(t, delta, 1)
+	require.Equal(t, "us", delta.(regionData)[other.String()].Region)
+	require.Equal(t, "eu", tr.Region(name))
+	require.Equal(t, "us", tr.Region(other))
+
+	delta, err = g.OnGossip(msg)
+	require.NoError(t, err)
+	require.Nil(t, delta)
+
+	// A restarted peer may change its region.
+	delta, err = g.OnGossip(regionData{name.String(): {Region: "us", Since: time.Unix(200, 0)}}.Encode()[0])
+	require.NoError(t, err)
+	require.NotNil(t, delta)
+	require.Equal(t, "us", tr.Region(name))
+}
+
+func TestOwners(t *testing.T) {
+	peers := []mesh.PeerName{1, 2, 3, 4, 5}
+
+	// Keys are spread across all peers.
+	owned := map[mesh.PeerName]int{}
+	for k := uint64(0); k < 1000; k++ {
+		owners := Owners(k, peers, 2)
+		require.Len(t, owners, 2)
+		require.NotEqual(t, owners[0], owners[1])
+		owned[owners[0]]++
+	}
+	require.Len(t, owned, len(peers))
+
+	// Removing a peer only moves the keys it owned.
+	for k := uint64(0); k < 1000; k++ {
+		before := Owners(k, peers, 1)[0]
+		after := Owners(k, peers[:4], 1)[0]
+		if before != 5 {
+			require.Equal(t, before, after)
+		}
+	}
+
+	require.Len(t, Owners(1, peers, 10), len(peers))
+}
@@ -0,0 +1,110 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/weaveworks/mesh"
+)
+
+// regionEntry is the region a peer announced when it started.
+type regionEntry struct {
+	Region string    `json:"region"`
+	Since  time.Time `json:"since"`
+}
+
+// regionData maps peer names to their regions. It is gossiped so that all
+// peers agree on the regions of each other.
+type regionData map[string]regionEntry
+
+func (d regionData) Encode() [][]byte {
+	b, err := json.Marshal(d)
+	if err != nil {
+		panic(err)
+	}
+	return [][]byte{b}
+}
+
+func (d regionData) Merge(other mesh.GossipData) mesh.GossipData {
+	d.merge(other.(regionData))
+	return d
+}
+
+// merge adds the entries of other that are newer than the known ones and
+// returns those entries.
+func (d regionData) merge(other regionData) regionData {
+	delta := regionData{}
+	for name, e := range other {
+		if cur, ok := d[name]; ok && !e.Since.After(cur.Since) {
+			continue
+		}
+		d[name] = e
+		delta[name] = e
+	}
+	return delta
+}
+
+// Region returns the region the peer announced or an empty string if it is
+// not known.
+func (t *Tracker) Region(name mesh.PeerName) string {
+	if t == nil {
+		return ""
+	}
+	t.mtx.RLock()
+	defer t.mtx.RUnlock()
+
+	return t.regions[name.String()].Region
+}
+
+// regionGossiper gossips the regions known to the tracker.
+type regionGossiper struct {
+	t *Tracker
+}
+
+func (g regionGossiper) Gossip() mesh.GossipData {
+	g.t.mtx.RLock()
+	defer g.t.mtx.RUnlock()
+
+	d := make(regionData, len(g.t.regions))
+	for name, e := range g.t.regions {
+		d[name] = e
+	}
+	return d
+}
+
+func (g regionGossiper) OnGossip(msg []byte) (mesh.GossipData, error) {
+	var d regionData
+	if err := json.Unmarshal(msg, &d); err != nil {
+		return nil, err
+	}
+
+	g.t.mtx.Lock()
+	defer g.t.mtx.Unlock()
+
+	delta := g.t.regions.merge(d)
+	if len(delta) == 0 {
+		return nil, nil
+	}
+	return delta, nil
+}
+
+func (g regionGossiper) OnGossipBroadcast(_ mesh.PeerName, msg []byte) (mesh.GossipData, error) {
+	return g.OnGossip(msg)
+}
+
+func (g regionGossiper) OnGossipUnicast(mesh.PeerName, []byte) error {
+	return nil
+}
@@ -0,0 +1,58 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"sort"
+
+	"github.com/weaveworks/mesh"
+)
+
+// Owners returns the n peers owning the key, ordered by their precedence.
+// Keys are assigned by rendezvous hashing, so adding or removing a peer
+// only moves the keys it owns. All peers agreeing on the list of peers
+// agree on the owners of a key.
+func Owners(key uint64, peers []mesh.PeerName, n int) []mesh.PeerName {
+	type scored struct {
+		peer  mesh.PeerName
+		score uint64
+	}
+	var (
+		res = make([]scored, 0, len(peers))
+		buf [16]byte
+	)
+	binary.BigEndian.PutUint64(buf[:8], key)
+	for _, p := range peers {
+		binary.BigEndian.PutUint64(buf[8:], uint64(p))
+		h := fnv.New64a()
+		h.Write(buf[:])
+		res = append(res, scored{peer: p, score: h.Sum64()})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].score != res[j].score {
+			return res[i].score > res[j].score
+		}
+		return res[i].peer < res[j].peer
+	})
+	if n > len(res) {
+		n = len(res)
+	}
+	owners := make([]mesh.PeerName, n)
+	for i := range owners {
+		owners[i] = res[i].peer
+	}
+	return owners
+}
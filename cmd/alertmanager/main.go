@@ -14,6 +14,7 @@
 package main
 
 import (
+	"crypto/tls"
 	"flag"
 	"fmt"
 	"io/ioutil"
@@ -25,6 +26,8 @@ import (
 	"os/signal"
 	"path"
 	"path/filepath"
+	"runtime"
+	"runtime/debug"
 	"sort"
 	"strconv"
 	"strings"
@@ -32,22 +35,49 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/prometheus/alertmanager/ack"
 	"github.com/prometheus/alertmanager/api"
+	"github.com/prometheus/alertmanager/apikey"
+	"github.com/prometheus/alertmanager/assignment"
+	"github.com/prometheus/alertmanager/audit"
+	"github.com/prometheus/alertmanager/auth"
+	"github.com/prometheus/alertmanager/backup"
+	"github.com/prometheus/alertmanager/cluster"
+	"github.com/prometheus/alertmanager/comment"
 	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/discovery"
 	"github.com/prometheus/alertmanager/dispatch"
+	"github.com/prometheus/alertmanager/events"
+	"github.com/prometheus/alertmanager/history"
+	"github.com/prometheus/alertmanager/ingest"
 	"github.com/prometheus/alertmanager/inhibit"
+	"github.com/prometheus/alertmanager/kv"
+	"github.com/prometheus/alertmanager/maintenance"
 	"github.com/prometheus/alertmanager/nflog"
 	"github.com/prometheus/alertmanager/notify"
+	"github.com/prometheus/alertmanager/pause"
+	"github.com/prometheus/alertmanager/profiling"
 	"github.com/prometheus/alertmanager/provider/mem"
 	"github.com/prometheus/alertmanager/silence"
+	"github.com/prometheus/alertmanager/silence/link"
+	"github.com/prometheus/alertmanager/silence/sqlstore"
+	"github.com/prometheus/alertmanager/snooze"
 	"github.com/prometheus/alertmanager/template"
+	"github.com/prometheus/alertmanager/topology"
+	"github.com/prometheus/alertmanager/tracing"
 	"github.com/prometheus/alertmanager/types"
 	"github.com/prometheus/alertmanager/ui"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/common/log"
+	"github.com/prometheus/common/model"
 	"github.com/prometheus/common/route"
 	"github.com/prometheus/common/version"
 	"github.com/weaveworks/mesh"
+	"golang.org/x/net/context"
+
+	// Register SQL drivers for silence storage.
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
 )
 
 var (
@@ -61,32 +91,121 @@ var (
 		Name:      "config_last_reload_success_timestamp_seconds",
 		Help:      "Timestamp of the last successful configuration reload.",
 	})
+	peerPosition = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "alertmanager",
+		Name:      "peer_position",
+		Help:      "Position of this instance among the mesh peers, which delays its notifications by as many peer timeouts.",
+	})
 )
 
 func init() {
 	prometheus.MustRegister(configSuccess)
 	prometheus.MustRegister(configSuccessTime)
+	prometheus.MustRegister(peerPosition)
 	prometheus.MustRegister(version.NewCollector("alertmanager"))
 }
 
 func main() {
 	peers := &stringset{}
+	peerNames := &stringset{}
+	resyncURLs := &stringset{}
 	var (
 		showVersion = flag.Bool("version", false, "Print version information.")
 
-		configFile = flag.String("config.file", "alertmanager.yml", "Alertmanager configuration file name.")
-		dataDir    = flag.String("storage.path", "data/", "Base path for data storage.")
-		retention  = flag.Duration("data.retention", 5*24*time.Hour, "How long to keep data for.")
+		configFile     = flag.String("config.file", "alertmanager.yml", "Alertmanager configuration file name.")
+		drainTimeout   = flag.Duration("config.reload-drain-timeout", 30*time.Second, "Time notifications in flight are given to complete on configuration reloads before they are canceled. Aggregation groups of unchanged routes keep their timers across reloads.")
+		tmplInterval   = flag.Duration("templates.reload-interval", 10*time.Second, "Interval at which the template files are checked for changes, which are applied without a configuration reload if all templates are valid. 0 disables reloading changed template files.")
+		dataDir        = flag.String("storage.path", "data/", "Base path for data storage.")
+		retention      = flag.Duration("data.retention", 5*24*time.Hour, "How long to keep data for.")
+		dataGCInterval = flag.Duration("data.gc-interval", 15*time.Minute, "Interval at which acknowledgements, comments, assignments, snoozes, pauses, the notification history and API keys are garbage collected and snapshotted.")
+
+		alertsRetention  = flag.Duration("alerts.retention", 0, "How long to keep resolved alerts for, e.g. for reviewing them after an incident.")
+		alertsGCInterval = flag.Duration("alerts.gc-interval", 30*time.Minute, "Interval at which resolved alerts past their retention are garbage collected.")
+		alertsMaxCount   = flag.Int("alerts.max-count", 0, "Maximum number of alerts held in memory. Beyond it, resolved alerts and then the firing alerts of the lowest severity are evicted. 0 means no limit.")
+		alertsMaxBytes   = flag.Int64("alerts.max-bytes", 0, "Maximum estimated size in bytes of the alerts held in memory, evicting alerts like alerts.max-count. 0 means no limit.")
+		alertsSevLabel   = flag.String("alerts.severity-label", "severity", "Label holding the severity of alerts, which orders firing alerts for eviction.")
+		alertsSeverities = flag.String("alerts.severities", "info,warning,critical", "Comma-separated severities from the lowest to the highest. Alerts with other severities are evicted first.")
+		alertsResyncWait = flag.Duration("alerts.resync-timeout", 30*time.Second, "Timeout of loading the firing alerts of each server given by alerts.resync-url on startup.")
+
+		retryInterval   = flag.Duration("notify.retry-interval", 0, "Interval at which failed notifications are retried from the retry queue, which is kept in the storage path so pending retries survive restarts. 0 disables the retry queue.")
+		retryMaxAge     = flag.Duration("notify.retry-max-age", 24*time.Hour, "Time after which failed notifications are dropped from the retry queue.")
+		breakerFailures = flag.Int("notify.circuit-breaker-failures", 0, "Number of consecutive failed attempts after which notifications via an integration are suspended for the cooldown, and a NotificationCircuitOpen alert fires. 0 disables circuit breakers.")
+		breakerCooldown = flag.Duration("notify.circuit-breaker-cooldown", 5*time.Minute, "Time notifications via an integration are suspended for, after which a single probe notification decides whether they are resumed.")
+		captureSize     = flag.Int("notify.capture-size", 100, "Number of requests to receivers, for which capturing was enabled via the API, that are kept with their responses for debugging. 0 disables capturing.")
+
+		silencesRetention  = flag.Duration("silences.retention", 0, "How long to keep expired silences for. Defaults to -data.retention.")
+		silencesGCInterval = flag.Duration("silences.gc-interval", 15*time.Minute, "Interval at which expired silences are garbage collected.")
+		silencesQuorum     = flag.Bool("silences.quorum-writes", false, "Acknowledge changes to silences only once a quorum of all mesh peers stored them. All peers must enable it.")
+		silencesQuorumWait = flag.Duration("silences.quorum-timeout", 10*time.Second, "Time to wait for a quorum of mesh peers to store a change to a silence.")
+
+		nflogRetention    = flag.Duration("nflog.retention", 0, "How long to keep notification log entries for. Defaults to -data.retention.")
+		nflogGCInterval   = flag.Duration("nflog.gc-interval", 15*time.Minute, "Interval at which expired notification log entries are garbage collected.")
+		nflogSnapInterval = flag.Duration("nflog.snapshot-interval", 0, "Interval at which the notification log is snapshotted. Defaults to a snapshot after each garbage collection.")
+
+		silencesSQLDriver = flag.String("storage.silences.sql-driver", "postgres", "SQL driver used for storing silences (postgres or mysql).")
+		silencesSQLDSN    = flag.String("storage.silences.sql-dsn", "", "Data source name of a SQL database in which silences are stored instead of local snapshots.")
+		silencesSync      = flag.Duration("storage.silences.sync-interval", 30*time.Second, "Interval at which silences are loaded from the external silence storage.")
+
+		storageBackend = flag.String("storage.backend", "", "Where alerts, silences and the notification log are persisted: \"local\" for files in the storage path, \"memory\" for not persisting them or \"kv\" for the key/value store. Defaults to \"kv\" if a key/value store is configured and \"local\" otherwise. Acknowledgements, comments, assignments, snoozes, pauses, the notification history and API keys are snapshotted to the storage path unless it is \"memory\".")
+
+		kvBackend = flag.String("storage.kv.backend", "", "Key/value store in which silences and the notification log are shared instead of local snapshots (consul, etcd or redis).")
+		kvAddress = flag.String("storage.kv.address", "http://localhost:8500", "Base URL of the key/value store's HTTP API, or redis://host:port/db for Redis (rediss:// for TLS).")
+		kvPrefix  = flag.String("storage.kv.prefix", "alertmanager/", "Prefix of all keys written to the key/value store.")
+		kvToken   = flag.String("storage.kv.token", "", "Token used to authenticate against the key/value store, which is the password for Redis.")
+		kvSync    = flag.Duration("storage.kv.sync-interval", 30*time.Second, "Interval at which state is loaded from the key/value store.")
+		kvTLSCA   = flag.String("storage.kv.tls-ca-file", "", "CA certificate file used to verify the key/value store's certificate instead of the system's CAs.")
+		kvTLSCert = flag.String("storage.kv.tls-cert-file", "", "Client certificate file presented to the key/value store for mutual TLS.")
+		kvTLSKey  = flag.String("storage.kv.tls-key-file", "", "Key file of the client certificate presented to the key/value store.")
+
+		backupURL        = flag.String("backup.url", "", "Bucket to which the silences, notification log and alerts in the storage path are backed up: s3://<bucket>/<prefix>, gs://<bucket>/<prefix> or azure://<account>/<container>/<prefix>. S3 and Cloud Storage use the HMAC credentials in AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY, Azure the shared access signature in AZURE_STORAGE_SAS_TOKEN.")
+		backupInterval   = flag.Duration("backup.interval", time.Hour, "Interval at which backups are taken.")
+		backupRetention  = flag.Duration("backup.retention", 7*24*time.Hour, "How long to keep backups for. The latest backup is always kept.")
+		backupRestore    = flag.Bool("backup.restore", false, "Restore the latest backup on start if the storage path holds no state.")
+		backupS3Endpoint = flag.String("backup.s3-endpoint", "", "Endpoint of the S3 API. Defaults to the one of Amazon for the region.")
+		backupS3Region   = flag.String("backup.s3-region", "us-east-1", "Region of the S3 bucket.")
+
+		gomaxprocs = flag.Int("runtime.gomaxprocs", 0, "Maximum number of CPUs executing Go code simultaneously. 0 keeps the default of GOMAXPROCS or the number of CPUs.")
+		gcPercent  = flag.Int("runtime.gc-percent", 0, "Garbage collection target percentage, trading memory for CPU during alert storms. 0 keeps the default of GOGC or 100, a negative value disables garbage collection.")
+
+		profilingURL        = flag.String("profiling.push-url", "", "Continuous profiling endpoint to which CPU, heap and goroutine profiles are pushed while the load exceeds a threshold. Profiles are sent as POST requests with the query parameters name, from and until.")
+		profilingCPU        = flag.Float64("profiling.cpu-threshold", 0, "CPU usage in cores from which on profiles are pushed. 0 disables the trigger.")
+		profilingGoroutines = flag.Int("profiling.goroutine-threshold", 0, "Number of goroutines from which on profiles are pushed. 0 disables the trigger.")
+		profilingInterval   = flag.Duration("profiling.check-interval", 15*time.Second, "Interval at which the load is checked.")
+		profilingDuration   = flag.Duration("profiling.duration", 10*time.Second, "Time CPU profiles are recorded for.")
+		profilingMinWait    = flag.Duration("profiling.min-interval", 10*time.Minute, "Minimum time between pushing profiles.")
+
+		tracingEndpoint    = flag.String("tracing.otlp-endpoint", "", "URL of an OpenTelemetry collector to which traces of the notification pipeline are exported via OTLP/HTTP, e.g. http://localhost:4318/v1/traces. Empty disables tracing.")
+		tracingService     = flag.String("tracing.service-name", "alertmanager", "Service name spans are attributed to.")
+		tracingSampleRatio = flag.Float64("tracing.sample-ratio", 1, "Ratio of traces started by the Alertmanager that are sampled. Traces continued from a traceparent header of an incoming request follow its sampling decision.")
 
 		externalURL   = flag.String("web.external-url", "", "The URL under which Alertmanager is externally reachable (for example, if Alertmanager is served via a reverse proxy). Used for generating relative and absolute links back to Alertmanager itself. If the URL has a path portion, it will be used to prefix all HTTP endpoints served by Alertmanager. If omitted, relevant URL components will be derived automatically.")
 		listenAddress = flag.String("web.listen-address", ":9093", "Address to listen on for the web interface and API.")
-
-		meshListen = flag.String("mesh.listen-address", net.JoinHostPort("0.0.0.0", strconv.Itoa(mesh.Port)), "mesh listen address")
-		hwaddr     = flag.String("mesh.hardware-address", mustHardwareAddr(), "MAC address, i.e. mesh peer ID")
-		nickname   = flag.String("mesh.nickname", mustHostname(), "peer nickname")
-		password   = flag.String("mesh.password", "", "password to join the peer network (empty password disables encryption)")
+		enableGraphQL = flag.Bool("web.enable-graphql", false, "Serve GraphQL queries over alerts, silences, receivers and the status under /api/graphql.")
+
+		auditSyslog     = flag.String("audit.syslog", "", "Syslog server to which the audit log of mutating API calls is shipped, either \"local\" or an address like \"udp://host:514\". The audit log is always written to the storage path.")
+		auditMaxSize    = flag.Int64("audit.max-size", 100<<20, "Size in bytes from which on the audit log is rotated, keeping the previous entries in a single rotated file. 0 disables rotation.")
+		auditSkipAlerts = flag.Bool("audit.skip-alerts", false, "Do not record alerts pushed by clients such as Prometheus in the audit log, e.g. to keep high alert volumes out of it.")
+
+		meshListen      = flag.String("mesh.listen-address", net.JoinHostPort("0.0.0.0", strconv.Itoa(mesh.Port)), "mesh listen address")
+		hwaddr          = flag.String("mesh.hardware-address", mustHardwareAddr(), "MAC address, i.e. mesh peer ID")
+		nickname        = flag.String("mesh.nickname", mustHostname(), "peer nickname")
+		password        = flag.String("mesh.password", "", "password to join the peer network (empty password disables encryption)")
+		passwordFile    = flag.String("mesh.password-file", "", "file containing the password to join the peer network, instead of mesh.password")
+		settleTime      = flag.Duration("mesh.settle-timeout", 30*time.Second, "maximum time to wait for the initial state replication with peers before reporting readiness")
+		peerTimeout     = flag.Duration("mesh.peer-timeout", 5*time.Second, "time to wait for each peer ahead of this instance to send a notification before sending it as well")
+		region          = flag.String("mesh.region", "", "region this instance runs in; peers send notifications region by region, ordered by region name")
+		regionTimeout   = flag.Duration("mesh.region-timeout", 15*time.Second, "additional time to wait for each region ahead of this instance's region to send a notification, covering the latency of gossip between regions")
+		shardReplicas   = flag.Int("mesh.shard-replication-factor", 0, "number of peers owning each group key; peers only send notifications of the groups they own, ordered by their precedence for the group, which spreads notifications across the cluster (0 disables sharding, every peer owns every group)")
+		gossipCompress  = flag.Bool("mesh.gossip-compression", false, "compress gossip sent to peers, which reduces the traffic across WAN links; all peers must run a version accepting compressed gossip")
+		peerDNSInterval = flag.Duration("mesh.peer-dns-interval", 30*time.Second, "interval at which the names given by mesh.peer-dns are resolved again")
+		readReplica     = flag.Bool("mesh.read-replica", false, "run as a read replica, which replicates the state of its peers and serves read-only API and UI traffic, but never sends notifications; changes through the API other than alerts are rejected")
+		replicaSource   = flag.String("mesh.read-replica-source", "", "URL of an instance whose alerts a read replica mirrors, as alerts are not replicated through the mesh; without it, the replica only knows alerts sent to it directly, e.g. by Prometheus")
+		replicaInterval = flag.Duration("mesh.read-replica-interval", 15*time.Second, "interval at which a read replica mirrors the alerts of mesh.read-replica-source")
+		peerKubernetes  = flag.String("mesh.peer-kubernetes", "", "Kubernetes service whose endpoints are watched for peers, given as [<namespace>/]<service>[:<port name or number>]; the namespace defaults to the one of the pod and the port to the mesh port")
 	)
+	flag.Var(resyncURLs, "alerts.resync-url", "Base URL of a Prometheus server whose firing alerts are loaded on startup, so ongoing incidents are known before the server sends them again (may be repeated).")
 	flag.Var(peers, "mesh.peer", "initial peers (may be repeated)")
+	flag.Var(peerNames, "mesh.peer-dns", "DNS name resolving to peers, either dns+<host>:<port> for A records or dnssrv+<name> for SRV records (may be repeated)")
 	flag.Parse()
 
 	if len(flag.Args()) > 0 {
@@ -97,47 +216,208 @@ func main() {
 		fmt.Fprintln(os.Stdout, version.Print("alertmanager"))
 		os.Exit(0)
 	}
+	for _, name := range peerNames.slice() {
+		if err := discovery.Validate(name); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if *peerKubernetes != "" && len(peerNames.slice()) > 0 {
+		log.Fatal("Mesh peers cannot be discovered from DNS and Kubernetes at the same time")
+	}
+	if *replicaSource != "" && !*readReplica {
+		log.Fatal("Alerts can only be mirrored by read replicas")
+	}
 
 	log.Infoln("Starting alertmanager", version.Info())
 	log.Infoln("Build context", version.BuildContext())
 
+	if *gomaxprocs > 0 {
+		runtime.GOMAXPROCS(*gomaxprocs)
+	}
+	if *gcPercent != 0 {
+		debug.SetGCPercent(*gcPercent)
+	}
+	log.Infof("Running with GOMAXPROCS=%d", runtime.GOMAXPROCS(0))
+
 	err := os.MkdirAll(*dataDir, 0777)
 	if err != nil {
 		log.Fatal(err)
 	}
 
+	if *passwordFile != "" {
+		if *password != "" {
+			log.Fatal("Only one of mesh.password and mesh.password-file may be given")
+		}
+		b, err := ioutil.ReadFile(*passwordFile)
+		if err != nil {
+			log.Fatalf("Reading mesh password: %s", err)
+		}
+		*password = strings.TrimSpace(string(b))
+		if *password == "" {
+			log.Fatalf("Mesh password file %s is empty", *passwordFile)
+		}
+	}
+	if *password == "" && (len(peers.slice()) > 0 || len(peerNames.slice()) > 0 || *peerKubernetes != "") {
+		log.Warnln("No mesh password is set, traffic between peers is neither encrypted nor authenticated")
+	}
+
 	logger := log.NewLogger(os.Stderr)
 	mrouter := initMesh(*meshListen, *hwaddr, *nickname, *password)
+	tracker := cluster.NewTracker(mrouter, cluster.Options{
+		Region:   *region,
+		Compress: *gossipCompress,
+	})
+	prometheus.MustRegister(tracker)
 
 	stopc := make(chan struct{})
 	var wg sync.WaitGroup
 	wg.Add(1)
 
-	notificationLog, err := nflog.New(
+	switch *storageBackend {
+	case "":
+		*storageBackend = "local"
+		if *kvBackend != "" {
+			*storageBackend = "kv"
+		}
+	case "local", "memory":
+		if *kvBackend != "" {
+			log.Fatalf("A key/value store backend cannot be set with the %s storage backend", *storageBackend)
+		}
+	case "kv":
+		if *kvBackend == "" {
+			log.Fatal("The kv storage backend requires a key/value store backend")
+		}
+	default:
+		log.Fatalf("Unknown storage backend %q", *storageBackend)
+	}
+
+	var bkp *backup.Backup
+	if *backupURL != "" {
+		if *storageBackend != "local" {
+			log.Fatal("Backups require the local storage backend")
+		}
+		if *backupInterval <= 0 {
+			log.Fatal("Backup interval must be positive")
+		}
+		bucket, err := backup.NewBucket(backup.BucketOptions{
+			URL:             *backupURL,
+			S3Endpoint:      *backupS3Endpoint,
+			S3Region:        *backupS3Region,
+			AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			SASToken:        os.Getenv("AZURE_STORAGE_SAS_TOKEN"),
+		})
+		if err != nil {
+			log.Fatalf("Backup bucket: %s", err)
+		}
+		bkp = backup.New(backup.Options{
+			Bucket:    bucket,
+			Dir:       *dataDir,
+			Files:     []string{"silences", "nflog", "alerts", "alerts.wal"},
+			Retention: *backupRetention,
+			Logger:    logger.With("component", "backup"),
+			Metrics:   prometheus.DefaultRegisterer,
+		})
+		if *backupRestore && bkp.Empty() {
+			name, err := bkp.Restore()
+			if err != nil {
+				log.Fatalf("Restoring backup: %s", err)
+			}
+			if name != "" {
+				log.Infof("Restored backup %s", name)
+			}
+		}
+	}
+
+	var kvStore kv.Store
+	if *kvBackend != "" {
+		if *silencesSQLDSN != "" {
+			log.Fatal("Silences cannot be stored in a SQL database and a key/value store at the same time")
+		}
+		var tlsConf *tls.Config
+		if *kvTLSCA != "" || *kvTLSCert != "" || *kvTLSKey != "" {
+			tlsConf, err = kv.NewTLSConfig(*kvTLSCA, *kvTLSCert, *kvTLSKey)
+			if err != nil {
+				log.Fatalf("TLS configuration of the key/value store: %s", err)
+			}
+		}
+		kvStore, err = kv.New(*kvBackend, *kvAddress, *kvToken, tlsConf)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if *nflogRetention == 0 {
+		*nflogRetention = *retention
+	}
+	if *nflogGCInterval <= 0 {
+		log.Fatal("Notification log garbage collection interval must be positive")
+	}
+
+	nflogOpts := []nflog.Option{
 		nflog.WithMesh(func(g mesh.Gossiper) mesh.Gossip {
-			return mrouter.NewGossip("nflog", g)
+			return mrouter.NewGossip("nflog", tracker.Gossiper("nflog", g))
 		}),
-		nflog.WithRetention(*retention),
-		nflog.WithSnapshot(filepath.Join(*dataDir, "nflog")),
-		nflog.WithMaintenance(15*time.Minute, stopc, wg.Done),
+		nflog.WithRetention(*nflogRetention),
+		nflog.WithMaintenance(*nflogGCInterval, stopc, wg.Done),
+		nflog.WithSnapshotInterval(*nflogSnapInterval),
 		nflog.WithMetrics(prometheus.DefaultRegisterer),
 		nflog.WithLogger(logger.With("component", "nflog")),
-	)
+	}
+	switch {
+	case kvStore != nil:
+		nflogOpts = append(nflogOpts, nflog.WithStore(kv.NewNflogStore(kvStore, *kvPrefix), *kvSync))
+	case *storageBackend == "local":
+		nflogOpts = append(nflogOpts, nflog.WithSnapshot(filepath.Join(*dataDir, "nflog")))
+	}
+
+	notificationLog, err := nflog.New(nflogOpts...)
 	if err != nil {
 		log.Fatal(err)
 	}
 
 	marker := types.NewMarker()
 
-	silences, err := silence.New(silence.Options{
+	if *silencesRetention == 0 {
+		*silencesRetention = *retention
+	}
+	if *silencesGCInterval <= 0 {
+		log.Fatal("Silence garbage collection interval must be positive")
+	}
+
+	silenceOpts := silence.Options{
 		SnapshotFile: filepath.Join(*dataDir, "silences"),
-		Retention:    *retention,
+		Retention:    *silencesRetention,
 		Logger:       logger.With("component", "silences"),
 		Metrics:      prometheus.DefaultRegisterer,
 		Gossip: func(g mesh.Gossiper) mesh.Gossip {
-			return mrouter.NewGossip("silences", g)
+			return mrouter.NewGossip("silences", tracker.Gossiper("silences", g))
 		},
-	})
+	}
+	if *storageBackend == "memory" {
+		silenceOpts.SnapshotFile = ""
+	}
+	if *silencesQuorum {
+		silenceOpts.Peers = meshPeers(mrouter)
+		silenceOpts.QuorumTimeout = *silencesQuorumWait
+	}
+	if *silencesSQLDSN != "" {
+		store, err := sqlstore.New(*silencesSQLDriver, *silencesSQLDSN)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer store.Close()
+
+		silenceOpts.SnapshotFile = ""
+		silenceOpts.Store = store
+	}
+	if kvStore != nil {
+		silenceOpts.SnapshotFile = ""
+		silenceOpts.Store = kv.NewSilenceStore(kvStore, *kvPrefix)
+		silencesSync = kvSync
+	}
+
+	silences, err := silence.New(silenceOpts)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -145,9 +425,162 @@ func main() {
 	// Start providers before router potentially sends updates.
 	wg.Add(1)
 	go func() {
-		silences.Maintenance(15*time.Minute, filepath.Join(*dataDir, "silences"), stopc)
+		silences.Maintenance(*silencesGCInterval, silenceOpts.SnapshotFile, stopc)
 		wg.Done()
 	}()
+	go silences.SyncStore(*silencesSync, stopc)
+	if *profilingURL != "" {
+		if *profilingCPU <= 0 && *profilingGoroutines <= 0 {
+			log.Fatal("Pushing profiles requires a CPU or goroutine threshold")
+		}
+		profiler := profiling.New(profiling.Options{
+			URL:                *profilingURL,
+			CPUThreshold:       *profilingCPU,
+			GoroutineThreshold: *profilingGoroutines,
+			CheckInterval:      *profilingInterval,
+			Duration:           *profilingDuration,
+			MinInterval:        *profilingMinWait,
+			Logger:             logger.With("component", "profiling"),
+			Metrics:            prometheus.DefaultRegisterer,
+		})
+		go profiler.Run(stopc)
+	}
+	if bkp != nil {
+		go bkp.Run(*backupInterval, stopc)
+	}
+	if *tracingEndpoint != "" {
+		tracer := tracing.New(tracing.Options{
+			Endpoint:    *tracingEndpoint,
+			ServiceName: *tracingService,
+			SampleRatio: *tracingSampleRatio,
+			Logger:      logger.With("component", "tracing"),
+			Metrics:     prometheus.DefaultRegisterer,
+		})
+		tracing.SetTracer(tracer)
+
+		wg.Add(1)
+		go func() {
+			tracer.Run(stopc)
+			wg.Done()
+		}()
+	}
+
+	if *dataGCInterval <= 0 {
+		log.Fatal("Data garbage collection interval must be positive")
+	}
+	// stateSnapshot returns the snapshot file of the state with the given
+	// name, which is not persisted with the memory storage backend.
+	stateSnapshot := func(name string) string {
+		if *storageBackend == "memory" {
+			return ""
+		}
+		return filepath.Join(*dataDir, name)
+	}
+	maintain := func(f func(time.Duration, string, <-chan struct{}), snapf string) {
+		wg.Add(1)
+		go func() {
+			f(*dataGCInterval, snapf, stopc)
+			wg.Done()
+		}()
+	}
+
+	acksSnapshot := stateSnapshot("acks")
+	acks, err := ack.New(ack.Options{
+		SnapshotFile: acksSnapshot,
+		Retention:    *retention,
+		Logger:       logger.With("component", "acks"),
+		Gossip: func(g mesh.Gossiper) mesh.Gossip {
+			return mrouter.NewGossip("acks", tracker.Gossiper("acks", g))
+		},
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	maintain(acks.Maintenance, acksSnapshot)
+
+	commentsSnapshot := stateSnapshot("comments")
+	comments, err := comment.New(comment.Options{
+		SnapshotFile: commentsSnapshot,
+		Retention:    *retention,
+		Logger:       logger.With("component", "comments"),
+		Gossip: func(g mesh.Gossiper) mesh.Gossip {
+			return mrouter.NewGossip("comments", tracker.Gossiper("comments", g))
+		},
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	maintain(comments.Maintenance, commentsSnapshot)
+
+	assignmentsSnapshot := stateSnapshot("assignments")
+	assignments, err := assignment.New(assignment.Options{
+		SnapshotFile: assignmentsSnapshot,
+		Retention:    *retention,
+		Logger:       logger.With("component", "assignments"),
+		Gossip: func(g mesh.Gossiper) mesh.Gossip {
+			return mrouter.NewGossip("assignments", tracker.Gossiper("assignments", g))
+		},
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	maintain(assignments.Maintenance, assignmentsSnapshot)
+
+	snoozesSnapshot := stateSnapshot("snoozes")
+	snoozes, err := snooze.New(snooze.Options{
+		SnapshotFile: snoozesSnapshot,
+		Retention:    *retention,
+		Logger:       logger.With("component", "snoozes"),
+		Gossip: func(g mesh.Gossiper) mesh.Gossip {
+			return mrouter.NewGossip("snoozes", tracker.Gossiper("snoozes", g))
+		},
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	maintain(snoozes.Maintenance, snoozesSnapshot)
+
+	pausesSnapshot := stateSnapshot("pauses")
+	pauses, err := pause.New(pause.Options{
+		SnapshotFile: pausesSnapshot,
+		Retention:    *retention,
+		Logger:       logger.With("component", "pauses"),
+		Gossip: func(g mesh.Gossiper) mesh.Gossip {
+			return mrouter.NewGossip("pauses", tracker.Gossiper("pauses", g))
+		},
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	maintain(pauses.Maintenance, pausesSnapshot)
+
+	historySnapshot := stateSnapshot("history")
+	hist, err := history.New(history.Options{
+		SnapshotFile: historySnapshot,
+		Retention:    *retention,
+		Logger:       logger.With("component", "history"),
+		Gossip: func(g mesh.Gossiper) mesh.Gossip {
+			return mrouter.NewGossip("history", tracker.Gossiper("history", g))
+		},
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	maintain(hist.Maintenance, historySnapshot)
+
+	apiKeysSnapshot := stateSnapshot("apikeys")
+	apiKeys, err := apikey.New(apikey.Options{
+		SnapshotFile: apiKeysSnapshot,
+		Retention:    *retention,
+		Logger:       logger.With("component", "apikeys"),
+		Gossip: func(g mesh.Gossiper) mesh.Gossip {
+			return mrouter.NewGossip("apikeys", tracker.Gossiper("apikeys", g))
+		},
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	maintain(apiKeys.Maintenance, apiKeysSnapshot)
 
 	mrouter.Start()
 
@@ -158,32 +591,183 @@ func main() {
 		wg.Wait()
 	}()
 
-	mrouter.ConnectionMaker.InitiateConnections(peers.slice(), true)
+	initialPeers := peers.slice()
+	if names := peerNames.slice(); len(names) > 0 {
+		resolver := discovery.NewResolver()
+		addrs, err := resolver.Resolve(names)
+		if err != nil {
+			log.Warnf("Resolving mesh peers failed: %s", err)
+		}
+		initialPeers = append(addrs, initialPeers...)
+
+		wg.Add(1)
+		go func() {
+			resolver.Run(names, peers.slice(), *peerDNSInterval, func(addrs []string) {
+				mrouter.ConnectionMaker.InitiateConnections(addrs, true)
+			}, stopc)
+			wg.Done()
+		}()
+	}
+	if *peerKubernetes != "" {
+		_, meshPort, err := net.SplitHostPort(*meshListen)
+		if err != nil {
+			log.Fatal(err)
+		}
+		k8s, err := discovery.NewKubernetes(*peerKubernetes, meshPort)
+		if err != nil {
+			log.Fatalf("Kubernetes peer discovery: %s", err)
+		}
+		addrs, err := k8s.Addresses()
+		if err != nil {
+			log.Warnf("Listing Kubernetes endpoints failed: %s", err)
+		}
+		initialPeers = append(addrs, initialPeers...)
+
+		wg.Add(1)
+		go func() {
+			k8s.Run(peers.slice(), func(addrs []string) {
+				mrouter.ConnectionMaker.InitiateConnections(addrs, true)
+			}, stopc)
+			wg.Done()
+		}()
+	}
+	mrouter.ConnectionMaker.InitiateConnections(initialPeers, true)
+
+	settled := make(chan struct{})
+	go meshSettle(mrouter, len(initialPeers), time.Second, *settleTime, settled)
 
-	alerts, err := mem.NewAlerts(*dataDir)
+	if *alertsGCInterval <= 0 {
+		log.Fatal("Alert garbage collection interval must be positive")
+	}
+
+	var alertStore mem.Store
+	switch {
+	case kvStore != nil:
+		alertStore = kv.NewAlertStore(kvStore, *kvPrefix)
+	case *storageBackend == "local":
+		alertStore = mem.NewDiskStore(*dataDir)
+	}
+	alerts, err := mem.New(mem.Options{
+		Store: alertStore,
+		Limits: mem.Limits{
+			MaxAlerts:     *alertsMaxCount,
+			MaxBytes:      *alertsMaxBytes,
+			SeverityLabel: model.LabelName(*alertsSevLabel),
+			Severities:    strings.Split(*alertsSeverities, ","),
+		},
+		Retention:  *alertsRetention,
+		GCInterval: *alertsGCInterval,
+		Metrics:    prometheus.DefaultRegisterer,
+	})
 	if err != nil {
 		log.Fatal(err)
 	}
 	defer alerts.Close()
 
+	usage := dispatch.NewSilenceUsageTracker(alerts, marker, silences)
+	go usage.Run()
+	defer usage.Stop()
+
+	breakers := notify.NewBreakers(notify.BreakerOptions{
+		Failures: *breakerFailures,
+		Cooldown: *breakerCooldown,
+		Alerts:   alerts.Put,
+	})
+
+	var captures *notify.Captures
+	if *captureSize > 0 {
+		captures = notify.NewCaptures(*captureSize)
+	}
+
+	var retries *notify.RetryQueue
+	if *retryInterval > 0 && !*readReplica {
+		queueOpts := notify.QueueOptions{
+			File:     filepath.Join(*dataDir, "retries"),
+			Interval: *retryInterval,
+			MaxAge:   *retryMaxAge,
+			Logger:   logger.With("component", "retries"),
+			Metrics:  prometheus.DefaultRegisterer,
+		}
+		if *storageBackend != "local" {
+			queueOpts.File = ""
+		}
+		retries, err = notify.NewRetryQueue(queueOpts)
+		if err != nil {
+			log.Fatal(err)
+		}
+		wg.Add(1)
+		go func() {
+			retries.Run(stopc)
+			wg.Done()
+		}()
+	}
+
+	bus := events.NewBus()
+	watcher := events.NewWatcher(alerts, silences, bus)
+	go watcher.Run()
+	defer watcher.Stop()
+
 	var (
 		inhibitor *inhibit.Inhibitor
 		tmpl      *template.Template
+		tmplWatch *template.Watcher
 		pipeline  notify.Stage
 		disp      *dispatch.Dispatcher
+		expiry    *dispatch.SilenceExpiryNotifier
+		summary   *dispatch.SummaryNotifier
+		calendars []*maintenance.Calendar
+		topo      *topology.Inhibitor
+		storm     *dispatch.StormSuppressor
+		webhooks  []func()
+
+		unsubscribeDisp = func() {}
 	)
 	defer disp.Stop()
+	defer func() { unsubscribeDisp() }()
+	defer func() { topo.Stop() }()
+	defer func() { storm.Stop() }()
+	defer func() { expiry.Stop() }()
+	defer func() { summary.Stop() }()
+	defer func() { tmplWatch.Stop() }()
+	defer func() {
+		for _, c := range calendars {
+			c.Stop()
+		}
+	}()
+	defer func() {
+		for _, unsubscribe := range webhooks {
+			unsubscribe()
+		}
+	}()
 
-	apiv := api.New(alerts, silences, func() dispatch.AlertOverview {
-		return disp.Groups()
+	auditLog, err := audit.New(audit.Options{
+		File:    filepath.Join(*dataDir, "audit.log"),
+		MaxSize: *auditMaxSize,
+		Syslog:  *auditSyslog,
+		Logger:  logger.With("component", "audit"),
 	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer auditLog.Close()
+
+	apiv := api.New(alerts, silences, acks, comments, assignments, snoozes, pauses, apiKeys, notificationLog, hist, auditLog, func() dispatch.AlertOverview {
+		return disp.Groups()
+	}, func(lset model.LabelSet) []*inhibit.Inhibition {
+		return inhibitor.Inhibitions(lset)
+	}, tracker)
+	apiv.SetAlertRetention(*alertsRetention, *alertsGCInterval)
+	apiv.SetReadOnly(*readReplica)
+	apiv.SetCaptures(captures)
 
 	amURL, err := extURL(*listenAddress, *externalURL)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	waitFunc := meshWait(mrouter, 5*time.Second)
+	authenticator := auth.New(amURL, apiKeys)
+
+	waitFunc := meshWait(mrouter, tracker.Region, *peerTimeout, *regionTimeout)
 	timeoutFunc := func(d time.Duration) time.Duration {
 		if d < notify.MinTimeout {
 			d = notify.MinTimeout
@@ -191,6 +775,10 @@ func main() {
 		return d + waitFunc()
 	}
 
+	var (
+		configLoaded     = make(chan struct{})
+		configLoadedOnce sync.Once
+	)
 	reload := func() (err error) {
 		log.With("file", *configFile).Infof("Loading configuration file")
 		defer func() {
@@ -200,6 +788,7 @@ func main() {
 			} else {
 				configSuccess.Set(1)
 				configSuccessTime.Set(float64(time.Now().Unix()))
+				configLoadedOnce.Do(func() { close(configLoaded) })
 			}
 		}()
 
@@ -212,30 +801,268 @@ func main() {
 		if err != nil {
 			return err
 		}
+		if *readReplica {
+			// Read replicas neither notify nor create silences, which
+			// are replicated from their peers instead.
+			conf.EventWebhooks = nil
+			conf.StormRules = nil
+			conf.SilenceExpiry = nil
+			conf.Summary = nil
+			conf.MaintenanceCalendars = nil
+		}
+
+		silenceRetention, silenceGCInterval := *silencesRetention, *silencesGCInterval
+		if rc := conf.SilenceRetention; rc != nil {
+			if rc.Retention > 0 {
+				silenceRetention = time.Duration(rc.Retention)
+			}
+			if rc.GCInterval > 0 {
+				silenceGCInterval = time.Duration(rc.GCInterval)
+			}
+		}
+		if err := silences.SetRetention(silenceRetention); err != nil {
+			return err
+		}
+		silences.SetMaintenanceInterval(silenceGCInterval)
 
 		tmpl, err = template.FromGlobs(conf.Templates...)
 		if err != nil {
 			return err
 		}
 		tmpl.ExternalURL = amURL
+		tmpl.MaxAlerts = map[string]int{}
+		tmpl.Locale = conf.Global.Locale
+		tmpl.Limits = template.Limits{
+			Timeout:       time.Duration(conf.TemplateLimits.Timeout),
+			MaxOutputSize: conf.TemplateLimits.MaxOutputSize,
+			MaxDepth:      conf.TemplateLimits.MaxDepth,
+		}
+		tmpl.Locales = map[string]string{}
+		for _, rc := range conf.Receivers {
+			if rc.MaxTemplateAlerts > 0 {
+				tmpl.MaxAlerts[rc.Name] = rc.MaxTemplateAlerts
+			}
+			tmpl.Locales[rc.Name] = rc.Locale
+		}
+		if lc := conf.SilenceLinks; lc != nil {
+			tmpl.SilenceLinks = link.NewSigner(string(lc.Secret), time.Duration(lc.Duration), time.Duration(lc.Validity))
+		}
+		if lc := conf.DashboardLinks; lc != nil {
+			tmpl.Links = template.Links{
+				GrafanaURL:   lc.GrafanaURL,
+				LogSearchURL: lc.LogSearchURL,
+				TraceURL:     lc.TraceURL,
+			}
+		}
+		apiv.SetNotifiers(conf.Receivers, tmpl)
+		authenticator.ApplyConfig(conf.OIDC)
+
+		var cals []*maintenance.Calendar
+		for _, cc := range conf.MaintenanceCalendars {
+			c, err := maintenance.New(cc, silences)
+			if err != nil {
+				return err
+			}
+			cals = append(cals, c)
+		}
+
+		var newTopo *topology.Inhibitor
+		if conf.Topology != nil {
+			newTopo, err = topology.New(conf.Topology, alerts, marker)
+			if err != nil {
+				return err
+			}
+		}
+
+		var hooks []*events.Webhook
+		for _, wc := range conf.EventWebhooks {
+			h, err := events.NewWebhook(wc)
+			if err != nil {
+				return err
+			}
+			hooks = append(hooks, h)
+		}
 
+		// Notifications in flight are sent through the previous pipeline
+		// before its components are stopped.
+		unsubscribeDisp()
+		disp.Drain(*drainTimeout)
 		inhibitor.Stop()
-		disp.Stop()
+		expiry.Stop()
+		summary.Stop()
+		topo.Stop()
+		storm.Stop()
+		for _, c := range calendars {
+			c.Stop()
+		}
+		for _, unsubscribe := range webhooks {
+			unsubscribe()
+		}
+
+		webhooks = webhooks[:0]
+		for _, h := range hooks {
+			webhooks = append(webhooks, h.Subscribe(bus))
+		}
 
 		inhibitor = inhibit.NewInhibitor(alerts, conf.InhibitRules, marker)
+		topo = newTopo
+
+		storm = nil
+		if len(conf.StormRules) > 0 {
+			var stages []notify.Stage
+			for _, sr := range conf.StormRules {
+				for _, rc := range conf.Receivers {
+					if rc.Name == sr.Receiver {
+						stages = append(stages, notify.BuildReceiverStage(rc, tmpl, waitFunc, notificationLog))
+						break
+					}
+				}
+			}
+			storm = dispatch.NewStormSuppressor(alerts, conf.StormRules, stages, marker, timeoutFunc)
+		}
+
+		muters := []types.Muter{inhibitor}
+		if topo != nil {
+			muters = append(muters, topo)
+		}
+		if storm != nil {
+			muters = append(muters, storm)
+		}
+		var muter types.Muter = inhibitor
+		if len(muters) > 1 {
+			muter = types.MuteFunc(func(lset model.LabelSet) bool {
+				for _, m := range muters {
+					if m.Mutes(lset) {
+						return true
+					}
+				}
+				return false
+			})
+		}
 		pipeline = notify.BuildPipeline(
 			conf.Receivers,
 			tmpl,
 			waitFunc,
-			inhibitor,
+			muter,
 			silences,
+			acks,
+			comments,
+			assignments,
+			snoozes,
+			pauses,
 			notificationLog,
+			hist,
 			marker,
+			bus,
+			breakers,
+			retries,
+			captures,
+		)
+		var flaps *dispatch.FlapDetector
+		if fc := conf.FlapDetection; fc != nil {
+			flaps = dispatch.NewFlapDetector(fc.Threshold, time.Duration(fc.Window), time.Duration(fc.GroupInterval), marker)
+		}
+		dc := config.DefaultDispatcherConfig
+		if conf.Dispatcher != nil {
+			dc = *conf.Dispatcher
+		}
+		var queue *dispatch.PriorityQueue
+		if pc := conf.NotificationPriority; pc != nil {
+			queue = dispatch.NewPriorityQueue(pc.Label, pc.Values, pc.Concurrency)
+		} else if dc.MaxConcurrentNotifications > 0 {
+			// Without priorities, waiting notifications are sent in order.
+			queue = dispatch.NewPriorityQueue("", nil, dc.MaxConcurrentNotifications)
+		}
+		var dispPipeline notify.Stage = pipeline
+		if *readReplica {
+			// Alerts are still grouped for the API and UI.
+			dispPipeline = notify.StageFunc(func(ctx context.Context, alerts ...*types.Alert) (context.Context, []*types.Alert, error) {
+				return ctx, nil, nil
+			})
+		} else if *shardReplicas > 0 {
+			dispPipeline = notify.NewShardStage(meshShard(mrouter, *shardReplicas, *peerTimeout), pipeline)
+		}
+		oldDisp := disp
+		disp = dispatch.NewDispatcher(
+			alerts,
+			dispatch.NewRoute(conf.Route, nil),
+			dispPipeline,
+			marker,
+			flaps,
+			queue,
+			dc.Workers,
+			dc.QueueCapacity,
+			timeoutFunc,
 		)
-		disp = dispatch.NewDispatcher(alerts, dispatch.NewRoute(conf.Route, nil), pipeline, marker, timeoutFunc)
 
+		disp.Inherit(oldDisp)
 		go disp.Run()
+
+		d := disp
+		unsubscribeDisp = bus.Subscribe("dispatcher", func(e *events.Event) {
+			d.SilenceExpired(e.Silence.ID)
+		}, events.SilenceExpired)
 		go inhibitor.Run()
+		if topo != nil {
+			go topo.Run()
+		}
+		if storm != nil {
+			go storm.Run()
+		}
+
+		expiry = nil
+		if ec := conf.SilenceExpiry; ec != nil {
+			for _, rc := range conf.Receivers {
+				if rc.Name != ec.Receiver {
+					continue
+				}
+				expiry = dispatch.NewSilenceExpiryNotifier(
+					alerts,
+					silences,
+					notify.BuildReceiverStage(rc, tmpl, waitFunc, notificationLog),
+					rc.Name,
+					time.Duration(ec.Before),
+					timeoutFunc,
+				)
+				go expiry.Run()
+			}
+		}
+
+		summary = nil
+		if sc := conf.Summary; sc != nil {
+			// The time of day has been validated when loading the config.
+			at, _ := time.Parse("15:04", sc.At)
+			for _, rc := range conf.Receivers {
+				if rc.Name != sc.Receiver {
+					continue
+				}
+				summary = dispatch.NewSummaryNotifier(
+					alerts,
+					silences,
+					notificationLog,
+					tmpl,
+					sc.Template,
+					notify.BuildReceiverStage(rc, tmpl, waitFunc, notificationLog),
+					rc.Name,
+					at,
+					time.Duration(sc.Interval),
+					timeoutFunc,
+				)
+				go summary.Run()
+			}
+		}
+
+		calendars = cals
+		for _, c := range calendars {
+			go c.Run()
+		}
+
+		tmplWatch.Stop()
+		tmplWatch = nil
+		if *tmplInterval > 0 {
+			tmplWatch = template.NewWatcher(tmpl, *tmplInterval)
+			go tmplWatch.Run()
+		}
 
 		return nil
 	}
@@ -244,14 +1071,69 @@ func main() {
 		os.Exit(1)
 	}
 
-	router := route.New(nil)
+	// Loading alerts requires the configuration for completing them.
+	resyncClient := &http.Client{Timeout: *alertsResyncWait}
+	for _, u := range resyncURLs.slice() {
+		as, err := ingest.FetchPrometheus(resyncClient, u)
+		if err == nil {
+			err = apiv.PutAlerts(as...)
+		}
+		if err != nil {
+			log.With("url", u).With("err", err).Warn("Loading firing alerts from Prometheus failed")
+			continue
+		}
+		log.With("url", u).Infof("Loaded %d firing alerts from Prometheus", len(as))
+	}
+	if *replicaSource != "" {
+		wg.Add(1)
+		go func() {
+			mirrorAlerts(*replicaSource, *replicaInterval, apiv.PutAlerts, stopc)
+			wg.Done()
+		}()
+	}
+
+	// Handlers find the authenticated user in the request context.
+	router := route.New(func(r *http.Request) (context.Context, error) {
+		return r.Context(), nil
+	})
 
 	webReload := make(chan struct{})
-	ui.Register(router.WithPrefix(amURL.Path), webReload)
+	ui.Register(router.WithPrefix(amURL.Path), webReload, func() error {
+		select {
+		case <-configLoaded:
+		default:
+			return fmt.Errorf("configuration not loaded")
+		}
+		select {
+		case <-settled:
+		default:
+			return fmt.Errorf("initial state replication with peers in progress")
+		}
+		return nil
+	})
 	apiv.Register(router.WithPrefix(path.Join(amURL.Path, "/api")))
+	if *enableGraphQL {
+		apiv.RegisterGraphQL(router.WithPrefix(path.Join(amURL.Path, "/api")))
+	}
 
 	log.Infoln("Listening on", *listenAddress)
-	go listen(*listenAddress, router)
+	// Queries sent as POST requests are not recorded in the audit log.
+	unaudited := []string{
+		path.Join(amURL.Path, "/api/graphql"),
+		path.Join(amURL.Path, "/api/v1/templates/render"),
+	}
+	if *auditSkipAlerts {
+		unaudited = append(unaudited,
+			path.Join(amURL.Path, "/api/alerts"),
+			path.Join(amURL.Path, "/api/v1/alerts"),
+			path.Join(amURL.Path, "/api/v1/alerts/bulk"),
+			path.Join(amURL.Path, "/api/v1/ingest")+"/",
+			path.Join(amURL.Path, "/api/v2/alerts"),
+			path.Join(amURL.Path, "/api/v2/alerts/bulk"),
+		)
+	}
+	audited := auditLog.Handler(router, unaudited...)
+	go listen(*listenAddress, authenticator.Handler(audited))
 
 	var (
 		hup      = make(chan os.Signal)
@@ -280,31 +1162,153 @@ func main() {
 	log.Infoln("Received SIGTERM, exiting gracefully...")
 }
 
-type peerDescSlice []mesh.PeerDescription
+// mirrorAlerts puts the alerts of the instance at the URL at the interval
+// until the stop channel is closed.
+func mirrorAlerts(u string, interval time.Duration, put func(...*types.Alert) error, stopc <-chan struct{}) {
+	var (
+		client = &http.Client{Timeout: interval}
+		tick   = time.NewTicker(interval)
+	)
+	defer tick.Stop()
 
-func (s peerDescSlice) Len() int           { return len(s) }
-func (s peerDescSlice) Less(i, j int) bool { return s[i].UID < s[j].UID }
-func (s peerDescSlice) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+	for {
+		as, err := ingest.FetchAlertmanager(client, u)
+		if err == nil {
+			err = put(as...)
+		}
+		if err != nil {
+			log.With("url", u).With("err", err).Warn("Mirroring alerts failed")
+		}
+		select {
+		case <-stopc:
+			return
+		case <-tick.C:
+		}
+	}
+}
 
 // meshWait returns a function that inspects the current peer state and returns
-// a duration of one base timeout for each peer with a higher ID than ourselves.
-func meshWait(r *mesh.Router, timeout time.Duration) func() time.Duration {
+// a duration of one base timeout for each peer ahead of ourselves, plus one
+// region timeout for each region ahead of our own. Peers are ordered by
+// region and then by ID. As the notification log is gossiped during the
+// wait, only the first peer sends a notification unless it fails to do so
+// in time.
+func meshWait(r *mesh.Router, region func(mesh.PeerName) string, timeout, regionTimeout time.Duration) func() time.Duration {
 	return func() time.Duration {
-		var peers peerDescSlice
-		for _, desc := range r.Peers.Descriptions() {
-			peers = append(peers, desc)
+		peers := r.Peers.Descriptions()
+		regions := make(map[mesh.PeerName]string, len(peers))
+		for _, desc := range peers {
+			regions[desc.Name] = region(desc.Name)
 		}
-		sort.Sort(peers)
+		sort.Slice(peers, func(i, j int) bool {
+			if ri, rj := regions[peers[i].Name], regions[peers[j].Name]; ri != rj {
+				return ri < rj
+			}
+			return peers[i].UID < peers[j].UID
+		})
 
-		k := 0
+		var (
+			k     = 0
+			ahead = map[string]struct{}{}
+		)
 		for _, desc := range peers {
 			if desc.Self {
+				delete(ahead, regions[desc.Name])
 				break
 			}
+			ahead[regions[desc.Name]] = struct{}{}
 			k++
 		}
-		// TODO(fabxc): add metric exposing the "position" from AM's own view.
-		return time.Duration(k) * timeout
+		peerPosition.Set(float64(k))
+		return time.Duration(k)*timeout + time.Duration(len(ahead))*regionTimeout
+	}
+}
+
+// meshShard returns a function that returns whether this instance is among
+// the n owners of a group key and a duration of one base timeout for each
+// owner ahead of ourselves. Owners are chosen by rendezvous hashing over the
+// current peers, so peers joining or leaving only move the groups they own.
+func meshShard(r *mesh.Router, n int, timeout time.Duration) func(model.Fingerprint) (time.Duration, bool) {
+	return func(gkey model.Fingerprint) (time.Duration, bool) {
+		var (
+			peers = r.Peers.Descriptions()
+			names = make([]mesh.PeerName, 0, len(peers))
+			self  mesh.PeerName
+		)
+		for _, desc := range peers {
+			names = append(names, desc.Name)
+			if desc.Self {
+				self = desc.Name
+			}
+		}
+		for k, owner := range cluster.Owners(uint64(gkey), names, n) {
+			if owner == self {
+				return time.Duration(k) * timeout, true
+			}
+		}
+		return 0, false
+	}
+}
+
+// meshPeers returns a function listing the names of all known peers other
+// than ourselves.
+func meshPeers(r *mesh.Router) func() []mesh.PeerName {
+	return func() []mesh.PeerName {
+		var names []mesh.PeerName
+		for _, desc := range r.Peers.Descriptions() {
+			if !desc.Self {
+				names = append(names, desc.Name)
+			}
+		}
+		return names
+	}
+}
+
+// meshSettle closes the settled channel once the connections to the initial
+// peers are established and stable, which is when the state of all gossip
+// channels has been exchanged with them. The mesh is considered stable if
+// the number of established connections did not change for three checks in
+// a row. Without initial peers, it is settled right away. After the timeout
+// it is considered settled regardless.
+func meshSettle(r *mesh.Router, numPeers int, interval, timeout time.Duration, settled chan<- struct{}) {
+	defer close(settled)
+
+	if numPeers == 0 {
+		return
+	}
+	var (
+		tick     = time.NewTicker(interval)
+		timeoutc = time.After(timeout)
+		last     int
+		stable   int
+	)
+	defer tick.Stop()
+
+	for {
+		select {
+		case <-tick.C:
+		case <-timeoutc:
+			log.Warnf("Mesh did not settle within %s, reporting readiness anyway", timeout)
+			return
+		}
+
+		n := 0
+		for _, c := range mesh.NewStatus(r).Connections {
+			if c.State == "established" {
+				n++
+			}
+		}
+		if n > 0 && n == last {
+			stable++
+		} else {
+			stable = 0
+		}
+		last = n
+
+		if stable >= 3 {
+			log.With("connections", n).Infof("Mesh settled")
+			return
+		}
 	}
 }
 
@@ -371,8 +1375,8 @@ func extURL(listen, external string) (*url.URL, error) {
 	return u, nil
 }
 
-func listen(listen string, router *route.Router) {
-	if err := http.ListenAndServe(listen, router); err != nil {
+func listen(listen string, h http.Handler) {
+	if err := http.ListenAndServe(listen, h); err != nil {
 		log.Fatal(err)
 	}
 }
@@ -0,0 +1,90 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"golang.org/x/net/context"
+
+	"github.com/prometheus/alertmanager/api/client"
+)
+
+const alertUsage = `Usage: amtool alert snooze [flags] <fingerprint>
+
+Snoozes a single alert, identified by its fingerprint, for -duration. The
+alert is not notified about while the snooze lasts, but its group keeps
+notifying about its other alerts.
+
+Flags:
+`
+
+func runAlert(args []string) error {
+	if len(args) == 0 || args[0] != "snooze" {
+		fmt.Fprint(os.Stderr, alertUsage)
+		return fmt.Errorf("unknown or missing alert command")
+	}
+
+	var (
+		fs       = flag.NewFlagSet("alert snooze", flag.ExitOnError)
+		amURL    = fs.String("alertmanager.url", "http://localhost:9093", "URL of the Alertmanager holding the alert.")
+		apiKey   = fs.String("api-key", "", "API key sent as bearer token.")
+		duration = fs.Duration("duration", time.Hour, "Duration the alert is snoozed for.")
+		comment  = fs.String("comment", "", "Comment recorded with the snooze.")
+	)
+	fs.Usage = func() {
+		fmt.Fprint(os.Stderr, alertUsage)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args[1:])
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("exactly one fingerprint must be given")
+	}
+	fp, err := model.ParseFingerprint(fs.Arg(0))
+	if err != nil {
+		return fmt.Errorf("invalid fingerprint %q: %s", fs.Arg(0), err)
+	}
+	if *duration <= 0 {
+		return fmt.Errorf("-duration must be positive")
+	}
+
+	hc := &http.Client{Timeout: 30 * time.Second}
+	if *apiKey != "" {
+		hc.Transport = &bearerTransport{token: *apiKey, next: http.DefaultTransport}
+	}
+	c, err := client.New(*amURL, hc)
+	if err != nil {
+		return err
+	}
+	return snoozeAlert(context.Background(), c, os.Stdout, fp, *duration, *comment)
+}
+
+// snoozeAlert snoozes the alert for the duration d and writes the end of
+// the snooze.
+func snoozeAlert(ctx context.Context, c *client.Client, w io.Writer, fp model.Fingerprint, d time.Duration, comment string) error {
+	sn, err := c.Snooze(ctx, fp, time.Time{}, d, comment)
+	if err != nil {
+		return fmt.Errorf("snoozing alert %s: %s", fp, err)
+	}
+	_, err = fmt.Fprintf(w, "%s\t%s\n", sn.Fingerprint, sn.EndsAt.Format(time.RFC3339))
+	return err
+}
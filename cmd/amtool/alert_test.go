@@ -0,0 +1,60 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+
+	"github.com/prometheus/alertmanager/api/client"
+)
+
+func TestSnoozeAlert(t *testing.T) {
+	var req map[string]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "POST", r.Method)
+		require.Equal(t, "/api/v2/snoozes", r.URL.Path)
+		req = nil
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+
+		if req["fingerprint"] != "000000000000002a" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"errorType":"bad_data","error":"alert 0000000000000001: alert not found"}`))
+			return
+		}
+		w.Write([]byte(`{"fingerprint":"000000000000002a","endsAt":"2016-01-01T14:00:00Z"}`))
+	}))
+	defer srv.Close()
+
+	c, err := client.New(srv.URL, nil)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	var buf bytes.Buffer
+	require.NoError(t, snoozeAlert(ctx, c, &buf, model.Fingerprint(42), 2*time.Hour, "known issue"))
+	require.Equal(t, map[string]string{"fingerprint": "000000000000002a", "duration": "2h0m0s", "comment": "known issue"}, req)
+	require.Equal(t, "000000000000002a\t2016-01-01T14:00:00Z\n", buf.String())
+
+	err = snoozeAlert(ctx, c, &buf, model.Fingerprint(1), time.Hour, "")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "alert not found")
+}
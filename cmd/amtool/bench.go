@@ -0,0 +1,306 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/common/model"
+)
+
+// benchOptions configures the synthetic load.
+type benchOptions struct {
+	url         string
+	apiKey      string
+	alerts      int
+	groups      int
+	rate        float64
+	batch       int
+	concurrency int
+	duration    time.Duration
+	churn       float64
+	resolve     float64
+	webhook     string
+	wait        time.Duration
+}
+
+// benchAlert is an alert of the generated set. Its generation changes with
+// label churn, which replaces the alert by one with a new instance label.
+type benchAlert struct {
+	idx int
+	gen int
+}
+
+func (a benchAlert) labels(o *benchOptions) model.LabelSet {
+	return model.LabelSet{
+		"alertname": model.LabelValue(fmt.Sprintf("BenchAlert%d", a.idx%10)),
+		"group":     model.LabelValue(fmt.Sprintf("group-%d", a.idx%o.groups)),
+		"instance":  model.LabelValue(fmt.Sprintf("host-%d-%d", a.idx, a.gen)),
+		"severity":  []model.LabelValue{"info", "warning", "critical"}[a.idx%3],
+		"bench":     "true",
+	}
+}
+
+// benchBatch is an encoded request of n alerts.
+type benchBatch struct {
+	body []byte
+	n    int
+}
+
+// benchResult collects the measurements of a run.
+type benchResult struct {
+	mtx       sync.Mutex
+	latencies []time.Duration
+
+	requests      int64
+	failures      int64
+	alertsSent    int64
+	notifications int64
+	notified      int64
+}
+
+func (r *benchResult) observe(d time.Duration, n int, err error) {
+	atomic.AddInt64(&r.requests, 1)
+	if err != nil {
+		atomic.AddInt64(&r.failures, 1)
+		return
+	}
+	atomic.AddInt64(&r.alertsSent, int64(n))
+
+	r.mtx.Lock()
+	r.latencies = append(r.latencies, d)
+	r.mtx.Unlock()
+}
+
+func runBench(args []string) error {
+	var o benchOptions
+
+	fs := flag.NewFlagSet("bench", flag.ExitOnError)
+	fs.StringVar(&o.url, "alertmanager.url", "http://localhost:9093", "URL of the Alertmanager to push alerts to.")
+	fs.StringVar(&o.apiKey, "api-key", "", "API key sent as bearer token.")
+	fs.IntVar(&o.alerts, "alerts", 1000, "Number of distinct alerts firing at once.")
+	fs.IntVar(&o.groups, "groups", 100, "Number of distinct values of the group label, which bounds the number of aggregation groups if the route groups by it.")
+	fs.Float64Var(&o.rate, "rate", 500, "Alerts pushed per second.")
+	fs.IntVar(&o.batch, "batch", 50, "Alerts pushed per request.")
+	fs.IntVar(&o.concurrency, "concurrency", 4, "Maximum number of requests in flight.")
+	fs.DurationVar(&o.duration, "duration", time.Minute, "How long to push alerts for.")
+	fs.Float64Var(&o.churn, "churn", 0.05, "Fraction of pushed alerts replaced by an alert with a new instance label, simulating targets coming and going.")
+	fs.Float64Var(&o.resolve, "resolve", 0.5, "Fraction of replaced alerts that are resolved explicitly rather than left to time out.")
+	fs.StringVar(&o.webhook, "webhook.listen-address", "", "Address on which notifications of a webhook receiver pointed at amtool are counted, e.g. :9099. Notifications are not measured if empty.")
+	fs.DurationVar(&o.wait, "webhook.wait", 30*time.Second, "Time to keep counting notifications after pushing stopped.")
+	fs.Parse(args)
+
+	if o.alerts < 1 || o.groups < 1 || o.batch < 1 || o.concurrency < 1 || o.rate <= 0 {
+		return fmt.Errorf("alerts, groups, batch, concurrency and rate must be positive")
+	}
+
+	res := &benchResult{}
+	client := &http.Client{Timeout: 30 * time.Second}
+
+	if o.webhook != "" {
+		l, err := net.Listen("tcp", o.webhook)
+		if err != nil {
+			return err
+		}
+		defer l.Close()
+		go http.Serve(l, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var msg struct {
+				Alerts []json.RawMessage `json:"alerts"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			atomic.AddInt64(&res.notifications, 1)
+			atomic.AddInt64(&res.notified, int64(len(msg.Alerts)))
+		}))
+	}
+
+	fmt.Fprintf(os.Stderr, "Pushing %d alerts/s in batches of %d to %s for %s\n", int(o.rate), o.batch, o.url, o.duration)
+
+	var (
+		batches = make(chan benchBatch, o.concurrency)
+		wg      sync.WaitGroup
+	)
+	for i := 0; i < o.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for b := range batches {
+				start := time.Now()
+				err := pushAlerts(client, &o, b.body)
+				res.observe(time.Since(start), b.n, err)
+			}
+		}()
+	}
+
+	start := time.Now()
+	generate(&o, batches)
+	close(batches)
+	wg.Wait()
+	pushed := time.Since(start)
+
+	if o.webhook != "" {
+		time.Sleep(o.wait)
+	}
+	groups, err := countGroups(client, &o)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Counting alert groups failed: %s\n", err)
+	}
+
+	report(os.Stdout, res, pushed, time.Since(start), groups, &o)
+	return nil
+}
+
+// generate sends batches of encoded alerts at the configured rate until the
+// duration passed.
+func generate(o *benchOptions, batches chan<- benchBatch) {
+	var (
+		alerts   = make([]benchAlert, o.alerts)
+		next     = 0
+		interval = time.Duration(float64(time.Second) * float64(o.batch) / o.rate)
+		tick     = time.NewTicker(interval)
+		deadline = time.After(o.duration)
+	)
+	defer tick.Stop()
+
+	for i := range alerts {
+		alerts[i].idx = i
+	}
+	for {
+		select {
+		case <-deadline:
+			return
+		case <-tick.C:
+		}
+		now := time.Now()
+
+		var batch []*model.Alert
+		for len(batch) < o.batch {
+			a := &alerts[next]
+			next = (next + 1) % len(alerts)
+
+			if rand.Float64() < o.churn {
+				if rand.Float64() < o.resolve {
+					batch = append(batch, &model.Alert{
+						Labels:   a.labels(o),
+						StartsAt: now,
+						EndsAt:   now,
+					})
+				}
+				a.gen++
+			}
+			batch = append(batch, &model.Alert{
+				Labels: a.labels(o),
+				Annotations: model.LabelSet{
+					"summary": model.LabelValue(fmt.Sprintf("Synthetic alert %d", a.idx)),
+				},
+			})
+		}
+		b, err := json.Marshal(batch)
+		if err != nil {
+			panic(err)
+		}
+		// Block rather than drop batches if the Alertmanager does not keep
+		// up, so the achieved rate shows in the report.
+		batches <- benchBatch{body: b, n: len(batch)}
+	}
+}
+
+func pushAlerts(client *http.Client, o *benchOptions, body []byte) error {
+	req, err := http.NewRequest("POST", strings.TrimRight(o.url, "/")+"/api/v1/alerts", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if o.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+o.apiKey)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// countGroups returns the number of alert groups of the Alertmanager.
+func countGroups(client *http.Client, o *benchOptions) (int, error) {
+	req, err := http.NewRequest("GET", strings.TrimRight(o.url, "/")+"/api/v1/alerts/groups", nil)
+	if err != nil {
+		return 0, err
+	}
+	if o.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+o.apiKey)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	var res struct {
+		Data []json.RawMessage `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return 0, err
+	}
+	return len(res.Data), nil
+}
+
+// percentile returns the p-th percentile of the sorted durations.
+func percentile(ds []time.Duration, p float64) time.Duration {
+	if len(ds) == 0 {
+		return 0
+	}
+	i := int(float64(len(ds)-1) * p)
+	return ds[i]
+}
+
+func report(w io.Writer, res *benchResult, pushed, total time.Duration, groups int, o *benchOptions) {
+	sort.Slice(res.latencies, func(i, j int) bool { return res.latencies[i] < res.latencies[j] })
+	lat := res.latencies
+
+	fmt.Fprintf(w, "Requests:           %d (%d failed)\n", res.requests, res.failures)
+	fmt.Fprintf(w, "Alerts pushed:      %d (%.1f/s)\n", res.alertsSent, float64(res.alertsSent)/pushed.Seconds())
+	fmt.Fprintf(w, "Ingestion latency:  p50=%s p90=%s p99=%s max=%s\n",
+		percentile(lat, 0.5), percentile(lat, 0.9), percentile(lat, 0.99), percentile(lat, 1),
+	)
+	fmt.Fprintf(w, "Alert groups:       %d\n", groups)
+	if o.webhook != "" {
+		notifications := atomic.LoadInt64(&res.notifications)
+		fmt.Fprintf(w, "Notifications:      %d (%.1f/s) with %d alerts\n",
+			notifications, float64(notifications)/total.Seconds(), atomic.LoadInt64(&res.notified),
+		)
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// amtool is a command line tool for operating the Alertmanager.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/prometheus/common/version"
+)
+
+// command is a subcommand of amtool, which parses its own flags.
+type command struct {
+	help string
+	run  func(args []string) error
+}
+
+var commands = map[string]command{
+	"alert": {
+		help: "Snooze a single alert of an Alertmanager: amtool alert snooze.",
+		run:  runAlert,
+	},
+	"bench": {
+		help: "Push synthetic alerts to an Alertmanager and report its ingestion latency and notification throughput.",
+		run:  runBench,
+	},
+	"receiver": {
+		help: "Send a test notification via a receiver of an Alertmanager: amtool receiver test.",
+		run:  runReceiver,
+	},
+	"silence": {
+		help: "Query or extend the silences of an Alertmanager: amtool silence query|extend.",
+		run:  runSilence,
+	},
+	"template": {
+		help: "Render a template with sample alerts or the alerts of an Alertmanager: amtool template render.",
+		run:  runTemplate,
+	},
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s [flags] <command> [command flags]\n\nCommands:\n", os.Args[0])
+
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", name, commands[name].help)
+	}
+	fmt.Fprintf(os.Stderr, "\nFlags:\n")
+	flag.PrintDefaults()
+}
+
+func main() {
+	showVersion := flag.Bool("version", false, "Print version information.")
+	flag.Usage = usage
+	flag.Parse()
+
+	if *showVersion {
+		fmt.Fprintln(os.Stdout, version.Print("amtool"))
+		os.Exit(0)
+	}
+	if flag.NArg() == 0 {
+		usage()
+		os.Exit(2)
+	}
+	cmd, ok := commands[flag.Arg(0)]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "amtool: unknown command %q\n", flag.Arg(0))
+		usage()
+		os.Exit(2)
+	}
+	if err := cmd.run(flag.Args()[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "amtool: %s\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,142 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"golang.org/x/net/context"
+
+	"github.com/prometheus/alertmanager/api/client"
+)
+
+const receiverUsage = `Usage: amtool receiver test [flags] <receiver>
+
+Sends a test notification with a synthetic alert via every integration of
+a receiver and reports the outcome per integration, e.g. to verify
+credentials after a configuration change. It fails if any integration
+failed.
+
+Flags:
+`
+
+// labelSetFlag is a flag of labels given as name=value, which may be
+// repeated.
+type labelSetFlag model.LabelSet
+
+func (f labelSetFlag) String() string {
+	return model.LabelSet(f).String()
+}
+
+func (f labelSetFlag) Set(s string) error {
+	i := strings.Index(s, "=")
+	if i <= 0 {
+		return fmt.Errorf("invalid label %q, expected name=value", s)
+	}
+	ln := model.LabelName(s[:i])
+	if !ln.IsValid() {
+		return fmt.Errorf("invalid label name %q", ln)
+	}
+	f[ln] = model.LabelValue(s[i+1:])
+	return nil
+}
+
+// bearerTransport sends an API key as bearer token with every request.
+type bearerTransport struct {
+	token string
+	next  http.RoundTripper
+}
+
+func (t *bearerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	r := *req
+	r.Header = make(http.Header, len(req.Header)+1)
+	for k, v := range req.Header {
+		r.Header[k] = v
+	}
+	r.Header.Set("Authorization", "Bearer "+t.token)
+	return t.next.RoundTrip(&r)
+}
+
+func runReceiver(args []string) error {
+	if len(args) == 0 || args[0] != "test" {
+		fmt.Fprint(os.Stderr, receiverUsage)
+		return fmt.Errorf("unknown or missing receiver command")
+	}
+
+	var (
+		labels      = labelSetFlag{}
+		annotations = labelSetFlag{}
+		fs          = flag.NewFlagSet("receiver test", flag.ExitOnError)
+		amURL       = fs.String("alertmanager.url", "http://localhost:9093", "URL of the Alertmanager sending the notification.")
+		apiKey      = fs.String("api-key", "", "API key sent as bearer token.")
+		timeout     = fs.Duration("timeout", 0, "Time the Alertmanager retries failed notifications for. 0 uses its default.")
+	)
+	fs.Var(labels, "label", "Label name=value added to the test alert (may be repeated).")
+	fs.Var(annotations, "annotation", "Annotation name=value added to the test alert (may be repeated).")
+	fs.Usage = func() {
+		fmt.Fprint(os.Stderr, receiverUsage)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args[1:])
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("exactly one receiver must be given")
+	}
+
+	hc := &http.Client{}
+	if *apiKey != "" {
+		hc.Transport = &bearerTransport{token: *apiKey, next: http.DefaultTransport}
+	}
+	c, err := client.New(*amURL, hc)
+	if err != nil {
+		return err
+	}
+	return testReceiver(context.Background(), c, os.Stdout, fs.Arg(0), model.LabelSet(labels), model.LabelSet(annotations), *timeout)
+}
+
+// testReceiver sends a test notification via the receiver and writes the
+// outcome per integration.
+func testReceiver(ctx context.Context, c *client.Client, w io.Writer, name string, labels, annotations model.LabelSet, timeout time.Duration) error {
+	res, err := c.TestReceiver(ctx, name, labels, annotations, timeout)
+	if err != nil {
+		return fmt.Errorf("testing receiver %q: %s", name, err)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	failed := 0
+	for _, i := range res.Integrations {
+		outcome := "ok"
+		if !i.Success {
+			outcome = "failed: " + i.Error
+			failed++
+		}
+		fmt.Fprintf(tw, "%s[%d]\t%s\n", i.Type, i.Index, outcome)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d integrations of receiver %q failed", failed, len(res.Integrations), name)
+	}
+	return nil
+}
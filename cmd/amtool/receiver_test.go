@@ -0,0 +1,87 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+
+	"github.com/prometheus/alertmanager/api/client"
+)
+
+func TestTestReceiver(t *testing.T) {
+	var (
+		auth string
+		req  struct {
+			Labels model.LabelSet `json:"labels"`
+		}
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+
+		switch r.URL.Path {
+		case "/api/v2/receivers/team/test":
+			require.Equal(t, "timeout=30s", r.URL.RawQuery)
+			w.Write([]byte(`{"receiver":"team","integrations":[
+				{"type":"slack","index":0,"success":true},
+				{"type":"webhook","index":1,"success":false,"error":"unexpected status code 401"}
+			]}`))
+		case "/api/v2/receivers/ok/test":
+			w.Write([]byte(`{"receiver":"ok","integrations":[{"type":"email","index":0,"success":true}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":"receiver not found"}`))
+		}
+	}))
+	defer srv.Close()
+
+	c, err := client.New(srv.URL, &http.Client{
+		Transport: &bearerTransport{token: "secret", next: http.DefaultTransport},
+	})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	labels := labelSetFlag{}
+	require.NoError(t, labels.Set("severity=critical"))
+	require.Error(t, labels.Set("severity"))
+	require.Error(t, labels.Set("1x=y"))
+
+	var buf bytes.Buffer
+	err = testReceiver(ctx, c, &buf, "team", model.LabelSet(labels), nil, 30*time.Second)
+	require.EqualError(t, err, `1 of 2 integrations of receiver "team" failed`)
+	require.Equal(t, "Bearer secret", auth)
+	require.Equal(t, model.LabelSet{"severity": "critical"}, req.Labels)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Equal(t, []string{"slack[0]", "ok"}, strings.Fields(lines[0]))
+	require.Equal(t, "webhook[1]  failed: unexpected status code 401", lines[1])
+
+	buf.Reset()
+	require.NoError(t, testReceiver(ctx, c, &buf, "ok", nil, nil, 0))
+	require.Equal(t, "email[0]  ok\n", buf.String())
+
+	err = testReceiver(ctx, c, &buf, "unknown", nil, nil, 0)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "receiver not found")
+}
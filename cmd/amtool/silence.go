@@ -0,0 +1,372 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"golang.org/x/net/context"
+	"gopkg.in/yaml.v2"
+
+	"github.com/prometheus/alertmanager/api/client"
+	"github.com/prometheus/alertmanager/types"
+)
+
+const silenceUsage = `Usage: amtool silence query [flags] [matchers...]
+       amtool silence extend [flags] <id>
+
+Run amtool silence <command> -help for the flags of a command.
+`
+
+const silenceQueryUsage = `Usage: amtool silence query [flags] [matchers...]
+
+Lists the active and pending silences of an Alertmanager, or the expired
+ones with -expired. Matchers select silences by their matchers: name=value
+selects silences matching alerts whose label has the value, name=~regex
+selects silences with a matcher for the label whose value or pattern
+matches the regular expression.
+
+Flags:
+`
+
+const silenceExtendUsage = `Usage: amtool silence extend [flags] <id>
+
+Moves the end of a silence by -duration or to -ends-at in place, so that
+its ID and history are kept.
+
+Flags:
+`
+
+// silenceColumns are the columns of the silence table by name.
+var silenceColumns = map[string]func(s *types.Silence, now time.Time) string{
+	"id":          func(s *types.Silence, _ time.Time) string { return s.ID },
+	"matchers":    func(s *types.Silence, _ time.Time) string { return matchersString(s.Matchers) },
+	"startsAt":    func(s *types.Silence, _ time.Time) string { return s.StartsAt.Format(time.RFC3339) },
+	"endsAt":      func(s *types.Silence, _ time.Time) string { return s.EndsAt.Format(time.RFC3339) },
+	"createdBy":   func(s *types.Silence, _ time.Time) string { return s.CreatedBy },
+	"comment":     func(s *types.Silence, _ time.Time) string { return s.Comment },
+	"state":       silenceState,
+	"mutedAlerts": func(s *types.Silence, _ time.Time) string { return strconv.Itoa(s.MutedAlerts) },
+}
+
+// silenceQuery holds the options of a silence query.
+type silenceQuery struct {
+	expired  bool
+	within   time.Duration
+	matchers []*silenceMatcher
+}
+
+// params returns the filter parameters of the silences API selecting the
+// silences of the query, apart from its matchers.
+func (q *silenceQuery) params(now time.Time) url.Values {
+	v := url.Values{}
+	if q.expired {
+		v["state"] = []string{"expired"}
+		if q.within > 0 {
+			v.Set("endsAfter", now.Add(-q.within).Format(time.RFC3339))
+		}
+	} else {
+		v["state"] = []string{"active", "pending"}
+		if q.within > 0 {
+			v.Set("endsBefore", now.Add(q.within).Format(time.RFC3339))
+		}
+	}
+	return v
+}
+
+// filter returns the silences selected by all matchers of the query.
+func (q *silenceQuery) filter(sils []*types.Silence) []*types.Silence {
+	var res []*types.Silence
+	for _, s := range sils {
+		ok := true
+		for _, m := range q.matchers {
+			if !m.selects(s) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			res = append(res, s)
+		}
+	}
+	return res
+}
+
+// silenceMatcher selects silences by one of their matchers.
+type silenceMatcher struct {
+	name  string
+	value string
+	re    *regexp.Regexp
+}
+
+// parseSilenceMatcher parses a matcher of the form name=value or
+// name=~regex.
+func parseSilenceMatcher(s string) (*silenceMatcher, error) {
+	i := strings.Index(s, "=")
+	if i <= 0 {
+		return nil, fmt.Errorf("invalid matcher %q", s)
+	}
+	m := &silenceMatcher{name: s[:i], value: s[i+1:]}
+	if strings.HasPrefix(m.value, "~") {
+		re, err := regexp.Compile("^(?:" + m.value[1:] + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid matcher %q: %s", s, err)
+		}
+		m.re = re
+	}
+	return m, nil
+}
+
+// selects returns whether the silence has a matcher for the label that
+// matches the value, or whose value or pattern matches the regex.
+func (m *silenceMatcher) selects(s *types.Silence) bool {
+	for _, sm := range s.Matchers {
+		if sm.Name != m.name {
+			continue
+		}
+		switch {
+		case m.re != nil:
+			if m.re.MatchString(sm.Value) {
+				return true
+			}
+		case sm.IsRegex:
+			re, err := regexp.Compile("^(?:" + sm.Value + ")$")
+			if err == nil && re.MatchString(m.value) {
+				return true
+			}
+		case sm.Value == m.value:
+			return true
+		}
+	}
+	return false
+}
+
+func runSilence(args []string) error {
+	if len(args) > 0 {
+		switch args[0] {
+		case "query":
+			return runSilenceQuery(args[1:])
+		case "extend":
+			return runSilenceExtend(args[1:])
+		}
+	}
+	fmt.Fprint(os.Stderr, silenceUsage)
+	return fmt.Errorf("unknown or missing silence command")
+}
+
+func runSilenceQuery(args []string) error {
+	var (
+		q       silenceQuery
+		fs      = flag.NewFlagSet("silence query", flag.ExitOnError)
+		amURL   = fs.String("alertmanager.url", "http://localhost:9093", "URL of the Alertmanager to query.")
+		output  = fs.String("output", "table", "Output format, one of table, json and yaml.")
+		columns = fs.String("columns", "id,matchers,endsAt,createdBy,comment", "Comma-separated columns of the table, out of id, matchers, startsAt, endsAt, createdBy, comment, state and mutedAlerts.")
+		quiet   = fs.Bool("quiet", false, "Only print the IDs of the silences.")
+	)
+	fs.BoolVar(&q.expired, "expired", false, "List expired silences instead of active and pending ones.")
+	fs.DurationVar(&q.within, "within", 0, "Only list silences that expire within the duration, or with -expired, that expired within it.")
+	fs.Usage = func() {
+		fmt.Fprint(os.Stderr, silenceQueryUsage)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	for _, s := range fs.Args() {
+		m, err := parseSilenceMatcher(s)
+		if err != nil {
+			return err
+		}
+		q.matchers = append(q.matchers, m)
+	}
+	switch *output {
+	case "table", "json", "yaml":
+	default:
+		return fmt.Errorf("unknown output format %q", *output)
+	}
+	cols, err := parseSilenceColumns(*columns)
+	if err != nil {
+		return err
+	}
+	if *quiet {
+		cols = []string{"id"}
+	}
+
+	c, err := client.New(*amURL, &http.Client{Timeout: 30 * time.Second})
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	sils, err := c.Silences(context.Background(), q.params(now))
+	if err != nil {
+		return fmt.Errorf("querying silences: %s", err)
+	}
+	sils = q.filter(sils)
+
+	switch {
+	case *quiet:
+		return writeSilenceTable(os.Stdout, sils, cols, now, false)
+	case *output == "table":
+		return writeSilenceTable(os.Stdout, sils, cols, now, true)
+	default:
+		return writeSilences(os.Stdout, sils, *output)
+	}
+}
+
+func runSilenceExtend(args []string) error {
+	var (
+		fs       = flag.NewFlagSet("silence extend", flag.ExitOnError)
+		amURL    = fs.String("alertmanager.url", "http://localhost:9093", "URL of the Alertmanager holding the silence.")
+		apiKey   = fs.String("api-key", "", "API key sent as bearer token.")
+		duration = fs.Duration("duration", 0, "Duration the end of the silence is moved by.")
+		endsAt   = fs.String("ends-at", "", "New end of the silence in the RFC 3339 format.")
+		comment  = fs.String("comment", "", "Comment recorded with the extension.")
+	)
+	fs.Usage = func() {
+		fmt.Fprint(os.Stderr, silenceExtendUsage)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("exactly one silence ID must be given")
+	}
+	if (*duration == 0) == (*endsAt == "") {
+		return fmt.Errorf("exactly one of -duration and -ends-at must be given")
+	}
+	var end time.Time
+	if *endsAt != "" {
+		var err error
+		if end, err = time.Parse(time.RFC3339, *endsAt); err != nil {
+			return fmt.Errorf("invalid -ends-at: %s", err)
+		}
+	}
+
+	hc := &http.Client{Timeout: 30 * time.Second}
+	if *apiKey != "" {
+		hc.Transport = &bearerTransport{token: *apiKey, next: http.DefaultTransport}
+	}
+	c, err := client.New(*amURL, hc)
+	if err != nil {
+		return err
+	}
+	return extendSilence(context.Background(), c, os.Stdout, fs.Arg(0), end, *duration, *comment)
+}
+
+// extendSilence extends the silence to endsAt, or if it is zero, by the
+// duration d, and writes its new end.
+func extendSilence(ctx context.Context, c *client.Client, w io.Writer, id string, endsAt time.Time, d time.Duration, comment string) error {
+	s, err := c.ExtendSilence(ctx, id, endsAt, d, comment)
+	if err != nil {
+		return fmt.Errorf("extending silence %s: %s", id, err)
+	}
+	_, err = fmt.Fprintf(w, "%s\t%s\n", s.ID, s.EndsAt.Format(time.RFC3339))
+	return err
+}
+
+// parseSilenceColumns parses a comma-separated list of table columns.
+func parseSilenceColumns(s string) ([]string, error) {
+	var cols []string
+	for _, c := range strings.Split(s, ",") {
+		c = strings.TrimSpace(c)
+		if _, ok := silenceColumns[c]; !ok {
+			return nil, fmt.Errorf("unknown column %q", c)
+		}
+		cols = append(cols, c)
+	}
+	return cols, nil
+}
+
+// writeSilenceTable writes the columns of the silences as a table, which
+// starts with a header unless it is disabled.
+func writeSilenceTable(w io.Writer, sils []*types.Silence, cols []string, now time.Time, header bool) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	if header {
+		names := make([]string, len(cols))
+		for i, c := range cols {
+			names[i] = strings.ToUpper(c)
+		}
+		fmt.Fprintln(tw, strings.Join(names, "\t"))
+	}
+	for _, s := range sils {
+		vals := make([]string, len(cols))
+		for i, c := range cols {
+			vals[i] = silenceColumns[c](s, now)
+		}
+		fmt.Fprintln(tw, strings.Join(vals, "\t"))
+	}
+	return tw.Flush()
+}
+
+// writeSilences writes the silences in the JSON or YAML format.
+func writeSilences(w io.Writer, sils []*types.Silence, format string) error {
+	if sils == nil {
+		sils = []*types.Silence{}
+	}
+	b, err := json.MarshalIndent(sils, "", "  ")
+	if err != nil {
+		return err
+	}
+	switch format {
+	case "json":
+		_, err = fmt.Fprintln(w, string(b))
+		return err
+	case "yaml":
+		// Decoding the JSON encoding keeps the field names of the API.
+		var v interface{}
+		if err := json.Unmarshal(b, &v); err != nil {
+			return err
+		}
+		if b, err = yaml.Marshal(v); err != nil {
+			return err
+		}
+		_, err = w.Write(b)
+		return err
+	}
+	return fmt.Errorf("unknown output format %q", format)
+}
+
+// matchersString returns the matchers in the form {name="value",...}.
+func matchersString(ms types.Matchers) string {
+	s := make([]string, len(ms))
+	for i, m := range ms {
+		op := "="
+		if m.IsRegex {
+			op = "=~"
+		}
+		s[i] = m.Name + op + strconv.Quote(m.Value)
+	}
+	return "{" + strings.Join(s, ",") + "}"
+}
+
+func silenceState(s *types.Silence, now time.Time) string {
+	switch {
+	case now.Before(s.StartsAt):
+		return "pending"
+	case now.Before(s.EndsAt):
+		return "active"
+	}
+	return "expired"
+}
@@ -0,0 +1,179 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+	"gopkg.in/yaml.v2"
+
+	"github.com/prometheus/alertmanager/api/client"
+	"github.com/prometheus/alertmanager/types"
+)
+
+func TestSilenceQueryParams(t *testing.T) {
+	now := time.Date(2016, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	for _, c := range []struct {
+		q   silenceQuery
+		exp url.Values
+	}{
+		{
+			q:   silenceQuery{},
+			exp: url.Values{"state": {"active", "pending"}},
+		},
+		{
+			q:   silenceQuery{within: time.Hour},
+			exp: url.Values{"state": {"active", "pending"}, "endsBefore": {"2016-01-01T13:00:00Z"}},
+		},
+		{
+			q:   silenceQuery{expired: true},
+			exp: url.Values{"state": {"expired"}},
+		},
+		{
+			q:   silenceQuery{expired: true, within: 24 * time.Hour},
+			exp: url.Values{"state": {"expired"}, "endsAfter": {"2015-12-31T12:00:00Z"}},
+		},
+	} {
+		require.Equal(t, c.exp, c.q.params(now))
+	}
+}
+
+func TestSilenceQueryFilter(t *testing.T) {
+	sils := []*types.Silence{
+		{ID: "1", Matchers: types.Matchers{types.NewMatcher("alertname", "DiskFull"), types.NewMatcher("job", "node")}},
+		{ID: "2", Matchers: types.Matchers{{Name: "alertname", Value: "Disk.*", IsRegex: true}}},
+		{ID: "3", Matchers: types.Matchers{types.NewMatcher("alertname", "HighLatency"), types.NewMatcher("job", "api")}},
+	}
+	ids := func(matchers ...string) []string {
+		var q silenceQuery
+		for _, s := range matchers {
+			m, err := parseSilenceMatcher(s)
+			require.NoError(t, err)
+			q.matchers = append(q.matchers, m)
+		}
+		res := []string{}
+		for _, s := range q.filter(sils) {
+			res = append(res, s.ID)
+		}
+		return res
+	}
+
+	require.Equal(t, []string{"1", "2", "3"}, ids())
+	require.Equal(t, []string{"1", "2"}, ids("alertname=DiskFull"))
+	require.Equal(t, []string{"1"}, ids("alertname=DiskFull", "job=node"))
+	require.Equal(t, []string{"1", "3"}, ids("job=~.+"))
+	require.Equal(t, []string{"3"}, ids("alertname=~High.*"))
+	require.Equal(t, []string{"2"}, ids(`alertname=~Disk\.\*`))
+	require.Equal(t, []string{}, ids("team=~.*"))
+
+	for _, s := range []string{"alertname", "=value", "alertname=~("} {
+		_, err := parseSilenceMatcher(s)
+		require.Error(t, err, s)
+	}
+}
+
+func TestWriteSilences(t *testing.T) {
+	now := time.Date(2016, 1, 1, 12, 0, 0, 0, time.UTC)
+	sils := []*types.Silence{{
+		ID:        "abc",
+		Matchers:  types.Matchers{types.NewMatcher("alertname", "DiskFull"), {Name: "job", Value: "node.*", IsRegex: true}},
+		StartsAt:  now.Add(-time.Hour),
+		EndsAt:    now.Add(time.Hour),
+		CreatedBy: "alice",
+		Comment:   "disk replacement",
+	}}
+
+	var buf bytes.Buffer
+	cols, err := parseSilenceColumns("id,matchers,state,createdBy")
+	require.NoError(t, err)
+	require.NoError(t, writeSilenceTable(&buf, sils, cols, now, true))
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2)
+	require.Equal(t, []string{"ID", "MATCHERS", "STATE", "CREATEDBY"}, strings.Fields(lines[0]))
+	require.Equal(t, []string{"abc", `{alertname="DiskFull",job=~"node.*"}`, "active", "alice"}, strings.Fields(lines[1]))
+
+	_, err = parseSilenceColumns("id,unknown")
+	require.Error(t, err)
+
+	buf.Reset()
+	require.NoError(t, writeSilences(&buf, sils, "json"))
+	var fromJSON []map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &fromJSON))
+	require.Equal(t, "abc", fromJSON[0]["id"])
+	require.Equal(t, "alice", fromJSON[0]["createdBy"])
+
+	buf.Reset()
+	require.NoError(t, writeSilences(&buf, sils, "yaml"))
+	var fromYAML []map[string]interface{}
+	require.NoError(t, yaml.Unmarshal(buf.Bytes(), &fromYAML))
+	require.Equal(t, "abc", fromYAML[0]["id"])
+	require.Equal(t, "disk replacement", fromYAML[0]["comment"])
+
+	buf.Reset()
+	require.NoError(t, writeSilences(&buf, nil, "json"))
+	require.Equal(t, "[]\n", buf.String())
+
+	require.Error(t, writeSilences(&buf, sils, "xml"))
+}
+
+func TestExtendSilence(t *testing.T) {
+	var req map[string]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "POST", r.Method)
+		req = nil
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+
+		switch r.URL.Path {
+		case "/api/v2/silence/abc/extend":
+			endsAt := req["endsAt"]
+			if endsAt == "" {
+				endsAt = "2016-01-01T14:00:00Z"
+			}
+			w.Write([]byte(`{"id":"abc","endsAt":"` + endsAt + `"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte("Error getting silence: <nil>\n"))
+		}
+	}))
+	defer srv.Close()
+
+	c, err := client.New(srv.URL, nil)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	var buf bytes.Buffer
+	require.NoError(t, extendSilence(ctx, c, &buf, "abc", time.Time{}, 2*time.Hour, "overran"))
+	require.Equal(t, map[string]string{"duration": "2h0m0s", "comment": "overran"}, req)
+	require.Equal(t, "abc\t2016-01-01T14:00:00Z\n", buf.String())
+
+	buf.Reset()
+	endsAt := time.Date(2016, 1, 2, 8, 0, 0, 0, time.UTC)
+	require.NoError(t, extendSilence(ctx, c, &buf, "abc", endsAt, 0, ""))
+	require.Equal(t, map[string]string{"endsAt": "2016-01-02T08:00:00Z"}, req)
+	require.Equal(t, "abc\t2016-01-02T08:00:00Z\n", buf.String())
+
+	err = extendSilence(ctx, c, &buf, "unknown", endsAt, 0, "")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "Error getting silence")
+}
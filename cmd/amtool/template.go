@@ -0,0 +1,168 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/prometheus/common/model"
+
+	"github.com/prometheus/alertmanager/ingest"
+	"github.com/prometheus/alertmanager/template"
+	"github.com/prometheus/alertmanager/types"
+)
+
+const templateUsage = `Usage: amtool template render [flags] [template files...]
+
+Renders a template with sample data, loading the template files like the
+templates setting of the configuration. The data is either a notification
+as passed to templates or a list of alerts, read from a JSON file, or the
+alerts of a running Alertmanager.
+
+Flags:
+`
+
+func runTemplate(args []string) error {
+	if len(args) == 0 || args[0] != "render" {
+		fmt.Fprint(os.Stderr, templateUsage)
+		return fmt.Errorf("unknown or missing template command")
+	}
+
+	var (
+		fs          = flag.NewFlagSet("template render", flag.ExitOnError)
+		name        = fs.String("name", "", "Name of the template to render, e.g. slack.default.text.")
+		text        = fs.String("text", "", "Template text to render instead of a named template, e.g. '{{ .CommonLabels.alertname }}'.")
+		html        = fs.Bool("html", false, "Render the template as HTML template, which escapes its output like for emails.")
+		dataFile    = fs.String("data", "", "JSON file holding the data, either an object like the data of webhook notifications or a list of alerts.")
+		amURL       = fs.String("alertmanager.url", "", "URL of an Alertmanager whose alerts are used as data instead of a file.")
+		receiver    = fs.String("receiver", "default", "Receiver name of data built from alerts.")
+		groupBy     = fs.String("group-by", "", "Comma-separated labels whose common values become the group labels of data built from alerts.")
+		externalURL = fs.String("external-url", "http://localhost:9093", "External URL of the Alertmanager used in links.")
+		locale      = fs.String("locale", "", "Locale of data built from alerts, which the default templates are translated to, e.g. de.")
+	)
+	fs.Usage = func() {
+		fmt.Fprint(os.Stderr, templateUsage)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args[1:])
+
+	if (*name == "") == (*text == "") {
+		return fmt.Errorf("exactly one of -name and -text must be given")
+	}
+	if (*dataFile == "") == (*amURL == "") {
+		return fmt.Errorf("exactly one of -data and -alertmanager.url must be given")
+	}
+
+	tmpl, err := template.FromGlobs(fs.Args()...)
+	if err != nil {
+		return err
+	}
+	if tmpl.ExternalURL, err = url.Parse(*externalURL); err != nil {
+		return err
+	}
+	tmpl.Locale = *locale
+
+	var data *template.Data
+	if *dataFile != "" {
+		b, err := ioutil.ReadFile(*dataFile)
+		if err != nil {
+			return err
+		}
+		if data, err = decodeTemplateData(b, tmpl, *receiver, *groupBy); err != nil {
+			return fmt.Errorf("reading %s: %s", *dataFile, err)
+		}
+	} else {
+		alerts, err := ingest.FetchAlertmanager(&http.Client{Timeout: 30 * time.Second}, *amURL)
+		if err != nil {
+			return fmt.Errorf("fetching alerts: %s", err)
+		}
+		data = alertsData(tmpl, *receiver, *groupBy, alerts)
+	}
+
+	if *name != "" {
+		if !tmpl.Defined(*name) {
+			return fmt.Errorf("template %q is not defined", *name)
+		}
+		*text = fmt.Sprintf("{{ template %q . }}", *name)
+	}
+	// Errors of parsing and executing templates state the name of the
+	// template, and the line and column of the failing expression.
+	var out string
+	if *html {
+		out, err = tmpl.ExecuteHTMLString(*text, data)
+	} else {
+		out, err = tmpl.ExecuteTextString(*text, data)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(os.Stdout, out)
+	return nil
+}
+
+// decodeTemplateData decodes either template data or a list of alerts from
+// which the data is built.
+func decodeTemplateData(b []byte, tmpl *template.Template, receiver, groupBy string) (*template.Data, error) {
+	if bytes.HasPrefix(bytes.TrimSpace(b), []byte("[")) {
+		var as []*model.Alert
+		if err := json.Unmarshal(b, &as); err != nil {
+			return nil, err
+		}
+		alerts := make([]*types.Alert, 0, len(as))
+		for _, a := range as {
+			alerts = append(alerts, &types.Alert{Alert: *a})
+		}
+		return alertsData(tmpl, receiver, groupBy, alerts), nil
+	}
+	var data template.Data
+	if err := json.Unmarshal(b, &data); err != nil {
+		return nil, err
+	}
+	return &data, nil
+}
+
+// alertsData builds the data of a notification of the alerts.
+func alertsData(tmpl *template.Template, receiver, groupBy string, alerts []*types.Alert) *template.Data {
+	now := time.Now()
+	for _, a := range alerts {
+		if a.StartsAt.IsZero() {
+			a.StartsAt = now
+		}
+	}
+	groupLabels := model.LabelSet{}
+	if groupBy != "" && len(alerts) > 0 {
+		for _, ln := range strings.Split(groupBy, ",") {
+			ln := model.LabelName(strings.TrimSpace(ln))
+			v, ok := alerts[0].Labels[ln]
+			for _, a := range alerts[1:] {
+				if a.Labels[ln] != v {
+					ok = false
+				}
+			}
+			if ok {
+				groupLabels[ln] = v
+			}
+		}
+	}
+	return tmpl.Data(receiver, groupLabels, alerts...)
+}
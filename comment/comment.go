@@ -0,0 +1,202 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package comment stores comments attached to alerts and groups. Comments
+// are shared with other Alertmanager instances through the mesh network.
+package comment
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/prometheus/common/log"
+	"github.com/prometheus/common/model"
+	"github.com/satori/go.uuid"
+	"github.com/weaveworks/mesh"
+
+	"github.com/prometheus/alertmanager/gossipstate"
+	"github.com/prometheus/alertmanager/types"
+)
+
+// maxCommentLength is the maximum length of a comment's text.
+const maxCommentLength = 4096
+
+func validateComment(c *types.Comment) error {
+	if c.ID == "" {
+		return errors.New("ID missing")
+	}
+	if len(c.Labels) == 0 {
+		return errors.New("at least one label required")
+	}
+	if err := c.Labels.Validate(); err != nil {
+		return err
+	}
+	if c.Author == "" {
+		return errors.New("author missing")
+	}
+	if c.Comment == "" {
+		return errors.New("comment missing")
+	}
+	if len(c.Comment) > maxCommentLength {
+		return fmt.Errorf("comment longer than %d characters", maxCommentLength)
+	}
+	if c.CreatedAt.IsZero() {
+		return errors.New("creation time missing")
+	}
+	return nil
+}
+
+// Comments holds the comments attached to alerts.
+type Comments struct {
+	retention time.Duration
+	now       func() time.Time
+	st        *gossipstate.State
+}
+
+// Options configures a new Comments object.
+type Options struct {
+	// A snapshot file from which the initial state is loaded.
+	SnapshotFile string
+
+	// Comments may be garbage collected the given duration after they
+	// were created.
+	Retention time.Duration
+
+	// A function creating a mesh.Gossip on being called with a mesh.Gossiper.
+	Gossip func(g mesh.Gossiper) mesh.Gossip
+
+	// A logger used by background processing.
+	Logger log.Logger
+}
+
+// New returns a new Comments object with the given configuration.
+func New(o Options) (*Comments, error) {
+	st, err := gossipstate.New(commentType{}, gossipstate.Options{
+		SnapshotFile: o.SnapshotFile,
+		Gossip:       o.Gossip,
+		Logger:       o.Logger,
+	})
+	if st == nil {
+		return nil, err
+	}
+	return &Comments{
+		retention: o.Retention,
+		now:       gossipstate.UTCNow,
+		st:        st,
+	}, err
+}
+
+// Maintenance garbage collects the comments at the given interval. If the
+// snapshot file is set, a snapshot is written to it afterwards.
+// Terminates on receiving from stopc.
+func (c *Comments) Maintenance(interval time.Duration, snapf string, stopc <-chan struct{}) {
+	c.st.Maintenance(interval, snapf, stopc, c.GC)
+}
+
+// GC removes comments that were created longer than the retention time ago.
+// It returns the number of removed comments.
+func (c *Comments) GC() (int, error) {
+	now := c.now()
+
+	return c.st.GC(func(e interface{}) bool {
+		return !e.(*types.Comment).CreatedAt.Add(c.retention).After(now)
+	}), nil
+}
+
+// Add adds a new comment and returns its ID.
+func (c *Comments) Add(cm *types.Comment) (string, error) {
+	cm.ID = uuid.NewV4().String()
+	cm.CreatedAt = c.now()
+
+	if err := validateComment(cm); err != nil {
+		return "", fmt.Errorf("invalid comment: %s", err)
+	}
+
+	c.st.Lock()
+	defer c.st.Unlock()
+
+	c.st.Set(cm)
+
+	return cm.ID, nil
+}
+
+type commentSlice []*types.Comment
+
+func (s commentSlice) Len() int           { return len(s) }
+func (s commentSlice) Less(i, j int) bool { return s[i].CreatedAt.Before(s[j].CreatedAt) }
+func (s commentSlice) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+// List returns all comments ordered by their creation time.
+func (c *Comments) List() []*types.Comment {
+	c.st.RLock()
+	defer c.st.RUnlock()
+
+	res := commentSlice{}
+	c.st.Range(func(e interface{}) {
+		res = append(res, e.(*types.Comment))
+	})
+	sort.Sort(res)
+	return res
+}
+
+// Query returns the comments about an alert with the given labels that
+// started firing at the given time, ordered by their creation time. It is
+// safe to call on a nil Comments.
+func (c *Comments) Query(lset model.LabelSet, startsAt time.Time) []*types.Comment {
+	if c == nil {
+		return nil
+	}
+	c.st.RLock()
+	defer c.st.RUnlock()
+
+	var res commentSlice
+	c.st.Range(func(e interface{}) {
+		if cm := e.(*types.Comment); cm.Applies(lset, startsAt) {
+			res = append(res, cm)
+		}
+	})
+	sort.Sort(res)
+	return res
+}
+
+// commentType implements the handling of comments by the state.
+type commentType struct{}
+
+func (commentType) Key(e interface{}) string {
+	return e.(*types.Comment).ID
+}
+
+func (commentType) Decode(b []byte) (interface{}, error) {
+	var cm types.Comment
+	if err := json.Unmarshal(b, &cm); err != nil {
+		return nil, err
+	}
+	if err := validateComment(&cm); err != nil {
+		return nil, fmt.Errorf("invalid comment: %s", err)
+	}
+	return &cm, nil
+}
+
+func (commentType) Size(e interface{}) int {
+	cm := e.(*types.Comment)
+	return len(cm.Comment) + 64*(len(cm.Labels)+1)
+}
+
+// Merge keeps the known comment as comments are never modified, only
+// comments unknown so far are merged.
+func (commentType) Merge(prev, e interface{}) interface{} {
+	return nil
+}
@@ -0,0 +1,97 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package comment
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+
+	"github.com/prometheus/alertmanager/gossipstate"
+	"github.com/prometheus/alertmanager/types"
+)
+
+func TestCommentsAddQuery(t *testing.T) {
+	c, err := New(Options{Retention: time.Hour})
+	require.NoError(t, err)
+
+	now := gossipstate.UTCNow()
+	c.now = func() time.Time { return now }
+
+	_, err = c.Add(&types.Comment{Labels: model.LabelSet{"job": "api"}, Author: "me"})
+	require.Error(t, err, "comment missing")
+
+	first, err := c.Add(&types.Comment{Labels: model.LabelSet{"job": "api"}, Author: "me", Comment: "looking into it"})
+	require.NoError(t, err)
+
+	now = now.Add(time.Minute)
+	second, err := c.Add(&types.Comment{
+		Labels:  model.LabelSet{"alertname": "HighLatency", "job": "api"},
+		Author:  "you",
+		Comment: "caused by the deploy",
+	})
+	require.NoError(t, err)
+
+	alert := model.LabelSet{"alertname": "HighLatency", "job": "api", "instance": "a"}
+
+	cms := c.Query(alert, now.Add(-time.Hour))
+	require.Len(t, cms, 2)
+	require.Equal(t, first, cms[0].ID)
+	require.Equal(t, second, cms[1].ID)
+
+	// Comments created before the alert started firing are not about it.
+	cms = c.Query(alert, now.Add(-time.Second))
+	require.Len(t, cms, 1)
+	require.Equal(t, second, cms[0].ID)
+
+	require.Len(t, c.Query(model.LabelSet{"job": "db"}, now.Add(-time.Hour)), 0)
+
+	var nilComments *Comments
+	require.Nil(t, nilComments.Query(alert, now))
+
+	now = now.Add(59*time.Minute + 30*time.Second)
+	n, err := c.GC()
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	require.Len(t, c.List(), 1)
+}
+
+func TestCommentTypeMerge(t *testing.T) {
+	now := gossipstate.UTCNow()
+
+	newComment := func(text string, created time.Time) *types.Comment {
+		return &types.Comment{
+			ID:        "a",
+			Labels:    model.LabelSet{"job": "api"},
+			Author:    "me",
+			Comment:   text,
+			CreatedAt: created,
+		}
+	}
+	prev := newComment("looking into it", now)
+
+	// Comments are never modified, so a known comment is always kept.
+	for _, c := range []struct {
+		name string
+		e    *types.Comment
+	}{
+		{name: "same", e: newComment("looking into it", now)},
+		{name: "different text", e: newComment("caused by the deploy", now)},
+		{name: "newer", e: newComment("caused by the deploy", now.Add(time.Minute))},
+	} {
+		require.Nil(t, commentType{}.Merge(prev, c.e), c.name)
+	}
+}
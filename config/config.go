@@ -14,19 +14,24 @@
 package config
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"math"
+	"net/url"
 	"path/filepath"
 	"regexp"
 	"strings"
 	"time"
 
-	"encoding/json"
 	"github.com/prometheus/common/model"
 	"gopkg.in/yaml.v2"
 )
 
+// patLocale matches locales like de or pt-BR.
+var patLocale = regexp.MustCompile(`^[a-z]{2,3}(?:[-_][A-Za-z]{2})?$`)
+
 var patAuthLine = regexp.MustCompile(`((?:api_key|service_key|api_url|token|user_key|password|secret):\s+)(".+"|'.+'|[^\s]+)`)
 
 // Secret is a string that must not be revealed on marshaling.
@@ -37,6 +42,11 @@ func (s Secret) MarshalYAML() (interface{}, error) {
 	return "<hidden>", nil
 }
 
+// MarshalJSON implements the json.Marshaler interface.
+func (s Secret) MarshalJSON() ([]byte, error) {
+	return json.Marshal("<hidden>")
+}
+
 // Load parses the YAML input s into a Config.
 func Load(s string) (*Config, error) {
 	cfg := &Config{}
@@ -92,6 +102,38 @@ type Config struct {
 	InhibitRules []*InhibitRule `yaml:"inhibit_rules,omitempty" json:"inhibit_rules,omitempty"`
 	Receivers    []*Receiver    `yaml:"receivers,omitempty" json:"receivers,omitempty"`
 	Templates    []string       `yaml:"templates" json:"templates"`
+	// TemplateLimits bounds the execution of templates. Defaults to
+	// DefaultTemplateLimits.
+	TemplateLimits *TemplateLimits `yaml:"template_limits,omitempty" json:"template_limits,omitempty"`
+
+	SilenceExpiry *SilenceExpiryConfig `yaml:"silence_expiry,omitempty" json:"silence_expiry,omitempty"`
+	SilencePolicy *SilencePolicy       `yaml:"silence_policy,omitempty" json:"silence_policy,omitempty"`
+
+	MaintenanceCalendars []*MaintenanceCalendar `yaml:"maintenance_calendars,omitempty" json:"maintenance_calendars,omitempty"`
+
+	SilenceRetention *SilenceRetentionConfig `yaml:"silence_retention,omitempty" json:"silence_retention,omitempty"`
+	SilenceLinks     *SilenceLinksConfig     `yaml:"silence_links,omitempty" json:"silence_links,omitempty"`
+	DashboardLinks   *DashboardLinksConfig   `yaml:"dashboard_links,omitempty" json:"dashboard_links,omitempty"`
+
+	Topology *TopologyConfig `yaml:"topology,omitempty" json:"topology,omitempty"`
+
+	StormRules    []*StormRule         `yaml:"storm_rules,omitempty" json:"storm_rules,omitempty"`
+	FlapDetection *FlapDetectionConfig `yaml:"flap_detection,omitempty" json:"flap_detection,omitempty"`
+
+	NotificationPriority *NotificationPriorityConfig `yaml:"notification_priority,omitempty" json:"notification_priority,omitempty"`
+	Dispatcher           *DispatcherConfig           `yaml:"dispatcher,omitempty" json:"dispatcher,omitempty"`
+
+	Summary *SummaryConfig `yaml:"summary,omitempty" json:"summary,omitempty"`
+
+	EventWebhooks []*EventWebhookConfig `yaml:"event_webhooks,omitempty" json:"event_webhooks,omitempty"`
+
+	OIDC *OIDCConfig `yaml:"oidc,omitempty" json:"oidc,omitempty"`
+
+	RateLimits *RateLimitsConfig `yaml:"rate_limits,omitempty" json:"rate_limits,omitempty"`
+	CORS       *CORSConfig       `yaml:"cors,omitempty" json:"cors,omitempty"`
+
+	IngestLimits   *IngestLimits    `yaml:"ingest_limits,omitempty" json:"ingest_limits,omitempty"`
+	IngestAdapters []*IngestAdapter `yaml:"ingest_adapters,omitempty" json:"ingest_adapters,omitempty"`
 
 	// Catches all undefined fields and must be empty after parsing.
 	XXX map[string]interface{} `yaml:",inline" json:"-"`
@@ -141,6 +183,14 @@ func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
 		c.Global = &GlobalConfig{}
 		*c.Global = DefaultGlobalConfig
 	}
+	if c.Global.HTTPClient == nil {
+		c.Global.HTTPClient = &HTTPClientConfig{}
+		*c.Global.HTTPClient = DefaultHTTPClientConfig
+	}
+	if c.TemplateLimits == nil {
+		c.TemplateLimits = &TemplateLimits{}
+		*c.TemplateLimits = DefaultTemplateLimits
+	}
 
 	names := map[string]struct{}{}
 
@@ -148,6 +198,12 @@ func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
 		if _, ok := names[rcv.Name]; ok {
 			return fmt.Errorf("notification config name %q is not unique", rcv.Name)
 		}
+		if rcv.HTTPClient == nil {
+			rcv.HTTPClient = c.Global.HTTPClient
+		}
+		if rcv.Locale == "" {
+			rcv.Locale = c.Global.Locale
+		}
 		for _, ec := range rcv.EmailConfigs {
 			if ec.Smarthost == "" {
 				if c.Global.SMTPSmarthost == "" {
@@ -252,6 +308,37 @@ func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	if err := checkReceiver(c.Route, names); err != nil {
 		return err
 	}
+	if c.Dispatcher != nil && c.Dispatcher.MaxConcurrentNotifications > 0 && c.NotificationPriority != nil {
+		return fmt.Errorf("max_concurrent_notifications of the dispatcher must not be set along with notification_priority, use its concurrency instead")
+	}
+	if c.Summary != nil {
+		if _, ok := names[c.Summary.Receiver]; !ok {
+			return fmt.Errorf("Undefined receiver %q used in summary config", c.Summary.Receiver)
+		}
+	}
+	if c.SilenceExpiry != nil {
+		if _, ok := names[c.SilenceExpiry.Receiver]; !ok {
+			return fmt.Errorf("Undefined receiver %q used in silence expiry notifications", c.SilenceExpiry.Receiver)
+		}
+	}
+	stormRules := map[string]struct{}{}
+	for _, sr := range c.StormRules {
+		if _, ok := stormRules[sr.Name]; ok {
+			return fmt.Errorf("storm rule %q is not unique", sr.Name)
+		}
+		stormRules[sr.Name] = struct{}{}
+
+		if _, ok := names[sr.Receiver]; !ok {
+			return fmt.Errorf("Undefined receiver %q used in storm rule %q", sr.Receiver, sr.Name)
+		}
+	}
+	adapters := map[string]struct{}{}
+	for _, ia := range c.IngestAdapters {
+		if _, ok := adapters[ia.Name]; ok {
+			return fmt.Errorf("ingest adapter %q is not unique", ia.Name)
+		}
+		adapters[ia.Name] = struct{}{}
+	}
 
 	return checkOverflow(c.XXX, "config")
 }
@@ -259,6 +346,11 @@ func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
 // checkReceiver returns an error if a node in the routing tree
 // references a receiver not in the given map.
 func checkReceiver(r *Route, receivers map[string]struct{}) error {
+	for _, e := range r.Escalations {
+		if _, ok := receivers[e.Receiver]; !ok {
+			return fmt.Errorf("Undefined receiver %q used in escalation", e.Receiver)
+		}
+	}
 	if r.Receiver == "" {
 		return nil
 	}
@@ -290,6 +382,11 @@ type GlobalConfig struct {
 	// ResolveTimeout is the time after which an alert is declared resolved
 	// if it has not been updated.
 	ResolveTimeout model.Duration `yaml:"resolve_timeout" json:"resolve_timeout"`
+	// SourceLabel is the label identifying the client sending an alert.
+	// It is removed from alerts so that copies of an alert sent by several
+	// clients are deduplicated. If unset, alerts are attributed to the host
+	// of their generator URL.
+	SourceLabel model.LabelName `yaml:"source_label,omitempty" json:"source_label,omitempty"`
 
 	SMTPFrom         string `yaml:"smtp_from" json:"smtp_from"`
 	SMTPSmarthost    string `yaml:"smtp_smarthost" json:"smtp_smarthost"`
@@ -305,6 +402,14 @@ type GlobalConfig struct {
 	OpsGenieAPIHost  string `yaml:"opsgenie_api_host" json:"opsgenie_api_host"`
 	VictorOpsAPIURL  string `yaml:"victorops_api_url" json:"victorops_api_url"`
 
+	// HTTPClient configures the HTTP clients of receivers that do not
+	// configure their own.
+	HTTPClient *HTTPClientConfig `yaml:"http_client,omitempty" json:"http_client,omitempty"`
+
+	// Locale selects the language of the default templates of receivers
+	// that do not set their own, e.g. de or pt-BR. Defaults to English.
+	Locale string `yaml:"locale,omitempty" json:"locale,omitempty"`
+
 	// Catches all undefined fields and must be empty after parsing.
 	XXX map[string]interface{} `yaml:",inline" json:"-"`
 }
@@ -316,6 +421,12 @@ func (c *GlobalConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	if err := unmarshal((*plain)(c)); err != nil {
 		return err
 	}
+	if c.SourceLabel != "" && !c.SourceLabel.IsValid() {
+		return fmt.Errorf("invalid source label %q", c.SourceLabel)
+	}
+	if c.Locale != "" && !patLocale.MatchString(c.Locale) {
+		return fmt.Errorf("invalid locale %q", c.Locale)
+	}
 	return checkOverflow(c.XXX, "global")
 }
 
@@ -332,6 +443,19 @@ type Route struct {
 	GroupWait      *model.Duration `yaml:"group_wait,omitempty" json:"group_wait,omitempty"`
 	GroupInterval  *model.Duration `yaml:"group_interval,omitempty" json:"group_interval,omitempty"`
 	RepeatInterval *model.Duration `yaml:"repeat_interval,omitempty" json:"repeat_interval,omitempty"`
+	// If set, the first notification of a group is delayed until no new
+	// alert joined the group for group_wait, but at most until group_wait_max
+	// after the group was created.
+	GroupWaitMax *model.Duration `yaml:"group_wait_max,omitempty" json:"group_wait_max,omitempty"`
+	// The time after which alerts matching the route are resolved if they
+	// are not updated and carry no end time. Overrides the global setting.
+	ResolveTimeout *model.Duration `yaml:"resolve_timeout,omitempty" json:"resolve_timeout,omitempty"`
+	// Receivers that are notified in addition if the group's alerts keep
+	// firing unacknowledged, ordered by their delay.
+	Escalations []*Escalation `yaml:"escalations,omitempty" json:"escalations,omitempty"`
+	// If true, groups with firing alerts muted by a silence are notified
+	// as soon as the silence expires instead of after the repeat interval.
+	FlushOnSilenceExpiry *bool `yaml:"flush_on_silence_expiry,omitempty" json:"flush_on_silence_expiry,omitempty"`
 
 	// Catches all undefined fields and must be empty after parsing.
 	XXX map[string]interface{} `yaml:",inline" json:"-"`
@@ -365,128 +489,1308 @@ func (r *Route) UnmarshalYAML(unmarshal func(interface{}) error) error {
 		groupBy[ln] = struct{}{}
 	}
 
+	if r.GroupWaitMax != nil && r.GroupWait != nil && *r.GroupWaitMax < *r.GroupWait {
+		return fmt.Errorf("group_wait_max must not be shorter than group_wait")
+	}
+
+	if r.ResolveTimeout != nil && *r.ResolveTimeout <= 0 {
+		return fmt.Errorf("resolve_timeout must be positive")
+	}
+
+	for i := 1; i < len(r.Escalations); i++ {
+		if r.Escalations[i].After <= r.Escalations[i-1].After {
+			return fmt.Errorf("escalations must be ordered by increasing delay")
+		}
+	}
+
 	return checkOverflow(r.XXX, "route")
 }
 
-// InhibitRule defines an inhibition rule that mutes alerts that match the
-// target labels if an alert matching the source labels exists.
-// Both alerts have to have a set of labels being equal.
-type InhibitRule struct {
-	// SourceMatch defines a set of labels that have to equal the given
-	// value for source alerts.
-	SourceMatch map[string]string `yaml:"source_match" json:"source_match"`
-	// SourceMatchRE defines pairs like SourceMatch but does regular expression
-	// matching.
-	SourceMatchRE map[string]Regexp `yaml:"source_match_re" json:"source_match_re"`
-	// TargetMatch defines a set of labels that have to equal the given
-	// value for target alerts.
-	TargetMatch map[string]string `yaml:"target_match" json:"target_match"`
-	// TargetMatchRE defines pairs like TargetMatch but does regular expression
-	// matching.
-	TargetMatchRE map[string]Regexp `yaml:"target_match_re" json:"target_match_re"`
-	// A set of labels that must be equal between the source and target alert
-	// for them to be a match.
-	Equal model.LabelNames `yaml:"equal" json:"equal"`
+// NotificationPriorityConfig limits the number of concurrently sent group
+// notifications. Waiting notifications are sent in the order of a label's
+// value rather than in the order the groups became ready.
+type NotificationPriorityConfig struct {
+	// The label whose value determines the priority of an alert.
+	Label model.LabelName `yaml:"label,omitempty" json:"label,omitempty"`
+	// The label values ordered from highest to lowest priority. Alerts
+	// with other values have the lowest priority. A group has the highest
+	// priority of its alerts.
+	Values []model.LabelValue `yaml:"values" json:"values"`
+	// The maximum number of group notifications sent at the same time.
+	Concurrency int `yaml:"concurrency,omitempty" json:"concurrency,omitempty"`
 
 	// Catches all undefined fields and must be empty after parsing.
 	XXX map[string]interface{} `yaml:",inline" json:"-"`
 }
 
 // UnmarshalYAML implements the yaml.Unmarshaler interface.
-func (r *InhibitRule) UnmarshalYAML(unmarshal func(interface{}) error) error {
-	type plain InhibitRule
-	if err := unmarshal((*plain)(r)); err != nil {
+func (c *NotificationPriorityConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	c.Label = "severity"
+	c.Concurrency = 10
+
+	type plain NotificationPriorityConfig
+	if err := unmarshal((*plain)(c)); err != nil {
 		return err
 	}
+	if !c.Label.IsValid() {
+		return fmt.Errorf("invalid notification priority label %q", c.Label)
+	}
+	if len(c.Values) == 0 {
+		return fmt.Errorf("missing values in notification priority config")
+	}
+	if c.Concurrency <= 0 {
+		return fmt.Errorf("notification concurrency must be positive")
+	}
+	return checkOverflow(c.XXX, "notification priority config")
+}
 
-	for k := range r.SourceMatch {
-		if !model.LabelNameRE.MatchString(k) {
-			return fmt.Errorf("invalid label name %q", k)
-		}
+// DefaultDispatcherConfig defines the default dispatcher configuration.
+var DefaultDispatcherConfig = DispatcherConfig{
+	Workers:       1,
+	QueueCapacity: 200,
+}
+
+// DispatcherConfig configures how many alerts and notifications are
+// processed concurrently.
+type DispatcherConfig struct {
+	// The number of workers sorting incoming alerts into aggregation groups.
+	Workers int `yaml:"workers,omitempty" json:"workers,omitempty"`
+	// The number of incoming alerts queued per worker. Further alerts
+	// are accepted once the worker caught up.
+	QueueCapacity int `yaml:"queue_capacity,omitempty" json:"queue_capacity,omitempty"`
+	// The maximum number of group notifications sent at the same time
+	// across all receivers. Zero means no limit.
+	MaxConcurrentNotifications int `yaml:"max_concurrent_notifications,omitempty" json:"max_concurrent_notifications,omitempty"`
+
+	// Catches all undefined fields and must be empty after parsing.
+	XXX map[string]interface{} `yaml:",inline" json:"-"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *DispatcherConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultDispatcherConfig
+	type plain DispatcherConfig
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	if c.Workers <= 0 {
+		return fmt.Errorf("dispatcher workers must be positive")
+	}
+	if c.QueueCapacity <= 0 {
+		return fmt.Errorf("dispatcher queue capacity must be positive")
 	}
+	if c.MaxConcurrentNotifications < 0 {
+		return fmt.Errorf("max_concurrent_notifications of the dispatcher must not be negative")
+	}
+	return checkOverflow(c.XXX, "dispatcher config")
+}
 
-	for k := range r.SourceMatchRE {
-		if !model.LabelNameRE.MatchString(k) {
-			return fmt.Errorf("invalid label name %q", k)
-		}
+// Escalation notifies a receiver if the alerts of a group are still firing
+// and not acknowledged the given time after the group was first notified.
+type Escalation struct {
+	Receiver string         `yaml:"receiver" json:"receiver"`
+	After    model.Duration `yaml:"after" json:"after"`
+
+	// Catches all undefined fields and must be empty after parsing.
+	XXX map[string]interface{} `yaml:",inline" json:"-"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (e *Escalation) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain Escalation
+	if err := unmarshal((*plain)(e)); err != nil {
+		return err
+	}
+	if e.Receiver == "" {
+		return fmt.Errorf("missing receiver in escalation")
 	}
+	if e.After <= 0 {
+		return fmt.Errorf("escalation delay must be positive")
+	}
+	return checkOverflow(e.XXX, "escalation")
+}
 
-	for k := range r.TargetMatch {
-		if !model.LabelNameRE.MatchString(k) {
-			return fmt.Errorf("invalid label name %q", k)
-		}
+// SilenceExpiryConfig configures notifications about silences that are about
+// to expire while alerts matched by them are still firing.
+type SilenceExpiryConfig struct {
+	// The receiver to which notifications about expiring silences are sent.
+	Receiver string `yaml:"receiver" json:"receiver"`
+	// How long before its end a silence is considered to be expiring.
+	Before model.Duration `yaml:"before" json:"before"`
+
+	// Catches all undefined fields and must be empty after parsing.
+	XXX map[string]interface{} `yaml:",inline" json:"-"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *SilenceExpiryConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	c.Before = model.Duration(15 * time.Minute)
+
+	type plain SilenceExpiryConfig
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	if c.Receiver == "" {
+		return fmt.Errorf("missing receiver in silence expiry config")
 	}
+	if c.Before <= 0 {
+		return fmt.Errorf("silence expiry notification time must be positive")
+	}
+	return checkOverflow(c.XXX, "silence expiry config")
+}
 
-	for k := range r.TargetMatchRE {
-		if !model.LabelNameRE.MatchString(k) {
-			return fmt.Errorf("invalid label name %q", k)
-		}
+// DefaultSummaryTemplate is the template used for summaries if none is
+// configured.
+const DefaultSummaryTemplate = `{{ len .Alerts }} alerts firing, {{ len .Silences }} silences active, {{ .Notifications }} groups notified since {{ .Since.Format "2006-01-02 15:04" }}.
+{{ range .Alerts }}
+* {{ range .Labels.SortedPairs }}{{ .Name }}={{ .Value }} {{ end }}since {{ .StartsAt.Format "2006-01-02 15:04" }}
+{{- end }}`
+
+// SummaryConfig configures periodic notifications summarizing the firing
+// alerts, active silences and recent notifications.
+type SummaryConfig struct {
+	// The receiver to which the summary is sent.
+	Receiver string `yaml:"receiver" json:"receiver"`
+	// The local time of day at which the summary is sent, in the form HH:MM.
+	At string `yaml:"at" json:"at"`
+	// The interval between two summaries.
+	Interval model.Duration `yaml:"interval,omitempty" json:"interval,omitempty"`
+	// The template rendering the summary. It is passed to the receiver in
+	// the summary annotation of the summary alert.
+	Template string `yaml:"template,omitempty" json:"template,omitempty"`
+
+	// Catches all undefined fields and must be empty after parsing.
+	XXX map[string]interface{} `yaml:",inline" json:"-"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *SummaryConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	c.Interval = model.Duration(24 * time.Hour)
+	c.Template = DefaultSummaryTemplate
+
+	type plain SummaryConfig
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	if c.Receiver == "" {
+		return fmt.Errorf("missing receiver in summary config")
+	}
+	if _, err := time.Parse("15:04", c.At); err != nil {
+		return fmt.Errorf("invalid summary time %q, expected HH:MM", c.At)
 	}
+	if c.Interval <= 0 {
+		return fmt.Errorf("summary interval must be positive")
+	}
+	return checkOverflow(c.XXX, "summary config")
+}
 
-	return checkOverflow(r.XXX, "inhibit rule")
+// EventWebhookConfig configures a URL to which lifecycle events of alerts,
+// silences and notifications are posted.
+type EventWebhookConfig struct {
+	// URL to send POST requests to.
+	URL string `yaml:"url" json:"url"`
+	// The types of events to send. All events are sent if empty.
+	Events []string `yaml:"events,omitempty" json:"events,omitempty"`
+
+	// Catches all undefined fields and must be empty after parsing.
+	XXX map[string]interface{} `yaml:",inline" json:"-"`
 }
 
-// Receiver configuration provides configuration on how to contact a receiver.
-type Receiver struct {
-	// A unique identifier for this receiver.
-	Name string `yaml:"name" json:"name"`
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *EventWebhookConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain EventWebhookConfig
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	if c.URL == "" {
+		return fmt.Errorf("missing URL in event webhook config")
+	}
+	return checkOverflow(c.XXX, "event webhook config")
+}
 
-	EmailConfigs     []*EmailConfig     `yaml:"email_configs,omitempty" json:"email_configs,omitempty"`
-	PagerdutyConfigs []*PagerdutyConfig `yaml:"pagerduty_configs,omitempty" json:"pagerduty_configs,omitempty"`
-	HipchatConfigs   []*HipchatConfig   `yaml:"hipchat_configs,omitempty" json:"hipchat_configs,omitempty"`
-	SlackConfigs     []*SlackConfig     `yaml:"slack_configs,omitempty" json:"slack_configs,omitempty"`
-	WebhookConfigs   []*WebhookConfig   `yaml:"webhook_configs,omitempty" json:"webhook_configs,omitempty"`
-	OpsGenieConfigs  []*OpsGenieConfig  `yaml:"opsgenie_configs,omitempty" json:"opsgenie_configs,omitempty"`
-	PushoverConfigs  []*PushoverConfig  `yaml:"pushover_configs,omitempty" json:"pushover_configs,omitempty"`
-	VictorOpsConfigs []*VictorOpsConfig `yaml:"victorops_configs,omitempty" json:"victorops_configs,omitempty"`
+// FlapDetectionConfig configures the detection of alerts that repeatedly
+// change between firing and resolved. Notifications for groups containing
+// flapping alerts are sent less frequently.
+type FlapDetectionConfig struct {
+	// An alert is flapping if it changed its state more than Threshold
+	// times within Window.
+	Threshold int            `yaml:"threshold" json:"threshold"`
+	Window    model.Duration `yaml:"window,omitempty" json:"window,omitempty"`
+	// The group interval used for groups containing flapping alerts if it
+	// is longer than the route's group interval.
+	GroupInterval model.Duration `yaml:"group_interval,omitempty" json:"group_interval,omitempty"`
 
 	// Catches all undefined fields and must be empty after parsing.
 	XXX map[string]interface{} `yaml:",inline" json:"-"`
 }
 
 // UnmarshalYAML implements the yaml.Unmarshaler interface.
-func (c *Receiver) UnmarshalYAML(unmarshal func(interface{}) error) error {
-	type plain Receiver
+func (c *FlapDetectionConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	c.Window = model.Duration(time.Hour)
+	c.GroupInterval = model.Duration(time.Hour)
+
+	type plain FlapDetectionConfig
 	if err := unmarshal((*plain)(c)); err != nil {
 		return err
 	}
-	if c.Name == "" {
-		return fmt.Errorf("missing name in receiver")
+	if c.Threshold <= 0 {
+		return fmt.Errorf("flap detection threshold must be positive")
 	}
-	return checkOverflow(c.XXX, "receiver config")
+	if c.Window <= 0 || c.GroupInterval <= 0 {
+		return fmt.Errorf("flap detection window and group interval must be positive")
+	}
+	return checkOverflow(c.XXX, "flap detection config")
 }
 
-// Regexp encapsulates a regexp.Regexp and makes it YAML marshalable.
-type Regexp struct {
-	*regexp.Regexp
+// SilenceRetentionConfig configures how long expired silences are kept. It
+// overrides the corresponding command line flags.
+type SilenceRetentionConfig struct {
+	// How long silences are retained after they expired.
+	Retention model.Duration `yaml:"retention,omitempty" json:"retention,omitempty"`
+	// The interval at which expired silences are garbage collected.
+	GCInterval model.Duration `yaml:"gc_interval,omitempty" json:"gc_interval,omitempty"`
+
+	// Catches all undefined fields and must be empty after parsing.
+	XXX map[string]interface{} `yaml:",inline" json:"-"`
 }
 
 // UnmarshalYAML implements the yaml.Unmarshaler interface.
-func (re *Regexp) UnmarshalYAML(unmarshal func(interface{}) error) error {
-	var s string
-	if err := unmarshal(&s); err != nil {
+func (c *SilenceRetentionConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain SilenceRetentionConfig
+	if err := unmarshal((*plain)(c)); err != nil {
 		return err
 	}
-	regex, err := regexp.Compile("^(?:" + s + ")$")
-	if err != nil {
+	if c.Retention < 0 {
+		return fmt.Errorf("silence retention must not be negative")
+	}
+	if c.GCInterval < 0 {
+		return fmt.Errorf("silence garbage collection interval must not be negative")
+	}
+	return checkOverflow(c.XXX, "silence retention config")
+}
+
+// SilenceLinksConfig configures signed links in notifications that create a
+// silence for the notified group once confirmed.
+type SilenceLinksConfig struct {
+	// The secret the link parameters are signed with.
+	Secret Secret `yaml:"secret" json:"secret"`
+	// The duration of silences created through links.
+	Duration model.Duration `yaml:"duration,omitempty" json:"duration,omitempty"`
+	// How long links stay valid after the notification was sent.
+	Validity model.Duration `yaml:"validity,omitempty" json:"validity,omitempty"`
+
+	// Catches all undefined fields and must be empty after parsing.
+	XXX map[string]interface{} `yaml:",inline" json:"-"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *SilenceLinksConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	c.Duration = model.Duration(time.Hour)
+	c.Validity = model.Duration(7 * 24 * time.Hour)
+
+	type plain SilenceLinksConfig
+	if err := unmarshal((*plain)(c)); err != nil {
 		return err
 	}
-	re.Regexp = regex
-	return nil
+	if c.Secret == "" {
+		return fmt.Errorf("missing secret for silence links")
+	}
+	if c.Duration <= 0 {
+		return fmt.Errorf("silence link duration must be positive")
+	}
+	if c.Validity <= 0 {
+		return fmt.Errorf("silence link validity must be positive")
+	}
+	return checkOverflow(c.XXX, "silence links config")
 }
 
-// MarshalYAML implements the yaml.Marshaler interface.
-func (re *Regexp) MarshalYAML() (interface{}, error) {
-	if re != nil {
-		return re.String(), nil
+// DashboardLinksConfig configures the base URLs of the systems the deep link
+// functions of templates point to.
+type DashboardLinksConfig struct {
+	// The base URL of Grafana, used by grafanaLink.
+	GrafanaURL string `yaml:"grafana_url,omitempty" json:"grafana_url,omitempty"`
+	// The URL of a log search, used by logSearchLink. {query} is replaced by
+	// a label selector like {job="api"}.
+	LogSearchURL string `yaml:"log_search_url,omitempty" json:"log_search_url,omitempty"`
+	// The URL of a trace view, used by traceLink. {trace_id} is replaced by
+	// the trace ID.
+	TraceURL string `yaml:"trace_url,omitempty" json:"trace_url,omitempty"`
+
+	// Catches all undefined fields and must be empty after parsing.
+	XXX map[string]interface{} `yaml:",inline" json:"-"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *DashboardLinksConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain DashboardLinksConfig
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
 	}
-	return nil, nil
+	for _, u := range []struct {
+		name, url, placeholder string
+	}{
+		{"grafana_url", c.GrafanaURL, ""},
+		{"log_search_url", c.LogSearchURL, "{query}"},
+		{"trace_url", c.TraceURL, "{trace_id}"},
+	} {
+		if u.url == "" {
+			continue
+		}
+		if _, err := url.Parse(u.url); err != nil {
+			return fmt.Errorf("invalid %s: %s", u.name, err)
+		}
+		if u.placeholder != "" && !strings.Contains(u.url, u.placeholder) {
+			return fmt.Errorf("%s must contain %s", u.name, u.placeholder)
+		}
+	}
+	return checkOverflow(c.XXX, "dashboard links config")
 }
 
-// MarshalJSON implements the json.Marshaler interface.
-func (re Regexp) MarshalJSON() ([]byte, error) {
-	if re.Regexp != nil {
-		return json.Marshal(re.String())
+// OIDCConfig configures authentication of the web interface and the API
+// against an OpenID Connect provider.
+type OIDCConfig struct {
+	// The issuer URL of the provider, which serves the discovery document.
+	Issuer       string `yaml:"issuer" json:"issuer"`
+	ClientID     string `yaml:"client_id" json:"client_id"`
+	ClientSecret Secret `yaml:"client_secret" json:"client_secret"`
+	// Scopes requested in addition to openid.
+	Scopes []string `yaml:"scopes,omitempty" json:"scopes,omitempty"`
+	// The ID token claims holding the user name and the groups of the user.
+	UsernameClaim string `yaml:"username_claim,omitempty" json:"username_claim,omitempty"`
+	GroupsClaim   string `yaml:"groups_claim,omitempty" json:"groups_claim,omitempty"`
+	// If set, only members of one of the groups are allowed access.
+	AllowedGroups []string `yaml:"allowed_groups,omitempty" json:"allowed_groups,omitempty"`
+	// How long a login is valid.
+	SessionDuration model.Duration `yaml:"session_duration,omitempty" json:"session_duration,omitempty"`
+
+	// Catches all undefined fields and must be empty after parsing.
+	XXX map[string]interface{} `yaml:",inline" json:"-"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *OIDCConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	c.Scopes = []string{"profile", "email"}
+	c.UsernameClaim = "email"
+	c.GroupsClaim = "groups"
+	c.SessionDuration = model.Duration(12 * time.Hour)
+
+	type plain OIDCConfig
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
 	}
-	return nil, nil
+	if c.Issuer == "" {
+		return fmt.Errorf("missing issuer in OIDC config")
+	}
+	if u, err := url.Parse(c.Issuer); err != nil || u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("invalid OIDC issuer URL %q", c.Issuer)
+	}
+	if c.ClientID == "" {
+		return fmt.Errorf("missing client_id in OIDC config")
+	}
+	if c.ClientSecret == "" {
+		return fmt.Errorf("missing client_secret in OIDC config")
+	}
+	if c.UsernameClaim == "" {
+		return fmt.Errorf("username_claim in OIDC config must not be empty")
+	}
+	if c.SessionDuration <= 0 {
+		return fmt.Errorf("OIDC session duration must be positive")
+	}
+	return checkOverflow(c.XXX, "oidc config")
+}
+
+// IngestLimits limits the size of alerts posted to the API. Zero values
+// mean unlimited.
+type IngestLimits struct {
+	MaxAlertsPerRequest    int `yaml:"max_alerts_per_request,omitempty" json:"max_alerts_per_request,omitempty"`
+	MaxLabelsPerAlert      int `yaml:"max_labels_per_alert,omitempty" json:"max_labels_per_alert,omitempty"`
+	MaxAnnotationsPerAlert int `yaml:"max_annotations_per_alert,omitempty" json:"max_annotations_per_alert,omitempty"`
+	// MaxValueLength is the maximum length in bytes of label and annotation
+	// values.
+	MaxValueLength int `yaml:"max_value_length,omitempty" json:"max_value_length,omitempty"`
+	// MaxBodySize is the maximum size in bytes of a request body. Gzip
+	// encoded bodies are limited to 64MiB after decompression if unset.
+	MaxBodySize int64 `yaml:"max_body_size,omitempty" json:"max_body_size,omitempty"`
+
+	// Catches all undefined fields and must be empty after parsing.
+	XXX map[string]interface{} `yaml:",inline" json:"-"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *IngestLimits) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain IngestLimits
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	if c.MaxAlertsPerRequest < 0 || c.MaxLabelsPerAlert < 0 || c.MaxAnnotationsPerAlert < 0 || c.MaxValueLength < 0 || c.MaxBodySize < 0 {
+		return fmt.Errorf("ingest limits must not be negative")
+	}
+	return checkOverflow(c.XXX, "ingest limits")
+}
+
+// DefaultTemplateLimits defines the default limits of template execution.
+var DefaultTemplateLimits = TemplateLimits{
+	Timeout:       model.Duration(10 * time.Second),
+	MaxOutputSize: 4 << 20,
+}
+
+// TemplateLimits bounds the execution of templates, so a pathological
+// template cannot hang the notification pipeline. Zero values mean
+// unlimited.
+type TemplateLimits struct {
+	// Timeout is the maximum time the execution of a template may take.
+	Timeout model.Duration `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+	// MaxOutputSize is the maximum size in bytes of the output of a template.
+	MaxOutputSize int `yaml:"max_output_size,omitempty" json:"max_output_size,omitempty"`
+	// MaxDepth is the maximum nesting depth of templates invoking other
+	// templates. It is checked before templates are executed, so templates
+	// invoking themselves, directly or indirectly, are rejected once it is
+	// set, even if their recursion ends.
+	MaxDepth int `yaml:"max_depth,omitempty" json:"max_depth,omitempty"`
+
+	// Catches all undefined fields and must be empty after parsing.
+	XXX map[string]interface{} `yaml:",inline" json:"-"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *TemplateLimits) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultTemplateLimits
+	type plain TemplateLimits
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	if c.Timeout < 0 || c.MaxOutputSize < 0 || c.MaxDepth < 0 {
+		return fmt.Errorf("template limits must not be negative")
+	}
+	return checkOverflow(c.XXX, "template limits")
+}
+
+// IngestAdapterTypes are the third-party alert formats ingest adapters
+// can translate.
+var IngestAdapterTypes = []string{"grafana", "cloudwatch"}
+
+// IngestAdapter configures an endpoint accepting alerts in a third-party
+// format at /api/v1/ingest/<name>.
+type IngestAdapter struct {
+	Name string `yaml:"name" json:"name"`
+	// Type is the format of the alerts, one of IngestAdapterTypes.
+	Type string `yaml:"type" json:"type"`
+	// LabelMap renames the labels derived from the alerts. Labels mapped to
+	// an empty name are dropped.
+	LabelMap map[string]string `yaml:"label_map,omitempty" json:"label_map,omitempty"`
+	// Labels are added to all alerts, overriding derived labels.
+	Labels model.LabelSet `yaml:"labels,omitempty" json:"labels,omitempty"`
+
+	// Catches all undefined fields and must be empty after parsing.
+	XXX map[string]interface{} `yaml:",inline" json:"-"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *IngestAdapter) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain IngestAdapter
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	if c.Name == "" || strings.Contains(c.Name, "/") {
+		return fmt.Errorf("invalid ingest adapter name %q", c.Name)
+	}
+	known := false
+	for _, t := range IngestAdapterTypes {
+		if c.Type == t {
+			known = true
+			break
+		}
+	}
+	if !known {
+		return fmt.Errorf("unknown type %q of ingest adapter %q", c.Type, c.Name)
+	}
+	for from, to := range c.LabelMap {
+		if to != "" && !model.LabelName(to).IsValid() {
+			return fmt.Errorf("invalid label name %q in label map of ingest adapter %q for %q", to, c.Name, from)
+		}
+	}
+	if err := c.Labels.Validate(); err != nil {
+		return fmt.Errorf("invalid labels of ingest adapter %q: %s", c.Name, err)
+	}
+	return checkOverflow(c.XXX, "ingest adapter")
+}
+
+// CORSConfig defines which other origins may call the API from browsers.
+type CORSConfig struct {
+	// Origins allowed to call the API, such as "https://dashboard.example.com",
+	// or "*" for all origins.
+	AllowedOrigins []string `yaml:"allowed_origins" json:"allowed_origins"`
+	AllowedMethods []string `yaml:"allowed_methods,omitempty" json:"allowed_methods,omitempty"`
+	AllowedHeaders []string `yaml:"allowed_headers,omitempty" json:"allowed_headers,omitempty"`
+	// Whether browsers may send cookies, such as the session cookie of the
+	// OIDC login. It cannot be combined with the "*" origin.
+	AllowCredentials bool `yaml:"allow_credentials,omitempty" json:"allow_credentials,omitempty"`
+	// How long browsers may cache the result of preflight requests.
+	MaxAge model.Duration `yaml:"max_age,omitempty" json:"max_age,omitempty"`
+
+	// Catches all undefined fields and must be empty after parsing.
+	XXX map[string]interface{} `yaml:",inline" json:"-"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *CORSConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	c.AllowedMethods = []string{"GET", "POST", "DELETE", "OPTIONS"}
+	c.AllowedHeaders = []string{"Accept", "Authorization", "Content-Type", "Origin"}
+
+	type plain CORSConfig
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	if len(c.AllowedOrigins) == 0 {
+		return fmt.Errorf("missing allowed_origins in CORS config")
+	}
+	for _, o := range c.AllowedOrigins {
+		if o == "*" {
+			if c.AllowCredentials {
+				return fmt.Errorf("CORS credentials cannot be allowed for all origins")
+			}
+			continue
+		}
+		u, err := url.Parse(o)
+		if err != nil || u.Scheme == "" || u.Host == "" || (u.Path != "" && u.Path != "/") || u.RawQuery != "" {
+			return fmt.Errorf("invalid CORS origin %q, must be like \"https://example.com\"", o)
+		}
+	}
+	for i, m := range c.AllowedMethods {
+		c.AllowedMethods[i] = strings.ToUpper(m)
+	}
+	if c.MaxAge < 0 {
+		return fmt.Errorf("CORS max age must not be negative")
+	}
+	return checkOverflow(c.XXX, "cors config")
+}
+
+// RateLimitsConfig limits the rate of API requests of each client. Clients
+// are identified by their API key or, without one, by their IP address.
+type RateLimitsConfig struct {
+	// Alerts limits requests posting alerts.
+	Alerts *RateLimit `yaml:"alerts,omitempty" json:"alerts,omitempty"`
+	// Silences limits requests creating, updating and expiring silences.
+	Silences *RateLimit `yaml:"silences,omitempty" json:"silences,omitempty"`
+
+	// Catches all undefined fields and must be empty after parsing.
+	XXX map[string]interface{} `yaml:",inline" json:"-"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *RateLimitsConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain RateLimitsConfig
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	return checkOverflow(c.XXX, "rate limits config")
+}
+
+// RateLimit is a token bucket allowing Rate requests per second on average
+// and bursts of up to Burst requests.
+type RateLimit struct {
+	Rate  float64 `yaml:"rate" json:"rate"`
+	Burst int     `yaml:"burst,omitempty" json:"burst,omitempty"`
+
+	// Catches all undefined fields and must be empty after parsing.
+	XXX map[string]interface{} `yaml:",inline" json:"-"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *RateLimit) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain RateLimit
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	if c.Rate <= 0 {
+		return fmt.Errorf("rate limit must be positive")
+	}
+	if c.Burst < 0 {
+		return fmt.Errorf("rate limit burst must not be negative")
+	}
+	// Allow at least a second worth of requests at once by default.
+	if c.Burst == 0 {
+		c.Burst = int(math.Ceil(c.Rate))
+	}
+	return checkOverflow(c.XXX, "rate limit")
+}
+
+// SilencePolicy defines constraints that new silences have to satisfy.
+type SilencePolicy struct {
+	// MaxDuration is the maximum duration of a silence. Zero means unlimited.
+	MaxDuration model.Duration `yaml:"max_duration,omitempty" json:"max_duration,omitempty"`
+	// MaxDurationExceptions override the maximum duration for silences
+	// whose matchers satisfy the exception's matchers. The first matching
+	// exception applies.
+	MaxDurationExceptions []*SilenceDurationException `yaml:"max_duration_exceptions,omitempty" json:"max_duration_exceptions,omitempty"`
+	// Approval defines which silences have to be approved by a second user
+	// before they take effect.
+	Approval *SilenceApprovalPolicy `yaml:"approval,omitempty" json:"approval,omitempty"`
+	// MaxActivePerCreator limits the number of active and pending silences
+	// of a single creator. Zero means unlimited.
+	MaxActivePerCreator int `yaml:"max_active_per_creator,omitempty" json:"max_active_per_creator,omitempty"`
+	// CreatorLimits overrides MaxActivePerCreator for individual creators.
+	CreatorLimits map[string]int `yaml:"creator_limits,omitempty" json:"creator_limits,omitempty"`
+	// Comment defines requirements for the comments of silences.
+	Comment *SilenceCommentPolicy `yaml:"comment,omitempty" json:"comment,omitempty"`
+
+	// Catches all undefined fields and must be empty after parsing.
+	XXX map[string]interface{} `yaml:",inline" json:"-"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *SilencePolicy) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain SilencePolicy
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	if c.MaxDuration < 0 {
+		return fmt.Errorf("maximum silence duration must not be negative")
+	}
+	if c.MaxActivePerCreator < 0 {
+		return fmt.Errorf("maximum number of silences per creator must not be negative")
+	}
+	for creator, n := range c.CreatorLimits {
+		if n < 0 {
+			return fmt.Errorf("maximum number of silences for creator %q must not be negative", creator)
+		}
+	}
+	return checkOverflow(c.XXX, "silence policy")
+}
+
+// SilenceCommentPolicy defines requirements for the comments of silences.
+type SilenceCommentPolicy struct {
+	// MinLength is the minimum number of characters of a comment.
+	MinLength int `yaml:"min_length,omitempty" json:"min_length,omitempty"`
+	// Regex must match the whole comment, e.g. ".*TICKET-[0-9]+.*".
+	Regex *Regexp `yaml:"regex,omitempty" json:"regex,omitempty"`
+	// Message is returned to users whose comment does not match the regex.
+	Message string `yaml:"message,omitempty" json:"message,omitempty"`
+
+	// Catches all undefined fields and must be empty after parsing.
+	XXX map[string]interface{} `yaml:",inline" json:"-"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *SilenceCommentPolicy) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain SilenceCommentPolicy
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	if c.MinLength < 0 {
+		return fmt.Errorf("minimum comment length must not be negative")
+	}
+	return checkOverflow(c.XXX, "silence comment policy")
+}
+
+// SilenceApprovalPolicy defines when silences are considered broad enough to
+// require the approval of a second user.
+type SilenceApprovalPolicy struct {
+	// MaxAffectedAlerts is the number of currently firing alerts a silence
+	// may match without approval. Zero disables the check.
+	MaxAffectedAlerts int `yaml:"max_affected_alerts,omitempty" json:"max_affected_alerts,omitempty"`
+	// RegexOnly requires approval for silences that only have regular
+	// expression matchers.
+	RegexOnly bool `yaml:"regex_only,omitempty" json:"regex_only,omitempty"`
+
+	// Catches all undefined fields and must be empty after parsing.
+	XXX map[string]interface{} `yaml:",inline" json:"-"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *SilenceApprovalPolicy) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain SilenceApprovalPolicy
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	if c.MaxAffectedAlerts < 0 {
+		return fmt.Errorf("maximum number of affected alerts must not be negative")
+	}
+	return checkOverflow(c.XXX, "silence approval policy")
+}
+
+// SilenceDurationException defines a maximum silence duration for silences
+// that only affect alerts with the given label values. A silence satisfies
+// the exception if it has an equality matcher for every given label whose
+// value is equal to, or matches, the configured value.
+type SilenceDurationException struct {
+	Match       map[string]string `yaml:"match,omitempty" json:"match,omitempty"`
+	MatchRE     map[string]Regexp `yaml:"match_re,omitempty" json:"match_re,omitempty"`
+	MaxDuration model.Duration    `yaml:"max_duration" json:"max_duration"`
+
+	// Catches all undefined fields and must be empty after parsing.
+	XXX map[string]interface{} `yaml:",inline" json:"-"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *SilenceDurationException) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain SilenceDurationException
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	if len(c.Match) == 0 && len(c.MatchRE) == 0 {
+		return fmt.Errorf("silence duration exception must have at least one matcher")
+	}
+	for k := range c.Match {
+		if !model.LabelNameRE.MatchString(k) {
+			return fmt.Errorf("invalid label name %q", k)
+		}
+	}
+	for k := range c.MatchRE {
+		if !model.LabelNameRE.MatchString(k) {
+			return fmt.Errorf("invalid label name %q", k)
+		}
+	}
+	if c.MaxDuration < 0 {
+		return fmt.Errorf("maximum silence duration must not be negative")
+	}
+	return checkOverflow(c.XXX, "silence duration exception")
+}
+
+// StormRule replaces the notifications for alerts matching its matchers
+// with a single notification about an alert storm while too many of them
+// are firing.
+type StormRule struct {
+	// Name identifies the storm in notifications.
+	Name string `yaml:"name" json:"name"`
+	// The alerts the rule applies to.
+	Match   map[string]string `yaml:"match,omitempty" json:"match,omitempty"`
+	MatchRE map[string]Regexp `yaml:"match_re,omitempty" json:"match_re,omitempty"`
+	// A storm starts once more than Threshold matching alerts started firing
+	// within Window. It ends once no more than Threshold matching alerts are
+	// firing.
+	Threshold int            `yaml:"threshold" json:"threshold"`
+	Window    model.Duration `yaml:"window,omitempty" json:"window,omitempty"`
+	// The receiver notified about the storm and how often the notification
+	// is repeated while the storm lasts.
+	Receiver       string         `yaml:"receiver" json:"receiver"`
+	RepeatInterval model.Duration `yaml:"repeat_interval,omitempty" json:"repeat_interval,omitempty"`
+
+	// Catches all undefined fields and must be empty after parsing.
+	XXX map[string]interface{} `yaml:",inline" json:"-"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (r *StormRule) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	r.Window = model.Duration(5 * time.Minute)
+	r.RepeatInterval = model.Duration(time.Hour)
+
+	type plain StormRule
+	if err := unmarshal((*plain)(r)); err != nil {
+		return err
+	}
+	if r.Name == "" {
+		return fmt.Errorf("missing name in storm rule")
+	}
+	if r.Receiver == "" {
+		return fmt.Errorf("missing receiver in storm rule %q", r.Name)
+	}
+	if r.Threshold <= 0 {
+		return fmt.Errorf("threshold of storm rule %q must be positive", r.Name)
+	}
+	if r.Window <= 0 || r.RepeatInterval <= 0 {
+		return fmt.Errorf("window and repeat interval of storm rule %q must be positive", r.Name)
+	}
+	for k := range r.Match {
+		if !model.LabelNameRE.MatchString(k) {
+			return fmt.Errorf("invalid label name %q", k)
+		}
+	}
+	for k := range r.MatchRE {
+		if !model.LabelNameRE.MatchString(k) {
+			return fmt.Errorf("invalid label name %q", k)
+		}
+	}
+	return checkOverflow(r.XXX, "storm rule")
+}
+
+// TopologyConfig configures the inhibition of alerts along a dependency graph
+// of services or hosts. Alerts of a node are inhibited while an alert of any
+// node it directly or indirectly depends on is firing.
+type TopologyConfig struct {
+	// The graph is either read from a YAML or JSON file, fetched from a URL,
+	// or defined in the configuration. It maps each node to the nodes it
+	// depends on.
+	File         string              `yaml:"file,omitempty" json:"file,omitempty"`
+	URL          string              `yaml:"url,omitempty" json:"url,omitempty"`
+	Dependencies map[string][]string `yaml:"dependencies,omitempty" json:"dependencies,omitempty"`
+	// How often the graph is reloaded from the file or URL.
+	RefreshInterval model.Duration `yaml:"refresh_interval,omitempty" json:"refresh_interval,omitempty"`
+	// The label whose value identifies the node of an alert.
+	Label model.LabelName `yaml:"label,omitempty" json:"label,omitempty"`
+
+	// Only alerts matching the source matchers inhibit the alerts of
+	// dependent nodes and only alerts matching the target matchers are
+	// inhibited.
+	SourceMatch   map[string]string `yaml:"source_match,omitempty" json:"source_match,omitempty"`
+	SourceMatchRE map[string]Regexp `yaml:"source_match_re,omitempty" json:"source_match_re,omitempty"`
+	TargetMatch   map[string]string `yaml:"target_match,omitempty" json:"target_match,omitempty"`
+	TargetMatchRE map[string]Regexp `yaml:"target_match_re,omitempty" json:"target_match_re,omitempty"`
+
+	// Catches all undefined fields and must be empty after parsing.
+	XXX map[string]interface{} `yaml:",inline" json:"-"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *TopologyConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	c.RefreshInterval = model.Duration(5 * time.Minute)
+	c.Label = "service"
+
+	type plain TopologyConfig
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	sources := 0
+	for _, set := range []bool{c.File != "", c.URL != "", len(c.Dependencies) > 0} {
+		if set {
+			sources++
+		}
+	}
+	if sources != 1 {
+		return fmt.Errorf("topology must have exactly one of file, url, and dependencies")
+	}
+	if c.RefreshInterval <= 0 {
+		return fmt.Errorf("topology refresh interval must be positive")
+	}
+	if !c.Label.IsValid() {
+		return fmt.Errorf("invalid topology label name %q", c.Label)
+	}
+	for _, m := range []map[string]string{c.SourceMatch, c.TargetMatch} {
+		for k := range m {
+			if !model.LabelNameRE.MatchString(k) {
+				return fmt.Errorf("invalid label name %q", k)
+			}
+		}
+	}
+	for _, m := range []map[string]Regexp{c.SourceMatchRE, c.TargetMatchRE} {
+		for k := range m {
+			if !model.LabelNameRE.MatchString(k) {
+				return fmt.Errorf("invalid label name %q", k)
+			}
+		}
+	}
+	return checkOverflow(c.XXX, "topology config")
+}
+
+// MaintenanceCalendar defines a calendar of maintenance windows during which
+// alerts are silenced. Silences are created from the matcher templates for
+// every upcoming window and expired if the window is removed.
+type MaintenanceCalendar struct {
+	// Name identifies the calendar as the creator of its silences.
+	Name string `yaml:"name" json:"name"`
+	// URL of an iCalendar feed, e.g. the export of a CalDAV calendar.
+	URL string `yaml:"url,omitempty" json:"url,omitempty"`
+	// Windows defines maintenance windows directly in the configuration.
+	Windows []*MaintenanceWindow `yaml:"windows,omitempty" json:"windows,omitempty"`
+	// How often the calendar is synchronized with the silences.
+	RefreshInterval model.Duration `yaml:"refresh_interval,omitempty" json:"refresh_interval,omitempty"`
+	// Templates of the matchers of the silences created for each window.
+	Matchers []*MatcherTemplate `yaml:"matchers" json:"matchers"`
+
+	// Catches all undefined fields and must be empty after parsing.
+	XXX map[string]interface{} `yaml:",inline" json:"-"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *MaintenanceCalendar) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	c.RefreshInterval = model.Duration(5 * time.Minute)
+
+	type plain MaintenanceCalendar
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	if c.Name == "" {
+		return fmt.Errorf("missing name in maintenance calendar")
+	}
+	if (c.URL == "") == (len(c.Windows) == 0) {
+		return fmt.Errorf("maintenance calendar %q must have either a URL or windows", c.Name)
+	}
+	if c.RefreshInterval <= 0 {
+		return fmt.Errorf("refresh interval of maintenance calendar %q must be positive", c.Name)
+	}
+	if len(c.Matchers) == 0 {
+		return fmt.Errorf("maintenance calendar %q must have at least one matcher", c.Name)
+	}
+	ids := map[string]struct{}{}
+	for _, w := range c.Windows {
+		if _, ok := ids[w.ID]; ok {
+			return fmt.Errorf("duplicated window %q in maintenance calendar %q", w.ID, c.Name)
+		}
+		ids[w.ID] = struct{}{}
+	}
+	return checkOverflow(c.XXX, "maintenance calendar")
+}
+
+// MaintenanceWindow is a single maintenance window of a calendar.
+type MaintenanceWindow struct {
+	ID          string            `yaml:"id" json:"id"`
+	Summary     string            `yaml:"summary,omitempty" json:"summary,omitempty"`
+	Description string            `yaml:"description,omitempty" json:"description,omitempty"`
+	Location    string            `yaml:"location,omitempty" json:"location,omitempty"`
+	Labels      map[string]string `yaml:"labels,omitempty" json:"labels,omitempty"`
+	StartsAt    Time              `yaml:"starts_at" json:"starts_at"`
+	EndsAt      Time              `yaml:"ends_at" json:"ends_at"`
+
+	// Catches all undefined fields and must be empty after parsing.
+	XXX map[string]interface{} `yaml:",inline" json:"-"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *MaintenanceWindow) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain MaintenanceWindow
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	if c.ID == "" {
+		return fmt.Errorf("missing id in maintenance window")
+	}
+	if c.StartsAt.IsZero() || c.EndsAt.IsZero() {
+		return fmt.Errorf("maintenance window %q must have a start and end time", c.ID)
+	}
+	if !c.EndsAt.After(c.StartsAt.Time) {
+		return fmt.Errorf("maintenance window %q must end after it starts", c.ID)
+	}
+	return checkOverflow(c.XXX, "maintenance window")
+}
+
+// MatcherTemplate is a silence matcher whose value is a template.
+type MatcherTemplate struct {
+	Name    string `yaml:"name" json:"name"`
+	Value   string `yaml:"value" json:"value"`
+	IsRegex bool   `yaml:"regex,omitempty" json:"regex,omitempty"`
+
+	// Catches all undefined fields and must be empty after parsing.
+	XXX map[string]interface{} `yaml:",inline" json:"-"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *MatcherTemplate) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain MatcherTemplate
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	if !model.LabelNameRE.MatchString(c.Name) {
+		return fmt.Errorf("invalid label name %q in matcher template", c.Name)
+	}
+	return checkOverflow(c.XXX, "matcher template")
+}
+
+// InhibitRule defines an inhibition rule that mutes alerts that match the
+// target labels if an alert matching the source labels exists.
+// Both alerts have to have a set of labels being equal.
+type InhibitRule struct {
+	// SourceMatch defines a set of labels that have to equal the given
+	// value for source alerts.
+	SourceMatch map[string]string `yaml:"source_match" json:"source_match"`
+	// SourceMatchRE defines pairs like SourceMatch but does regular expression
+	// matching.
+	SourceMatchRE map[string]Regexp `yaml:"source_match_re" json:"source_match_re"`
+	// TargetMatch defines a set of labels that have to equal the given
+	// value for target alerts.
+	TargetMatch map[string]string `yaml:"target_match" json:"target_match"`
+	// TargetMatchRE defines pairs like TargetMatch but does regular expression
+	// matching.
+	TargetMatchRE map[string]Regexp `yaml:"target_match_re" json:"target_match_re"`
+	// A set of labels that must be equal between the source and target alert
+	// for them to be a match.
+	Equal []*EqualLabel `yaml:"equal" json:"equal"`
+
+	// Catches all undefined fields and must be empty after parsing.
+	XXX map[string]interface{} `yaml:",inline" json:"-"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (r *InhibitRule) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain InhibitRule
+	if err := unmarshal((*plain)(r)); err != nil {
+		return err
+	}
+
+	for k := range r.SourceMatch {
+		if !model.LabelNameRE.MatchString(k) {
+			return fmt.Errorf("invalid label name %q", k)
+		}
+	}
+
+	for k := range r.SourceMatchRE {
+		if !model.LabelNameRE.MatchString(k) {
+			return fmt.Errorf("invalid label name %q", k)
+		}
+	}
+
+	for k := range r.TargetMatch {
+		if !model.LabelNameRE.MatchString(k) {
+			return fmt.Errorf("invalid label name %q", k)
+		}
+	}
+
+	for k := range r.TargetMatchRE {
+		if !model.LabelNameRE.MatchString(k) {
+			return fmt.Errorf("invalid label name %q", k)
+		}
+	}
+
+	return checkOverflow(r.XXX, "inhibit rule")
+}
+
+// EqualLabel is a label whose value must be equal in the source and target
+// alerts of an inhibition rule. It is configured as a plain label name or as
+// a mapping that transforms the values before they are compared.
+type EqualLabel struct {
+	Label model.LabelName `yaml:"label" json:"label"`
+	// If set, label values matching the expression are replaced with the
+	// expanded replacement before being compared. Other values are compared
+	// unchanged.
+	Regex       *Regexp `yaml:"regex,omitempty" json:"regex,omitempty"`
+	Replacement string  `yaml:"replacement,omitempty" json:"replacement,omitempty"`
+
+	// Catches all undefined fields and must be empty after parsing.
+	XXX map[string]interface{} `yaml:",inline" json:"-"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (e *EqualLabel) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var name string
+	if err := unmarshal(&name); err == nil {
+		if !model.LabelNameRE.MatchString(name) {
+			return fmt.Errorf("invalid label name %q", name)
+		}
+		e.Label = model.LabelName(name)
+		return nil
+	}
+
+	e.Replacement = "$1"
+
+	type plain EqualLabel
+	if err := unmarshal((*plain)(e)); err != nil {
+		return err
+	}
+	if !model.LabelNameRE.MatchString(string(e.Label)) {
+		return fmt.Errorf("invalid label name %q", e.Label)
+	}
+	return checkOverflow(e.XXX, "equal label")
+}
+
+// MarshalYAML implements the yaml.Marshaler interface.
+func (e EqualLabel) MarshalYAML() (interface{}, error) {
+	if e.Regex == nil {
+		return string(e.Label), nil
+	}
+	type plain EqualLabel
+	return plain(e), nil
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (e EqualLabel) MarshalJSON() ([]byte, error) {
+	if e.Regex == nil {
+		return json.Marshal(e.Label)
+	}
+	type plain EqualLabel
+	return json.Marshal(plain(e))
+}
+
+// Receiver configuration provides configuration on how to contact a receiver.
+type Receiver struct {
+	// A unique identifier for this receiver.
+	Name string `yaml:"name" json:"name"`
+	// DryRun makes notifications for this receiver go through the full
+	// pipeline but only be logged instead of being sent to the integrations.
+	DryRun bool `yaml:"dry_run,omitempty" json:"dry_run,omitempty"`
+	// MaxTemplateAlerts limits the number of alerts exposed to notification
+	// templates and webhook pushes. Firing alerts are kept first. Zero means
+	// no limit.
+	MaxTemplateAlerts int `yaml:"max_template_alerts,omitempty" json:"max_template_alerts,omitempty"`
+	// DigestInterval makes notifications about all groups that changed
+	// within the interval be sent as a single combined notification.
+	DigestInterval model.Duration `yaml:"digest_interval,omitempty" json:"digest_interval,omitempty"`
+	// MaxConcurrentNotifications limits the number of group notifications
+	// sent to this receiver at the same time. Zero means no limit.
+	MaxConcurrentNotifications int `yaml:"max_concurrent_notifications,omitempty" json:"max_concurrent_notifications,omitempty"`
+	// HTTPClient configures the HTTP client shared by the integrations of
+	// this receiver. Defaults to the global HTTP client configuration.
+	HTTPClient *HTTPClientConfig `yaml:"http_client,omitempty" json:"http_client,omitempty"`
+	// Locale selects the language of the default templates for this
+	// receiver. Defaults to the global locale.
+	Locale string `yaml:"locale,omitempty" json:"locale,omitempty"`
+
+	EmailConfigs     []*EmailConfig     `yaml:"email_configs,omitempty" json:"email_configs,omitempty"`
+	PagerdutyConfigs []*PagerdutyConfig `yaml:"pagerduty_configs,omitempty" json:"pagerduty_configs,omitempty"`
+	HipchatConfigs   []*HipchatConfig   `yaml:"hipchat_configs,omitempty" json:"hipchat_configs,omitempty"`
+	SlackConfigs     []*SlackConfig     `yaml:"slack_configs,omitempty" json:"slack_configs,omitempty"`
+	WebhookConfigs   []*WebhookConfig   `yaml:"webhook_configs,omitempty" json:"webhook_configs,omitempty"`
+	OpsGenieConfigs  []*OpsGenieConfig  `yaml:"opsgenie_configs,omitempty" json:"opsgenie_configs,omitempty"`
+	PushoverConfigs  []*PushoverConfig  `yaml:"pushover_configs,omitempty" json:"pushover_configs,omitempty"`
+	VictorOpsConfigs []*VictorOpsConfig `yaml:"victorops_configs,omitempty" json:"victorops_configs,omitempty"`
+
+	// Catches all undefined fields and must be empty after parsing.
+	XXX map[string]interface{} `yaml:",inline" json:"-"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *Receiver) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain Receiver
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	if c.Name == "" {
+		return fmt.Errorf("missing name in receiver")
+	}
+	if c.MaxTemplateAlerts < 0 {
+		return fmt.Errorf("max_template_alerts of receiver %q must not be negative", c.Name)
+	}
+	if c.Locale != "" && !patLocale.MatchString(c.Locale) {
+		return fmt.Errorf("invalid locale %q of receiver %q", c.Locale, c.Name)
+	}
+	if c.DigestInterval < 0 {
+		return fmt.Errorf("digest_interval of receiver %q must not be negative", c.Name)
+	}
+	if c.MaxConcurrentNotifications < 0 {
+		return fmt.Errorf("max_concurrent_notifications of receiver %q must not be negative", c.Name)
+	}
+	for _, nc := range c.notifierConfigs() {
+		if nc.VMaxConcurrentNotifications < 0 {
+			return fmt.Errorf("max_concurrent_notifications of the integrations of receiver %q must not be negative", c.Name)
+		}
+	}
+	return checkOverflow(c.XXX, "receiver config")
+}
+
+// notifierConfigs returns the options common to all integrations of the
+// receiver.
+func (c *Receiver) notifierConfigs() []*NotifierConfig {
+	var res []*NotifierConfig
+	for _, nc := range c.EmailConfigs {
+		res = append(res, &nc.NotifierConfig)
+	}
+	for _, nc := range c.PagerdutyConfigs {
+		res = append(res, &nc.NotifierConfig)
+	}
+	for _, nc := range c.HipchatConfigs {
+		res = append(res, &nc.NotifierConfig)
+	}
+	for _, nc := range c.SlackConfigs {
+		res = append(res, &nc.NotifierConfig)
+	}
+	for _, nc := range c.WebhookConfigs {
+		res = append(res, &nc.NotifierConfig)
+	}
+	for _, nc := range c.OpsGenieConfigs {
+		res = append(res, &nc.NotifierConfig)
+	}
+	for _, nc := range c.PushoverConfigs {
+		res = append(res, &nc.NotifierConfig)
+	}
+	for _, nc := range c.VictorOpsConfigs {
+		res = append(res, &nc.NotifierConfig)
+	}
+	return res
+}
+
+// DefaultHTTPClientConfig defines the default connection pooling of the HTTP
+// clients of receivers.
+var DefaultHTTPClientConfig = HTTPClientConfig{
+	KeepAlive:           model.Duration(30 * time.Second),
+	IdleConnTimeout:     model.Duration(90 * time.Second),
+	MaxIdleConns:        100,
+	MaxIdleConnsPerHost: 10,
+}
+
+// HTTPClientConfig configures the connection pooling of an HTTP client. The
+// client is reused for all notifications of a receiver, so connections to
+// the same endpoints are kept open between notifications.
+type HTTPClientConfig struct {
+	// Whether connections are closed after each request instead of being
+	// reused.
+	DisableKeepAlives bool `yaml:"disable_keep_alives,omitempty" json:"disable_keep_alives,omitempty"`
+	// The interval of TCP keep-alive probes on open connections.
+	KeepAlive model.Duration `yaml:"keep_alive,omitempty" json:"keep_alive,omitempty"`
+	// How long idle connections are kept open.
+	IdleConnTimeout model.Duration `yaml:"idle_conn_timeout,omitempty" json:"idle_conn_timeout,omitempty"`
+	// The maximum number of idle connections kept open in total and per
+	// host. Zero means no limit for the total.
+	MaxIdleConns        int `yaml:"max_idle_conns,omitempty" json:"max_idle_conns,omitempty"`
+	MaxIdleConnsPerHost int `yaml:"max_idle_conns_per_host,omitempty" json:"max_idle_conns_per_host,omitempty"`
+	// The maximum number of connections per host. Zero means no limit.
+	MaxConnsPerHost int `yaml:"max_conns_per_host,omitempty" json:"max_conns_per_host,omitempty"`
+	// Whether HTTP/2, which is otherwise negotiated for TLS connections, is
+	// disabled.
+	DisableHTTP2 bool `yaml:"disable_http2,omitempty" json:"disable_http2,omitempty"`
+
+	// Catches all undefined fields and must be empty after parsing.
+	XXX map[string]interface{} `yaml:",inline" json:"-"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *HTTPClientConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultHTTPClientConfig
+	type plain HTTPClientConfig
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	if c.KeepAlive < 0 || c.IdleConnTimeout < 0 {
+		return fmt.Errorf("keep_alive and idle_conn_timeout of the HTTP client must not be negative")
+	}
+	if c.MaxIdleConns < 0 || c.MaxIdleConnsPerHost < 0 || c.MaxConnsPerHost < 0 {
+		return fmt.Errorf("connection limits of the HTTP client must not be negative")
+	}
+	return checkOverflow(c.XXX, "http client config")
+}
+
+// Regexp encapsulates a regexp.Regexp and makes it YAML marshalable.
+type Regexp struct {
+	*regexp.Regexp
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (re *Regexp) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+	regex, err := regexp.Compile("^(?:" + s + ")$")
+	if err != nil {
+		return err
+	}
+	re.Regexp = regex
+	return nil
+}
+
+// MarshalYAML implements the yaml.Marshaler interface.
+func (re *Regexp) MarshalYAML() (interface{}, error) {
+	if re != nil {
+		return re.String(), nil
+	}
+	return nil, nil
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (re Regexp) MarshalJSON() ([]byte, error) {
+	if re.Regexp != nil {
+		return json.Marshal(re.String())
+	}
+	return nil, nil
+}
+
+// Time encapsulates a time.Time and unmarshals it from RFC3339 strings.
+type Time struct {
+	time.Time
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (t *Time) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+	v, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return err
+	}
+	t.Time = v
+	return nil
+}
+
+// MarshalYAML implements the yaml.Marshaler interface.
+func (t Time) MarshalYAML() (interface{}, error) {
+	return t.Format(time.RFC3339), nil
 }
@@ -14,8 +14,13 @@
 package config
 
 import (
+	"encoding/json"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/prometheus/common/model"
 	"gopkg.in/yaml.v2"
 )
 
@@ -37,3 +42,370 @@ route:
 		t.Errorf("\nexpected:\n%v\ngot:\n%v", expected, err.Error())
 	}
 }
+
+func TestInhibitRuleEqualLabels(t *testing.T) {
+	in := `
+source_match:
+  alertname: NodeDown
+equal:
+- cluster
+- label: instance
+  regex: '(.+):\d+'
+`
+	r := &InhibitRule{}
+	if err := yaml.Unmarshal([]byte(in), r); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(r.Equal) != 2 {
+		t.Fatalf("expected 2 equal labels, got %d", len(r.Equal))
+	}
+	if r.Equal[0].Label != "cluster" || r.Equal[0].Regex != nil {
+		t.Errorf("unexpected plain equal label %+v", r.Equal[0])
+	}
+	if r.Equal[1].Label != "instance" || r.Equal[1].Regex == nil || r.Equal[1].Replacement != "$1" {
+		t.Errorf("unexpected transformed equal label %+v", r.Equal[1])
+	}
+
+	out, err := yaml.Marshal(r)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	r2 := &InhibitRule{}
+	if err := yaml.Unmarshal(out, r2); err != nil {
+		t.Fatalf("unexpected error unmarshaling marshaled rule: %s", err)
+	}
+	if r2.Equal[0].Label != "cluster" || r2.Equal[0].Regex != nil || r2.Equal[1].Regex == nil {
+		t.Errorf("equal labels changed after marshaling:\n%s", out)
+	}
+
+	in = `
+equal:
+- label: "in-valid"
+`
+	if err := yaml.Unmarshal([]byte(in), &InhibitRule{}); err == nil {
+		t.Errorf("expected error for invalid label name")
+	}
+}
+
+func TestRouteEscalations(t *testing.T) {
+	in := `
+route:
+  receiver: team-slack
+  escalations:
+  - receiver: team-pager
+    after: 15m
+  - receiver: manager-sms
+    after: 1h
+receivers:
+- name: team-slack
+- name: team-pager
+`
+	conf := &Config{}
+	err := yaml.Unmarshal([]byte(in), conf)
+
+	expected := `Undefined receiver "manager-sms" used in escalation`
+
+	if err == nil {
+		t.Fatalf("no error returned, expected:\n%v", expected)
+	}
+	if err.Error() != expected {
+		t.Errorf("\nexpected:\n%v\ngot:\n%v", expected, err.Error())
+	}
+
+	if err := yaml.Unmarshal([]byte(in+"- name: manager-sms\n"), &Config{}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	in = `
+escalations:
+- receiver: manager-sms
+  after: 1h
+- receiver: team-pager
+  after: 15m
+`
+	if err := yaml.Unmarshal([]byte(in), &Route{}); err == nil {
+		t.Errorf("expected error for unordered escalations")
+	}
+}
+
+func TestDispatcherConfigDefaults(t *testing.T) {
+	c := &DispatcherConfig{}
+	if err := yaml.Unmarshal([]byte("workers: 4\n"), c); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if c.Workers != 4 || c.QueueCapacity != 200 || c.MaxConcurrentNotifications != 0 {
+		t.Errorf("unexpected defaults %+v", c)
+	}
+
+	if err := yaml.Unmarshal([]byte("queue_capacity: 0\n"), &DispatcherConfig{}); err == nil {
+		t.Errorf("expected error for zero queue capacity")
+	}
+}
+
+func TestIntegrationConcurrency(t *testing.T) {
+	in := `
+name: team
+webhook_configs:
+- url: http://example.com/
+  max_concurrent_notifications: 2
+`
+	c := &Receiver{}
+	if err := yaml.Unmarshal([]byte(in), c); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if n := c.WebhookConfigs[0].MaxConcurrentNotifications(); n != 2 {
+		t.Errorf("expected integration limit 2 but got %d", n)
+	}
+
+	in = strings.Replace(in, "2", "-1", 1)
+	if err := yaml.Unmarshal([]byte(in), &Receiver{}); err == nil {
+		t.Errorf("expected error for negative integration limit")
+	}
+}
+
+func TestNotificationPriorityDefaults(t *testing.T) {
+	in := `
+values: [critical, warning]
+`
+	c := &NotificationPriorityConfig{}
+	if err := yaml.Unmarshal([]byte(in), c); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if c.Label != "severity" || c.Concurrency != 10 {
+		t.Errorf("unexpected defaults %+v", c)
+	}
+
+	if err := yaml.Unmarshal([]byte("label: severity\n"), &NotificationPriorityConfig{}); err == nil {
+		t.Errorf("expected error for missing values")
+	}
+}
+
+func TestSecretMarshalJSON(t *testing.T) {
+	b, err := json.Marshal(struct{ S Secret }{S: "password"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if string(b) != `{"S":"\u003chidden\u003e"}` {
+		t.Errorf("secret not hidden: %s", b)
+	}
+}
+
+func TestIngestLimits(t *testing.T) {
+	for _, in := range []string{
+		"max_alerts_per_request: -1\n",
+		"max_body_size: -1\n",
+		"max_labels: 10\n",
+	} {
+		if err := yaml.Unmarshal([]byte(in), &IngestLimits{}); err == nil {
+			t.Errorf("expected error for %q", in)
+		}
+	}
+}
+
+func TestIngestAdapter(t *testing.T) {
+	c := &IngestAdapter{}
+	if err := yaml.Unmarshal([]byte("name: aws\ntype: cloudwatch\nlabel_map:\n  InstanceId: instance\n  AWSAccountId: ''\nlabels:\n  source: aws\n"), c); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if c.LabelMap["InstanceId"] != "instance" {
+		t.Errorf("unexpected label map %v", c.LabelMap)
+	}
+
+	for _, in := range []string{
+		"type: grafana\n",
+		"name: a/b\ntype: grafana\n",
+		"name: x\ntype: nagios\n",
+		"name: x\ntype: grafana\nlabel_map:\n  a: not-a-label\n",
+		"name: x\ntype: grafana\nlabels:\n  not-a-label: x\n",
+	} {
+		if err := yaml.Unmarshal([]byte(in), &IngestAdapter{}); err == nil {
+			t.Errorf("expected error for %q", in)
+		}
+	}
+
+	in := `
+route:
+  receiver: default
+receivers:
+- name: default
+ingest_adapters:
+- name: grafana
+  type: grafana
+- name: grafana
+  type: cloudwatch
+`
+	if _, err := Load(in); err == nil {
+		t.Errorf("expected error for duplicate ingest adapters")
+	}
+}
+
+func TestCORSConfig(t *testing.T) {
+	c := &CORSConfig{}
+	if err := yaml.Unmarshal([]byte("allowed_origins:\n- https://dash.example.com\nallowed_methods: [get]\n"), c); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(c.AllowedMethods, []string{"GET"}) {
+		t.Errorf("unexpected methods %v", c.AllowedMethods)
+	}
+	if len(c.AllowedHeaders) == 0 {
+		t.Errorf("expected default headers")
+	}
+
+	for _, in := range []string{
+		"allowed_methods: [GET]\n",
+		"allowed_origins: [dash.example.com]\n",
+		"allowed_origins:\n- https://dash.example.com/path\n",
+		"allowed_origins: ['*']\nallow_credentials: true\n",
+	} {
+		if err := yaml.Unmarshal([]byte(in), &CORSConfig{}); err == nil {
+			t.Errorf("expected error for %q", in)
+		}
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	c := &RateLimit{}
+	if err := yaml.Unmarshal([]byte("rate: 2.5\n"), c); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if c.Burst != 3 {
+		t.Errorf("expected default burst 3, got %d", c.Burst)
+	}
+
+	for _, in := range []string{
+		"burst: 10\n",
+		"rate: -1\n",
+		"rate: 1\nburst: -1\n",
+		"rate: 1\nunknown: 1\n",
+	} {
+		if err := yaml.Unmarshal([]byte(in), &RateLimit{}); err == nil {
+			t.Errorf("expected error for %q", in)
+		}
+	}
+}
+
+func TestOIDCConfig(t *testing.T) {
+	in := `
+issuer: https://accounts.example.com
+client_id: alertmanager
+client_secret: s3cret
+`
+	c := &OIDCConfig{}
+	if err := yaml.Unmarshal([]byte(in), c); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if c.UsernameClaim != "email" || c.GroupsClaim != "groups" || c.SessionDuration != model.Duration(12*time.Hour) {
+		t.Errorf("unexpected defaults %+v", c)
+	}
+
+	for _, in := range []string{
+		"client_id: a\nclient_secret: b\n",
+		"issuer: accounts.example.com\nclient_id: a\nclient_secret: b\n",
+		"issuer: https://accounts.example.com\nclient_secret: b\n",
+		"issuer: https://accounts.example.com\nclient_id: a\n",
+	} {
+		if err := yaml.Unmarshal([]byte(in), &OIDCConfig{}); err == nil {
+			t.Errorf("expected error for %q", in)
+		}
+	}
+}
+
+func TestHTTPClientConfig(t *testing.T) {
+	in := `
+global:
+  http_client:
+    max_idle_conns_per_host: 20
+route:
+  receiver: team-a
+receivers:
+- name: team-a
+- name: team-b
+  http_client:
+    disable_http2: true
+`
+	c, err := Load(in)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	a, b := c.Receivers[0].HTTPClient, c.Receivers[1].HTTPClient
+	if a == nil || a.MaxIdleConnsPerHost != 20 || a.KeepAlive != model.Duration(30*time.Second) {
+		t.Errorf("expected receiver to inherit the global HTTP client config but got %+v", a)
+	}
+	if b == nil || !b.DisableHTTP2 || b.MaxIdleConnsPerHost != 10 {
+		t.Errorf("expected receiver HTTP client config with defaults but got %+v", b)
+	}
+
+	for _, in := range []string{
+		"max_idle_conns: -1\n",
+		"keep_alive: -1s\n",
+	} {
+		if err := yaml.Unmarshal([]byte(in), &HTTPClientConfig{}); err == nil {
+			t.Errorf("expected error for %q", in)
+		}
+	}
+}
+
+func TestLocale(t *testing.T) {
+	in := `
+global:
+  locale: de
+route:
+  receiver: team-a
+receivers:
+- name: team-a
+- name: team-b
+  locale: pt-BR
+`
+	c, err := Load(in)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if l := c.Receivers[0].Locale; l != "de" {
+		t.Errorf("expected receiver to inherit the global locale but got %q", l)
+	}
+	if l := c.Receivers[1].Locale; l != "pt-BR" {
+		t.Errorf("expected receiver locale pt-BR but got %q", l)
+	}
+
+	if _, err := Load("global:\n  locale: German\n"); err == nil {
+		t.Errorf("expected error for invalid locale")
+	}
+}
+
+func TestTemplateLimits(t *testing.T) {
+	c, err := Load("template_limits:\n  max_depth: 20\nroute:\n  receiver: default\nreceivers:\n- name: default\n")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if l := c.TemplateLimits; l.MaxDepth != 20 || l.Timeout != DefaultTemplateLimits.Timeout {
+		t.Errorf("expected template limits with defaults but got %+v", l)
+	}
+
+	c, err = Load("route:\n  receiver: default\nreceivers:\n- name: default\n")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if l := c.TemplateLimits; l == nil || l.MaxOutputSize != DefaultTemplateLimits.MaxOutputSize {
+		t.Errorf("expected default template limits but got %+v", l)
+	}
+
+	if _, err := Load("template_limits:\n  timeout: -1s\n"); err == nil {
+		t.Errorf("expected error for negative timeout")
+	}
+}
+
+func TestDashboardLinks(t *testing.T) {
+	for _, in := range []string{
+		"log_search_url: https://logs.example.com/search\n",
+		"trace_url: https://traces.example.com/trace\n",
+		"grafana_url: \"http://[::1\"\n",
+	} {
+		if err := yaml.Unmarshal([]byte(in), &DashboardLinksConfig{}); err == nil {
+			t.Errorf("expected error for %q", in)
+		}
+	}
+	in := "grafana_url: https://grafana.example.com\ntrace_url: https://traces.example.com/trace/{trace_id}\n"
+	if err := yaml.Unmarshal([]byte(in), &DashboardLinksConfig{}); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+}
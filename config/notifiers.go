@@ -120,12 +120,21 @@ var (
 // NotifierConfig contains base options common across all notifier configurations.
 type NotifierConfig struct {
 	VSendResolved bool `yaml:"send_resolved" json:"send_resolved"`
+	// VMaxConcurrentNotifications limits the number of group notifications
+	// sent through this integration at the same time. Zero means no limit.
+	VMaxConcurrentNotifications int `yaml:"max_concurrent_notifications,omitempty" json:"max_concurrent_notifications,omitempty"`
 }
 
 func (nc *NotifierConfig) SendResolved() bool {
 	return nc.VSendResolved
 }
 
+// MaxConcurrentNotifications returns the number of group notifications that
+// may be sent through the integration at the same time.
+func (nc *NotifierConfig) MaxConcurrentNotifications() int {
+	return nc.VMaxConcurrentNotifications
+}
+
 // EmailConfig configures notifications via mail.
 type EmailConfig struct {
 	NotifierConfig `yaml:",inline" json:",inline"`
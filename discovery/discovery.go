@@ -0,0 +1,164 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package discovery finds the addresses of mesh peers in DNS records or in
+// the endpoints of a Kubernetes service so that peers of autoscaled or
+// rescheduled deployments need not be listed statically.
+//
+// DNS names are given in one of two forms:
+//
+//	dns+<host>:<port>      A and AAAA records of the host, with the given port
+//	dnssrv+<name>          SRV records of the name, with their targets and ports
+package discovery
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/common/log"
+)
+
+// Resolver resolves peer names to addresses.
+type Resolver struct {
+	lookupHost func(host string) ([]string, error)
+	lookupSRV  func(service, proto, name string) (string, []*net.SRV, error)
+}
+
+// NewResolver returns a resolver using the system's DNS resolver.
+func NewResolver() *Resolver {
+	return &Resolver{
+		lookupHost: net.LookupHost,
+		lookupSRV:  net.LookupSRV,
+	}
+}
+
+// Validate returns an error if the name is not of a supported form.
+func Validate(name string) error {
+	switch {
+	case strings.HasPrefix(name, "dns+"):
+		if _, _, err := net.SplitHostPort(strings.TrimPrefix(name, "dns+")); err != nil {
+			return fmt.Errorf("invalid peer name %q: %s", name, err)
+		}
+	case strings.HasPrefix(name, "dnssrv+"):
+		if strings.TrimPrefix(name, "dnssrv+") == "" {
+			return fmt.Errorf("invalid peer name %q: missing SRV name", name)
+		}
+	default:
+		return fmt.Errorf("invalid peer name %q: expected dns+<host>:<port> or dnssrv+<name>", name)
+	}
+	return nil
+}
+
+// Resolve returns the sorted addresses of the peers with the given names. It
+// returns the addresses it could resolve along with the error of the first
+// name that failed.
+func (r *Resolver) Resolve(names []string) ([]string, error) {
+	var (
+		seen  = map[string]struct{}{}
+		res   []string
+		first error
+	)
+	for _, name := range names {
+		addrs, err := r.resolve(name)
+		if err != nil {
+			if first == nil {
+				first = err
+			}
+			continue
+		}
+		for _, a := range addrs {
+			if _, ok := seen[a]; !ok {
+				seen[a] = struct{}{}
+				res = append(res, a)
+			}
+		}
+	}
+	sort.Strings(res)
+	return res, first
+}
+
+func (r *Resolver) resolve(name string) ([]string, error) {
+	if err := Validate(name); err != nil {
+		return nil, err
+	}
+	if strings.HasPrefix(name, "dnssrv+") {
+		_, srvs, err := r.lookupSRV("", "", strings.TrimPrefix(name, "dnssrv+"))
+		if err != nil {
+			return nil, fmt.Errorf("looking up SRV records of %q: %s", name, err)
+		}
+		res := make([]string, 0, len(srvs))
+		for _, srv := range srvs {
+			res = append(res, net.JoinHostPort(strings.TrimSuffix(srv.Target, "."), strconv.Itoa(int(srv.Port))))
+		}
+		return res, nil
+	}
+
+	host, port, _ := net.SplitHostPort(strings.TrimPrefix(name, "dns+"))
+	ips, err := r.lookupHost(host)
+	if err != nil {
+		return nil, fmt.Errorf("looking up %q: %s", name, err)
+	}
+	res := make([]string, 0, len(ips))
+	for _, ip := range ips {
+		res = append(res, net.JoinHostPort(ip, port))
+	}
+	return res, nil
+}
+
+// Run resolves the names every interval and passes the resolved addresses,
+// along with the static ones, to connect whenever they change. It returns
+// when stopc is closed.
+func (r *Resolver) Run(names, static []string, interval time.Duration, connect func([]string), stopc <-chan struct{}) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	var last []string
+	for {
+		addrs, err := r.Resolve(names)
+		if err != nil {
+			log.Warnf("Resolving mesh peers failed: %s", err)
+		}
+		// Keep the previous peers if resolving failed entirely rather than
+		// dropping all of them.
+		if err == nil || len(addrs) > 0 {
+			addrs = append(addrs, static...)
+			if !equal(addrs, last) {
+				log.Infof("Resolved mesh peers %v", addrs)
+				connect(addrs)
+				last = addrs
+			}
+		}
+
+		select {
+		case <-stopc:
+			return
+		case <-t.C:
+		}
+	}
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,108 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package discovery
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	for _, name := range []string{"dns+am.example.com:6783", "dnssrv+_mesh._tcp.am.example.com"} {
+		require.NoError(t, Validate(name), name)
+	}
+	for _, name := range []string{"am.example.com:6783", "dns+am.example.com", "dnssrv+"} {
+		require.Error(t, Validate(name), name)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	r := &Resolver{
+		lookupHost: func(host string) ([]string, error) {
+			if host == "am.example.com" {
+				return []string{"10.0.0.2", "10.0.0.1"}, nil
+			}
+			return nil, errors.New("no such host")
+		},
+		lookupSRV: func(service, proto, name string) (string, []*net.SRV, error) {
+			return "", []*net.SRV{
+				{Target: "am-0.am.example.com.", Port: 6783},
+				{Target: "am-1.am.example.com.", Port: 6783},
+			}, nil
+		},
+	}
+
+	addrs, err := r.Resolve([]string{"dns+am.example.com:6783", "dnssrv+_mesh._tcp.am.example.com", "dns+am.example.com:6783"})
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"10.0.0.1:6783",
+		"10.0.0.2:6783",
+		"am-0.am.example.com:6783",
+		"am-1.am.example.com:6783",
+	}, addrs)
+
+	// Names that can be resolved are returned along with the error.
+	addrs, err = r.Resolve([]string{"dns+unknown.example.com:6783", "dns+am.example.com:6783"})
+	require.Error(t, err)
+	require.Len(t, addrs, 2)
+}
+
+func TestRun(t *testing.T) {
+	var (
+		mtx   sync.Mutex
+		ips   = []string{"10.0.0.1"}
+		fail  bool
+		calls = make(chan []string, 10)
+	)
+	r := &Resolver{
+		lookupHost: func(host string) ([]string, error) {
+			mtx.Lock()
+			defer mtx.Unlock()
+			if fail {
+				return nil, errors.New("timeout")
+			}
+			return ips, nil
+		},
+	}
+
+	stopc := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		r.Run([]string{"dns+am:6783"}, []string{"static:6783"}, 10*time.Millisecond, func(addrs []string) { calls <- addrs }, stopc)
+		close(done)
+	}()
+
+	require.Equal(t, []string{"10.0.0.1:6783", "static:6783"}, <-calls)
+
+	// Failed lookups keep the peers.
+	mtx.Lock()
+	fail = true
+	mtx.Unlock()
+	time.Sleep(50 * time.Millisecond)
+	require.Len(t, calls, 0)
+
+	mtx.Lock()
+	fail = false
+	ips = []string{"10.0.0.1", "10.0.0.3"}
+	mtx.Unlock()
+	require.Equal(t, []string{"10.0.0.1:6783", "10.0.0.3:6783", "static:6783"}, <-calls)
+
+	close(stopc)
+	<-done
+}
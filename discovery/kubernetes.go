@@ -0,0 +1,312 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package discovery
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/common/log"
+	"golang.org/x/net/context"
+)
+
+const serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+// Kubernetes discovers peers from the endpoints of a Kubernetes service by
+// watching them through the API server.
+type Kubernetes struct {
+	client    *http.Client
+	server    string
+	token     string
+	namespace string
+	service   string
+	port      string
+	// retry is the time to wait before listing the endpoints again after
+	// the watch failed.
+	retry time.Duration
+}
+
+// NewKubernetes returns a discovery of the peers behind the service given as
+// [<namespace>/]<service>[:<port>], where the port is the name or number
+// of a port of the endpoints and defaults to defaultPort. It uses the
+// service account of the pod it runs in to access the API server. The
+// namespace defaults to the namespace of the pod.
+func NewKubernetes(service, defaultPort string) (*Kubernetes, error) {
+	k := &Kubernetes{port: defaultPort, retry: 5 * time.Second}
+	if err := k.parseService(service); err != nil {
+		return nil, err
+	}
+
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, fmt.Errorf("not running in a Kubernetes cluster")
+	}
+	k.server = "https://" + net.JoinHostPort(host, port)
+
+	token, err := ioutil.ReadFile(serviceAccountDir + "/token")
+	if err != nil {
+		return nil, err
+	}
+	k.token = strings.TrimSpace(string(token))
+
+	if k.namespace == "" {
+		ns, err := ioutil.ReadFile(serviceAccountDir + "/namespace")
+		if err != nil {
+			return nil, err
+		}
+		k.namespace = strings.TrimSpace(string(ns))
+	}
+
+	ca, err := ioutil.ReadFile(serviceAccountDir + "/ca.crt")
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, fmt.Errorf("no certificates in %s/ca.crt", serviceAccountDir)
+	}
+	k.client = &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{RootCAs: pool},
+		},
+	}
+	return k, nil
+}
+
+func (k *Kubernetes) parseService(service string) error {
+	s := service
+	if i := strings.Index(s, "/"); i >= 0 {
+		k.namespace, s = s[:i], s[i+1:]
+	}
+	if i := strings.Index(s, ":"); i >= 0 {
+		k.port, s = s[i+1:], s[:i]
+	}
+	if s == "" || k.port == "" {
+		return fmt.Errorf("invalid Kubernetes service %q, expected [<namespace>/]<service>[:<port>]", service)
+	}
+	k.service = s
+	return nil
+}
+
+// endpoints is the part of a Kubernetes Endpoints object holding the
+// addresses of a service.
+type endpoints struct {
+	Metadata struct {
+		ResourceVersion string `json:"resourceVersion"`
+	} `json:"metadata"`
+	Subsets []struct {
+		Addresses         []endpointAddress `json:"addresses"`
+		NotReadyAddresses []endpointAddress `json:"notReadyAddresses"`
+		Ports             []struct {
+			Name string `json:"name"`
+			Port int    `json:"port"`
+		} `json:"ports"`
+	} `json:"subsets"`
+}
+
+type endpointAddress struct {
+	IP string `json:"ip"`
+}
+
+// addresses returns the sorted addresses of the endpoints with the given
+// port. Addresses of pods that are not ready are included since new
+// instances only report readiness once they joined the mesh.
+func (e *endpoints) addresses(port string) []string {
+	var res []string
+	for _, ss := range e.Subsets {
+		p := ""
+		for _, sp := range ss.Ports {
+			if sp.Name == port || strconv.Itoa(sp.Port) == port {
+				p = strconv.Itoa(sp.Port)
+				break
+			}
+		}
+		if p == "" {
+			if _, err := strconv.Atoi(port); err != nil {
+				continue
+			}
+			p = port
+		}
+		for _, a := range append(ss.Addresses, ss.NotReadyAddresses...) {
+			res = append(res, net.JoinHostPort(a.IP, p))
+		}
+	}
+	sort.Strings(res)
+	return res
+}
+
+// Run watches the endpoints of the service and passes their addresses,
+// along with the static ones, to connect whenever they change. It returns
+// when stopc is closed.
+func (k *Kubernetes) Run(static []string, connect func([]string), stopc <-chan struct{}) {
+	var last []string
+	update := func(e *endpoints) {
+		addrs := append(e.addresses(k.port), static...)
+		if !equal(addrs, last) {
+			log.Infof("Kubernetes endpoints of %s/%s changed, mesh peers are %v", k.namespace, k.service, addrs)
+			connect(addrs)
+			last = addrs
+		}
+	}
+
+	for {
+		// The API server ends watches after a while, after which the
+		// endpoints are listed again right away.
+		var retry <-chan time.Time
+		if err := k.watch(update, stopc); err != nil {
+			log.Warnf("Watching Kubernetes endpoints of %s/%s failed: %s", k.namespace, k.service, err)
+			retry = time.After(k.retry)
+		} else {
+			retry = time.After(0)
+		}
+		select {
+		case <-stopc:
+			return
+		case <-retry:
+		}
+	}
+}
+
+// Addresses returns the current addresses of the service's endpoints.
+func (k *Kubernetes) Addresses() ([]string, error) {
+	e, err := k.list(nil)
+	if err != nil {
+		return nil, err
+	}
+	return e.addresses(k.port), nil
+}
+
+func (k *Kubernetes) path() string {
+	return fmt.Sprintf("/api/v1/namespaces/%s/endpoints", url.PathEscape(k.namespace))
+}
+
+func (k *Kubernetes) list(stopc <-chan struct{}) (*endpoints, error) {
+	resp, err := k.get(k.path()+"/"+url.PathEscape(k.service), nil, stopc)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var e endpoints
+	if err := json.NewDecoder(resp.Body).Decode(&e); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// watch lists the endpoints and then watches them for changes until the
+// watch ends or stopc is closed.
+func (k *Kubernetes) watch(update func(*endpoints), stopc <-chan struct{}) error {
+	e, err := k.list(stopc)
+	if err != nil {
+		return err
+	}
+	update(e)
+
+	resp, err := k.get(k.path(), url.Values{
+		"watch":           {"true"},
+		"fieldSelector":   {"metadata.name=" + k.service},
+		"resourceVersion": {e.Metadata.ResourceVersion},
+	}, stopc)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var ev struct {
+			Type   string    `json:"type"`
+			Object endpoints `json:"object"`
+		}
+		if err := dec.Decode(&ev); err != nil {
+			select {
+			case <-stopc:
+				return nil
+			default:
+			}
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		switch ev.Type {
+		case "ADDED", "MODIFIED":
+			update(&ev.Object)
+		case "DELETED":
+			update(&endpoints{})
+		case "ERROR":
+			return fmt.Errorf("watch error event")
+		}
+	}
+}
+
+func (k *Kubernetes) get(path string, q url.Values, stopc <-chan struct{}) (*http.Response, error) {
+	u := k.server + path
+	if q != nil {
+		u += "?" + q.Encode()
+	}
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+k.token)
+	req.Header.Set("Accept", "application/json")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		select {
+		case <-stopc:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	resp, err := k.client.Do(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		cancel()
+		return nil, fmt.Errorf("unexpected status code %d from %s", resp.StatusCode, path)
+	}
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// cancelBody cancels the context of its request when closed.
+type cancelBody struct {
+	io.ReadCloser
+	cancel func()
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
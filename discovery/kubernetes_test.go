@@ -0,0 +1,106 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package discovery
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestKubernetesParseService(t *testing.T) {
+	k := &Kubernetes{port: "6783"}
+	require.NoError(t, k.parseService("monitoring/alertmanager:mesh"))
+	require.Equal(t, "monitoring", k.namespace)
+	require.Equal(t, "alertmanager", k.service)
+	require.Equal(t, "mesh", k.port)
+
+	k = &Kubernetes{port: "6783"}
+	require.NoError(t, k.parseService("alertmanager"))
+	require.Equal(t, "", k.namespace)
+	require.Equal(t, "6783", k.port)
+
+	require.Error(t, (&Kubernetes{port: "6783"}).parseService("monitoring/"))
+	require.Error(t, (&Kubernetes{port: "6783"}).parseService("alertmanager:"))
+}
+
+func TestKubernetesRun(t *testing.T) {
+	events := make(chan string)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+
+		if r.URL.Query().Get("watch") != "true" {
+			require.Equal(t, "/api/v1/namespaces/monitoring/endpoints/alertmanager", r.URL.Path)
+			fmt.Fprint(w, `{
+				"metadata": {"resourceVersion": "1"},
+				"subsets": [{
+					"addresses": [{"ip": "10.0.0.2"}],
+					"notReadyAddresses": [{"ip": "10.0.0.1"}],
+					"ports": [{"name": "web", "port": 9093}, {"name": "mesh", "port": 6783}]
+				}]
+			}`)
+			return
+		}
+
+		require.Equal(t, "/api/v1/namespaces/monitoring/endpoints", r.URL.Path)
+		require.Equal(t, "1", r.URL.Query().Get("resourceVersion"))
+		for ev := range events {
+			fmt.Fprintln(w, ev)
+			w.(http.Flusher).Flush()
+		}
+	}))
+	defer srv.Close()
+
+	k := &Kubernetes{
+		client:    http.DefaultClient,
+		server:    srv.URL,
+		token:     "secret",
+		namespace: "monitoring",
+		service:   "alertmanager",
+		port:      "mesh",
+		retry:     time.Millisecond,
+	}
+
+	addrs, err := k.Addresses()
+	require.NoError(t, err)
+	require.Equal(t, []string{"10.0.0.1:6783", "10.0.0.2:6783"}, addrs)
+
+	calls := make(chan []string, 10)
+	stopc := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		k.Run([]string{"static:6783"}, func(addrs []string) { calls <- addrs }, stopc)
+		close(done)
+	}()
+
+	// Addresses of pods that are not ready yet are included.
+	require.Equal(t, []string{"10.0.0.1:6783", "10.0.0.2:6783", "static:6783"}, <-calls)
+
+	// A pod replaced during a rolling update.
+	events <- `{"type": "MODIFIED", "object": {"subsets": [{"addresses": [{"ip": "10.0.0.2"}, {"ip": "10.0.0.3"}], "ports": [{"name": "mesh", "port": 6783}]}]}}`
+	require.Equal(t, []string{"10.0.0.2:6783", "10.0.0.3:6783", "static:6783"}, <-calls)
+
+	// Unchanged endpoints do not reconnect.
+	events <- `{"type": "MODIFIED", "object": {"subsets": [{"addresses": [{"ip": "10.0.0.3"}, {"ip": "10.0.0.2"}], "ports": [{"name": "mesh", "port": 6783}]}]}}`
+	events <- `{"type": "DELETED", "object": {}}`
+	require.Equal(t, []string{"static:6783"}, <-calls)
+
+	close(stopc)
+	close(events)
+	<-done
+}
@@ -6,15 +6,47 @@ import (
 	"sync"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/common/log"
 	"github.com/prometheus/common/model"
 	"golang.org/x/net/context"
 
+	"github.com/prometheus/alertmanager/ack"
 	"github.com/prometheus/alertmanager/notify"
+	"github.com/prometheus/alertmanager/pause"
 	"github.com/prometheus/alertmanager/provider"
+	"github.com/prometheus/alertmanager/snooze"
+	"github.com/prometheus/alertmanager/tracing"
 	"github.com/prometheus/alertmanager/types"
 )
 
+var (
+	queuedAlerts = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "alertmanager",
+		Subsystem: "dispatcher",
+		Name:      "queued_alerts",
+		Help:      "The number of incoming alerts waiting to be sorted into aggregation groups.",
+	})
+	queueFull = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "alertmanager",
+		Subsystem: "dispatcher",
+		Name:      "queue_full_total",
+		Help:      "The total number of incoming alerts that had to wait for a full worker queue.",
+	})
+	waitingNotifications = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "alertmanager",
+		Subsystem: "dispatcher",
+		Name:      "notifications_waiting",
+		Help:      "The number of group notifications waiting for the global concurrency limit.",
+	})
+)
+
+func init() {
+	prometheus.Register(queuedAlerts)
+	prometheus.Register(queueFull)
+	prometheus.Register(waitingNotifications)
+}
+
 // Dispatcher sorts incoming alerts into aggregation groups and
 // assigns the correct notifiers to each.
 type Dispatcher struct {
@@ -23,11 +55,22 @@ type Dispatcher struct {
 	stage  notify.Stage
 
 	marker  types.Marker
+	flaps   *FlapDetector
+	queue   *PriorityQueue
 	timeout func(time.Duration) time.Duration
 
+	workers  int
+	capacity int
+
 	aggrGroups map[*Route]map[model.Fingerprint]*aggrGroup
 	mtx        sync.RWMutex
 
+	// The state of the aggregation groups left by a drained dispatcher and
+	// the state inherited from one, both by route key and group.
+	drained   map[string]map[model.Fingerprint]*groupState
+	inherited map[string]map[model.Fingerprint]*groupState
+
+	quit   chan struct{}
 	done   chan struct{}
 	ctx    context.Context
 	cancel func()
@@ -35,34 +78,47 @@ type Dispatcher struct {
 	log log.Logger
 }
 
-// NewDispatcher returns a new Dispatcher.
+// NewDispatcher returns a new Dispatcher. The FlapDetector may be nil to
+// disable flap detection and the PriorityQueue may be nil to send
+// notifications without limiting their concurrency. Incoming alerts are
+// sorted into groups by the given number of workers, each of which queues
+// up to capacity alerts.
 func NewDispatcher(
 	ap provider.Alerts,
 	r *Route,
 	s notify.Stage,
 	mk types.Marker,
+	fd *FlapDetector,
+	pq *PriorityQueue,
+	workers, capacity int,
 	to func(time.Duration) time.Duration,
 ) *Dispatcher {
+	if workers < 1 {
+		workers = 1
+	}
 	disp := &Dispatcher{
-		alerts:  ap,
-		stage:   s,
-		route:   r,
-		marker:  mk,
-		timeout: to,
-		log:     log.With("component", "dispatcher"),
+		alerts:   ap,
+		stage:    s,
+		route:    r,
+		marker:   mk,
+		flaps:    fd,
+		queue:    pq,
+		workers:  workers,
+		capacity: capacity,
+		timeout:  to,
+		quit:     make(chan struct{}),
+		log:      log.With("component", "dispatcher"),
 	}
 	return disp
 }
 
 // Run starts dispatching alerts incoming via the updates channel.
 func (d *Dispatcher) Run() {
-	d.done = make(chan struct{})
-
 	d.mtx.Lock()
+	d.done = make(chan struct{})
 	d.aggrGroups = map[*Route]map[model.Fingerprint]*aggrGroup{}
-	d.mtx.Unlock()
-
 	d.ctx, d.cancel = context.WithCancel(context.Background())
+	d.mtx.Unlock()
 
 	d.run(d.alerts.Subscribe())
 	close(d.done)
@@ -71,8 +127,9 @@ func (d *Dispatcher) Run() {
 // AlertBlock contains a list of alerts associated with a set of
 // routing options.
 type AlertBlock struct {
-	RouteOpts *RouteOpts  `json:"routeOpts"`
-	Alerts    []*APIAlert `json:"alerts"`
+	RouteOpts *RouteOpts   `json:"routeOpts"`
+	Alerts    []*APIAlert  `json:"alerts"`
+	Pause     *pause.Pause `json:"pause,omitempty"`
 }
 
 // APIAlert is the API representation of an alert, which is a regular alert
@@ -82,6 +139,17 @@ type APIAlert struct {
 
 	Inhibited bool   `json:"inhibited"`
 	Silenced  string `json:"silenced,omitempty"`
+	Flapping  bool   `json:"flapping,omitempty"`
+	// Ack is the acknowledgement of the alert, if any.
+	Ack *ack.Ack `json:"ack,omitempty"`
+	// Comments about the alert, oldest first.
+	Comments []*types.Comment `json:"comments,omitempty"`
+	// AssignedTo is the person the alert is assigned to, if any.
+	AssignedTo string `json:"assignedTo,omitempty"`
+	// Snooze is the active snooze of the alert, if any.
+	Snooze *snooze.Snooze `json:"snooze,omitempty"`
+	// Sources is the state of the alert reported by each of its sources.
+	Sources []*types.AlertSource `json:"sources,omitempty"`
 }
 
 // AlertGroup is a list of alert blocks grouped by the same label set.
@@ -121,13 +189,16 @@ func (d *Dispatcher) Groups() AlertOverview {
 			now := time.Now()
 
 			var apiAlerts []*APIAlert
-			for _, a := range types.Alerts(ag.alertSlice()...) {
+			for _, ta := range ag.alertSlice() {
+				a := types.Alerts(ta)[0]
 				if !a.EndsAt.IsZero() && a.EndsAt.Before(now) {
 					continue
 				}
 				aa := &APIAlert{
 					Alert:     a,
 					Inhibited: d.marker.Inhibited(a.Fingerprint()),
+					Flapping:  d.marker.Flapping(a.Fingerprint()),
+					Sources:   ta.Sources,
 				}
 				if sid, ok := d.marker.Silenced(a.Fingerprint()); ok {
 					aa.Silenced = sid
@@ -156,6 +227,31 @@ func (d *Dispatcher) run(it provider.AlertIterator) {
 
 	defer it.Close()
 
+	// Alerts with the same fingerprint are always handled by the same
+	// worker so that their updates are applied in order.
+	var wg sync.WaitGroup
+	queues := make([]chan *types.Alert, d.workers)
+	for i := range queues {
+		queues[i] = make(chan *types.Alert, d.capacity)
+
+		wg.Add(1)
+		go func(q <-chan *types.Alert) {
+			defer wg.Done()
+			for alert := range q {
+				queuedAlerts.Dec()
+				for _, r := range d.route.Match(alert.Labels) {
+					d.processAlert(alert, r)
+				}
+			}
+		}(queues[i])
+	}
+	defer func() {
+		for _, q := range queues {
+			close(q)
+		}
+		wg.Wait()
+	}()
+
 	for {
 		select {
 		case alert, ok := <-it.Next():
@@ -175,8 +271,27 @@ func (d *Dispatcher) run(it provider.AlertIterator) {
 				continue
 			}
 
-			for _, r := range d.route.Match(alert.Labels) {
-				d.processAlert(alert, r)
+			if d.flaps.observe(alert, time.Now()) {
+				d.log.With("alert", alert).Debug("Alert is flapping")
+			}
+
+			q := queues[uint64(alert.Fingerprint())%uint64(len(queues))]
+			queuedAlerts.Inc()
+			select {
+			case q <- alert:
+			default:
+				// Block until the worker caught up, pushing back on
+				// the alert provider.
+				queueFull.Inc()
+				select {
+				case q <- alert:
+				case <-d.ctx.Done():
+					queuedAlerts.Dec()
+					return
+				case <-d.quit:
+					queuedAlerts.Dec()
+					return
+				}
 			}
 
 		case <-cleanup.C:
@@ -184,7 +299,7 @@ func (d *Dispatcher) run(it provider.AlertIterator) {
 
 			for _, groups := range d.aggrGroups {
 				for _, ag := range groups {
-					if ag.empty() {
+					if ag.remove() {
 						ag.stop()
 						delete(groups, ag.fingerprint())
 					}
@@ -193,8 +308,12 @@ func (d *Dispatcher) run(it provider.AlertIterator) {
 
 			d.mtx.Unlock()
 
+			d.flaps.gc(time.Now())
+
 		case <-d.ctx.Done():
 			return
+		case <-d.quit:
+			return
 		}
 	}
 }
@@ -210,6 +329,26 @@ func (d *Dispatcher) Stop() {
 	<-d.done
 }
 
+// SilenceExpired flushes all groups whose route enables flushing on silence
+// expiry and that contain firing alerts last muted by the silence with the
+// given ID. Their next notification is sent regardless of the repeat interval.
+func (d *Dispatcher) SilenceExpired(id string) {
+	d.mtx.RLock()
+	defer d.mtx.RUnlock()
+
+	for route, groups := range d.aggrGroups {
+		if !route.RouteOpts.FlushOnSilenceExpiry {
+			continue
+		}
+		for _, ag := range groups {
+			if ag.silencedBy(d.marker, id) {
+				ag.log.With("silence", id).Debug("Flushing group after silence expired")
+				ag.flushNow()
+			}
+		}
+	}
+}
+
 // notifyFunc is a function that performs notifcation for the alert
 // with the given fingerprint. It aborts on context cancelation.
 // Returns false iff notifying failed.
@@ -228,30 +367,70 @@ func (d *Dispatcher) processAlert(alert *types.Alert, route *Route) {
 
 	fp := group.Fingerprint()
 
+	// The lock is only held to look up the group, so that workers insert
+	// alerts in parallel. If the group was cleaned up in the meantime, the
+	// alert is inserted into a new one.
+	for {
+		if d.aggrGroup(route, group, fp).insert(alert) {
+			return
+		}
+	}
+}
+
+// aggrGroup returns the aggregation group of the route with the labels,
+// which is created if it does not exist.
+func (d *Dispatcher) aggrGroup(route *Route, group model.LabelSet, fp model.Fingerprint) *aggrGroup {
 	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
 	groups, ok := d.aggrGroups[route]
 	if !ok {
 		groups = map[model.Fingerprint]*aggrGroup{}
 		d.aggrGroups[route] = groups
 	}
-	d.mtx.Unlock()
 
 	// If the group does not exist, create it.
 	ag, ok := groups[fp]
 	if !ok {
 		ag = newAggrGroup(d.ctx, group, &route.RouteOpts, d.timeout)
+		ag.flaps = d.flaps
 		groups[fp] = ag
 
+		if len(d.inherited) > 0 {
+			key := routeKey(route)
+			if st, ok := d.inherited[key][fp]; ok {
+				ag.restore(st)
+				delete(d.inherited[key], fp)
+			}
+		}
+
 		go ag.run(func(ctx context.Context, alerts ...*types.Alert) bool {
+			if err := d.queue.acquire(ctx, alerts); err != nil {
+				log.Errorf("Notify for %d alerts was not sent: %s", len(alerts), err)
+				return false
+			}
+			defer d.queue.release()
+
+			ctx, span := tracing.Start(ctx, "notify", tracing.KindInternal)
+			span.SetAttribute("receiver", route.RouteOpts.Receiver)
+			span.SetAttribute("group", group.String())
+			span.SetAttribute("alerts", len(alerts))
+			for _, a := range alerts {
+				if sc, ok := tracing.ParseTraceParent(a.TraceParent); ok {
+					span.AddLink(sc)
+				}
+			}
+			defer span.End()
+
 			_, _, err := d.stage.Exec(ctx, alerts...)
 			if err != nil {
+				span.SetError(err)
 				log.Errorf("Notify for %d alerts failed: %s", len(alerts), err)
 			}
 			return err == nil
 		})
 	}
-
-	ag.insert(alert)
+	return ag
 }
 
 // aggrGroup aggregates alert fingerprints into groups to which a
@@ -265,13 +444,30 @@ type aggrGroup struct {
 
 	ctx     context.Context
 	cancel  func()
+	quit    chan struct{}
 	done    chan struct{}
 	next    *time.Timer
 	timeout func(time.Duration) time.Duration
+	flaps   *FlapDetector
 
 	mtx     sync.RWMutex
 	alerts  map[model.Fingerprint]*types.Alert
 	hasSent bool
+	// removed is set once the group was cleaned up.
+	removed bool
+	// Whether the next flush ignores the repeat interval.
+	renotify bool
+	// When the timer fires next.
+	nextFlush time.Time
+
+	// When the group was created and when the last new alert joined it.
+	createdAt time.Time
+	lastNew   time.Time
+
+	// When the group was first flushed with firing alerts and how many of
+	// the route's escalations were reached since.
+	firingSince time.Time
+	escalated   int
 }
 
 // newAggrGroup returns a new aggregation group.
@@ -284,6 +480,10 @@ func newAggrGroup(ctx context.Context, labels model.LabelSet, opts *RouteOpts, t
 		opts:    opts,
 		timeout: to,
 		alerts:  map[model.Fingerprint]*types.Alert{},
+		quit:    make(chan struct{}),
+		done:    make(chan struct{}),
+
+		createdAt: time.Now(),
 	}
 	ag.ctx, ag.cancel = context.WithCancel(ctx)
 
@@ -292,10 +492,18 @@ func newAggrGroup(ctx context.Context, labels model.LabelSet, opts *RouteOpts, t
 	// Set an initial one-time wait before flushing
 	// the first batch of notifications.
 	ag.next = time.NewTimer(ag.opts.GroupWait)
+	ag.nextFlush = ag.createdAt.Add(ag.opts.GroupWait)
 
 	return ag
 }
 
+// resetTimer resets the timer to fire after the duration. The caller must
+// hold the lock.
+func (ag *aggrGroup) resetTimer(d time.Duration) {
+	ag.next.Reset(d)
+	ag.nextFlush = time.Now().Add(d)
+}
+
 func (ag *aggrGroup) String() string {
 	return fmt.Sprint(ag.fingerprint())
 }
@@ -312,14 +520,22 @@ func (ag *aggrGroup) alertSlice() []*types.Alert {
 }
 
 func (ag *aggrGroup) run(nf notifyFunc) {
-	ag.done = make(chan struct{})
-
 	defer close(ag.done)
 	defer ag.next.Stop()
 
 	for {
 		select {
 		case now := <-ag.next.C:
+			// Keep waiting for the first notification while alerts
+			// are still streaming into the group.
+			ag.mtx.Lock()
+			if d, ok := ag.extendWait(now); ok {
+				ag.resetTimer(d)
+				ag.mtx.Unlock()
+				continue
+			}
+			ag.mtx.Unlock()
+
 			// Give the notifcations time until the next flush to
 			// finish before terminating them.
 			ctx, cancel := context.WithTimeout(ag.ctx, ag.timeout(ag.opts.GroupInterval))
@@ -334,23 +550,134 @@ func (ag *aggrGroup) run(nf notifyFunc) {
 			ctx = notify.WithGroupKey(ctx, model.Fingerprint(ag.GroupKey()))
 			ctx = notify.WithGroupLabels(ctx, ag.labels)
 			ctx = notify.WithReceiverName(ctx, ag.opts.Receiver)
-			ctx = notify.WithRepeatInterval(ctx, ag.opts.RepeatInterval)
 
 			// Wait the configured interval before calling flush again.
 			ag.mtx.Lock()
-			ag.next.Reset(ag.opts.GroupInterval)
+			ag.resetTimer(ag.groupInterval())
+			renotify := ag.renotify
+			ag.renotify = false
 			ag.mtx.Unlock()
 
+			if renotify {
+				ctx = notify.WithRepeatInterval(ctx, 0)
+			} else {
+				ctx = notify.WithRepeatInterval(ctx, ag.opts.RepeatInterval)
+			}
+
 			ag.flush(func(alerts ...*types.Alert) bool {
-				return nf(ctx, alerts...)
+				ok := nf(ctx, alerts...)
+
+				ag.mtx.Lock()
+				escs := ag.escalations(now, alerts)
+				ag.mtx.Unlock()
+
+				for _, e := range escs {
+					ectx := notify.WithReceiverName(ctx, e.Receiver)
+					nf(notify.WithEscalation(ectx), alerts...)
+				}
+				return ok
 			})
 
 			cancel()
 
 		case <-ag.ctx.Done():
 			return
+		case <-ag.quit:
+			return
+		}
+	}
+}
+
+// escalations returns the escalations whose receivers are notified about
+// the given alerts in addition to the route's receiver. Escalations are
+// reached at the first flush after their delay passed and stay active until
+// none of the group's alerts is firing anymore, so that their receivers learn
+// about the resolution. The caller must hold the lock.
+func (ag *aggrGroup) escalations(now time.Time, alerts []*types.Alert) []Escalation {
+	if len(ag.opts.Escalations) == 0 {
+		return nil
+	}
+	firing := false
+	for _, a := range alerts {
+		if !a.Resolved() {
+			firing = true
+			break
+		}
+	}
+	if !firing {
+		res := ag.opts.Escalations[:ag.escalated]
+		ag.firingSince, ag.escalated = time.Time{}, 0
+		return res
+	}
+	if ag.firingSince.IsZero() {
+		ag.firingSince = now
+	}
+	for ag.escalated < len(ag.opts.Escalations) {
+		if now.Before(ag.firingSince.Add(ag.opts.Escalations[ag.escalated].After)) {
+			break
+		}
+		ag.escalated++
+	}
+	return ag.opts.Escalations[:ag.escalated]
+}
+
+// extendWait returns how much longer to wait before the first flush of the
+// group if adaptive group wait is enabled. It returns false if the group is
+// to be flushed now. The caller must hold the lock.
+func (ag *aggrGroup) extendWait(now time.Time) (time.Duration, bool) {
+	if ag.hasSent || ag.opts.GroupWaitMax <= ag.opts.GroupWait {
+		return 0, false
+	}
+	quiet := ag.lastNew.Add(ag.opts.GroupWait).Sub(now)
+	remaining := ag.createdAt.Add(ag.opts.GroupWaitMax).Sub(now)
+	if quiet <= 0 || remaining <= 0 {
+		return 0, false
+	}
+	if remaining < quiet {
+		return remaining, true
+	}
+	return quiet, true
+}
+
+// groupInterval returns the interval until the next flush. It is extended
+// while the group contains flapping alerts. The caller must hold the lock.
+func (ag *aggrGroup) groupInterval() time.Duration {
+	for fp := range ag.alerts {
+		if ag.flaps.flapping(fp) {
+			return ag.flaps.groupInterval(ag.opts.GroupInterval)
+		}
+	}
+	return ag.opts.GroupInterval
+}
+
+// silencedBy returns whether the group contains firing alerts that were
+// last muted by the silence with the given ID.
+func (ag *aggrGroup) silencedBy(mk types.Marker, id string) bool {
+	ag.mtx.RLock()
+	defer ag.mtx.RUnlock()
+
+	for fp, a := range ag.alerts {
+		if a.Resolved() {
+			continue
+		}
+		if sid, ok := mk.Silenced(fp); ok && sid == id {
+			return true
 		}
 	}
+	return false
+}
+
+// flushNow triggers a flush of the group that ignores the repeat interval.
+// Groups that were not flushed yet keep waiting for their first flush.
+func (ag *aggrGroup) flushNow() {
+	ag.mtx.Lock()
+	defer ag.mtx.Unlock()
+
+	if !ag.hasSent {
+		return
+	}
+	ag.renotify = true
+	ag.resetTimer(0)
 }
 
 func (ag *aggrGroup) stop() {
@@ -368,19 +695,39 @@ func (ag *aggrGroup) GroupKey() uint64 {
 	return uint64(ag.labels.Fingerprint() ^ ag.routeFP)
 }
 
-// insert inserts the alert into the aggregation group. If the aggregation group
-// is empty afterwards, it returns true.
-func (ag *aggrGroup) insert(alert *types.Alert) {
+// insert inserts the alert into the aggregation group. It returns false if
+// the group was removed, in which case the alert was not inserted.
+func (ag *aggrGroup) insert(alert *types.Alert) bool {
 	ag.mtx.Lock()
 	defer ag.mtx.Unlock()
 
+	if ag.removed {
+		return false
+	}
+
+	if _, ok := ag.alerts[alert.Fingerprint()]; !ok {
+		ag.lastNew = time.Now()
+	}
 	ag.alerts[alert.Fingerprint()] = alert
 
 	// Immediately trigger a flush if the wait duration for this
 	// alert is already over.
 	if !ag.hasSent && alert.StartsAt.Add(ag.opts.GroupWait).Before(time.Now()) {
-		ag.next.Reset(0)
+		ag.resetTimer(0)
 	}
+	return true
+}
+
+// remove marks the aggregation group as removed if it is empty, after which
+// no alerts are inserted anymore. It returns whether it was marked.
+func (ag *aggrGroup) remove() bool {
+	ag.mtx.Lock()
+	defer ag.mtx.Unlock()
+
+	if len(ag.alerts) == 0 {
+		ag.removed = true
+	}
+	return ag.removed
 }
 
 func (ag *aggrGroup) empty() bool {
@@ -403,6 +750,11 @@ func (ag *aggrGroup) flush(notify func(...*types.Alert) bool) {
 	)
 	for fp, alert := range ag.alerts {
 		alerts[fp] = alert
+		if ag.flaps.flapping(fp) {
+			c := *alert
+			c.Flapping = true
+			alert = &c
+		}
 		alertsSlice = append(alertsSlice, alert)
 	}
 
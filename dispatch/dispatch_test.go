@@ -1,6 +1,7 @@
 package dispatch
 
 import (
+	"fmt"
 	"reflect"
 	"sort"
 	"testing"
@@ -9,7 +10,9 @@ import (
 	"github.com/prometheus/common/model"
 	"golang.org/x/net/context"
 
+	"github.com/prometheus/alertmanager/config"
 	"github.com/prometheus/alertmanager/notify"
+	"github.com/prometheus/alertmanager/provider/mem"
 	"github.com/prometheus/alertmanager/types"
 )
 
@@ -214,3 +217,237 @@ func TestAggrGroup(t *testing.T) {
 
 	ag.stop()
 }
+
+func TestAggrGroupExtendWait(t *testing.T) {
+	opts := &RouteOpts{
+		GroupWait:    30 * time.Second,
+		GroupWaitMax: 2 * time.Minute,
+	}
+	ag := newAggrGroup(context.Background(), model.LabelSet{}, opts, nil)
+
+	start := ag.createdAt
+	ag.lastNew = start.Add(20 * time.Second)
+
+	// New alerts joined recently, wait until the group was quiet for group_wait.
+	d, ok := ag.extendWait(start.Add(30 * time.Second))
+	if !ok || d != 20*time.Second {
+		t.Fatalf("expected wait to be extended by 20s but got %v, %v", d, ok)
+	}
+
+	// The wait is capped at group_wait_max.
+	ag.lastNew = start.Add(110 * time.Second)
+	d, ok = ag.extendWait(start.Add(115 * time.Second))
+	if !ok || d != 5*time.Second {
+		t.Fatalf("expected wait to be extended by 5s but got %v, %v", d, ok)
+	}
+	if _, ok := ag.extendWait(start.Add(2 * time.Minute)); ok {
+		t.Fatalf("expected wait not to be extended beyond group_wait_max")
+	}
+
+	// The group was quiet for group_wait.
+	ag.lastNew = start
+	if _, ok := ag.extendWait(start.Add(30 * time.Second)); ok {
+		t.Fatalf("expected wait not to be extended for quiet group")
+	}
+
+	// Without group_wait_max or after the first notification, the wait is never extended.
+	ag.lastNew = start.Add(20 * time.Second)
+	ag.hasSent = true
+	if _, ok := ag.extendWait(start.Add(30 * time.Second)); ok {
+		t.Fatalf("expected wait not to be extended after first notification")
+	}
+	ag.hasSent = false
+	opts.GroupWaitMax = 0
+	if _, ok := ag.extendWait(start.Add(30 * time.Second)); ok {
+		t.Fatalf("expected wait not to be extended without group_wait_max")
+	}
+}
+
+func TestAggrGroupEscalations(t *testing.T) {
+	escalations := []Escalation{
+		{Receiver: "pagerduty", After: 15 * time.Minute},
+		{Receiver: "manager", After: time.Hour},
+	}
+	opts := &RouteOpts{Escalations: escalations}
+	ag := newAggrGroup(context.Background(), model.LabelSet{}, opts, nil)
+
+	firing := []*types.Alert{{Alert: model.Alert{
+		Labels:   model.LabelSet{"a": "v1"},
+		StartsAt: time.Now().Add(-time.Hour),
+		EndsAt:   time.Now().Add(time.Hour),
+	}}}
+	resolved := []*types.Alert{{Alert: model.Alert{
+		Labels:   model.LabelSet{"a": "v1"},
+		StartsAt: time.Now().Add(-time.Hour),
+		EndsAt:   time.Now().Add(-time.Minute),
+	}}}
+
+	start := time.Now()
+	if escs := ag.escalations(start, firing); len(escs) != 0 {
+		t.Fatalf("expected no escalation on first notification but got %v", escs)
+	}
+	if escs := ag.escalations(start.Add(15*time.Minute), firing); !reflect.DeepEqual(escs, escalations[:1]) {
+		t.Fatalf("expected first escalation but got %v", escs)
+	}
+	if escs := ag.escalations(start.Add(30*time.Minute), firing); !reflect.DeepEqual(escs, escalations[:1]) {
+		t.Fatalf("expected first escalation to stay active but got %v", escs)
+	}
+	if escs := ag.escalations(start.Add(time.Hour), firing); !reflect.DeepEqual(escs, escalations) {
+		t.Fatalf("expected all escalations but got %v", escs)
+	}
+
+	// The escalation receivers are notified about the resolution once.
+	if escs := ag.escalations(start.Add(70*time.Minute), resolved); !reflect.DeepEqual(escs, escalations) {
+		t.Fatalf("expected resolution to be escalated but got %v", escs)
+	}
+	if escs := ag.escalations(start.Add(80*time.Minute), resolved); len(escs) != 0 {
+		t.Fatalf("expected no escalation after resolution but got %v", escs)
+	}
+
+	// Escalation starts over once alerts fire again.
+	if escs := ag.escalations(start.Add(90*time.Minute), firing); len(escs) != 0 {
+		t.Fatalf("expected no escalation after firing again but got %v", escs)
+	}
+}
+
+func TestAggrGroupFlushOnSilenceExpiry(t *testing.T) {
+	opts := &RouteOpts{
+		Receiver:       "team",
+		GroupWait:      10 * time.Millisecond,
+		GroupInterval:  time.Hour,
+		RepeatInterval: 4 * time.Hour,
+	}
+	ag := newAggrGroup(context.Background(), model.LabelSet{}, opts, nil)
+
+	marker := types.NewMarker()
+	alert := &types.Alert{Alert: model.Alert{
+		Labels:   model.LabelSet{"a": "v1"},
+		StartsAt: time.Now(),
+		EndsAt:   time.Now().Add(time.Hour),
+	}}
+
+	intervals := make(chan time.Duration, 2)
+	go ag.run(func(ctx context.Context, alerts ...*types.Alert) bool {
+		d, _ := notify.RepeatInterval(ctx)
+		intervals <- d
+		return true
+	})
+	defer ag.stop()
+
+	// Flushing before the first notification has no effect.
+	ag.flushNow()
+	ag.insert(alert)
+
+	select {
+	case d := <-intervals:
+		if d != opts.RepeatInterval {
+			t.Fatalf("expected repeat interval %v on first flush but got %v", opts.RepeatInterval, d)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected first flush")
+	}
+
+	if ag.silencedBy(marker, "sil1") {
+		t.Fatalf("expected group not to be silenced")
+	}
+	marker.SetSilenced(alert.Fingerprint(), "sil1")
+	if !ag.silencedBy(marker, "sil1") {
+		t.Fatalf("expected group to be silenced by sil1")
+	}
+	if ag.silencedBy(marker, "sil2") {
+		t.Fatalf("expected group not to be silenced by sil2")
+	}
+
+	ag.flushNow()
+
+	select {
+	case d := <-intervals:
+		if d != 0 {
+			t.Fatalf("expected repeat interval to be ignored but got %v", d)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected flush after silence expiry")
+	}
+}
+
+func TestAggrGroupRemove(t *testing.T) {
+	opts := &RouteOpts{GroupWait: time.Minute}
+	alert := &types.Alert{Alert: model.Alert{
+		Labels:   model.LabelSet{"a": "v1"},
+		StartsAt: time.Now(),
+		EndsAt:   time.Now().Add(time.Hour),
+	}}
+
+	ag := newAggrGroup(context.Background(), model.LabelSet{}, opts, nil)
+	if !ag.insert(alert) {
+		t.Fatalf("expected alert to be inserted")
+	}
+	if ag.remove() {
+		t.Fatalf("expected group with alerts not to be removed")
+	}
+
+	ag = newAggrGroup(context.Background(), model.LabelSet{}, opts, nil)
+	if !ag.remove() {
+		t.Fatalf("expected empty group to be removed")
+	}
+	if ag.insert(alert) {
+		t.Fatalf("expected no alert to be inserted into a removed group")
+	}
+}
+
+func TestDispatcherWorkers(t *testing.T) {
+	alerts, err := mem.NewAlerts("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer alerts.Close()
+
+	groupWait := model.Duration(10 * time.Millisecond)
+	route := NewRoute(&config.Route{
+		Receiver:  "team",
+		GroupBy:   []model.LabelName{"instance"},
+		GroupWait: &groupWait,
+	}, nil)
+
+	notified := make(chan model.LabelValue, 20)
+	stage := notify.StageFunc(func(ctx context.Context, as ...*types.Alert) (context.Context, []*types.Alert, error) {
+		for _, a := range as {
+			notified <- a.Labels["instance"]
+		}
+		return ctx, as, nil
+	})
+
+	d := NewDispatcher(alerts, route, stage, types.NewMarker(), nil, nil, 4, 1, nil)
+	go d.Run()
+	defer d.Stop()
+
+	now := time.Now()
+	expected := map[model.LabelValue]bool{}
+	for i := 0; i < 10; i++ {
+		inst := model.LabelValue(fmt.Sprintf("host%d", i))
+		expected[inst] = true
+		if err := alerts.Put(&types.Alert{
+			Alert: model.Alert{
+				Labels:   model.LabelSet{"alertname": "down", "instance": inst},
+				StartsAt: now,
+				EndsAt:   now.Add(time.Hour),
+			},
+			UpdatedAt: now,
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got := map[model.LabelValue]bool{}
+	for len(got) < len(expected) {
+		select {
+		case inst := <-notified:
+			got[inst] = true
+		case <-time.After(5 * time.Second):
+			t.Fatalf("expected notifications for all groups but got %v", got)
+		}
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected notifications for %v but got %v", expected, got)
+	}
+}
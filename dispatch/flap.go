@@ -0,0 +1,136 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dispatch
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/common/model"
+
+	"github.com/prometheus/alertmanager/types"
+)
+
+// FlapDetector detects alerts that change between firing and resolved more
+// than a threshold number of times within a window. Groups containing
+// flapping alerts are flushed at a longer interval.
+//
+// All methods are safe to call on a nil FlapDetector, which detects no
+// flapping alerts.
+type FlapDetector struct {
+	threshold int
+	window    time.Duration
+	interval  time.Duration
+	marker    types.Marker
+
+	mtx    sync.Mutex
+	states map[model.Fingerprint]*flapState
+}
+
+type flapState struct {
+	resolved    bool
+	transitions []time.Time
+}
+
+// NewFlapDetector returns a new FlapDetector. Groups containing flapping
+// alerts are flushed at the given interval if it is longer than their group
+// interval.
+func NewFlapDetector(threshold int, window, interval time.Duration, mk types.Marker) *FlapDetector {
+	return &FlapDetector{
+		threshold: threshold,
+		window:    window,
+		interval:  interval,
+		marker:    mk,
+		states:    map[model.Fingerprint]*flapState{},
+	}
+}
+
+// observe records the state of the alert at the given time and returns
+// whether it is flapping.
+func (fd *FlapDetector) observe(a *types.Alert, now time.Time) bool {
+	if fd == nil {
+		return false
+	}
+	fd.mtx.Lock()
+	defer fd.mtx.Unlock()
+
+	var (
+		fp       = a.Fingerprint()
+		resolved = a.ResolvedAt(now)
+	)
+	s, ok := fd.states[fp]
+	if !ok {
+		fd.states[fp] = &flapState{resolved: resolved}
+		return false
+	}
+	if s.resolved != resolved {
+		s.resolved = resolved
+		s.transitions = append(s.transitions, now)
+	}
+	return fd.update(fp, s, now)
+}
+
+// update drops transitions that left the window and marks the alert
+// accordingly. It returns whether the alert is flapping.
+func (fd *FlapDetector) update(fp model.Fingerprint, s *flapState, now time.Time) bool {
+	i := 0
+	for i < len(s.transitions) && !s.transitions[i].After(now.Add(-fd.window)) {
+		i++
+	}
+	s.transitions = s.transitions[i:]
+
+	flapping := len(s.transitions) > fd.threshold
+	fd.marker.SetFlapping(fp, flapping)
+
+	return flapping
+}
+
+// flapping returns whether the alert with the given fingerprint is flapping.
+func (fd *FlapDetector) flapping(fp model.Fingerprint) bool {
+	if fd == nil {
+		return false
+	}
+	fd.mtx.Lock()
+	defer fd.mtx.Unlock()
+
+	s, ok := fd.states[fp]
+	return ok && len(s.transitions) > fd.threshold
+}
+
+// groupInterval returns the interval at which a group with the given group
+// interval is flushed if it contains a flapping alert.
+func (fd *FlapDetector) groupInterval(d time.Duration) time.Duration {
+	if fd == nil || fd.interval < d {
+		return d
+	}
+	return fd.interval
+}
+
+// gc updates the flapping state of all tracked alerts and stops tracking
+// resolved alerts that did not change their state within the window.
+func (fd *FlapDetector) gc(now time.Time) {
+	if fd == nil {
+		return
+	}
+	fd.mtx.Lock()
+	defer fd.mtx.Unlock()
+
+	for fp, s := range fd.states {
+		fd.update(fp, s, now)
+
+		if s.resolved && len(s.transitions) == 0 {
+			delete(fd.states, fp)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dispatch
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+
+	"github.com/prometheus/alertmanager/types"
+)
+
+func TestFlapDetector(t *testing.T) {
+	var (
+		marker = types.NewMarker()
+		fd     = NewFlapDetector(2, 10*time.Minute, time.Hour, marker)
+		now    = time.Now()
+		lset   = model.LabelSet{"alertname": "a"}
+	)
+	observe := func(ts time.Time, resolved bool) bool {
+		a := &types.Alert{Alert: model.Alert{Labels: lset, StartsAt: ts.Add(-time.Minute)}}
+		if resolved {
+			a.EndsAt = ts.Add(-time.Second)
+		}
+		return fd.observe(a, ts)
+	}
+
+	require.False(t, observe(now, false))
+	require.False(t, observe(now.Add(1*time.Minute), true))
+	require.False(t, observe(now.Add(2*time.Minute), false))
+	require.False(t, observe(now.Add(3*time.Minute), false))
+	require.True(t, observe(now.Add(4*time.Minute), true))
+
+	require.True(t, fd.flapping(lset.Fingerprint()))
+	require.True(t, marker.Flapping(lset.Fingerprint()))
+	require.Equal(t, time.Hour, fd.groupInterval(5*time.Minute))
+	require.Equal(t, 2*time.Hour, fd.groupInterval(2*time.Hour))
+
+	// Transitions leaving the window end the flapping.
+	fd.gc(now.Add(12 * time.Minute))
+	require.False(t, fd.flapping(lset.Fingerprint()))
+	require.False(t, marker.Flapping(lset.Fingerprint()))
+
+	// Resolved alerts without recent transitions are no longer tracked.
+	fd.gc(now.Add(20 * time.Minute))
+	require.Len(t, fd.states, 0)
+}
+
+func TestFlapDetectorNil(t *testing.T) {
+	var fd *FlapDetector
+
+	require.False(t, fd.observe(&types.Alert{}, time.Now()))
+	require.False(t, fd.flapping(0))
+	require.Equal(t, time.Minute, fd.groupInterval(time.Minute))
+	fd.gc(time.Now())
+}
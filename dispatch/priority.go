@@ -0,0 +1,174 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dispatch
+
+import (
+	"container/heap"
+	"sync"
+
+	"github.com/prometheus/common/model"
+	"golang.org/x/net/context"
+
+	"github.com/prometheus/alertmanager/types"
+)
+
+// PriorityQueue limits the number of group notifications sent at the same
+// time. Notifications waiting for a free slot are sent in the order of their
+// priority, which is given by the value of a label of their alerts, and in
+// the order they started waiting within the same priority.
+type PriorityQueue struct {
+	label  model.LabelName
+	values map[model.LabelValue]int
+
+	mtx     sync.Mutex
+	running int
+	limit   int
+	seq     uint64
+	waiting waiters
+}
+
+// NewPriorityQueue returns a new PriorityQueue sending at most limit
+// notifications at the same time. The values of the label are given from
+// highest to lowest priority.
+func NewPriorityQueue(label model.LabelName, values []model.LabelValue, limit int) *PriorityQueue {
+	q := &PriorityQueue{
+		label:  label,
+		values: make(map[model.LabelValue]int, len(values)),
+		limit:  limit,
+	}
+	for i, v := range values {
+		if _, ok := q.values[v]; !ok {
+			q.values[v] = i
+		}
+	}
+	return q
+}
+
+// priority returns the priority of a notification about the given alerts.
+// Lower values have a higher priority.
+func (q *PriorityQueue) priority(alerts []*types.Alert) int {
+	res := len(q.values)
+	for _, a := range alerts {
+		if p, ok := q.values[a.Labels[q.label]]; ok && p < res {
+			res = p
+		}
+	}
+	return res
+}
+
+// acquire blocks until the notification about the given alerts may be sent
+// or the context is canceled. It is safe to call on a nil PriorityQueue.
+func (q *PriorityQueue) acquire(ctx context.Context, alerts []*types.Alert) error {
+	if q == nil {
+		return nil
+	}
+	q.mtx.Lock()
+	if q.running < q.limit && len(q.waiting) == 0 {
+		q.running++
+		q.mtx.Unlock()
+		return nil
+	}
+	w := &waiter{
+		priority: q.priority(alerts),
+		seq:      q.seq,
+		ready:    make(chan struct{}),
+	}
+	q.seq++
+	heap.Push(&q.waiting, w)
+	q.mtx.Unlock()
+
+	waitingNotifications.Inc()
+	defer waitingNotifications.Dec()
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+		q.mtx.Lock()
+		defer q.mtx.Unlock()
+
+		// The slot may have been handed over in the meantime.
+		if w.index < 0 {
+			q.releaseLocked()
+		} else {
+			heap.Remove(&q.waiting, w.index)
+		}
+		return ctx.Err()
+	}
+}
+
+// release frees the slot of a sent notification. It is safe to call on
+// a nil PriorityQueue.
+func (q *PriorityQueue) release() {
+	if q == nil {
+		return
+	}
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	q.releaseLocked()
+}
+
+// releaseLocked hands the slot over to the waiting notification with the
+// highest priority. The caller must hold the lock.
+func (q *PriorityQueue) releaseLocked() {
+	if len(q.waiting) == 0 {
+		q.running--
+		return
+	}
+	w := heap.Pop(&q.waiting).(*waiter)
+	close(w.ready)
+}
+
+// waiter is a notification waiting for a free slot.
+type waiter struct {
+	priority int
+	seq      uint64
+	ready    chan struct{}
+	// The index in the heap, or -1 once the waiter was removed from it.
+	index int
+}
+
+// waiters implements heap.Interface.
+type waiters []*waiter
+
+func (ws waiters) Len() int { return len(ws) }
+
+func (ws waiters) Less(i, j int) bool {
+	if ws[i].priority != ws[j].priority {
+		return ws[i].priority < ws[j].priority
+	}
+	return ws[i].seq < ws[j].seq
+}
+
+func (ws waiters) Swap(i, j int) {
+	ws[i], ws[j] = ws[j], ws[i]
+	ws[i].index = i
+	ws[j].index = j
+}
+
+func (ws *waiters) Push(x interface{}) {
+	w := x.(*waiter)
+	w.index = len(*ws)
+	*ws = append(*ws, w)
+}
+
+func (ws *waiters) Pop() interface{} {
+	old := *ws
+	n := len(old)
+	w := old[n-1]
+	w.index = -1
+	*ws = old[:n-1]
+	return w
+}
@@ -0,0 +1,118 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dispatch
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"golang.org/x/net/context"
+
+	"github.com/prometheus/alertmanager/types"
+)
+
+func severityAlerts(sevs ...model.LabelValue) []*types.Alert {
+	var res []*types.Alert
+	for _, s := range sevs {
+		res = append(res, &types.Alert{Alert: model.Alert{
+			Labels: model.LabelSet{"severity": s},
+		}})
+	}
+	return res
+}
+
+func TestPriorityQueueOrder(t *testing.T) {
+	q := NewPriorityQueue("severity", []model.LabelValue{"critical", "warning"}, 1)
+
+	// Occupy the only slot.
+	if err := q.acquire(context.Background(), severityAlerts("info")); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	var (
+		order = make(chan string, 4)
+		sevs  = []struct {
+			name   string
+			alerts []*types.Alert
+		}{
+			{"info", severityAlerts("info")},
+			{"warning", severityAlerts("warning")},
+			{"critical", severityAlerts("warning", "critical")},
+			{"warning2", severityAlerts("warning")},
+		}
+	)
+	for i, s := range sevs {
+		s := s
+		go func() {
+			if err := q.acquire(context.Background(), s.alerts); err != nil {
+				t.Errorf("unexpected error: %s", err)
+			}
+			order <- s.name
+			q.release()
+		}()
+		// Wait until the notification is queued to have a stable order
+		// within the same priority.
+		for {
+			q.mtx.Lock()
+			n := len(q.waiting)
+			q.mtx.Unlock()
+			if n == i+1 {
+				break
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	q.release()
+
+	var got []string
+	for range sevs {
+		got = append(got, <-order)
+	}
+	expected := []string{"critical", "warning", "warning2", "info"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected order %v but got %v", expected, got)
+	}
+}
+
+func TestPriorityQueueCancel(t *testing.T) {
+	q := NewPriorityQueue("severity", []model.LabelValue{"critical"}, 1)
+
+	if err := q.acquire(context.Background(), severityAlerts("critical")); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if err := q.acquire(ctx, severityAlerts("critical")); err != context.DeadlineExceeded {
+		t.Fatalf("expected deadline to be exceeded but got %v", err)
+	}
+	if len(q.waiting) != 0 {
+		t.Fatalf("expected canceled notification to be removed from the queue")
+	}
+
+	q.release()
+	if err := q.acquire(context.Background(), severityAlerts("critical")); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	var nilQueue *PriorityQueue
+	if err := nilQueue.acquire(ctx, nil); err != nil {
+		t.Fatalf("unexpected error for nil queue: %s", err)
+	}
+	nilQueue.release()
+}
@@ -0,0 +1,172 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dispatch
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/prometheus/common/model"
+)
+
+// groupState is the state of an aggregation group that is carried over to
+// the dispatcher of a reloaded configuration.
+type groupState struct {
+	hasSent     bool
+	renotify    bool
+	createdAt   time.Time
+	lastNew     time.Time
+	firingSince time.Time
+	escalated   int
+	nextFlush   time.Time
+}
+
+// routeKey identifies a route across configuration reloads by its routing
+// options and the matchers on its path from the root. Groups of routes with
+// the same key are continued after a reload.
+func routeKey(r *Route) string {
+	var b bytes.Buffer
+	for _, pr := range r.Path() {
+		ms := make([]string, 0, len(pr.Matchers))
+		for _, m := range pr.Matchers {
+			ms = append(ms, m.String())
+		}
+		sort.Strings(ms)
+		fmt.Fprintf(&b, "%s|%v/", strings.Join(ms, ","), pr.Continue)
+	}
+
+	o := r.RouteOpts
+	groupBy := make([]string, 0, len(o.GroupBy))
+	for ln := range o.GroupBy {
+		groupBy = append(groupBy, string(ln))
+	}
+	sort.Strings(groupBy)
+
+	fmt.Fprintf(&b, "%q %q %v %v %v %v %v %v %v",
+		o.Receiver, groupBy, o.GroupWait, o.GroupInterval, o.RepeatInterval,
+		o.GroupWaitMax, o.Escalations, o.ResolveTimeout, o.FlushOnSilenceExpiry,
+	)
+	return b.String()
+}
+
+// Drain stops the dispatcher like Stop, but lets notifications in flight
+// complete for up to the timeout before canceling them. Afterwards, the
+// state of its aggregation groups can be continued by another dispatcher
+// with Inherit.
+func (d *Dispatcher) Drain(timeout time.Duration) {
+	if d == nil {
+		return
+	}
+	d.mtx.Lock()
+	cancel, done := d.cancel, d.done
+	d.cancel = nil
+	d.mtx.Unlock()
+
+	if cancel == nil {
+		return
+	}
+	// Stop sorting alerts into groups first, so no groups are created
+	// while the existing ones are drained.
+	close(d.quit)
+	<-done
+
+	d.mtx.RLock()
+	var ags []*aggrGroup
+	keys := map[*aggrGroup]string{}
+	for route, groups := range d.aggrGroups {
+		key := routeKey(route)
+		for _, ag := range groups {
+			ags = append(ags, ag)
+			keys[ag] = key
+		}
+	}
+	d.mtx.RUnlock()
+
+	// Groups stop once their current flush, if any, is done.
+	for _, ag := range ags {
+		close(ag.quit)
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for _, ag := range ags {
+		select {
+		case <-ag.done:
+		case <-timer.C:
+			d.log.Warn("Canceling notifications still in flight after the drain timeout")
+			cancel()
+			<-ag.done
+		}
+	}
+	cancel()
+
+	d.drained = map[string]map[model.Fingerprint]*groupState{}
+	for _, ag := range ags {
+		key := keys[ag]
+		if d.drained[key] == nil {
+			d.drained[key] = map[model.Fingerprint]*groupState{}
+		}
+		d.drained[key][ag.fingerprint()] = ag.state()
+	}
+}
+
+// Inherit makes the dispatcher continue the aggregation groups of the
+// drained dispatcher whose routes are unchanged. Their timers keep running
+// instead of starting over with the group wait, while groups of changed
+// routes start afresh. It must be called before Run.
+func (d *Dispatcher) Inherit(old *Dispatcher) {
+	if old == nil {
+		return
+	}
+	d.inherited = old.drained
+}
+
+// state returns the state of the group.
+func (ag *aggrGroup) state() *groupState {
+	ag.mtx.RLock()
+	defer ag.mtx.RUnlock()
+
+	return &groupState{
+		hasSent:     ag.hasSent,
+		renotify:    ag.renotify,
+		createdAt:   ag.createdAt,
+		lastNew:     ag.lastNew,
+		firingSince: ag.firingSince,
+		escalated:   ag.escalated,
+		nextFlush:   ag.nextFlush,
+	}
+}
+
+// restore continues the group from the state of a group of a previous
+// dispatcher. It must be called before the group runs.
+func (ag *aggrGroup) restore(st *groupState) {
+	ag.mtx.Lock()
+	defer ag.mtx.Unlock()
+
+	ag.hasSent = st.hasSent
+	ag.renotify = st.renotify
+	ag.createdAt = st.createdAt
+	ag.lastNew = st.lastNew
+	ag.firingSince = st.firingSince
+	ag.escalated = st.escalated
+
+	if d := st.nextFlush.Sub(time.Now()); d > 0 {
+		ag.resetTimer(d)
+	} else {
+		ag.resetTimer(0)
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dispatch
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"golang.org/x/net/context"
+
+	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/notify"
+	"github.com/prometheus/alertmanager/provider/mem"
+	"github.com/prometheus/alertmanager/types"
+)
+
+func reloadTestRoute(receiver string) *Route {
+	groupWait := model.Duration(10 * time.Millisecond)
+	groupInterval := model.Duration(time.Hour)
+	return NewRoute(&config.Route{
+		Receiver:      receiver,
+		GroupBy:       []model.LabelName{"alertname"},
+		GroupWait:     &groupWait,
+		GroupInterval: &groupInterval,
+		Routes: []*config.Route{
+			{Match: map[string]string{"team": "a"}},
+		},
+	}, nil)
+}
+
+func TestRouteKey(t *testing.T) {
+	a, b := reloadTestRoute("team"), reloadTestRoute("team")
+	if routeKey(a.Routes[0]) != routeKey(b.Routes[0]) {
+		t.Fatalf("Expected same key for unchanged routes")
+	}
+	if routeKey(a) == routeKey(a.Routes[0]) {
+		t.Fatalf("Expected different keys for different routes")
+	}
+	if routeKey(a) == routeKey(reloadTestRoute("other")) {
+		t.Fatalf("Expected different key for changed route")
+	}
+}
+
+func TestDispatcherDrainInherit(t *testing.T) {
+	alerts, err := mem.NewAlerts("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer alerts.Close()
+
+	var (
+		notified = make(chan error, 10)
+		unblock  = make(chan struct{})
+	)
+	blocking := notify.StageFunc(func(ctx context.Context, as ...*types.Alert) (context.Context, []*types.Alert, error) {
+		<-unblock
+		notified <- ctx.Err()
+		return ctx, as, nil
+	})
+
+	d := NewDispatcher(alerts, reloadTestRoute("team"), blocking, types.NewMarker(), nil, nil, 1, 1, nil)
+	go d.Run()
+
+	now := time.Now()
+	if err := alerts.Put(&types.Alert{
+		Alert: model.Alert{
+			Labels:   model.LabelSet{"alertname": "down"},
+			StartsAt: now,
+			EndsAt:   now.Add(time.Hour),
+		},
+		UpdatedAt: now,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	// Wait for the flush to be in flight.
+	time.Sleep(100 * time.Millisecond)
+
+	drained := make(chan struct{})
+	go func() {
+		d.Drain(5 * time.Second)
+		close(drained)
+	}()
+	select {
+	case <-drained:
+		t.Fatalf("Expected drain to wait for the notification in flight")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(unblock)
+
+	select {
+	case err := <-notified:
+		if err != nil {
+			t.Fatalf("Expected notification to complete but got %s", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected notification to complete")
+	}
+	<-drained
+
+	// The group of the unchanged route keeps waiting for its group interval.
+	d2 := NewDispatcher(alerts, reloadTestRoute("team"), blocking, types.NewMarker(), nil, nil, 1, 1, nil)
+	d2.Inherit(d)
+	go d2.Run()
+
+	select {
+	case <-notified:
+		t.Fatalf("Expected no notification for the continued group")
+	case <-time.After(200 * time.Millisecond):
+	}
+	d2.Drain(time.Second)
+
+	// The group of a changed route starts afresh.
+	d3 := NewDispatcher(alerts, reloadTestRoute("other"), blocking, types.NewMarker(), nil, nil, 1, 1, nil)
+	d3.Inherit(d2)
+	go d3.Run()
+	defer d3.Stop()
+
+	select {
+	case <-notified:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected notification for the group of the changed route")
+	}
+}
@@ -79,6 +79,24 @@ func NewRoute(cr *config.Route, parent *Route) *Route {
 	if cr.RepeatInterval != nil {
 		opts.RepeatInterval = time.Duration(*cr.RepeatInterval)
 	}
+	if cr.GroupWaitMax != nil {
+		opts.GroupWaitMax = time.Duration(*cr.GroupWaitMax)
+	}
+	if cr.ResolveTimeout != nil {
+		opts.ResolveTimeout = time.Duration(*cr.ResolveTimeout)
+	}
+	if cr.FlushOnSilenceExpiry != nil {
+		opts.FlushOnSilenceExpiry = *cr.FlushOnSilenceExpiry
+	}
+	if cr.Escalations != nil {
+		opts.Escalations = make([]Escalation, 0, len(cr.Escalations))
+		for _, e := range cr.Escalations {
+			opts.Escalations = append(opts.Escalations, Escalation{
+				Receiver: e.Receiver,
+				After:    time.Duration(e.After),
+			})
+		}
+	}
 
 	// Build matchers.
 	var matchers types.Matchers
@@ -111,6 +129,22 @@ func NewRoutes(croutes []*config.Route, parent *Route) []*Route {
 	return res
 }
 
+// ResolveTimeout returns the resolve timeout of alerts with the given labels.
+// If the alerts match several routes, the longest of their resolve timeouts
+// is used. It returns def if none of the routes overrides the resolve timeout.
+func (r *Route) ResolveTimeout(lset model.LabelSet, def time.Duration) time.Duration {
+	var res time.Duration
+	for _, m := range r.Match(lset) {
+		if m.RouteOpts.ResolveTimeout > res {
+			res = m.RouteOpts.ResolveTimeout
+		}
+	}
+	if res == 0 {
+		return def
+	}
+	return res
+}
+
 // Match does a depth-first left-to-right search through the route tree
 // and returns the matching routing nodes.
 func (r *Route) Match(lset model.LabelSet) []*Route {
@@ -139,6 +173,14 @@ func (r *Route) Match(lset model.LabelSet) []*Route {
 	return all
 }
 
+// Path returns the routes from the root of the tree to the route.
+func (r *Route) Path() []*Route {
+	if r.parent == nil {
+		return []*Route{r}
+	}
+	return append(r.parent.Path(), r)
+}
+
 // SquashMatchers returns the total set of matchers on the path of the tree
 // that have to apply to reach the route.
 func (r *Route) SquashMatchers() types.Matchers {
@@ -181,6 +223,29 @@ type RouteOpts struct {
 	GroupWait      time.Duration
 	GroupInterval  time.Duration
 	RepeatInterval time.Duration
+
+	// If longer than GroupWait, the first notification is delayed while
+	// new alerts keep joining the group, up to GroupWaitMax.
+	GroupWaitMax time.Duration
+
+	// Receivers notified in addition while the group's alerts keep
+	// firing unacknowledged.
+	Escalations []Escalation
+
+	// If set, overrides the global resolve timeout for alerts matching
+	// the route.
+	ResolveTimeout time.Duration
+
+	// Whether groups are flushed immediately once a silence muting their
+	// firing alerts expired.
+	FlushOnSilenceExpiry bool
+}
+
+// Escalation notifies a receiver if a group's alerts are still firing and
+// not acknowledged the given time after the group was first notified.
+type Escalation struct {
+	Receiver string        `json:"receiver"`
+	After    time.Duration `json:"after"`
 }
 
 func (ro *RouteOpts) String() string {
@@ -199,11 +264,21 @@ func (ro *RouteOpts) MarshalJSON() ([]byte, error) {
 		GroupWait      time.Duration    `json:"groupWait"`
 		GroupInterval  time.Duration    `json:"groupInterval"`
 		RepeatInterval time.Duration    `json:"repeatInterval"`
+		GroupWaitMax   time.Duration    `json:"groupWaitMax,omitempty"`
+		Escalations    []Escalation     `json:"escalations,omitempty"`
+		ResolveTimeout time.Duration    `json:"resolveTimeout,omitempty"`
+
+		FlushOnSilenceExpiry bool `json:"flushOnSilenceExpiry,omitempty"`
 	}{
 		Receiver:       ro.Receiver,
 		GroupWait:      ro.GroupWait,
 		GroupInterval:  ro.GroupInterval,
 		RepeatInterval: ro.RepeatInterval,
+		GroupWaitMax:   ro.GroupWaitMax,
+		Escalations:    ro.Escalations,
+		ResolveTimeout: ro.ResolveTimeout,
+
+		FlushOnSilenceExpiry: ro.FlushOnSilenceExpiry,
 	}
 	for ln := range ro.GroupBy {
 		v.GroupBy = append(v.GroupBy, ln)
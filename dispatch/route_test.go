@@ -238,3 +238,94 @@ routes:
 		}
 	}
 }
+
+func TestRouteResolveTimeout(t *testing.T) {
+	in := `
+receiver: 'notify-def'
+
+routes:
+- match:
+    job: 'blackbox'
+  resolve_timeout: 2m
+  continue: true
+  routes:
+  - match:
+      env: 'production'
+    receiver: 'notify-production'
+
+- match:
+    job: 'blackbox'
+    env: 'production'
+  resolve_timeout: 10m
+
+- match:
+    job: 'batch'
+  resolve_timeout: 2h
+`
+	var ctree config.Route
+	if err := yaml.Unmarshal([]byte(in), &ctree); err != nil {
+		t.Fatal(err)
+	}
+	tree := NewRoute(&ctree, nil)
+
+	tests := []struct {
+		input    model.LabelSet
+		expected time.Duration
+	}{
+		{
+			input:    model.LabelSet{"job": "other"},
+			expected: 5 * time.Minute,
+		},
+		{
+			input:    model.LabelSet{"job": "blackbox"},
+			expected: 2 * time.Minute,
+		},
+		{
+			// Child routes inherit the resolve timeout and the longest
+			// timeout of all matching routes is used.
+			input:    model.LabelSet{"job": "blackbox", "env": "production"},
+			expected: 10 * time.Minute,
+		},
+		{
+			input:    model.LabelSet{"job": "batch"},
+			expected: 2 * time.Hour,
+		},
+	}
+	for _, test := range tests {
+		if got := tree.ResolveTimeout(test.input, 5*time.Minute); got != test.expected {
+			t.Errorf("expected resolve timeout %s for %v but got %s", test.expected, test.input, got)
+		}
+	}
+}
+
+func TestRoutePath(t *testing.T) {
+	in := `
+receiver: 'notify-def'
+
+routes:
+- match:
+    owner: 'team-A'
+  receiver: 'notify-A'
+  routes:
+  - match:
+      env: 'production'
+    receiver: 'notify-productionA'
+`
+	var ctree config.Route
+	if err := yaml.Unmarshal([]byte(in), &ctree); err != nil {
+		t.Fatal(err)
+	}
+	tree := NewRoute(&ctree, nil)
+
+	matches := tree.Match(model.LabelSet{"owner": "team-A", "env": "production"})
+	if len(matches) != 1 {
+		t.Fatalf("expected one matching route but got %d", len(matches))
+	}
+	var receivers []string
+	for _, r := range matches[0].Path() {
+		receivers = append(receivers, r.RouteOpts.Receiver)
+	}
+	if !reflect.DeepEqual(receivers, []string{"notify-def", "notify-A", "notify-productionA"}) {
+		t.Errorf("unexpected path %v", receivers)
+	}
+}
@@ -0,0 +1,230 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dispatch
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/prometheus/common/log"
+	"github.com/prometheus/common/model"
+	"golang.org/x/net/context"
+
+	"github.com/prometheus/alertmanager/notify"
+	"github.com/prometheus/alertmanager/provider"
+	"github.com/prometheus/alertmanager/silence"
+	"github.com/prometheus/alertmanager/silence/silencepb"
+	"github.com/prometheus/alertmanager/types"
+)
+
+// SilenceExpiringAlertName is the alertname of the alerts that are sent for
+// silences about to expire.
+const SilenceExpiringAlertName = "SilenceExpiring"
+
+// silenceExpiryInterval is the interval at which silences are checked for
+// their upcoming expiry.
+const silenceExpiryInterval = 30 * time.Second
+
+// SilenceExpiryNotifier sends a notification to a receiver for every active
+// silence that ends within a configured duration while alerts it matches are
+// still firing.
+//
+// Each expiring silence is represented by a single alert carrying the silence's
+// ID and creator as labels. Notifications are deduplicated through the regular
+// notification log.
+type SilenceExpiryNotifier struct {
+	alerts   provider.Alerts
+	silences *silence.Silences
+	stage    notify.Stage
+	receiver string
+	before   time.Duration
+	timeout  func(time.Duration) time.Duration
+
+	mtx   sync.Mutex
+	stopc chan struct{}
+}
+
+// NewSilenceExpiryNotifier returns a new SilenceExpiryNotifier. The stage
+// must deliver notifications to the given receiver without muting them.
+func NewSilenceExpiryNotifier(
+	ap provider.Alerts,
+	s *silence.Silences,
+	st notify.Stage,
+	receiver string,
+	before time.Duration,
+	to func(time.Duration) time.Duration,
+) *SilenceExpiryNotifier {
+	if to == nil {
+		to = func(d time.Duration) time.Duration { return d }
+	}
+	return &SilenceExpiryNotifier{
+		alerts:   ap,
+		silences: s,
+		stage:    st,
+		receiver: receiver,
+		before:   before,
+		timeout:  to,
+	}
+}
+
+// Run checks for expiring silences until Stop is called.
+func (n *SilenceExpiryNotifier) Run() {
+	n.mtx.Lock()
+	n.stopc = make(chan struct{})
+	stopc := n.stopc
+	n.mtx.Unlock()
+
+	t := time.NewTicker(silenceExpiryInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-stopc:
+			return
+		case now := <-t.C:
+			n.check(now)
+		}
+	}
+}
+
+// Stop the background processing of the SilenceExpiryNotifier.
+func (n *SilenceExpiryNotifier) Stop() {
+	if n == nil {
+		return
+	}
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+
+	if n.stopc != nil {
+		close(n.stopc)
+		n.stopc = nil
+	}
+}
+
+// check notifies about all silences that expire within the configured
+// duration after now and still match firing alerts.
+func (n *SilenceExpiryNotifier) check(now time.Time) {
+	sils, err := n.silences.Query(silence.QState(silence.StateActive))
+	if err != nil {
+		log.Errorf("Querying silences failed: %s", err)
+		return
+	}
+	expiring := map[string]*silencepb.Silence{}
+	for _, sil := range sils {
+		endsAt, err := ptypes.Timestamp(sil.EndsAt)
+		if err != nil {
+			log.Errorf("Invalid end time of silence %s: %s", sil.Id, err)
+			continue
+		}
+		if endsAt.Before(now.Add(n.before)) {
+			expiring[sil.Id] = sil
+		}
+	}
+	if len(expiring) == 0 {
+		return
+	}
+
+	firing, err := n.firingAlerts(expiring)
+	if err != nil {
+		log.Errorf("Retrieving firing alerts failed: %s", err)
+		return
+	}
+
+	for id, sil := range expiring {
+		if firing[id] == 0 {
+			continue
+		}
+		a, err := silenceExpiringAlert(sil, firing[id], now)
+		if err != nil {
+			log.Errorf("Creating expiry notification for silence %s failed: %s", id, err)
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), n.timeout(silenceExpiryInterval))
+
+		ctx = notify.WithNow(ctx, now)
+		ctx = notify.WithGroupKey(ctx, a.Fingerprint())
+		ctx = notify.WithGroupLabels(ctx, a.Labels)
+		ctx = notify.WithReceiverName(ctx, n.receiver)
+		// The notification is only repeated if the silence was extended.
+		ctx = notify.WithRepeatInterval(ctx, n.before)
+
+		if _, _, err := n.stage.Exec(ctx, a); err != nil {
+			log.Errorf("Notifying about expiring silence %s failed: %s", id, err)
+		}
+		cancel()
+	}
+}
+
+// firingAlerts returns the number of firing alerts matched by each of the
+// given silences.
+func (n *SilenceExpiryNotifier) firingAlerts(sils map[string]*silencepb.Silence) (map[string]int, error) {
+	it := n.alerts.GetPending()
+	defer it.Close()
+
+	res := map[string]int{}
+	for a := range it.Next() {
+		if err := it.Err(); err != nil {
+			return nil, err
+		}
+		if a.Resolved() {
+			continue
+		}
+		matched, err := n.silences.Query(
+			silence.QState(silence.StateActive),
+			silence.QMatchesAlert(a.Labels, a.Annotations),
+		)
+		if err != nil {
+			return nil, err
+		}
+		for _, sil := range matched {
+			if _, ok := sils[sil.Id]; ok {
+				res[sil.Id]++
+			}
+		}
+	}
+	return res, nil
+}
+
+// silenceExpiringAlert returns the alert representing the given expiring silence.
+func silenceExpiringAlert(sil *silencepb.Silence, firing int, now time.Time) (*types.Alert, error) {
+	endsAt, err := ptypes.Timestamp(sil.EndsAt)
+	if err != nil {
+		return nil, err
+	}
+	var createdBy, comment string
+	if len(sil.Comments) > 0 {
+		createdBy = sil.Comments[0].Author
+		comment = sil.Comments[0].Comment
+	}
+	return &types.Alert{
+		Alert: model.Alert{
+			Labels: model.LabelSet{
+				model.AlertNameLabel: SilenceExpiringAlertName,
+				"silence_id":         model.LabelValue(sil.Id),
+				"created_by":         model.LabelValue(createdBy),
+			},
+			Annotations: model.LabelSet{
+				"comment":       model.LabelValue(comment),
+				"ends_at":       model.LabelValue(endsAt.Format(time.RFC3339)),
+				"firing_alerts": model.LabelValue(strconv.Itoa(firing)),
+			},
+			StartsAt: now,
+			EndsAt:   endsAt,
+		},
+		UpdatedAt: now,
+	}, nil
+}
@@ -0,0 +1,86 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dispatch
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+
+	"github.com/prometheus/alertmanager/notify"
+	"github.com/prometheus/alertmanager/provider/mem"
+	"github.com/prometheus/alertmanager/silence"
+	"github.com/prometheus/alertmanager/silence/silencepb"
+	"github.com/prometheus/alertmanager/types"
+)
+
+func TestSilenceExpiryNotifierCheck(t *testing.T) {
+	alerts, err := mem.NewAlerts("")
+	require.NoError(t, err)
+	defer alerts.Close()
+
+	sils, err := silence.New(silence.Options{})
+	require.NoError(t, err)
+
+	now := time.Now()
+
+	newSilence := func(name string, end time.Time) string {
+		endsAt, err := ptypes.TimestampProto(end)
+		require.NoError(t, err)
+
+		id, err := sils.Create(&silencepb.Silence{
+			Matchers: []*silencepb.Matcher{{Name: "alertname", Pattern: name}},
+			EndsAt:   endsAt,
+			Comments: []*silencepb.Comment{{Author: "me", Comment: "maintenance"}},
+		})
+		require.NoError(t, err)
+		return id
+	}
+	expiring := newSilence("a", now.Add(5*time.Minute))
+	newSilence("a", now.Add(time.Hour))
+	newSilence("b", now.Add(5*time.Minute))
+
+	require.NoError(t, alerts.Put(&types.Alert{
+		Alert: model.Alert{
+			Labels:   model.LabelSet{"alertname": "a"},
+			StartsAt: now.Add(-time.Minute),
+			EndsAt:   now.Add(time.Hour),
+		},
+		UpdatedAt: now,
+	}))
+
+	var got []*types.Alert
+	stage := notify.StageFunc(func(ctx context.Context, as ...*types.Alert) (context.Context, []*types.Alert, error) {
+		if rcv, ok := notify.ReceiverName(ctx); !ok || rcv != "expiry" {
+			t.Errorf("wrong receiver: %q", rcv)
+		}
+		got = append(got, as...)
+		return ctx, as, nil
+	})
+
+	n := NewSilenceExpiryNotifier(alerts, sils, stage, "expiry", 10*time.Minute, nil)
+	n.check(now)
+
+	require.Len(t, got, 1)
+	require.Equal(t, model.LabelSet{
+		"alertname":  SilenceExpiringAlertName,
+		"silence_id": model.LabelValue(expiring),
+		"created_by": "me",
+	}, got[0].Labels)
+	require.Equal(t, model.LabelValue("1"), got[0].Annotations["firing_alerts"])
+}
@@ -0,0 +1,109 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dispatch
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/common/log"
+
+	"github.com/prometheus/alertmanager/provider"
+	"github.com/prometheus/alertmanager/silence"
+	"github.com/prometheus/alertmanager/types"
+)
+
+// silenceUsageInterval is the interval at which the usage of silences is
+// recorded.
+const silenceUsageInterval = 30 * time.Second
+
+// SilenceUsageTracker periodically records how many firing alerts each
+// silence mutes. Silences that match nothing for a long time can thereby be
+// identified and cleaned up.
+//
+// An alert counts towards the silence it was last marked as silenced by
+// in the notification pipeline.
+type SilenceUsageTracker struct {
+	alerts   provider.Alerts
+	marker   types.Marker
+	silences *silence.Silences
+
+	mtx   sync.Mutex
+	stopc chan struct{}
+}
+
+// NewSilenceUsageTracker returns a new SilenceUsageTracker.
+func NewSilenceUsageTracker(ap provider.Alerts, mk types.Marker, s *silence.Silences) *SilenceUsageTracker {
+	return &SilenceUsageTracker{
+		alerts:   ap,
+		marker:   mk,
+		silences: s,
+	}
+}
+
+// Run records the usage of silences until Stop is called.
+func (u *SilenceUsageTracker) Run() {
+	u.mtx.Lock()
+	u.stopc = make(chan struct{})
+	stopc := u.stopc
+	u.mtx.Unlock()
+
+	t := time.NewTicker(silenceUsageInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-stopc:
+			return
+		case <-t.C:
+			if err := u.update(); err != nil {
+				log.Errorf("Recording silence usage failed: %s", err)
+			}
+		}
+	}
+}
+
+// Stop the background processing of the SilenceUsageTracker.
+func (u *SilenceUsageTracker) Stop() {
+	if u == nil {
+		return
+	}
+	u.mtx.Lock()
+	defer u.mtx.Unlock()
+
+	if u.stopc != nil {
+		close(u.stopc)
+		u.stopc = nil
+	}
+}
+
+// update counts the firing alerts muted by each silence and records them.
+func (u *SilenceUsageTracker) update() error {
+	it := u.alerts.GetPending()
+	defer it.Close()
+
+	counts := map[string]int{}
+	for a := range it.Next() {
+		if err := it.Err(); err != nil {
+			return err
+		}
+		if a.Resolved() {
+			continue
+		}
+		if sid, ok := u.marker.Silenced(a.Fingerprint()); ok {
+			counts[sid]++
+		}
+	}
+	return u.silences.SetUsage(counts)
+}
@@ -0,0 +1,94 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dispatch
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+
+	"github.com/prometheus/alertmanager/provider/mem"
+	"github.com/prometheus/alertmanager/silence"
+	"github.com/prometheus/alertmanager/silence/silencepb"
+	"github.com/prometheus/alertmanager/types"
+)
+
+func TestSilenceUsageTrackerUpdate(t *testing.T) {
+	alerts, err := mem.NewAlerts("")
+	require.NoError(t, err)
+	defer alerts.Close()
+
+	sils, err := silence.New(silence.Options{})
+	require.NoError(t, err)
+
+	now := time.Now()
+
+	newSilence := func(name string) string {
+		endsAt, err := ptypes.TimestampProto(now.Add(time.Hour))
+		require.NoError(t, err)
+
+		id, err := sils.Create(&silencepb.Silence{
+			Matchers: []*silencepb.Matcher{{Name: "alertname", Pattern: name}},
+			EndsAt:   endsAt,
+		})
+		require.NoError(t, err)
+		return id
+	}
+	used := newSilence("a")
+	unused := newSilence("b")
+
+	a := &types.Alert{
+		Alert: model.Alert{
+			Labels:   model.LabelSet{"alertname": "a"},
+			StartsAt: now.Add(-time.Minute),
+			EndsAt:   now.Add(time.Hour),
+		},
+		UpdatedAt: now,
+	}
+	require.NoError(t, alerts.Put(a))
+
+	marker := types.NewMarker()
+	marker.SetSilenced(a.Fingerprint(), used)
+
+	u := NewSilenceUsageTracker(alerts, marker, sils)
+	require.NoError(t, u.update())
+
+	usage, ok := sils.Usage(used)
+	require.True(t, ok)
+	require.Equal(t, 1, usage.MutedAlerts)
+	require.False(t, usage.LastMatched.IsZero())
+	lastMatched := usage.LastMatched
+
+	usage, ok = sils.Usage(unused)
+	require.True(t, ok)
+	require.Equal(t, silence.Usage{}, usage)
+
+	// The last match is kept once the silence stops muting alerts.
+	marker.SetSilenced(a.Fingerprint())
+	require.NoError(t, u.update())
+
+	usage, ok = sils.Usage(used)
+	require.True(t, ok)
+	require.Equal(t, silence.Usage{LastMatched: lastMatched}, usage)
+
+	// Usage is no longer recorded for expired silences.
+	require.NoError(t, sils.Expire(unused))
+	require.NoError(t, u.update())
+
+	_, ok = sils.Usage(unused)
+	require.False(t, ok)
+}
@@ -0,0 +1,233 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dispatch
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/common/log"
+	"github.com/prometheus/common/model"
+	"golang.org/x/net/context"
+
+	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/notify"
+	"github.com/prometheus/alertmanager/provider"
+	"github.com/prometheus/alertmanager/types"
+)
+
+// AlertStormAlertName is the alertname of the alerts that are sent for
+// alert storms.
+const AlertStormAlertName = "AlertStorm"
+
+// stormCheckInterval is the interval at which storm rules are evaluated.
+const stormCheckInterval = 10 * time.Second
+
+// StormSuppressor detects alert storms as defined by storm rules. While a
+// storm lasts, the alerts of the rule are muted and a single alert about the
+// storm is sent to the rule's receiver instead.
+type StormSuppressor struct {
+	alerts  provider.Alerts
+	marker  types.Marker
+	rules   []*stormRule
+	timeout func(time.Duration) time.Duration
+
+	mtx   sync.RWMutex
+	stopc chan struct{}
+}
+
+type stormRule struct {
+	conf     *config.StormRule
+	matchers types.Matchers
+	stage    notify.Stage
+
+	// The start of the current storm, zero if there is none.
+	startedAt time.Time
+}
+
+// NewStormSuppressor returns a new StormSuppressor. The stages must deliver
+// notifications to the receiver of the respective rule without muting them.
+func NewStormSuppressor(
+	ap provider.Alerts,
+	rules []*config.StormRule,
+	stages []notify.Stage,
+	mk types.Marker,
+	to func(time.Duration) time.Duration,
+) *StormSuppressor {
+	if to == nil {
+		to = func(d time.Duration) time.Duration { return d }
+	}
+	s := &StormSuppressor{
+		alerts:  ap,
+		marker:  mk,
+		timeout: to,
+	}
+	for i, cr := range rules {
+		r := &stormRule{conf: cr, stage: stages[i]}
+		for ln, lv := range cr.Match {
+			r.matchers = append(r.matchers, types.NewMatcher(model.LabelName(ln), lv))
+		}
+		for ln, lv := range cr.MatchRE {
+			r.matchers = append(r.matchers, types.NewRegexMatcher(model.LabelName(ln), lv.Regexp))
+		}
+		s.rules = append(s.rules, r)
+	}
+	return s
+}
+
+// Run evaluates the storm rules until Stop is called.
+func (s *StormSuppressor) Run() {
+	s.mtx.Lock()
+	s.stopc = make(chan struct{})
+	stopc := s.stopc
+	s.mtx.Unlock()
+
+	t := time.NewTicker(stormCheckInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-stopc:
+			return
+		case now := <-t.C:
+			s.check(now)
+		}
+	}
+}
+
+// Stop the background processing of the StormSuppressor.
+func (s *StormSuppressor) Stop() {
+	if s == nil {
+		return
+	}
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if s.stopc != nil {
+		close(s.stopc)
+		s.stopc = nil
+	}
+}
+
+// Mutes implements the types.Muter interface. Alerts are only marked as
+// inhibited if they are muted.
+func (s *StormSuppressor) Mutes(lset model.LabelSet) bool {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	for _, r := range s.rules {
+		if !r.startedAt.IsZero() && r.matchers.Match(lset) {
+			s.marker.SetInhibited(lset.Fingerprint(), true)
+			return true
+		}
+	}
+	return false
+}
+
+// check starts and ends storms based on the currently firing alerts and
+// notifies about ongoing storms.
+func (s *StormSuppressor) check(now time.Time) {
+	firing, recent, err := s.count(now)
+	if err != nil {
+		log.Errorf("Counting alerts for storm rules failed: %s", err)
+		return
+	}
+	for i, r := range s.rules {
+		s.mtx.Lock()
+		if r.startedAt.IsZero() && recent[i] > r.conf.Threshold {
+			r.startedAt = now
+			log.With("storm", r.conf.Name).Warnf("Alert storm started with %d alerts firing", firing[i])
+		}
+		startedAt := r.startedAt
+		ended := !startedAt.IsZero() && firing[i] <= r.conf.Threshold
+		if ended {
+			r.startedAt = time.Time{}
+			log.With("storm", r.conf.Name).Infof("Alert storm ended with %d alerts firing", firing[i])
+		}
+		s.mtx.Unlock()
+
+		if startedAt.IsZero() {
+			continue
+		}
+		a := stormAlert(r.conf, startedAt, firing[i], now, ended)
+		s.notify(r, a, now)
+	}
+}
+
+// count returns the number of firing alerts matched by each rule and the
+// number of those that started firing within the rule's window.
+func (s *StormSuppressor) count(now time.Time) (firing, recent []int, err error) {
+	it := s.alerts.GetPending()
+	defer it.Close()
+
+	firing = make([]int, len(s.rules))
+	recent = make([]int, len(s.rules))
+
+	for a := range it.Next() {
+		if err := it.Err(); err != nil {
+			return nil, nil, err
+		}
+		if a.Resolved() {
+			continue
+		}
+		for i, r := range s.rules {
+			if !r.matchers.Match(a.Labels) {
+				continue
+			}
+			firing[i]++
+			if a.StartsAt.After(now.Add(-time.Duration(r.conf.Window))) {
+				recent[i]++
+			}
+		}
+	}
+	return firing, recent, nil
+}
+
+func (s *StormSuppressor) notify(r *stormRule, a *types.Alert, now time.Time) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout(stormCheckInterval))
+	defer cancel()
+
+	ctx = notify.WithNow(ctx, now)
+	ctx = notify.WithGroupKey(ctx, a.Fingerprint())
+	ctx = notify.WithGroupLabels(ctx, a.Labels)
+	ctx = notify.WithReceiverName(ctx, r.conf.Receiver)
+	ctx = notify.WithRepeatInterval(ctx, time.Duration(r.conf.RepeatInterval))
+
+	if _, _, err := r.stage.Exec(ctx, a); err != nil {
+		log.With("storm", r.conf.Name).Errorf("Notifying about alert storm failed: %s", err)
+	}
+}
+
+// stormAlert returns the alert representing a storm of the given rule.
+func stormAlert(r *config.StormRule, startedAt time.Time, firing int, now time.Time, ended bool) *types.Alert {
+	a := &types.Alert{
+		Alert: model.Alert{
+			Labels: model.LabelSet{
+				model.AlertNameLabel: AlertStormAlertName,
+				"storm":              model.LabelValue(r.Name),
+			},
+			Annotations: model.LabelSet{
+				"firing_alerts": model.LabelValue(strconv.Itoa(firing)),
+				"threshold":     model.LabelValue(strconv.Itoa(r.Threshold)),
+			},
+			StartsAt: startedAt,
+		},
+		UpdatedAt: now,
+	}
+	if ended {
+		a.EndsAt = now
+	}
+	return a
+}
@@ -0,0 +1,102 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dispatch
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+
+	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/notify"
+	"github.com/prometheus/alertmanager/provider/mem"
+	"github.com/prometheus/alertmanager/types"
+)
+
+func TestStormSuppressorCheck(t *testing.T) {
+	alerts, err := mem.NewAlerts("")
+	require.NoError(t, err)
+	defer alerts.Close()
+
+	now := time.Now()
+
+	put := func(i int, endsAt time.Time) {
+		require.NoError(t, alerts.Put(&types.Alert{
+			Alert: model.Alert{
+				Labels: model.LabelSet{
+					"alertname": "NodeDown",
+					"instance":  model.LabelValue(fmt.Sprintf("node-%d", i)),
+				},
+				StartsAt: now.Add(-time.Minute),
+				EndsAt:   endsAt,
+			},
+			UpdatedAt: now,
+			Timeout:   endsAt.After(now),
+		}))
+	}
+	for i := 0; i < 4; i++ {
+		put(i, now.Add(time.Hour))
+	}
+
+	var got []*types.Alert
+	stage := notify.StageFunc(func(ctx context.Context, as ...*types.Alert) (context.Context, []*types.Alert, error) {
+		if rcv, ok := notify.ReceiverName(ctx); !ok || rcv != "storms" {
+			t.Errorf("wrong receiver: %q", rcv)
+		}
+		got = append(got, as...)
+		return ctx, as, nil
+	})
+
+	rule := &config.StormRule{
+		Name:           "nodes",
+		Match:          map[string]string{"alertname": "NodeDown"},
+		Threshold:      3,
+		Window:         model.Duration(5 * time.Minute),
+		Receiver:       "storms",
+		RepeatInterval: model.Duration(time.Hour),
+	}
+	marker := types.NewMarker()
+	s := NewStormSuppressor(alerts, []*config.StormRule{rule}, []notify.Stage{stage}, marker, nil)
+
+	lset := model.LabelSet{"alertname": "NodeDown", "instance": "node-0"}
+	require.False(t, s.Mutes(lset))
+
+	s.check(now)
+
+	require.Len(t, got, 1)
+	require.Equal(t, model.LabelSet{
+		"alertname": AlertStormAlertName,
+		"storm":     "nodes",
+	}, got[0].Labels)
+	require.Equal(t, model.LabelValue("4"), got[0].Annotations["firing_alerts"])
+	require.False(t, got[0].Resolved())
+
+	require.True(t, s.Mutes(lset))
+	require.True(t, marker.Inhibited(lset.Fingerprint()))
+	require.False(t, s.Mutes(model.LabelSet{"alertname": "Other"}))
+
+	// Resolving an alert ends the storm once the threshold is no longer exceeded.
+	put(0, now.Add(-time.Second))
+	got = nil
+	s.check(now.Add(time.Minute))
+
+	require.Len(t, got, 1)
+	require.Equal(t, now.Add(time.Minute), got[0].EndsAt)
+	require.Equal(t, model.LabelValue("3"), got[0].Annotations["firing_alerts"])
+	require.False(t, s.Mutes(lset))
+}
@@ -0,0 +1,272 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dispatch
+
+import (
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/prometheus/common/log"
+	"github.com/prometheus/common/model"
+	"golang.org/x/net/context"
+
+	"github.com/prometheus/alertmanager/nflog"
+	"github.com/prometheus/alertmanager/notify"
+	"github.com/prometheus/alertmanager/provider"
+	"github.com/prometheus/alertmanager/silence"
+	"github.com/prometheus/alertmanager/silence/silencepb"
+	"github.com/prometheus/alertmanager/template"
+	"github.com/prometheus/alertmanager/types"
+)
+
+// SummaryAlertName is the alertname of the alerts that carry summaries.
+const SummaryAlertName = "AlertmanagerSummary"
+
+// SummaryData is the data passed to the summary template.
+type SummaryData struct {
+	// The time of the summary and of the previous one.
+	Now   time.Time
+	Since time.Time
+
+	// The currently firing alerts.
+	Alerts template.Alerts
+	// The currently active silences.
+	Silences []*SummarySilence
+	// The number of groups that were notified since the previous summary,
+	// counted once per receiver integration.
+	Notifications int
+}
+
+// SummarySilence is an active silence in the summary.
+type SummarySilence struct {
+	ID        string
+	Matchers  string
+	CreatedBy string
+	Comment   string
+	EndsAt    time.Time
+}
+
+// SummaryNotifier periodically sends a summary of the firing alerts, active
+// silences and recent notifications to a receiver. The summary is rendered
+// from a template and sent as the summary annotation of a single alert.
+type SummaryNotifier struct {
+	alerts   provider.Alerts
+	silences *silence.Silences
+	nflog    nflog.Log
+	tmpl     *template.Template
+	text     string
+	stage    notify.Stage
+	receiver string
+	at       time.Time
+	interval time.Duration
+	timeout  func(time.Duration) time.Duration
+
+	mtx   sync.Mutex
+	stopc chan struct{}
+}
+
+// NewSummaryNotifier returns a new SummaryNotifier sending summaries every
+// interval at the time of day of at. The stage must deliver notifications
+// to the given receiver without muting them.
+func NewSummaryNotifier(
+	ap provider.Alerts,
+	s *silence.Silences,
+	l nflog.Log,
+	tmpl *template.Template,
+	text string,
+	st notify.Stage,
+	receiver string,
+	at time.Time,
+	interval time.Duration,
+	to func(time.Duration) time.Duration,
+) *SummaryNotifier {
+	if to == nil {
+		to = func(d time.Duration) time.Duration { return d }
+	}
+	return &SummaryNotifier{
+		alerts:   ap,
+		silences: s,
+		nflog:    l,
+		tmpl:     tmpl,
+		text:     text,
+		stage:    st,
+		receiver: receiver,
+		at:       at,
+		interval: interval,
+		timeout:  to,
+	}
+}
+
+// Run sends summaries until Stop is called.
+func (n *SummaryNotifier) Run() {
+	n.mtx.Lock()
+	n.stopc = make(chan struct{})
+	stopc := n.stopc
+	n.mtx.Unlock()
+
+	var (
+		next  = n.next(time.Now())
+		since = next.Add(-n.interval)
+		t     = time.NewTimer(next.Sub(time.Now()))
+	)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-stopc:
+			return
+		case <-t.C:
+			n.send(next, since)
+
+			since, next = next, n.next(next)
+			t.Reset(next.Sub(time.Now()))
+		}
+	}
+}
+
+// Stop the background processing of the SummaryNotifier.
+func (n *SummaryNotifier) Stop() {
+	if n == nil {
+		return
+	}
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+
+	if n.stopc != nil {
+		close(n.stopc)
+		n.stopc = nil
+	}
+}
+
+// next returns the first time after now at which a summary is due.
+func (n *SummaryNotifier) next(now time.Time) time.Time {
+	t := time.Date(now.Year(), now.Month(), now.Day(), n.at.Hour(), n.at.Minute(), 0, 0, now.Location())
+	for t.After(now) {
+		t = t.Add(-n.interval)
+	}
+	for !t.After(now) {
+		t = t.Add(n.interval)
+	}
+	return t
+}
+
+// send sends the summary for the given time.
+func (n *SummaryNotifier) send(now, since time.Time) {
+	data, err := n.data(now, since)
+	if err != nil {
+		log.Errorf("Gathering summary failed: %s", err)
+		return
+	}
+	text, err := n.tmpl.ExecuteTextString(n.text, data)
+	if err != nil {
+		log.Errorf("Rendering summary failed: %s", err)
+		return
+	}
+	a := summaryAlert(data, text, now.Add(n.interval))
+
+	ctx, cancel := context.WithTimeout(context.Background(), n.timeout(n.interval))
+	defer cancel()
+
+	ctx = notify.WithNow(ctx, now)
+	ctx = notify.WithGroupKey(ctx, a.Fingerprint())
+	ctx = notify.WithGroupLabels(ctx, a.Labels)
+	ctx = notify.WithReceiverName(ctx, n.receiver)
+	// Every summary is sent, even if nothing changed.
+	ctx = notify.WithRepeatInterval(ctx, 0)
+
+	if _, _, err := n.stage.Exec(ctx, a); err != nil {
+		log.Errorf("Sending summary failed: %s", err)
+	}
+}
+
+// data gathers the data of the summary.
+func (n *SummaryNotifier) data(now, since time.Time) (*SummaryData, error) {
+	it := n.alerts.GetPending()
+	defer it.Close()
+
+	var firing []*types.Alert
+	for a := range it.Next() {
+		if err := it.Err(); err != nil {
+			return nil, err
+		}
+		if !a.Resolved() {
+			firing = append(firing, a)
+		}
+	}
+	sort.Sort(types.AlertSlice(firing))
+
+	sils, err := n.silences.Query(silence.QState(silence.StateActive))
+	if err != nil {
+		return nil, err
+	}
+	var silences []*SummarySilence
+	for _, sil := range sils {
+		endsAt, err := ptypes.Timestamp(sil.EndsAt)
+		if err != nil {
+			return nil, err
+		}
+		s := &SummarySilence{ID: sil.Id, EndsAt: endsAt}
+		for i, m := range sil.Matchers {
+			if i > 0 {
+				s.Matchers += ", "
+			}
+			op := "="
+			if m.Type == silencepb.Matcher_REGEXP {
+				op = "=~"
+			}
+			s.Matchers += m.Name + op + strconv.Quote(m.Pattern)
+		}
+		if len(sil.Comments) > 0 {
+			s.CreatedBy = sil.Comments[0].Author
+			s.Comment = sil.Comments[0].Comment
+		}
+		silences = append(silences, s)
+	}
+
+	entries, err := n.nflog.Query(nflog.QSince(since))
+	if err != nil {
+		return nil, err
+	}
+
+	return &SummaryData{
+		Now:           now,
+		Since:         since,
+		Alerts:        n.tmpl.Data(n.receiver, nil, firing...).Alerts,
+		Silences:      silences,
+		Notifications: len(entries),
+	}, nil
+}
+
+// summaryAlert returns the alert carrying the summary.
+func summaryAlert(data *SummaryData, text string, endsAt time.Time) *types.Alert {
+	return &types.Alert{
+		Alert: model.Alert{
+			Labels: model.LabelSet{
+				model.AlertNameLabel: SummaryAlertName,
+			},
+			Annotations: model.LabelSet{
+				"summary":         model.LabelValue(text),
+				"firing_alerts":   model.LabelValue(strconv.Itoa(len(data.Alerts))),
+				"active_silences": model.LabelValue(strconv.Itoa(len(data.Silences))),
+				"notifications":   model.LabelValue(strconv.Itoa(data.Notifications)),
+			},
+			StartsAt: data.Now,
+			EndsAt:   endsAt,
+		},
+		UpdatedAt: data.Now,
+	}
+}
@@ -0,0 +1,129 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dispatch
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+
+	"github.com/prometheus/alertmanager/nflog"
+	"github.com/prometheus/alertmanager/nflog/nflogpb"
+	"github.com/prometheus/alertmanager/notify"
+	"github.com/prometheus/alertmanager/provider/mem"
+	"github.com/prometheus/alertmanager/silence"
+	"github.com/prometheus/alertmanager/silence/silencepb"
+	"github.com/prometheus/alertmanager/template"
+	"github.com/prometheus/alertmanager/types"
+)
+
+func TestSummaryNotifierNext(t *testing.T) {
+	at, err := time.Parse("15:04", "09:00")
+	require.NoError(t, err)
+
+	n := NewSummaryNotifier(nil, nil, nil, nil, "", nil, "summary", at, 24*time.Hour, nil)
+
+	day := func(d, h, m int) time.Time {
+		return time.Date(2016, 11, d, h, m, 0, 0, time.Local)
+	}
+	require.Equal(t, day(1, 9, 0), n.next(day(1, 8, 30)))
+	require.Equal(t, day(2, 9, 0), n.next(day(1, 9, 0)))
+	require.Equal(t, day(2, 9, 0), n.next(day(1, 17, 0)))
+
+	n.interval = 6 * time.Hour
+	require.Equal(t, day(1, 3, 0), n.next(day(1, 1, 0)))
+	require.Equal(t, day(1, 21, 0), n.next(day(1, 17, 0)))
+}
+
+func TestSummaryNotifierSend(t *testing.T) {
+	alerts, err := mem.NewAlerts("")
+	require.NoError(t, err)
+	defer alerts.Close()
+
+	sils, err := silence.New(silence.Options{})
+	require.NoError(t, err)
+
+	tmpl, err := template.FromGlobs()
+	require.NoError(t, err)
+	tmpl.ExternalURL, err = url.Parse("http://localhost:9093")
+	require.NoError(t, err)
+
+	now := time.Now()
+
+	endsAt, err := ptypes.TimestampProto(now.Add(time.Hour))
+	require.NoError(t, err)
+	_, err = sils.Create(&silencepb.Silence{
+		Matchers: []*silencepb.Matcher{{Name: "alertname", Pattern: "b"}},
+		EndsAt:   endsAt,
+		Comments: []*silencepb.Comment{{Author: "me", Comment: "maintenance"}},
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, alerts.Put(
+		&types.Alert{
+			Alert: model.Alert{
+				Labels:   model.LabelSet{"alertname": "a"},
+				StartsAt: now.Add(-time.Minute),
+				EndsAt:   now.Add(time.Hour),
+			},
+			UpdatedAt: now,
+		},
+		&types.Alert{
+			Alert: model.Alert{
+				Labels:   model.LabelSet{"alertname": "resolved"},
+				StartsAt: now.Add(-time.Hour),
+				EndsAt:   now.Add(-time.Minute),
+			},
+			UpdatedAt: now,
+		},
+	))
+	nlog := &fakeNflog{entries: []*nflogpb.Entry{{
+		Receiver: &nflogpb.Receiver{GroupName: "team", Integration: "slack"},
+		GroupKey: []byte("group"),
+	}}}
+
+	var got []*types.Alert
+	stage := notify.StageFunc(func(ctx context.Context, as ...*types.Alert) (context.Context, []*types.Alert, error) {
+		if rcv, ok := notify.ReceiverName(ctx); !ok || rcv != "summary" {
+			t.Errorf("wrong receiver: %q", rcv)
+		}
+		got = append(got, as...)
+		return ctx, as, nil
+	})
+
+	text := `{{ len .Alerts }} {{ range .Silences }}{{ .Matchers }} by {{ .CreatedBy }}{{ end }} {{ .Notifications }}`
+
+	n := NewSummaryNotifier(alerts, sils, nlog, tmpl, text, stage, "summary", now, 24*time.Hour, nil)
+	n.send(now, now.Add(-time.Hour))
+
+	require.Len(t, got, 1)
+	require.Equal(t, model.LabelValue(SummaryAlertName), got[0].Labels[model.AlertNameLabel])
+	require.Equal(t, model.LabelValue(`1 alertname="b" by me 1`), got[0].Annotations["summary"])
+	require.Equal(t, model.LabelValue("1"), got[0].Annotations["firing_alerts"])
+	require.Equal(t, model.LabelValue("1"), got[0].Annotations["active_silences"])
+}
+
+type fakeNflog struct {
+	nflog.Log
+	entries []*nflogpb.Entry
+}
+
+func (l *fakeNflog) Query(p ...nflog.QueryParam) ([]*nflogpb.Entry, error) {
+	return l.entries, nil
+}
@@ -0,0 +1,190 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package events distributes lifecycle events of alerts, silences and
+// notifications to registered subscribers.
+package events
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+
+	"github.com/prometheus/alertmanager/types"
+)
+
+// Type is the type of an event.
+type Type string
+
+// The types of events published on the bus.
+const (
+	AlertCreated   Type = "alert_created"
+	AlertResolved  Type = "alert_resolved"
+	GroupNotified  Type = "group_notified"
+	SilenceCreated Type = "silence_created"
+	SilenceExpired Type = "silence_expired"
+)
+
+// Types contains all known event types.
+var Types = []Type{
+	AlertCreated,
+	AlertResolved,
+	GroupNotified,
+	SilenceCreated,
+	SilenceExpired,
+}
+
+// ParseType returns the event type with the given name.
+func ParseType(s string) (Type, error) {
+	for _, t := range Types {
+		if string(t) == s {
+			return t, nil
+		}
+	}
+	return "", fmt.Errorf("unknown event type %q", s)
+}
+
+// Event describes a state change of an alert, silence or notification.
+type Event struct {
+	Type Type      `json:"type"`
+	Time time.Time `json:"time"`
+
+	// The alert that was created or resolved.
+	Alert *types.Alert `json:"alert,omitempty"`
+	// The silence that was created or expired.
+	Silence *Silence `json:"silence,omitempty"`
+
+	// The receiver, integration, group key and alerts of a notification.
+	Receiver    string         `json:"receiver,omitempty"`
+	Integration string         `json:"integration,omitempty"`
+	GroupKey    string         `json:"groupKey,omitempty"`
+	Alerts      []*types.Alert `json:"alerts,omitempty"`
+}
+
+// Silence is the representation of a silence within an event.
+type Silence struct {
+	ID        string           `json:"id"`
+	Matchers  []*types.Matcher `json:"matchers"`
+	StartsAt  time.Time        `json:"startsAt"`
+	EndsAt    time.Time        `json:"endsAt"`
+	CreatedBy string           `json:"createdBy"`
+	Comment   string           `json:"comment"`
+}
+
+// A Handler processes events it has been subscribed to.
+type Handler func(*Event)
+
+// subscriptionBuffer is the number of events queued for a subscriber
+// before further events are dropped.
+const subscriptionBuffer = 1024
+
+var droppedEvents = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "alertmanager",
+	Name:      "events_dropped_total",
+	Help:      "The total number of events dropped because a subscriber fell behind.",
+}, []string{"subscriber"})
+
+func init() {
+	prometheus.MustRegister(droppedEvents)
+}
+
+type subscription struct {
+	name  string
+	types map[Type]bool
+	ch    chan *Event
+	done  chan struct{}
+}
+
+// Bus distributes published events to its subscribers. Every subscriber
+// processes its events sequentially in its own goroutine so that slow
+// subscribers never block publishers.
+type Bus struct {
+	mtx  sync.RWMutex
+	next int
+	subs map[int]*subscription
+}
+
+// NewBus returns a new event bus without subscribers.
+func NewBus() *Bus {
+	return &Bus{subs: map[int]*subscription{}}
+}
+
+// Subscribe registers a handler for events of the given types. If no types
+// are given, the handler receives all events. The returned function removes
+// the subscription and waits for the handler to finish processing.
+func (b *Bus) Subscribe(name string, h Handler, ts ...Type) (unsubscribe func()) {
+	s := &subscription{
+		name:  name,
+		types: map[Type]bool{},
+		ch:    make(chan *Event, subscriptionBuffer),
+		done:  make(chan struct{}),
+	}
+	for _, t := range ts {
+		s.types[t] = true
+	}
+
+	b.mtx.Lock()
+	id := b.next
+	b.next++
+	b.subs[id] = s
+	b.mtx.Unlock()
+
+	go func() {
+		defer close(s.done)
+		for e := range s.ch {
+			h(e)
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			b.mtx.Lock()
+			delete(b.subs, id)
+			close(s.ch)
+			b.mtx.Unlock()
+
+			<-s.done
+		})
+	}
+}
+
+// Publish sends the event to all subscribers of its type. Events are
+// dropped for subscribers whose queue is full. It is safe to call on a
+// nil Bus.
+func (b *Bus) Publish(e *Event) {
+	if b == nil {
+		return
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+
+	b.mtx.RLock()
+	defer b.mtx.RUnlock()
+
+	for _, s := range b.subs {
+		if len(s.types) > 0 && !s.types[e.Type] {
+			continue
+		}
+		select {
+		case s.ch <- e:
+		default:
+			droppedEvents.WithLabelValues(s.name).Inc()
+			log.With("subscriber", s.name).Warnf("Dropping %s event, subscriber is falling behind", e.Type)
+		}
+	}
+}
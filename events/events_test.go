@@ -0,0 +1,191 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package events
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+
+	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/provider/mem"
+	"github.com/prometheus/alertmanager/silence"
+	"github.com/prometheus/alertmanager/silence/silencepb"
+	"github.com/prometheus/alertmanager/types"
+)
+
+type recorder struct {
+	mtx    sync.Mutex
+	events []*Event
+}
+
+func (r *recorder) handle(e *Event) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.events = append(r.events, e)
+}
+
+func (r *recorder) types() []string {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	var res []string
+	for _, e := range r.events {
+		res = append(res, string(e.Type))
+	}
+	sort.Strings(res)
+	return res
+}
+
+func TestBus(t *testing.T) {
+	b := NewBus()
+
+	var all, silences recorder
+	unsubAll := b.Subscribe("all", all.handle)
+	unsubSilences := b.Subscribe("silences", silences.handle, SilenceCreated, SilenceExpired)
+
+	b.Publish(&Event{Type: AlertCreated})
+	b.Publish(&Event{Type: SilenceCreated})
+
+	// Unsubscribing waits for all queued events to be handled.
+	unsubAll()
+	unsubSilences()
+	unsubSilences()
+
+	b.Publish(&Event{Type: SilenceExpired})
+
+	require.Equal(t, []string{"alert_created", "silence_created"}, all.types())
+	require.Equal(t, []string{"silence_created"}, silences.types())
+
+	// Publishing on a nil bus is a no-op.
+	var nb *Bus
+	nb.Publish(&Event{Type: AlertCreated})
+}
+
+func TestParseType(t *testing.T) {
+	typ, err := ParseType("group_notified")
+	require.NoError(t, err)
+	require.Equal(t, GroupNotified, typ)
+
+	_, err = ParseType("unknown")
+	require.Error(t, err)
+}
+
+func TestWatcher(t *testing.T) {
+	alerts, err := mem.NewAlerts("")
+	require.NoError(t, err)
+	defer alerts.Close()
+
+	sils, err := silence.New(silence.Options{})
+	require.NoError(t, err)
+
+	b := NewBus()
+	var rec recorder
+	unsubscribe := b.Subscribe("test", rec.handle)
+
+	w := NewWatcher(alerts, sils, b)
+
+	now := time.Now()
+	newAlert := func(name string, endsAt time.Time) *types.Alert {
+		return &types.Alert{
+			Alert: model.Alert{
+				Labels:   model.LabelSet{"alertname": model.LabelValue(name)},
+				StartsAt: now.Add(-time.Minute),
+				EndsAt:   endsAt,
+			},
+			UpdatedAt: now,
+		}
+	}
+	newSilence := func(name string) string {
+		endsAt, err := ptypes.TimestampProto(now.Add(time.Hour))
+		require.NoError(t, err)
+		id, err := sils.Create(&silencepb.Silence{
+			Matchers: []*silencepb.Matcher{{Name: "alertname", Pattern: name}},
+			EndsAt:   endsAt,
+			Comments: []*silencepb.Comment{{Author: "me", Comment: "test"}},
+		})
+		require.NoError(t, err)
+		return id
+	}
+
+	// The initial state is not reported.
+	firing := newAlert("existing", now.Add(time.Hour))
+	firing.Timeout = true
+	require.NoError(t, alerts.Put(firing))
+	existing := newSilence("existing")
+	w.check(now)
+
+	resolved := newAlert("existing", now.Add(-time.Second))
+	resolved.UpdatedAt = now.Add(time.Second)
+	require.NoError(t, alerts.Put(resolved, newAlert("new", now.Add(time.Hour))))
+	require.NoError(t, sils.Expire(existing))
+	newSilence("new")
+	w.check(now)
+
+	unsubscribe()
+
+	require.Equal(t, []string{"alert_created", "alert_resolved", "silence_created", "silence_expired"}, rec.types())
+	for _, e := range rec.events {
+		switch e.Type {
+		case AlertCreated:
+			require.Equal(t, model.LabelValue("new"), e.Alert.Labels["alertname"])
+		case AlertResolved:
+			require.Equal(t, model.LabelValue("existing"), e.Alert.Labels["alertname"])
+		case SilenceCreated:
+			require.Equal(t, "new", e.Silence.Matchers[0].Value)
+			require.Equal(t, "me", e.Silence.CreatedBy)
+		case SilenceExpired:
+			require.Equal(t, existing, e.Silence.ID)
+		}
+	}
+}
+
+func TestWebhook(t *testing.T) {
+	received := make(chan *Event, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var e Event
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&e))
+		received <- &e
+	}))
+	defer srv.Close()
+
+	_, err := NewWebhook(&config.EventWebhookConfig{URL: srv.URL, Events: []string{"unknown"}})
+	require.Error(t, err)
+
+	h, err := NewWebhook(&config.EventWebhookConfig{URL: srv.URL, Events: []string{"group_notified"}})
+	require.NoError(t, err)
+
+	b := NewBus()
+	unsubscribe := h.Subscribe(b)
+	defer unsubscribe()
+
+	b.Publish(&Event{Type: AlertCreated})
+	b.Publish(&Event{Type: GroupNotified, Receiver: "team"})
+
+	select {
+	case e := <-received:
+		require.Equal(t, GroupNotified, e.Type)
+		require.Equal(t, "team", e.Receiver)
+	case <-time.After(5 * time.Second):
+		t.Fatal("event was not delivered")
+	}
+}
@@ -0,0 +1,213 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package events
+
+import (
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/prometheus/common/log"
+	"github.com/prometheus/common/model"
+
+	"github.com/prometheus/alertmanager/provider"
+	"github.com/prometheus/alertmanager/silence"
+	"github.com/prometheus/alertmanager/silence/silencepb"
+	"github.com/prometheus/alertmanager/types"
+)
+
+// watchInterval is the interval at which the Watcher checks alerts and
+// silences for state changes.
+const watchInterval = 10 * time.Second
+
+// Watcher publishes events for alerts that started firing or were resolved
+// and for silences that were created or expired.
+//
+// Alerts resolve and silences expire by the passing of time rather than
+// through an update, so their state is checked periodically. The state found
+// on the first check is not reported.
+type Watcher struct {
+	alerts   provider.Alerts
+	silences *silence.Silences
+	bus      *Bus
+
+	firing map[model.Fingerprint]*types.Alert
+	active map[string]*silencepb.Silence
+	seeded bool
+
+	mtx   sync.Mutex
+	stopc chan struct{}
+}
+
+// NewWatcher returns a new Watcher publishing to the given bus.
+func NewWatcher(ap provider.Alerts, s *silence.Silences, b *Bus) *Watcher {
+	return &Watcher{
+		alerts:   ap,
+		silences: s,
+		bus:      b,
+		firing:   map[model.Fingerprint]*types.Alert{},
+		active:   map[string]*silencepb.Silence{},
+	}
+}
+
+// Run checks for state changes until Stop is called.
+func (w *Watcher) Run() {
+	w.mtx.Lock()
+	w.stopc = make(chan struct{})
+	stopc := w.stopc
+	w.mtx.Unlock()
+
+	t := time.NewTicker(watchInterval)
+	defer t.Stop()
+
+	w.check(time.Now())
+
+	for {
+		select {
+		case <-stopc:
+			return
+		case now := <-t.C:
+			w.check(now)
+		}
+	}
+}
+
+// Stop the background processing of the Watcher.
+func (w *Watcher) Stop() {
+	if w == nil {
+		return
+	}
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	if w.stopc != nil {
+		close(w.stopc)
+		w.stopc = nil
+	}
+}
+
+// check publishes events for all changes since the previous check.
+func (w *Watcher) check(now time.Time) {
+	if err := w.checkAlerts(now); err != nil {
+		log.Errorf("Checking alerts for events failed: %s", err)
+	}
+	if err := w.checkSilences(now); err != nil {
+		log.Errorf("Checking silences for events failed: %s", err)
+	}
+	w.seeded = true
+}
+
+func (w *Watcher) checkAlerts(now time.Time) error {
+	it := w.alerts.GetPending()
+	defer it.Close()
+
+	firing := map[model.Fingerprint]*types.Alert{}
+	for a := range it.Next() {
+		if err := it.Err(); err != nil {
+			return err
+		}
+		if !a.ResolvedAt(now) {
+			firing[a.Fingerprint()] = a
+		}
+	}
+
+	if w.seeded {
+		for fp, a := range firing {
+			if _, ok := w.firing[fp]; !ok {
+				w.bus.Publish(&Event{Type: AlertCreated, Time: now, Alert: a})
+			}
+		}
+		for fp, a := range w.firing {
+			if _, ok := firing[fp]; ok {
+				continue
+			}
+			// Report the latest state of the alert if it is still known.
+			if cur, err := w.alerts.Get(fp); err == nil {
+				a = cur
+			}
+			w.bus.Publish(&Event{Type: AlertResolved, Time: now, Alert: a})
+		}
+	}
+	w.firing = firing
+	return nil
+}
+
+func (w *Watcher) checkSilences(now time.Time) error {
+	sils, err := w.silences.Query(silence.QState(silence.StateActive, silence.StatePending))
+	if err != nil {
+		return err
+	}
+	active := make(map[string]*silencepb.Silence, len(sils))
+	for _, sil := range sils {
+		active[sil.Id] = sil
+	}
+
+	if w.seeded {
+		for id, sil := range active {
+			if _, ok := w.active[id]; !ok {
+				w.publishSilence(SilenceCreated, now, sil)
+			}
+		}
+		for id, sil := range w.active {
+			if _, ok := active[id]; ok {
+				continue
+			}
+			// Report the updated end time of silences expired early.
+			if cur, err := w.silences.Query(silence.QIDs(id)); err == nil && len(cur) == 1 {
+				sil = cur[0]
+			}
+			w.publishSilence(SilenceExpired, now, sil)
+		}
+	}
+	w.active = active
+	return nil
+}
+
+func (w *Watcher) publishSilence(t Type, now time.Time, sil *silencepb.Silence) {
+	s, err := silenceFromProto(sil)
+	if err != nil {
+		log.Errorf("Invalid silence %s: %s", sil.Id, err)
+		return
+	}
+	w.bus.Publish(&Event{Type: t, Time: now, Silence: s})
+}
+
+func silenceFromProto(sil *silencepb.Silence) (*Silence, error) {
+	startsAt, err := ptypes.Timestamp(sil.StartsAt)
+	if err != nil {
+		return nil, err
+	}
+	endsAt, err := ptypes.Timestamp(sil.EndsAt)
+	if err != nil {
+		return nil, err
+	}
+	s := &Silence{
+		ID:       sil.Id,
+		StartsAt: startsAt,
+		EndsAt:   endsAt,
+	}
+	for _, m := range sil.Matchers {
+		s.Matchers = append(s.Matchers, &types.Matcher{
+			Name:         m.Name,
+			Value:        m.Pattern,
+			IsRegex:      m.Type == silencepb.Matcher_REGEXP,
+			IsAnnotation: m.Annotation,
+		})
+	}
+	if len(sil.Comments) > 0 {
+		s.CreatedBy = sil.Comments[0].Author
+		s.Comment = sil.Comments[0].Comment
+	}
+	return s, nil
+}
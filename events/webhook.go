@@ -0,0 +1,82 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package events
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/common/log"
+	"golang.org/x/net/context"
+	"golang.org/x/net/context/ctxhttp"
+
+	"github.com/prometheus/alertmanager/config"
+)
+
+// webhookTimeout is the timeout for delivering a single event.
+const webhookTimeout = 10 * time.Second
+
+// Webhook posts events as JSON to a URL.
+type Webhook struct {
+	url   string
+	types []Type
+}
+
+// NewWebhook returns a new Webhook for the given configuration.
+func NewWebhook(conf *config.EventWebhookConfig) (*Webhook, error) {
+	w := &Webhook{url: conf.URL}
+	for _, s := range conf.Events {
+		t, err := ParseType(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid event webhook for %s: %s", conf.URL, err)
+		}
+		w.types = append(w.types, t)
+	}
+	return w, nil
+}
+
+// Subscribe registers the webhook on the bus and returns a function removing
+// the subscription again.
+func (w *Webhook) Subscribe(b *Bus) (unsubscribe func()) {
+	return b.Subscribe(w.url, w.handle, w.types...)
+}
+
+func (w *Webhook) handle(e *Event) {
+	ctx, cancel := context.WithTimeout(context.Background(), webhookTimeout)
+	defer cancel()
+
+	if err := w.post(ctx, e); err != nil {
+		log.With("url", w.url).Errorf("Delivering %s event failed: %s", e.Type, err)
+	}
+}
+
+func (w *Webhook) post(ctx context.Context, e *Event) error {
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(e); err != nil {
+		return err
+	}
+	resp, err := ctxhttp.Post(ctx, http.DefaultClient, w.url, "application/json", &buf)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("unexpected status code %v", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,407 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package gossipstate implements the state of stores whose entries are
+// shared with other Alertmanager instances through the mesh network and
+// snapshotted to disk, such as acknowledgements and comments. The stores
+// only implement the handling specific to the type of their entries.
+package gossipstate
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/prometheus/common/log"
+	"github.com/weaveworks/mesh"
+)
+
+// UTCNow returns the current time in UTC.
+func UTCNow() time.Time {
+	return time.Now().UTC()
+}
+
+// Type implements the handling specific to the type of the entries of a
+// State.
+type Type interface {
+	// Key returns the key of the entry in the state.
+	Key(e interface{}) string
+
+	// Decode decodes and validates a JSON encoded entry.
+	Decode(b []byte) (interface{}, error)
+
+	// Size estimates the size of the JSON encoded entry.
+	Size(e interface{}) int
+
+	// Merge merges an entry received from another instance into the known
+	// entry with the same key. It returns the merged entry, or nil if the
+	// known entry remains unchanged.
+	Merge(prev, e interface{}) interface{}
+}
+
+// Options configures a new State.
+type Options struct {
+	// A snapshot file from which the initial state is loaded.
+	SnapshotFile string
+
+	// A function creating a mesh.Gossip on being called with a mesh.Gossiper.
+	Gossip func(g mesh.Gossiper) mesh.Gossip
+
+	// A logger used by background processing.
+	Logger log.Logger
+}
+
+// State holds the entries of a store and shares them with other instances.
+// It implements the mesh.Gossiper interface.
+//
+// Get, Set and Range must be called while holding the lock of the state.
+// All other methods acquire it themselves.
+type State struct {
+	sync.RWMutex
+
+	t      Type
+	logger log.Logger
+	gossip mesh.Gossip
+	d      Data
+}
+
+type nopGossip struct{}
+
+func (nopGossip) GossipBroadcast(d mesh.GossipData)         {}
+func (nopGossip) GossipUnicast(mesh.PeerName, []byte) error { return nil }
+
+// New returns a new State holding entries of the given type. If loading the
+// snapshot fails, the returned state is empty but usable.
+func New(t Type, o Options) (*State, error) {
+	s := &State{
+		t:      t,
+		logger: log.NewNopLogger(),
+		gossip: nopGossip{},
+		d:      newData(t),
+	}
+	if o.Logger != nil {
+		s.logger = o.Logger
+	}
+	if o.Gossip != nil {
+		s.gossip = o.Gossip(s)
+	}
+	if o.SnapshotFile != "" {
+		f, err := os.Open(o.SnapshotFile)
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+
+		if err := s.LoadSnapshot(f); err != nil {
+			return s, err
+		}
+	}
+	return s, nil
+}
+
+// Get returns the entry with the given key.
+func (s *State) Get(key string) (interface{}, bool) {
+	e, ok := s.d.m[key]
+	return e, ok
+}
+
+// Set stores the entry and gossips it to the other instances.
+func (s *State) Set(e interface{}) {
+	key := s.t.Key(e)
+	s.d.m[key] = e
+
+	d := newData(s.t)
+	d.m[key] = e
+	s.gossip.GossipBroadcast(d)
+}
+
+// Range calls f for every entry.
+func (s *State) Range(f func(e interface{})) {
+	for _, e := range s.d.m {
+		f(e)
+	}
+}
+
+// GC removes the entries for which expired returns true and returns their
+// number.
+func (s *State) GC(expired func(e interface{}) bool) int {
+	s.Lock()
+	defer s.Unlock()
+
+	var n int
+	for key, e := range s.d.m {
+		if expired(e) {
+			delete(s.d.m, key)
+			n++
+		}
+	}
+	return n
+}
+
+// Maintenance runs gc at the given interval. If the snapshot file is set,
+// a snapshot is written to it afterwards. Terminates on receiving from
+// stopc.
+func (s *State) Maintenance(interval time.Duration, snapf string, stopc <-chan struct{}, gc func() (int, error)) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	f := func() error {
+		if _, err := gc(); err != nil {
+			return err
+		}
+		if snapf == "" {
+			return nil
+		}
+		f, err := openReplace(snapf)
+		if err != nil {
+			return err
+		}
+		if _, err := s.Snapshot(f); err != nil {
+			return err
+		}
+		return f.Close()
+	}
+
+Loop:
+	for {
+		select {
+		case <-stopc:
+			break Loop
+		case <-t.C:
+			if err := f(); err != nil {
+				s.logger.With("err", err).Error("running maintenance failed")
+			}
+		}
+	}
+	// No need for final maintenance if we don't want to snapshot.
+	if snapf == "" {
+		return
+	}
+	if err := f(); err != nil {
+		s.logger.With("err", err).Error("creating shutdown snapshot failed")
+	}
+}
+
+// Snapshot writes all entries to w as a JSON array and returns the number
+// of bytes written.
+func (s *State) Snapshot(w io.Writer) (int, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	b, err := json.Marshal(s.d.slice())
+	if err != nil {
+		return 0, err
+	}
+	return w.Write(b)
+}
+
+// LoadSnapshot replaces all entries with the ones of a snapshot read from r.
+func (s *State) LoadSnapshot(r io.Reader) error {
+	var msgs []json.RawMessage
+	if err := json.NewDecoder(r).Decode(&msgs); err != nil && err != io.EOF {
+		return err
+	}
+	d, err := decodeData(s.t, msgs)
+	if err != nil {
+		return fmt.Errorf("loading snapshot: %s", err)
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	s.d = d
+	return nil
+}
+
+// Gossip implements the mesh.Gossiper interface.
+func (s *State) Gossip() mesh.GossipData {
+	s.RLock()
+	defer s.RUnlock()
+
+	return s.d.clone()
+}
+
+// OnGossip implements the mesh.Gossiper interface.
+func (s *State) OnGossip(msg []byte) (mesh.GossipData, error) {
+	d, err := s.decode(msg)
+	if err != nil {
+		return nil, err
+	}
+	s.Lock()
+	defer s.Unlock()
+
+	if delta := s.d.mergeDelta(d); delta.Len() > 0 {
+		return delta, nil
+	}
+	return nil, nil
+}
+
+// OnGossipBroadcast implements the mesh.Gossiper interface.
+func (s *State) OnGossipBroadcast(src mesh.PeerName, msg []byte) (mesh.GossipData, error) {
+	d, err := s.decode(msg)
+	if err != nil {
+		return nil, err
+	}
+	s.Lock()
+	defer s.Unlock()
+
+	return s.d.mergeDelta(d), nil
+}
+
+// OnGossipUnicast implements the mesh.Gossiper interface.
+func (s *State) OnGossipUnicast(src mesh.PeerName, msg []byte) error {
+	panic("not implemented")
+}
+
+func (s *State) decode(msg []byte) (Data, error) {
+	var msgs []json.RawMessage
+	if err := json.Unmarshal(msg, &msgs); err != nil {
+		return Data{}, err
+	}
+	return decodeData(s.t, msgs)
+}
+
+// Data is a set of entries by their key. It implements the mesh.GossipData
+// interface.
+type Data struct {
+	t Type
+	m map[string]interface{}
+}
+
+func newData(t Type) Data {
+	return Data{t: t, m: map[string]interface{}{}}
+}
+
+func decodeData(t Type, msgs []json.RawMessage) (Data, error) {
+	d := newData(t)
+	for _, b := range msgs {
+		e, err := t.Decode(b)
+		if err != nil {
+			return Data{}, err
+		}
+		d.m[t.Key(e)] = e
+	}
+	return d, nil
+}
+
+// Len returns the number of entries.
+func (d Data) Len() int {
+	return len(d.m)
+}
+
+func (d Data) slice() []interface{} {
+	res := make([]interface{}, 0, len(d.m))
+	for _, e := range d.m {
+		res = append(res, e)
+	}
+	return res
+}
+
+// Encode implements the mesh.GossipData interface.
+func (d Data) Encode() [][]byte {
+	// Split into sub-messages of ~1MB.
+	const maxSize = 1024 * 1024
+
+	var (
+		res   [][]byte
+		batch []interface{}
+		n     int
+	)
+	flush := func() {
+		b, err := json.Marshal(batch)
+		if err != nil {
+			// Entries consist of strings, numbers and timestamps only.
+			panic(err)
+		}
+		res = append(res, b)
+		batch, n = nil, 0
+	}
+	for _, e := range d.m {
+		batch = append(batch, e)
+		n += d.t.Size(e)
+		if n > maxSize {
+			flush()
+		}
+	}
+	if len(batch) > 0 {
+		flush()
+	}
+	return res
+}
+
+func (d Data) clone() Data {
+	res := Data{t: d.t, m: make(map[string]interface{}, len(d.m))}
+	for key, e := range d.m {
+		res.m[key] = e
+	}
+	return res
+}
+
+// Merge the entries with gossip data and return the new state.
+func (d Data) Merge(other mesh.GossipData) mesh.GossipData {
+	d.mergeDelta(other.(Data))
+	return d
+}
+
+// mergeDelta behaves like Merge but returns a Data only containing
+// things that have changed.
+func (d Data) mergeDelta(od Data) Data {
+	delta := newData(d.t)
+	for key, e := range od.m {
+		if prev, ok := d.m[key]; ok {
+			if e = d.t.Merge(prev, e); e == nil {
+				continue
+			}
+		}
+		d.m[key] = e
+		delta.m[key] = e
+	}
+	return delta
+}
+
+// replaceFile wraps a file that is moved to another filename on closing.
+type replaceFile struct {
+	*os.File
+	filename string
+}
+
+func (f *replaceFile) Close() error {
+	if err := f.File.Sync(); err != nil {
+		return err
+	}
+	if err := f.File.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.File.Name(), f.filename)
+}
+
+// openReplace opens a new temporary file that is moved to filename on closing.
+func openReplace(filename string) (*replaceFile, error) {
+	tmpFilename := fmt.Sprintf("%s.%x", filename, uint64(rand.Int63()))
+
+	f, err := os.Create(tmpFilename)
+	if err != nil {
+		return nil, err
+	}
+	return &replaceFile{
+		File:     f,
+		filename: filename,
+	}, nil
+}
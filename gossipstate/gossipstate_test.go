@@ -0,0 +1,185 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gossipstate
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type entry struct {
+	ID      string `json:"id"`
+	Version int    `json:"version"`
+	Text    string `json:"text"`
+}
+
+// entryType merges entries by their version.
+type entryType struct{}
+
+func (entryType) Key(e interface{}) string {
+	return e.(*entry).ID
+}
+
+func (entryType) Decode(b []byte) (interface{}, error) {
+	var e entry
+	if err := json.Unmarshal(b, &e); err != nil {
+		return nil, err
+	}
+	if e.ID == "" {
+		return nil, errors.New("ID missing")
+	}
+	return &e, nil
+}
+
+func (entryType) Size(e interface{}) int {
+	return len(e.(*entry).Text) + 64
+}
+
+func (entryType) Merge(prev, e interface{}) interface{} {
+	if prev.(*entry).Version >= e.(*entry).Version {
+		return nil
+	}
+	return e
+}
+
+func TestDataMergeDelta(t *testing.T) {
+	d := newData(entryType{})
+	d.m["a"] = &entry{ID: "a", Version: 1}
+	d.m["b"] = &entry{ID: "b", Version: 1}
+
+	od := newData(entryType{})
+	od.m["a"] = &entry{ID: "a", Version: 2}
+	od.m["b"] = &entry{ID: "b", Version: 0}
+	od.m["c"] = &entry{ID: "c", Version: 1}
+
+	delta := d.mergeDelta(od)
+	require.Equal(t, 2, delta.Len())
+	require.Contains(t, delta.m, "a")
+	require.Contains(t, delta.m, "c")
+	require.Equal(t, 2, d.m["a"].(*entry).Version)
+	require.Equal(t, 1, d.m["b"].(*entry).Version)
+	require.Equal(t, 3, d.Len())
+}
+
+func TestDataEncode(t *testing.T) {
+	s, err := New(entryType{}, Options{})
+	require.NoError(t, err)
+
+	text := strings.Repeat("x", 1024)
+	s.Lock()
+	for i := 0; i < 2048; i++ {
+		s.Set(&entry{ID: strconv.Itoa(i), Text: text})
+	}
+	s.Unlock()
+
+	// Large states are split into several messages.
+	msgs := s.Gossip().Encode()
+	require.True(t, len(msgs) > 1)
+
+	other, err := New(entryType{}, Options{})
+	require.NoError(t, err)
+	for _, msg := range msgs {
+		_, err := other.OnGossip(msg)
+		require.NoError(t, err)
+	}
+	require.Equal(t, 2048, other.Gossip().(Data).Len())
+
+	// Known entries are not merged again.
+	delta, err := other.OnGossip(msgs[0])
+	require.NoError(t, err)
+	require.Nil(t, delta)
+
+	_, err = other.OnGossip([]byte(`[{"version":1}]`))
+	require.Error(t, err)
+}
+
+func TestStateSnapshotGC(t *testing.T) {
+	s, err := New(entryType{}, Options{})
+	require.NoError(t, err)
+
+	s.Lock()
+	s.Set(&entry{ID: "a", Version: 1})
+	s.Set(&entry{ID: "b", Version: 2})
+	s.Unlock()
+
+	var buf bytes.Buffer
+	_, err = s.Snapshot(&buf)
+	require.NoError(t, err)
+
+	restored, err := New(entryType{}, Options{})
+	require.NoError(t, err)
+	require.NoError(t, restored.LoadSnapshot(&buf))
+
+	restored.RLock()
+	e, ok := restored.Get("b")
+	restored.RUnlock()
+	require.True(t, ok)
+	require.Equal(t, 2, e.(*entry).Version)
+
+	n := restored.GC(func(e interface{}) bool { return e.(*entry).Version < 2 })
+	require.Equal(t, 1, n)
+
+	restored.RLock()
+	_, ok = restored.Get("a")
+	restored.RUnlock()
+	require.False(t, ok)
+
+	require.Error(t, restored.LoadSnapshot(strings.NewReader(`[{"version":1}]`)))
+}
+
+func TestStateMaintenance(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gossipstate")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	snapf := filepath.Join(dir, "state")
+
+	s, err := New(entryType{}, Options{SnapshotFile: snapf})
+	require.NoError(t, err)
+
+	s.Lock()
+	s.Set(&entry{ID: "a", Version: 1})
+	s.Unlock()
+
+	var gcs int
+	stopc := make(chan struct{})
+	close(stopc)
+	s.Maintenance(time.Hour, snapf, stopc, func() (int, error) {
+		gcs++
+		return 0, nil
+	})
+	require.Equal(t, 1, gcs, "maintenance runs on shutdown")
+
+	// The shutdown snapshot is loaded on startup.
+	restored, err := New(entryType{}, Options{SnapshotFile: snapf})
+	require.NoError(t, err)
+	require.Equal(t, 1, restored.Gossip().(Data).Len())
+
+	// Without a snapshot file there is no shutdown maintenance.
+	s.Maintenance(time.Hour, "", stopc, func() (int, error) {
+		gcs++
+		return 0, nil
+	})
+	require.Equal(t, 1, gcs)
+}
@@ -0,0 +1,329 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package graphql implements the execution of GraphQL queries against
+// objects whose fields are resolved by Go functions.
+//
+// Only the query subset of the language is supported: operations may
+// declare variables and select fields with aliases and arguments, but
+// fragments, directives and introspection beyond __typename are not
+// available.
+package graphql
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Object is a value of a GraphQL object type.
+type Object struct {
+	// Type is the name of the object type.
+	Type string
+	// Fields resolve the fields of the object.
+	Fields map[string]FieldFunc
+}
+
+// FieldFunc resolves a field with the given arguments. It returns nil, an
+// *Object, a slice of values or a scalar that is encoded as JSON.
+type FieldFunc func(args Args) (interface{}, error)
+
+// Args holds the arguments of a field.
+type Args map[string]interface{}
+
+// String returns the string argument with the given name, or the empty
+// string if it is not set.
+func (a Args) String(name string) (string, error) {
+	switch v := a[name].(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case Enum:
+		return string(v), nil
+	}
+	return "", fmt.Errorf("argument %q must be a string", name)
+}
+
+// Strings returns the list of strings argument with the given name. A
+// single string is treated as a list of one.
+func (a Args) Strings(name string) ([]string, error) {
+	switch v := a[name].(type) {
+	case nil:
+		return nil, nil
+	case []interface{}:
+		res := make([]string, 0, len(v))
+		for _, e := range v {
+			s, err := Args{name: e}.String(name)
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, s)
+		}
+		return res, nil
+	}
+	s, err := a.String(name)
+	if err != nil {
+		return nil, fmt.Errorf("argument %q must be a list of strings", name)
+	}
+	return []string{s}, nil
+}
+
+// Int returns the integer argument with the given name, or zero if it is
+// not set.
+func (a Args) Int(name string) (int, error) {
+	switch v := a[name].(type) {
+	case nil:
+		return 0, nil
+	case int:
+		return v, nil
+	case float64:
+		if v == float64(int(v)) {
+			return int(v), nil
+		}
+	}
+	return 0, fmt.Errorf("argument %q must be an integer", name)
+}
+
+// Request is a GraphQL request as sent over HTTP.
+type Request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// Response is the result of executing a request.
+type Response struct {
+	Data   interface{} `json:"data"`
+	Errors []*Error    `json:"errors,omitempty"`
+}
+
+// Error is an error that occurred while executing a request. The path
+// leads to the field whose resolution failed.
+type Error struct {
+	Message string        `json:"message"`
+	Path    []interface{} `json:"path,omitempty"`
+}
+
+// Execute executes the request against the root query object. Requests
+// that cannot be executed at all yield a response without data.
+func Execute(root *Object, req *Request) *Response {
+	op, vars, err := prepare(req)
+	if err != nil {
+		return &Response{Errors: []*Error{{Message: err.Error()}}}
+	}
+	e := &executor{vars: vars}
+	data := e.object(root, op.Selection, nil)
+
+	return &Response{Data: data, Errors: e.errs}
+}
+
+// prepare parses the request and selects the operation to execute. It
+// returns the values of the operation's variables.
+func prepare(req *Request) (*Operation, map[string]interface{}, error) {
+	ops, err := Parse(req.Query)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var op *Operation
+	if req.OperationName == "" {
+		if len(ops) > 1 {
+			return nil, nil, fmt.Errorf("operation name required for documents with multiple operations")
+		}
+		op = ops[0]
+	} else {
+		for _, o := range ops {
+			if o.Name == req.OperationName {
+				op = o
+			}
+		}
+		if op == nil {
+			return nil, nil, fmt.Errorf("unknown operation %q", req.OperationName)
+		}
+	}
+
+	vars := map[string]interface{}{}
+	for _, v := range op.Variables {
+		val, ok := req.Variables[v.Name]
+		if !ok {
+			val = v.Default
+		}
+		if val == nil && v.Required {
+			return nil, nil, fmt.Errorf("variable $%s of type %s is required", v.Name, v.Type)
+		}
+		// Whole numbers are decoded from JSON as float64 but used as
+		// integers in queries.
+		if f, ok := val.(float64); ok && f == float64(int(f)) && strings.TrimSuffix(v.Type, "!") == "Int" {
+			val = int(f)
+		}
+		vars[v.Name] = val
+	}
+	return op, vars, nil
+}
+
+type executor struct {
+	vars map[string]interface{}
+	errs []*Error
+}
+
+func (e *executor) errorf(path []interface{}, format string, args ...interface{}) {
+	e.errs = append(e.errs, &Error{
+		Message: fmt.Sprintf(format, args...),
+		Path:    append([]interface{}(nil), path...),
+	})
+}
+
+// object resolves the selected fields of the object.
+func (e *executor) object(o *Object, sel []*Field, path []interface{}) orderedMap {
+	res := make(orderedMap, 0, len(sel))
+	for _, f := range sel {
+		p := append(path[:len(path):len(path)], f.Key())
+
+		if f.Name == "__typename" {
+			res = append(res, keyValue{f.Key(), o.Type})
+			continue
+		}
+		fn, ok := o.Fields[f.Name]
+		if !ok {
+			e.errorf(p, "cannot query field %q on type %q", f.Name, o.Type)
+			res = append(res, keyValue{f.Key(), nil})
+			continue
+		}
+		args, err := e.arguments(f.Arguments)
+		if err != nil {
+			e.errorf(p, "%s", err)
+			res = append(res, keyValue{f.Key(), nil})
+			continue
+		}
+		v, err := fn(args)
+		if err != nil {
+			e.errorf(p, "%s", err)
+			res = append(res, keyValue{f.Key(), nil})
+			continue
+		}
+		res = append(res, keyValue{f.Key(), e.value(v, f, p)})
+	}
+	return res
+}
+
+// value completes the resolved value of a field.
+func (e *executor) value(v interface{}, f *Field, path []interface{}) interface{} {
+	if v == nil {
+		return nil
+	}
+	if o, ok := v.(*Object); ok {
+		if o == nil {
+			return nil
+		}
+		if f.Selection == nil {
+			e.errorf(path, "field %q of type %q must have a selection of subfields", f.Name, o.Type)
+			return nil
+		}
+		return e.object(o, f.Selection, path)
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() != reflect.Uint8 {
+		res := make([]interface{}, rv.Len())
+		for i := range res {
+			res[i] = e.value(rv.Index(i).Interface(), f, append(path[:len(path):len(path)], i))
+		}
+		return res
+	}
+	if f.Selection != nil {
+		e.errorf(path, "field %q is a scalar and cannot have a selection of subfields", f.Name)
+		return nil
+	}
+	return v
+}
+
+// arguments replaces variables in the arguments with their values.
+func (e *executor) arguments(args map[string]interface{}) (Args, error) {
+	res := make(Args, len(args))
+	for k, v := range args {
+		v, err := e.resolve(v)
+		if err != nil {
+			return nil, err
+		}
+		res[k] = v
+	}
+	return res, nil
+}
+
+func (e *executor) resolve(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case Variable:
+		val, ok := e.vars[string(v)]
+		if !ok {
+			return nil, fmt.Errorf("undefined variable $%s", v)
+		}
+		return val, nil
+	case []interface{}:
+		res := make([]interface{}, len(v))
+		for i, x := range v {
+			r, err := e.resolve(x)
+			if err != nil {
+				return nil, err
+			}
+			res[i] = r
+		}
+		return res, nil
+	case map[string]interface{}:
+		res := make(map[string]interface{}, len(v))
+		for k, x := range v {
+			r, err := e.resolve(x)
+			if err != nil {
+				return nil, err
+			}
+			res[k] = r
+		}
+		return res, nil
+	}
+	return v, nil
+}
+
+type keyValue struct {
+	key   string
+	value interface{}
+}
+
+// orderedMap is a JSON object whose keys are encoded in the order in which
+// the fields were selected, as required by GraphQL.
+type orderedMap []keyValue
+
+// MarshalJSON implements the json.Marshaler interface.
+func (m orderedMap) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, kv := range m {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		k, err := json.Marshal(kv.key)
+		if err != nil {
+			return nil, err
+		}
+		v, err := json.Marshal(kv.value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(k)
+		buf.WriteByte(':')
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
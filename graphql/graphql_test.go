@@ -0,0 +1,148 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package graphql
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	ops, err := Parse(`
+		# Alerts of a receiver.
+		query Alerts($receiver: String!, $limit: Int = 10) {
+			firing: alerts(receiver: $receiver, state: [active, "suppressed"], limit: $limit) {
+				labels
+				silence { id }
+			}
+		}`)
+	require.NoError(t, err)
+	require.Len(t, ops, 1)
+
+	op := ops[0]
+	require.Equal(t, "Alerts", op.Name)
+	require.Equal(t, []*VariableDefinition{
+		{Name: "receiver", Type: "String!", Required: true},
+		{Name: "limit", Type: "Int", Default: 10},
+	}, op.Variables)
+
+	require.Len(t, op.Selection, 1)
+	f := op.Selection[0]
+	require.Equal(t, "firing", f.Key())
+	require.Equal(t, "alerts", f.Name)
+	require.Equal(t, map[string]interface{}{
+		"receiver": Variable("receiver"),
+		"state":    []interface{}{Enum("active"), "suppressed"},
+		"limit":    Variable("limit"),
+	}, f.Arguments)
+	require.Len(t, f.Selection, 2)
+	require.Equal(t, "id", f.Selection[1].Selection[0].Name)
+
+	for _, q := range []string{
+		"",
+		"{}",
+		"{ alerts",
+		"{ alerts(limit: ) }",
+		`{ alerts(matcher: "job) }`,
+		"{ ...fields }",
+		"{ alerts @skip(if: true) }",
+		"mutation { expire }",
+		"query ($a: Int = $b) { alerts }",
+		"{ alerts } %",
+		strings.Repeat("{ a ", 100000) + strings.Repeat("}", 100000),
+		"{ alerts(matcher: " + strings.Repeat("[", 100000) + ") }",
+	} {
+		_, err := Parse(q)
+		require.Error(t, err, q)
+	}
+}
+
+func TestExecute(t *testing.T) {
+	item := func(name string) *Object {
+		return &Object{Type: "Item", Fields: map[string]FieldFunc{
+			"name": func(Args) (interface{}, error) { return name, nil },
+		}}
+	}
+	root := &Object{Type: "Query", Fields: map[string]FieldFunc{
+		"items": func(args Args) (interface{}, error) {
+			n, err := args.Int("first")
+			if err != nil {
+				return nil, err
+			}
+			res := []*Object{item("a"), item("b"), item("c")}
+			if n > 0 && n < len(res) {
+				res = res[:n]
+			}
+			return res, nil
+		},
+		"item": func(args Args) (interface{}, error) {
+			name, err := args.String("name")
+			if err != nil || name == "" {
+				return nil, err
+			}
+			return item(name), nil
+		},
+		"labels": func(Args) (interface{}, error) {
+			return map[string]string{"a": "b"}, nil
+		},
+		"broken": func(Args) (interface{}, error) {
+			return nil, errors.New("broken")
+		},
+	}}
+
+	for _, c := range []struct {
+		req  Request
+		resp string
+	}{
+		{
+			req:  Request{Query: `{ labels b: items(first: 2) { __typename name } item(name: "x") { name } }`},
+			resp: `{"data":{"labels":{"a":"b"},"b":[{"__typename":"Item","name":"a"},{"__typename":"Item","name":"b"}],"item":{"name":"x"}}}`,
+		},
+		{
+			req: Request{
+				Query:     `query Q($n: Int!) { items(first: $n) { name } }`,
+				Variables: map[string]interface{}{"n": float64(1)},
+			},
+			resp: `{"data":{"items":[{"name":"a"}]}}`,
+		},
+		{
+			req:  Request{Query: `{ item { name } broken items(first: "x") { name } }`},
+			resp: `{"data":{"item":null,"broken":null,"items":null},"errors":[{"message":"broken","path":["broken"]},{"message":"argument \"first\" must be an integer","path":["items"]}]}`,
+		},
+		{
+			req:  Request{Query: `{ items { name unknown } labels { a } }`},
+			resp: `{"data":{"items":[{"name":"a","unknown":null},{"name":"b","unknown":null},{"name":"c","unknown":null}],"labels":null},"errors":[{"message":"cannot query field \"unknown\" on type \"Item\"","path":["items",0,"unknown"]},{"message":"cannot query field \"unknown\" on type \"Item\"","path":["items",1,"unknown"]},{"message":"cannot query field \"unknown\" on type \"Item\"","path":["items",2,"unknown"]},{"message":"field \"labels\" is a scalar and cannot have a selection of subfields","path":["labels"]}]}`,
+		},
+		{
+			req:  Request{Query: `query Q($n: Int!) { items(first: $n) { name } }`},
+			resp: `{"data":null,"errors":[{"message":"variable $n of type Int! is required"}]}`,
+		},
+		{
+			req:  Request{Query: `query A { labels } query B { broken }`, OperationName: "A"},
+			resp: `{"data":{"labels":{"a":"b"}}}`,
+		},
+		{
+			req:  Request{Query: `query A { labels } query B { broken }`},
+			resp: `{"data":null,"errors":[{"message":"operation name required for documents with multiple operations"}]}`,
+		},
+	} {
+		b, err := json.Marshal(Execute(root, &c.req))
+		require.NoError(t, err)
+		require.JSONEq(t, c.resp, string(b), c.req.Query)
+	}
+}
@@ -0,0 +1,511 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package graphql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Operation is a parsed query operation.
+type Operation struct {
+	Name      string
+	Variables []*VariableDefinition
+	Selection []*Field
+}
+
+// VariableDefinition declares a variable of an operation.
+type VariableDefinition struct {
+	Name     string
+	Type     string
+	Default  interface{}
+	Required bool
+}
+
+// Field is a selected field with its arguments and sub-selection.
+type Field struct {
+	Alias     string
+	Name      string
+	Arguments map[string]interface{}
+	Selection []*Field
+}
+
+// Key returns the key of the field in the response.
+func (f *Field) Key() string {
+	if f.Alias != "" {
+		return f.Alias
+	}
+	return f.Name
+}
+
+// Variable is an argument value referring to a variable of the operation.
+type Variable string
+
+// Enum is an enum argument value.
+type Enum string
+
+// Parse parses a document consisting of query operations. Fragments,
+// directives, mutations and subscriptions are not supported.
+func Parse(s string) ([]*Operation, error) {
+	p := &parser{lexer: lexer{input: s}}
+	p.next()
+
+	var ops []*Operation
+	for p.tok.typ != tokEOF {
+		op, err := p.parseOperation()
+		if err != nil {
+			return nil, err
+		}
+		ops = append(ops, op)
+	}
+	if p.err != nil {
+		return nil, p.err
+	}
+	if len(ops) == 0 {
+		return nil, fmt.Errorf("document contains no operation")
+	}
+	return ops, nil
+}
+
+type tokenType int
+
+const (
+	tokEOF tokenType = iota
+	tokPunct
+	tokName
+	tokInt
+	tokFloat
+	tokString
+)
+
+type token struct {
+	typ tokenType
+	val string
+	pos int
+}
+
+type lexer struct {
+	input string
+	pos   int
+}
+
+// next returns the next token. Whitespace, commas and comments are
+// skipped as they are insignificant in GraphQL.
+func (l *lexer) next() (token, error) {
+Skip:
+	for l.pos < len(l.input) {
+		switch c := l.input[l.pos]; {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',':
+			l.pos++
+		case c == '#':
+			for l.pos < len(l.input) && l.input[l.pos] != '\n' {
+				l.pos++
+			}
+		default:
+			break Skip
+		}
+	}
+	if l.pos >= len(l.input) {
+		return token{typ: tokEOF, pos: l.pos}, nil
+	}
+
+	start := l.pos
+	c := l.input[l.pos]
+	switch {
+	case strings.HasPrefix(l.input[l.pos:], "..."):
+		l.pos += 3
+		return token{typ: tokPunct, val: "...", pos: start}, nil
+	case strings.IndexByte("{}()[]:$!=@", c) >= 0:
+		l.pos++
+		return token{typ: tokPunct, val: string(c), pos: start}, nil
+	case c == '_' || isLetter(c):
+		for l.pos < len(l.input) && (l.input[l.pos] == '_' || isLetter(l.input[l.pos]) || isDigit(l.input[l.pos])) {
+			l.pos++
+		}
+		return token{typ: tokName, val: l.input[start:l.pos], pos: start}, nil
+	case c == '-' || isDigit(c):
+		return l.lexNumber()
+	case c == '"':
+		return l.lexString()
+	}
+	r, _ := utf8.DecodeRuneInString(l.input[l.pos:])
+	return token{}, fmt.Errorf("unexpected character %q at position %d", r, start)
+}
+
+func (l *lexer) lexNumber() (token, error) {
+	start := l.pos
+	typ := tokInt
+	if l.input[l.pos] == '-' {
+		l.pos++
+	}
+	for l.pos < len(l.input) {
+		c := l.input[l.pos]
+		switch {
+		case isDigit(c):
+		case c == '.' || c == 'e' || c == 'E':
+			typ = tokFloat
+		case (c == '+' || c == '-') && typ == tokFloat:
+		default:
+			return token{typ: typ, val: l.input[start:l.pos], pos: start}, nil
+		}
+		l.pos++
+	}
+	return token{typ: typ, val: l.input[start:l.pos], pos: start}, nil
+}
+
+func (l *lexer) lexString() (token, error) {
+	start := l.pos
+	l.pos++
+	for l.pos < len(l.input) {
+		switch l.input[l.pos] {
+		case '\\':
+			l.pos += 2
+			continue
+		case '\n':
+			return token{}, fmt.Errorf("unterminated string at position %d", start)
+		case '"':
+			l.pos++
+			s, err := strconv.Unquote(l.input[start:l.pos])
+			if err != nil {
+				return token{}, fmt.Errorf("invalid string at position %d: %s", start, err)
+			}
+			return token{typ: tokString, val: s, pos: start}, nil
+		}
+		l.pos++
+	}
+	return token{}, fmt.Errorf("unterminated string at position %d", start)
+}
+
+func isLetter(c byte) bool { return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' }
+func isDigit(c byte) bool  { return '0' <= c && c <= '9' }
+
+// maxDepth is the maximum nesting depth of selection sets and values, which
+// protects the recursive descent from exhausting the stack.
+const maxDepth = 32
+
+type parser struct {
+	lexer lexer
+	tok   token
+	err   error
+	depth int
+}
+
+// next advances to the next token. Lexing errors are reported by the
+// following call to expect or by the parse functions checking p.err.
+func (p *parser) next() {
+	if p.err != nil {
+		return
+	}
+	p.tok, p.err = p.lexer.next()
+	if p.err != nil {
+		p.tok = token{typ: tokEOF, pos: p.lexer.pos}
+	}
+}
+
+func (p *parser) is(val string) bool {
+	return p.tok.typ == tokPunct && p.tok.val == val
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	if p.err != nil {
+		return p.err
+	}
+	return fmt.Errorf("syntax error at position %d: %s", p.tok.pos, fmt.Sprintf(format, args...))
+}
+
+func (p *parser) expect(val string) error {
+	if !p.is(val) {
+		return p.errorf("expected %q", val)
+	}
+	p.next()
+	return nil
+}
+
+// enter increases the nesting depth and fails if it exceeds the maximum.
+// Callers must call leave afterwards.
+func (p *parser) enter() error {
+	p.depth++
+	if p.depth > maxDepth {
+		return p.errorf("nesting exceeds the maximum depth of %d", maxDepth)
+	}
+	return nil
+}
+
+func (p *parser) leave() {
+	p.depth--
+}
+
+func (p *parser) name() (string, error) {
+	if p.tok.typ != tokName {
+		return "", p.errorf("expected name")
+	}
+	n := p.tok.val
+	p.next()
+	return n, nil
+}
+
+func (p *parser) parseOperation() (*Operation, error) {
+	op := &Operation{}
+
+	if p.tok.typ == tokName {
+		switch p.tok.val {
+		case "query":
+			p.next()
+		case "mutation", "subscription", "fragment":
+			return nil, fmt.Errorf("%s operations are not supported", p.tok.val)
+		default:
+			return nil, p.errorf("unexpected %q", p.tok.val)
+		}
+		if p.tok.typ == tokName {
+			op.Name = p.tok.val
+			p.next()
+		}
+		if p.is("(") {
+			vars, err := p.parseVariableDefinitions()
+			if err != nil {
+				return nil, err
+			}
+			op.Variables = vars
+		}
+	}
+
+	sel, err := p.parseSelectionSet()
+	if err != nil {
+		return nil, err
+	}
+	op.Selection = sel
+	return op, nil
+}
+
+func (p *parser) parseVariableDefinitions() ([]*VariableDefinition, error) {
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	var vars []*VariableDefinition
+	for !p.is(")") {
+		if err := p.expect("$"); err != nil {
+			return nil, err
+		}
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		typ, err := p.parseType()
+		if err != nil {
+			return nil, err
+		}
+		v := &VariableDefinition{
+			Name:     name,
+			Type:     typ,
+			Required: strings.HasSuffix(typ, "!"),
+		}
+		if p.is("=") {
+			p.next()
+			if v.Default, err = p.parseValue(true); err != nil {
+				return nil, err
+			}
+		}
+		vars = append(vars, v)
+	}
+	p.next()
+	return vars, nil
+}
+
+func (p *parser) parseType() (string, error) {
+	var typ string
+	if p.is("[") {
+		p.next()
+		t, err := p.parseType()
+		if err != nil {
+			return "", err
+		}
+		if err := p.expect("]"); err != nil {
+			return "", err
+		}
+		typ = "[" + t + "]"
+	} else {
+		n, err := p.name()
+		if err != nil {
+			return "", err
+		}
+		typ = n
+	}
+	if p.is("!") {
+		p.next()
+		typ += "!"
+	}
+	return typ, nil
+}
+
+func (p *parser) parseSelectionSet() ([]*Field, error) {
+	defer p.leave()
+	if err := p.enter(); err != nil {
+		return nil, err
+	}
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	var fields []*Field
+	for !p.is("}") {
+		switch {
+		case p.is("..."):
+			return nil, fmt.Errorf("fragments are not supported")
+		case p.tok.typ == tokEOF:
+			return nil, p.errorf("unexpected end of document")
+		}
+		f, err := p.parseField()
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, f)
+	}
+	p.next()
+
+	if len(fields) == 0 {
+		return nil, p.errorf("empty selection set")
+	}
+	return fields, nil
+}
+
+func (p *parser) parseField() (*Field, error) {
+	name, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+	f := &Field{Name: name}
+	if p.is(":") {
+		p.next()
+		if f.Name, err = p.name(); err != nil {
+			return nil, err
+		}
+		f.Alias = name
+	}
+	if p.is("(") {
+		p.next()
+		f.Arguments = map[string]interface{}{}
+		for !p.is(")") {
+			n, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(":"); err != nil {
+				return nil, err
+			}
+			if f.Arguments[n], err = p.parseValue(false); err != nil {
+				return nil, err
+			}
+		}
+		p.next()
+	}
+	if p.is("@") {
+		return nil, fmt.Errorf("directives are not supported")
+	}
+	if p.is("{") {
+		if f.Selection, err = p.parseSelectionSet(); err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+// parseValue parses an argument value. Constant values must not contain
+// variables.
+func (p *parser) parseValue(constant bool) (interface{}, error) {
+	defer p.leave()
+	if err := p.enter(); err != nil {
+		return nil, err
+	}
+	tok := p.tok
+	switch {
+	case p.is("$"):
+		if constant {
+			return nil, p.errorf("unexpected variable")
+		}
+		p.next()
+		n, err := p.name()
+		return Variable(n), err
+
+	case p.is("["):
+		p.next()
+		list := []interface{}{}
+		for !p.is("]") {
+			if p.tok.typ == tokEOF {
+				return nil, p.errorf("unexpected end of document")
+			}
+			v, err := p.parseValue(constant)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		p.next()
+		return list, nil
+
+	case p.is("{"):
+		p.next()
+		obj := map[string]interface{}{}
+		for !p.is("}") {
+			n, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(":"); err != nil {
+				return nil, err
+			}
+			if obj[n], err = p.parseValue(constant); err != nil {
+				return nil, err
+			}
+		}
+		p.next()
+		return obj, nil
+
+	case tok.typ == tokInt:
+		p.next()
+		v, err := strconv.Atoi(tok.val)
+		if err != nil {
+			return nil, fmt.Errorf("invalid integer %q", tok.val)
+		}
+		return v, nil
+
+	case tok.typ == tokFloat:
+		p.next()
+		v, err := strconv.ParseFloat(tok.val, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid float %q", tok.val)
+		}
+		return v, nil
+
+	case tok.typ == tokString:
+		p.next()
+		return tok.val, nil
+
+	case tok.typ == tokName:
+		p.next()
+		switch tok.val {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "null":
+			return nil, nil
+		}
+		return Enum(tok.val), nil
+	}
+	return nil, p.errorf("expected value")
+}
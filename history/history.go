@@ -0,0 +1,318 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package history keeps a record of the notifications that were sent to
+// receivers, including failed attempts. Unlike the notification log, which
+// only holds the latest notification of each aggregation group, it keeps
+// every notification until its retention has passed. Entries are shared with
+// other Alertmanager instances through the mesh network.
+package history
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/prometheus/common/log"
+	"github.com/prometheus/common/model"
+	"github.com/satori/go.uuid"
+	"github.com/weaveworks/mesh"
+
+	"github.com/prometheus/alertmanager/gossipstate"
+)
+
+// Entry records a notification sent to an integration of a receiver.
+type Entry struct {
+	ID          string `json:"id"`
+	Receiver    string `json:"receiver"`
+	Integration string `json:"integration"`
+	// GroupKey is the key of the notified aggregation group.
+	GroupKey uint64 `json:"groupKey"`
+	// Fingerprints of the alerts in the notification.
+	Fingerprints []string `json:"fingerprints"`
+	// Error is empty if the notification succeeded.
+	Error string `json:"error,omitempty"`
+	// Reason classifies the error, e.g. timeout or status_5xx.
+	Reason string    `json:"reason,omitempty"`
+	Time   time.Time `json:"time"`
+}
+
+// Success returns whether the notification succeeded.
+func (e *Entry) Success() bool {
+	return e.Error == ""
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (e *Entry) MarshalJSON() ([]byte, error) {
+	type plain Entry
+	return json.Marshal(struct {
+		*plain
+		Success bool `json:"success"`
+	}{
+		plain:   (*plain)(e),
+		Success: e.Success(),
+	})
+}
+
+func validateEntry(e *Entry) error {
+	if e.ID == "" {
+		return errors.New("ID missing")
+	}
+	if e.Receiver == "" {
+		return errors.New("receiver missing")
+	}
+	if e.Time.IsZero() {
+		return errors.New("timestamp missing")
+	}
+	return nil
+}
+
+// History holds the recorded notifications.
+type History struct {
+	retention time.Duration
+	now       func() time.Time
+	st        *gossipstate.State
+}
+
+// Options configures a new History.
+type Options struct {
+	// A snapshot file from which the initial state is loaded.
+	SnapshotFile string
+
+	// Entries may be garbage collected the given duration after they
+	// were recorded.
+	Retention time.Duration
+
+	// A function creating a mesh.Gossip on being called with a mesh.Gossiper.
+	Gossip func(g mesh.Gossiper) mesh.Gossip
+
+	// A logger used by background processing.
+	Logger log.Logger
+}
+
+// New returns a new History with the given configuration.
+func New(o Options) (*History, error) {
+	st, err := gossipstate.New(entryType{}, gossipstate.Options{
+		SnapshotFile: o.SnapshotFile,
+		Gossip:       o.Gossip,
+		Logger:       o.Logger,
+	})
+	if st == nil {
+		return nil, err
+	}
+	return &History{
+		retention: o.Retention,
+		now:       gossipstate.UTCNow,
+		st:        st,
+	}, err
+}
+
+// Maintenance garbage collects the history at the given interval. If the
+// snapshot file is set, a snapshot is written to it afterwards.
+// Terminates on receiving from stopc.
+func (h *History) Maintenance(interval time.Duration, snapf string, stopc <-chan struct{}) {
+	h.st.Maintenance(interval, snapf, stopc, h.GC)
+}
+
+// GC removes entries recorded longer than the retention time ago.
+// It returns the number of removed entries.
+func (h *History) GC() (int, error) {
+	now := h.now()
+
+	return h.st.GC(func(v interface{}) bool {
+		return !v.(*Entry).Time.Add(h.retention).After(now)
+	}), nil
+}
+
+// Record records a notification of the given alerts to the integration of
+// the receiver. A nil err marks the notification as successful, otherwise
+// reason classifies the error. It is safe to call on a nil History.
+func (h *History) Record(receiver, integration string, gkey uint64, fps []model.Fingerprint, err error, reason string) {
+	if h == nil {
+		return
+	}
+	e := &Entry{
+		ID:          uuid.NewV4().String(),
+		Receiver:    receiver,
+		Integration: integration,
+		GroupKey:    gkey,
+		Time:        h.now(),
+	}
+	for _, fp := range fps {
+		e.Fingerprints = append(e.Fingerprints, fp.String())
+	}
+	if err != nil {
+		e.Error = err.Error()
+		e.Reason = reason
+	}
+
+	h.st.Lock()
+	defer h.st.Unlock()
+
+	h.st.Set(e)
+}
+
+// query holds the parameters of a history query.
+type query struct {
+	receiver    string
+	integration string
+	groupKey    *uint64
+	fingerprint string
+	failed      *bool
+	reason      string
+	since       time.Time
+	until       time.Time
+}
+
+// QueryParam is a function that modifies a query to incorporate
+// a set of parameters.
+type QueryParam func(*query)
+
+// QReceiver selects notifications to the receiver.
+func QReceiver(name string) QueryParam {
+	return func(q *query) { q.receiver = name }
+}
+
+// QIntegration selects notifications through the integration, e.g. "email".
+func QIntegration(name string) QueryParam {
+	return func(q *query) { q.integration = name }
+}
+
+// QGroupKey selects notifications of the aggregation group with the key.
+func QGroupKey(gkey uint64) QueryParam {
+	return func(q *query) { q.groupKey = &gkey }
+}
+
+// QFingerprint selects notifications including the alert with the
+// fingerprint.
+func QFingerprint(fp model.Fingerprint) QueryParam {
+	return func(q *query) { q.fingerprint = fp.String() }
+}
+
+// QFailed selects failed notifications if failed is true and successful
+// ones otherwise.
+func QFailed(failed bool) QueryParam {
+	return func(q *query) { q.failed = &failed }
+}
+
+// QReason selects failed notifications with the reason.
+func QReason(reason string) QueryParam {
+	return func(q *query) { q.reason = reason }
+}
+
+// QSince selects notifications sent at or after the given time.
+func QSince(t time.Time) QueryParam {
+	return func(q *query) { q.since = t }
+}
+
+// QUntil selects notifications sent before the given time.
+func QUntil(t time.Time) QueryParam {
+	return func(q *query) { q.until = t }
+}
+
+func (q *query) match(e *Entry) bool {
+	if q.receiver != "" && e.Receiver != q.receiver {
+		return false
+	}
+	if q.integration != "" && e.Integration != q.integration {
+		return false
+	}
+	if q.groupKey != nil && e.GroupKey != *q.groupKey {
+		return false
+	}
+	if q.failed != nil && e.Success() == *q.failed {
+		return false
+	}
+	if q.reason != "" && e.Reason != q.reason {
+		return false
+	}
+	if !q.since.IsZero() && e.Time.Before(q.since) {
+		return false
+	}
+	if !q.until.IsZero() && !e.Time.Before(q.until) {
+		return false
+	}
+	if q.fingerprint != "" {
+		for _, fp := range e.Fingerprints {
+			if fp == q.fingerprint {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+type entrySlice []*Entry
+
+func (s entrySlice) Len() int      { return len(s) }
+func (s entrySlice) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+
+func (s entrySlice) Less(i, j int) bool {
+	if !s[i].Time.Equal(s[j].Time) {
+		return s[i].Time.After(s[j].Time)
+	}
+	return s[i].ID < s[j].ID
+}
+
+// Query returns the entries matching all given parameters, most recent
+// first.
+func (h *History) Query(params ...QueryParam) []*Entry {
+	q := &query{}
+	for _, p := range params {
+		p(q)
+	}
+
+	h.st.RLock()
+	defer h.st.RUnlock()
+
+	res := entrySlice{}
+	h.st.Range(func(v interface{}) {
+		if e := v.(*Entry); q.match(e) {
+			res = append(res, e)
+		}
+	})
+	sort.Sort(res)
+	return res
+}
+
+// entryType implements the handling of entries by the state.
+type entryType struct{}
+
+func (entryType) Key(v interface{}) string {
+	return v.(*Entry).ID
+}
+
+func (entryType) Decode(b []byte) (interface{}, error) {
+	var e Entry
+	if err := json.Unmarshal(b, &e); err != nil {
+		return nil, err
+	}
+	if err := validateEntry(&e); err != nil {
+		return nil, fmt.Errorf("invalid entry: %s", err)
+	}
+	return &e, nil
+}
+
+func (entryType) Size(v interface{}) int {
+	e := v.(*Entry)
+	return len(e.Receiver) + len(e.Integration) + len(e.Error) + 20*len(e.Fingerprints) + 128
+}
+
+// Merge keeps the known entry as entries never change, only entries unknown
+// so far are merged.
+func (entryType) Merge(prev, e interface{}) interface{} {
+	return nil
+}
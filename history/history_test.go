@@ -0,0 +1,101 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package history
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+
+	"github.com/prometheus/alertmanager/gossipstate"
+)
+
+func TestHistoryRecordQuery(t *testing.T) {
+	h, err := New(Options{Retention: time.Hour})
+	require.NoError(t, err)
+
+	now := gossipstate.UTCNow()
+	h.now = func() time.Time { return now }
+
+	h.Record("team-X", "email", 1, []model.Fingerprint{1, 2}, nil, "")
+	now = now.Add(time.Minute)
+	h.Record("team-X", "pagerduty", 1, []model.Fingerprint{2}, errors.New("timeout"), "timeout")
+	now = now.Add(time.Minute)
+	h.Record("team-Y", "email", 2, []model.Fingerprint{3}, nil, "")
+
+	var nilHistory *History
+	nilHistory.Record("team-X", "email", 1, nil, nil, "")
+
+	res := h.Query()
+	require.Len(t, res, 3)
+	require.Equal(t, "team-Y", res[0].Receiver, "most recent entry first")
+
+	res = h.Query(QReceiver("team-X"), QFailed(true))
+	require.Len(t, res, 1)
+	require.Equal(t, "pagerduty", res[0].Integration)
+	require.Equal(t, "timeout", res[0].Error)
+	require.False(t, res[0].Success())
+
+	require.Len(t, h.Query(QFingerprint(2)), 2)
+	require.Len(t, h.Query(QFingerprint(2), QIntegration("email")), 1)
+	require.Len(t, h.Query(QGroupKey(2)), 1)
+	require.Len(t, h.Query(QFailed(false)), 2)
+	require.Len(t, h.Query(QReason("timeout")), 1)
+	require.Len(t, h.Query(QReason("tls")), 0)
+	require.Len(t, h.Query(QSince(now.Add(-time.Minute))), 2)
+	require.Len(t, h.Query(QUntil(now.Add(-time.Minute))), 1)
+
+	b, err := json.Marshal(res[0])
+	require.NoError(t, err)
+	require.Contains(t, string(b), `"success":false`)
+	require.Contains(t, string(b), `"reason":"timeout"`)
+
+	now = now.Add(58 * time.Minute)
+	n, err := h.GC()
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	require.Len(t, h.Query(), 2)
+}
+
+func TestEntryTypeMerge(t *testing.T) {
+	now := gossipstate.UTCNow()
+
+	newEntry := func(err string, at time.Time) *Entry {
+		return &Entry{
+			ID:           "a",
+			Receiver:     "team-X",
+			Integration:  "email",
+			Fingerprints: []string{"0000000000000001"},
+			Error:        err,
+			Time:         at,
+		}
+	}
+	prev := newEntry("", now)
+
+	// Entries never change, so a known entry is always kept.
+	for _, c := range []struct {
+		name string
+		e    *Entry
+	}{
+		{name: "same", e: newEntry("", now)},
+		{name: "different error", e: newEntry("timeout", now)},
+		{name: "newer", e: newEntry("timeout", now.Add(time.Minute))},
+	} {
+		require.Nil(t, entryType{}.Merge(prev, c.e), c.name)
+	}
+}
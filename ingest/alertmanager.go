@@ -0,0 +1,44 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ingest
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/prometheus/common/model"
+
+	"github.com/prometheus/alertmanager/types"
+)
+
+// FetchAlertmanager returns the alerts of the Alertmanager at the base URL
+// as listed by its /api/v1/alerts endpoint, including their start and end
+// times. Read replicas use it to mirror the alerts of a primary instance.
+func FetchAlertmanager(client *http.Client, baseURL string) ([]*types.Alert, error) {
+	// The API wraps its responses like the one of Prometheus.
+	data, err := getPrometheus(client, strings.TrimRight(baseURL, "/")+"/api/v1/alerts")
+	if err != nil {
+		return nil, err
+	}
+	var as []*model.Alert
+	if err := json.Unmarshal(data, &as); err != nil {
+		return nil, err
+	}
+	alerts := make([]*types.Alert, 0, len(as))
+	for _, a := range as {
+		alerts = append(alerts, &types.Alert{Alert: *a})
+	}
+	return alerts, nil
+}
@@ -0,0 +1,125 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ingest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/prometheus/common/log"
+	"github.com/prometheus/common/model"
+
+	"github.com/prometheus/alertmanager/types"
+)
+
+// snsMessage is the body of an HTTP notification of Amazon SNS.
+type snsMessage struct {
+	Type         string `json:"Type"`
+	TopicArn     string `json:"TopicArn"`
+	Subject      string `json:"Subject"`
+	Message      string `json:"Message"`
+	SubscribeURL string `json:"SubscribeURL"`
+}
+
+// cloudWatchAlarm is the message of a CloudWatch alarm state change.
+type cloudWatchAlarm struct {
+	AlarmName        string `json:"AlarmName"`
+	AlarmDescription string `json:"AlarmDescription"`
+	AWSAccountID     string `json:"AWSAccountId"`
+	NewStateValue    string `json:"NewStateValue"`
+	NewStateReason   string `json:"NewStateReason"`
+	StateChangeTime  string `json:"StateChangeTime"`
+	Region           string `json:"Region"`
+	AlarmArn         string `json:"AlarmArn"`
+	Trigger          struct {
+		MetricName string `json:"MetricName"`
+		Namespace  string `json:"Namespace"`
+		Dimensions []struct {
+			Name  string `json:"name"`
+			Value string `json:"value"`
+		} `json:"Dimensions"`
+	} `json:"Trigger"`
+}
+
+// decodeCloudWatch translates CloudWatch alarms delivered by SNS. The
+// dimensions of the alarm's metric become labels. Alarms with insufficient
+// data are ignored.
+//
+// Subscriptions are not confirmed automatically. The confirmation URL is
+// logged and has to be visited once.
+func decodeCloudWatch(r io.Reader, now time.Time) ([]*types.Alert, error) {
+	var msg snsMessage
+	if err := json.NewDecoder(r).Decode(&msg); err != nil {
+		return nil, err
+	}
+
+	switch msg.Type {
+	case "Notification":
+	case "SubscriptionConfirmation":
+		log.Infof("Confirm the SNS subscription to topic %s by visiting %s", msg.TopicArn, msg.SubscribeURL)
+		return nil, nil
+	case "UnsubscribeConfirmation":
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unknown SNS message type %q", msg.Type)
+	}
+
+	var alarm cloudWatchAlarm
+	if err := json.Unmarshal([]byte(msg.Message), &alarm); err != nil {
+		return nil, fmt.Errorf("decoding CloudWatch alarm: %s", err)
+	}
+	if alarm.AlarmName == "" {
+		return nil, fmt.Errorf("missing alarm name in CloudWatch alarm")
+	}
+
+	a := &types.Alert{
+		Alert: model.Alert{
+			Labels: model.LabelSet{
+				model.AlertNameLabel: model.LabelValue(alarm.AlarmName),
+				"region":             model.LabelValue(alarm.Region),
+				"account_id":         model.LabelValue(alarm.AWSAccountID),
+				"namespace":          model.LabelValue(alarm.Trigger.Namespace),
+				"metric_name":        model.LabelValue(alarm.Trigger.MetricName),
+			},
+			Annotations: annotations(
+				"summary", msg.Subject,
+				"description", alarm.AlarmDescription,
+				"reason", alarm.NewStateReason,
+				"alarm_arn", alarm.AlarmArn,
+			),
+		},
+	}
+	for _, d := range alarm.Trigger.Dimensions {
+		a.Labels[model.LabelName(d.Name)] = model.LabelValue(d.Value)
+	}
+
+	// CloudWatch reports times like 2016-01-02T15:04:05.000+0000.
+	changed, err := time.Parse("2006-01-02T15:04:05.000-0700", alarm.StateChangeTime)
+	if err != nil || changed.After(now) {
+		changed = now
+	}
+	switch alarm.NewStateValue {
+	case "ALARM":
+		a.StartsAt = changed
+	case "OK":
+		a.EndsAt = changed
+	case "INSUFFICIENT_DATA":
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unknown CloudWatch alarm state %q", alarm.NewStateValue)
+	}
+	return []*types.Alert{a}, nil
+}
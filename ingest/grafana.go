@@ -0,0 +1,103 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ingest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/prometheus/common/model"
+
+	"github.com/prometheus/alertmanager/types"
+)
+
+// grafanaMessage is the body of a Grafana webhook notification. Unified
+// alerting sends the alerts of a group, legacy alerting a single rule.
+type grafanaMessage struct {
+	Alerts []struct {
+		Status       string            `json:"status"`
+		Labels       map[string]string `json:"labels"`
+		Annotations  map[string]string `json:"annotations"`
+		StartsAt     time.Time         `json:"startsAt"`
+		EndsAt       time.Time         `json:"endsAt"`
+		GeneratorURL string            `json:"generatorURL"`
+	} `json:"alerts"`
+
+	Title    string            `json:"title"`
+	RuleName string            `json:"ruleName"`
+	RuleURL  string            `json:"ruleUrl"`
+	State    string            `json:"state"`
+	Message  string            `json:"message"`
+	Tags     map[string]string `json:"tags"`
+}
+
+func decodeGrafana(r io.Reader, now time.Time) ([]*types.Alert, error) {
+	var msg grafanaMessage
+	if err := json.NewDecoder(r).Decode(&msg); err != nil {
+		return nil, err
+	}
+
+	if msg.Alerts == nil {
+		if msg.RuleName == "" {
+			return nil, fmt.Errorf("neither alerts nor rule name in Grafana message")
+		}
+		a := &types.Alert{
+			Alert: model.Alert{
+				Labels:       labelSet(msg.Tags),
+				Annotations:  annotations("summary", msg.Title, "description", msg.Message),
+				GeneratorURL: msg.RuleURL,
+			},
+		}
+		a.Labels[model.AlertNameLabel] = model.LabelValue(msg.RuleName)
+		switch msg.State {
+		case "ok", "paused":
+			a.EndsAt = now
+		case "pending":
+			return nil, nil
+		}
+		return []*types.Alert{a}, nil
+	}
+
+	res := make([]*types.Alert, 0, len(msg.Alerts))
+	for _, ga := range msg.Alerts {
+		a := &types.Alert{
+			Alert: model.Alert{
+				Labels:       labelSet(ga.Labels),
+				Annotations:  labelSet(ga.Annotations),
+				StartsAt:     ga.StartsAt,
+				GeneratorURL: ga.GeneratorURL,
+			},
+		}
+		// Grafana sends the zero time or a time far in the future as the
+		// end of firing alerts.
+		if ga.Status == "resolved" {
+			a.EndsAt = ga.EndsAt
+			if a.EndsAt.IsZero() || a.EndsAt.After(now) {
+				a.EndsAt = now
+			}
+		}
+		res = append(res, a)
+	}
+	return res, nil
+}
+
+func labelSet(m map[string]string) model.LabelSet {
+	res := make(model.LabelSet, len(m))
+	for k, v := range m {
+		res[model.LabelName(k)] = model.LabelValue(v)
+	}
+	return res
+}
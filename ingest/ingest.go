@@ -0,0 +1,110 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package ingest translates alerts of third-party formats, such as Grafana
+// webhooks and CloudWatch alarms delivered by SNS, into alerts of the
+// Alertmanager.
+package ingest
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/prometheus/common/model"
+
+	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/types"
+)
+
+// A Decoder translates a request body of a third-party format into alerts.
+// Firing alerts without a known end have a zero EndsAt, resolved alerts end
+// at the given time.
+type Decoder func(r io.Reader, now time.Time) ([]*types.Alert, error)
+
+// decoders holds the decoder of each type of config.IngestAdapterTypes.
+var decoders = map[string]Decoder{
+	"grafana":    decodeGrafana,
+	"cloudwatch": decodeCloudWatch,
+}
+
+// Decode reads the alerts of the adapter's type from r and applies the
+// label mapping of the adapter to them.
+func Decode(c *config.IngestAdapter, r io.Reader) ([]*types.Alert, error) {
+	dec, ok := decoders[c.Type]
+	if !ok {
+		return nil, fmt.Errorf("unknown ingest adapter type %q", c.Type)
+	}
+	alerts, err := dec(r, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range alerts {
+		a.Labels = mapLabels(c, a.Labels)
+
+		anns := make(model.LabelSet, len(a.Annotations))
+		for an, av := range a.Annotations {
+			anns[sanitizeLabelName(string(an))] = av
+		}
+		a.Annotations = anns
+	}
+	return alerts, nil
+}
+
+// mapLabels renames the labels according to the label map of the adapter,
+// adds its static labels and sanitizes the remaining label names.
+func mapLabels(c *config.IngestAdapter, lset model.LabelSet) model.LabelSet {
+	res := make(model.LabelSet, len(lset)+len(c.Labels))
+	for ln, lv := range lset {
+		if to, ok := c.LabelMap[string(ln)]; ok {
+			if to == "" {
+				continue
+			}
+			ln = model.LabelName(to)
+		}
+		if lv != "" {
+			res[sanitizeLabelName(string(ln))] = lv
+		}
+	}
+	for ln, lv := range c.Labels {
+		res[ln] = lv
+	}
+	return res
+}
+
+// sanitizeLabelName replaces the characters that are not allowed in label
+// names by underscores.
+func sanitizeLabelName(s string) model.LabelName {
+	if s == "" {
+		return "_"
+	}
+	b := []byte(s)
+	for i, c := range b {
+		if !(c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9' && i > 0)) {
+			b[i] = '_'
+		}
+	}
+	return model.LabelName(b)
+}
+
+// annotations returns the non-empty values as annotations.
+func annotations(kv ...string) model.LabelSet {
+	res := model.LabelSet{}
+	for i := 0; i+1 < len(kv); i += 2 {
+		if v := strings.TrimSpace(kv[i+1]); v != "" {
+			res[model.LabelName(kv[i])] = model.LabelValue(v)
+		}
+	}
+	return res
+}
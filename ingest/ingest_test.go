@@ -0,0 +1,129 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ingest
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+
+	"github.com/prometheus/alertmanager/config"
+)
+
+func TestDecodeGrafana(t *testing.T) {
+	c := &config.IngestAdapter{
+		Type:     "grafana",
+		LabelMap: map[string]string{"grafana_folder": "folder", "__alert_rule_uid__": ""},
+		Labels:   model.LabelSet{"source": "grafana"},
+	}
+
+	alerts, err := Decode(c, strings.NewReader(`{
+		"receiver": "am",
+		"status": "firing",
+		"alerts": [{
+			"status": "firing",
+			"labels": {"alertname": "HighCPU", "grafana_folder": "infra", "__alert_rule_uid__": "abc", "team-name": "ops"},
+			"annotations": {"summary": "CPU is high"},
+			"startsAt": "2016-01-01T00:00:00Z",
+			"endsAt": "0001-01-01T00:00:00Z",
+			"generatorURL": "http://grafana/alerting/abc/edit"
+		}, {
+			"status": "resolved",
+			"labels": {"alertname": "DiskFull"},
+			"startsAt": "2016-01-01T00:00:00Z",
+			"endsAt": "2016-01-01T01:00:00Z"
+		}]
+	}`))
+	require.NoError(t, err)
+	require.Len(t, alerts, 2)
+
+	require.Equal(t, model.LabelSet{
+		"alertname": "HighCPU",
+		"folder":    "infra",
+		"team_name": "ops",
+		"source":    "grafana",
+	}, alerts[0].Labels)
+	require.Equal(t, model.LabelSet{"summary": "CPU is high"}, alerts[0].Annotations)
+	require.True(t, alerts[0].EndsAt.IsZero())
+	require.Equal(t, "http://grafana/alerting/abc/edit", alerts[0].GeneratorURL)
+	require.Equal(t, time.Date(2016, 1, 1, 1, 0, 0, 0, time.UTC), alerts[1].EndsAt.UTC())
+
+	// Legacy alerting.
+	alerts, err = Decode(c, strings.NewReader(`{
+		"title": "[OK] Latency",
+		"ruleName": "Latency",
+		"ruleUrl": "http://grafana/d/xyz",
+		"state": "ok",
+		"message": "Latency is fine",
+		"tags": {"service": "api"}
+	}`))
+	require.NoError(t, err)
+	require.Len(t, alerts, 1)
+	require.Equal(t, model.LabelSet{"alertname": "Latency", "service": "api", "source": "grafana"}, alerts[0].Labels)
+	require.Equal(t, model.LabelValue("Latency is fine"), alerts[0].Annotations["description"])
+	require.False(t, alerts[0].EndsAt.IsZero())
+
+	_, err = Decode(c, strings.NewReader(`{"title": "no rule"}`))
+	require.Error(t, err)
+}
+
+func TestDecodeCloudWatch(t *testing.T) {
+	c := &config.IngestAdapter{
+		Type:     "cloudwatch",
+		LabelMap: map[string]string{"InstanceId": "instance"},
+	}
+
+	alarm := func(state string) string {
+		return `{
+			"Type": "Notification",
+			"TopicArn": "arn:aws:sns:eu-west-1:123456789012:alarms",
+			"Subject": "ALARM: \"HighLatency\" in EU (Ireland)",
+			"Message": "{\"AlarmName\":\"HighLatency\",\"AWSAccountId\":\"123456789012\",\"NewStateValue\":\"` + state + `\",\"NewStateReason\":\"Threshold crossed\",\"StateChangeTime\":\"2016-01-01T00:00:00.000+0000\",\"Region\":\"EU (Ireland)\",\"Trigger\":{\"MetricName\":\"Latency\",\"Namespace\":\"AWS/ELB\",\"Dimensions\":[{\"name\":\"InstanceId\",\"value\":\"i-123\"},{\"name\":\"LoadBalancerName\",\"value\":\"web\"}]}}"
+		}`
+	}
+
+	alerts, err := Decode(c, strings.NewReader(alarm("ALARM")))
+	require.NoError(t, err)
+	require.Len(t, alerts, 1)
+	require.Equal(t, model.LabelSet{
+		"alertname":        "HighLatency",
+		"region":           "EU (Ireland)",
+		"account_id":       "123456789012",
+		"namespace":        "AWS/ELB",
+		"metric_name":      "Latency",
+		"instance":         "i-123",
+		"LoadBalancerName": "web",
+	}, alerts[0].Labels)
+	require.Equal(t, model.LabelValue("Threshold crossed"), alerts[0].Annotations["reason"])
+	require.Equal(t, time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC), alerts[0].StartsAt.UTC())
+	require.True(t, alerts[0].EndsAt.IsZero())
+
+	alerts, err = Decode(c, strings.NewReader(alarm("OK")))
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC), alerts[0].EndsAt.UTC())
+
+	alerts, err = Decode(c, strings.NewReader(alarm("INSUFFICIENT_DATA")))
+	require.NoError(t, err)
+	require.Len(t, alerts, 0)
+
+	alerts, err = Decode(c, strings.NewReader(`{"Type": "SubscriptionConfirmation", "SubscribeURL": "https://sns/confirm"}`))
+	require.NoError(t, err)
+	require.Len(t, alerts, 0)
+
+	_, err = Decode(c, strings.NewReader(`{"Type": "Notification", "Message": "not json"}`))
+	require.Error(t, err)
+}
@@ -0,0 +1,151 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ingest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/prometheus/common/model"
+
+	"github.com/prometheus/alertmanager/types"
+)
+
+// errNotFound is returned for endpoints that the Prometheus server lacks.
+var errNotFound = errors.New("endpoint not found")
+
+// prometheusResponse is the envelope of responses of the Prometheus HTTP API.
+type prometheusResponse struct {
+	Status string          `json:"status"`
+	Error  string          `json:"error"`
+	Data   json.RawMessage `json:"data"`
+}
+
+// prometheusAlerts is the data of the /api/v1/alerts endpoint.
+type prometheusAlerts struct {
+	Alerts []struct {
+		Labels      model.LabelSet `json:"labels"`
+		Annotations model.LabelSet `json:"annotations"`
+		State       string         `json:"state"`
+		ActiveAt    *time.Time     `json:"activeAt"`
+	} `json:"alerts"`
+}
+
+// prometheusVector is the data of an instant query returning a vector.
+type prometheusVector struct {
+	ResultType string `json:"resultType"`
+	Result     []struct {
+		Metric model.LabelSet `json:"metric"`
+	} `json:"result"`
+}
+
+// FetchPrometheus returns the firing alerts of the Prometheus server at the
+// base URL. They are read from its /api/v1/alerts endpoint or, for servers
+// lacking it, from the ALERTS series, which holds no annotations and start
+// times. Pending alerts are skipped. The alerts have a zero EndsAt and the
+// server's URL as generator URL.
+func FetchPrometheus(client *http.Client, baseURL string) ([]*types.Alert, error) {
+	base := strings.TrimRight(baseURL, "/")
+
+	data, err := getPrometheus(client, base+"/api/v1/alerts")
+	if err == errNotFound {
+		q := url.Values{"query": {`ALERTS{alertstate="firing"}`}}
+		if data, err = getPrometheus(client, base+"/api/v1/query?"+q.Encode()); err != nil {
+			return nil, err
+		}
+		return decodeAlertsSeries(data, base)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return decodePrometheusAlerts(data, base)
+}
+
+func getPrometheus(client *http.Client, u string) (json.RawMessage, error) {
+	resp, err := client.Get(u)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, errNotFound
+	}
+	var res prometheusResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 64<<20)).Decode(&res); err != nil {
+		return nil, fmt.Errorf("unexpected response with status code %d: %s", resp.StatusCode, err)
+	}
+	if res.Status != "success" {
+		return nil, fmt.Errorf("request failed: %s", res.Error)
+	}
+	return res.Data, nil
+}
+
+func decodePrometheusAlerts(data json.RawMessage, generatorURL string) ([]*types.Alert, error) {
+	var pa prometheusAlerts
+	if err := json.Unmarshal(data, &pa); err != nil {
+		return nil, err
+	}
+	var alerts []*types.Alert
+	for _, a := range pa.Alerts {
+		if a.State != "firing" {
+			continue
+		}
+		alert := &types.Alert{
+			Alert: model.Alert{
+				Labels:       a.Labels,
+				Annotations:  a.Annotations,
+				GeneratorURL: generatorURL,
+			},
+		}
+		if a.ActiveAt != nil {
+			alert.StartsAt = *a.ActiveAt
+		}
+		alerts = append(alerts, alert)
+	}
+	return alerts, nil
+}
+
+func decodeAlertsSeries(data json.RawMessage, generatorURL string) ([]*types.Alert, error) {
+	var v prometheusVector
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	if v.ResultType != "vector" {
+		return nil, fmt.Errorf("unexpected result type %q", v.ResultType)
+	}
+	var alerts []*types.Alert
+	for _, s := range v.Result {
+		lset := make(model.LabelSet, len(s.Metric))
+		for ln, lv := range s.Metric {
+			if ln != model.MetricNameLabel && ln != "alertstate" {
+				lset[ln] = lv
+			}
+		}
+		alerts = append(alerts, &types.Alert{
+			Alert: model.Alert{
+				Labels:       lset,
+				Annotations:  model.LabelSet{},
+				GeneratorURL: generatorURL,
+			},
+		})
+	}
+	return alerts, nil
+}
@@ -0,0 +1,103 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ingest
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFetchPrometheus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/api/v1/alerts", r.URL.Path)
+		fmt.Fprint(w, `{"status": "success", "data": {"alerts": [{
+			"labels": {"alertname": "HighLatency", "job": "api"},
+			"annotations": {"summary": "Latency is high"},
+			"state": "firing",
+			"activeAt": "2016-01-01T00:00:00Z",
+			"value": "1e+00"
+		}, {
+			"labels": {"alertname": "DiskFull"},
+			"state": "pending",
+			"activeAt": "2016-01-01T00:00:00Z"
+		}]}}`)
+	}))
+	defer srv.Close()
+
+	alerts, err := FetchPrometheus(srv.Client(), srv.URL+"/")
+	require.NoError(t, err)
+	require.Len(t, alerts, 1)
+	require.Equal(t, model.LabelSet{"alertname": "HighLatency", "job": "api"}, alerts[0].Labels)
+	require.Equal(t, model.LabelSet{"summary": "Latency is high"}, alerts[0].Annotations)
+	require.Equal(t, time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC), alerts[0].StartsAt.UTC())
+	require.True(t, alerts[0].EndsAt.IsZero())
+	require.Equal(t, srv.URL, alerts[0].GeneratorURL)
+}
+
+func TestFetchPrometheusAlertsSeries(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/v1/alerts" {
+			http.NotFound(w, r)
+			return
+		}
+		require.Equal(t, "/api/v1/query", r.URL.Path)
+		require.Equal(t, `ALERTS{alertstate="firing"}`, r.URL.Query().Get("query"))
+		fmt.Fprint(w, `{"status": "success", "data": {"resultType": "vector", "result": [{
+			"metric": {"__name__": "ALERTS", "alertname": "HighLatency", "alertstate": "firing", "job": "api"},
+			"value": [1451606400, "1"]
+		}]}}`)
+	}))
+	defer srv.Close()
+
+	alerts, err := FetchPrometheus(srv.Client(), srv.URL)
+	require.NoError(t, err)
+	require.Len(t, alerts, 1)
+	require.Equal(t, model.LabelSet{"alertname": "HighLatency", "job": "api"}, alerts[0].Labels)
+
+	// Failed queries are reported.
+	srv.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"status": "error", "error": "bad query"}`)
+	})
+	_, err = FetchPrometheus(srv.Client(), srv.URL)
+	require.EqualError(t, err, "request failed: bad query")
+}
+
+func TestFetchAlertmanager(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/api/v1/alerts", r.URL.Path)
+		fmt.Fprint(w, `{"status": "success", "data": [{
+			"labels": {"alertname": "HighLatency"},
+			"annotations": {"summary": "Latency is high"},
+			"startsAt": "2016-01-01T00:00:00Z",
+			"endsAt": "2016-01-01T00:05:00Z",
+			"generatorURL": "http://prometheus/graph",
+			"ack": {"by": "oncall"}
+		}]}`)
+	}))
+	defer srv.Close()
+
+	alerts, err := FetchAlertmanager(srv.Client(), srv.URL)
+	require.NoError(t, err)
+	require.Len(t, alerts, 1)
+	require.Equal(t, model.LabelSet{"alertname": "HighLatency"}, alerts[0].Labels)
+	require.Equal(t, time.Date(2016, 1, 1, 0, 5, 0, 0, time.UTC), alerts[0].EndsAt.UTC())
+	require.Equal(t, "http://prometheus/graph", alerts[0].GeneratorURL)
+}
@@ -14,9 +14,13 @@
 package inhibit
 
 import (
+	"regexp"
+	"sort"
+	"strconv"
 	"sync"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/common/log"
 	"github.com/prometheus/common/model"
 
@@ -25,6 +29,28 @@ import (
 	"github.com/prometheus/alertmanager/types"
 )
 
+var (
+	inhibitedAlerts = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "alertmanager",
+		Name:      "inhibit_rule_inhibited_alerts",
+		Help:      "How many alerts are currently inhibited by an inhibit rule.",
+	}, []string{"rule"})
+	inhibitionsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "alertmanager",
+		Name:      "inhibit_rule_inhibitions_total",
+		Help:      "How many times an inhibit rule started inhibiting an alert.",
+	}, []string{"rule"})
+)
+
+func init() {
+	prometheus.Register(inhibitedAlerts)
+	prometheus.Register(inhibitionsTotal)
+}
+
+// inhibitedGCInterval is the interval at which resolved alerts are removed
+// from the alerts tracked as inhibited.
+const inhibitedGCInterval = time.Minute
+
 // An Inhibitor determines whether a given label set is muted
 // based on the currently active alerts and a set of inhibition rules.
 type Inhibitor struct {
@@ -42,26 +68,51 @@ func NewInhibitor(ap provider.Alerts, rs []*config.InhibitRule, mk types.Marker)
 		alerts: ap,
 		marker: mk,
 	}
-	for _, cr := range rs {
+	// Rules are identified by their index in metrics. Reset the metrics
+	// of the previous configuration's rules.
+	inhibitedAlerts.Reset()
+	inhibitionsTotal.Reset()
+
+	for i, cr := range rs {
 		r := NewInhibitRule(cr)
 		ih.rules = append(ih.rules, r)
+
+		inhibitedAlerts.WithLabelValues(strconv.Itoa(i)).Set(0)
+		inhibitionsTotal.WithLabelValues(strconv.Itoa(i))
 	}
 	return ih
 }
 
 func (ih *Inhibitor) runGC() {
+	t := time.NewTicker(inhibitedGCInterval)
+	defer t.Stop()
+
 	for {
 		select {
 		case <-time.After(15 * time.Minute):
 			for _, r := range ih.rules {
 				r.gc()
 			}
+		case <-t.C:
+			ih.gcInhibited()
 		case <-ih.stopc:
 			return
 		}
 	}
 }
 
+// gcInhibited stops tracking alerts as inhibited that are resolved or no
+// longer exist.
+func (ih *Inhibitor) gcInhibited() {
+	for i, r := range ih.rules {
+		n := r.gcInhibited(func(fp model.Fingerprint) bool {
+			a, err := ih.alerts.Get(fp)
+			return err != nil || a.Resolved()
+		})
+		inhibitedAlerts.WithLabelValues(strconv.Itoa(i)).Set(float64(n))
+	}
+}
+
 // Run the Inihibitor's background processing.
 func (ih *Inhibitor) Run() {
 	ih.mtx.Lock()
@@ -115,15 +166,53 @@ func (ih *Inhibitor) Stop() {
 func (ih *Inhibitor) Mutes(lset model.LabelSet) bool {
 	fp := lset.Fingerprint()
 
-	for _, r := range ih.rules {
-		if r.TargetMatchers.Match(lset) && r.hasEqual(lset) {
-			ih.marker.SetInhibited(fp, true)
-			return true
+	// Only the first matching rule inhibits the alert, it is no longer
+	// tracked as inhibited by any other rule.
+	var muted bool
+	for i, r := range ih.rules {
+		inhibits := !muted && r.TargetMatchers.Match(lset) && r.hasEqual(lset)
+
+		added, n := r.setInhibited(fp, inhibits)
+		if added {
+			inhibitionsTotal.WithLabelValues(strconv.Itoa(i)).Inc()
 		}
+		inhibitedAlerts.WithLabelValues(strconv.Itoa(i)).Set(float64(n))
+
+		muted = muted || inhibits
 	}
-	ih.marker.SetInhibited(fp, false)
-	return false
+	ih.marker.SetInhibited(fp, muted)
+	return muted
+}
 
+// Inhibition describes why alerts with a label set are inhibited by a rule.
+type Inhibition struct {
+	// The index of the rule in the configuration and the rule itself.
+	RuleIndex int                 `json:"ruleIndex"`
+	Rule      *config.InhibitRule `json:"rule"`
+	// The firing source alerts that inhibit the alerts.
+	Sources []*types.Alert `json:"sources"`
+}
+
+// Inhibitions returns the rules and source alerts that inhibit alerts with
+// the given label set. Unlike Mutes it does not mark the alert.
+func (ih *Inhibitor) Inhibitions(lset model.LabelSet) []*Inhibition {
+	if ih == nil {
+		return nil
+	}
+	var res []*Inhibition
+	for i, r := range ih.rules {
+		if !r.TargetMatchers.Match(lset) {
+			continue
+		}
+		if sources := r.sources(lset); len(sources) > 0 {
+			res = append(res, &Inhibition{
+				RuleIndex: i,
+				Rule:      r.conf,
+				Sources:   sources,
+			})
+		}
+	}
+	return res
 }
 
 // An InhibitRule specifies that a class of (source) alerts should inhibit
@@ -141,10 +230,18 @@ type InhibitRule struct {
 	// A set of label names whose label values need to be identical in source and
 	// target alerts in order for the inhibition to take effect.
 	Equal map[model.LabelName]struct{}
+	// Labels whose values need to be identical in source and target alerts
+	// after being transformed.
+	EqualTransformed []*EqualTransform
+
+	// The configuration the rule was created from.
+	conf *config.InhibitRule
 
 	mtx sync.RWMutex
 	// Cache of alerts matching source labels.
 	scache map[model.Fingerprint]*types.Alert
+	// Target alerts currently inhibited by the rule.
+	inhibited map[model.Fingerprint]struct{}
 }
 
 // NewInhibitRule returns a new InihibtRule based on a configuration definition.
@@ -168,17 +265,51 @@ func NewInhibitRule(cr *config.InhibitRule) *InhibitRule {
 		targetm = append(targetm, types.NewRegexMatcher(model.LabelName(ln), lv.Regexp))
 	}
 
-	equal := map[model.LabelName]struct{}{}
-	for _, ln := range cr.Equal {
-		equal[ln] = struct{}{}
+	var (
+		equal       = map[model.LabelName]struct{}{}
+		transformed []*EqualTransform
+	)
+	for _, e := range cr.Equal {
+		if e.Regex == nil {
+			equal[e.Label] = struct{}{}
+			continue
+		}
+		transformed = append(transformed, &EqualTransform{
+			Name:        e.Label,
+			Regex:       e.Regex.Regexp,
+			Replacement: e.Replacement,
+		})
 	}
 
 	return &InhibitRule{
-		SourceMatchers: sourcem,
-		TargetMatchers: targetm,
-		Equal:          equal,
-		scache:         map[model.Fingerprint]*types.Alert{},
+		SourceMatchers:   sourcem,
+		TargetMatchers:   targetm,
+		Equal:            equal,
+		EqualTransformed: transformed,
+		conf:             cr,
+		scache:           map[model.Fingerprint]*types.Alert{},
+	}
+}
+
+// EqualTransform transforms the value of a label before it is compared
+// between source and target alerts.
+type EqualTransform struct {
+	Name model.LabelName
+	// Values matching Regex are replaced with the expansion of Replacement.
+	// Other values are compared unchanged.
+	Regex       *regexp.Regexp
+	Replacement string
+}
+
+// value returns the transformed value of the label in the label set.
+func (t *EqualTransform) value(lset model.LabelSet) model.LabelValue {
+	v := string(lset[t.Name])
+
+	m := t.Regex.FindStringSubmatchIndex(v)
+	if m == nil {
+		return model.LabelValue(v)
 	}
+	return model.LabelValue(t.Regex.ExpandString(nil, t.Replacement, v, m))
 }
 
 // set the alert in the source cache.
@@ -195,22 +326,84 @@ func (r *InhibitRule) hasEqual(lset model.LabelSet) bool {
 	r.mtx.RLock()
 	defer r.mtx.RUnlock()
 
-Outer:
 	for _, a := range r.scache {
-		// The cache might be stale and contain resolved alerts.
-		if a.Resolved() {
-			continue
-		}
-		for n := range r.Equal {
-			if a.Labels[n] != lset[n] {
-				continue Outer
-			}
+		if r.equal(a, lset) {
+			return true
 		}
-		return true
 	}
 	return false
 }
 
+// sources returns the alerts in the source cache matching the equal labels
+// for the given label set.
+func (r *InhibitRule) sources(lset model.LabelSet) []*types.Alert {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+
+	var res []*types.Alert
+	for _, a := range r.scache {
+		if r.equal(a, lset) {
+			res = append(res, a)
+		}
+	}
+	sort.Sort(types.AlertSlice(res))
+	return res
+}
+
+// equal checks whether the source alert is firing and matches the equal
+// labels for the given label set.
+func (r *InhibitRule) equal(a *types.Alert, lset model.LabelSet) bool {
+	// The cache might be stale and contain resolved alerts.
+	if a.Resolved() {
+		return false
+	}
+	for n := range r.Equal {
+		if a.Labels[n] != lset[n] {
+			return false
+		}
+	}
+	for _, t := range r.EqualTransformed {
+		if t.value(a.Labels) != t.value(lset) {
+			return false
+		}
+	}
+	return true
+}
+
+// setInhibited sets whether the alert with the given fingerprint is inhibited
+// by the rule. It returns whether the alert was newly inhibited and the number
+// of inhibited alerts.
+func (r *InhibitRule) setInhibited(fp model.Fingerprint, b bool) (bool, int) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	if r.inhibited == nil {
+		r.inhibited = map[model.Fingerprint]struct{}{}
+	}
+	if !b {
+		delete(r.inhibited, fp)
+		return false, len(r.inhibited)
+	}
+	_, ok := r.inhibited[fp]
+	r.inhibited[fp] = struct{}{}
+
+	return !ok, len(r.inhibited)
+}
+
+// gcInhibited removes the alerts for which the function returns true from
+// the inhibited alerts and returns the number of remaining ones.
+func (r *InhibitRule) gcInhibited(resolved func(model.Fingerprint) bool) int {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	for fp := range r.inhibited {
+		if resolved(fp) {
+			delete(r.inhibited, fp)
+		}
+	}
+	return len(r.inhibited)
+}
+
 // gc clears out resolved alerts from the source cache.
 func (r *InhibitRule) gc() {
 	r.mtx.Lock()
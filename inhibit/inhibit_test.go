@@ -15,10 +15,12 @@ package inhibit
 
 import (
 	"reflect"
+	"regexp"
 	"testing"
 	"time"
 
 	"github.com/kylelemons/godebug/pretty"
+	"github.com/prometheus/alertmanager/config"
 	"github.com/prometheus/alertmanager/types"
 	"github.com/prometheus/common/model"
 )
@@ -166,3 +168,236 @@ func TestInhibitRuleGC(t *testing.T) {
 		t.Errorf(pretty.Compare(r.scache, after))
 	}
 }
+
+func TestInhibitorMutesRegex(t *testing.T) {
+	now := time.Now()
+
+	rules := []*config.InhibitRule{{
+		SourceMatch:   map[string]string{"alertname": "ClusterDown"},
+		SourceMatchRE: map[string]config.Regexp{"cluster": {Regexp: regexp.MustCompile("^(?:prod-.*)$")}},
+		TargetMatch:   map[string]string{"severity": "warning"},
+		TargetMatchRE: map[string]config.Regexp{"instance": {Regexp: regexp.MustCompile("^(?:db-.*)$")}},
+		Equal:         []*config.EqualLabel{{Label: "cluster"}},
+	}}
+	ih := NewInhibitor(nil, rules, types.NewMarker())
+
+	source := &types.Alert{
+		Alert: model.Alert{
+			Labels:   model.LabelSet{"alertname": "ClusterDown", "cluster": "prod-eu"},
+			StartsAt: now.Add(-time.Minute),
+			EndsAt:   now.Add(time.Hour),
+		},
+	}
+	for _, r := range ih.rules {
+		if r.SourceMatchers.Match(source.Labels) {
+			r.set(source)
+		}
+	}
+
+	cases := []struct {
+		lset  model.LabelSet
+		muted bool
+	}{
+		{
+			lset:  model.LabelSet{"severity": "warning", "instance": "db-1", "cluster": "prod-eu"},
+			muted: true,
+		},
+		{
+			// Instance does not match the target regex.
+			lset:  model.LabelSet{"severity": "warning", "instance": "web-1", "cluster": "prod-eu"},
+			muted: false,
+		},
+		{
+			// Regular expressions are anchored.
+			lset:  model.LabelSet{"severity": "warning", "instance": "mydb-1", "cluster": "prod-eu"},
+			muted: false,
+		},
+		{
+			// No source alert in the same cluster.
+			lset:  model.LabelSet{"severity": "warning", "instance": "db-1", "cluster": "prod-us"},
+			muted: false,
+		},
+	}
+	for i, c := range cases {
+		if muted := ih.Mutes(c.lset); muted != c.muted {
+			t.Errorf("case %d: unexpected result %t, expected %t", i, muted, c.muted)
+		}
+	}
+
+	// Source alerts not matching the source regex are not cached.
+	staging := model.LabelSet{"alertname": "ClusterDown", "cluster": "staging"}
+	if ih.rules[0].SourceMatchers.Match(staging) {
+		t.Errorf("source matchers unexpectedly matched %s", staging)
+	}
+}
+
+func TestInhibitRuleHasEqualTransformed(t *testing.T) {
+	now := time.Now()
+
+	r := NewInhibitRule(&config.InhibitRule{
+		Equal: []*config.EqualLabel{
+			{Label: "cluster"},
+			{
+				Label:       "instance",
+				Regex:       &config.Regexp{Regexp: regexp.MustCompile(`^(?:(.+):\d+)$`)},
+				Replacement: "$1",
+			},
+		},
+	})
+	r.set(&types.Alert{
+		Alert: model.Alert{
+			Labels:   model.LabelSet{"alertname": "NodeDown", "cluster": "a", "instance": "host:9100"},
+			StartsAt: now.Add(-time.Minute),
+			EndsAt:   now.Add(time.Hour),
+		},
+	})
+
+	cases := []struct {
+		input  model.LabelSet
+		result bool
+	}{
+		{
+			input:  model.LabelSet{"cluster": "a", "instance": "host:8080"},
+			result: true,
+		},
+		{
+			input:  model.LabelSet{"cluster": "a", "instance": "other:8080"},
+			result: false,
+		},
+		{
+			// Plain equal labels still have to match.
+			input:  model.LabelSet{"cluster": "b", "instance": "host:8080"},
+			result: false,
+		},
+		{
+			// Values not matching the expression are compared unchanged.
+			input:  model.LabelSet{"cluster": "a", "instance": "host"},
+			result: true,
+		},
+	}
+	for i, c := range cases {
+		if have := r.hasEqual(c.input); have != c.result {
+			t.Errorf("case %d: unexpected result %t, expected %t", i, have, c.result)
+		}
+	}
+}
+
+func TestInhibitorInhibitions(t *testing.T) {
+	now := time.Now()
+
+	rules := []*config.InhibitRule{
+		{
+			SourceMatch: map[string]string{"alertname": "NodeDown"},
+			TargetMatch: map[string]string{"severity": "critical"},
+		},
+		{
+			SourceMatch: map[string]string{"alertname": "ClusterDown"},
+			TargetMatch: map[string]string{"severity": "warning"},
+			Equal:       []*config.EqualLabel{{Label: "cluster"}},
+		},
+	}
+	ih := NewInhibitor(nil, rules, types.NewMarker())
+
+	newAlert := func(lset model.LabelSet, updated time.Time) *types.Alert {
+		return &types.Alert{
+			Alert: model.Alert{
+				Labels:   lset,
+				StartsAt: now.Add(-time.Minute),
+				EndsAt:   now.Add(time.Hour),
+			},
+			UpdatedAt: updated,
+		}
+	}
+	sources := []*types.Alert{
+		newAlert(model.LabelSet{"alertname": "ClusterDown", "cluster": "a", "instance": "2"}, now),
+		newAlert(model.LabelSet{"alertname": "ClusterDown", "cluster": "a", "instance": "1"}, now.Add(-time.Second)),
+		newAlert(model.LabelSet{"alertname": "ClusterDown", "cluster": "b"}, now),
+	}
+	for _, a := range sources {
+		for _, r := range ih.rules {
+			if r.SourceMatchers.Match(a.Labels) {
+				r.set(a)
+			}
+		}
+	}
+
+	res := ih.Inhibitions(model.LabelSet{"severity": "warning", "cluster": "a"})
+	if len(res) != 1 {
+		t.Fatalf("expected one inhibition, got %d", len(res))
+	}
+	if res[0].RuleIndex != 1 || res[0].Rule != rules[1] {
+		t.Errorf("unexpected rule %d", res[0].RuleIndex)
+	}
+	if !reflect.DeepEqual(res[0].Sources, []*types.Alert{sources[1], sources[0]}) {
+		t.Errorf("unexpected source alerts")
+		t.Errorf(pretty.Compare(res[0].Sources, []*types.Alert{sources[1], sources[0]}))
+	}
+
+	if res := ih.Inhibitions(model.LabelSet{"severity": "critical", "cluster": "a"}); len(res) != 0 {
+		t.Errorf("expected no inhibitions, got %d", len(res))
+	}
+
+	var nilInhibitor *Inhibitor
+	if res := nilInhibitor.Inhibitions(model.LabelSet{"a": "b"}); res != nil {
+		t.Errorf("expected no inhibitions for nil inhibitor")
+	}
+}
+
+func TestInhibitorTracksInhibited(t *testing.T) {
+	now := time.Now()
+
+	rules := []*config.InhibitRule{
+		{
+			SourceMatch: map[string]string{"alertname": "ClusterDown"},
+			TargetMatch: map[string]string{"severity": "warning"},
+		},
+		{
+			SourceMatch: map[string]string{"alertname": "ClusterDown"},
+			TargetMatch: map[string]string{"job": "api"},
+		},
+	}
+	ih := NewInhibitor(nil, rules, types.NewMarker())
+
+	source := &types.Alert{
+		Alert: model.Alert{
+			Labels:   model.LabelSet{"alertname": "ClusterDown"},
+			StartsAt: now.Add(-time.Minute),
+			EndsAt:   now.Add(time.Hour),
+		},
+	}
+	for _, r := range ih.rules {
+		r.set(source)
+	}
+
+	target := model.LabelSet{"severity": "warning", "job": "api"}
+	if !ih.Mutes(target) {
+		t.Fatalf("expected alert to be muted")
+	}
+	// Only the first matching rule tracks the alert.
+	if _, ok := ih.rules[0].inhibited[target.Fingerprint()]; !ok {
+		t.Errorf("alert not tracked as inhibited by first rule")
+	}
+	if len(ih.rules[1].inhibited) != 0 {
+		t.Errorf("alert unexpectedly tracked as inhibited by second rule")
+	}
+
+	// The alert is no longer inhibited by the first rule.
+	ih.rules[0].scache = map[model.Fingerprint]*types.Alert{}
+	if !ih.Mutes(target) {
+		t.Fatalf("expected alert to be muted")
+	}
+	if len(ih.rules[0].inhibited) != 0 {
+		t.Errorf("alert still tracked as inhibited by first rule")
+	}
+	if _, ok := ih.rules[1].inhibited[target.Fingerprint()]; !ok {
+		t.Errorf("alert not tracked as inhibited by second rule")
+	}
+
+	added, n := ih.rules[1].setInhibited(target.Fingerprint(), true)
+	if added || n != 1 {
+		t.Errorf("unexpected result %t, %d for already inhibited alert", added, n)
+	}
+	if n := ih.rules[1].gcInhibited(func(model.Fingerprint) bool { return true }); n != 0 {
+		t.Errorf("expected no inhibited alerts after GC, got %d", n)
+	}
+}
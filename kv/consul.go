@@ -0,0 +1,110 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kv
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+)
+
+// consul is a Store using Consul's KV HTTP API.
+type consul struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+type consulPair struct {
+	Key   string
+	Value []byte
+}
+
+func (c *consul) do(method, key string, body io.Reader, query string) (*http.Response, error) {
+	u := c.url + "/v1/kv/" + key
+	if query != "" {
+		u += "?" + query
+	}
+	req, err := http.NewRequest(method, u, body)
+	if err != nil {
+		return nil, err
+	}
+	if c.token != "" {
+		req.Header.Set("X-Consul-Token", c.token)
+	}
+	return c.client.Do(req)
+}
+
+func (c *consul) get(key, query string) ([]consulPair, error) {
+	resp, err := c.do("GET", key, nil, query)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if err := checkStatus(resp); err != nil {
+		return nil, err
+	}
+	var pairs []consulPair
+	if err := json.NewDecoder(resp.Body).Decode(&pairs); err != nil {
+		return nil, err
+	}
+	return pairs, nil
+}
+
+// List implements the Store interface.
+func (c *consul) List(prefix string) (map[string][]byte, error) {
+	pairs, err := c.get(prefix, "recurse")
+	if err != nil {
+		return nil, err
+	}
+	res := make(map[string][]byte, len(pairs))
+	for _, p := range pairs {
+		res[p.Key] = p.Value
+	}
+	return res, nil
+}
+
+// Get implements the Store interface.
+func (c *consul) Get(key string) ([]byte, bool, error) {
+	pairs, err := c.get(key, "")
+	if err != nil || len(pairs) == 0 {
+		return nil, false, err
+	}
+	return pairs[0].Value, true, nil
+}
+
+// Put implements the Store interface.
+func (c *consul) Put(key string, value []byte) error {
+	resp, err := c.do("PUT", key, bytes.NewReader(value), "")
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return checkStatus(resp)
+}
+
+// Delete implements the Store interface.
+func (c *consul) Delete(key string) error {
+	resp, err := c.do("DELETE", key, nil, "")
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return checkStatus(resp)
+}
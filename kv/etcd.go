@@ -0,0 +1,122 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kv
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+)
+
+// etcd is a Store using the JSON gateway of the etcd v3 API.
+type etcd struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+type etcdKeyValue struct {
+	Key   []byte `json:"key"`
+	Value []byte `json:"value"`
+}
+
+type etcdRangeRequest struct {
+	Key      []byte `json:"key"`
+	RangeEnd []byte `json:"range_end,omitempty"`
+}
+
+type etcdRangeResponse struct {
+	Kvs []etcdKeyValue `json:"kvs"`
+}
+
+func (e *etcd) post(path string, in, out interface{}) error {
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(in); err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", e.url+path, &buf)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if e.token != "" {
+		req.Header.Set("Authorization", e.token)
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := checkStatus(resp); err != nil {
+		return err
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// prefixEnd returns the end of the key range covering all keys with
+// the given prefix.
+func prefixEnd(prefix []byte) []byte {
+	end := append([]byte{}, prefix...)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return end[:i+1]
+		}
+	}
+	// The prefix consists of 0xff bytes only, select all keys after it.
+	return []byte{0}
+}
+
+// List implements the Store interface.
+func (e *etcd) List(prefix string) (map[string][]byte, error) {
+	var resp etcdRangeResponse
+	err := e.post("/v3/kv/range", etcdRangeRequest{
+		Key:      []byte(prefix),
+		RangeEnd: prefixEnd([]byte(prefix)),
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+	res := make(map[string][]byte, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		res[string(kv.Key)] = kv.Value
+	}
+	return res, nil
+}
+
+// Get implements the Store interface.
+func (e *etcd) Get(key string) ([]byte, bool, error) {
+	var resp etcdRangeResponse
+	if err := e.post("/v3/kv/range", etcdRangeRequest{Key: []byte(key)}, &resp); err != nil {
+		return nil, false, err
+	}
+	if len(resp.Kvs) == 0 {
+		return nil, false, nil
+	}
+	return resp.Kvs[0].Value, true, nil
+}
+
+// Put implements the Store interface.
+func (e *etcd) Put(key string, value []byte) error {
+	return e.post("/v3/kv/put", etcdKeyValue{Key: []byte(key), Value: value}, nil)
+}
+
+// Delete implements the Store interface.
+func (e *etcd) Delete(key string) error {
+	return e.post("/v3/kv/deleterange", etcdRangeRequest{Key: []byte(key)}, nil)
+}
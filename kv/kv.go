@@ -0,0 +1,100 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package kv provides access to remote key/value stores such as Consul, etcd
+// and Redis and adapts them to store silences, notification log entries and
+// alerts.
+package kv
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Store is a remote key/value store.
+type Store interface {
+	// List returns all keys and values under the given prefix.
+	List(prefix string) (map[string][]byte, error)
+	// Get returns the value stored at the key. The second return value is
+	// false if the key does not exist.
+	Get(key string) ([]byte, bool, error)
+	// Put stores the value at the key.
+	Put(key string, value []byte) error
+	// Delete removes the key.
+	Delete(key string) error
+}
+
+// New returns a Store for the given backend, which is "consul", "etcd" or
+// "redis". The address is the base URL of the backend's HTTP API or, for
+// Redis, of the form redis://host:port/db. The token is used for
+// authentication if it is not empty. The TLS configuration is used for HTTPS
+// and rediss addresses if it is not nil.
+func New(backend, address, token string, tlsConf *tls.Config) (Store, error) {
+	var (
+		client = &http.Client{Timeout: 10 * time.Second}
+		url    = strings.TrimRight(address, "/")
+	)
+	if tlsConf != nil {
+		client.Transport = &http.Transport{TLSClientConfig: tlsConf}
+	}
+	switch backend {
+	case "consul":
+		return &consul{url: url, token: token, client: client}, nil
+	case "etcd":
+		return &etcd{url: url, token: token, client: client}, nil
+	case "redis":
+		return newRedis(url, token, tlsConf, client.Timeout)
+	}
+	return nil, fmt.Errorf("unknown key/value store backend %q", backend)
+}
+
+// NewTLSConfig returns a TLS configuration verifying the server against the
+// CA certificates in caFile and, if certFile and keyFile are given,
+// presenting the client certificate in them. Empty files are ignored.
+func NewTLSConfig(caFile, certFile, keyFile string) (*tls.Config, error) {
+	tlsConf := &tls.Config{}
+	if caFile != "" {
+		ca, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no certificates in %s", caFile)
+		}
+		tlsConf.RootCAs = pool
+	}
+	if (certFile == "") != (keyFile == "") {
+		return nil, fmt.Errorf("client certificate and key must be given together")
+	}
+	if certFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConf.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConf, nil
+}
+
+func checkStatus(resp *http.Response) error {
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("unexpected status code %v from %s", resp.StatusCode, resp.Request.URL)
+	}
+	return nil
+}
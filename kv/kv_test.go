@@ -0,0 +1,250 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kv
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+
+	silencepb "github.com/prometheus/alertmanager/silence/silencepb"
+	"github.com/prometheus/alertmanager/types"
+)
+
+// fakeConsul serves a subset of Consul's KV HTTP API from memory.
+func fakeConsul(t *testing.T) *httptest.Server {
+	var (
+		mtx sync.Mutex
+		m   = map[string][]byte{}
+	)
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mtx.Lock()
+		defer mtx.Unlock()
+
+		key := strings.TrimPrefix(r.URL.Path, "/v1/kv/")
+		switch r.Method {
+		case "GET":
+			var pairs []consulPair
+			for k, v := range m {
+				if k == key || (r.URL.Query()["recurse"] != nil && strings.HasPrefix(k, key)) {
+					pairs = append(pairs, consulPair{Key: k, Value: v})
+				}
+			}
+			if len(pairs) == 0 {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			json.NewEncoder(w).Encode(pairs)
+		case "PUT":
+			b, err := ioutil.ReadAll(r.Body)
+			require.NoError(t, err)
+			m[key] = b
+			w.Write([]byte("true"))
+		case "DELETE":
+			delete(m, key)
+			w.Write([]byte("true"))
+		}
+	}))
+}
+
+// fakeEtcd serves a subset of etcd's v3 JSON gateway from memory.
+func fakeEtcd(t *testing.T) *httptest.Server {
+	var (
+		mtx sync.Mutex
+		m   = map[string][]byte{}
+	)
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mtx.Lock()
+		defer mtx.Unlock()
+
+		switch r.URL.Path {
+		case "/v3/kv/range":
+			var req etcdRangeRequest
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+
+			var resp etcdRangeResponse
+			for k, v := range m {
+				if k == string(req.Key) || (req.RangeEnd != nil && k >= string(req.Key) && k < string(req.RangeEnd)) {
+					resp.Kvs = append(resp.Kvs, etcdKeyValue{Key: []byte(k), Value: v})
+				}
+			}
+			json.NewEncoder(w).Encode(resp)
+		case "/v3/kv/put":
+			var req etcdKeyValue
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			m[string(req.Key)] = req.Value
+			w.Write([]byte("{}"))
+		case "/v3/kv/deleterange":
+			var req etcdRangeRequest
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			delete(m, string(req.Key))
+			w.Write([]byte("{}"))
+		}
+	}))
+}
+
+func TestStores(t *testing.T) {
+	for backend, fake := range map[string]func(*testing.T) *httptest.Server{
+		"consul": fakeConsul,
+		"etcd":   fakeEtcd,
+	} {
+		srv := fake(t)
+		defer srv.Close()
+
+		s, err := New(backend, srv.URL, "", nil)
+		require.NoError(t, err)
+
+		_, ok, err := s.Get("am/a")
+		require.NoError(t, err)
+		require.False(t, ok, backend)
+
+		require.NoError(t, s.Put("am/a", []byte("1")))
+		require.NoError(t, s.Put("am/b", []byte("2")))
+		require.NoError(t, s.Put("other", []byte("3")))
+
+		v, ok, err := s.Get("am/a")
+		require.NoError(t, err)
+		require.True(t, ok, backend)
+		require.Equal(t, []byte("1"), v, backend)
+
+		require.NoError(t, s.Delete("am/b"))
+
+		vals, err := s.List("am/")
+		require.NoError(t, err)
+		require.Equal(t, map[string][]byte{"am/a": []byte("1")}, vals, backend)
+	}
+
+	_, err := New("zookeeper", "", "", nil)
+	require.Error(t, err)
+}
+
+func TestStoreTLS(t *testing.T) {
+	plain := fakeConsul(t)
+	defer plain.Close()
+	srv := httptest.NewTLSServer(plain.Config.Handler)
+	defer srv.Close()
+
+	// The server's certificate is not trusted by default.
+	s, err := New("consul", srv.URL, "", &tls.Config{})
+	require.NoError(t, err)
+	require.Error(t, s.Put("am/a", []byte("1")))
+
+	pool := x509.NewCertPool()
+	pool.AddCert(srv.Certificate())
+	s, err = New("consul", srv.URL, "", &tls.Config{RootCAs: pool})
+	require.NoError(t, err)
+	require.NoError(t, s.Put("am/a", []byte("1")))
+}
+
+func TestNewTLSConfig(t *testing.T) {
+	tlsConf, err := NewTLSConfig("", "", "")
+	require.NoError(t, err)
+	require.Nil(t, tlsConf.RootCAs)
+
+	_, err = NewTLSConfig("", "client.crt", "")
+	require.Error(t, err)
+
+	_, err = NewTLSConfig("does-not-exist.crt", "", "")
+	require.Error(t, err)
+}
+
+func TestPrefixEnd(t *testing.T) {
+	require.Equal(t, []byte("am0"), prefixEnd([]byte("am/")))
+	require.Equal(t, []byte("b"), prefixEnd([]byte{'a', 0xff}))
+	require.Equal(t, []byte{0}, prefixEnd([]byte{0xff}))
+}
+
+func TestSilenceStore(t *testing.T) {
+	srv := fakeConsul(t)
+	defer srv.Close()
+
+	kv, err := New("consul", srv.URL, "", nil)
+	require.NoError(t, err)
+	s := NewSilenceStore(kv, "am/")
+
+	now := time.Now()
+	newSilence := func(comment string, updatedAt time.Time) *silencepb.MeshSilence {
+		ts, err := ptypes.TimestampProto(updatedAt)
+		require.NoError(t, err)
+		return &silencepb.MeshSilence{
+			Silence: &silencepb.Silence{
+				Id:        "id1",
+				UpdatedAt: ts,
+				Comments:  []*silencepb.Comment{{Comment: comment}},
+			},
+			ExpiresAt: ts,
+		}
+	}
+
+	require.NoError(t, s.Set(newSilence("new", now)))
+	// Older versions must not overwrite the stored silence.
+	require.NoError(t, s.Set(newSilence("old", now.Add(-time.Minute))))
+
+	sils, err := s.Load()
+	require.NoError(t, err)
+	require.Len(t, sils, 1)
+	require.Equal(t, "new", sils[0].Silence.Comments[0].Comment)
+
+	require.NoError(t, s.Delete("id1"))
+
+	sils, err = s.Load()
+	require.NoError(t, err)
+	require.Len(t, sils, 0)
+}
+
+func TestAlertStore(t *testing.T) {
+	srv := fakeConsul(t)
+	defer srv.Close()
+
+	kv, err := New("consul", srv.URL, "", nil)
+	require.NoError(t, err)
+	s := NewAlertStore(kv, "am/")
+
+	now := time.Now().UTC()
+	a1 := &types.Alert{
+		Alert: model.Alert{
+			Labels:   model.LabelSet{"alertname": "a1"},
+			StartsAt: now,
+			EndsAt:   now.Add(time.Hour),
+		},
+		UpdatedAt: now,
+	}
+	a2 := &types.Alert{
+		Alert: model.Alert{
+			Labels:   model.LabelSet{"alertname": "a2"},
+			StartsAt: now,
+			EndsAt:   now.Add(time.Hour),
+		},
+		UpdatedAt: now,
+	}
+	require.NoError(t, s.Set(a1, a2))
+	require.NoError(t, s.Delete(a2.Fingerprint()))
+
+	alerts, err := s.Load()
+	require.NoError(t, err)
+	require.Len(t, alerts, 1)
+	require.Equal(t, a1.Fingerprint(), alerts[0].Fingerprint())
+	require.True(t, a1.EndsAt.Equal(alerts[0].EndsAt))
+}
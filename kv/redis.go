@@ -0,0 +1,295 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kv
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// redis is a Store using the Redis protocol over a single connection, which
+// is established again after errors.
+type redis struct {
+	addr    string
+	db      int
+	token   string
+	tlsConf *tls.Config
+	timeout time.Duration
+
+	mtx  sync.Mutex
+	conn net.Conn
+	rd   *bufio.Reader
+}
+
+// newRedis returns a Store for a Redis server at an address of the form
+// redis://host:port/db, using TLS for the rediss scheme. The database
+// number is optional.
+func newRedis(address, token string, tlsConf *tls.Config, timeout time.Duration) (*redis, error) {
+	u, err := url.Parse(address)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "redis":
+	case "rediss":
+		if tlsConf == nil {
+			tlsConf = &tls.Config{}
+		}
+	default:
+		return nil, fmt.Errorf("invalid Redis address %q, must start with redis:// or rediss://", address)
+	}
+	r := &redis{
+		addr:    u.Host,
+		token:   token,
+		tlsConf: tlsConf,
+		timeout: timeout,
+	}
+	if _, _, err := net.SplitHostPort(r.addr); err != nil {
+		r.addr = net.JoinHostPort(r.addr, "6379")
+	}
+	if db := strings.Trim(u.Path, "/"); db != "" {
+		if r.db, err = strconv.Atoi(db); err != nil {
+			return nil, fmt.Errorf("invalid Redis database %q", db)
+		}
+	}
+	return r, nil
+}
+
+// redisError is an error reply of the server.
+type redisError string
+
+func (e redisError) Error() string { return "redis: " + string(e) }
+
+func (r *redis) dial() error {
+	var (
+		conn net.Conn
+		err  error
+	)
+	d := &net.Dialer{Timeout: r.timeout}
+	if r.tlsConf != nil {
+		conn, err = tls.DialWithDialer(d, "tcp", r.addr, r.tlsConf)
+	} else {
+		conn, err = d.Dial("tcp", r.addr)
+	}
+	if err != nil {
+		return err
+	}
+	r.conn = conn
+	r.rd = bufio.NewReader(conn)
+
+	if r.token != "" {
+		if _, err := r.roundTrip("AUTH", r.token); err != nil {
+			return err
+		}
+	}
+	if r.db != 0 {
+		if _, err := r.roundTrip("SELECT", strconv.Itoa(r.db)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// do sends a command and returns its reply, which is nil, an int64, a
+// []byte or an []interface{} of those.
+func (r *redis) do(args ...string) (interface{}, error) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	if r.conn == nil {
+		if err := r.dial(); err != nil {
+			r.close()
+			return nil, err
+		}
+	}
+	reply, err := r.roundTrip(args...)
+	if _, ok := err.(redisError); err != nil && !ok {
+		// The connection is in an unknown state after I/O errors.
+		r.close()
+	}
+	return reply, err
+}
+
+func (r *redis) close() {
+	if r.conn != nil {
+		r.conn.Close()
+	}
+	r.conn = nil
+	r.rd = nil
+}
+
+func (r *redis) roundTrip(args ...string) (interface{}, error) {
+	if err := r.conn.SetDeadline(time.Now().Add(r.timeout)); err != nil {
+		return nil, err
+	}
+	w := bufio.NewWriter(r.conn)
+	fmt.Fprintf(w, "*%d\r\n", len(args))
+	for _, a := range args {
+		fmt.Fprintf(w, "$%d\r\n%s\r\n", len(a), a)
+	}
+	if err := w.Flush(); err != nil {
+		return nil, err
+	}
+	return readReply(r.rd)
+}
+
+// readReply reads a reply in the Redis serialization protocol.
+func readReply(rd *bufio.Reader) (interface{}, error) {
+	line, err := rd.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || !strings.HasSuffix(line, "\r\n") {
+		return nil, fmt.Errorf("malformed Redis reply %q", line)
+	}
+	typ, line := line[0], line[1:len(line)-2]
+
+	switch typ {
+	case '+':
+		return []byte(line), nil
+	case '-':
+		return nil, redisError(line)
+	case ':':
+		return strconv.ParseInt(line, 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line)
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		b := make([]byte, n+2)
+		if _, err := io.ReadFull(rd, b); err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	case '*':
+		n, err := strconv.Atoi(line)
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		res := make([]interface{}, n)
+		for i := range res {
+			if res[i], err = readReply(rd); err != nil {
+				return nil, err
+			}
+		}
+		return res, nil
+	}
+	return nil, fmt.Errorf("unknown Redis reply type %q", typ)
+}
+
+var errRedisReply = errors.New("unexpected Redis reply")
+
+// globEscape escapes the special characters of Redis glob patterns.
+func globEscape(s string) string {
+	var b bytes.Buffer
+	for _, c := range s {
+		switch c {
+		case '*', '?', '[', ']', '\\':
+			b.WriteByte('\\')
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// List implements the Store interface.
+func (r *redis) List(prefix string) (map[string][]byte, error) {
+	var (
+		cursor = "0"
+		keys   []string
+	)
+	for {
+		reply, err := r.do("SCAN", cursor, "MATCH", globEscape(prefix)+"*", "COUNT", "1000")
+		if err != nil {
+			return nil, err
+		}
+		res, ok := reply.([]interface{})
+		if !ok || len(res) != 2 {
+			return nil, errRedisReply
+		}
+		next, ok := res[0].([]byte)
+		if !ok {
+			return nil, errRedisReply
+		}
+		batch, ok := res[1].([]interface{})
+		if !ok {
+			return nil, errRedisReply
+		}
+		for _, k := range batch {
+			b, ok := k.([]byte)
+			if !ok {
+				return nil, errRedisReply
+			}
+			keys = append(keys, string(b))
+		}
+		if cursor = string(next); cursor == "0" {
+			break
+		}
+	}
+
+	res := make(map[string][]byte, len(keys))
+	if len(keys) == 0 {
+		return res, nil
+	}
+	reply, err := r.do(append([]string{"MGET"}, keys...)...)
+	if err != nil {
+		return nil, err
+	}
+	vals, ok := reply.([]interface{})
+	if !ok || len(vals) != len(keys) {
+		return nil, errRedisReply
+	}
+	for i, v := range vals {
+		// Keys deleted since the scan are missing.
+		if b, ok := v.([]byte); ok {
+			res[keys[i]] = b
+		}
+	}
+	return res, nil
+}
+
+// Get implements the Store interface.
+func (r *redis) Get(key string) ([]byte, bool, error) {
+	reply, err := r.do("GET", key)
+	if err != nil || reply == nil {
+		return nil, false, err
+	}
+	b, ok := reply.([]byte)
+	if !ok {
+		return nil, false, errRedisReply
+	}
+	return b, true, nil
+}
+
+// Put implements the Store interface.
+func (r *redis) Put(key string, value []byte) error {
+	_, err := r.do("SET", key, string(value))
+	return err
+}
+
+// Delete implements the Store interface.
+func (r *redis) Delete(key string) error {
+	_, err := r.do("DEL", key)
+	return err
+}
@@ -0,0 +1,147 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kv
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRedis serves the subset of Redis commands used by the store from
+// memory and requires the given password.
+func fakeRedis(t *testing.T, password string) net.Listener {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	var (
+		mtx sync.Mutex
+		m   = map[string]string{}
+	)
+	serve := func(conn net.Conn) {
+		defer conn.Close()
+		rd := bufio.NewReader(conn)
+		authed := false
+		for {
+			reply, err := readReply(rd)
+			if err != nil {
+				return
+			}
+			var args []string
+			for _, a := range reply.([]interface{}) {
+				args = append(args, string(a.([]byte)))
+			}
+
+			mtx.Lock()
+			var resp string
+			switch cmd := strings.ToUpper(args[0]); {
+			case cmd == "AUTH":
+				authed = args[1] == password
+				resp = "+OK\r\n"
+				if !authed {
+					resp = "-ERR invalid password\r\n"
+				}
+			case !authed:
+				resp = "-NOAUTH Authentication required.\r\n"
+			case cmd == "SET":
+				m[args[1]] = args[2]
+				resp = "+OK\r\n"
+			case cmd == "GET":
+				v, ok := m[args[1]]
+				resp = "$-1\r\n"
+				if ok {
+					resp = fmt.Sprintf("$%d\r\n%s\r\n", len(v), v)
+				}
+			case cmd == "DEL":
+				delete(m, args[1])
+				resp = ":1\r\n"
+			case cmd == "SCAN":
+				prefix := strings.TrimSuffix(args[3], "*")
+				var keys []string
+				for k := range m {
+					if strings.HasPrefix(k, prefix) {
+						keys = append(keys, fmt.Sprintf("$%d\r\n%s\r\n", len(k), k))
+					}
+				}
+				resp = fmt.Sprintf("*2\r\n$1\r\n0\r\n*%d\r\n%s", len(keys), strings.Join(keys, ""))
+			case cmd == "MGET":
+				resp = fmt.Sprintf("*%d\r\n", len(args)-1)
+				for _, k := range args[1:] {
+					v := m[k]
+					resp += fmt.Sprintf("$%d\r\n%s\r\n", len(v), v)
+				}
+			default:
+				resp = "-ERR unknown command\r\n"
+			}
+			mtx.Unlock()
+
+			if _, err := conn.Write([]byte(resp)); err != nil {
+				return
+			}
+		}
+	}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go serve(conn)
+		}
+	}()
+	return l
+}
+
+func TestRedisStore(t *testing.T) {
+	l := fakeRedis(t, "secret")
+	defer l.Close()
+
+	s, err := New("redis", "redis://"+l.Addr().String(), "wrong", nil)
+	require.NoError(t, err)
+	require.Error(t, s.Put("am/a", []byte("1")))
+
+	s, err = New("redis", "redis://"+l.Addr().String(), "secret", nil)
+	require.NoError(t, err)
+
+	_, ok, err := s.Get("am/a")
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	require.NoError(t, s.Put("am/a", []byte("1\r\n")))
+	require.NoError(t, s.Put("am/b", []byte("2")))
+	require.NoError(t, s.Put("other", []byte("3")))
+
+	v, ok, err := s.Get("am/a")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, []byte("1\r\n"), v)
+
+	require.NoError(t, s.Delete("am/b"))
+
+	vals, err := s.List("am/")
+	require.NoError(t, err)
+	require.Equal(t, map[string][]byte{"am/a": []byte("1\r\n")}, vals)
+
+	_, err = New("redis", "http://localhost:6379", "", nil)
+	require.Error(t, err)
+}
+
+func TestGlobEscape(t *testing.T) {
+	require.Equal(t, `am/\*\?\[x\]\\`, globEscape(`am/*?[x]\`))
+}
@@ -0,0 +1,233 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kv
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/timestamp"
+
+	"github.com/prometheus/common/model"
+
+	nflogpb "github.com/prometheus/alertmanager/nflog/nflogpb"
+	silencepb "github.com/prometheus/alertmanager/silence/silencepb"
+	"github.com/prometheus/alertmanager/types"
+)
+
+// SilenceStore is a silence.Store keeping silences in a Store below a prefix.
+type SilenceStore struct {
+	kv     Store
+	prefix string
+}
+
+// NewSilenceStore returns a new SilenceStore storing silences in kv below
+// the given prefix.
+func NewSilenceStore(kv Store, prefix string) *SilenceStore {
+	return &SilenceStore{kv: kv, prefix: prefix + "silences/"}
+}
+
+// Load implements the silence.Store interface.
+func (s *SilenceStore) Load() ([]*silencepb.MeshSilence, error) {
+	vals, err := s.kv.List(s.prefix)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*silencepb.MeshSilence, 0, len(vals))
+	for k, v := range vals {
+		var sil silencepb.MeshSilence
+		if err := proto.Unmarshal(v, &sil); err != nil {
+			return nil, fmt.Errorf("decoding silence %q: %s", k, err)
+		}
+		res = append(res, &sil)
+	}
+	return res, nil
+}
+
+// Set implements the silence.Store interface.
+func (s *SilenceStore) Set(sil *silencepb.MeshSilence) error {
+	key := s.prefix + sil.Silence.Id
+
+	b, ok, err := s.kv.Get(key)
+	if err != nil {
+		return err
+	}
+	if ok {
+		var prev silencepb.MeshSilence
+		if err := proto.Unmarshal(b, &prev); err != nil {
+			return err
+		}
+		newer, err := after(prev.Silence.UpdatedAt, sil.Silence.UpdatedAt)
+		if err != nil || !newer {
+			return err
+		}
+	}
+	if b, err = proto.Marshal(sil); err != nil {
+		return err
+	}
+	return s.kv.Put(key, b)
+}
+
+// Delete implements the silence.Store interface.
+func (s *SilenceStore) Delete(ids ...string) error {
+	for _, id := range ids {
+		if err := s.kv.Delete(s.prefix + id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NflogStore is a nflog.Store keeping notification log entries in a Store
+// below a prefix.
+type NflogStore struct {
+	kv     Store
+	prefix string
+}
+
+// NewNflogStore returns a new NflogStore storing log entries in kv below
+// the given prefix.
+func NewNflogStore(kv Store, prefix string) *NflogStore {
+	return &NflogStore{kv: kv, prefix: prefix + "nflog/"}
+}
+
+// key returns the key of an entry. Group keys are arbitrary bytes and
+// are hashed together with the receiver to get a valid key.
+func (s *NflogStore) key(e *nflogpb.MeshEntry) string {
+	h := sha256.New()
+	h.Write(e.Entry.GroupKey)
+	h.Write([]byte{0})
+	h.Write([]byte(e.Entry.Receiver.String()))
+	return fmt.Sprintf("%s%x", s.prefix, h.Sum(nil))
+}
+
+// Load implements the nflog.Store interface.
+func (s *NflogStore) Load() ([]*nflogpb.MeshEntry, error) {
+	vals, err := s.kv.List(s.prefix)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*nflogpb.MeshEntry, 0, len(vals))
+	for k, v := range vals {
+		var e nflogpb.MeshEntry
+		if err := proto.Unmarshal(v, &e); err != nil {
+			return nil, fmt.Errorf("decoding log entry %q: %s", k, err)
+		}
+		res = append(res, &e)
+	}
+	return res, nil
+}
+
+// Set implements the nflog.Store interface.
+func (s *NflogStore) Set(e *nflogpb.MeshEntry) error {
+	key := s.key(e)
+
+	b, ok, err := s.kv.Get(key)
+	if err != nil {
+		return err
+	}
+	if ok {
+		var prev nflogpb.MeshEntry
+		if err := proto.Unmarshal(b, &prev); err != nil {
+			return err
+		}
+		newer, err := after(prev.Entry.Timestamp, e.Entry.Timestamp)
+		if err != nil || !newer {
+			return err
+		}
+	}
+	if b, err = proto.Marshal(e); err != nil {
+		return err
+	}
+	return s.kv.Put(key, b)
+}
+
+// Delete implements the nflog.Store interface.
+func (s *NflogStore) Delete(es ...*nflogpb.MeshEntry) error {
+	for _, e := range es {
+		if err := s.kv.Delete(s.key(e)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AlertStore is a mem.Store keeping alerts in a Store below a prefix.
+type AlertStore struct {
+	kv     Store
+	prefix string
+}
+
+// NewAlertStore returns a new AlertStore storing alerts in kv below the
+// given prefix.
+func NewAlertStore(kv Store, prefix string) *AlertStore {
+	return &AlertStore{kv: kv, prefix: prefix + "alerts/"}
+}
+
+// Load implements the mem.Store interface.
+func (s *AlertStore) Load() ([]*types.Alert, error) {
+	vals, err := s.kv.List(s.prefix)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*types.Alert, 0, len(vals))
+	for k, v := range vals {
+		var a types.Alert
+		if err := json.Unmarshal(v, &a); err != nil {
+			return nil, fmt.Errorf("decoding alert %q: %s", k, err)
+		}
+		res = append(res, &a)
+	}
+	return res, nil
+}
+
+// Set implements the mem.Store interface.
+func (s *AlertStore) Set(alerts ...*types.Alert) error {
+	for _, a := range alerts {
+		b, err := json.Marshal(a)
+		if err != nil {
+			return err
+		}
+		if err := s.kv.Put(s.prefix+a.Fingerprint().String(), b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Delete implements the mem.Store interface.
+func (s *AlertStore) Delete(fps ...model.Fingerprint) error {
+	for _, fp := range fps {
+		if err := s.kv.Delete(s.prefix + fp.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// after returns whether the timestamp b is after a.
+func after(a, b *timestamp.Timestamp) (bool, error) {
+	ta, err := ptypes.Timestamp(a)
+	if err != nil {
+		return false, err
+	}
+	tb, err := ptypes.Timestamp(b)
+	if err != nil {
+		return false, err
+	}
+	return tb.After(ta), nil
+}
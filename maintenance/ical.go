@@ -0,0 +1,224 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package maintenance
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// parseICal returns the events of an iCalendar (RFC 5545) document.
+// Recurrence rules are not supported, only the first occurrence of a
+// recurring event is returned. Cancelled events are skipped.
+func parseICal(r io.Reader) ([]*Event, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		events []*Event
+		cur    *Event
+		// Properties of the current event that are needed to compute
+		// its time range.
+		start, end, duration *property
+		cancelled            bool
+	)
+	for i, l := range lines {
+		p, err := parseProperty(l)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", i+1, err)
+		}
+		switch {
+		case p.name == "BEGIN" && p.value == "VEVENT":
+			cur = &Event{}
+			start, end, duration, cancelled = nil, nil, nil, false
+			continue
+		case cur == nil:
+			continue
+		}
+
+		switch p.name {
+		case "END":
+			if p.value != "VEVENT" {
+				continue
+			}
+			if !cancelled {
+				if err := setTimeRange(cur, start, end, duration); err != nil {
+					return nil, fmt.Errorf("event %q: %s", cur.UID, err)
+				}
+				events = append(events, cur)
+			}
+			cur = nil
+		case "UID":
+			cur.UID = p.value
+		case "SUMMARY":
+			cur.Summary = unescapeText(p.value)
+		case "DESCRIPTION":
+			cur.Description = unescapeText(p.value)
+		case "LOCATION":
+			cur.Location = unescapeText(p.value)
+		case "STATUS":
+			cancelled = p.value == "CANCELLED"
+		case "DTSTART":
+			start = p
+		case "DTEND":
+			end = p
+		case "DURATION":
+			duration = p
+		}
+	}
+	return events, nil
+}
+
+// unfold reads the content lines of an iCalendar document, joining lines
+// that were folded.
+func unfold(r io.Reader) ([]string, error) {
+	var (
+		lines []string
+		sc    = bufio.NewScanner(r)
+	)
+	for sc.Scan() {
+		l := strings.TrimRight(sc.Text(), "\r")
+		if len(l) > 0 && (l[0] == ' ' || l[0] == '\t') && len(lines) > 0 {
+			lines[len(lines)-1] += l[1:]
+			continue
+		}
+		if l != "" {
+			lines = append(lines, l)
+		}
+	}
+	return lines, sc.Err()
+}
+
+type property struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+// parseProperty parses a content line of the form name *(";" param) ":" value.
+func parseProperty(l string) (*property, error) {
+	// Find the colon separating name and parameters from the value while
+	// skipping quoted parameter values.
+	quoted := false
+	sep := -1
+	for i, c := range l {
+		if c == '"' {
+			quoted = !quoted
+		} else if c == ':' && !quoted {
+			sep = i
+			break
+		}
+	}
+	if sep < 0 {
+		return nil, fmt.Errorf("invalid content line %q", l)
+	}
+	parts := strings.Split(l[:sep], ";")
+
+	p := &property{
+		name:   strings.ToUpper(parts[0]),
+		params: map[string]string{},
+		value:  l[sep+1:],
+	}
+	for _, param := range parts[1:] {
+		kv := strings.SplitN(param, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid parameter %q", param)
+		}
+		p.params[strings.ToUpper(kv[0])] = strings.Trim(kv[1], `"`)
+	}
+	return p, nil
+}
+
+var textReplacer = strings.NewReplacer(`\n`, "\n", `\N`, "\n", `\,`, ",", `\;`, ";", `\\`, `\`)
+
+func unescapeText(s string) string {
+	return textReplacer.Replace(s)
+}
+
+// setTimeRange sets the start and end time of the event from the DTSTART,
+// DTEND and DURATION properties.
+func setTimeRange(e *Event, start, end, duration *property) error {
+	if start == nil {
+		return fmt.Errorf("missing start time")
+	}
+	var err error
+	if e.StartsAt, err = parseDateTime(start); err != nil {
+		return err
+	}
+	switch {
+	case end != nil:
+		e.EndsAt, err = parseDateTime(end)
+	case duration != nil:
+		var d time.Duration
+		d, err = parseDuration(duration.value)
+		e.EndsAt = e.StartsAt.Add(d)
+	case start.params["VALUE"] == "DATE":
+		// All-day events without an end last for one day.
+		e.EndsAt = e.StartsAt.AddDate(0, 0, 1)
+	default:
+		e.EndsAt = e.StartsAt
+	}
+	return err
+}
+
+// parseDateTime parses a DATE or DATE-TIME value. Times without a time zone
+// are interpreted as UTC.
+func parseDateTime(p *property) (time.Time, error) {
+	loc := time.UTC
+	if tzid, ok := p.params["TZID"]; ok {
+		var err error
+		if loc, err = time.LoadLocation(tzid); err != nil {
+			return time.Time{}, fmt.Errorf("unknown time zone %q", tzid)
+		}
+	}
+	if p.params["VALUE"] == "DATE" {
+		return time.ParseInLocation("20060102", p.value, loc)
+	}
+	if strings.HasSuffix(p.value, "Z") {
+		return time.Parse("20060102T150405Z", p.value)
+	}
+	return time.ParseInLocation("20060102T150405", p.value, loc)
+}
+
+var durationRE = regexp.MustCompile(`^([+-])?P(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)?$`)
+
+// parseDuration parses an iCalendar duration such as P1DT2H.
+func parseDuration(s string) (time.Duration, error) {
+	m := durationRE.FindStringSubmatch(s)
+	if m == nil || s == "P" || s == "PT" {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	var d time.Duration
+	for i, unit := range []time.Duration{7 * 24 * time.Hour, 24 * time.Hour, time.Hour, time.Minute, time.Second} {
+		if m[i+2] == "" {
+			continue
+		}
+		n, err := strconv.Atoi(m[i+2])
+		if err != nil {
+			return 0, err
+		}
+		d += time.Duration(n) * unit
+	}
+	if m[1] == "-" {
+		d = -d
+	}
+	return d, nil
+}
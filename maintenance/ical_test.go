@@ -0,0 +1,95 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package maintenance
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testCalendar = "BEGIN:VCALENDAR\r\n" +
+	"VERSION:2.0\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:db-upgrade@example.com\r\n" +
+	"SUMMARY:Database upgrade\\, part 1\r\n" +
+	"DESCRIPTION:Upgrading the primary\r\n" +
+	"  database.\r\n" +
+	"LOCATION:db-1\r\n" +
+	"DTSTART:20170102T100000Z\r\n" +
+	"DTEND:20170102T120000Z\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:network\r\n" +
+	"DTSTART;TZID=Europe/Berlin:20170103T100000\r\n" +
+	"DURATION:PT1H30M\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:all-day\r\n" +
+	"DTSTART;VALUE=DATE:20170104\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:cancelled\r\n" +
+	"STATUS:CANCELLED\r\n" +
+	"DTSTART:20170105T100000Z\r\n" +
+	"DTEND:20170105T120000Z\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+func TestParseICal(t *testing.T) {
+	events, err := parseICal(strings.NewReader(testCalendar))
+	require.NoError(t, err)
+
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+
+	require.Equal(t, []*Event{
+		{
+			UID:         "db-upgrade@example.com",
+			Summary:     "Database upgrade, part 1",
+			Description: "Upgrading the primary database.",
+			Location:    "db-1",
+			StartsAt:    time.Date(2017, 1, 2, 10, 0, 0, 0, time.UTC),
+			EndsAt:      time.Date(2017, 1, 2, 12, 0, 0, 0, time.UTC),
+		}, {
+			UID:      "network",
+			StartsAt: time.Date(2017, 1, 3, 10, 0, 0, 0, berlin),
+			EndsAt:   time.Date(2017, 1, 3, 11, 30, 0, 0, berlin),
+		}, {
+			UID:      "all-day",
+			StartsAt: time.Date(2017, 1, 4, 0, 0, 0, 0, time.UTC),
+			EndsAt:   time.Date(2017, 1, 5, 0, 0, 0, 0, time.UTC),
+		},
+	}, events)
+}
+
+func TestParseDuration(t *testing.T) {
+	for in, exp := range map[string]time.Duration{
+		"PT15M":    15 * time.Minute,
+		"P1DT2H":   26 * time.Hour,
+		"P1W":      7 * 24 * time.Hour,
+		"-PT30S":   -30 * time.Second,
+		"+P2DT10S": 48*time.Hour + 10*time.Second,
+	} {
+		d, err := parseDuration(in)
+		require.NoError(t, err, in)
+		require.Equal(t, exp, d, in)
+	}
+	for _, in := range []string{"", "P", "PT", "1H", "P1H"} {
+		_, err := parseDuration(in)
+		require.Error(t, err, in)
+	}
+}
@@ -0,0 +1,329 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package maintenance creates silences for the maintenance windows of
+// calendars, which are either iCalendar feeds or defined in the configuration.
+package maintenance
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/prometheus/common/log"
+
+	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/silence"
+	"github.com/prometheus/alertmanager/silence/silencepb"
+)
+
+// Event is a maintenance window. It is the data passed to matcher templates.
+type Event struct {
+	UID         string
+	Summary     string
+	Description string
+	Location    string
+	Labels      map[string]string
+	StartsAt    time.Time
+	EndsAt      time.Time
+}
+
+// commentPrefix starts the comment of every silence created for a
+// maintenance window. It is followed by the window's UID.
+const commentPrefix = "Maintenance window "
+
+// Calendar keeps the silences for the maintenance windows of a calendar in
+// sync with the calendar.
+//
+// Silences created by a calendar carry the calendar's name as their author
+// and the UID of the window in their comment. Silences of windows that were
+// removed or changed are expired.
+type Calendar struct {
+	conf     *config.MaintenanceCalendar
+	silences *silence.Silences
+	matchers []*matcherTemplate
+	client   *http.Client
+	now      func() time.Time
+
+	mtx   sync.Mutex
+	stopc chan struct{}
+}
+
+type matcherTemplate struct {
+	name    string
+	value   *template.Template
+	isRegex bool
+}
+
+// New returns a new Calendar for the given configuration.
+func New(conf *config.MaintenanceCalendar, s *silence.Silences) (*Calendar, error) {
+	c := &Calendar{
+		conf:     conf,
+		silences: s,
+		client:   &http.Client{Timeout: 30 * time.Second},
+		now:      time.Now,
+	}
+	for _, m := range conf.Matchers {
+		tmpl, err := template.New(m.Name).Option("missingkey=zero").Parse(m.Value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid template for matcher %q: %s", m.Name, err)
+		}
+		c.matchers = append(c.matchers, &matcherTemplate{
+			name:    m.Name,
+			value:   tmpl,
+			isRegex: m.IsRegex,
+		})
+	}
+	return c, nil
+}
+
+// Run synchronizes the calendar at the configured interval until Stop is called.
+func (c *Calendar) Run() {
+	c.mtx.Lock()
+	c.stopc = make(chan struct{})
+	stopc := c.stopc
+	c.mtx.Unlock()
+
+	t := time.NewTicker(time.Duration(c.conf.RefreshInterval))
+	defer t.Stop()
+
+	for {
+		if err := c.sync(); err != nil {
+			log.With("calendar", c.conf.Name).Errorf("Synchronizing maintenance calendar failed: %s", err)
+		}
+		select {
+		case <-stopc:
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// Stop the synchronization of the calendar.
+func (c *Calendar) Stop() {
+	if c == nil {
+		return
+	}
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if c.stopc != nil {
+		close(c.stopc)
+		c.stopc = nil
+	}
+}
+
+// author returns the author of the silences created by the calendar.
+func (c *Calendar) author() string {
+	return "calendar:" + c.conf.Name
+}
+
+// events returns all maintenance windows of the calendar.
+func (c *Calendar) events() ([]*Event, error) {
+	if c.conf.URL == "" {
+		var events []*Event
+		for _, w := range c.conf.Windows {
+			events = append(events, &Event{
+				UID:         w.ID,
+				Summary:     w.Summary,
+				Description: w.Description,
+				Location:    w.Location,
+				Labels:      w.Labels,
+				StartsAt:    w.StartsAt.Time,
+				EndsAt:      w.EndsAt.Time,
+			})
+		}
+		return events, nil
+	}
+
+	resp, err := c.client.Get(c.conf.URL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return nil, fmt.Errorf("unexpected status code %v", resp.StatusCode)
+	}
+	return parseICal(resp.Body)
+}
+
+// sync creates, updates and expires silences so that there is exactly one
+// silence for every current and upcoming maintenance window.
+func (c *Calendar) sync() error {
+	events, err := c.events()
+	if err != nil {
+		return err
+	}
+	now := c.now()
+
+	sils, err := c.silences.Query(silence.QState(silence.StateActive, silence.StatePending))
+	if err != nil {
+		return err
+	}
+	existing := map[string]*silencepb.Silence{}
+	for _, sil := range sils {
+		if len(sil.Comments) == 0 || sil.Comments[0].Author != c.author() {
+			continue
+		}
+		uid := windowUID(sil.Comments[0].Comment)
+		if _, ok := existing[uid]; ok {
+			// Duplicates may be created by concurrent synchronizations of
+			// several instances. Only keep one of them.
+			if err := c.silences.Expire(sil.Id); err != nil {
+				return err
+			}
+			continue
+		}
+		existing[uid] = sil
+	}
+
+	for _, e := range events {
+		if !e.EndsAt.After(now) || !e.EndsAt.After(e.StartsAt) {
+			continue
+		}
+		matchers, err := c.silenceMatchers(e)
+		if err != nil {
+			log.With("calendar", c.conf.Name).Errorf("Creating matchers for maintenance window %q failed: %s", e.UID, err)
+			continue
+		}
+
+		sil, ok := existing[e.UID]
+		delete(existing, e.UID)
+
+		if ok && matchersEqual(sil.Matchers, matchers) {
+			if err := c.updateTimeRange(sil, e, now); err != nil {
+				return err
+			}
+			continue
+		}
+		if ok {
+			if err := c.silences.Expire(sil.Id); err != nil {
+				return err
+			}
+		}
+		if err := c.create(e, matchers, now); err != nil {
+			return err
+		}
+	}
+
+	// Expire silences of windows that no longer exist.
+	for _, sil := range existing {
+		if err := c.silences.Expire(sil.Id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Calendar) create(e *Event, matchers []*silencepb.Matcher, now time.Time) error {
+	sil := &silencepb.Silence{
+		Matchers: matchers,
+		Comments: []*silencepb.Comment{{
+			Author:  c.author(),
+			Comment: fmt.Sprintf("%s%s: %s", commentPrefix, e.UID, e.Summary),
+		}},
+	}
+	var err error
+	if sil.Comments[0].Timestamp, err = ptypes.TimestampProto(now); err != nil {
+		return err
+	}
+	// Windows that already started are silenced from now on.
+	if e.StartsAt.After(now) {
+		if sil.StartsAt, err = ptypes.TimestampProto(e.StartsAt); err != nil {
+			return err
+		}
+	}
+	if sil.EndsAt, err = ptypes.TimestampProto(e.EndsAt); err != nil {
+		return err
+	}
+	_, err = c.silences.Create(sil)
+	return err
+}
+
+// updateTimeRange adjusts the time range of an existing silence to the
+// window's time range where possible.
+func (c *Calendar) updateTimeRange(sil *silencepb.Silence, e *Event, now time.Time) error {
+	startsAt, err := ptypes.Timestamp(sil.StartsAt)
+	if err != nil {
+		return err
+	}
+	endsAt, err := ptypes.Timestamp(sil.EndsAt)
+	if err != nil {
+		return err
+	}
+
+	var start time.Time
+	// The start of active silences cannot be modified.
+	if startsAt.After(now) && !startsAt.Equal(e.StartsAt) {
+		start = e.StartsAt
+		if start.Before(now) {
+			start = now
+		}
+	}
+	if start.IsZero() && endsAt.Equal(e.EndsAt) {
+		return nil
+	}
+	return c.silences.SetTimeRange(sil.Id, start, e.EndsAt)
+}
+
+// silenceMatchers returns the matchers of the silence for the event.
+func (c *Calendar) silenceMatchers(e *Event) ([]*silencepb.Matcher, error) {
+	var res []*silencepb.Matcher
+	for _, m := range c.matchers {
+		var buf bytes.Buffer
+		if err := m.value.Execute(&buf, e); err != nil {
+			return nil, err
+		}
+		pm := &silencepb.Matcher{
+			Name:    m.name,
+			Pattern: buf.String(),
+			Type:    silencepb.Matcher_EQUAL,
+		}
+		if m.isRegex {
+			pm.Type = silencepb.Matcher_REGEXP
+		}
+		if pm.Pattern == "" {
+			return nil, fmt.Errorf("empty value for matcher %q", m.name)
+		}
+		res = append(res, pm)
+	}
+	return res, nil
+}
+
+// windowUID returns the UID of the maintenance window from the comment of
+// a silence created for it.
+func windowUID(comment string) string {
+	s := strings.TrimPrefix(comment, commentPrefix)
+	if i := strings.Index(s, ": "); i >= 0 {
+		s = s[:i]
+	}
+	return s
+}
+
+func matchersEqual(a, b []*silencepb.Matcher) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Name != b[i].Name || a[i].Pattern != b[i].Pattern || a[i].Type != b[i].Type {
+			return false
+		}
+	}
+	return true
+}
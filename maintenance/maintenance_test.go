@@ -0,0 +1,107 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package maintenance
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/stretchr/testify/require"
+
+	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/silence"
+	"github.com/prometheus/alertmanager/silence/silencepb"
+)
+
+func TestCalendarSync(t *testing.T) {
+	silences, err := silence.New(silence.Options{})
+	require.NoError(t, err)
+
+	now := time.Now().UTC()
+	conf := &config.MaintenanceCalendar{
+		Name: "ops",
+		Windows: []*config.MaintenanceWindow{
+			{
+				ID:       "running",
+				Labels:   map[string]string{"instance": "db-1"},
+				StartsAt: config.Time{Time: now.Add(-time.Hour)},
+				EndsAt:   config.Time{Time: now.Add(time.Hour)},
+			}, {
+				ID:       "upcoming",
+				Labels:   map[string]string{"instance": "db-2"},
+				StartsAt: config.Time{Time: now.Add(time.Hour)},
+				EndsAt:   config.Time{Time: now.Add(2 * time.Hour)},
+			}, {
+				ID:       "past",
+				Labels:   map[string]string{"instance": "db-3"},
+				StartsAt: config.Time{Time: now.Add(-2 * time.Hour)},
+				EndsAt:   config.Time{Time: now.Add(-time.Hour)},
+			},
+		},
+		Matchers: []*config.MatcherTemplate{
+			{Name: "instance", Value: `{{ .Labels.instance }}`},
+		},
+	}
+	c, err := New(conf, silences)
+	require.NoError(t, err)
+
+	windows := func() map[string]*silencepb.Silence {
+		sils, err := silences.Query(silence.QState(silence.StateActive, silence.StatePending))
+		require.NoError(t, err)
+
+		res := map[string]*silencepb.Silence{}
+		for _, sil := range sils {
+			require.Equal(t, "calendar:ops", sil.Comments[0].Author)
+			res[windowUID(sil.Comments[0].Comment)] = sil
+		}
+		return res
+	}
+
+	require.NoError(t, c.sync())
+	sils := windows()
+	require.Len(t, sils, 2)
+	require.Equal(t, "db-1", sils["running"].Matchers[0].Pattern)
+	require.Equal(t, "db-2", sils["upcoming"].Matchers[0].Pattern)
+
+	startsAt, err := ptypes.Timestamp(sils["upcoming"].StartsAt)
+	require.NoError(t, err)
+	require.True(t, startsAt.Equal(conf.Windows[1].StartsAt.Time))
+
+	// Synchronizing again must not create duplicates.
+	require.NoError(t, c.sync())
+	require.Equal(t, sils, windows())
+
+	// Changed windows are updated and removed windows expired.
+	conf.Windows = conf.Windows[1:2]
+	conf.Windows[0].EndsAt = config.Time{Time: now.Add(3 * time.Hour)}
+
+	require.NoError(t, c.sync())
+	updated := windows()
+	require.Len(t, updated, 1)
+	require.Equal(t, sils["upcoming"].Id, updated["upcoming"].Id)
+
+	endsAt, err := ptypes.Timestamp(updated["upcoming"].EndsAt)
+	require.NoError(t, err)
+	require.True(t, endsAt.Equal(now.Add(3*time.Hour)))
+
+	// Silences are recreated if their matchers change.
+	conf.Windows[0].Labels["instance"] = "db-4"
+
+	require.NoError(t, c.sync())
+	updated = windows()
+	require.Len(t, updated, 1)
+	require.NotEqual(t, sils["upcoming"].Id, updated["upcoming"].Id)
+	require.Equal(t, "db-4", updated["upcoming"].Matchers[0].Pattern)
+}
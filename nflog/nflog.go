@@ -57,6 +57,9 @@ type Log interface {
 	// Snapshot the current log state and return the number
 	// of bytes written.
 	Snapshot(w io.Writer) (int, error)
+	// Restore merges a snapshot generated by Snapshot into the log
+	// state and returns the number of entries that changed.
+	Restore(r io.Reader) (int, error)
 	// GC removes expired entries from the log. It returns
 	// the total number of deleted entries.
 	GC() (int, error)
@@ -70,6 +73,7 @@ type Log interface {
 type query struct {
 	recv     *pb.Receiver
 	groupKey []byte
+	since    *time.Time
 }
 
 // QueryParam is a function that modifies a query to incorporate
@@ -85,6 +89,16 @@ func QReceiver(r *pb.Receiver) QueryParam {
 	}
 }
 
+// QSince selects the most recent entries of all receivers and groups that
+// were logged at or after the given time. It cannot be combined with other
+// parameters.
+func QSince(t time.Time) QueryParam {
+	return func(q *query) error {
+		q.since = &t
+		return nil
+	}
+}
+
 // QGroupKey adds a group key as querying argument.
 func QGroupKey(gk []byte) QueryParam {
 	return func(q *query) error {
@@ -99,13 +113,17 @@ type nlog struct {
 	now       func() time.Time
 	retention time.Duration
 
-	runInterval time.Duration
-	snapf       string
-	stopc       chan struct{}
-	done        func()
+	runInterval  time.Duration
+	snapInterval time.Duration
+	snapf        string
+	stopc        chan struct{}
+	done         func()
 
 	gossip mesh.Gossip // gossip channel for sharing log state.
 
+	store        Store // optional persistent storage backend.
+	syncInterval time.Duration
+
 	// For now we only store the most recently added log entry.
 	// The key is a serialized concatenation of group key and receiver.
 	// Currently our memory state is equivalent to the mesh.GossipData
@@ -121,9 +139,12 @@ type metrics struct {
 	queriesTotal     prometheus.Counter
 	queryErrorsTotal prometheus.Counter
 	queryDuration    prometheus.Histogram
+	entries          prometheus.GaugeFunc
+	snapshotSize     prometheus.Gauge
+	gcExpiredTotal   prometheus.Counter
 }
 
-func newMetrics(r prometheus.Registerer) *metrics {
+func newMetrics(r prometheus.Registerer, l *nlog) *metrics {
 	m := &metrics{}
 
 	m.gcDuration = prometheus.NewSummary(prometheus.SummaryOpts{
@@ -146,6 +167,22 @@ func newMetrics(r prometheus.Registerer) *metrics {
 		Name: "alertmanager_nflog_query_duration_seconds",
 		Help: "Duration of notification log query evaluation.",
 	})
+	m.entries = prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "alertmanager_nflog_entries",
+		Help: "Number of entries in the notification log.",
+	}, func() float64 {
+		l.mtx.RLock()
+		defer l.mtx.RUnlock()
+		return float64(len(l.st))
+	})
+	m.snapshotSize = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "alertmanager_nflog_snapshot_size_bytes",
+		Help: "Size of the last notification log snapshot in bytes.",
+	})
+	m.gcExpiredTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "alertmanager_nflog_gc_expired_entries_total",
+		Help: "Number of notification log entries removed by garbage collection after their retention.",
+	})
 
 	if r != nil {
 		r.MustRegister(
@@ -154,6 +191,9 @@ func newMetrics(r prometheus.Registerer) *metrics {
 			m.queriesTotal,
 			m.queryErrorsTotal,
 			m.queryDuration,
+			m.entries,
+			m.snapshotSize,
+			m.gcExpiredTotal,
 		)
 	}
 	return m
@@ -200,7 +240,7 @@ func WithLogger(logger log.Logger) Option {
 // WithMetrics registers metrics for the notification log.
 func WithMetrics(r prometheus.Registerer) Option {
 	return func(l *nlog) error {
-		l.metrics = newMetrics(r)
+		l.metrics = newMetrics(r, l)
 		return nil
 	}
 }
@@ -222,6 +262,19 @@ func WithMaintenance(d time.Duration, stopc chan struct{}, done func()) Option {
 	}
 }
 
+// WithSnapshotInterval configures the Log to save a snapshot at the given
+// interval instead of after each garbage collection. It has no effect unless
+// maintenance and a snapshot file are configured.
+func WithSnapshotInterval(d time.Duration) Option {
+	return func(l *nlog) error {
+		if d < 0 {
+			return fmt.Errorf("snapshot interval must not be negative")
+		}
+		l.snapInterval = d
+		return nil
+	}
+}
+
 // WithSnapshot configures the log to be initialized from a given snapshot file.
 // If maintenance is configured, a snapshot will be saved periodically and on
 // shutdown as well.
@@ -232,6 +285,29 @@ func WithSnapshot(sf string) Option {
 	}
 }
 
+// WithStore configures the log to persist its entries in the given store
+// instead of a snapshot file. Entries added to the store by other instances
+// are loaded at the given interval until the maintenance stops.
+func WithStore(s Store, syncInterval time.Duration) Option {
+	return func(l *nlog) error {
+		l.store = s
+		l.syncInterval = syncInterval
+		return nil
+	}
+}
+
+// Store persists log entries outside of the process, e.g. in a key/value
+// store that is shared by several Alertmanager instances.
+type Store interface {
+	// Load returns all entries in the store.
+	Load() ([]*pb.MeshEntry, error)
+	// Set creates or replaces an entry in the store unless the stored
+	// entry is more recent.
+	Set(*pb.MeshEntry) error
+	// Delete removes the given entries from the store.
+	Delete(es ...*pb.MeshEntry) error
+}
+
 func utcNow() time.Time {
 	return time.Now().UTC()
 }
@@ -250,7 +326,15 @@ func New(opts ...Option) (Log, error) {
 		}
 	}
 	if l.metrics == nil {
-		l.metrics = newMetrics(nil)
+		l.metrics = newMetrics(nil, l)
+	}
+	if l.store != nil && l.snapf != "" {
+		return nil, fmt.Errorf("snapshot must not be set along with a store")
+	}
+	if l.store != nil {
+		if err := l.loadStore(); err != nil {
+			return l, err
+		}
 	}
 
 	if l.snapf != "" {
@@ -267,10 +351,52 @@ func New(opts ...Option) (Log, error) {
 	}
 
 	go l.run()
+	go l.syncStore()
 
 	return l, nil
 }
 
+// loadStore merges the entries from the store into the state.
+func (l *nlog) loadStore() error {
+	es, err := l.store.Load()
+	if err != nil {
+		return err
+	}
+	st := gossipData{}
+	for _, e := range es {
+		st[stateKey(e.Entry.GroupKey, e.Entry.Receiver)] = e
+	}
+
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	if delta := l.st.mergeDelta(st); len(delta) > 0 && l.gossip != nil {
+		l.gossip.GossipBroadcast(delta)
+	}
+	return nil
+}
+
+// syncStore periodically loads entries that were added to the store by
+// other instances.
+func (l *nlog) syncStore() {
+	if l.store == nil || l.syncInterval == 0 || l.stopc == nil {
+		return
+	}
+	t := time.NewTicker(l.syncInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-l.stopc:
+			return
+		case <-t.C:
+			if err := l.loadStore(); err != nil {
+				l.logger.With("err", err).Error("loading entries from store failed")
+			}
+		}
+	}
+}
+
 // run periodic background maintenance.
 func (l *nlog) run() {
 	if l.runInterval == 0 || l.stopc == nil {
@@ -279,18 +405,28 @@ func (l *nlog) run() {
 	t := time.NewTicker(l.runInterval)
 	defer t.Stop()
 
+	// Without a separate snapshot interval, a snapshot is saved after
+	// each garbage collection.
+	var snapc <-chan time.Time
+	if l.snapInterval > 0 && l.snapf != "" {
+		st := time.NewTicker(l.snapInterval)
+		defer st.Stop()
+		snapc = st.C
+	}
+
 	if l.done != nil {
 		defer l.done()
 	}
 
-	f := func() error {
+	gc := func() error {
 		start := l.now()
 		l.logger.Info("running maintenance")
 		defer l.logger.With("duration", l.now().Sub(start)).Info("maintenance done")
 
-		if _, err := l.GC(); err != nil {
-			return err
-		}
+		_, err := l.GC()
+		return err
+	}
+	snapshot := func() error {
 		if l.snapf == "" {
 			return nil
 		}
@@ -298,10 +434,12 @@ func (l *nlog) run() {
 		if err != nil {
 			return err
 		}
-		// TODO(fabxc): potentially expose snapshot size in log message.
-		if _, err := l.Snapshot(f); err != nil {
+		n, err := l.Snapshot(f)
+		if err != nil {
 			return err
 		}
+		l.metrics.snapshotSize.Set(float64(n))
+		l.logger.With("size", n).Debug("notification log snapshot saved")
 		return f.Close()
 	}
 
@@ -311,8 +449,19 @@ Loop:
 		case <-l.stopc:
 			break Loop
 		case <-t.C:
-			if err := f(); err != nil {
+			if err := gc(); err != nil {
 				l.logger.With("err", err).Error("running maintenance failed")
+				continue
+			}
+			if snapc != nil {
+				continue
+			}
+			if err := snapshot(); err != nil {
+				l.logger.With("err", err).Error("creating snapshot failed")
+			}
+		case <-snapc:
+			if err := snapshot(); err != nil {
+				l.logger.With("err", err).Error("creating snapshot failed")
 			}
 		}
 	}
@@ -320,7 +469,10 @@ Loop:
 	if l.snapf == "" {
 		return
 	}
-	if err := f(); err != nil {
+	if err := gc(); err != nil {
+		l.logger.With("err", err).Error("running maintenance failed")
+	}
+	if err := snapshot(); err != nil {
 		l.logger.With("err", err).Error("creating shutdown snapshot failed")
 	}
 }
@@ -380,6 +532,11 @@ func (l *nlog) log(r *pb.Receiver, gkey, ghash []byte, resolved bool) error {
 		},
 		ExpiresAt: expts,
 	}
+	if l.store != nil {
+		if err := l.store.Set(e); err != nil {
+			return err
+		}
+	}
 	l.gossip.GossipBroadcast(gossipData{
 		key: e,
 	})
@@ -394,21 +551,27 @@ func (l *nlog) GC() (int, error) {
 	defer func() { l.metrics.gcDuration.Observe(time.Since(start).Seconds()) }()
 
 	now := l.now()
-	var n int
+	var expired []*pb.MeshEntry
 
 	l.mtx.Lock()
 	defer l.mtx.Unlock()
 
 	for k, le := range l.st {
 		if ets, err := ptypes.Timestamp(le.ExpiresAt); err != nil {
-			return n, err
+			return len(expired), err
 		} else if !ets.After(now) {
 			delete(l.st, k)
-			n++
+			expired = append(expired, le)
+		}
+	}
+	l.metrics.gcExpiredTotal.Add(float64(len(expired)))
+	if l.store != nil && len(expired) > 0 {
+		if err := l.store.Delete(expired...); err != nil {
+			return len(expired), err
 		}
 	}
 
-	return n, nil
+	return len(expired), nil
 }
 
 // Query implements the Log interface.
@@ -423,6 +586,12 @@ func (l *nlog) Query(params ...QueryParam) ([]*pb.Entry, error) {
 				return nil, err
 			}
 		}
+		if q.since != nil {
+			if q.recv != nil || q.groupKey != nil {
+				return nil, errors.New("time range queries cannot be combined with other parameters")
+			}
+			return l.querySince(*q.since)
+		}
 		// TODO(fabxc): For now our only query mode is the most recent entry for a
 		// receiver/group_key combination.
 		if q.recv == nil || q.groupKey == nil {
@@ -446,6 +615,24 @@ func (l *nlog) Query(params ...QueryParam) ([]*pb.Entry, error) {
 	return entries, err
 }
 
+// querySince returns the entries that were logged at or after the given time.
+func (l *nlog) querySince(t time.Time) ([]*pb.Entry, error) {
+	l.mtx.RLock()
+	defer l.mtx.RUnlock()
+
+	var res []*pb.Entry
+	for _, le := range l.st {
+		ts, err := ptypes.Timestamp(le.Entry.Timestamp)
+		if err != nil {
+			return nil, err
+		}
+		if !ts.Before(t) {
+			res = append(res, le.Entry)
+		}
+	}
+	return res, nil
+}
+
 // loadSnapshot loads a snapshot generated by Snapshot() into the state.
 func (l *nlog) loadSnapshot(r io.Reader) error {
 	l.mtx.Lock()
@@ -468,6 +655,46 @@ func (l *nlog) loadSnapshot(r io.Reader) error {
 	return nil
 }
 
+// Restore implements the Log interface.
+func (l *nlog) Restore(r io.Reader) (int, error) {
+	st := gossipData{}
+	for {
+		var e pb.MeshEntry
+		if _, err := pbutil.ReadDelimited(r, &e); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return 0, err
+		}
+		if e.Entry == nil {
+			return 0, errors.New("log entry missing")
+		}
+		if _, err := ptypes.Timestamp(e.Entry.Timestamp); err != nil {
+			return 0, fmt.Errorf("invalid log entry timestamp: %s", err)
+		}
+		if _, err := ptypes.Timestamp(e.ExpiresAt); err != nil {
+			return 0, fmt.Errorf("invalid log entry expiry time: %s", err)
+		}
+		st[stateKey(e.Entry.GroupKey, e.Entry.Receiver)] = &e
+	}
+
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	delta := l.st.mergeDelta(st)
+	if l.store != nil {
+		for _, e := range delta {
+			if err := l.store.Set(e); err != nil {
+				return 0, err
+			}
+		}
+	}
+	if len(delta) > 0 && l.gossip != nil {
+		l.gossip.GossipBroadcast(delta)
+	}
+	return len(delta), nil
+}
+
 // Snapshot implements the Log interface.
 func (l *nlog) Snapshot(w io.Writer) (int, error) {
 	start := time.Now()
@@ -14,6 +14,7 @@
 package nflog
 
 import (
+	"bytes"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -24,6 +25,7 @@ import (
 	"github.com/golang/protobuf/ptypes/timestamp"
 	pb "github.com/prometheus/alertmanager/nflog/nflogpb"
 	"github.com/stretchr/testify/require"
+	"github.com/weaveworks/mesh"
 )
 
 func TestNlogGC(t *testing.T) {
@@ -42,7 +44,7 @@ func TestNlogGC(t *testing.T) {
 			"a3": newEntry(now.Add(-time.Second)),
 		},
 		now:     func() time.Time { return now },
-		metrics: newMetrics(nil),
+		metrics: newMetrics(nil, nil),
 	}
 	n, err := l.GC()
 	require.NoError(t, err, "unexpected error in garbage collection")
@@ -54,6 +56,150 @@ func TestNlogGC(t *testing.T) {
 	require.Equal(t, l.st, expected, "unepexcted state after garbage collection")
 }
 
+type mockStore struct {
+	entries map[string]*pb.MeshEntry
+}
+
+func (s *mockStore) Load() ([]*pb.MeshEntry, error) {
+	var res []*pb.MeshEntry
+	for _, e := range s.entries {
+		res = append(res, e)
+	}
+	return res, nil
+}
+
+func (s *mockStore) Set(e *pb.MeshEntry) error {
+	s.entries[stateKey(e.Entry.GroupKey, e.Entry.Receiver)] = e
+	return nil
+}
+
+func (s *mockStore) Delete(es ...*pb.MeshEntry) error {
+	for _, e := range es {
+		delete(s.entries, stateKey(e.Entry.GroupKey, e.Entry.Receiver))
+	}
+	return nil
+}
+
+func TestNlogStore(t *testing.T) {
+	now := utcNow()
+	recv := &pb.Receiver{GroupName: "abc", Integration: "test1", Idx: 1}
+
+	stored := &pb.MeshEntry{
+		Entry: &pb.Entry{
+			GroupKey:  []byte("stored"),
+			Receiver:  recv,
+			Timestamp: mustTimestampProto(now.Add(-time.Hour)),
+		},
+		ExpiresAt: mustTimestampProto(now.Add(-time.Minute)),
+	}
+	store := &mockStore{entries: map[string]*pb.MeshEntry{
+		stateKey(stored.Entry.GroupKey, recv): stored,
+	}}
+
+	l, err := New(
+		WithStore(store, 0),
+		WithNow(func() time.Time { return now }),
+		WithRetention(time.Hour),
+	)
+	require.NoError(t, err)
+
+	// Entries in the store are loaded on creation.
+	res, err := l.Query(QGroupKey([]byte("stored")), QReceiver(recv))
+	require.NoError(t, err)
+	require.Equal(t, []*pb.Entry{stored.Entry}, res)
+
+	// New entries are written through to the store.
+	l.(*nlog).gossip = nopGossip{}
+	require.NoError(t, l.LogActive(recv, []byte("new"), []byte("hash")))
+	require.Contains(t, store.entries, stateKey([]byte("new"), recv))
+
+	// Garbage collected entries are removed from the store.
+	n, err := l.GC()
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	require.NotContains(t, store.entries, stateKey(stored.Entry.GroupKey, recv))
+
+	_, err = New(WithStore(store, 0), WithSnapshot("nflog"))
+	require.Error(t, err)
+}
+
+func TestNlogRestore(t *testing.T) {
+	now := utcNow()
+	recv := &pb.Receiver{GroupName: "abc", Integration: "test", Idx: 1}
+
+	e := &pb.MeshEntry{
+		Entry: &pb.Entry{
+			GroupKey:  []byte("key"),
+			Receiver:  recv,
+			GroupHash: []byte("hash"),
+			Timestamp: mustTimestampProto(now),
+		},
+		ExpiresAt: mustTimestampProto(now.Add(time.Hour)),
+	}
+	l1 := &nlog{
+		st:      gossipData{stateKey(e.Entry.GroupKey, recv): e},
+		metrics: newMetrics(nil, nil),
+	}
+	var buf bytes.Buffer
+	_, err := l1.Snapshot(&buf)
+	require.NoError(t, err)
+	snap := buf.Bytes()
+
+	l, err := New(WithNow(func() time.Time { return now.Add(time.Minute) }))
+	require.NoError(t, err)
+
+	n, err := l.Restore(bytes.NewReader(snap))
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+
+	res, err := l.Query(QGroupKey([]byte("key")), QReceiver(recv))
+	require.NoError(t, err)
+	require.Equal(t, []*pb.Entry{e.Entry}, res)
+
+	// More recent entries are not replaced by the snapshot.
+	l.(*nlog).gossip = nopGossip{}
+	require.NoError(t, l.LogResolved(recv, []byte("key"), []byte("hash")))
+
+	n, err = l.Restore(bytes.NewReader(snap))
+	require.NoError(t, err)
+	require.Equal(t, 0, n)
+
+	res, err = l.Query(QGroupKey([]byte("key")), QReceiver(recv))
+	require.NoError(t, err)
+	require.True(t, res[0].Resolved)
+}
+
+func TestNlogQuerySince(t *testing.T) {
+	now := utcNow()
+	recv := &pb.Receiver{GroupName: "abc", Integration: "test", Idx: 1}
+
+	entry := func(key string, ts time.Time) *pb.MeshEntry {
+		return &pb.MeshEntry{
+			Entry: &pb.Entry{
+				GroupKey:  []byte(key),
+				Receiver:  recv,
+				Timestamp: mustTimestampProto(ts),
+			},
+			ExpiresAt: mustTimestampProto(ts.Add(time.Hour)),
+		}
+	}
+	l := &nlog{
+		st: gossipData{
+			stateKey([]byte("old"), recv): entry("old", now.Add(-2*time.Hour)),
+			stateKey([]byte("new"), recv): entry("new", now.Add(-time.Minute)),
+		},
+		metrics: newMetrics(nil, nil),
+	}
+
+	res, err := l.Query(QSince(now.Add(-time.Hour)))
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	require.Equal(t, []byte("new"), res[0].GroupKey)
+
+	_, err = l.Query(QSince(now), QReceiver(recv))
+	require.Error(t, err)
+}
+
 func TestNlogSnapshot(t *testing.T) {
 	// Check whether storing and loading the snapshot is symmetric.
 	now := utcNow()
@@ -101,7 +247,7 @@ func TestNlogSnapshot(t *testing.T) {
 
 		l1 := &nlog{
 			st:      gossipData{},
-			metrics: newMetrics(nil),
+			metrics: newMetrics(nil, nil),
 		}
 		// Setup internal state manually.
 		for _, e := range c.entries {
@@ -125,6 +271,42 @@ func TestNlogSnapshot(t *testing.T) {
 	}
 }
 
+func TestNlogSnapshotInterval(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nflog")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	var (
+		snapf = filepath.Join(dir, "nflog")
+		stopc = make(chan struct{})
+		donec = make(chan struct{})
+		recv  = &pb.Receiver{GroupName: "abc", Integration: "test1", Idx: 1}
+	)
+	// Snapshots are saved although garbage collection never runs.
+	l, err := New(
+		WithSnapshot(snapf),
+		WithMaintenance(time.Hour, stopc, func() { close(donec) }),
+		WithSnapshotInterval(10*time.Millisecond),
+		WithRetention(time.Hour),
+	)
+	require.NoError(t, err)
+	l.(*nlog).gossip = nopGossip{}
+	require.NoError(t, l.LogActive(recv, []byte("key"), []byte("hash")))
+
+	var fi os.FileInfo
+	for i := 0; i < 100; i++ {
+		if fi, err = os.Stat(snapf); err == nil && fi.Size() > 0 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	require.NoError(t, err)
+	require.NotZero(t, fi.Size(), "no snapshot was saved")
+
+	close(stopc)
+	<-donec
+}
+
 func TestReplaceFile(t *testing.T) {
 	dir, err := ioutil.TempDir("", "replace_file")
 	require.NoError(t, err, "creating temp dir failed")
@@ -282,3 +464,8 @@ func mustTimestampProto(ts time.Time) *timestamp.Timestamp {
 	}
 	return res
 }
+
+type nopGossip struct{}
+
+func (nopGossip) GossipBroadcast(d mesh.GossipData)         {}
+func (nopGossip) GossipUnicast(mesh.PeerName, []byte) error { return nil }
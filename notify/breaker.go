@@ -0,0 +1,208 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/prometheus/common/log"
+	"github.com/prometheus/common/model"
+
+	"github.com/prometheus/alertmanager/types"
+)
+
+// CircuitOpenAlertName is the name of the meta-alert firing while the circuit
+// breaker of an integration is open.
+const CircuitOpenAlertName = "NotificationCircuitOpen"
+
+// BreakerOptions configures the circuit breakers of integrations.
+type BreakerOptions struct {
+	// The number of consecutive failed attempts after which the breaker of
+	// an integration opens. Zero disables circuit breakers.
+	Failures int
+	// How long an open breaker rejects attempts before letting a single
+	// probe attempt through, whose outcome closes or reopens it.
+	Cooldown time.Duration
+	// Alerts receives the meta-alert of a breaker when it opens or reopens
+	// and, resolved, when it closes. It may be nil.
+	Alerts func(...*types.Alert) error
+}
+
+// Breakers holds the circuit breakers of all integrations. Breakers are kept
+// across configuration reloads, so an integration with an unchanged name
+// keeps its state.
+type Breakers struct {
+	o BreakerOptions
+
+	mtx      sync.Mutex
+	breakers map[string]*breaker
+}
+
+// NewBreakers returns new circuit breakers.
+func NewBreakers(o BreakerOptions) *Breakers {
+	return &Breakers{
+		o:        o,
+		breakers: map[string]*breaker{},
+	}
+}
+
+// get returns the breaker of an integration of the receiver. It returns nil
+// if circuit breakers are disabled.
+func (bs *Breakers) get(receiver string, i Integration) *breaker {
+	if bs == nil || bs.o.Failures <= 0 {
+		return nil
+	}
+	bs.mtx.Lock()
+	defer bs.mtx.Unlock()
+
+	key := fmt.Sprintf("%s/%s[%d]", receiver, i.name, i.idx)
+	b, ok := bs.breakers[key]
+	if !ok {
+		b = &breaker{
+			o:           &bs.o,
+			receiver:    receiver,
+			integration: i.name,
+			idx:         i.idx,
+			now:         time.Now,
+		}
+		bs.breakers[key] = b
+	}
+	return b
+}
+
+// breaker is the circuit breaker of a single integration. It is closed while
+// attempts succeed, opens after too many consecutive failures and lets a
+// probe attempt through once the cooldown passed.
+type breaker struct {
+	o           *BreakerOptions
+	receiver    string
+	integration string
+	idx         int
+	now         func() time.Time
+
+	mtx      sync.Mutex
+	failures int
+	open     bool
+	probing  bool
+	openedAt time.Time
+	alert    *types.Alert
+}
+
+// allow returns an error if the breaker rejects an attempt. A nil breaker
+// allows all attempts.
+func (b *breaker) allow() error {
+	if b == nil {
+		return nil
+	}
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	if !b.open {
+		return nil
+	}
+	if !b.probing && b.now().Sub(b.openedAt) >= b.o.Cooldown {
+		b.probing = true
+		return nil
+	}
+	numCircuitRejections.WithLabelValues(b.receiver, b.integration).Inc()
+	return fmt.Errorf("circuit breaker open after %d consecutive failures", b.failures)
+}
+
+// success records a successful attempt and closes the breaker.
+func (b *breaker) success() {
+	if b == nil {
+		return
+	}
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	b.failures = 0
+	b.probing = false
+	if !b.open {
+		return
+	}
+	b.open = false
+	circuitsOpen.WithLabelValues(b.receiver, b.integration).Dec()
+	log.With("receiver", b.receiver).With("integration", b.name()).Info("Circuit breaker closed")
+
+	now := b.now()
+	b.alert.EndsAt = now
+	b.alert.UpdatedAt = now
+	b.putAlert()
+}
+
+// failure records a failed attempt, opening the breaker after too many
+// consecutive failures and reopening it after a failed probe.
+func (b *breaker) failure(err error) {
+	if b == nil {
+		return
+	}
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	b.failures++
+	if b.open {
+		if !b.probing {
+			return
+		}
+		// The failed probe starts another cooldown.
+		b.probing = false
+	} else if b.failures < b.o.Failures {
+		return
+	} else {
+		b.open = true
+		circuitsOpen.WithLabelValues(b.receiver, b.integration).Inc()
+	}
+	now := b.now()
+	b.openedAt = now
+
+	log.With("receiver", b.receiver).With("integration", b.name()).With("err", err).
+		Warnf("Circuit breaker open after %d consecutive failures, next attempt in %s", b.failures, b.o.Cooldown)
+
+	if b.alert == nil || !b.alert.EndsAt.IsZero() {
+		b.alert = &types.Alert{
+			Alert: model.Alert{
+				Labels: model.LabelSet{
+					model.AlertNameLabel: CircuitOpenAlertName,
+					"receiver":           model.LabelValue(b.receiver),
+					"integration":        model.LabelValue(b.name()),
+				},
+				StartsAt: now,
+			},
+		}
+	}
+	b.alert.Annotations = model.LabelSet{
+		"summary": model.LabelValue(fmt.Sprintf("Notifications via %s of receiver %s are suspended after %d consecutive failures", b.name(), b.receiver, b.failures)),
+		"error":   model.LabelValue(err.Error()),
+	}
+	b.alert.UpdatedAt = now
+	b.putAlert()
+}
+
+func (b *breaker) name() string {
+	return fmt.Sprintf("%s[%d]", b.integration, b.idx)
+}
+
+func (b *breaker) putAlert() {
+	if b.o.Alerts == nil {
+		return
+	}
+	// Copy the alert, as the stored one is modified on state changes.
+	a := *b.alert
+	if err := b.o.Alerts(&a); err != nil {
+		log.With("receiver", b.receiver).With("integration", b.name()).With("err", err).Error("Sending circuit breaker alert failed")
+	}
+}
@@ -0,0 +1,353 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/alertmanager/config"
+)
+
+// maxCaptureBody is the maximum number of bytes of a request or response
+// body that is captured.
+const maxCaptureBody = 64 << 10
+
+// redacted replaces secrets in captured requests and responses.
+const redacted = "<secret>"
+
+// Headers whose values are always redacted.
+var sensitiveHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"}
+
+// CapturedRequest is an HTTP request a notifier sent and the response it
+// received, with the secrets of the receiver redacted.
+type CapturedRequest struct {
+	Receiver    string    `json:"receiver"`
+	Integration string    `json:"integration"`
+	Index       int       `json:"index"`
+	Time        time.Time `json:"time"`
+	// Duration of the request in seconds.
+	Duration float64          `json:"duration"`
+	Request  CapturedMessage  `json:"request"`
+	Response *CapturedMessage `json:"response,omitempty"`
+	// Error is set if no response was received.
+	Error string `json:"error,omitempty"`
+}
+
+// CapturedMessage is a captured request or response.
+type CapturedMessage struct {
+	Method     string      `json:"method,omitempty"`
+	URL        string      `json:"url,omitempty"`
+	StatusCode int         `json:"statusCode,omitempty"`
+	Header     http.Header `json:"header"`
+	Body       string      `json:"body"`
+	// Truncated is set if the body exceeded the captured size.
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// Captures records the requests notifiers send to receivers, for which
+// capturing was enabled at runtime, in a ring buffer. It is meant for
+// diagnosing rejected notifications without capturing network traffic.
+// Captures are kept across configuration reloads.
+type Captures struct {
+	mtx     sync.RWMutex
+	enabled map[string]bool
+	buf     []*CapturedRequest
+	next    int
+	full    bool
+}
+
+// NewCaptures returns new Captures keeping the given number of requests.
+func NewCaptures(size int) *Captures {
+	if size <= 0 {
+		size = 1
+	}
+	return &Captures{
+		enabled: map[string]bool{},
+		buf:     make([]*CapturedRequest, size),
+	}
+}
+
+// SetEnabled enables or disables capturing the requests sent to the
+// receiver. Captured requests are kept when it is disabled.
+func (c *Captures) SetEnabled(receiver string, enabled bool) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if enabled {
+		c.enabled[receiver] = true
+	} else {
+		delete(c.enabled, receiver)
+	}
+}
+
+// Enabled returns whether requests sent to the receiver are captured. It is
+// safe to call on nil Captures.
+func (c *Captures) Enabled(receiver string) bool {
+	if c == nil {
+		return false
+	}
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+
+	return c.enabled[receiver]
+}
+
+// Requests returns the captured requests sent to the receiver, most recent
+// first. An empty receiver returns the requests sent to all receivers.
+func (c *Captures) Requests(receiver string) []*CapturedRequest {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+
+	res := []*CapturedRequest{}
+	n := c.next
+	if c.full {
+		n = len(c.buf)
+	}
+	for i := 1; i <= n; i++ {
+		r := c.buf[(c.next-i+len(c.buf))%len(c.buf)]
+		if receiver == "" || r.Receiver == receiver {
+			res = append(res, r)
+		}
+	}
+	return res
+}
+
+func (c *Captures) add(r *CapturedRequest) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.buf[c.next] = r
+	c.next = (c.next + 1) % len(c.buf)
+	if c.next == 0 {
+		c.full = true
+	}
+}
+
+// captureTarget identifies the integration whose requests are captured.
+type captureTarget struct {
+	captures    *Captures
+	receiver    string
+	integration string
+	idx         int
+	redact      redactor
+}
+
+// captureSetter is implemented by notifiers whose requests can be captured.
+type captureSetter interface {
+	setCapture(*captureTarget)
+}
+
+// start returns the capture of the request, or nil if capturing is disabled
+// for the receiver. The body of the request is buffered to be recorded.
+func (t *captureTarget) start(req *http.Request) (*CapturedRequest, error) {
+	if t == nil || !t.captures.Enabled(t.receiver) {
+		return nil, nil
+	}
+	var b []byte
+	if req.Body != nil {
+		var err error
+		if b, err = ioutil.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+		req.Body = ioutil.NopCloser(bytes.NewReader(b))
+		req.ContentLength = int64(len(b))
+	}
+	// Passwords of URLs are not necessarily configured as secrets.
+	u := *req.URL
+	u.User = nil
+
+	c := &CapturedRequest{
+		Receiver:    t.receiver,
+		Integration: t.integration,
+		Index:       t.idx,
+		Time:        time.Now(),
+		Request: CapturedMessage{
+			Method: req.Method,
+			URL:    t.redact.redact(u.String()),
+			Header: t.redact.header(req.Header),
+		},
+	}
+	c.Request.Body, c.Request.Truncated = t.redact.body(b)
+	return c, nil
+}
+
+// finish records the response to the captured request, or the error if
+// there was none, and adds the capture to the buffer. The body of the
+// response remains readable.
+func (t *captureTarget) finish(c *CapturedRequest, resp *http.Response, err error) {
+	if c == nil {
+		return
+	}
+	c.Duration = time.Since(c.Time).Seconds()
+	if err != nil {
+		c.Error = t.redact.redact(err.Error())
+		t.captures.add(c)
+		return
+	}
+	c.Response = &CapturedMessage{
+		StatusCode: resp.StatusCode,
+		Header:     t.redact.header(resp.Header),
+	}
+	// Read beyond the captured size to detect truncated bodies and to
+	// redact secrets crossing it.
+	b, err := ioutil.ReadAll(io.LimitReader(resp.Body, int64(maxCaptureBody+t.redact.maxLen()+1)))
+	if err != nil {
+		c.Error = t.redact.redact(err.Error())
+	}
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(b), resp.Body), resp.Body}
+
+	c.Response.Body, c.Response.Truncated = t.redact.body(b)
+	t.captures.add(c)
+}
+
+// redactor replaces secrets in captured text. Longer secrets are replaced
+// first so that secrets containing others are fully redacted.
+type redactor []string
+
+// newRedactor returns a redactor of the secrets of the receiver's
+// configuration, including the forms they take in URLs and JSON strings.
+func newRedactor(rc *config.Receiver) redactor {
+	seen := map[string]bool{}
+	var r redactor
+	for _, s := range secrets(reflect.ValueOf(rc)) {
+		forms := []string{s, url.QueryEscape(s), url.PathEscape(s)}
+		if b, err := json.Marshal(s); err == nil {
+			forms = append(forms, string(b[1:len(b)-1]))
+		}
+		for _, f := range forms {
+			if f != "" && !seen[f] {
+				seen[f] = true
+				r = append(r, f)
+			}
+		}
+	}
+	sort.Slice(r, func(i, j int) bool { return len(r[i]) > len(r[j]) })
+	return r
+}
+
+var secretType = reflect.TypeOf(config.Secret(""))
+
+// secrets returns the values of all secrets within v.
+func secrets(v reflect.Value) []string {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return secrets(v.Elem())
+	case reflect.Struct:
+		var res []string
+		for i := 0; i < v.NumField(); i++ {
+			res = append(res, secrets(v.Field(i))...)
+		}
+		return res
+	case reflect.Slice, reflect.Array:
+		var res []string
+		for i := 0; i < v.Len(); i++ {
+			res = append(res, secrets(v.Index(i))...)
+		}
+		return res
+	case reflect.Map:
+		var res []string
+		for _, k := range v.MapKeys() {
+			res = append(res, secrets(v.MapIndex(k))...)
+		}
+		return res
+	case reflect.String:
+		if v.String() == "" {
+			return nil
+		}
+		if v.Type() == secretType {
+			return []string{v.String()}
+		}
+		// Passwords in URLs are secrets, too.
+		if strings.Contains(v.String(), "@") {
+			if u, err := url.Parse(v.String()); err == nil && u.User != nil {
+				if pw, ok := u.User.Password(); ok && pw != "" {
+					return []string{pw}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// maxLen returns the length of the longest secret.
+func (r redactor) maxLen() int {
+	if len(r) == 0 {
+		return 0
+	}
+	return len(r[0])
+}
+
+func (r redactor) redact(s string) string {
+	for _, secret := range r {
+		s = strings.Replace(s, secret, redacted, -1)
+	}
+	return s
+}
+
+// header returns a redacted copy of the header.
+func (r redactor) header(h http.Header) http.Header {
+	res := make(http.Header, len(h))
+	for k, vs := range h {
+		for _, v := range vs {
+			res.Add(k, r.redact(v))
+		}
+	}
+	for _, k := range sensitiveHeaders {
+		if _, ok := res[k]; ok {
+			res.Set(k, redacted)
+		}
+	}
+	return res
+}
+
+// body returns the redacted body, truncated to the captured size. A body
+// cut off at the captured size is cut before any secret crossing it, so
+// that no part of the secret is leaked.
+func (r redactor) body(b []byte) (string, bool) {
+	s := string(b)
+	if len(s) <= maxCaptureBody {
+		return r.redact(s), false
+	}
+	cut := maxCaptureBody
+	for changed := true; changed; {
+		changed = false
+		for _, secret := range r {
+			from := cut - len(secret) + 1
+			if from < 0 {
+				from = 0
+			}
+			if i := strings.Index(s[from:], secret); i >= 0 && from+i < cut {
+				cut, changed = from+i, true
+			}
+		}
+	}
+	return r.redact(s[:cut]), true
+}
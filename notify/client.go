@@ -0,0 +1,118 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"golang.org/x/net/context"
+	"golang.org/x/net/context/ctxhttp"
+
+	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/tracing"
+)
+
+// newHTTPClient returns an HTTP client pooling connections as configured.
+// A nil configuration results in the default pooling.
+func newHTTPClient(c *config.HTTPClientConfig) *http.Client {
+	if c == nil {
+		c = &config.DefaultHTTPClientConfig
+	}
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: time.Duration(c.KeepAlive),
+	}
+	tr := &http.Transport{
+		Proxy:               http.ProxyFromEnvironment,
+		DialContext:         dialer.DialContext,
+		TLSHandshakeTimeout: 10 * time.Second,
+		DisableKeepAlives:   c.DisableKeepAlives,
+		IdleConnTimeout:     time.Duration(c.IdleConnTimeout),
+		MaxIdleConns:        c.MaxIdleConns,
+		MaxIdleConnsPerHost: c.MaxIdleConnsPerHost,
+		MaxConnsPerHost:     c.MaxConnsPerHost,
+		ForceAttemptHTTP2:   !c.DisableHTTP2,
+	}
+	if c.DisableHTTP2 {
+		// A non-nil, empty map prevents the transport from upgrading TLS
+		// connections to HTTP/2.
+		tr.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	}
+	return &http.Client{Transport: tr}
+}
+
+// clientSetter is implemented by notifiers sending HTTP requests.
+type clientSetter interface {
+	setClient(*http.Client)
+}
+
+// pooledClient is embedded by notifiers sending HTTP requests, so they can
+// share the client, and with it the open connections, of their receiver.
+type pooledClient struct {
+	c       *http.Client
+	capture *captureTarget
+}
+
+func (p *pooledClient) setClient(c *http.Client) {
+	p.c = c
+}
+
+func (p *pooledClient) setCapture(t *captureTarget) {
+	p.capture = t
+}
+
+// client returns the shared client or, if none is set, the default client.
+func (p *pooledClient) client() *http.Client {
+	if p.c == nil {
+		return http.DefaultClient
+	}
+	return p.c
+}
+
+// post sends a POST request with the client, recording it in a span whose
+// trace context is propagated to the receiving service. The request is
+// captured if capturing is enabled for the receiver.
+func (p *pooledClient) post(ctx context.Context, u, bodyType string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequest("POST", u, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", bodyType)
+
+	ctx, span := tracing.Start(ctx, "HTTP POST", tracing.KindClient)
+	defer span.End()
+	// The query may hold credentials, e.g. of Pushover.
+	if pu, err := url.Parse(u); err == nil {
+		span.SetAttribute("http.url", pu.Scheme+"://"+pu.Host+pu.Path)
+	}
+	tracing.Inject(ctx, req.Header)
+
+	capture, err := p.capture.start(req)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := ctxhttp.Do(ctx, p.client(), req)
+	p.capture.finish(capture, resp, err)
+	if err != nil {
+		span.SetError(err)
+		return nil, err
+	}
+	span.SetAttribute("http.status_code", resp.StatusCode)
+	return resp, nil
+}
@@ -23,7 +23,6 @@ import (
 	"io/ioutil"
 	"mime"
 	"net"
-	"net/http"
 	"net/mail"
 	"net/smtp"
 	"net/url"
@@ -33,7 +32,6 @@ import (
 	"github.com/prometheus/common/log"
 	"github.com/prometheus/common/model"
 	"golang.org/x/net/context"
-	"golang.org/x/net/context/ctxhttp"
 
 	"github.com/prometheus/alertmanager/config"
 	"github.com/prometheus/alertmanager/template"
@@ -42,6 +40,7 @@ import (
 
 type notifierConfig interface {
 	SendResolved() bool
+	MaxConcurrentNotifications() int
 }
 
 // A Notifier notifies about alerts under constraints of the given context.
@@ -87,7 +86,11 @@ func (i *Integration) Notify(ctx context.Context, alerts ...*types.Alert) (bool,
 func BuildReceiverIntegrations(nc *config.Receiver, tmpl *template.Template) []Integration {
 	var (
 		integrations []Integration
+		client       = newHTTPClient(nc.HTTPClient)
 		add          = func(name string, i int, n Notifier, nc notifierConfig) {
+			if cs, ok := n.(clientSetter); ok {
+				cs.setClient(client)
+			}
 			integrations = append(integrations, Integration{
 				notifier: n,
 				conf:     nc,
@@ -139,6 +142,8 @@ type Webhook struct {
 	// The URL to which notifications are sent.
 	URL  string
 	tmpl *template.Template
+
+	pooledClient
 }
 
 // NewWebhook returns a new Webhook.
@@ -175,7 +180,7 @@ func (w *Webhook) Notify(ctx context.Context, alerts ...*types.Alert) (bool, err
 		return false, err
 	}
 
-	resp, err := ctxhttp.Post(ctx, http.DefaultClient, w.URL, contentTypeJSON, &buf)
+	resp, err := w.post(ctx, w.URL, contentTypeJSON, &buf)
 	if err != nil {
 		return true, err
 	}
@@ -329,7 +334,7 @@ func (n *Email) Notify(ctx context.Context, as ...*types.Alert) (bool, error) {
 	for header, t := range n.conf.Headers {
 		value, err := n.tmpl.ExecuteTextString(t, data)
 		if err != nil {
-			return false, fmt.Errorf("executing %q header template: %s", header, err)
+			return false, templateError{fmt.Errorf("executing %q header template: %s", header, err)}
 		}
 		fmt.Fprintf(wc, "%s: %s\r\n", header, mime.QEncoding.Encode("utf-8", value))
 	}
@@ -344,7 +349,7 @@ func (n *Email) Notify(ctx context.Context, as ...*types.Alert) (bool, error) {
 	// TODO(fabxc): do a multipart write that considers the plain template.
 	body, err := n.tmpl.ExecuteHTMLString(n.conf.HTML, data)
 	if err != nil {
-		return false, fmt.Errorf("executing email html template: %s", err)
+		return false, templateError{fmt.Errorf("executing email html template: %s", err)}
 	}
 	_, err = io.WriteString(wc, body)
 	if err != nil {
@@ -358,6 +363,8 @@ func (n *Email) Notify(ctx context.Context, as ...*types.Alert) (bool, error) {
 type PagerDuty struct {
 	conf *config.PagerdutyConfig
 	tmpl *template.Template
+
+	pooledClient
 }
 
 // NewPagerDuty returns a new PagerDuty notifier.
@@ -427,7 +434,7 @@ func (n *PagerDuty) Notify(ctx context.Context, as ...*types.Alert) (bool, error
 		return false, err
 	}
 
-	resp, err := ctxhttp.Post(ctx, http.DefaultClient, n.conf.URL, contentTypeJSON, &buf)
+	resp, err := n.post(ctx, n.conf.URL, contentTypeJSON, &buf)
 	if err != nil {
 		return true, err
 	}
@@ -451,6 +458,8 @@ func (n *PagerDuty) retry(statusCode int) (bool, error) {
 type Slack struct {
 	conf *config.SlackConfig
 	tmpl *template.Template
+
+	pooledClient
 }
 
 // NewSlack returns a new Slack notification handler.
@@ -522,7 +531,7 @@ func (n *Slack) Notify(ctx context.Context, as ...*types.Alert) (bool, error) {
 		return false, err
 	}
 
-	resp, err := ctxhttp.Post(ctx, http.DefaultClient, string(n.conf.APIURL), contentTypeJSON, &buf)
+	resp, err := n.post(ctx, string(n.conf.APIURL), contentTypeJSON, &buf)
 	if err != nil {
 		return true, err
 	}
@@ -546,6 +555,8 @@ func (n *Slack) retry(statusCode int) (bool, error) {
 type Hipchat struct {
 	conf *config.HipchatConfig
 	tmpl *template.Template
+
+	pooledClient
 }
 
 // NewHipchat returns a new Hipchat notification handler.
@@ -597,7 +608,7 @@ func (n *Hipchat) Notify(ctx context.Context, as ...*types.Alert) (bool, error)
 		return false, err
 	}
 
-	resp, err := ctxhttp.Post(ctx, http.DefaultClient, url, contentTypeJSON, &buf)
+	resp, err := n.post(ctx, url, contentTypeJSON, &buf)
 	if err != nil {
 		return true, err
 	}
@@ -622,6 +633,8 @@ func (n *Hipchat) retry(statusCode int) (bool, error) {
 type OpsGenie struct {
 	conf *config.OpsGenieConfig
 	tmpl *template.Template
+
+	pooledClient
 }
 
 // NewOpsGenie returns a new OpsGenie notifier.
@@ -709,7 +722,7 @@ func (n *OpsGenie) Notify(ctx context.Context, as ...*types.Alert) (bool, error)
 		return false, err
 	}
 
-	resp, err := ctxhttp.Post(ctx, http.DefaultClient, apiURL, contentTypeJSON, &buf)
+	resp, err := n.post(ctx, apiURL, contentTypeJSON, &buf)
 	if err != nil {
 		return true, err
 	}
@@ -747,6 +760,8 @@ func (n *OpsGenie) Notify(ctx context.Context, as ...*types.Alert) (bool, error)
 type VictorOps struct {
 	conf *config.VictorOpsConfig
 	tmpl *template.Template
+
+	pooledClient
 }
 
 // NewVictorOps returns a new VictorOps notifier.
@@ -820,7 +835,7 @@ func (n *VictorOps) Notify(ctx context.Context, as ...*types.Alert) (bool, error
 		return false, err
 	}
 
-	resp, err := ctxhttp.Post(ctx, http.DefaultClient, apiURL, contentTypeJSON, &buf)
+	resp, err := n.post(ctx, apiURL, contentTypeJSON, &buf)
 	if err != nil {
 		return true, err
 	}
@@ -854,6 +869,8 @@ func (n *VictorOps) Notify(ctx context.Context, as ...*types.Alert) (bool, error
 type Pushover struct {
 	conf *config.PushoverConfig
 	tmpl *template.Template
+
+	pooledClient
 }
 
 // NewPushover returns a new Pushover notifier.
@@ -910,7 +927,7 @@ func (n *Pushover) Notify(ctx context.Context, as ...*types.Alert) (bool, error)
 	u.RawQuery = parameters.Encode()
 	log.With("incident", key).Debugf("Pushover URL = %q", u.String())
 
-	resp, err := ctxhttp.Post(ctx, http.DefaultClient, u.String(), "text/plain", nil)
+	resp, err := n.post(ctx, u.String(), "text/plain", nil)
 	if err != nil {
 		return true, err
 	}
@@ -940,6 +957,9 @@ func tmplText(tmpl *template.Template, data *template.Data, err *error) func(str
 			return
 		}
 		s, *err = tmpl.ExecuteTextString(name, data)
+		if *err != nil {
+			*err = templateError{*err}
+		}
 		return s
 	}
 }
@@ -950,10 +970,22 @@ func tmplHTML(tmpl *template.Template, data *template.Data, err *error) func(str
 			return
 		}
 		s, *err = tmpl.ExecuteHTMLString(name, data)
+		if *err != nil {
+			*err = templateError{*err}
+		}
 		return s
 	}
 }
 
+// templateError is the error of executing a notification template.
+type templateError struct {
+	err error
+}
+
+func (e templateError) Error() string {
+	return e.err.Error()
+}
+
 type loginAuth struct {
 	username, password string
 }
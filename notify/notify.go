@@ -16,8 +16,17 @@ package notify
 import (
 	"bytes"
 	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/binary"
+	"encoding/json"
 	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -28,12 +37,20 @@ import (
 	"github.com/prometheus/common/model"
 	"golang.org/x/net/context"
 
+	"github.com/prometheus/alertmanager/ack"
+	"github.com/prometheus/alertmanager/assignment"
+	"github.com/prometheus/alertmanager/comment"
 	"github.com/prometheus/alertmanager/config"
-	"github.com/prometheus/alertmanager/inhibit"
+	"github.com/prometheus/alertmanager/events"
+	"github.com/prometheus/alertmanager/history"
 	"github.com/prometheus/alertmanager/nflog"
 	"github.com/prometheus/alertmanager/nflog/nflogpb"
+	"github.com/prometheus/alertmanager/pause"
 	"github.com/prometheus/alertmanager/silence"
+	"github.com/prometheus/alertmanager/silence/silencepb"
+	"github.com/prometheus/alertmanager/snooze"
 	"github.com/prometheus/alertmanager/template"
+	"github.com/prometheus/alertmanager/tracing"
 	"github.com/prometheus/alertmanager/types"
 )
 
@@ -49,11 +66,75 @@ var (
 		Name:      "notifications_failed_total",
 		Help:      "The total number of failed notifications.",
 	}, []string{"integration"})
+
+	numDryRunNotifications = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "alertmanager",
+		Name:      "notifications_dry_run_total",
+		Help:      "The total number of notifications that were only logged by dry-run receivers.",
+	}, []string{"integration"})
+
+	inFlightNotifications = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "alertmanager",
+		Name:      "notifications_in_flight",
+		Help:      "The number of group notifications currently sent to receivers or integrations with a concurrency limit.",
+	}, []string{"receiver", "integration"})
+
+	waitingNotifications = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "alertmanager",
+		Name:      "notifications_waiting",
+		Help:      "The number of group notifications waiting for the concurrency limit of their receiver or integration.",
+	}, []string{"receiver", "integration"})
+
+	numNotificationAttempts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "alertmanager",
+		Name:      "receiver_notification_attempts_total",
+		Help:      "The total number of attempts to send a notification, including retries.",
+	}, []string{"receiver", "integration"})
+
+	numNotificationFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "alertmanager",
+		Name:      "receiver_notification_failures_total",
+		Help:      "The total number of failed attempts to send a notification by reason.",
+	}, []string{"receiver", "integration", "reason"})
+
+	numNotificationRetries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "alertmanager",
+		Name:      "receiver_notification_retries_total",
+		Help:      "The total number of attempts to send a notification that retried a failed attempt.",
+	}, []string{"receiver", "integration"})
+
+	notificationLatency = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "alertmanager",
+		Name:      "receiver_notification_latency_seconds",
+		Help:      "The time from the first attempt to send a notification until it was sent, including retries.",
+		Buckets:   prometheus.ExponentialBuckets(0.05, 2, 12),
+	}, []string{"receiver", "integration"})
+
+	circuitsOpen = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "alertmanager",
+		Name:      "receiver_circuits_open",
+		Help:      "The number of integrations whose circuit breaker is open.",
+	}, []string{"receiver", "integration"})
+
+	numCircuitRejections = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "alertmanager",
+		Name:      "receiver_circuit_rejections_total",
+		Help:      "The total number of notifications not attempted because the circuit breaker of the integration was open.",
+	}, []string{"receiver", "integration"})
 )
 
 func init() {
 	prometheus.Register(numNotifications)
 	prometheus.Register(numFailedNotifications)
+	prometheus.Register(numDryRunNotifications)
+	prometheus.Register(inFlightNotifications)
+	prometheus.Register(waitingNotifications)
+	prometheus.Register(numNotificationAttempts)
+	prometheus.Register(numNotificationFailures)
+	prometheus.Register(numNotificationRetries)
+	prometheus.Register(notificationLatency)
+	prometheus.Register(circuitsOpen)
+	prometheus.Register(numCircuitRejections)
 }
 
 // MinTimeout is the minimum timeout that is set for the context of a call
@@ -71,6 +152,8 @@ const (
 	keyGroupKey
 	keyNotificationHash
 	keyNow
+	keyEscalation
+	keyWait
 )
 
 // WithReceiverName populates a context with a receiver name.
@@ -110,6 +193,32 @@ func RepeatInterval(ctx context.Context) (time.Duration, bool) {
 	return v, ok
 }
 
+// WithWait populates a context with the time to wait for peers before
+// sending a notification, overriding the wait of the WaitStage.
+func WithWait(ctx context.Context, d time.Duration) context.Context {
+	return context.WithValue(ctx, keyWait, d)
+}
+
+// Wait extracts the time to wait for peers from the context. Iff none
+// exists, the second argument is false.
+func Wait(ctx context.Context) (time.Duration, bool) {
+	v, ok := ctx.Value(keyWait).(time.Duration)
+	return v, ok
+}
+
+// WithEscalation marks a context as belonging to a notification sent to an
+// escalation receiver.
+func WithEscalation(ctx context.Context) context.Context {
+	return context.WithValue(ctx, keyEscalation, true)
+}
+
+// Escalation returns true if the context belongs to a notification sent to
+// an escalation receiver.
+func Escalation(ctx context.Context) bool {
+	v, _ := ctx.Value(keyEscalation).(bool)
+	return v
+}
+
 // ReceiverName extracts a receiver name from the context. Iff none exists, the
 // second argument is false.
 func ReceiverName(ctx context.Context) (string, bool) {
@@ -179,42 +288,264 @@ func BuildPipeline(
 	confs []*config.Receiver,
 	tmpl *template.Template,
 	wait func() time.Duration,
-	inhibitor *inhibit.Inhibitor,
+	inhibitor types.Muter,
 	silences *silence.Silences,
+	acks *ack.Acks,
+	comments *comment.Comments,
+	assignments *assignment.Assignments,
+	snoozes *snooze.Snoozes,
+	pauses *pause.Pauses,
 	notificationLog nflog.Log,
+	hist *history.History,
 	marker types.Marker,
+	bus *events.Bus,
+	breakers *Breakers,
+	queue *RetryQueue,
+	captures *Captures,
 ) RoutingStage {
 	rs := RoutingStage{}
 
+	ps := NewPauseStage(pauses)
 	is := NewInhibitStage(inhibitor, marker)
 	ss := NewSilenceStage(silences, marker)
-
+	sns := NewSnoozeStage(snoozes)
+	as := NewAckStage(acks)
+	cs := NewCommentStage(comments)
+	ags := NewAssignmentStage(assignments)
+	es := EscalationStage{}
+
+	filters := MultiStage{
+		ps,
+		NewTracedStage("inhibit", is, nil),
+		NewTracedStage("silence", ss, nil),
+		sns, as, es, cs, ags,
+	}
 	for _, rc := range confs {
-		rs[rc.Name] = MultiStage{is, ss, createStage(rc, tmpl, wait, notificationLog)}
+		var send Stage = createStage(rc, tmpl, wait, notificationLog, hist, bus, breakers, queue, captures)
+		if rc.MaxConcurrentNotifications > 0 {
+			send = NewConcurrencyStage(rc.Name, rc.MaxConcurrentNotifications, send)
+		}
+		queue.filter(rc.Name, filters)
+		rs[rc.Name] = MultiStage{filters, send}
 	}
 	return rs
 }
 
+// BuildReceiverStage builds the notification stage for a single receiver.
+// Unlike the stages returned by BuildPipeline, it does not filter out
+// inhibited or silenced alerts.
+func BuildReceiverStage(
+	rc *config.Receiver,
+	tmpl *template.Template,
+	wait func() time.Duration,
+	notificationLog nflog.Log,
+) Stage {
+	return createStage(rc, tmpl, wait, notificationLog, nil, nil, nil, nil, nil)
+}
+
+// IntegrationResult is the outcome of a test notification sent via an
+// integration of a receiver.
+type IntegrationResult struct {
+	Integration string
+	Index       int
+	Error       error
+}
+
+// SendTestNotification sends the alerts via every integration of the receiver
+// and returns the outcome per integration in configuration order. Failed
+// attempts are retried until the context is done. Unlike notifications sent
+// by the pipeline, test notifications are neither deduplicated nor recorded
+// in the notification log.
+func SendTestNotification(ctx context.Context, rc *config.Receiver, tmpl *template.Template, alerts ...*types.Alert) []IntegrationResult {
+	lset := model.LabelSet{}
+	for _, a := range alerts {
+		for ln, lv := range a.Labels {
+			lset[ln] = lv
+		}
+	}
+	ctx = WithReceiverName(ctx, rc.Name)
+	ctx = WithGroupLabels(ctx, lset)
+	ctx = WithGroupKey(ctx, lset.Fingerprint())
+	ctx = WithNow(ctx, time.Now())
+
+	var (
+		integrations = BuildReceiverIntegrations(rc, tmpl)
+		res          = make([]IntegrationResult, len(integrations))
+		wg           sync.WaitGroup
+	)
+	for k, i := range integrations {
+		var s Stage = NewRetryStage(i)
+		if rc.DryRun {
+			s = NewDryRunStage(i, tmpl)
+		}
+		res[k] = IntegrationResult{Integration: i.name, Index: i.idx}
+
+		wg.Add(1)
+		go func(k int, s Stage) {
+			defer wg.Done()
+			_, _, res[k].Error = s.Exec(ctx, alerts...)
+		}(k, s)
+	}
+	wg.Wait()
+
+	return res
+}
+
 // createStage creates a pipeline of stages for a receiver.
-func createStage(rc *config.Receiver, tmpl *template.Template, wait func() time.Duration, notificationLog nflog.Log) Stage {
-	var fs FanoutStage
+func createStage(rc *config.Receiver, tmpl *template.Template, wait func() time.Duration, notificationLog nflog.Log, hist *history.History, bus *events.Bus, breakers *Breakers, queue *RetryQueue, captures *Captures) Stage {
+	var (
+		fs     FanoutStage
+		redact redactor
+	)
+	if captures != nil {
+		redact = newRedactor(rc)
+	}
 	for _, i := range BuildReceiverIntegrations(rc, tmpl) {
+		if cs, ok := i.notifier.(captureSetter); ok && captures != nil {
+			cs.setCapture(&captureTarget{
+				captures:    captures,
+				receiver:    rc.Name,
+				integration: i.name,
+				idx:         i.idx,
+				redact:      redact,
+			})
+		}
 		recv := &nflogpb.Receiver{
 			GroupName:   rc.Name,
 			Integration: i.name,
 			Idx:         uint32(i.idx),
 		}
-		var s MultiStage
-		s = append(s, NewWaitStage(wait))
-		s = append(s, NewDedupStage(notificationLog, recv))
-		s = append(s, NewRetryStage(i))
-		s = append(s, NewSetNotifiesStage(notificationLog, recv))
+		ws := NewTracedStage("wait", NewWaitStage(wait), nil)
+		s := MultiStage{ws}
+		var dedup Stage = NewTracedStage("dedup", NewDedupStage(notificationLog, recv), nil)
+
+		rs := NewRetryStage(i)
+		rs.breaker = breakers.get(rc.Name, i)
+
+		var send Stage = rs
+		if rc.DryRun {
+			send = NewDryRunStage(i, tmpl)
+		} else if hist != nil {
+			send = NewHistoryStage(hist, rc.Name, i.name, send)
+		}
+		if n := i.conf.MaxConcurrentNotifications(); n > 0 {
+			send = NewIntegrationConcurrencyStage(rc.Name, i.name, n, send)
+		}
+		var notifies Stage = NewSetNotifiesStage(notificationLog, recv)
+		if bus != nil {
+			notifies = MultiStage{notifies, NewEventStage(bus, rc.Name, i.name)}
+		}
 
-		fs = append(fs, s)
+		switch {
+		case rc.DigestInterval > 0:
+			s = append(s, dedup, NewDigestStage(rc.Name, time.Duration(rc.DigestInterval), send, notifies))
+		case rc.DryRun:
+			s = append(s, dedup, send, notifies)
+		default:
+			// Failed notifications are queued for retries. They are
+			// filtered, waited for and deduplicated again when retried, so
+			// they are dropped once they are muted or a later notification
+			// of their group succeeded on any peer.
+			s = append(s, queue.wrap(rc.Name, i, ws, MultiStage{dedup, send, notifies}))
+		}
+
+		fs = append(fs, NewTracedStage("integration", s, map[string]interface{}{
+			"integration": fmt.Sprintf("%s[%d]", i.name, i.idx),
+		}))
 	}
 	return fs
 }
 
+// HistoryStage records the outcome of its inner stage, which sends
+// notifications, in the notification history.
+type HistoryStage struct {
+	history     *history.History
+	receiver    string
+	integration string
+	stage       Stage
+}
+
+// NewHistoryStage returns a new HistoryStage recording notifications sent by
+// the stage to the integration of the receiver.
+func NewHistoryStage(h *history.History, receiver, integration string, s Stage) *HistoryStage {
+	return &HistoryStage{
+		history:     h,
+		receiver:    receiver,
+		integration: integration,
+		stage:       s,
+	}
+}
+
+// Exec implements the Stage interface.
+func (hs *HistoryStage) Exec(ctx context.Context, alerts ...*types.Alert) (context.Context, []*types.Alert, error) {
+	ctx, res, err := hs.stage.Exec(ctx, alerts...)
+
+	gkey, _ := GroupKey(ctx)
+	fps := make([]model.Fingerprint, 0, len(alerts))
+	for _, a := range alerts {
+		fps = append(fps, a.Fingerprint())
+	}
+	var reason string
+	if err != nil {
+		reason = FailureReason(err)
+	}
+	hs.history.Record(hs.receiver, hs.integration, uint64(gkey), fps, err, reason)
+
+	return ctx, res, err
+}
+
+// ConcurrencyStage limits the number of concurrent executions of its inner
+// stage. Further executions wait until a slot is freed or their context is
+// canceled.
+type ConcurrencyStage struct {
+	receiver    string
+	integration string
+	slots       chan struct{}
+	stage       Stage
+}
+
+// NewConcurrencyStage returns a new ConcurrencyStage executing the stage for
+// the receiver at most limit times at once.
+func NewConcurrencyStage(receiver string, limit int, s Stage) *ConcurrencyStage {
+	return NewIntegrationConcurrencyStage(receiver, "", limit, s)
+}
+
+// NewIntegrationConcurrencyStage returns a new ConcurrencyStage executing the
+// stage for an integration of the receiver at most limit times at once.
+func NewIntegrationConcurrencyStage(receiver, integration string, limit int, s Stage) *ConcurrencyStage {
+	return &ConcurrencyStage{
+		receiver:    receiver,
+		integration: integration,
+		slots:       make(chan struct{}, limit),
+		stage:       s,
+	}
+}
+
+// Exec implements the Stage interface.
+func (cs *ConcurrencyStage) Exec(ctx context.Context, alerts ...*types.Alert) (context.Context, []*types.Alert, error) {
+	select {
+	case cs.slots <- struct{}{}:
+	default:
+		waiting := waitingNotifications.WithLabelValues(cs.receiver, cs.integration)
+		waiting.Inc()
+		select {
+		case cs.slots <- struct{}{}:
+			waiting.Dec()
+		case <-ctx.Done():
+			waiting.Dec()
+			return ctx, nil, ctx.Err()
+		}
+	}
+	inFlight := inFlightNotifications.WithLabelValues(cs.receiver, cs.integration)
+	inFlight.Inc()
+	defer func() {
+		inFlight.Dec()
+		<-cs.slots
+	}()
+
+	return cs.stage.Exec(ctx, alerts...)
+}
+
 // RoutingStage executes the inner stages based on the receiver specified in
 // the context.
 type RoutingStage map[string]Stage
@@ -330,6 +661,12 @@ func NewSilenceStage(s *silence.Silences, mk types.Marker) *SilenceStage {
 
 // Exec implements the Stage interface.
 func (n *SilenceStage) Exec(ctx context.Context, alerts ...*types.Alert) (context.Context, []*types.Alert, error) {
+	// Silences may be restricted to the aggregation group being notified.
+	var groupKey string
+	if gkey, ok := GroupKey(ctx); ok {
+		groupKey = strconv.FormatUint(uint64(gkey), 10)
+	}
+
 	var filtered []*types.Alert
 	for _, a := range alerts {
 		_, ok := n.marker.Silenced(a.Fingerprint())
@@ -337,25 +674,180 @@ func (n *SilenceStage) Exec(ctx context.Context, alerts ...*types.Alert) (contex
 		// Do not send the alert if the silencer mutes it.
 		sils, err := n.silences.Query(
 			silence.QState(silence.StateActive),
-			silence.QMatches(a.Labels),
+			silence.QGroupKey(groupKey),
+			silence.QMatchesAlert(a.Labels, a.Annotations),
 		)
 		if err != nil {
 			log.Errorf("Querying silences failed: %s", err)
 		}
-		if len(sils) == 0 {
+		var hard, soft []*silencepb.Silence
+		for _, s := range sils {
+			if s.Soft {
+				soft = append(soft, s)
+			} else {
+				hard = append(hard, s)
+			}
+		}
+		if len(hard) == 0 {
 			// TODO(fabxc): increment muted alerts counter.
-			filtered = append(filtered, a)
 			n.marker.SetSilenced(a.Labels.Fingerprint())
 			// Store whether a previously silenced alert is firing again.
 			a.WasSilenced = ok
+			if len(soft) > 0 {
+				a = softSilenced(a)
+			}
+			filtered = append(filtered, a)
 		} else {
-			n.marker.SetSilenced(a.Labels.Fingerprint(), sils[0].Id)
+			n.marker.SetSilenced(a.Labels.Fingerprint(), hard[0].Id)
 		}
 	}
 
 	return ctx, filtered, nil
 }
 
+// softSilenced returns a copy of the alert that is marked as matched by a
+// soft silence. Its labels are left unchanged so that later stages, such as
+// snoozes and deduplication, still identify the alert.
+func softSilenced(a *types.Alert) *types.Alert {
+	c := *a
+	c.SoftSilenced = true
+	return &c
+}
+
+// PauseStage drops all alerts while notifications of their group to the
+// receiver are paused.
+type PauseStage struct {
+	pauses *pause.Pauses
+}
+
+// NewPauseStage returns a new PauseStage. The Pauses may be nil.
+func NewPauseStage(p *pause.Pauses) *PauseStage {
+	return &PauseStage{pauses: p}
+}
+
+// Exec implements the Stage interface.
+func (n *PauseStage) Exec(ctx context.Context, alerts ...*types.Alert) (context.Context, []*types.Alert, error) {
+	receiver, ok := ReceiverName(ctx)
+	if !ok {
+		return ctx, nil, fmt.Errorf("receiver missing")
+	}
+	gkey, ok := GroupKey(ctx)
+	if !ok {
+		return ctx, nil, fmt.Errorf("group key missing")
+	}
+	if p := n.pauses.Paused(receiver, uint64(gkey)); p != nil {
+		log.With("receiver", receiver).With("groupKey", gkey).Debugf("Notifications paused until %s", p.EndsAt)
+		return ctx, nil, nil
+	}
+	return ctx, alerts, nil
+}
+
+// SnoozeStage filters out snoozed alerts.
+type SnoozeStage struct {
+	snoozes *snooze.Snoozes
+}
+
+// NewSnoozeStage returns a new SnoozeStage. The Snoozes may be nil.
+func NewSnoozeStage(s *snooze.Snoozes) *SnoozeStage {
+	return &SnoozeStage{snoozes: s}
+}
+
+// Exec implements the Stage interface.
+func (n *SnoozeStage) Exec(ctx context.Context, alerts ...*types.Alert) (context.Context, []*types.Alert, error) {
+	res := make([]*types.Alert, 0, len(alerts))
+	for _, a := range alerts {
+		if n.snoozes.Snoozed(a.Fingerprint()) == nil {
+			res = append(res, a)
+		}
+	}
+	return ctx, res, nil
+}
+
+// AckStage marks acknowledged alerts. Repeated notifications are not sent
+// while all firing alerts of a group are acknowledged.
+type AckStage struct {
+	acks *ack.Acks
+}
+
+// NewAckStage returns a new AckStage. The Acks may be nil.
+func NewAckStage(a *ack.Acks) *AckStage {
+	return &AckStage{acks: a}
+}
+
+// Exec implements the Stage interface.
+func (n *AckStage) Exec(ctx context.Context, alerts ...*types.Alert) (context.Context, []*types.Alert, error) {
+	res := make([]*types.Alert, 0, len(alerts))
+	for _, a := range alerts {
+		if n.acks.Acked(a.Labels, a.StartsAt) != nil {
+			c := *a
+			c.Acked = true
+			a = &c
+		}
+		res = append(res, a)
+	}
+	return ctx, res, nil
+}
+
+// EscalationStage stops the notification of escalation receivers about
+// groups whose firing alerts have all been acknowledged.
+type EscalationStage struct{}
+
+// Exec implements the Stage interface.
+func (EscalationStage) Exec(ctx context.Context, alerts ...*types.Alert) (context.Context, []*types.Alert, error) {
+	if Escalation(ctx) && allFiringAcked(alerts) {
+		return ctx, nil, nil
+	}
+	return ctx, alerts, nil
+}
+
+// CommentStage attaches the comments about alerts to them.
+type CommentStage struct {
+	comments *comment.Comments
+}
+
+// NewCommentStage returns a new CommentStage. The Comments may be nil.
+func NewCommentStage(c *comment.Comments) *CommentStage {
+	return &CommentStage{comments: c}
+}
+
+// Exec implements the Stage interface.
+func (n *CommentStage) Exec(ctx context.Context, alerts ...*types.Alert) (context.Context, []*types.Alert, error) {
+	res := make([]*types.Alert, 0, len(alerts))
+	for _, a := range alerts {
+		if cms := n.comments.Query(a.Labels, a.StartsAt); len(cms) > 0 {
+			c := *a
+			c.Comments = cms
+			a = &c
+		}
+		res = append(res, a)
+	}
+	return ctx, res, nil
+}
+
+// AssignmentStage sets who alerts are assigned to.
+type AssignmentStage struct {
+	assignments *assignment.Assignments
+}
+
+// NewAssignmentStage returns a new AssignmentStage. The Assignments may be nil.
+func NewAssignmentStage(a *assignment.Assignments) *AssignmentStage {
+	return &AssignmentStage{assignments: a}
+}
+
+// Exec implements the Stage interface.
+func (n *AssignmentStage) Exec(ctx context.Context, alerts ...*types.Alert) (context.Context, []*types.Alert, error) {
+	res := make([]*types.Alert, 0, len(alerts))
+	for _, a := range alerts {
+		if assignee := n.assignments.Assignee(a.Labels, a.StartsAt); assignee != "" {
+			c := *a
+			c.AssignedTo = assignee
+			a = &c
+		}
+		res = append(res, a)
+	}
+	return ctx, res, nil
+}
+
 // WaitStage waits for a certain amount of time before continuing or until the
 // context is done.
 type WaitStage struct {
@@ -371,14 +863,49 @@ func NewWaitStage(wait func() time.Duration) *WaitStage {
 
 // Exec implements the Stage interface.
 func (ws *WaitStage) Exec(ctx context.Context, alerts ...*types.Alert) (context.Context, []*types.Alert, error) {
+	d, ok := Wait(ctx)
+	if !ok {
+		d = ws.wait()
+	}
 	select {
-	case <-time.After(ws.wait()):
+	case <-time.After(d):
 	case <-ctx.Done():
 		return ctx, nil, ctx.Err()
 	}
 	return ctx, alerts, nil
 }
 
+// ShardStage passes notifications on to the next stage only if this
+// instance owns their group key. Owners wait for the owners ahead of them
+// as returned by the owner function instead of for all peers.
+type ShardStage struct {
+	owner func(model.Fingerprint) (time.Duration, bool)
+	next  Stage
+}
+
+// NewShardStage returns a new ShardStage. The owner function returns whether
+// this instance owns the group key and how long it waits for the other
+// owners to send a notification.
+func NewShardStage(owner func(model.Fingerprint) (time.Duration, bool), next Stage) *ShardStage {
+	return &ShardStage{
+		owner: owner,
+		next:  next,
+	}
+}
+
+// Exec implements the Stage interface.
+func (ss *ShardStage) Exec(ctx context.Context, alerts ...*types.Alert) (context.Context, []*types.Alert, error) {
+	gkey, ok := GroupKey(ctx)
+	if !ok {
+		return ctx, nil, fmt.Errorf("group key missing")
+	}
+	wait, ok := ss.owner(gkey)
+	if !ok {
+		return ctx, nil, nil
+	}
+	return ss.next.Exec(WithWait(ctx, wait), alerts...)
+}
+
 // DedupStage filters alerts.
 // Filtering happens based on a notification log.
 type DedupStage struct {
@@ -433,6 +960,22 @@ func hashAlerts(alerts []*types.Alert) []byte {
 	return xsum[:]
 }
 
+// allFiringAcked returns whether at least one alert is firing and all firing
+// alerts are acknowledged.
+func allFiringAcked(alerts []*types.Alert) bool {
+	firing := false
+	for _, a := range alerts {
+		if a.Resolved() {
+			continue
+		}
+		if !a.Acked {
+			return false
+		}
+		firing = true
+	}
+	return firing
+}
+
 func allAlertsResolved(alerts []*types.Alert) bool {
 	for _, a := range alerts {
 		if !a.Resolved() {
@@ -442,7 +985,7 @@ func allAlertsResolved(alerts []*types.Alert) bool {
 	return true
 }
 
-func (n *DedupStage) needsUpdate(entry *nflogpb.Entry, hash []byte, resolved bool, repeat time.Duration) (bool, error) {
+func (n *DedupStage) needsUpdate(entry *nflogpb.Entry, hash []byte, resolved, acked bool, repeat time.Duration) (bool, error) {
 	// If we haven't notified about the alert group before, notify right away
 	// unless we only have resolved alerts.
 	if entry == nil {
@@ -453,7 +996,11 @@ func (n *DedupStage) needsUpdate(entry *nflogpb.Entry, hash []byte, resolved boo
 		return true, nil
 	}
 
-	// Nothing changed, only notify if the repeat interval has passed.
+	// Nothing changed, only notify if the repeat interval has passed
+	// and the alerts were not acknowledged.
+	if acked {
+		return false, nil
+	}
 	ts, err := ptypes.Timestamp(entry.Timestamp)
 	if err != nil {
 		return false, err
@@ -494,7 +1041,7 @@ func (n *DedupStage) Exec(ctx context.Context, alerts ...*types.Alert) (context.
 	case 2:
 		return ctx, nil, fmt.Errorf("Unexpected entry result size %d", len(entries))
 	}
-	if ok, err := n.needsUpdate(entry, hash, resolved, repeatInterval); err != nil {
+	if ok, err := n.needsUpdate(entry, hash, resolved, allFiringAcked(alerts), repeatInterval); err != nil {
 		return ctx, nil, err
 	} else if ok {
 		return ctx, alerts, nil
@@ -504,9 +1051,11 @@ func (n *DedupStage) Exec(ctx context.Context, alerts ...*types.Alert) (context.
 }
 
 // RetryStage notifies via passed integration with exponential backoff until it
-// succeeds. It aborts if the context is canceled or timed out.
+// succeeds. It aborts if the context is canceled or timed out, or if the
+// circuit breaker of the integration is open.
 type RetryStage struct {
 	integration Integration
+	breaker     *breaker
 }
 
 // NewRetryStage returns a new instance of a RetryStage.
@@ -519,10 +1068,12 @@ func NewRetryStage(i Integration) *RetryStage {
 // Exec implements the Stage interface.
 func (r RetryStage) Exec(ctx context.Context, alerts ...*types.Alert) (context.Context, []*types.Alert, error) {
 	var (
-		i    = 0
-		b    = backoff.NewExponentialBackOff()
-		tick = backoff.NewTicker(b)
-		iErr error
+		i     = 0
+		b     = backoff.NewExponentialBackOff()
+		tick  = backoff.NewTicker(b)
+		iErr  error
+		recv  = receiverName(ctx)
+		start = time.Now()
 	)
 	defer tick.Stop()
 
@@ -541,18 +1092,37 @@ func (r RetryStage) Exec(ctx context.Context, alerts ...*types.Alert) (context.C
 
 		select {
 		case <-tick.C:
-			if retry, err := r.integration.Notify(ctx, alerts...); err != nil {
+			if err := r.breaker.allow(); err != nil {
+				if iErr != nil {
+					err = failure{fmt.Errorf("%s, last error: %s", err, iErr), FailureReason(iErr), false}
+				}
+				return ctx, nil, err
+			}
+			numNotificationAttempts.WithLabelValues(recv, r.integration.name).Inc()
+			if i > 1 {
+				numNotificationRetries.WithLabelValues(recv, r.integration.name).Inc()
+			}
+			actx, span := tracing.Start(ctx, "attempt", tracing.KindInternal)
+			span.SetAttribute("attempt", i)
+			retry, err := r.integration.Notify(actx, alerts...)
+			span.SetError(err)
+			span.End()
+			if err != nil {
 				numFailedNotifications.WithLabelValues(r.integration.name).Inc()
+				numNotificationFailures.WithLabelValues(recv, r.integration.name, FailureReason(err)).Inc()
 				log.Debugf("Notify attempt %d failed: %s", i, err)
+				r.breaker.failure(err)
 				if !retry {
-					return ctx, alerts, fmt.Errorf("Cancelling notify retry due to unrecoverable error: %s", err)
+					return ctx, alerts, failure{fmt.Errorf("Cancelling notify retry due to unrecoverable error: %s", err), FailureReason(err), true}
 				}
 
 				// Save this error to be able to return the last seen error by an
 				// integration upon context timeout.
 				iErr = err
 			} else {
+				r.breaker.success()
 				numNotifications.WithLabelValues(r.integration.name).Inc()
+				notificationLatency.WithLabelValues(recv, r.integration.name).Observe(time.Since(start).Seconds())
 				return ctx, alerts, nil
 			}
 		case <-ctx.Done():
@@ -565,6 +1135,162 @@ func (r RetryStage) Exec(ctx context.Context, alerts ...*types.Alert) (context.C
 	}
 }
 
+var statusCodeErr = regexp.MustCompile(`unexpected status code ([1-5])([0-9][0-9])`)
+
+// failure is the error of a failed notification that retains the reason of
+// the underlying error it wraps.
+type failure struct {
+	err    error
+	reason string
+	// permanent is set if retrying the notification cannot succeed.
+	permanent bool
+}
+
+func (f failure) Error() string {
+	return f.err.Error()
+}
+
+// retryable returns false if the error of a failed notification is
+// permanent, e.g. because the receiver rejected the notification.
+func retryable(err error) bool {
+	f, ok := err.(failure)
+	return !ok || !f.permanent
+}
+
+// FailureReason classifies the error of a failed notification for the
+// reason label of the failure metric and the notification history. The
+// reasons are timeout, connection, tls, template, rate_limited, status_4xx,
+// status_5xx and other.
+func FailureReason(err error) string {
+	switch e := err.(type) {
+	case failure:
+		return e.reason
+	case templateError:
+		return "template"
+	case *url.Error:
+		err = e.Err
+	}
+	if err == context.DeadlineExceeded || err == context.Canceled {
+		return "timeout"
+	}
+	if isTLSError(err) {
+		return "tls"
+	}
+	if ne, ok := err.(net.Error); ok {
+		if ne.Timeout() {
+			return "timeout"
+		}
+		return "connection"
+	}
+	if m := statusCodeErr.FindStringSubmatch(err.Error()); m != nil {
+		if m[1] == "4" && m[2] == "29" {
+			return "rate_limited"
+		}
+		return "status_" + m[1] + "xx"
+	}
+	return "other"
+}
+
+// isTLSError returns whether the error occurred while establishing a TLS
+// connection, e.g. because the certificate of the receiver is invalid.
+func isTLSError(err error) bool {
+	switch err.(type) {
+	case x509.UnknownAuthorityError, x509.HostnameError, x509.CertificateInvalidError,
+		*x509.UnknownAuthorityError, *x509.HostnameError, *x509.CertificateInvalidError,
+		tls.RecordHeaderError:
+		return true
+	}
+	// Errors of the TLS handshake are mostly unexported or wrapped.
+	msg := err.Error()
+	return strings.Contains(msg, "x509: ") || strings.Contains(msg, "tls: ") || strings.Contains(msg, "starttls failed")
+}
+
+// DryRunStage logs the notification that would have been sent via the passed
+// integration instead of sending it. Subsequent stages are executed as if the
+// notification succeeded so that deduplication and repeat intervals behave
+// like they would for a live receiver.
+type DryRunStage struct {
+	integration Integration
+	tmpl        *template.Template
+}
+
+// NewDryRunStage returns a new instance of a DryRunStage.
+func NewDryRunStage(i Integration, tmpl *template.Template) *DryRunStage {
+	return &DryRunStage{
+		integration: i,
+		tmpl:        tmpl,
+	}
+}
+
+// Exec implements the Stage interface.
+func (d DryRunStage) Exec(ctx context.Context, alerts ...*types.Alert) (context.Context, []*types.Alert, error) {
+	res := alerts
+	if !d.integration.conf.SendResolved() {
+		res = nil
+		for _, a := range alerts {
+			if a.Status() != model.AlertResolved {
+				res = append(res, a)
+			}
+		}
+	}
+	if len(res) == 0 {
+		return ctx, alerts, nil
+	}
+
+	data := d.tmpl.Data(receiverName(ctx), groupLabels(ctx), res...)
+	b, err := json.Marshal(data)
+	if err != nil {
+		return ctx, nil, err
+	}
+	gkey, _ := GroupKey(ctx)
+
+	log.With("receiver", data.Receiver).
+		With("integration", fmt.Sprintf("%s[%d]", d.integration.name, d.integration.idx)).
+		With("groupKey", gkey).
+		Infof("Dry run, not sending notification: %s", b)
+
+	numDryRunNotifications.WithLabelValues(d.integration.name).Inc()
+
+	return ctx, alerts, nil
+}
+
+// EventStage publishes an event for every notification that was sent.
+type EventStage struct {
+	bus         *events.Bus
+	receiver    string
+	integration string
+}
+
+// NewEventStage returns a new EventStage publishing to the given bus.
+func NewEventStage(b *events.Bus, receiver, integration string) *EventStage {
+	return &EventStage{
+		bus:         b,
+		receiver:    receiver,
+		integration: integration,
+	}
+}
+
+// Exec implements the Stage interface.
+func (n *EventStage) Exec(ctx context.Context, alerts ...*types.Alert) (context.Context, []*types.Alert, error) {
+	gkey, ok := GroupKey(ctx)
+	if !ok {
+		return ctx, nil, fmt.Errorf("group key missing")
+	}
+	now, ok := Now(ctx)
+	if !ok {
+		now = time.Now()
+	}
+	n.bus.Publish(&events.Event{
+		Type:        events.GroupNotified,
+		Time:        now,
+		Receiver:    n.receiver,
+		Integration: n.integration,
+		GroupKey:    gkey.String(),
+		Alerts:      alerts,
+	})
+	return ctx, alerts, nil
+}
+
 // SetNotifiesStage sets the notification information about passed alerts. The
 // passed alerts should have already been sent to the receivers.
 type SetNotifiesStage struct {
@@ -601,3 +1327,100 @@ func (n SetNotifiesStage) Exec(ctx context.Context, alerts ...*types.Alert) (con
 	}
 	return ctx, alerts, n.nflog.LogActive(n.recv, gkeyb, hash)
 }
+
+// DigestStage collects the notifications about the groups of a receiver over
+// an interval and sends them as a single notification about all their
+// alerts. The groups are only logged as notified once the digest was sent.
+type DigestStage struct {
+	receiver string
+	interval time.Duration
+	send     Stage
+	notifies Stage
+
+	mtx     sync.Mutex
+	pending map[model.Fingerprint]*digestGroup
+}
+
+// digestGroup is a group notification waiting to be sent with the digest.
+type digestGroup struct {
+	hash   []byte
+	alerts []*types.Alert
+}
+
+// NewDigestStage returns a new DigestStage. The send stage delivers the
+// digest and the notifies stage logs the notification of each of its groups.
+func NewDigestStage(receiver string, interval time.Duration, send, notifies Stage) *DigestStage {
+	return &DigestStage{
+		receiver: receiver,
+		interval: interval,
+		send:     send,
+		notifies: notifies,
+		pending:  map[model.Fingerprint]*digestGroup{},
+	}
+}
+
+// Exec implements the Stage interface. It only stores the notification to
+// be sent with the next digest.
+func (n *DigestStage) Exec(ctx context.Context, alerts ...*types.Alert) (context.Context, []*types.Alert, error) {
+	gkey, ok := GroupKey(ctx)
+	if !ok {
+		return ctx, nil, fmt.Errorf("group key missing")
+	}
+	hash, ok := NotificationHash(ctx)
+	if !ok {
+		return ctx, nil, fmt.Errorf("notification hash missing")
+	}
+
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+
+	if len(n.pending) == 0 {
+		time.AfterFunc(n.interval, n.flush)
+	}
+	// A later notification about a group replaces the earlier one.
+	n.pending[gkey] = &digestGroup{hash: hash, alerts: alerts}
+
+	return ctx, nil, nil
+}
+
+// flush sends the digest about all pending notifications.
+func (n *DigestStage) flush() {
+	n.mtx.Lock()
+	groups := n.pending
+	n.pending = map[model.Fingerprint]*digestGroup{}
+	n.mtx.Unlock()
+
+	if len(groups) == 0 {
+		return
+	}
+	gkeys := make(model.Fingerprints, 0, len(groups))
+	for gkey := range groups {
+		gkeys = append(gkeys, gkey)
+	}
+	sort.Sort(gkeys)
+
+	var alerts []*types.Alert
+	for _, gkey := range gkeys {
+		alerts = append(alerts, groups[gkey].alerts...)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), n.interval)
+	defer cancel()
+
+	ctx = WithNow(ctx, time.Now())
+	ctx = WithReceiverName(ctx, n.receiver)
+	ctx = WithGroupKey(ctx, model.LabelSet{"receiver": model.LabelValue(n.receiver)}.Fingerprint())
+	ctx = WithGroupLabels(ctx, model.LabelSet{})
+
+	if _, _, err := n.send.Exec(ctx, alerts...); err != nil {
+		// The groups are notified again and end up in a later digest.
+		log.Errorf("Sending digest of %d groups to %s failed: %s", len(groups), n.receiver, err)
+		return
+	}
+	for gkey, g := range groups {
+		gctx := WithNotificationHash(WithGroupKey(ctx, gkey), g.hash)
+		if _, _, err := n.notifies.Exec(gctx, g.alerts...); err != nil {
+			log.Errorf("Logging notification of digested group failed: %s", err)
+		}
+	}
+}
@@ -0,0 +1,399 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+	"github.com/prometheus/common/model"
+	"golang.org/x/net/context"
+
+	"github.com/prometheus/alertmanager/tracing"
+	"github.com/prometheus/alertmanager/types"
+)
+
+// QueueOptions configures a new RetryQueue.
+type QueueOptions struct {
+	// The file the queue is written to at the interval, if it changed, and
+	// loaded from on creation. The queue is held in memory only if it is
+	// empty.
+	File string
+
+	// The interval at which queued notifications are retried and the time
+	// after which a notification is retried by the queue if it was not
+	// attempted otherwise.
+	Interval time.Duration
+
+	// Queued notifications are dropped once they were queued for longer.
+	// Zero keeps them until they are sent.
+	MaxAge time.Duration
+
+	Logger  log.Logger
+	Metrics prometheus.Registerer
+}
+
+// queuedNotification is a notification that failed to be sent.
+type queuedNotification struct {
+	Receiver       string            `json:"receiver"`
+	Integration    string            `json:"integration"`
+	Index          int               `json:"index"`
+	GroupKey       model.Fingerprint `json:"groupKey"`
+	GroupLabels    model.LabelSet    `json:"groupLabels"`
+	RepeatInterval time.Duration     `json:"repeatInterval"`
+	Escalation     bool              `json:"escalation,omitempty"`
+	Alerts         []*types.Alert    `json:"alerts"`
+
+	QueuedAt    time.Time `json:"queuedAt"`
+	LastAttempt time.Time `json:"lastAttempt"`
+	Attempts    int       `json:"attempts"`
+	Error       string    `json:"error"`
+}
+
+func (n *queuedNotification) key() string {
+	return queueKey(n.Receiver, n.Integration, n.Index, n.GroupKey)
+}
+
+func queueKey(receiver, integration string, idx int, gkey model.Fingerprint) string {
+	return fmt.Sprintf("%s/%s[%d]/%s", receiver, integration, idx, gkey)
+}
+
+// RetryQueue holds notifications that failed to be sent and retries them
+// until they succeed. As it is persisted to disk, notifications awaiting a
+// retry, e.g. during an outage of the notification service, survive a
+// restart even if their aggregation group is not flushed again.
+//
+// A queued notification is replaced by each further notification of its
+// group and removed once one of them succeeds or is found to be no longer
+// needed, so the queue never sends outdated notifications of a group that
+// is still notified regularly. Retries pass the same filters as other
+// notifications, so notifications whose alerts were silenced, inhibited or
+// otherwise muted in the meantime are dropped, and wait for peers before
+// being deduplicated, so that only one peer retries a notification.
+type RetryQueue struct {
+	o      QueueOptions
+	logger log.Logger
+	now    func() time.Time
+
+	mtx     sync.Mutex
+	pending map[string]*queuedNotification
+	// Whether pending changed since the queue was last written.
+	dirty bool
+	// The stages filtering notifications of a receiver and the stages
+	// delivering notifications of an integration, by receiver and
+	// integration. They are replaced on configuration reloads.
+	filters map[string]Stage
+	stages  map[string]Stage
+
+	queued  prometheus.Gauge
+	retried *prometheus.CounterVec
+	dropped prometheus.Counter
+}
+
+// NewRetryQueue returns a new RetryQueue holding the notifications read from
+// the file, if set.
+func NewRetryQueue(o QueueOptions) (*RetryQueue, error) {
+	q := &RetryQueue{
+		o:       o,
+		logger:  log.NewNopLogger(),
+		now:     time.Now,
+		pending: map[string]*queuedNotification{},
+		filters: map[string]Stage{},
+		stages:  map[string]Stage{},
+		queued: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "alertmanager_notification_queue_length",
+			Help: "The number of failed notifications awaiting a retry.",
+		}),
+		retried: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "alertmanager_notification_queue_retries_total",
+			Help: "The total number of retries of queued notifications by outcome, which is success, failure or muted.",
+		}, []string{"outcome"}),
+		dropped: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "alertmanager_notification_queue_dropped_total",
+			Help: "The total number of queued notifications dropped for exceeding the maximum age.",
+		}),
+	}
+	if o.Logger != nil {
+		q.logger = o.Logger
+	}
+	if o.Metrics != nil {
+		o.Metrics.MustRegister(q.queued, q.retried, q.dropped)
+	}
+	if o.File == "" {
+		return q, nil
+	}
+	f, err := os.Open(o.File)
+	if os.IsNotExist(err) {
+		return q, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var ns []*queuedNotification
+	if err := json.NewDecoder(f).Decode(&ns); err != nil {
+		return nil, fmt.Errorf("loading notification queue: %s", err)
+	}
+	for _, n := range ns {
+		q.pending[n.key()] = n
+	}
+	q.queued.Set(float64(len(q.pending)))
+	return q, nil
+}
+
+// filter sets the stage filtering notifications of the receiver, e.g. of
+// silenced alerts, before they are retried.
+func (q *RetryQueue) filter(receiver string, s Stage) {
+	if q == nil {
+		return
+	}
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	q.filters[receiver] = s
+}
+
+// wrap returns a stage executing s, which delivers notifications of the
+// integration of the receiver after deduplicating them, and queuing the
+// notification if it fails with a retryable error. Queued notifications are
+// retried with the wait stage followed by s.
+func (q *RetryQueue) wrap(receiver string, i Integration, wait, s Stage) Stage {
+	if q == nil {
+		return s
+	}
+	q.mtx.Lock()
+	q.stages[fmt.Sprintf("%s/%s[%d]", receiver, i.name, i.idx)] = MultiStage{wait, s}
+	q.mtx.Unlock()
+
+	return StageFunc(func(ctx context.Context, alerts ...*types.Alert) (context.Context, []*types.Alert, error) {
+		ctx, res, err := s.Exec(ctx, alerts...)
+
+		gkey, ok := GroupKey(ctx)
+		if !ok {
+			return ctx, res, err
+		}
+		if err == nil || !retryable(err) {
+			// A notification that cannot succeed also supersedes the
+			// queued one.
+			q.remove(queueKey(receiver, i.name, i.idx, gkey))
+			return ctx, res, err
+		}
+		repeat, _ := RepeatInterval(ctx)
+		now := q.now()
+		q.add(&queuedNotification{
+			Receiver:       receiver,
+			Integration:    i.name,
+			Index:          i.idx,
+			GroupKey:       gkey,
+			GroupLabels:    groupLabels(ctx),
+			RepeatInterval: repeat,
+			Escalation:     Escalation(ctx),
+			Alerts:         alerts,
+			QueuedAt:       now,
+			LastAttempt:    now,
+			Attempts:       1,
+			Error:          err.Error(),
+		})
+		return ctx, res, err
+	})
+}
+
+func (q *RetryQueue) add(n *queuedNotification) {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	if prev, ok := q.pending[n.key()]; ok {
+		n.QueuedAt = prev.QueuedAt
+		n.Attempts += prev.Attempts
+	}
+	q.pending[n.key()] = n
+	q.changed()
+}
+
+func (q *RetryQueue) remove(key string) {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	if _, ok := q.pending[key]; !ok {
+		return
+	}
+	delete(q.pending, key)
+	q.changed()
+}
+
+// changed marks the queue to be written. It must be called with the lock
+// held.
+func (q *RetryQueue) changed() {
+	q.queued.Set(float64(len(q.pending)))
+	q.dirty = true
+}
+
+// flush writes the queue to its file if it changed since it was last
+// written. The lock is not held while writing.
+func (q *RetryQueue) flush() {
+	q.mtx.Lock()
+	if !q.dirty || q.o.File == "" {
+		q.mtx.Unlock()
+		return
+	}
+	ns := make([]*queuedNotification, 0, len(q.pending))
+	for _, n := range q.pending {
+		ns = append(ns, n)
+	}
+	b, err := json.Marshal(ns)
+	q.dirty = false
+	q.mtx.Unlock()
+
+	if err == nil {
+		err = q.write(b)
+	}
+	if err != nil {
+		q.logger.With("err", err).Error("Writing notification queue failed")
+		q.mtx.Lock()
+		q.dirty = true
+		q.mtx.Unlock()
+	}
+}
+
+func (q *RetryQueue) write(b []byte) error {
+	tmp := fmt.Sprintf("%s.%x", q.o.File, uint64(rand.Int63()))
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, q.o.File)
+}
+
+// Run retries queued notifications and writes the queue to its file at the
+// interval until the stop channel is closed.
+func (q *RetryQueue) Run(stopc <-chan struct{}) {
+	t := time.NewTicker(q.o.Interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-stopc:
+			q.flush()
+			return
+		case <-t.C:
+			q.retry()
+			q.flush()
+		}
+	}
+}
+
+// retry sends the queued notifications that were not attempted within the
+// interval and drops those exceeding the maximum age. Notifications whose
+// alerts are all muted by now are dropped, too.
+func (q *RetryQueue) retry() {
+	now := q.now()
+
+	q.mtx.Lock()
+	var due []*queuedNotification
+	for key, n := range q.pending {
+		if q.o.MaxAge > 0 && now.Sub(n.QueuedAt) > q.o.MaxAge {
+			q.logger.With("receiver", n.Receiver).With("integration", fmt.Sprintf("%s[%d]", n.Integration, n.Index)).
+				With("attempts", n.Attempts).Warn("Dropping queued notification exceeding the maximum age")
+			delete(q.pending, key)
+			q.dropped.Inc()
+			q.changed()
+			continue
+		}
+		if now.Sub(n.LastAttempt) >= q.o.Interval {
+			due = append(due, n)
+		}
+	}
+	q.mtx.Unlock()
+
+	for _, n := range due {
+		q.mtx.Lock()
+		f, fok := q.filters[n.Receiver]
+		s, ok := q.stages[fmt.Sprintf("%s/%s[%d]", n.Receiver, n.Integration, n.Index)]
+		q.mtx.Unlock()
+		if !fok || !ok {
+			// The integration is not configured (anymore). The notification
+			// is dropped once it exceeds the maximum age.
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), q.o.Interval)
+		ctx = WithReceiverName(ctx, n.Receiver)
+		ctx = WithGroupKey(ctx, n.GroupKey)
+		ctx = WithGroupLabels(ctx, n.GroupLabels)
+		ctx = WithRepeatInterval(ctx, n.RepeatInterval)
+		ctx = WithNow(ctx, now)
+		if n.Escalation {
+			ctx = WithEscalation(ctx)
+		}
+		ctx, span := tracing.Start(ctx, "queued retry", tracing.KindInternal)
+		span.SetAttribute("receiver", n.Receiver)
+		span.SetAttribute("attempts", n.Attempts)
+
+		ctx, alerts, err := f.Exec(ctx, n.Alerts...)
+		muted := err == nil && len(alerts) == 0
+		if err == nil && !muted {
+			_, _, err = s.Exec(ctx, alerts...)
+		}
+		span.SetError(err)
+		span.End()
+		cancel()
+
+		q.mtx.Lock()
+		// The notification may have been replaced or removed by a flush of
+		// its group in the meantime.
+		if cur, ok := q.pending[n.key()]; ok && cur == n {
+			if err != nil && retryable(err) {
+				n.Attempts++
+				n.LastAttempt = now
+				n.Error = err.Error()
+			} else {
+				delete(q.pending, n.key())
+			}
+			q.changed()
+		}
+		q.mtx.Unlock()
+
+		switch {
+		case muted:
+			q.retried.WithLabelValues("muted").Inc()
+		case err != nil:
+			q.retried.WithLabelValues("failure").Inc()
+			q.logger.With("receiver", n.Receiver).With("integration", fmt.Sprintf("%s[%d]", n.Integration, n.Index)).
+				With("err", err).Debug("Retrying queued notification failed")
+		default:
+			q.retried.WithLabelValues("success").Inc()
+		}
+	}
+}
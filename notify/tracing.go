@@ -0,0 +1,53 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"golang.org/x/net/context"
+
+	"github.com/prometheus/alertmanager/tracing"
+	"github.com/prometheus/alertmanager/types"
+)
+
+// TracedStage records the execution of its stage in a span.
+type TracedStage struct {
+	name  string
+	stage Stage
+	attrs map[string]interface{}
+}
+
+// NewTracedStage returns a new TracedStage recording spans with the name
+// and attributes.
+func NewTracedStage(name string, s Stage, attrs map[string]interface{}) *TracedStage {
+	return &TracedStage{name: name, stage: s, attrs: attrs}
+}
+
+// Exec implements the Stage interface.
+func (s *TracedStage) Exec(ctx context.Context, alerts ...*types.Alert) (context.Context, []*types.Alert, error) {
+	parent := tracing.SpanFromContext(ctx)
+	ctx, span := tracing.Start(ctx, s.name, tracing.KindInternal)
+	for k, v := range s.attrs {
+		span.SetAttribute(k, v)
+	}
+	span.SetAttribute("alerts.in", len(alerts))
+
+	ctx, res, err := s.stage.Exec(ctx, alerts...)
+
+	span.SetAttribute("alerts.out", len(res))
+	span.SetError(err)
+	span.End()
+
+	// Following stages are siblings rather than children of the span.
+	return tracing.ContextWithSpan(ctx, parent), res, err
+}
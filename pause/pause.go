@@ -0,0 +1,254 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package pause implements pausing the notifications of a receiver or of a
+// single aggregation group for a limited time, for example while the
+// receiver itself is unavailable. Pauses are shared with other Alertmanager
+// instances through the mesh network.
+package pause
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/prometheus/common/log"
+	"github.com/weaveworks/mesh"
+
+	"github.com/prometheus/alertmanager/gossipstate"
+)
+
+// ErrNotFound is returned if a pause was not found.
+var ErrNotFound = errors.New("pause not found")
+
+// Pause suppresses notifications to a receiver until it ends. If the group
+// key is set, only notifications of the aggregation group with that key are
+// suppressed.
+type Pause struct {
+	Receiver  string    `json:"receiver"`
+	GroupKey  uint64    `json:"groupKey,omitempty"`
+	CreatedBy string    `json:"createdBy"`
+	Comment   string    `json:"comment,omitempty"`
+	EndsAt    time.Time `json:"endsAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Active returns whether the pause did not end at the given time.
+func (p *Pause) Active(now time.Time) bool {
+	return p.EndsAt.After(now)
+}
+
+func (p *Pause) key() string {
+	return pauseKey(p.Receiver, p.GroupKey)
+}
+
+func pauseKey(receiver string, gkey uint64) string {
+	return fmt.Sprintf("%s/%x", receiver, gkey)
+}
+
+func validatePause(p *Pause) error {
+	if p.Receiver == "" {
+		return errors.New("receiver missing")
+	}
+	if p.CreatedBy == "" {
+		return errors.New("creator missing")
+	}
+	if p.EndsAt.IsZero() || p.UpdatedAt.IsZero() {
+		return errors.New("timestamps missing")
+	}
+	return nil
+}
+
+// Pauses holds the pauses of receivers and groups.
+type Pauses struct {
+	retention time.Duration
+	now       func() time.Time
+	st        *gossipstate.State
+}
+
+// Options configures a new Pauses object.
+type Options struct {
+	// A snapshot file from which the initial state is loaded.
+	SnapshotFile string
+
+	// Pauses may be garbage collected the given duration after they ended.
+	Retention time.Duration
+
+	// A function creating a mesh.Gossip on being called with a mesh.Gossiper.
+	Gossip func(g mesh.Gossiper) mesh.Gossip
+
+	// A logger used by background processing.
+	Logger log.Logger
+}
+
+// New returns a new Pauses object with the given configuration.
+func New(o Options) (*Pauses, error) {
+	st, err := gossipstate.New(pauseType{}, gossipstate.Options{
+		SnapshotFile: o.SnapshotFile,
+		Gossip:       o.Gossip,
+		Logger:       o.Logger,
+	})
+	if st == nil {
+		return nil, err
+	}
+	return &Pauses{
+		retention: o.Retention,
+		now:       gossipstate.UTCNow,
+		st:        st,
+	}, err
+}
+
+// Maintenance garbage collects the pauses at the given interval. If the
+// snapshot file is set, a snapshot is written to it afterwards.
+// Terminates on receiving from stopc.
+func (s *Pauses) Maintenance(interval time.Duration, snapf string, stopc <-chan struct{}) {
+	s.st.Maintenance(interval, snapf, stopc, s.GC)
+}
+
+// GC removes pauses that ended longer than the retention time ago.
+// It returns the number of removed pauses.
+func (s *Pauses) GC() (int, error) {
+	now := s.now()
+
+	return s.st.GC(func(e interface{}) bool {
+		return !e.(*Pause).EndsAt.Add(s.retention).After(now)
+	}), nil
+}
+
+// Pause suppresses notifications to the receiver until endsAt. If gkey is
+// not zero, only notifications of the aggregation group with that key are
+// suppressed. An existing pause of the receiver or group is replaced.
+func (s *Pauses) Pause(receiver string, gkey uint64, endsAt time.Time, createdBy, comment string) (*Pause, error) {
+	now := s.now()
+
+	if !endsAt.After(now) {
+		return nil, errors.New("invalid pause: end must be in the future")
+	}
+	p := &Pause{
+		Receiver:  receiver,
+		GroupKey:  gkey,
+		CreatedBy: createdBy,
+		Comment:   comment,
+		EndsAt:    endsAt,
+		UpdatedAt: now,
+	}
+	if err := validatePause(p); err != nil {
+		return nil, fmt.Errorf("invalid pause: %s", err)
+	}
+
+	s.st.Lock()
+	defer s.st.Unlock()
+
+	s.st.Set(p)
+	c := *p
+	return &c, nil
+}
+
+// Resume ends the pause of the receiver or, if gkey is not zero, of the
+// aggregation group with that key immediately.
+func (s *Pauses) Resume(receiver string, gkey uint64) error {
+	now := s.now()
+
+	s.st.Lock()
+	defer s.st.Unlock()
+
+	e, ok := s.st.Get(pauseKey(receiver, gkey))
+	if !ok || !e.(*Pause).Active(now) {
+		return ErrNotFound
+	}
+	c := *e.(*Pause)
+	c.EndsAt = now
+	c.UpdatedAt = now
+
+	s.st.Set(&c)
+	return nil
+}
+
+type pauseSlice []*Pause
+
+func (s pauseSlice) Len() int           { return len(s) }
+func (s pauseSlice) Less(i, j int) bool { return s[i].EndsAt.Before(s[j].EndsAt) }
+func (s pauseSlice) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+// List returns all active pauses ordered by their end time.
+func (s *Pauses) List() []*Pause {
+	now := s.now()
+
+	s.st.RLock()
+	defer s.st.RUnlock()
+
+	res := pauseSlice{}
+	s.st.Range(func(e interface{}) {
+		if p := e.(*Pause); p.Active(now) {
+			c := *p
+			res = append(res, &c)
+		}
+	})
+	sort.Sort(res)
+	return res
+}
+
+// Paused returns the active pause suppressing notifications of the
+// aggregation group with the given key to the receiver. A pause of the
+// whole receiver takes precedence over a pause of the group. It returns nil
+// if notifications are not paused. It is safe to call on a nil Pauses.
+func (s *Pauses) Paused(receiver string, gkey uint64) *Pause {
+	if s == nil {
+		return nil
+	}
+	now := s.now()
+
+	s.st.RLock()
+	defer s.st.RUnlock()
+
+	for _, k := range []string{pauseKey(receiver, 0), pauseKey(receiver, gkey)} {
+		if e, ok := s.st.Get(k); ok && e.(*Pause).Active(now) {
+			c := *e.(*Pause)
+			return &c
+		}
+	}
+	return nil
+}
+
+// pauseType implements the handling of pauses by the state.
+type pauseType struct{}
+
+func (pauseType) Key(e interface{}) string {
+	return e.(*Pause).key()
+}
+
+func (pauseType) Decode(b []byte) (interface{}, error) {
+	var p Pause
+	if err := json.Unmarshal(b, &p); err != nil {
+		return nil, err
+	}
+	if err := validatePause(&p); err != nil {
+		return nil, fmt.Errorf("invalid pause: %s", err)
+	}
+	return &p, nil
+}
+
+func (pauseType) Size(e interface{}) int {
+	p := e.(*Pause)
+	return len(p.Receiver) + len(p.Comment) + len(p.CreatedBy) + 128
+}
+
+// Merge keeps the more recently updated pause.
+func (pauseType) Merge(prev, e interface{}) interface{} {
+	if !prev.(*Pause).UpdatedAt.Before(e.(*Pause).UpdatedAt) {
+		return nil
+	}
+	return e
+}
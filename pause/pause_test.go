@@ -0,0 +1,108 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pause
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/prometheus/alertmanager/gossipstate"
+)
+
+func TestPausesPauseResume(t *testing.T) {
+	s, err := New(Options{Retention: time.Hour})
+	require.NoError(t, err)
+
+	now := gossipstate.UTCNow()
+	s.now = func() time.Time { return now }
+
+	_, err = s.Pause("team", 0, now, "me", "")
+	require.Error(t, err, "end must be in the future")
+	_, err = s.Pause("team", 0, now.Add(time.Hour), "", "")
+	require.Error(t, err, "creator missing")
+	_, err = s.Pause("", 0, now.Add(time.Hour), "me", "")
+	require.Error(t, err, "receiver missing")
+
+	// Pausing a group only affects that group.
+	_, err = s.Pause("team", 1, now.Add(time.Hour), "me", "pager down")
+	require.NoError(t, err)
+
+	p := s.Paused("team", 1)
+	require.NotNil(t, p)
+	require.Equal(t, "pager down", p.Comment)
+	require.Nil(t, s.Paused("team", 2))
+	require.Nil(t, s.Paused("other", 1))
+
+	var nilPauses *Pauses
+	require.Nil(t, nilPauses.Paused("team", 1))
+
+	// Pausing the receiver affects all of its groups.
+	_, err = s.Pause("team", 0, now.Add(2*time.Hour), "me", "")
+	require.NoError(t, err)
+	require.NotNil(t, s.Paused("team", 2))
+	require.Len(t, s.List(), 2)
+
+	// Pauses end automatically.
+	now = now.Add(time.Hour)
+	require.Equal(t, "", s.Paused("team", 1).Comment)
+	require.Len(t, s.List(), 1)
+	require.Equal(t, ErrNotFound, s.Resume("team", 1))
+
+	require.NoError(t, s.Resume("team", 0))
+	require.Nil(t, s.Paused("team", 2))
+	require.Equal(t, ErrNotFound, s.Resume("team", 0))
+
+	now = now.Add(59 * time.Minute)
+	n, err := s.GC()
+	require.NoError(t, err)
+	require.Equal(t, 0, n)
+
+	now = now.Add(time.Minute)
+	n, err = s.GC()
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+}
+
+func TestPauseTypeMerge(t *testing.T) {
+	now := gossipstate.UTCNow()
+
+	newPause := func(createdBy string, updated time.Time) *Pause {
+		return &Pause{
+			Receiver:  "team",
+			CreatedBy: createdBy,
+			EndsAt:    now.Add(time.Hour),
+			UpdatedAt: updated,
+		}
+	}
+	prev := newPause("alice", now)
+
+	for _, c := range []struct {
+		name   string
+		e      *Pause
+		merged bool
+	}{
+		{name: "newer", e: newPause("carol", now.Add(time.Minute)), merged: true},
+		{name: "same update", e: newPause("carol", now)},
+		{name: "older", e: newPause("carol", now.Add(-time.Minute))},
+	} {
+		res := pauseType{}.Merge(prev, c.e)
+		if !c.merged {
+			require.Nil(t, res, c.name)
+			continue
+		}
+		require.Equal(t, c.e, res, c.name)
+	}
+}
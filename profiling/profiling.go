@@ -0,0 +1,207 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package profiling takes pprof profiles while the process is under high
+// load and pushes them to a continuous profiling endpoint, so load spikes
+// such as alert storms can be diagnosed after the fact.
+package profiling
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/url"
+	"runtime"
+	"runtime/pprof"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+)
+
+// Options configures a new Profiler.
+type Options struct {
+	// The URL profiles are pushed to. Each profile is sent in a POST request
+	// with the pprof data as body and the query parameters name, holding the
+	// profile type (cpu, heap or goroutine), from and until, holding the
+	// Unix timestamps the profile covers.
+	URL string
+
+	// The CPU usage as a multiple of one core and the number of goroutines
+	// from which on profiles are taken. Zero disables the trigger.
+	CPUThreshold       float64
+	GoroutineThreshold int
+
+	// The interval at which the load is checked, the time CPU profiles are
+	// recorded for and the minimum time between the profiles of two
+	// triggers.
+	CheckInterval time.Duration
+	Duration      time.Duration
+	MinInterval   time.Duration
+
+	Logger  log.Logger
+	Metrics prometheus.Registerer
+}
+
+// Profiler pushes profiles once the load exceeds a threshold.
+type Profiler struct {
+	o      Options
+	client *http.Client
+	logger log.Logger
+
+	cpuTime    func() (time.Duration, error)
+	goroutines func() int
+	now        func() time.Time
+
+	lastCheck time.Time
+	lastCPU   time.Duration
+	lastPush  time.Time
+
+	pushed   *prometheus.CounterVec
+	failures prometheus.Counter
+}
+
+// New returns a new Profiler.
+func New(o Options) *Profiler {
+	p := &Profiler{
+		o:          o,
+		client:     &http.Client{Timeout: time.Minute},
+		logger:     log.NewNopLogger(),
+		cpuTime:    cpuTime,
+		goroutines: runtime.NumGoroutine,
+		now:        time.Now,
+		pushed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "alertmanager_profiles_pushed_total",
+			Help: "Number of profiles pushed to the continuous profiling endpoint by type.",
+		}, []string{"type"}),
+		failures: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "alertmanager_profile_push_failures_total",
+			Help: "Number of profiles that failed to be taken or pushed.",
+		}),
+	}
+	if o.Logger != nil {
+		p.logger = o.Logger
+	}
+	if o.Metrics != nil {
+		o.Metrics.MustRegister(p.pushed, p.failures)
+	}
+	return p
+}
+
+// Run checks the load at the check interval until the stop channel is
+// closed.
+func (p *Profiler) Run(stopc <-chan struct{}) {
+	t := time.NewTicker(p.o.CheckInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-stopc:
+			return
+		case <-t.C:
+			if reason, ok := p.check(); ok {
+				p.logger.With("reason", reason).Info("High load, pushing profiles")
+				p.profile()
+			}
+		}
+	}
+}
+
+// check returns whether the load exceeds a threshold and profiles are due,
+// and the reason for it.
+func (p *Profiler) check() (string, bool) {
+	var (
+		now    = p.now()
+		reason string
+	)
+	if p.o.CPUThreshold > 0 {
+		cpu, err := p.cpuTime()
+		if err != nil {
+			p.logger.With("err", err).Warn("Measuring CPU usage failed")
+		} else {
+			if !p.lastCheck.IsZero() {
+				usage := float64(cpu-p.lastCPU) / float64(now.Sub(p.lastCheck))
+				if usage >= p.o.CPUThreshold {
+					reason = fmt.Sprintf("CPU usage of %.2f cores", usage)
+				}
+			}
+			p.lastCheck, p.lastCPU = now, cpu
+		}
+	}
+	if n := p.goroutines(); p.o.GoroutineThreshold > 0 && n >= p.o.GoroutineThreshold && reason == "" {
+		reason = fmt.Sprintf("%d goroutines", n)
+	}
+	if reason == "" || (!p.lastPush.IsZero() && now.Sub(p.lastPush) < p.o.MinInterval) {
+		return "", false
+	}
+	p.lastPush = now
+	return reason, true
+}
+
+// profile takes and pushes a CPU, heap and goroutine profile.
+func (p *Profiler) profile() {
+	var buf bytes.Buffer
+
+	from := p.now()
+	if err := pprof.StartCPUProfile(&buf); err != nil {
+		// Another CPU profile, e.g. requested through the pprof handlers,
+		// is in progress.
+		p.failures.Inc()
+		p.logger.With("err", err).Warn("Taking CPU profile failed")
+	} else {
+		time.Sleep(p.o.Duration)
+		pprof.StopCPUProfile()
+		p.push("cpu", buf.Bytes(), from)
+	}
+
+	for _, name := range []string{"heap", "goroutine"} {
+		buf.Reset()
+		from = p.now()
+		if err := pprof.Lookup(name).WriteTo(&buf, 0); err != nil {
+			p.failures.Inc()
+			p.logger.With("err", err).With("profile", name).Warn("Taking profile failed")
+			continue
+		}
+		p.push(name, buf.Bytes(), from)
+	}
+}
+
+func (p *Profiler) push(name string, data []byte, from time.Time) {
+	q := url.Values{
+		"name":  {name},
+		"from":  {strconv.FormatInt(from.Unix(), 10)},
+		"until": {strconv.FormatInt(p.now().Unix(), 10)},
+	}
+	u := p.o.URL
+	if strings.Contains(u, "?") {
+		u += "&" + q.Encode()
+	} else {
+		u += "?" + q.Encode()
+	}
+
+	resp, err := p.client.Post(u, "application/octet-stream", bytes.NewReader(data))
+	if err == nil {
+		resp.Body.Close()
+		if resp.StatusCode/100 != 2 {
+			err = fmt.Errorf("unexpected status code %d", resp.StatusCode)
+		}
+	}
+	if err != nil {
+		p.failures.Inc()
+		p.logger.With("err", err).With("profile", name).Warn("Pushing profile failed")
+		return
+	}
+	p.pushed.WithLabelValues(name).Inc()
+}
@@ -0,0 +1,93 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package profiling
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProfilerCheck(t *testing.T) {
+	var (
+		now        = time.Unix(1000, 0)
+		cpu        time.Duration
+		goroutines = 10
+	)
+	p := New(Options{
+		CPUThreshold:       1.5,
+		GoroutineThreshold: 100,
+		MinInterval:        time.Minute,
+	})
+	p.now = func() time.Time { return now }
+	p.cpuTime = func() (time.Duration, error) { return cpu, nil }
+	p.goroutines = func() int { return goroutines }
+
+	// The first check only records the CPU time.
+	_, ok := p.check()
+	require.False(t, ok)
+
+	now, cpu = now.Add(10*time.Second), cpu+10*time.Second
+	_, ok = p.check()
+	require.False(t, ok)
+
+	now, cpu = now.Add(10*time.Second), cpu+20*time.Second
+	reason, ok := p.check()
+	require.True(t, ok)
+	require.Equal(t, "CPU usage of 2.00 cores", reason)
+
+	// Profiles are not pushed again before the minimum interval passed.
+	now, cpu = now.Add(10*time.Second), cpu+20*time.Second
+	_, ok = p.check()
+	require.False(t, ok)
+
+	now, goroutines = now.Add(time.Minute), 200
+	reason, ok = p.check()
+	require.True(t, ok)
+	require.Equal(t, "200 goroutines", reason)
+}
+
+func TestProfilerProfile(t *testing.T) {
+	var (
+		mtx    sync.Mutex
+		pushed = map[string]int{}
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		require.Equal(t, "am", r.URL.Query().Get("app"))
+		require.NotEmpty(t, r.URL.Query().Get("from"))
+		require.NotEmpty(t, r.URL.Query().Get("until"))
+
+		mtx.Lock()
+		pushed[r.URL.Query().Get("name")] = len(body)
+		mtx.Unlock()
+	}))
+	defer srv.Close()
+
+	p := New(Options{URL: srv.URL + "/ingest?app=am", Duration: 10 * time.Millisecond})
+	p.profile()
+
+	mtx.Lock()
+	defer mtx.Unlock()
+	require.Len(t, pushed, 3)
+	for name, n := range pushed {
+		require.NotZero(t, n, "empty %s profile", name)
+	}
+}
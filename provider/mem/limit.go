@@ -0,0 +1,125 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mem
+
+import (
+	"sort"
+	"time"
+
+	"github.com/prometheus/alertmanager/types"
+	"github.com/prometheus/common/model"
+)
+
+// Limits caps the alerts held in memory. Once a limit is exceeded, alerts
+// are evicted: resolved alerts first, the longest resolved ones before
+// others, then firing alerts by their severity, the least recently updated
+// ones first.
+type Limits struct {
+	// The maximum number of alerts. Zero means no limit.
+	MaxAlerts int
+	// The maximum estimated size of all alerts in bytes. Zero means no
+	// limit.
+	MaxBytes int64
+
+	// The label holding the severity of an alert and the known
+	// severities from the lowest to the highest. Alerts without a known
+	// severity rank below all known ones.
+	SeverityLabel model.LabelName
+	Severities    []string
+}
+
+// alertOverhead approximates the memory used by an alert besides its labels,
+// annotations and generator URL.
+const alertOverhead = 256
+
+// alertSize estimates the memory used by the alert.
+func alertSize(a *types.Alert) int64 {
+	n := alertOverhead + len(a.GeneratorURL)
+	for k, v := range a.Labels {
+		n += len(k) + len(v)
+	}
+	for k, v := range a.Annotations {
+		n += len(k) + len(v)
+	}
+	n += len(a.Sources) * alertOverhead / 4
+	return int64(n)
+}
+
+func (l Limits) exceeded(count int, size int64) bool {
+	return (l.MaxAlerts > 0 && count > l.MaxAlerts) || (l.MaxBytes > 0 && size > l.MaxBytes)
+}
+
+// severity returns the rank of the alert's severity, which is 0 for unknown
+// severities.
+func (l Limits) severity(a *types.Alert) int {
+	v := string(a.Labels[l.SeverityLabel])
+	for i, s := range l.Severities {
+		if s == v {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// evict removes alerts until the limits are met and returns their
+// fingerprints. The caller must hold the lock.
+func (a *Alerts) evict() []model.Fingerprint {
+	if !a.limits.exceeded(len(a.alerts), a.size) {
+		return nil
+	}
+	type candidate struct {
+		fp       model.Fingerprint
+		alert    *types.Alert
+		resolved bool
+		severity int
+	}
+	now := time.Now()
+	candidates := make([]candidate, 0, len(a.alerts))
+	for fp, alert := range a.alerts {
+		candidates = append(candidates, candidate{
+			fp:       fp,
+			alert:    alert,
+			resolved: alert.EndsAt.Before(now),
+			severity: a.limits.severity(alert),
+		})
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		ci, cj := candidates[i], candidates[j]
+		if ci.resolved != cj.resolved {
+			return ci.resolved
+		}
+		if ci.resolved {
+			return ci.alert.EndsAt.Before(cj.alert.EndsAt)
+		}
+		if ci.severity != cj.severity {
+			return ci.severity < cj.severity
+		}
+		return ci.alert.UpdatedAt.Before(cj.alert.UpdatedAt)
+	})
+
+	var evicted []model.Fingerprint
+	for _, c := range candidates {
+		if !a.limits.exceeded(len(a.alerts), a.size) {
+			break
+		}
+		state := "firing"
+		if c.resolved {
+			state = "resolved"
+		}
+		a.remove(c.fp)
+		evicted = append(evicted, c.fp)
+		a.metrics.evictedTotal.WithLabelValues(state).Inc()
+	}
+	return evicted
+}
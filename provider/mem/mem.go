@@ -14,11 +14,14 @@
 package mem
 
 import (
+	"io"
 	"sync"
 	"time"
 
 	"github.com/prometheus/alertmanager/provider"
 	"github.com/prometheus/alertmanager/types"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
 	"github.com/prometheus/common/model"
 )
 
@@ -30,47 +33,173 @@ type Alerts struct {
 
 	listeners map[int]chan *types.Alert
 	next      int
+
+	// The store the alerts are persisted in, if any.
+	store Store
+
+	limits     Limits
+	retention  time.Duration
+	gcInterval time.Duration
+	size       int64
+	metrics    *metrics
+}
+
+type metrics struct {
+	evictedTotal *prometheus.CounterVec
+	sizeBytes    prometheus.GaugeFunc
+}
+
+func newMetrics(r prometheus.Registerer, a *Alerts) *metrics {
+	m := &metrics{
+		evictedTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "alertmanager_alerts_evicted_total",
+			Help: "Number of alerts evicted from memory to stay within the limits, by whether they were resolved or firing.",
+		}, []string{"state"}),
+		sizeBytes: prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "alertmanager_alerts_size_bytes",
+			Help: "Estimated size of the alerts held in memory.",
+		}, func() float64 {
+			a.mtx.RLock()
+			defer a.mtx.RUnlock()
+			return float64(a.size)
+		}),
+	}
+	if r != nil {
+		r.MustRegister(m.evictedTotal, m.sizeBytes)
+	}
+	return m
+}
+
+// Options configures a new alert provider.
+type Options struct {
+	// The store the alerts are persisted in and restored from. If it is
+	// nil, the alerts are only held in memory.
+	Store Store
+	// The limits of the alerts held in memory.
+	Limits Limits
+	// How long resolved alerts are kept after they ended.
+	Retention time.Duration
+	// The interval at which resolved alerts past their retention are
+	// removed. Defaults to 30 minutes.
+	GCInterval time.Duration
+
+	Metrics prometheus.Registerer
 }
 
-// NewAlerts returns a new alert provider.
+// NewAlerts returns a new alert provider. If path is not empty, the alerts
+// are persisted in a DiskStore in that directory and are restored from it.
 func NewAlerts(path string) (*Alerts, error) {
+	if path == "" {
+		return New(Options{})
+	}
+	return New(Options{Store: NewDiskStore(path)})
+}
+
+// New returns a new alert provider with the given options.
+func New(o Options) (*Alerts, error) {
 	a := &Alerts{
-		alerts:    map[model.Fingerprint]*types.Alert{},
-		stopGC:    make(chan struct{}),
-		listeners: map[int]chan *types.Alert{},
-		next:      0,
+		alerts:     map[model.Fingerprint]*types.Alert{},
+		stopGC:     make(chan struct{}),
+		listeners:  map[int]chan *types.Alert{},
+		next:       0,
+		store:      o.Store,
+		limits:     o.Limits,
+		retention:  o.Retention,
+		gcInterval: o.GCInterval,
+	}
+	if a.gcInterval == 0 {
+		a.gcInterval = 30 * time.Minute
+	}
+	a.metrics = newMetrics(o.Metrics, a)
+
+	if a.store != nil {
+		alerts, err := a.store.Load()
+		if err != nil {
+			return nil, err
+		}
+		for _, alert := range alerts {
+			a.set(alert)
+		}
+		a.deleteFromStore(a.evict())
 	}
 	go a.runGC()
 
 	return a, nil
 }
 
+// set adds or replaces the alert and accounts for its size. The caller must
+// hold the lock.
+func (a *Alerts) set(alert *types.Alert) {
+	fp := alert.Fingerprint()
+	if old, ok := a.alerts[fp]; ok {
+		a.size -= alertSize(old)
+	}
+	a.alerts[fp] = alert
+	a.size += alertSize(alert)
+}
+
+// remove deletes the alert and accounts for its size. The caller must hold
+// the lock.
+func (a *Alerts) remove(fp model.Fingerprint) {
+	if old, ok := a.alerts[fp]; ok {
+		a.size -= alertSize(old)
+		delete(a.alerts, fp)
+	}
+}
+
+// deleteFromStore deletes the alerts from the store, if any. The caller must
+// hold the lock.
+func (a *Alerts) deleteFromStore(fps []model.Fingerprint) {
+	if a.store == nil || len(fps) == 0 {
+		return
+	}
+	if err := a.store.Delete(fps...); err != nil {
+		log.Errorf("Deleting alerts from store failed: %s", err)
+	}
+}
+
 func (a *Alerts) runGC() {
 	for {
 		select {
 		case <-a.stopGC:
 			return
-		case <-time.After(30 * time.Minute):
+		case <-time.After(a.gcInterval):
 		}
 
-		a.mtx.Lock()
+		a.gc()
+	}
+}
 
-		for fp, alert := range a.alerts {
-			// As we don't persist alerts, we no longer consider them after
-			// they are resolved. Alerts waiting for resolved notifications are
-			// held in memory in aggregation groups redundantly.
-			if alert.EndsAt.Before(time.Now()) {
-				delete(a.alerts, fp)
-			}
-		}
+func (a *Alerts) gc() {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
 
-		a.mtx.Unlock()
+	var (
+		resolved []model.Fingerprint
+		cutoff   = time.Now().Add(-a.retention)
+	)
+	for fp, alert := range a.alerts {
+		// We no longer consider alerts once their retention after being
+		// resolved is over. Alerts waiting for resolved notifications are
+		// held in memory in aggregation groups redundantly.
+		if alert.EndsAt.Before(cutoff) {
+			a.remove(fp)
+			resolved = append(resolved, fp)
+		}
 	}
+	a.deleteFromStore(resolved)
 }
 
-// Close the alert provider.
+// Close the alert provider and its store if it can be closed.
 func (a *Alerts) Close() error {
 	close(a.stopGC)
+
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	if c, ok := a.store.(io.Closer); ok {
+		return c.Close()
+	}
 	return nil
 }
 
@@ -167,19 +296,58 @@ func (a *Alerts) Put(alerts ...*types.Alert) error {
 	a.mtx.Lock()
 	defer a.mtx.Unlock()
 
+	var (
+		merged = make([]*types.Alert, 0, len(alerts))
+		// The alerts merged so far, which later alerts of the same
+		// fingerprint are merged with.
+		latest = map[model.Fingerprint]*types.Alert{}
+	)
 	for _, alert := range alerts {
 		fp := alert.Fingerprint()
 
-		if old, ok := a.alerts[fp]; ok {
+		old, ok := latest[fp]
+		if !ok {
+			old, ok = a.alerts[fp]
+		}
+		if ok {
 			// Merge alerts if there is an overlap in activity range.
 			if (alert.EndsAt.After(old.StartsAt) && alert.EndsAt.Before(old.EndsAt)) ||
 				(alert.StartsAt.After(old.StartsAt) && alert.StartsAt.Before(old.EndsAt)) {
 				alert = old.Merge(alert)
+			} else if len(old.Sources) > 0 && len(alert.Sources) > 0 &&
+				!alert.StartsAt.After(old.EndsAt) && !old.StartsAt.After(alert.EndsAt) {
+				// Updates of alerts with known sources must not replace
+				// the state reported by other sources.
+				alert = old.Merge(alert)
 			}
 		}
 
-		a.alerts[fp] = alert
+		latest[fp] = alert
+		merged = append(merged, alert)
+	}
 
+	// Store the alerts before they become visible so none of them is lost
+	// on restart.
+	if a.store != nil {
+		if err := a.store.Set(merged...); err != nil {
+			return err
+		}
+	}
+	for _, alert := range merged {
+		a.set(alert)
+	}
+	evicted := a.evict()
+	a.deleteFromStore(evicted)
+
+	// Alerts that were evicted right away are not passed on.
+	skip := make(map[model.Fingerprint]struct{}, len(evicted))
+	for _, fp := range evicted {
+		skip[fp] = struct{}{}
+	}
+	for _, alert := range merged {
+		if _, ok := skip[alert.Fingerprint()]; ok {
+			continue
+		}
 		for _, ch := range a.listeners {
 			ch <- alert
 		}
@@ -15,6 +15,8 @@ package mem
 
 import (
 	"io/ioutil"
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 	"time"
@@ -127,3 +129,210 @@ func alertListEqual(a1, a2 []*types.Alert) bool {
 	}
 	return true
 }
+
+func TestAlertsPutSources(t *testing.T) {
+	dir, err := ioutil.TempDir("", "alerts_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	alerts, err := NewAlerts(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t0 := time.Now()
+	alert := func(source string, endsAt, updatedAt time.Time, timeout bool) *types.Alert {
+		return &types.Alert{
+			Alert: model.Alert{
+				Labels:   model.LabelSet{"bar": "foo"},
+				StartsAt: t0,
+				EndsAt:   endsAt,
+			},
+			UpdatedAt: updatedAt,
+			Timeout:   timeout,
+			Sources: []*types.AlertSource{{
+				Name:      source,
+				EndsAt:    endsAt,
+				Timeout:   timeout,
+				UpdatedAt: updatedAt,
+			}},
+		}
+	}
+
+	if err := alerts.Put(
+		alert("prom-a", t0.Add(5*time.Minute), t0, true),
+		alert("prom-b", t0.Add(6*time.Minute), t0.Add(time.Minute), true),
+		// One of the servers restarted and resolved the alert.
+		alert("prom-a", t0.Add(2*time.Minute), t0.Add(2*time.Minute), false),
+	); err != nil {
+		t.Fatalf("Insert failed: %s", err)
+	}
+
+	res, err := alerts.Get(model.LabelSet{"bar": "foo"}.Fingerprint())
+	if err != nil {
+		t.Fatalf("retrieval error: %s", err)
+	}
+	if len(res.Sources) != 2 {
+		t.Fatalf("expected 2 sources but got %v", res.Sources)
+	}
+	if !res.EndsAt.Equal(t0.Add(6*time.Minute)) || !res.Timeout {
+		t.Fatalf("expected alert to keep firing while a source reports it but got end %s", res.EndsAt)
+	}
+}
+
+func TestAlertsRestore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "alerts_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	alerts, err := NewAlerts(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t0 := time.Now()
+	insert := []*types.Alert{
+		{
+			Alert: model.Alert{
+				Labels:   model.LabelSet{"bar": "foo"},
+				StartsAt: t0,
+				EndsAt:   t0.Add(time.Hour),
+			},
+			UpdatedAt: t0,
+		}, {
+			Alert: model.Alert{
+				Labels:   model.LabelSet{"bar": "foo2"},
+				StartsAt: t0,
+				EndsAt:   t0.Add(time.Hour),
+			},
+			UpdatedAt: t0,
+			Timeout:   true,
+		},
+	}
+	if err := alerts.Put(insert...); err != nil {
+		t.Fatalf("Insert failed: %s", err)
+	}
+
+	// A crash leaves only the write-ahead log, possibly with a torn record.
+	f, err := os.OpenFile(filepath.Join(dir, "alerts.wal"), os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteString(`{"labels":{"bar":`); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	for _, step := range []string{"log", "snapshot"} {
+		restored, err := NewAlerts(dir)
+		if err != nil {
+			t.Fatalf("restoring from %s failed: %s", step, err)
+		}
+		for i, a := range insert {
+			res, err := restored.Get(a.Fingerprint())
+			if err != nil {
+				t.Fatalf("retrieval error after restoring from %s: %s", step, err)
+			}
+			if !alertsEqual(res, a) {
+				t.Errorf("Unexpected alert %d after restoring from %s", i, step)
+				t.Fatal(pretty.Compare(res, a))
+			}
+		}
+		if err := restored.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestAlertsLimits(t *testing.T) {
+	alerts, err := New(Options{
+		Limits: Limits{
+			MaxAlerts:     2,
+			SeverityLabel: "severity",
+			Severities:    []string{"info", "warning", "critical"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer alerts.Close()
+
+	t0 := time.Now()
+	alert := func(name, severity string, endsAt time.Time) *types.Alert {
+		return &types.Alert{
+			Alert: model.Alert{
+				Labels:   model.LabelSet{"alertname": model.LabelValue(name), "severity": model.LabelValue(severity)},
+				StartsAt: t0.Add(-time.Hour),
+				EndsAt:   endsAt,
+			},
+			UpdatedAt: t0,
+		}
+	}
+	var (
+		resolved = alert("resolved", "critical", t0.Add(-time.Minute))
+		critical = alert("critical", "critical", t0.Add(time.Hour))
+		info     = alert("info", "info", t0.Add(time.Hour))
+		warning  = alert("warning", "warning", t0.Add(time.Hour))
+	)
+
+	for _, c := range []struct {
+		put    *types.Alert
+		remain []*types.Alert
+	}{
+		{put: resolved, remain: []*types.Alert{resolved}},
+		{put: critical, remain: []*types.Alert{resolved, critical}},
+		// Resolved alerts are evicted first.
+		{put: info, remain: []*types.Alert{critical, info}},
+		// Then the firing alerts of the lowest severity.
+		{put: warning, remain: []*types.Alert{critical, warning}},
+		// New alerts of the lowest severity are not kept.
+		{put: info, remain: []*types.Alert{critical, warning}},
+	} {
+		if err := alerts.Put(c.put); err != nil {
+			t.Fatalf("Insert failed: %s", err)
+		}
+		if n := len(alerts.alerts); n != len(c.remain) {
+			t.Fatalf("expected %d alerts after inserting %s but got %d", len(c.remain), c.put.Name(), n)
+		}
+		for _, a := range c.remain {
+			if _, err := alerts.Get(a.Fingerprint()); err != nil {
+				t.Fatalf("expected alert %s to remain after inserting %s", a.Name(), c.put.Name())
+			}
+		}
+	}
+}
+
+func TestAlertsGCRetention(t *testing.T) {
+	alerts, err := New(Options{Retention: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer alerts.Close()
+
+	t0 := time.Now()
+	recent := &types.Alert{Alert: model.Alert{
+		Labels:   model.LabelSet{"alertname": "recent"},
+		StartsAt: t0.Add(-3 * time.Hour),
+		EndsAt:   t0.Add(-30 * time.Minute),
+	}}
+	old := &types.Alert{Alert: model.Alert{
+		Labels:   model.LabelSet{"alertname": "old"},
+		StartsAt: t0.Add(-3 * time.Hour),
+		EndsAt:   t0.Add(-2 * time.Hour),
+	}}
+	if err := alerts.Put(recent, old); err != nil {
+		t.Fatalf("Insert failed: %s", err)
+	}
+
+	alerts.gc()
+
+	if _, err := alerts.Get(recent.Fingerprint()); err != nil {
+		t.Fatalf("expected alert resolved within the retention to be kept: %s", err)
+	}
+	if _, err := alerts.Get(old.Fingerprint()); err == nil {
+		t.Fatal("expected alert resolved before the retention to be removed")
+	}
+}
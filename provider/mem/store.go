@@ -0,0 +1,236 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mem
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/prometheus/alertmanager/types"
+	"github.com/prometheus/common/model"
+)
+
+// Store persists alerts so they survive restarts, e.g. on local disk or in a
+// key/value store that is shared by several Alertmanager instances.
+type Store interface {
+	// Load returns all alerts in the store.
+	Load() ([]*types.Alert, error)
+	// Set creates or replaces the given alerts in the store.
+	Set(...*types.Alert) error
+	// Delete removes the alerts with the given fingerprints from the store.
+	Delete(...model.Fingerprint) error
+}
+
+// minCompactSize is the size the write-ahead log of a DiskStore must reach
+// before it is compacted.
+const minCompactSize = 1 << 20
+
+// DiskStore is a Store keeping alerts in a snapshot and a write-ahead log in
+// a local directory. The log is compacted into the snapshot once it grows
+// larger than the snapshot.
+type DiskStore struct {
+	mtx      sync.Mutex
+	snapf    string
+	walf     string
+	wal      *os.File
+	walSize  int64
+	snapSize int64
+}
+
+// walRecord is an entry of the write-ahead log, which either sets an alert
+// or deletes alerts.
+type walRecord struct {
+	Alert   *types.Alert        `json:"alert,omitempty"`
+	Deleted []model.Fingerprint `json:"deleted,omitempty"`
+}
+
+// NewDiskStore returns a new DiskStore keeping its files in the given
+// directory.
+func NewDiskStore(dir string) *DiskStore {
+	return &DiskStore{
+		snapf: filepath.Join(dir, "alerts"),
+		walf:  filepath.Join(dir, "alerts.wal"),
+	}
+}
+
+// Load implements the Store interface. It compacts the write-ahead log and
+// must be called before alerts are set or deleted.
+func (s *DiskStore) Load() ([]*types.Alert, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	alerts, err := s.compact()
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*types.Alert, 0, len(alerts))
+	for _, a := range alerts {
+		res = append(res, a)
+	}
+	return res, nil
+}
+
+// Set implements the Store interface.
+func (s *DiskStore) Set(alerts ...*types.Alert) error {
+	recs := make([]walRecord, 0, len(alerts))
+	for _, a := range alerts {
+		recs = append(recs, walRecord{Alert: a})
+	}
+	return s.log(recs...)
+}
+
+// Delete implements the Store interface.
+func (s *DiskStore) Delete(fps ...model.Fingerprint) error {
+	if len(fps) == 0 {
+		return nil
+	}
+	return s.log(walRecord{Deleted: fps})
+}
+
+// Close compacts the write-ahead log into the snapshot and closes it.
+func (s *DiskStore) Close() error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if s.wal == nil {
+		return nil
+	}
+	_, err := s.compact()
+	s.wal.Close()
+	s.wal = nil
+	return err
+}
+
+// log appends the records to the write-ahead log and compacts it if it grew
+// too large.
+func (s *DiskStore) log(recs ...walRecord) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if s.wal == nil {
+		return fmt.Errorf("alert store not loaded")
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, r := range recs {
+		if err := enc.Encode(r); err != nil {
+			return err
+		}
+	}
+	n, err := s.wal.Write(buf.Bytes())
+	s.walSize += int64(n)
+	if err != nil {
+		return err
+	}
+	if s.walSize > minCompactSize && s.walSize > s.snapSize {
+		_, err = s.compact()
+	}
+	return err
+}
+
+// compact reads the alerts from the snapshot and the write-ahead log, writes
+// them to a new snapshot and truncates the log. The caller must hold the lock.
+func (s *DiskStore) compact() (map[model.Fingerprint]*types.Alert, error) {
+	alerts := map[model.Fingerprint]*types.Alert{}
+
+	if err := readRecords(s.snapf, alerts, false); err != nil {
+		return nil, fmt.Errorf("loading alerts snapshot: %s", err)
+	}
+	if err := readRecords(s.walf, alerts, true); err != nil {
+		return nil, fmt.Errorf("replaying alerts log: %s", err)
+	}
+
+	tmp := fmt.Sprintf("%s.%x", s.snapf, uint64(rand.Int63()))
+	f, err := os.Create(tmp)
+	if err != nil {
+		return nil, err
+	}
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, a := range alerts {
+		if err := enc.Encode(walRecord{Alert: a}); err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if err := f.Close(); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(tmp, s.snapf); err != nil {
+		return nil, err
+	}
+	s.snapSize = fi.Size()
+
+	if s.wal != nil {
+		s.wal.Close()
+	}
+	if s.wal, err = os.Create(s.walf); err != nil {
+		return nil, err
+	}
+	s.walSize = 0
+
+	return alerts, nil
+}
+
+// readRecords applies the records in the given file to the alerts. A torn
+// record at the end of the write-ahead log is ignored.
+func readRecords(filename string, alerts map[model.Fingerprint]*types.Alert, wal bool) error {
+	f, err := os.Open(filename)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	dec := json.NewDecoder(bufio.NewReader(f))
+	for {
+		var r walRecord
+		if err := dec.Decode(&r); err == io.EOF {
+			return nil
+		} else if err != nil {
+			if wal {
+				return nil
+			}
+			return err
+		}
+		if r.Alert != nil {
+			alerts[r.Alert.Fingerprint()] = r.Alert
+		}
+		for _, fp := range r.Deleted {
+			delete(alerts, fp)
+		}
+	}
+}
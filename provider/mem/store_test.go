@@ -0,0 +1,72 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mem
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/prometheus/alertmanager/types"
+	"github.com/prometheus/common/model"
+)
+
+func TestDiskStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "alerts_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s := NewDiskStore(dir)
+	if err := s.Set(&types.Alert{}); err == nil {
+		t.Fatal("expected error setting alerts before loading the store")
+	}
+	if _, err := s.Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	t0 := time.Now()
+	newAlert := func(name string) *types.Alert {
+		return &types.Alert{
+			Alert: model.Alert{
+				Labels:   model.LabelSet{"alertname": model.LabelValue(name)},
+				StartsAt: t0,
+				EndsAt:   t0.Add(time.Hour),
+			},
+			UpdatedAt: t0,
+		}
+	}
+	a1, a2 := newAlert("a1"), newAlert("a2")
+	if err := s.Set(a1, a2); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Delete(a1.Fingerprint()); err != nil {
+		t.Fatal(err)
+	}
+
+	// Deleted alerts are not restored from the write-ahead log.
+	restored := NewDiskStore(dir)
+	alerts, err := restored.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(alerts) != 1 || !alertsEqual(alerts[0], a2) {
+		t.Fatalf("unexpected restored alerts %v", alerts)
+	}
+	if err := restored.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
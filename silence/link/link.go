@@ -0,0 +1,156 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package link generates and verifies signed links that create a silence
+// when confirmed. The parameters of the silence are signed with HMAC-SHA256 so
+// that links cannot be altered to silence other alerts.
+package link
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/common/model"
+)
+
+// Path is the API path silence links point to, relative to the external URL.
+const Path = "/api/v1/silences/link"
+
+// Query parameters of a silence link.
+const (
+	paramMatcher   = "matcher"
+	paramDuration  = "duration"
+	paramExpires   = "expires"
+	paramSignature = "signature"
+)
+
+// Signer creates and verifies silence links.
+type Signer struct {
+	secret   []byte
+	duration time.Duration
+	validity time.Duration
+
+	now func() time.Time
+}
+
+// NewSigner returns a new Signer. Links are signed with the secret, create
+// silences of the given duration, and are valid for the given time after
+// they were created.
+func NewSigner(secret string, duration, validity time.Duration) *Signer {
+	return &Signer{
+		secret:   []byte(secret),
+		duration: duration,
+		validity: validity,
+		now:      time.Now,
+	}
+}
+
+// Link holds the verified parameters of a silence link.
+type Link struct {
+	// The labels whose values the silence matches on equality.
+	Labels model.LabelSet
+	// The duration of the silence.
+	Duration time.Duration
+}
+
+// URL returns a signed link below the external URL that creates a silence
+// for alerts with the given labels.
+func (s *Signer) URL(externalURL *url.URL, lset model.LabelSet) (string, error) {
+	if len(lset) == 0 {
+		return "", errors.New("at least one label required")
+	}
+	matchers := make([]string, 0, len(lset))
+	for ln, lv := range lset {
+		matchers = append(matchers, fmt.Sprintf("%s=%s", ln, lv))
+	}
+	sort.Strings(matchers)
+
+	q := url.Values{
+		paramMatcher:  matchers,
+		paramDuration: {model.Duration(s.duration).String()},
+		paramExpires:  {strconv.FormatInt(s.now().Add(s.validity).Unix(), 10)},
+	}
+	q.Set(paramSignature, s.sign(q))
+
+	u := *externalURL
+	u.Path = path.Join(u.Path, Path)
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// Verify checks the signature and expiry of a link's query parameters and
+// returns the link they describe.
+func (s *Signer) Verify(q url.Values) (*Link, error) {
+	sig, err := hex.DecodeString(q.Get(paramSignature))
+	if err != nil {
+		return nil, errors.New("invalid signature")
+	}
+	signed := url.Values{}
+	for k, v := range q {
+		if k != paramSignature {
+			signed[k] = v
+		}
+	}
+	expected, err := hex.DecodeString(s.sign(signed))
+	if err != nil {
+		return nil, err
+	}
+	if !hmac.Equal(sig, expected) {
+		return nil, errors.New("invalid signature")
+	}
+
+	expires, err := strconv.ParseInt(q.Get(paramExpires), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expiry time: %s", err)
+	}
+	if s.now().After(time.Unix(expires, 0)) {
+		return nil, errors.New("link expired")
+	}
+	d, err := model.ParseDuration(q.Get(paramDuration))
+	if err != nil {
+		return nil, fmt.Errorf("invalid duration: %s", err)
+	}
+
+	l := &Link{
+		Labels:   model.LabelSet{},
+		Duration: time.Duration(d),
+	}
+	for _, m := range q[paramMatcher] {
+		parts := strings.SplitN(m, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid matcher %q", m)
+		}
+		l.Labels[model.LabelName(parts[0])] = model.LabelValue(parts[1])
+	}
+	if len(l.Labels) == 0 {
+		return nil, errors.New("at least one matcher required")
+	}
+	return l, nil
+}
+
+// sign returns the hex encoded signature of the query parameters.
+func (s *Signer) sign(q url.Values) string {
+	mac := hmac.New(sha256.New, s.secret)
+	// Encode sorts the parameters by key.
+	mac.Write([]byte(q.Encode()))
+	return hex.EncodeToString(mac.Sum(nil))
+}
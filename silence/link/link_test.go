@@ -0,0 +1,73 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package link
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignerURL(t *testing.T) {
+	now := time.Now()
+	s := NewSigner("secret", 2*time.Hour, time.Hour)
+	s.now = func() time.Time { return now }
+
+	ext, err := url.Parse("http://alertmanager.example.com/am")
+	require.NoError(t, err)
+
+	lset := model.LabelSet{"alertname": "HighLatency", "job": "api"}
+	res, err := s.URL(ext, lset)
+	require.NoError(t, err)
+
+	u, err := url.Parse(res)
+	require.NoError(t, err)
+	require.Equal(t, "alertmanager.example.com", u.Host)
+	require.Equal(t, "/am"+Path, u.Path)
+
+	l, err := s.Verify(u.Query())
+	require.NoError(t, err)
+	require.Equal(t, &Link{Labels: lset, Duration: 2 * time.Hour}, l)
+
+	// Links signed with another secret are rejected.
+	_, err = NewSigner("other", 2*time.Hour, time.Hour).Verify(u.Query())
+	require.Error(t, err)
+
+	// Altered links are rejected.
+	q := u.Query()
+	q.Set(paramDuration, "30d")
+	_, err = s.Verify(q)
+	require.Error(t, err)
+
+	q = u.Query()
+	q.Add(paramMatcher, "instance=a")
+	_, err = s.Verify(q)
+	require.Error(t, err)
+
+	q = u.Query()
+	q.Del(paramSignature)
+	_, err = s.Verify(q)
+	require.Error(t, err)
+
+	// Expired links are rejected.
+	s.now = func() time.Time { return now.Add(2 * time.Hour) }
+	_, err = s.Verify(u.Query())
+	require.Error(t, err)
+
+	_, err = s.URL(ext, model.LabelSet{})
+	require.Error(t, err)
+}
@@ -0,0 +1,163 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package silence
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/weaveworks/mesh"
+)
+
+// ErrNoQuorum is returned if a change to a silence was not acknowledged by a
+// quorum of peers in time. The change was still applied locally and is
+// gossiped to the other peers as usual.
+var ErrNoQuorum = errors.New("silence was not replicated to a quorum of peers")
+
+// quorumMsg is unicast between peers to replicate silences to a quorum of
+// them. A request carries the sender's version of the silences with the
+// given IDs, a response the receiver's version after merging them.
+type quorumMsg struct {
+	Response bool     `json:"response,omitempty"`
+	ID       uint64   `json:"id"`
+	IDs      []string `json:"ids"`
+	Silences []byte   `json:"silences,omitempty"`
+}
+
+func encodeQuorumMsg(m *quorumMsg, gd gossipData) ([]byte, error) {
+	m.Silences = bytes.Join(gd.Encode(), nil)
+	return json.Marshal(m)
+}
+
+// Sync replicates the silence with the given ID to a quorum of peers and
+// merges newer versions of it held by them, which repairs the silence on
+// both sides. It returns ErrNoQuorum if not enough peers responded in time.
+// It does nothing unless quorum writes are enabled.
+func (s *Silences) Sync(id string) error {
+	if s.peers == nil {
+		return nil
+	}
+	peers := s.peers()
+	// A quorum is a majority of all peers including this one.
+	need := (len(peers) + 1) / 2
+	if need == 0 {
+		return nil
+	}
+
+	s.mtx.Lock()
+	gd := gossipData{}
+	if msil, ok := s.st[id]; ok {
+		gd[id] = msil
+	}
+	s.mtx.Unlock()
+
+	s.qmtx.Lock()
+	s.qnext++
+	reqID := s.qnext
+	respc := make(chan mesh.PeerName, len(peers))
+	s.qpending[reqID] = respc
+	s.qmtx.Unlock()
+
+	defer func() {
+		s.qmtx.Lock()
+		delete(s.qpending, reqID)
+		s.qmtx.Unlock()
+	}()
+
+	msg, err := encodeQuorumMsg(&quorumMsg{ID: reqID, IDs: []string{id}}, gd)
+	if err != nil {
+		return err
+	}
+	for _, p := range peers {
+		if err := s.gossip.GossipUnicast(p, msg); err != nil {
+			s.logger.With("peer", p).With("err", err).Debug("replicating silence failed")
+		}
+	}
+
+	timeout := time.NewTimer(s.quorumTimeout)
+	defer timeout.Stop()
+
+	acked := map[mesh.PeerName]struct{}{}
+	for len(acked) < need {
+		select {
+		case p := <-respc:
+			acked[p] = struct{}{}
+		case <-timeout.C:
+			s.metrics.quorumFailuresTotal.Inc()
+			s.logger.With("silence_id", id).With("acked", len(acked)).With("required", need).Warn("silence was not replicated to a quorum of peers")
+			return ErrNoQuorum
+		}
+	}
+	return nil
+}
+
+// syncWrite syncs the silence after a successful change. It is deferred by
+// the methods changing silences before they lock the state.
+func (s *Silences) syncWrite(id *string, err *error) {
+	if *err == nil {
+		*err = s.Sync(*id)
+	}
+}
+
+func (g gossiper) onQuorumMsg(src mesh.PeerName, msg []byte) error {
+	var m quorumMsg
+	if err := json.Unmarshal(msg, &m); err != nil {
+		return err
+	}
+	gd, err := decodeGossipData(m.Silences)
+	if err != nil {
+		return err
+	}
+
+	g.mtx.Lock()
+	delta := g.st.mergeDelta(gd)
+	resp := gossipData{}
+	for _, id := range m.IDs {
+		if msil, ok := g.st[id]; ok {
+			resp[id] = msil
+		}
+	}
+	g.mtx.Unlock()
+
+	if len(delta) > 0 {
+		g.gossip.GossipBroadcast(delta)
+	}
+
+	if m.Response {
+		g.qmtx.Lock()
+		respc, ok := g.qpending[m.ID]
+		g.qmtx.Unlock()
+		if ok {
+			select {
+			case respc <- src:
+			default:
+			}
+		}
+		return nil
+	}
+
+	b, err := encodeQuorumMsg(&quorumMsg{Response: true, ID: m.ID, IDs: m.IDs}, resp)
+	if err != nil {
+		return err
+	}
+	// Respond outside of the receiving connection's goroutine.
+	go func() {
+		if err := g.gossip.GossipUnicast(src, b); err != nil {
+			g.logger.With("peer", src).With("err", err).Debug("responding to silence replication failed")
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,99 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package silence
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	pb "github.com/prometheus/alertmanager/silence/silencepb"
+	"github.com/stretchr/testify/require"
+	"github.com/weaveworks/mesh"
+)
+
+// fakeMesh delivers unicasts between silences of the listed peers.
+type fakeMesh map[mesh.PeerName]*Silences
+
+type fakeGossip struct {
+	self mesh.PeerName
+	mesh fakeMesh
+}
+
+func (g *fakeGossip) GossipBroadcast(mesh.GossipData) {}
+
+func (g *fakeGossip) GossipUnicast(dst mesh.PeerName, msg []byte) error {
+	s, ok := g.mesh[dst]
+	if !ok {
+		return fmt.Errorf("unknown peer %s", dst)
+	}
+	go gossiper{s}.OnGossipUnicast(g.self, msg)
+	return nil
+}
+
+func newQuorumSilences(t *testing.T, m fakeMesh, self mesh.PeerName, peers ...mesh.PeerName) *Silences {
+	s, err := New(Options{
+		Retention:     time.Hour,
+		Gossip:        func(mesh.Gossiper) mesh.Gossip { return &fakeGossip{self: self, mesh: m} },
+		Peers:         func() []mesh.PeerName { return peers },
+		QuorumTimeout: 50 * time.Millisecond,
+	})
+	require.NoError(t, err)
+	m[self] = s
+	return s
+}
+
+func TestSilencesQuorum(t *testing.T) {
+	m := fakeMesh{}
+	// Peer 3 is unreachable, which still leaves a quorum of 2 out of 3.
+	s1 := newQuorumSilences(t, m, 1, 2, 3)
+	s2 := newQuorumSilences(t, m, 2, 1, 3)
+
+	now := utcNow()
+	sil := &pb.Silence{
+		Matchers: []*pb.Matcher{{Name: "a", Pattern: "b"}},
+		StartsAt: mustTimeProto(now.Add(time.Minute)),
+		EndsAt:   mustTimeProto(now.Add(time.Hour)),
+	}
+	id, err := s1.Create(sil)
+	require.NoError(t, err)
+
+	sils, err := s2.Query(QIDs(id))
+	require.NoError(t, err)
+	require.Len(t, sils, 1)
+
+	// A change on the second peer that was not replicated is repaired
+	// when the first one syncs the silence.
+	s2.peers = nil
+	require.NoError(t, s2.Expire(id))
+	require.NoError(t, s1.Sync(id))
+
+	sils, err = s1.Query(QIDs(id), QState(StateExpired))
+	require.NoError(t, err)
+	require.Len(t, sils, 1)
+
+	// Without a quorum the change is applied locally but reported.
+	s3 := newQuorumSilences(t, fakeMesh{}, 1, 2, 3)
+	id, err = s3.Create(&pb.Silence{
+		Matchers: []*pb.Matcher{{Name: "a", Pattern: "b"}},
+		StartsAt: mustTimeProto(now.Add(time.Minute)),
+		EndsAt:   mustTimeProto(now.Add(time.Hour)),
+	})
+	require.Equal(t, ErrNoQuorum, err)
+	require.NotEqual(t, "", id)
+
+	sils, err = s3.Query(QIDs(id))
+	require.NoError(t, err)
+	require.Len(t, sils, 1)
+}
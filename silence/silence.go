@@ -67,8 +67,9 @@ func (c matcherCache) add(s *pb.Silence) (types.Matchers, error) {
 
 	for _, m := range s.Matchers {
 		mt = &types.Matcher{
-			Name:  m.Name,
-			Value: m.Pattern,
+			Name:         m.Name,
+			Value:        m.Pattern,
+			IsAnnotation: m.Annotation,
 		}
 		switch m.Type {
 		case pb.Matcher_EQUAL:
@@ -98,22 +99,42 @@ type Silences struct {
 
 	gossip mesh.Gossip // gossip channel for sharing silences
 
+	intervalc chan time.Duration // changes of the maintenance interval
+	store     Store              // optional persistent storage backend
+
 	// We store silences in a map of IDs for now. Currently, the memory
 	// state is equivalent to the mesh.GossipData representation.
 	// In the future we'll want support for efficient queries by time
 	// range and affected labels.
 	// Mutex also guards the matcherCache, which always need write lock access.
-	mtx sync.Mutex
-	st  gossipData
-	mc  matcherCache
+	mtx   sync.Mutex
+	st    gossipData
+	mc    matcherCache
+	usage map[string]Usage
+
+	// peers returns the other peers if changes are replicated to a
+	// quorum of them.
+	peers         func() []mesh.PeerName
+	quorumTimeout time.Duration
+	// qmtx guards the replication requests awaiting responses by ID.
+	qmtx     sync.Mutex
+	qnext    uint64
+	qpending map[uint64]chan mesh.PeerName
 }
 
 type metrics struct {
 	gcDuration       prometheus.Summary
+	gcRemovedTotal   prometheus.Counter
+	gcErrorsTotal    prometheus.Counter
+	retention        prometheus.Gauge
 	snapshotDuration prometheus.Summary
 	queriesTotal     prometheus.Counter
 	queryErrorsTotal prometheus.Counter
 	queryDuration    prometheus.Histogram
+	mutedAlerts      *prometheus.GaugeVec
+	lastMatch        *prometheus.GaugeVec
+
+	quorumFailuresTotal prometheus.Counter
 }
 
 func newMetrics(r prometheus.Registerer) *metrics {
@@ -123,6 +144,18 @@ func newMetrics(r prometheus.Registerer) *metrics {
 		Name: "alertmanager_silences_gc_duration_seconds",
 		Help: "Duration of the last silence garbage collection cycle.",
 	})
+	m.gcRemovedTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "alertmanager_silences_gc_removed_total",
+		Help: "How many expired silences were removed by garbage collection.",
+	})
+	m.gcErrorsTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "alertmanager_silences_gc_errors_total",
+		Help: "How many silence garbage collection cycles failed.",
+	})
+	m.retention = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "alertmanager_silences_retention_seconds",
+		Help: "How long expired silences are retained before they are garbage collected.",
+	})
 	m.snapshotDuration = prometheus.NewSummary(prometheus.SummaryOpts{
 		Name: "alertmanager_silences_snapshot_duration_seconds",
 		Help: "Duration of the last silence snapshot.",
@@ -139,14 +172,32 @@ func newMetrics(r prometheus.Registerer) *metrics {
 		Name: "alertmanager_silences_query_duration_seconds",
 		Help: "Duration of silence query evaluation.",
 	})
+	m.mutedAlerts = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "alertmanager_silence_muted_alerts",
+		Help: "How many alerts an active silence currently mutes.",
+	}, []string{"silence_id"})
+	m.lastMatch = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "alertmanager_silence_last_match_timestamp_seconds",
+		Help: "When an active silence last muted an alert, 0 if it did not since startup.",
+	}, []string{"silence_id"})
+	m.quorumFailuresTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "alertmanager_silences_quorum_failures_total",
+		Help: "How many silence changes were not replicated to a quorum of peers in time.",
+	})
 
 	if r != nil {
 		r.MustRegister(
 			m.gcDuration,
+			m.gcRemovedTotal,
+			m.gcErrorsTotal,
+			m.retention,
 			m.snapshotDuration,
 			m.queriesTotal,
 			m.queryErrorsTotal,
 			m.queryDuration,
+			m.mutedAlerts,
+			m.lastMatch,
+			m.quorumFailuresTotal,
 		)
 	}
 	return m
@@ -167,6 +218,17 @@ type Options struct {
 	// A function creating a mesh.Gossip on being called with a mesh.Gossiper.
 	Gossip func(g mesh.Gossiper) mesh.Gossip
 
+	// A storage backend silences are persisted to and loaded from. It is
+	// an alternative to snapshots and must not be set with them.
+	Store Store
+
+	// Peers returns the other peers of the mesh. If it is set, changes to
+	// silences are only acknowledged once a quorum of all peers stored
+	// them. All peers must support quorum writes.
+	Peers func() []mesh.PeerName
+	// The time to wait for a quorum of peers. Defaults to 10 seconds.
+	QuorumTimeout time.Duration
+
 	// A logger used by background processing.
 	Logger  log.Logger
 	Metrics prometheus.Registerer
@@ -176,6 +238,9 @@ func (o *Options) validate() error {
 	if o.SnapshotFile != "" && o.SnapshotReader != nil {
 		return fmt.Errorf("only one of SnapshotFile and SnapshotReader must be set")
 	}
+	if o.Store != nil && (o.SnapshotFile != "" || o.SnapshotReader != nil) {
+		return fmt.Errorf("snapshots must not be set along with a Store")
+	}
 	return nil
 }
 
@@ -201,7 +266,16 @@ func New(o Options) (*Silences, error) {
 		now:       utcNow,
 		gossip:    nopGossip{},
 		st:        gossipData{},
+		intervalc: make(chan time.Duration, 1),
+		peers:     o.Peers,
+		qpending:  map[uint64]chan mesh.PeerName{},
 	}
+	s.quorumTimeout = o.QuorumTimeout
+	if s.quorumTimeout == 0 {
+		s.quorumTimeout = 10 * time.Second
+	}
+	s.metrics.retention.Set(o.Retention.Seconds())
+
 	if o.Logger != nil {
 		s.logger = o.Logger
 	}
@@ -213,9 +287,68 @@ func New(o Options) (*Silences, error) {
 			return s, err
 		}
 	}
+	if o.Store != nil {
+		s.store = o.Store
+		if err := s.loadStore(); err != nil {
+			return s, err
+		}
+	}
 	return s, nil
 }
 
+// Store persists silences outside of the process, e.g. in a database that
+// is shared by several Alertmanager instances.
+type Store interface {
+	// Load returns all silences in the store.
+	Load() ([]*pb.MeshSilence, error)
+	// Set creates or replaces a silence in the store unless the stored
+	// version was updated more recently.
+	Set(*pb.MeshSilence) error
+	// Delete removes the silences with the given IDs from the store.
+	Delete(ids ...string) error
+}
+
+// loadStore merges the silences from the store into the state.
+func (s *Silences) loadStore() error {
+	sils, err := s.store.Load()
+	if err != nil {
+		return err
+	}
+	st := gossipData{}
+	for _, sil := range sils {
+		st[sil.Silence.Id] = sil
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if delta := s.st.mergeDelta(st); len(delta) > 0 {
+		s.gossip.GossipBroadcast(delta)
+	}
+	return nil
+}
+
+// SyncStore periodically loads silences that were added to the store by other
+// instances. It terminates on receiving from stopc.
+func (s *Silences) SyncStore(interval time.Duration, stopc <-chan struct{}) {
+	if s.store == nil {
+		return
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-stopc:
+			return
+		case <-t.C:
+			if err := s.loadStore(); err != nil {
+				s.logger.With("err", err).Error("loading silences from store failed")
+			}
+		}
+	}
+}
+
 type nopGossip struct{}
 
 func (nopGossip) GossipBroadcast(d mesh.GossipData)         {}
@@ -226,7 +359,7 @@ func (nopGossip) GossipUnicast(mesh.PeerName, []byte) error { return nil }
 // Terminates on receiving from stopc.
 func (s *Silences) Maintenance(interval time.Duration, snapf string, stopc <-chan struct{}) {
 	t := time.NewTicker(interval)
-	defer t.Stop()
+	defer func() { t.Stop() }()
 
 	f := func() error {
 		start := s.now()
@@ -255,6 +388,9 @@ Loop:
 		select {
 		case <-stopc:
 			break Loop
+		case d := <-s.intervalc:
+			t.Stop()
+			t = time.NewTicker(d)
 		case <-t.C:
 			if err := f(); err != nil {
 				s.logger.With("err", err).Error("running maintenance failed")
@@ -270,28 +406,83 @@ Loop:
 	}
 }
 
+// SetMaintenanceInterval changes the interval at which a running Maintenance
+// garbage collects the silence state.
+func (s *Silences) SetMaintenanceInterval(d time.Duration) {
+	for {
+		select {
+		case s.intervalc <- d:
+			return
+		default:
+		}
+		// Replace a change that was not picked up yet.
+		select {
+		case <-s.intervalc:
+		default:
+		}
+	}
+}
+
+// SetRetention changes how long silences are retained after they ended.
+// The new retention also applies to existing silences.
+func (s *Silences) SetRetention(d time.Duration) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	for id, msil := range s.st {
+		endsAt, err := ptypes.Timestamp(msil.Silence.EndsAt)
+		if err != nil {
+			return err
+		}
+		expiresAt, err := ptypes.TimestampProto(endsAt.Add(d))
+		if err != nil {
+			return err
+		}
+		s.st[id] = &pb.MeshSilence{
+			Silence:   msil.Silence,
+			ExpiresAt: expiresAt,
+		}
+	}
+	s.retention = d
+	s.metrics.retention.Set(d.Seconds())
+
+	return nil
+}
+
 // GC runs a garbage collection that removes silences that have ended longer
 // than the configured retention time ago.
-func (s *Silences) GC() (int, error) {
+func (s *Silences) GC() (n int, err error) {
 	start := time.Now()
-	defer func() { s.metrics.gcDuration.Observe(time.Since(start).Seconds()) }()
+	defer func() {
+		s.metrics.gcDuration.Observe(time.Since(start).Seconds())
+		s.metrics.gcRemovedTotal.Add(float64(n))
+		if err != nil {
+			s.metrics.gcErrorsTotal.Inc()
+		}
+	}()
 
 	now, err := s.nowProto()
 	if err != nil {
 		return 0, err
 	}
-	var n int
 
 	s.mtx.Lock()
 	defer s.mtx.Unlock()
 
+	var ids []string
 	for id, sil := range s.st {
 		if !protoBefore(now, sil.ExpiresAt) {
 			delete(s.st, id)
 			delete(s.mc, sil.Silence)
+			ids = append(ids, id)
 			n++
 		}
 	}
+	if s.store != nil && len(ids) > 0 {
+		if err := s.store.Delete(ids...); err != nil {
+			return n, err
+		}
+	}
 	return n, nil
 }
 
@@ -328,8 +519,8 @@ func validateSilence(s *pb.Silence) error {
 	if s.Id == "" {
 		return errors.New("ID missing")
 	}
-	if len(s.Matchers) == 0 {
-		return errors.New("at least one matcher required")
+	if len(s.Matchers) == 0 && s.GroupKey == "" {
+		return errors.New("at least one matcher or a group key required")
 	}
 	for i, m := range s.Matchers {
 		if err := validateMatcher(m); err != nil {
@@ -381,6 +572,11 @@ func (s *Silences) setSilence(sil *pb.Silence) error {
 		Silence:   sil,
 		ExpiresAt: expiresAt,
 	}
+	if s.store != nil {
+		if err := s.store.Set(msil); err != nil {
+			return err
+		}
+	}
 	st := gossipData{sil.Id: msil}
 
 	s.st.Merge(st)
@@ -396,6 +592,8 @@ func (s *Silences) nowProto() (*timestamp.Timestamp, error) {
 
 // Create adds a new silence and returns its ID.
 func (s *Silences) Create(sil *pb.Silence) (id string, err error) {
+	defer s.syncWrite(&id, &err)
+
 	if sil.Id != "" {
 		return "", fmt.Errorf("unexpected ID in new silence")
 	}
@@ -426,7 +624,9 @@ func (s *Silences) Create(sil *pb.Silence) (id string, err error) {
 }
 
 // Expire the silence with the given ID immediately.
-func (s *Silences) Expire(id string) error {
+func (s *Silences) Expire(id string) (err error) {
+	defer s.syncWrite(&id, &err)
+
 	s.mtx.Lock()
 	defer s.mtx.Unlock()
 
@@ -439,7 +639,12 @@ func (s *Silences) Expire(id string) error {
 	if err != nil {
 		return err
 	}
-	if sil, err = silenceSetTimeRange(sil, now, sil.StartsAt, now); err != nil {
+	// Pending silences never take effect.
+	start := sil.StartsAt
+	if getState(sil, now) == StatePending {
+		start = now
+	}
+	if sil, err = silenceSetTimeRange(sil, now, start, now); err != nil {
 		return err
 	}
 	return s.setSilence(sil)
@@ -447,7 +652,9 @@ func (s *Silences) Expire(id string) error {
 
 // SetTimeRange adjust the time range of a silence if allowed. If start or end
 // are zero times, the current value remains unmodified.
-func (s *Silences) SetTimeRange(id string, start, end time.Time) error {
+func (s *Silences) SetTimeRange(id string, start, end time.Time) (err error) {
+	defer s.syncWrite(&id, &err)
+
 	now, err := s.nowProto()
 	if err != nil {
 		return err
@@ -511,6 +718,80 @@ func silenceSetTimeRange(sil *pb.Silence, now, start, end *timestamp.Timestamp)
 	return sil, nil
 }
 
+// Extend moves the end of the silence with the given ID to a later time. The
+// silence keeps its ID and comments. If an author is given, a comment about the
+// extension is added.
+func (s *Silences) Extend(id string, end time.Time, author, comment string) (err error) {
+	defer s.syncWrite(&id, &err)
+
+	now, err := s.nowProto()
+	if err != nil {
+		return err
+	}
+	endp, err := ptypes.TimestampProto(end)
+	if err != nil {
+		return err
+	}
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	sil, ok := s.getSilence(id)
+	if !ok {
+		return ErrNotFound
+	}
+	if !protoBefore(sil.EndsAt, endp) {
+		return errors.New("extended end time must be after the current end time")
+	}
+	if sil, err = silenceSetTimeRange(sil, now, sil.StartsAt, endp); err != nil {
+		return err
+	}
+	if author != "" {
+		sil.Comments = append(sil.Comments[:len(sil.Comments):len(sil.Comments)], &pb.Comment{
+			Author:    author,
+			Comment:   comment,
+			Timestamp: now,
+		})
+	}
+	return s.setSilence(sil)
+}
+
+// Approve the silence with the given ID on behalf of the given user, who must
+// not be the creator of the silence.
+func (s *Silences) Approve(id, approver string) (err error) {
+	defer s.syncWrite(&id, &err)
+
+	if approver == "" {
+		return errors.New("approver missing")
+	}
+	now, err := s.nowProto()
+	if err != nil {
+		return err
+	}
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	sil, ok := s.getSilence(id)
+	if !ok {
+		return ErrNotFound
+	}
+	switch {
+	case !sil.ApprovalRequired:
+		return errors.New("silence does not require approval")
+	case sil.ApprovedBy != "":
+		return fmt.Errorf("silence was already approved by %s", sil.ApprovedBy)
+	case getState(sil, now) == StateExpired:
+		return errors.New("expired silence must not be modified")
+	case len(sil.Comments) > 0 && sil.Comments[0].Author == approver:
+		return errors.New("silence must be approved by a user other than its creator")
+	}
+
+	sil = cloneSilence(sil)
+	sil.ApprovedBy = approver
+	sil.UpdatedAt = now
+
+	return s.setSilence(sil)
+}
+
 // AddComment adds a new comment to the silence with the given ID.
 func (s *Silences) AddComment(id string, author, comment string) error {
 	panic("not implemented")
@@ -520,8 +801,9 @@ func (s *Silences) AddComment(id string, author, comment string) error {
 type QueryParam func(*query) error
 
 type query struct {
-	ids     []string
-	filters []silenceFilter
+	ids      []string
+	groupKey string
+	filters  []silenceFilter
 }
 
 // silenceFilter is a function that returns true if a silence
@@ -547,15 +829,38 @@ func QTimeRange(start, end time.Time) QueryParam {
 	}
 }
 
+// QGroupKey configures a query to consider silences restricted to the
+// aggregation group with the given key when matching alerts.
+func QGroupKey(key string) QueryParam {
+	return func(q *query) error {
+		q.groupKey = key
+		return nil
+	}
+}
+
 // QMatches returns silences that match the given label set.
 func QMatches(set model.LabelSet) QueryParam {
+	return QMatchesAlert(set, nil)
+}
+
+// QMatchesAlert returns silences that match the given labels and
+// annotations of an alert. Silences restricted to an aggregation group
+// only match if the query is for the same group key.
+func QMatchesAlert(labels, annotations model.LabelSet) QueryParam {
 	return func(q *query) error {
 		f := func(sil *pb.Silence, s *Silences, _ *timestamp.Timestamp) (bool, error) {
+			if sil.GroupKey != "" && sil.GroupKey != q.groupKey {
+				return false, nil
+			}
+			// Silences awaiting approval do not take effect.
+			if sil.ApprovalRequired && sil.ApprovedBy == "" {
+				return false, nil
+			}
 			m, err := s.mc.Get(sil)
 			if err != nil {
 				return true, err
 			}
-			return m.Match(set), nil
+			return m.MatchAlert(labels, annotations), nil
 		}
 		q.filters = append(q.filters, f)
 		return nil
@@ -668,6 +973,67 @@ func (s *Silences) query(q *query, now *timestamp.Timestamp) ([]*pb.Silence, err
 	return resf, nil
 }
 
+// Usage describes how effective an active silence currently is.
+type Usage struct {
+	// The number of alerts the silence currently mutes.
+	MutedAlerts int
+	// The last time the silence muted any alert. It is the zero time if
+	// it did not mute anything since the process started.
+	LastMatched time.Time
+}
+
+// SetUsage records the number of alerts each active silence currently mutes.
+// Active silences missing from counts are considered to mute no alerts.
+func (s *Silences) SetUsage(counts map[string]int) error {
+	now := s.now()
+	nowpb, err := ptypes.TimestampProto(now)
+	if err != nil {
+		return err
+	}
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	usage := make(map[string]Usage, len(s.usage))
+	for id, sil := range s.st {
+		if getState(sil.Silence, nowpb) != StateActive {
+			continue
+		}
+		u := s.usage[id]
+		u.MutedAlerts = counts[id]
+		if u.MutedAlerts > 0 {
+			u.LastMatched = now
+		}
+		usage[id] = u
+
+		s.metrics.mutedAlerts.WithLabelValues(id).Set(float64(u.MutedAlerts))
+		if u.LastMatched.IsZero() {
+			s.metrics.lastMatch.WithLabelValues(id).Set(0)
+		} else {
+			s.metrics.lastMatch.WithLabelValues(id).Set(float64(u.LastMatched.UnixNano()) / 1e9)
+		}
+	}
+	for id := range s.usage {
+		if _, ok := usage[id]; !ok {
+			s.metrics.mutedAlerts.DeleteLabelValues(id)
+			s.metrics.lastMatch.DeleteLabelValues(id)
+		}
+	}
+	s.usage = usage
+
+	return nil
+}
+
+// Usage returns the last recorded usage of the silence with the given ID.
+// The second result is false if no usage was recorded for the silence, e.g.
+// because it is not active.
+func (s *Silences) Usage(id string) (Usage, bool) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	u, ok := s.usage[id]
+	return u, ok
+}
+
 // loadSnapshot loads a snapshot generated by Snapshot() into the state.
 // Any previous state is wiped.
 func (s *Silences) loadSnapshot(r io.Reader) error {
@@ -696,6 +1062,48 @@ func (s *Silences) loadSnapshot(r io.Reader) error {
 	return nil
 }
 
+// Restore merges the silences of a snapshot generated by Snapshot() into the
+// state. Existing silences are only replaced by more recently updated versions.
+// It returns the number of silences that were added or replaced.
+func (s *Silences) Restore(r io.Reader) (int, error) {
+	st := gossipData{}
+	for {
+		var sil pb.MeshSilence
+		if _, err := pbutil.ReadDelimited(r, &sil); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return 0, err
+		}
+		if sil.Silence == nil {
+			return 0, errors.New("silence missing")
+		}
+		if err := validateSilence(sil.Silence); err != nil {
+			return 0, fmt.Errorf("invalid silence %q: %s", sil.Silence.Id, err)
+		}
+		if _, err := ptypes.Timestamp(sil.ExpiresAt); err != nil {
+			return 0, fmt.Errorf("invalid expiry time of silence %q: %s", sil.Silence.Id, err)
+		}
+		st[sil.Silence.Id] = &sil
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	delta := s.st.mergeDelta(st)
+	if s.store != nil {
+		for _, sil := range delta {
+			if err := s.store.Set(sil); err != nil {
+				return 0, err
+			}
+		}
+	}
+	if len(delta) > 0 {
+		s.gossip.GossipBroadcast(delta)
+	}
+	return len(delta), nil
+}
+
 // Snapshot writes the full internal state into the writer and returns the number of bytes
 // written.
 func (s *Silences) Snapshot(w io.Writer) (int, error) {
@@ -756,9 +1164,9 @@ func (g gossiper) OnGossipBroadcast(src mesh.PeerName, msg []byte) (mesh.GossipD
 }
 
 // OnGossipUnicast implements the mesh.Gossiper interface.
-// It always panics.
+// Unicasts replicate silences to a quorum of peers.
 func (g gossiper) OnGossipUnicast(src mesh.PeerName, msg []byte) error {
-	panic("not implemented")
+	return g.onQuorumMsg(src, msg)
 }
 
 type gossipData map[string]*pb.MeshSilence
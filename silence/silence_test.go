@@ -52,6 +52,13 @@ func TestOptionsValidate(t *testing.T) {
 			},
 			err: "only one of SnapshotFile and SnapshotReader must be set",
 		},
+		{
+			options: &Options{
+				SnapshotFile: "test bkp",
+				Store:        &mockStore{},
+			},
+			err: "snapshots must not be set along with a Store",
+		},
 	}
 
 	for _, c := range cases {
@@ -97,6 +104,86 @@ func TestSilencesGC(t *testing.T) {
 	require.Equal(t, want, s.st)
 }
 
+func TestSilencesSetRetention(t *testing.T) {
+	s, err := New(Options{Retention: time.Hour})
+	require.NoError(t, err)
+
+	now := utcNow()
+	s.now = func() time.Time { return now }
+
+	id, err := s.Create(&pb.Silence{
+		Matchers: []*pb.Matcher{{Name: "a", Pattern: "b"}},
+		EndsAt:   mustTimeProto(now.Add(time.Minute)),
+	})
+	require.NoError(t, err)
+	require.Equal(t, mustTimeProto(now.Add(time.Minute+time.Hour)), s.st[id].ExpiresAt)
+
+	// Existing silences are retained for the new duration.
+	require.NoError(t, s.SetRetention(90*24*time.Hour))
+	require.Equal(t, mustTimeProto(now.Add(time.Minute+90*24*time.Hour)), s.st[id].ExpiresAt)
+
+	now = now.Add(2 * time.Hour)
+	n, err := s.GC()
+	require.NoError(t, err)
+	require.Equal(t, 0, n)
+}
+
+func TestSilencesRestore(t *testing.T) {
+	now := utcNow()
+
+	s1, err := New(Options{Retention: time.Hour})
+	require.NoError(t, err)
+	s1.now = func() time.Time { return now }
+
+	id, err := s1.Create(&pb.Silence{
+		Matchers: []*pb.Matcher{{Name: "a", Pattern: "b"}},
+		EndsAt:   mustTimeProto(now.Add(time.Hour)),
+	})
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	_, err = s1.Snapshot(&buf)
+	require.NoError(t, err)
+	snap := buf.Bytes()
+
+	s2, err := New(Options{Retention: time.Hour})
+	require.NoError(t, err)
+
+	n, err := s2.Restore(bytes.NewReader(snap))
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	require.Equal(t, s1.st, s2.st)
+
+	// Restoring the same state again does not change anything.
+	n, err = s2.Restore(bytes.NewReader(snap))
+	require.NoError(t, err)
+	require.Equal(t, 0, n)
+
+	// More recently updated silences are not replaced.
+	now = now.Add(time.Minute)
+	s2.now = func() time.Time { return now }
+	require.NoError(t, s2.Expire(id))
+
+	n, err = s2.Restore(bytes.NewReader(snap))
+	require.NoError(t, err)
+	require.Equal(t, 0, n)
+	sils, err := s2.Query(QIDs(id))
+	require.NoError(t, err)
+	require.Equal(t, mustTimeProto(now), sils[0].UpdatedAt)
+
+	// Invalid silences are rejected.
+	buf.Reset()
+	s3 := &Silences{st: gossipData{"invalid": {
+		Silence:   &pb.Silence{Id: "invalid"},
+		ExpiresAt: mustTimeProto(now),
+	}}, metrics: newMetrics(nil)}
+	_, err = s3.Snapshot(&buf)
+	require.NoError(t, err)
+
+	_, err = s2.Restore(&buf)
+	require.Error(t, err)
+}
+
 func TestSilencesSnapshot(t *testing.T) {
 	// Check whether storing and loading the snapshot is symmetric.
 	now := utcNow()
@@ -205,6 +292,81 @@ func TestSilencesSetSilence(t *testing.T) {
 	require.Equal(t, want, s.st, "Unexpected silence state")
 }
 
+type mockStore struct {
+	sils map[string]*pb.MeshSilence
+}
+
+func (s *mockStore) Load() ([]*pb.MeshSilence, error) {
+	var res []*pb.MeshSilence
+	for _, sil := range s.sils {
+		res = append(res, sil)
+	}
+	return res, nil
+}
+
+func (s *mockStore) Set(sil *pb.MeshSilence) error {
+	s.sils[sil.Silence.Id] = sil
+	return nil
+}
+
+func (s *mockStore) Delete(ids ...string) error {
+	for _, id := range ids {
+		delete(s.sils, id)
+	}
+	return nil
+}
+
+func TestSilencesStore(t *testing.T) {
+	now := utcNow()
+
+	store := &mockStore{sils: map[string]*pb.MeshSilence{
+		"1": {
+			Silence: &pb.Silence{
+				Id:        "1",
+				EndsAt:    mustTimeProto(now.Add(-time.Hour)),
+				UpdatedAt: mustTimeProto(now.Add(-time.Hour)),
+			},
+			ExpiresAt: mustTimeProto(now.Add(-time.Minute)),
+		},
+	}}
+
+	s, err := New(Options{
+		Retention: time.Hour,
+		Store:     store,
+	})
+	require.NoError(t, err)
+	require.Equal(t, gossipData(store.sils), s.st, "state not loaded from store")
+
+	s.now = func() time.Time { return now }
+
+	// New silences must be written to the store.
+	id, err := s.Create(&pb.Silence{
+		Matchers: []*pb.Matcher{{Name: "a", Pattern: "b"}},
+		EndsAt:   mustTimeProto(now.Add(time.Minute)),
+	})
+	require.NoError(t, err)
+	require.Contains(t, store.sils, id)
+	require.Equal(t, s.st[id], store.sils[id])
+
+	// Garbage collected silences must be removed from the store.
+	n, err := s.GC()
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	require.NotContains(t, store.sils, "1")
+
+	// Silences added by other instances are picked up on reload.
+	store.sils["2"] = &pb.MeshSilence{
+		Silence: &pb.Silence{
+			Id:        "2",
+			EndsAt:    mustTimeProto(now.Add(time.Hour)),
+			UpdatedAt: mustTimeProto(now),
+		},
+		ExpiresAt: mustTimeProto(now.Add(2 * time.Hour)),
+	}
+	require.NoError(t, s.loadStore())
+	require.Equal(t, gossipData(store.sils), s.st)
+}
+
 func TestSilenceCreate(t *testing.T) {
 	s, err := New(Options{
 		Retention: time.Hour,
@@ -273,6 +435,92 @@ func TestSilenceCreate(t *testing.T) {
 
 }
 
+func TestSilenceExpirePending(t *testing.T) {
+	s, err := New(Options{})
+	require.NoError(t, err)
+
+	now := utcNow()
+	s.now = func() time.Time { return now }
+
+	id, err := s.Create(&pb.Silence{
+		Matchers: []*pb.Matcher{{Name: "a", Pattern: "b"}},
+		StartsAt: mustTimeProto(now.Add(time.Minute)),
+		EndsAt:   mustTimeProto(now.Add(time.Hour)),
+	})
+	require.NoError(t, err)
+
+	now = now.Add(time.Second)
+	require.NoError(t, s.Expire(id))
+
+	sil, ok := s.getSilence(id)
+	require.True(t, ok)
+	require.Equal(t, mustTimeProto(now), sil.StartsAt)
+	require.Equal(t, mustTimeProto(now), sil.EndsAt)
+}
+
+func TestSilenceApprove(t *testing.T) {
+	s, err := New(Options{})
+	require.NoError(t, err)
+
+	now := utcNow()
+	s.now = func() time.Time { return now }
+
+	id, err := s.Create(&pb.Silence{
+		Matchers:         []*pb.Matcher{{Name: "a", Pattern: "b"}},
+		EndsAt:           mustTimeProto(now.Add(time.Hour)),
+		Comments:         []*pb.Comment{{Author: "alice"}},
+		ApprovalRequired: true,
+	})
+	require.NoError(t, err)
+
+	// Silences awaiting approval do not match.
+	sils, err := s.Query(QMatches(model.LabelSet{"a": "b"}))
+	require.NoError(t, err)
+	require.Len(t, sils, 0)
+
+	require.Equal(t, ErrNotFound, s.Approve("unknown", "bob"))
+	require.Error(t, s.Approve(id, ""))
+	require.Error(t, s.Approve(id, "alice"), "creator must not approve")
+
+	now = now.Add(time.Second)
+	require.NoError(t, s.Approve(id, "bob"))
+	require.Error(t, s.Approve(id, "carol"), "silence must only be approved once")
+
+	sils, err = s.Query(QMatches(model.LabelSet{"a": "b"}))
+	require.NoError(t, err)
+	require.Len(t, sils, 1)
+	require.Equal(t, "bob", sils[0].ApprovedBy)
+}
+
+func TestSilenceExtend(t *testing.T) {
+	s, err := New(Options{})
+	require.NoError(t, err)
+
+	now := utcNow()
+	s.now = func() time.Time { return now }
+
+	id, err := s.Create(&pb.Silence{
+		Matchers: []*pb.Matcher{{Name: "a", Pattern: "b"}},
+		EndsAt:   mustTimeProto(now.Add(time.Hour)),
+		Comments: []*pb.Comment{{Author: "alice", Comment: "maintenance"}},
+	})
+	require.NoError(t, err)
+
+	now = now.Add(time.Minute)
+
+	require.Equal(t, ErrNotFound, s.Extend("unknown", now.Add(2*time.Hour), "", ""))
+	require.Error(t, s.Extend(id, now.Add(30*time.Minute), "", ""), "silence must not be shortened")
+	require.NoError(t, s.Extend(id, now.Add(2*time.Hour), "bob", "takes longer"))
+
+	sil, ok := s.getSilence(id)
+	require.True(t, ok)
+	require.Equal(t, mustTimeProto(now.Add(2*time.Hour)), sil.EndsAt)
+	require.Equal(t, mustTimeProto(now), sil.UpdatedAt)
+	require.Len(t, sil.Comments, 2)
+	require.Equal(t, "alice", sil.Comments[0].Author)
+	require.Equal(t, "bob", sil.Comments[1].Author)
+}
+
 func TestSilencesCreateFail(t *testing.T) {
 	s, err := New(Options{})
 	require.NoError(t, err)
@@ -415,6 +663,62 @@ func TestQMatches(t *testing.T) {
 	}
 }
 
+func TestQMatchesAlert(t *testing.T) {
+	qp := QMatchesAlert(
+		model.LabelSet{"job": "test", "customer": "label"},
+		model.LabelSet{"customer": "acme", "summary": "disk full"},
+	)
+
+	q := &query{}
+	qp(q)
+	f := q.filters[0]
+
+	cases := []struct {
+		sil  *pb.Silence
+		drop bool
+	}{
+		{
+			sil: &pb.Silence{
+				Matchers: []*pb.Matcher{
+					{Name: "customer", Pattern: "acme", Type: pb.Matcher_EQUAL, Annotation: true},
+				},
+			},
+			drop: true,
+		},
+		{
+			sil: &pb.Silence{
+				Matchers: []*pb.Matcher{
+					{Name: "customer", Pattern: "acme", Type: pb.Matcher_EQUAL},
+				},
+			},
+			drop: false,
+		},
+		{
+			sil: &pb.Silence{
+				Matchers: []*pb.Matcher{
+					{Name: "job", Pattern: "test", Type: pb.Matcher_EQUAL},
+					{Name: "summary", Pattern: "disk.*", Type: pb.Matcher_REGEXP, Annotation: true},
+				},
+			},
+			drop: true,
+		},
+		{
+			sil: &pb.Silence{
+				Matchers: []*pb.Matcher{
+					{Name: "job", Pattern: "test", Type: pb.Matcher_EQUAL},
+					{Name: "customer", Pattern: "other", Type: pb.Matcher_EQUAL, Annotation: true},
+				},
+			},
+			drop: false,
+		},
+	}
+	for _, c := range cases {
+		drop, err := f(c.sil, &Silences{mc: matcherCache{}}, nil)
+		require.NoError(t, err)
+		require.Equal(t, c.drop, drop, "unexpected filter result")
+	}
+}
+
 func TestSilencesQuery(t *testing.T) {
 	s, err := New(Options{})
 	require.NoError(t, err)
@@ -723,7 +1027,17 @@ func TestValidateSilence(t *testing.T) {
 				EndsAt:    validTimestamp,
 				UpdatedAt: validTimestamp,
 			},
-			err: "at least one matcher required",
+			err: "at least one matcher or a group key required",
+		},
+		{
+			s: &pb.Silence{
+				Id:        "some_id",
+				GroupKey:  "42",
+				StartsAt:  validTimestamp,
+				EndsAt:    validTimestamp,
+				UpdatedAt: validTimestamp,
+			},
+			err: "",
 		},
 		{
 			s: &pb.Silence{
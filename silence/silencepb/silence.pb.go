@@ -63,6 +63,9 @@ type Matcher struct {
 	Name string `protobuf:"bytes,2,opt,name=name" json:"name,omitempty"`
 	// The pattern being checked according to the matcher's type.
 	Pattern string `protobuf:"bytes,3,opt,name=pattern" json:"pattern,omitempty"`
+	// Whether the pattern is checked against the annotation with the
+	// given name instead of the label.
+	Annotation bool `protobuf:"varint,4,opt,name=annotation" json:"annotation,omitempty"`
 }
 
 func (m *Matcher) Reset()                    { *m = Matcher{} }
@@ -102,8 +105,19 @@ type Silence struct {
 	EndsAt   *google_protobuf.Timestamp `protobuf:"bytes,4,opt,name=ends_at,json=endsAt" json:"ends_at,omitempty"`
 	// The last motification made to the silence.
 	UpdatedAt *google_protobuf.Timestamp `protobuf:"bytes,5,opt,name=updated_at,json=updatedAt" json:"updated_at,omitempty"`
+	// If set, the silence only affects alerts of the aggregation group
+	// with the given key. All alerts of the group are affected if no
+	// matchers are set.
+	GroupKey string `protobuf:"bytes,6,opt,name=group_key,json=groupKey" json:"group_key,omitempty"`
 	// A set of comments made on the silence.
 	Comments []*Comment `protobuf:"bytes,7,rep,name=comments" json:"comments,omitempty"`
+	// Whether the silence only takes effect after it was approved.
+	ApprovalRequired bool `protobuf:"varint,8,opt,name=approval_required,json=approvalRequired" json:"approval_required,omitempty"`
+	// The user who approved the silence.
+	ApprovedBy string `protobuf:"bytes,9,opt,name=approved_by,json=approvedBy" json:"approved_by,omitempty"`
+	// Soft silences do not suppress notifications but mark the alerts
+	// they match.
+	Soft bool `protobuf:"varint,10,opt,name=soft" json:"soft,omitempty"`
 }
 
 func (m *Silence) Reset()                    { *m = Silence{} }
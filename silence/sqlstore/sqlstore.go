@@ -0,0 +1,160 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package sqlstore implements a silence.Store backed by a PostgreSQL or
+// MySQL database. The drivers have to be registered with database/sql by
+// importing them.
+package sqlstore
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+
+	pb "github.com/prometheus/alertmanager/silence/silencepb"
+)
+
+// dialect holds the statements that differ between the supported databases.
+type dialect struct {
+	createTable string
+	upsert      string
+	placeholder func(i int) string
+}
+
+var dialects = map[string]dialect{
+	"postgres": {
+		createTable: `CREATE TABLE IF NOT EXISTS silences (
+			id         VARCHAR(64) PRIMARY KEY,
+			data       BYTEA NOT NULL,
+			updated_at BIGINT NOT NULL,
+			expires_at BIGINT NOT NULL
+		)`,
+		upsert: `INSERT INTO silences (id, data, updated_at, expires_at) VALUES ($1, $2, $3, $4)
+			ON CONFLICT (id) DO UPDATE SET
+				data = EXCLUDED.data,
+				updated_at = EXCLUDED.updated_at,
+				expires_at = EXCLUDED.expires_at
+			WHERE silences.updated_at < EXCLUDED.updated_at`,
+		placeholder: func(i int) string { return fmt.Sprintf("$%d", i) },
+	},
+	"mysql": {
+		createTable: `CREATE TABLE IF NOT EXISTS silences (
+			id         VARCHAR(64) PRIMARY KEY,
+			data       LONGBLOB NOT NULL,
+			updated_at BIGINT NOT NULL,
+			expires_at BIGINT NOT NULL
+		)`,
+		// Assignments are evaluated from left to right, updated_at must
+		// therefore be updated last.
+		upsert: `INSERT INTO silences (id, data, updated_at, expires_at) VALUES (?, ?, ?, ?)
+			ON DUPLICATE KEY UPDATE
+				data = IF(VALUES(updated_at) > updated_at, VALUES(data), data),
+				expires_at = IF(VALUES(updated_at) > updated_at, VALUES(expires_at), expires_at),
+				updated_at = GREATEST(updated_at, VALUES(updated_at))`,
+		placeholder: func(int) string { return "?" },
+	},
+}
+
+// Store is a silence.Store that keeps silences in a SQL database.
+type Store struct {
+	db *sql.DB
+	d  dialect
+}
+
+// New opens the database with the given driver and data source name and
+// creates the silences table if it does not exist yet.
+// Supported drivers are "postgres" and "mysql".
+func New(driver, dsn string) (*Store, error) {
+	d, ok := dialects[driver]
+	if !ok {
+		return nil, fmt.Errorf("unsupported SQL driver %q", driver)
+	}
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.Exec(d.createTable); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("creating silences table: %s", err)
+	}
+	return &Store{db: db, d: d}, nil
+}
+
+// Close the underlying database.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Load implements the silence.Store interface.
+func (s *Store) Load() ([]*pb.MeshSilence, error) {
+	rows, err := s.db.Query(`SELECT data FROM silences`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res []*pb.MeshSilence
+	for rows.Next() {
+		var b []byte
+		if err := rows.Scan(&b); err != nil {
+			return nil, err
+		}
+		var sil pb.MeshSilence
+		if err := proto.Unmarshal(b, &sil); err != nil {
+			return nil, err
+		}
+		res = append(res, &sil)
+	}
+	return res, rows.Err()
+}
+
+// Set implements the silence.Store interface.
+func (s *Store) Set(sil *pb.MeshSilence) error {
+	b, err := proto.Marshal(sil)
+	if err != nil {
+		return err
+	}
+	updatedAt, err := ptypes.Timestamp(sil.Silence.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	expiresAt, err := ptypes.Timestamp(sil.ExpiresAt)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(s.d.upsert, sil.Silence.Id, b, updatedAt.UnixNano(), expiresAt.UnixNano())
+	return err
+}
+
+// Delete implements the silence.Store interface.
+func (s *Store) Delete(ids ...string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	var (
+		ps   = make([]string, 0, len(ids))
+		args = make([]interface{}, 0, len(ids))
+	)
+	for i, id := range ids {
+		ps = append(ps, s.d.placeholder(i+1))
+		args = append(args, id)
+	}
+	_, err := s.db.Exec(
+		fmt.Sprintf(`DELETE FROM silences WHERE id IN (%s)`, strings.Join(ps, ", ")),
+		args...,
+	)
+	return err
+}
@@ -0,0 +1,241 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package snooze implements snoozing of single alerts. Unlike silences,
+// snoozes do not select alerts by matchers but apply to exactly one alert
+// fingerprint. Snoozes are shared with other Alertmanager instances through
+// the mesh network.
+package snooze
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/prometheus/common/log"
+	"github.com/prometheus/common/model"
+	"github.com/weaveworks/mesh"
+
+	"github.com/prometheus/alertmanager/gossipstate"
+)
+
+// ErrNotFound is returned if a snooze was not found.
+var ErrNotFound = errors.New("snooze not found")
+
+// Snooze suppresses notifications about the alert with its fingerprint
+// until it ends.
+type Snooze struct {
+	Fingerprint string    `json:"fingerprint"`
+	CreatedBy   string    `json:"createdBy"`
+	Comment     string    `json:"comment,omitempty"`
+	EndsAt      time.Time `json:"endsAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// Active returns whether the snooze did not end at the given time.
+func (s *Snooze) Active(now time.Time) bool {
+	return s.EndsAt.After(now)
+}
+
+func validateSnooze(s *Snooze) error {
+	if _, err := model.ParseFingerprint(s.Fingerprint); err != nil {
+		return fmt.Errorf("invalid fingerprint %q: %s", s.Fingerprint, err)
+	}
+	if s.CreatedBy == "" {
+		return errors.New("creator missing")
+	}
+	if s.EndsAt.IsZero() || s.UpdatedAt.IsZero() {
+		return errors.New("timestamps missing")
+	}
+	return nil
+}
+
+// Snoozes holds the snoozes of alerts.
+type Snoozes struct {
+	retention time.Duration
+	now       func() time.Time
+	st        *gossipstate.State
+}
+
+// Options configures a new Snoozes object.
+type Options struct {
+	// A snapshot file from which the initial state is loaded.
+	SnapshotFile string
+
+	// Snoozes may be garbage collected the given duration after they ended.
+	Retention time.Duration
+
+	// A function creating a mesh.Gossip on being called with a mesh.Gossiper.
+	Gossip func(g mesh.Gossiper) mesh.Gossip
+
+	// A logger used by background processing.
+	Logger log.Logger
+}
+
+// New returns a new Snoozes object with the given configuration.
+func New(o Options) (*Snoozes, error) {
+	st, err := gossipstate.New(snoozeType{}, gossipstate.Options{
+		SnapshotFile: o.SnapshotFile,
+		Gossip:       o.Gossip,
+		Logger:       o.Logger,
+	})
+	if st == nil {
+		return nil, err
+	}
+	return &Snoozes{
+		retention: o.Retention,
+		now:       gossipstate.UTCNow,
+		st:        st,
+	}, err
+}
+
+// Maintenance garbage collects the snoozes at the given interval. If the
+// snapshot file is set, a snapshot is written to it afterwards.
+// Terminates on receiving from stopc.
+func (s *Snoozes) Maintenance(interval time.Duration, snapf string, stopc <-chan struct{}) {
+	s.st.Maintenance(interval, snapf, stopc, s.GC)
+}
+
+// GC removes snoozes that ended longer than the retention time ago.
+// It returns the number of removed snoozes.
+func (s *Snoozes) GC() (int, error) {
+	now := s.now()
+
+	return s.st.GC(func(e interface{}) bool {
+		return !e.(*Snooze).EndsAt.Add(s.retention).After(now)
+	}), nil
+}
+
+// Snooze suppresses notifications about the alert with the given fingerprint
+// until endsAt. An existing snooze of the alert is replaced.
+func (s *Snoozes) Snooze(fp model.Fingerprint, endsAt time.Time, createdBy, comment string) (*Snooze, error) {
+	now := s.now()
+
+	if !endsAt.After(now) {
+		return nil, errors.New("invalid snooze: end must be in the future")
+	}
+	sn := &Snooze{
+		Fingerprint: fp.String(),
+		CreatedBy:   createdBy,
+		Comment:     comment,
+		EndsAt:      endsAt,
+		UpdatedAt:   now,
+	}
+	if err := validateSnooze(sn); err != nil {
+		return nil, fmt.Errorf("invalid snooze: %s", err)
+	}
+
+	s.st.Lock()
+	defer s.st.Unlock()
+
+	s.st.Set(sn)
+	c := *sn
+	return &c, nil
+}
+
+// Unsnooze ends the snooze of the alert with the given fingerprint
+// immediately.
+func (s *Snoozes) Unsnooze(fp model.Fingerprint) error {
+	now := s.now()
+
+	s.st.Lock()
+	defer s.st.Unlock()
+
+	e, ok := s.st.Get(fp.String())
+	if !ok || !e.(*Snooze).Active(now) {
+		return ErrNotFound
+	}
+	c := *e.(*Snooze)
+	c.EndsAt = now
+	c.UpdatedAt = now
+
+	s.st.Set(&c)
+	return nil
+}
+
+type snoozeSlice []*Snooze
+
+func (s snoozeSlice) Len() int           { return len(s) }
+func (s snoozeSlice) Less(i, j int) bool { return s[i].EndsAt.Before(s[j].EndsAt) }
+func (s snoozeSlice) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+// List returns all active snoozes ordered by their end time.
+func (s *Snoozes) List() []*Snooze {
+	now := s.now()
+
+	s.st.RLock()
+	defer s.st.RUnlock()
+
+	res := snoozeSlice{}
+	s.st.Range(func(e interface{}) {
+		if sn := e.(*Snooze); sn.Active(now) {
+			c := *sn
+			res = append(res, &c)
+		}
+	})
+	sort.Sort(res)
+	return res
+}
+
+// Snoozed returns the active snooze of the alert with the given fingerprint.
+// It returns nil if the alert is not snoozed. It is safe to call on a nil
+// Snoozes.
+func (s *Snoozes) Snoozed(fp model.Fingerprint) *Snooze {
+	if s == nil {
+		return nil
+	}
+	now := s.now()
+
+	s.st.RLock()
+	defer s.st.RUnlock()
+
+	e, ok := s.st.Get(fp.String())
+	if !ok || !e.(*Snooze).Active(now) {
+		return nil
+	}
+	c := *e.(*Snooze)
+	return &c
+}
+
+// snoozeType implements the handling of snoozes by the state.
+type snoozeType struct{}
+
+func (snoozeType) Key(e interface{}) string {
+	return e.(*Snooze).Fingerprint
+}
+
+func (snoozeType) Decode(b []byte) (interface{}, error) {
+	var sn Snooze
+	if err := json.Unmarshal(b, &sn); err != nil {
+		return nil, err
+	}
+	if err := validateSnooze(&sn); err != nil {
+		return nil, fmt.Errorf("invalid snooze: %s", err)
+	}
+	return &sn, nil
+}
+
+func (snoozeType) Size(e interface{}) int {
+	sn := e.(*Snooze)
+	return len(sn.Comment) + len(sn.CreatedBy) + 128
+}
+
+// Merge keeps the more recently updated snooze.
+func (snoozeType) Merge(prev, e interface{}) interface{} {
+	if !prev.(*Snooze).UpdatedAt.Before(e.(*Snooze).UpdatedAt) {
+		return nil
+	}
+	return e
+}
@@ -0,0 +1,104 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snooze
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+
+	"github.com/prometheus/alertmanager/gossipstate"
+)
+
+func TestSnoozesSnoozeUnsnooze(t *testing.T) {
+	s, err := New(Options{Retention: time.Hour})
+	require.NoError(t, err)
+
+	now := gossipstate.UTCNow()
+	s.now = func() time.Time { return now }
+
+	fp := model.LabelSet{"alertname": "HighLatency"}.Fingerprint()
+
+	_, err = s.Snooze(fp, now, "me", "")
+	require.Error(t, err, "end must be in the future")
+	_, err = s.Snooze(fp, now.Add(time.Hour), "", "")
+	require.Error(t, err, "creator missing")
+
+	_, err = s.Snooze(fp, now.Add(time.Hour), "me", "known issue")
+	require.NoError(t, err)
+
+	sn := s.Snoozed(fp)
+	require.NotNil(t, sn)
+	require.Equal(t, "known issue", sn.Comment)
+	require.Nil(t, s.Snoozed(model.LabelSet{"alertname": "Other"}.Fingerprint()))
+	require.Len(t, s.List(), 1)
+
+	var nilSnoozes *Snoozes
+	require.Nil(t, nilSnoozes.Snoozed(fp))
+
+	// Snoozing again replaces the previous snooze.
+	_, err = s.Snooze(fp, now.Add(2*time.Hour), "me", "")
+	require.NoError(t, err)
+	require.Len(t, s.List(), 1)
+
+	now = now.Add(time.Hour)
+	require.NotNil(t, s.Snoozed(fp))
+
+	require.NoError(t, s.Unsnooze(fp))
+	require.Nil(t, s.Snoozed(fp))
+	require.Equal(t, ErrNotFound, s.Unsnooze(fp))
+
+	now = now.Add(59 * time.Minute)
+	n, err := s.GC()
+	require.NoError(t, err)
+	require.Equal(t, 0, n)
+
+	now = now.Add(time.Minute)
+	n, err = s.GC()
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+}
+
+func TestSnoozeTypeMerge(t *testing.T) {
+	now := gossipstate.UTCNow()
+
+	newSnooze := func(createdBy string, updated time.Time) *Snooze {
+		return &Snooze{
+			Fingerprint: "0000000000000001",
+			CreatedBy:   createdBy,
+			EndsAt:      now.Add(time.Hour),
+			UpdatedAt:   updated,
+		}
+	}
+	prev := newSnooze("alice", now)
+
+	for _, c := range []struct {
+		name   string
+		e      *Snooze
+		merged bool
+	}{
+		{name: "newer", e: newSnooze("carol", now.Add(time.Minute)), merged: true},
+		{name: "same update", e: newSnooze("carol", now)},
+		{name: "older", e: newSnooze("carol", now.Add(-time.Minute))},
+	} {
+		res := snoozeType{}.Merge(prev, c.e)
+		if !c.merged {
+			require.Nil(t, res, c.name)
+			continue
+		}
+		require.Equal(t, c.e, res, c.name)
+	}
+}
@@ -0,0 +1,258 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package template
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// toFloat64 converts numbers and strings holding numbers, such as label and
+// annotation values, to a float64.
+func toFloat64(v interface{}) (float64, error) {
+	switch v := v.(type) {
+	case float64:
+		return v, nil
+	case float32:
+		return float64(v), nil
+	case int:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case int32:
+		return float64(v), nil
+	case uint:
+		return float64(v), nil
+	case uint64:
+		return float64(v), nil
+	case uint32:
+		return float64(v), nil
+	case string:
+		return strconv.ParseFloat(strings.TrimSpace(v), 64)
+	}
+	return 0, fmt.Errorf("cannot convert %T to a number", v)
+}
+
+// humanize formats a number with a metric prefix, e.g. 1234567 as 1.235M.
+func humanize(v interface{}) (string, error) {
+	f, err := toFloat64(v)
+	if err != nil {
+		return "", err
+	}
+	return humanizeBase(f, 1000, []string{"k", "M", "G", "T", "P", "E", "Z", "Y"}, []string{"m", "u", "n", "p", "f", "a", "z", "y"}), nil
+}
+
+// humanize1024 formats a number with a binary prefix, e.g. 1048576 as 1Mi.
+func humanize1024(v interface{}) (string, error) {
+	f, err := toFloat64(v)
+	if err != nil {
+		return "", err
+	}
+	return humanizeBase(f, 1024, []string{"ki", "Mi", "Gi", "Ti", "Pi", "Ei", "Zi", "Yi"}, nil), nil
+}
+
+func humanizeBase(f, base float64, prefixes, smallPrefixes []string) string {
+	if f == 0 || math.IsNaN(f) || math.IsInf(f, 0) {
+		return fmt.Sprintf("%.4g", f)
+	}
+	prefix := ""
+	if math.Abs(f) >= 1 {
+		for _, p := range prefixes {
+			if math.Abs(f) < base {
+				break
+			}
+			f /= base
+			prefix = p
+		}
+	} else {
+		for _, p := range smallPrefixes {
+			if math.Abs(f) >= 1 {
+				break
+			}
+			f *= base
+			prefix = p
+		}
+	}
+	return fmt.Sprintf("%.4g%s", f, prefix)
+}
+
+// humanizePercentage formats a ratio as percentage, e.g. 0.1234 as 12.34%.
+func humanizePercentage(v interface{}) (string, error) {
+	f, err := toFloat64(v)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%.4g%%", f*100), nil
+}
+
+// humanizeDuration formats a duration, given as time.Duration or number of
+// seconds, with its two most significant units, e.g. 3h12m or 2d4h.
+// Durations below a minute keep fractions of seconds, e.g. 1.5s or 250ms.
+func humanizeDuration(v interface{}) (string, error) {
+	var d time.Duration
+	switch v := v.(type) {
+	case time.Duration:
+		d = v
+	default:
+		f, err := toFloat64(v)
+		if err != nil {
+			return "", err
+		}
+		d = time.Duration(f * float64(time.Second))
+	}
+
+	sign := ""
+	if d < 0 {
+		sign, d = "-", -d
+	}
+	if d < time.Minute {
+		switch {
+		case d == 0:
+			return "0s", nil
+		case d < time.Second:
+			return fmt.Sprintf("%s%.4gms", sign, float64(d)/float64(time.Millisecond)), nil
+		default:
+			return fmt.Sprintf("%s%.4gs", sign, d.Seconds()), nil
+		}
+	}
+
+	units := []struct {
+		name string
+		d    time.Duration
+	}{
+		{"d", 24 * time.Hour},
+		{"h", time.Hour},
+		{"m", time.Minute},
+		{"s", time.Second},
+	}
+	for k, u := range units {
+		if d < u.d {
+			continue
+		}
+		s := fmt.Sprintf("%d%s", d/u.d, u.name)
+		if k+1 < len(units) {
+			if n := (d % u.d) / units[k+1].d; n > 0 {
+				s += fmt.Sprintf("%d%s", n, units[k+1].name)
+			}
+		}
+		return sign + s, nil
+	}
+	return "", nil
+}
+
+// since returns the time passed since t, e.g. to show how long an alert is
+// firing for with {{ .StartsAt | since | humanizeDuration }}.
+func since(t time.Time) time.Duration {
+	return time.Since(t)
+}
+
+// dateFormat formats the time with the Go reference layout in the time zone
+// with the given IANA name, e.g. Europe/Berlin. An empty zone means UTC.
+// Its argument order allows {{ .StartsAt | dateFormat "Jan 2 15:04 MST" "Europe/Berlin" }}.
+func dateFormat(layout, zone string, t time.Time) (string, error) {
+	loc, err := time.LoadLocation(zone)
+	if err != nil {
+		return "", err
+	}
+	return t.In(loc).Format(layout), nil
+}
+
+// toJSON encodes the value as JSON, e.g. to embed annotations in a custom
+// webhook payload or Slack blocks with proper escaping.
+func toJSON(v interface{}) (string, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	// The output is escaped by the HTML templates where needed, while the
+	// text templates must not escape e.g. the ampersands of URLs.
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
+
+// toPrettyJSON encodes the value as JSON indented by two spaces.
+func toPrettyJSON(v interface{}) (string, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
+
+// fromJSON decodes a JSON string, e.g. held by an annotation, into maps,
+// slices, strings, float64 numbers and booleans.
+func fromJSON(s string) (interface{}, error) {
+	var v interface{}
+	if err := json.Unmarshal([]byte(s), &v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// reReplaceAll replaces all matches of the regular expression in the text,
+// expanding $1 and similar in the replacement.
+func reReplaceAll(pattern, repl, text string) (string, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return "", err
+	}
+	return re.ReplaceAllString(text, repl), nil
+}
+
+// match returns whether the text contains a match of the regular expression.
+func match(pattern, text string) (bool, error) {
+	return regexp.MatchString(pattern, text)
+}
+
+// stringSlice returns its arguments as a slice, e.g. for KV.Remove.
+func stringSlice(s ...string) []string {
+	return s
+}
+
+// filterLabels returns the pairs of the set whose names fully match the
+// regular expression, e.g. {{ .CommonLabels | filterLabels "job|instance" }}.
+func filterLabels(pattern string, kv KV) (KV, error) {
+	return selectLabels(pattern, kv, true)
+}
+
+// excludeLabels returns the pairs of the set whose names do not fully match
+// the regular expression, e.g. to strip noisy labels with
+// {{ .Labels | excludeLabels "pod_template_hash|__.*" }}.
+func excludeLabels(pattern string, kv KV) (KV, error) {
+	return selectLabels(pattern, kv, false)
+}
+
+func selectLabels(pattern string, kv KV, keep bool) (KV, error) {
+	re, err := regexp.Compile("^(?:" + pattern + ")$")
+	if err != nil {
+		return nil, err
+	}
+	res := KV{}
+	for k, v := range kv {
+		if re.MatchString(k) == keep {
+			res[k] = v
+		}
+	}
+	return res, nil
+}
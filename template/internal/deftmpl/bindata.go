@@ -68,7 +68,7 @@ func (fi bindataFileInfo) Sys() interface{} {
 	return nil
 }
 
-var _templateDefaultTmpl = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xec\x1b\x7b\x6f\xdb\x36\xfe\x7f\x7d\x8a\xdf\x34\x1c\xd6\x00\x96\xe5\xb4\x5b\xb1\x38\x76\x0e\xae\xa3\x34\xc2\x39\x72\x20\x2b\xed\x8a\x61\x18\x68\x89\xb6\xd9\x4a\xa4\x46\x52\x49\xbc\xcc\xdf\xfd\x40\x4a\x7e\xc8\x96\x53\x27\xe8\x12\xdf\x2d\x09\xda\x48\x14\x7f\xef\x27\x45\xea\xee\x0e\x22\x3c\x22\x14\x83\xf9\xfb\xef\x28\xc6\x5c\x26\x88\xa2\x31\xe6\x26\xcc\x66\x1d\x75\x7f\x91\xdf\xdf\xdd\x01\xa6\x11\xcc\x66\xc6\x56\x90\x2b\xbf\xa7\xa0\xee\xee\xa0\xee\xdc\x4a\xcc\x29\x8a\xaf\xfc\x1e\xcc\x66\xf6\xf7\xb6\x9e\x27\xfe\xcd\x71\x88\xc9\x35\xe6\x6d\x35\xc9\x2f\x6e\x72\x98\x02\x7b\x19\xbd\xc8\x86\x9f\x71\x28\x15\xda\x5f\x15\xc8\x40\x22\x99\x09\xf8\x0b\x24\xbb\x4a\xd3\x39\x28\x19\x01\xfe\x63\xf1\xd0\x1c\x11\x4e\xe8\x58\xc1\x34\x15\x8c\x96\x42\xd4\xcf\xf4\x28\xfc\x05\x31\xa6\xab\x14\x7f\x03\x35\xe9\x3d\x67\x59\xda\x43\x43\x1c\x8b\xfa\x80\x71\x89\xa3\x4b\x44\xb8\xa8\x7f\x40\x71\x86\x15\xc1\xcf\x8c\x50\x30\x41\x61\x85\x9c\xe4\x58\xc2\x2b\x85\xab\xde\x65\x49\xc2\x68\x0e\x7c\x50\x8c\xad\xe0\x3b\x80\xd9\xec\xd5\xdd\x1d\xdc\x10\x39\x29\x4f\xae\xfb\x38\x61\xd7\xb8\x4c\xdd\x43\x09\x16\x85\x1a\xab\xa8\x2f\x18\x3f\x58\x5c\x6d\xb1\x4d\x84\x45\xc8\x49\x2a\x09\xa3\xe6\x3d\x3a\x96\xf8\x56\xe6\x76\xfc\x3d\x26\x42\x16\x53\x39\xa2\x63\x0c\x75\x98\xcd\x72\xbe\x9a\xc6\x72\x70\x53\x4f\x4a\x2b\x96\x56\xa4\x62\x5f\xdd\xb5\x61\x21\x40\xc1\x58\x4e\xbc\x43\x29\x93\x48\xf1\x54\x42\xb9\x32\xfc\x38\xbc\x03\x96\xf1\x10\x37\x73\x63\x62\x8a\x39\x92\x8c\xe7\xee\x67\x54\x28\xaa\xa4\x03\x11\xa3\xf0\x4b\x3d\xc2\x23\x94\xc5\xb2\x2e\x89\x8c\x71\xa1\x05\x89\x93\x34\x46\xb2\xec\x8b\xf5\x6d\x2a\x2f\xe3\xc9\x84\x0a\x81\xa4\x0a\x55\x39\xd0\x76\xc4\x37\x42\x71\x3c\x44\xe1\x97\x0d\x7c\x95\xec\x2b\xa4\xf0\x17\x7c\x6d\x62\x4c\xe8\x97\x9d\x39\x48\x39\x56\xce\x62\xee\x36\x7b\x05\xff\xbd\x0a\xd0\x69\x63\x47\x0e\x48\xc8\x28\x4e\xd8\x67\xb2\x23\x0f\x6a\x7e\xc6\xe3\x5d\x39\xde\x10\xae\xe4\x26\x13\x92\x86\x13\x24\x97\x06\xe1\x2c\x79\xbc\x71\xd7\xb1\x25\x58\x08\x34\x7e\x80\xe3\x95\x78\x4b\x15\xb5\x28\x93\xd3\x05\xbe\xcd\xe8\x7f\x98\x33\x6f\x62\x0c\x63\x82\xa9\x7c\xbc\xc4\xdb\x30\x2e\xeb\xc6\xe3\x5c\x64\x13\x2f\xa1\x42\x22\x1a\x62\x51\x81\x77\x23\xdd\xdd\xa3\x55\x96\x8a\x31\xa6\x04\x3f\xde\x48\xf7\x21\xdb\xb4\x50\x51\x1d\xb6\x24\xc3\xca\x72\x60\xac\x15\xa3\x52\xb5\x3b\x80\x06\x58\xb3\x99\x91\x0f\x42\x3e\xa8\xd3\xee\xfd\x1a\x29\x97\x4c\x4d\xc4\x5a\x91\xa8\x82\x9e\x8f\x05\x8b\xaf\x71\xb4\x46\x71\x3e\xbc\x3b\xcd\x39\xc4\x06\x55\x6b\x17\x95\x0a\x5d\x05\x1e\xee\x4d\x25\xab\x5f\x93\x50\x32\xce\x52\xf1\x50\xb3\xaf\xe7\xdb\x87\x38\xf1\x26\xd1\x47\xa4\x97\x92\x18\x38\x41\x24\x5e\x6a\x66\xd9\x49\x3d\xd8\x73\xcb\x98\x26\x32\xd1\x09\xd5\x68\x7d\x77\xda\xef\x06\x9f\x2e\x1d\x50\x43\x70\x79\xf5\xae\xe7\x76\xc1\xb4\x6c\xfb\xe3\x9b\xae\x6d\x9f\x06\xa7\xf0\xcb\x79\x70\xd1\x83\xc3\x7a\x03\x02\x8e\xa8\x20\xca\xa7\x51\x6c\xdb\x8e\x67\x82\x39\x91\x32\x6d\xda\xf6\xcd\xcd\x4d\xfd\xe6\x4d\x9d\xf1\xb1\x1d\xf8\xf6\xad\xc2\x75\xa8\x80\x8b\x4b\x4b\xae\x40\xd6\x23\x19\x99\x27\x46\xeb\x3b\xcb\x32\x06\x72\x1a\x63\x40\x34\x02\x4d\x24\xc2\x9c\x28\xbf\x51\x6a\x03\x85\x5a\x34\x6d\x7b\x4c\xe4\x24\x1b\xd6\x43\x96\xd8\x4a\x86\x71\x46\x6d\x8d\x0e\x85\x39\x3e\x4b\x8b\x66\xcd\xd5\x21\x0c\xc3\x08\x26\x18\x2e\xdc\x00\x7a\x24\xc4\x54\x60\x78\x75\xe1\x06\x07\x86\xd1\x65\xe9\x94\x93\xf1\x44\xc2\xab\xf0\x00\x5e\x37\x0e\x7f\x84\x8b\x1c\xa3\x61\x5c\x62\x9e\x10\x21\x08\xa3\x40\x04\x4c\x30\xc7\xc3\x29\x8c\x39\xa2\x12\x47\x35\x18\x71\x8c\x81\x8d\x20\x9c\x20\x3e\xc6\x35\x90\x0c\x10\x9d\x42\x8a\xb9\x60\x14\xd8\x50\x22\x42\x55\x98\x21\x08\x59\x3a\x35\xd8\x08\xe4\x84\x08\x10\x6c\x24\x6f\x10\xcf\x25\x44\x42\xb0\x90\x20\x89\x23\x88\x58\x98\x25\x98\xe6\xf9\x01\x46\x24\xc6\x02\x5e\xc9\x09\x06\x73\x50\x40\x98\x07\x9a\x48\x84\x51\x6c\x10\x0a\xea\xd9\xfc\x91\x6e\x42\x59\x26\x81\x63\x21\x39\xd1\x5a\xa8\x01\xa1\x61\x9c\x45\x8a\x87\xf9\xe3\x98\x24\xa4\xa0\xa0\xc0\xb5\xe0\xc2\x90\x0c\x32\x81\x6b\x9a\xcf\x1a\x24\x2c\x22\x23\xf5\x17\x6b\xb1\xd2\x6c\x18\x13\x31\xa9\x41\x44\x14\xea\x61\x26\x71\x0d\x84\x1a\xd4\x7a\xac\x29\x39\x6c\xc6\x41\xe0\x38\x36\x42\x96\x12\x2c\x40\xcb\xba\xe4\x4e\xcf\x51\xac\xa7\x4a\xa1\xb2\x50\x91\x50\x23\x37\x13\x96\x94\x25\x21\xc2\x18\x65\x9c\x12\x31\xc1\x1a\x26\x62\x20\x98\xa6\xa8\xbc\x59\x8d\xa8\xe9\x23\x16\xc7\xec\x46\x89\x16\x32\x1a\x91\xa2\xef\xd4\x46\x46\x43\xd5\x7b\x87\x0b\xbb\x52\x26\x49\x98\xab\x5b\x1b\x20\x5d\x5a\xb5\x78\x24\x26\x28\x8e\x61\x88\x0b\x85\xe1\x08\x08\x05\xb4\x22\x0e\x57\xe4\x55\xe9\x91\x04\xc5\x90\x32\xae\xe9\xad\x8b\x59\x37\x8c\xe0\xdc\x81\x41\xff\x2c\xf8\xd8\xf1\x1d\x70\x07\x70\xe9\xf7\x3f\xb8\xa7\xce\x29\x98\x9d\x01\xb8\x03\xb3\x06\x1f\xdd\xe0\xbc\x7f\x15\xc0\xc7\x8e\xef\x77\xbc\xe0\x13\xf4\xcf\xa0\xe3\x7d\x82\xff\xb8\xde\x69\x0d\x9c\x5f\x2e\x7d\x67\x30\x80\xbe\x6f\xb8\x17\x97\x3d\xd7\x39\xad\x81\xeb\x75\x7b\x57\xa7\xae\xf7\x1e\xde\x5d\x05\xe0\xf5\x03\xe8\xb9\x17\x6e\xe0\x9c\x42\xd0\x07\x45\xb0\x40\xe5\x3a\x03\x85\xec\xc2\xf1\xbb\xe7\x1d\x2f\xe8\xbc\x73\x7b\x6e\xf0\xa9\x66\x9c\xb9\x81\xa7\x70\x9e\xf5\x7d\xe8\xc0\x65\xc7\x0f\xdc\xee\x55\xaf\xe3\xc3\xe5\x95\x7f\xd9\x1f\x38\xd0\xf1\x4e\xc1\xeb\x7b\xae\x77\xe6\xbb\xde\x7b\xe7\xc2\xf1\x82\x3a\xb8\x1e\x78\x7d\x70\x3e\x38\x5e\x00\x83\xf3\x4e\xaf\xa7\x48\x19\x9d\xab\xe0\xbc\xef\x2b\xfe\xa0\xdb\xbf\xfc\xe4\xbb\xef\xcf\x03\x38\xef\xf7\x4e\x1d\x7f\x00\xef\x1c\xe8\xb9\x9d\x77\x3d\x27\x27\xe5\x7d\x82\x6e\xaf\xe3\x5e\xd4\xe0\xb4\x73\xd1\x79\xef\x68\xa8\x7e\x70\xee\xf8\x86\x9a\x96\x73\x07\x1f\xcf\x1d\x35\xa4\xe8\x75\x3c\xe8\x74\x03\xb7\xef\x29\x31\xba\x7d\x2f\xf0\x3b\xdd\xa0\x06\x41\xdf\x0f\x16\xa0\x1f\xdd\x81\x53\x83\x8e\xef\x0e\x94\x42\xce\xfc\xfe\x45\xcd\x50\xea\xec\x9f\xa9\x29\xae\xa7\xe0\x3c\x27\xc7\xa2\x54\x0d\x25\x8b\xf4\x7d\x7d\x7f\x35\x70\x16\x08\xe1\xd4\xe9\xf4\x5c\xef\xfd\x40\x01\x2b\x11\xe7\x93\xeb\x86\x65\x9d\x18\x2d\x9d\x02\x6f\x93\x98\x8a\x76\x45\x62\x3b\x3c\x3a\x3a\xca\xf3\x99\xb9\xdb\x24\xa1\x92\x5b\xdb\x1c\x31\x2a\xad\x11\x4a\x48\x3c\x6d\xc2\x0f\xe7\x38\xbe\xc6\x92\x84\x08\x3c\x9c\xe1\x1f\x6a\xb0\x18\xa8\x41\x87\x13\x14\xd7\x40\x20\x2a\x2c\x81\x39\x19\x1d\xc3\x90\xdd\x5a\x82\xfc\xa9\x4a\x3e\x0c\x19\x8f\x30\xb7\x86\xec\xf6\x18\x34\x52\x41\xfe\xc4\x4d\x38\xfc\x31\xbd\x3d\x86\x04\xf1\x31\xa1\x4d\x68\x1c\xab\xdc\x3a\xc1\x28\x7a\x4e\xfa\x09\x96\x08\xd4\xc2\xa9\x6d\x5e\x13\x7c\xa3\xa2\xc8\x54\xd1\x2b\x31\x95\x6d\xf3\x86\x44\x72\xd2\x8e\xf0\x35\x09\xb1\xa5\x6f\x9e\x4f\x59\x60\xcf\xd9\x55\xc6\xb4\xf0\x1f\x19\xb9\x6e\x9b\xdd\x9c\x55\x2b\x98\xa6\x78\x85\x71\xd5\xf1\xd8\xca\xb8\xc7\xba\x12\x08\x2c\xdb\x57\xc1\x99\xf5\xf3\x33\xb3\xaf\x57\x69\xcf\x67\xee\xfb\x7a\x91\x96\xad\x99\x3b\x31\x8c\x96\xad\x9c\x52\x5d\x0c\x59\x34\x05\x22\x71\x22\x42\x96\xe2\xb6\x69\xea\x1b\x39\x55\xd7\x45\x44\x89\x70\x82\x13\xa4\x23\xca\x51\xd5\xfd\x62\xde\xbc\x3d\xa9\x90\xd6\x0d\x1e\x7e\x21\xd2\xca\x1f\x24\x8c\xc9\x89\x06\xca\x6b\x03\x41\x02\x47\xcb\x49\xca\x37\x34\xb4\x85\xa2\xcf\x99\x90\x4d\xa0\x8c\xe2\x63\x98\x60\x55\x99\x9a\x70\xd8\x68\xfc\xeb\x18\x62\x42\xb1\xb5\x18\xaa\xbf\xc5\xc9\x31\xe8\x08\xc8\x27\xc0\x77\x24\x51\xc1\x82\xa8\x3c\x86\x21\x0a\xbf\x8c\x39\xcb\x68\x64\x85\x2c\x66\xbc\x09\xdf\x8f\xde\xaa\xdf\x55\xf5\x43\x8a\xa2\x48\x73\xa5\xbc\x61\x38\xd6\x33\xdb\x66\x31\xd3\x54\xfa\x96\x68\xf8\xd4\xee\xb1\x22\xd2\x8e\x72\x54\xf2\x0e\xd0\x92\xfc\x19\xf3\x18\x80\xe2\xe0\x89\x33\xe9\x35\xe6\x0a\x49\x6c\xa1\x98\x8c\x69\x13\x24\x4b\xcb\x8a\xba\xd6\x0f\xda\xa6\x64\xa9\x79\xd2\xb2\x65\xb4\x64\x34\xcf\xac\xe6\xdb\x46\xe3\x89\x43\xa5\x92\xe9\x88\x88\x34\x46\xd3\x26\x0c\x63\x16\x7e\x29\xf9\x76\x82\x6e\xad\xc2\x49\xde\x36\x1a\xe9\x6d\xe9\x61\x18\x63\xc4\x15\x41\x39\x29\x8d\x6f\x0b\x94\x85\x72\x00\x65\x92\xad\x85\x44\x49\x5b\x5a\x51\x00\xad\x88\x5c\x3f\xb5\x5b\x95\xe5\x5d\x57\xce\xfd\x42\xcc\xf9\x56\x46\xd6\xc1\x5c\xd8\x59\x69\xc2\x84\x10\xc7\x71\x31\xbb\x6d\x36\xf2\x7b\x91\xa2\x70\x7e\xff\xa4\x82\x16\x0f\x39\x8a\x48\x26\x9a\xf0\x46\x8f\x55\x24\x80\xd1\xa8\x94\xc5\x72\xb0\x26\x1c\xa6\xb7\x20\x58\x4c\x22\xf8\x1e\x1f\xa9\xdf\x72\x62\x18\x8d\x56\x74\xb1\x0f\xd9\x61\xc9\xc9\xd3\x65\x89\xb7\x5b\x03\xae\xa4\x5d\x0d\x72\x53\x94\x9a\x9f\x1a\x8d\x63\xd0\x25\xaa\x98\x1f\x62\x2a\x31\xaf\xb2\x97\xfe\xd7\xd0\x46\xd9\xb4\x9b\xf3\xf6\xa7\xd7\xaf\xbb\xd5\x05\xe8\xb5\xf2\x6b\x13\x8a\x78\xcb\x09\xac\x5a\x2f\x87\xad\x8e\xc8\xf9\xcf\x72\xb3\x67\xb1\xcb\x03\xfa\x6d\x49\xe5\x2b\xab\x03\x38\x84\xd9\x4c\x2c\x5e\x78\xc0\x88\x71\x58\x6e\x48\x6c\xd9\x10\x82\xd9\x6c\x8d\x2a\xac\x6e\x4f\xb4\x4b\x9b\x13\x1b\xd3\x8a\x57\x2b\x25\xe3\x2f\x72\xf0\xe2\x9e\xbf\xb8\xe9\x2e\xc5\x6c\xe9\x3c\x87\xb9\xf3\xdc\xe7\x1b\x7b\x9f\xfb\xb6\xaa\x7d\xbf\x9c\x60\xdf\x5d\xa1\x01\x8d\x79\x2e\xb9\xcf\x1d\x0a\x31\x10\x4c\x38\x1e\xb5\xcd\x5d\xde\xda\x3e\xb1\x3f\xcc\x93\xe6\xd9\xd9\x59\x91\x7c\x23\x1c\x32\xae\xdf\xc9\xcd\x97\x07\xa5\x05\xc1\x6b\xb5\x1c\x28\xe5\xed\x21\x8b\xa3\xea\xc4\x1d\x66\x5c\x28\xec\x29\x23\xf9\xc0\xa2\xa1\x20\x54\x23\x2d\xfa\x8a\xb5\x04\xff\x93\x62\x4c\xe3\xd3\x2f\x51\x47\x8c\x27\x4d\x08\x51\x4a\x24\x8a\xc9\x9f\xb8\x32\xe9\xbf\xf9\xf1\x67\x1c\xa1\x8a\x7a\xbd\x31\xa3\x18\xd6\x5a\x6e\xe6\x85\x7c\x31\xb8\xe8\xde\xd2\xdb\xc2\xbc\x27\x1f\x08\xbe\x01\x42\xef\x7b\xe3\x3e\x5f\x46\xa2\x4a\x1f\x5e\x4b\xbc\xd5\xe9\x37\xff\xf9\xda\x1e\x4b\x45\x51\x78\x09\xd9\xbf\x27\x64\x85\xe4\x8c\x8e\x9f\x4f\xb5\xbf\x6e\x3f\x52\xf2\x5b\xb1\xc1\xd6\xb2\x73\x26\xbf\x81\xd7\x55\x34\x0c\xc5\x93\xf9\xb9\x89\xf5\x9d\xba\x17\x3f\xfc\x67\xf8\x61\xde\x9a\x2e\x5c\xad\x35\x7c\x3e\x33\x83\x5d\xad\xa3\xaf\x1c\x18\xda\x7e\xaa\xe7\x99\x85\xd9\x1e\x77\x50\x51\x0b\x96\x7b\xf5\x79\x25\x78\x76\xcf\x58\xe1\x68\x5f\xdc\xe3\xab\x1a\xfd\xea\x29\xb0\xff\x51\x67\x59\xed\x30\xd7\x8f\xa5\x3d\x53\x43\x39\x6f\xb7\x36\x7a\xca\x8c\x46\x98\xab\xee\xaf\xec\x4e\xf9\xc1\x3a\xd5\x44\xed\x5f\x8e\x79\x5c\x35\xdd\xb1\xbd\x5b\x3d\xd2\x52\x69\xde\x97\xae\x70\x6f\xaa\xf1\xde\x79\x26\x40\x6b\xb2\x87\x3c\xed\x9d\x9e\x1e\x12\xc1\xf7\x75\xc4\x2f\x81\xf5\xff\xd9\xe6\xae\x2e\xb7\x16\x47\x03\x97\x0b\xae\xf9\xd0\x33\x2c\xb9\x56\x0f\x2a\xbe\x78\xe3\x3f\xc3\x1b\x5f\x16\x5d\x2f\x8b\xae\x97\x45\xd7\xbe\x3b\xcb\xcb\xa2\x6b\x6f\x5a\xb6\x6d\x86\x6a\xd9\x7a\x3f\xee\xe4\x01\x5b\xa1\x0b\x90\xe5\xc8\x93\x9f\xc4\x28\x1d\x4d\x5a\x39\x69\xb2\x34\xf4\xd1\xd1\xd1\x7d\x1b\xdc\xe5\x9d\xdd\xcd\x2d\xc9\xfd\x68\x1a\xf6\xa9\x7d\x79\xca\xd6\xe5\xf5\xd6\xd6\xa5\x72\x13\xed\x6b\x26\x5f\xe9\x6d\xd6\xce\x35\x94\x4f\x61\xad\xa6\xab\xf2\x87\xb3\x4f\xe7\x10\xaf\x57\xb3\x95\x96\x68\xe7\x54\x85\xa9\x84\xe1\x74\xb7\x7d\xb8\xcd\xdc\xb1\x71\xde\x61\x3d\x33\xb4\xec\x88\x5c\x9f\xe4\xff\x1b\xe5\x34\xb1\x6f\x6d\xed\x96\xe3\x75\xb9\x88\xcb\xfc\xd5\xb2\x87\x2c\x9a\xaa\x91\x89\x4c\xe2\x13\xc3\xa8\xfe\x32\x37\xcd\xc4\x84\x5d\x63\xfe\x0d\x3e\x4c\xdd\x40\x55\xfe\xa0\xe9\xef\xf8\xec\xec\xdb\x7c\x75\xb6\xfb\x47\x67\xdf\xee\x9b\xb3\x15\x9a\x3b\x68\x72\xf9\x75\xe9\x03\xbe\xfb\xfa\x6f\x00\x00\x00\xff\xff\x07\xde\x61\xfd\x76\x3f\x00\x00")
+var _templateDefaultTmpl = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xed\x5c\x7b\x73\xda\x48\x12\xff\x5f\x9f\xa2\x57\xd9\xad\x8d\xab\x10\xd8\x79\xd5\xfa\x81\xaf\x08\x96\x63\x6a\x31\xb8\x00\x27\x9b\xda\xba\xda\x12\xd2\x00\x93\x08\x8d\x56\x33\x32\xf6\xe6\xf6\xbb\x5f\xf7\x48\x80\x04\x02\xe3\xd4\xc6\x26\x77\xc4\x79\x30\x8f\x7e\xce\x6f\xba\x7b\xa4\x21\x5f\xbe\x80\xc7\x06\x3c\x60\x60\xfe\xf1\x87\xe3\xb3\x48\x8d\x9d\xc0\x19\xb2\xc8\x84\xbf\xff\xae\x51\xfb\x32\x69\x7f\xf9\x02\x2c\xf0\xb0\xd3\xf8\xb2\x8a\xe4\xba\xd3\x24\x2a\x1c\x2f\xdb\xb7\x8a\x45\x81\xe3\x63\x17\xf6\x54\x9e\x55\xf4\x3c\xf9\xaf\x88\xb9\x8c\xdf\xb0\xa8\x4a\x93\x3a\x69\x23\xa1\x49\xb9\xe7\xd9\xcb\xb8\xff\x89\xb9\x8a\xd8\xfe\x8e\x03\x2a\x82\x72\x53\xb8\xc8\x0c\xca\x5d\xe5\xa8\x58\xc2\x7f\x40\x89\xeb\x30\x9c\x72\xe1\x03\x60\x7f\xce\x06\xcd\x01\x8f\x78\x30\x24\xf2\x23\x92\xa8\x0d\x92\xe5\x73\xdd\x8b\xa4\x3e\x0b\xb2\xc2\xff\x0d\x34\xe9\x5d\x24\xe2\xb0\xe9\xf4\x99\x2f\xcb\x5d\x11\x29\xe6\x5d\x39\x3c\x92\xe5\xf7\x8e\x1f\x33\x12\xf8\x49\xf0\x00\x4c\x20\xae\x90\x88\x1c\x2a\x78\x4e\xbc\xca\x75\x31\x1e\x8b\x20\x21\xde\x4b\xfb\x32\xfc\xf6\x90\xe4\x39\x92\x4c\xb8\x1a\xe5\x27\xa3\x33\xc6\xe2\x86\xe5\xa5\xb7\x9c\x31\x0a\x4c\x3c\x5a\x24\x7d\xa6\xf8\xde\xec\xd3\x8a\x65\xf2\x98\x74\x23\x1e\x2a\x2e\x02\x73\x8d\xbb\x15\xbb\x55\xc9\x92\xfe\xe1\x73\xa9\xd2\xa9\x91\x13\x0c\x51\xb3\xa4\x91\x59\x02\x33\x51\x53\x7b\xd7\x98\xcf\x5b\x76\x1d\x39\xca\xd2\xbe\x25\x8b\xa8\x55\x85\x99\x4d\xa9\xae\x33\xf5\xb3\x02\x6a\x41\x20\x70\x25\x51\xeb\x25\x29\x99\xa1\x7f\x44\x54\x57\xc4\x91\xcb\xb4\x94\x04\x05\x2c\x60\x91\xa3\x44\x94\x40\xd8\x28\xf0\x70\xce\x79\xd2\x77\xdc\xcf\x65\x6c\x39\xb1\xaf\xca\x8a\x2b\x9f\xa5\xee\x53\x6c\x1c\xfa\x8e\xca\xe3\xb9\xbc\x6a\xad\xf2\x7c\x62\x49\xdb\x68\x5c\xc4\x2a\xbf\x59\x37\xe4\x37\x70\x7c\xbf\x8f\x1d\x4b\xfc\x0a\xd5\x27\xa6\x88\xb8\xfb\x26\xfa\x3c\xf8\xbc\xb1\x06\x61\xc4\x08\x65\xe6\x66\xb3\x33\xfc\xd7\x3a\x40\x87\x9e\x0d\x35\xe0\xae\x08\x70\xb3\x7d\xe2\xe6\xe6\xf3\xe3\xc8\xdf\x54\xe3\xcd\x8d\x73\x85\x2f\x22\xf3\x9e\xb8\xe5\x11\xdc\x75\xf4\xf5\x25\x01\x78\x28\x84\x97\xc1\x22\xfe\xbb\x88\xc4\x11\x0f\xdd\x91\xa3\xe6\x6b\x1e\x89\xf1\xd7\xe3\x67\x91\x1b\x46\x24\x89\x24\x9b\x63\x3b\xa7\x5b\x48\xd2\xbc\x58\xdd\xcd\xf8\x2d\x47\xa6\x87\xed\x97\x65\x8e\xae\xcf\x59\xa0\xbe\xde\xe2\x55\x1c\xe7\xe9\xed\xeb\x50\xb8\xcc\x97\x07\x52\x39\x81\xcb\x64\x01\xdf\xa5\x50\xbc\xc6\xab\x22\x94\x43\x16\x70\xf6\xf5\x8b\xb4\x8e\xd9\xf2\x0a\xa5\x99\x6b\x45\x08\x2e\x4c\x55\xc6\x42\xa2\xcc\x65\xe2\x3d\xd8\x07\x2b\x99\x93\x8b\xfe\x7a\x0e\x9c\xcf\x73\xb8\x71\xaf\x93\xf2\x19\x5e\xf3\xb4\x32\x46\x16\xa8\xd0\x61\x52\xf8\x37\xcc\x5b\xaf\xc4\x74\xd6\xc3\xd4\x98\x52\x2d\x29\x62\x6d\xe2\x78\x39\x4b\x49\x0f\xc3\x5c\x0e\x1b\x37\xdc\xc5\x24\x86\xbc\x1f\x0a\x8e\xc5\xc0\xff\x10\xa8\x2f\x0b\xfd\x8a\x20\x94\x33\x83\x8d\x1d\xee\xcf\x3d\x33\x2f\x0b\x1f\x8c\xef\x3c\xa7\x91\x1a\xeb\xc8\x6e\x9c\xfc\x70\xd6\xae\xf7\x3e\x5e\xd9\x40\x5d\x70\x75\xfd\xb6\xd9\xa8\x83\x69\x55\x2a\x1f\x5e\xd6\x2b\x95\xb3\xde\x19\xfc\x76\xd1\xbb\x6c\xc2\x41\x79\x1f\x7a\x58\x84\x48\x4e\xc8\x77\xfc\x4a\xc5\x6e\x21\xc6\x47\x4a\x85\x47\x95\xca\x64\x32\x29\x4f\x5e\x96\x45\x34\xac\xf4\x3a\x95\x5b\xe2\x75\x40\xc4\xe9\x47\x4b\x65\x28\xcb\x9e\xf2\xcc\x53\x94\x6c\x59\x46\x57\xdd\x21\xda\x1c\xd4\x56\x0b\xf1\x58\xc4\x09\x37\xe4\x36\x20\xd6\x12\x79\x0f\xb1\x6a\x8c\xfb\x98\x36\xc6\x15\xb2\x61\x18\x07\x15\xcd\xce\x71\x13\x7e\x96\x36\xcd\x9a\xba\x43\xa2\x07\x7b\x23\x06\x97\x8d\x1e\x34\xb9\xcb\x02\xcc\x1f\xcf\xb1\xb1\x67\x18\x75\x11\xde\x45\x7c\x38\xc2\xad\xe0\xee\xc1\x8b\xfd\x83\x57\x70\x99\x70\x34\x8c\x2b\x16\x8d\xb9\x94\xc8\x11\xb8\x84\x11\x8b\x58\xff\x0e\x86\x28\x07\xf7\x77\x09\x15\x62\x0c\xc4\x00\x30\x27\x44\x43\x56\xc2\xe2\x1b\x95\xbe\x03\xac\xbf\x25\x12\x88\xbe\x72\x78\x40\x3b\xcf\x01\x17\x65\x18\x38\x53\x8d\x90\x8d\x14\x03\x35\x71\xa2\xc4\x42\x47\x4a\xe1\x72\xd4\xd0\x03\x4f\xb8\xf1\x18\x63\xab\x8e\x22\x30\xe0\x3e\xc6\x8d\xe7\x6a\xa4\xeb\xb1\x84\xc2\xdc\xd3\x42\x3c\xe6\xf8\x06\x46\x13\x1a\x9b\x0e\xe9\x32\x5a\xc4\x0a\x22\x26\x55\xc4\xb5\x17\x4a\xc0\x03\xd7\x8f\x3d\xd2\x61\x3a\xec\xf3\x31\x4f\x25\x10\xb9\x36\x5c\x1a\xc8\x14\xab\xab\x92\xd6\xb3\x04\x63\xe1\xf1\x01\xfd\xcb\xb4\x59\x61\xdc\xc7\x9d\x3c\x2a\x81\xc7\x89\x75\x3f\x56\xd8\x29\xa9\x53\xfb\xb1\x44\x76\x54\x44\x04\x92\xf9\xbe\x81\x1c\x38\xea\xad\x6d\x9d\x6b\xa7\xe7\x90\xea\x21\x39\x54\xa5\x2e\x92\xd4\x33\x19\xe1\xaa\xe6\x2c\xe1\xd2\x18\xc4\x51\x80\x22\x99\xa6\xf1\x04\xba\x4c\x4b\x24\x34\x53\x0f\x4d\x1f\x08\xdf\x17\x13\x32\x0d\x4b\x12\x4f\xa3\x48\x1e\x25\x8b\xec\xf4\xe9\xf4\xe0\xce\xd6\x15\x23\x33\xaa\x9a\xa8\x40\x0b\x10\xce\x57\x35\x1d\x92\x23\xac\x05\xa1\xcf\x52\x87\xa1\x5c\x74\xaf\x93\x31\x27\x22\xf1\x94\xa0\x14\x77\x7c\x08\x31\xc0\x93\xbc\x45\x33\xcb\x28\xff\xc2\x86\x6e\xfb\xbc\xf7\xa1\xd6\xb1\xa1\xd1\x85\xab\x4e\xfb\x7d\xe3\xcc\x3e\xc3\xe0\xd9\xc5\xb6\x59\x82\x0f\x8d\xde\x45\xfb\xba\x07\x38\xa3\x53\x6b\xf5\x3e\x42\xfb\x1c\x6a\xad\x8f\xf0\x6b\xa3\x75\x56\x02\xfb\xb7\xab\x8e\xdd\xed\x42\xbb\x63\x34\x2e\xaf\x9a\x0d\x1b\xfb\x1a\xad\x7a\xf3\xfa\xac\xd1\x7a\x07\x6f\x91\xae\xd5\x46\x08\x37\x10\xbb\xc8\xb4\xd7\x06\x12\x98\xb2\x6a\xd8\x5d\x62\x76\x69\x77\xea\x17\xd8\xac\xbd\x6d\x34\x1b\xbd\x8f\x25\xe3\xbc\xd1\x6b\x11\xcf\xf3\x76\x07\x6a\x70\x55\xeb\xf4\x1a\xf5\xeb\x66\xad\x83\x1b\xbb\x73\xd5\xee\xda\x28\xfe\x0c\xd9\xb6\x1a\xad\xf3\x0e\x4a\xb1\x2f\xed\x56\xaf\x8c\x52\xb1\x0f\xec\xf7\xd8\x80\xee\x45\xad\xd9\x24\x51\x46\xed\x1a\xb5\xef\x90\x7e\x50\x6f\x5f\x7d\xec\x34\xde\x5d\xf4\xe0\xa2\xdd\x3c\xb3\xb1\xf3\xad\x8d\x9a\xd5\xde\x36\xed\x44\x14\x1a\x55\x6f\xd6\x1a\x97\x25\x38\xab\x5d\xd6\xde\xd9\x9a\xaa\x8d\x5c\x3a\x06\x4d\x4b\xb4\x83\x0f\x17\x36\x75\x91\xbc\x1a\xfe\xae\xf7\x1a\xed\x16\x99\x51\x6f\xb7\x7a\x1d\x6c\x96\xd0\xca\x4e\x6f\x46\xfa\xa1\xd1\xb5\x4b\x50\xeb\x34\xba\xe4\x90\xf3\x4e\xfb\xb2\x64\x90\x3b\x91\xa2\xad\x99\x20\x5d\xcb\x4e\xb8\x90\xab\x21\xb7\x22\x38\x85\xda\xd7\x5d\x7b\xc6\x10\xce\xec\x5a\x13\x79\x75\x89\x98\x4c\x9c\x4e\x2e\x1b\x96\x85\x11\x49\x87\xc0\xdb\xb1\x1f\xc8\x6a\x41\x60\x3b\x38\x3c\x3c\x4c\xe2\x99\xb9\xd9\x24\x49\xc1\xad\x6a\x0e\x44\xa0\xac\x81\x33\xe6\xfe\xdd\x11\xfc\x7c\xc1\x30\x33\x22\x12\x1d\x68\xb1\x98\xfd\x5c\x82\x59\x07\x9a\x1a\x21\xe4\x10\xfe\x18\xdc\x2c\x3c\x01\xf1\xc1\x31\xf4\xc5\xad\x25\xf9\x5f\x08\xfe\x23\xfc\x1c\x61\x80\xb4\xb0\xeb\x18\x34\x53\x1c\x60\x47\x70\xf0\x2a\xc4\x8e\x31\x06\x26\x1e\x1c\xc1\xfe\x31\xc5\xd6\x11\x73\xbc\xa7\x94\x3f\x66\xca\x01\x3a\xc1\x55\x31\x29\xb2\x09\xed\x22\x93\x76\xaf\xc2\xa0\x57\x35\x27\xdc\x53\xa3\xaa\xc7\x30\x5f\x32\x4b\x37\x9e\xce\x59\x50\x99\xaa\x4b\x8b\x69\xb1\x3f\x63\x7e\x53\x35\xeb\x89\xaa\x56\xef\x2e\x64\x19\xc5\xa9\xe2\xa9\xd0\xe2\x1e\xeb\x4c\x20\x99\xaa\x5e\xf7\xce\xad\x5f\x9e\x58\x7d\x7d\x5c\x7c\xba\xe5\x5e\x57\x8b\x9c\x54\xb4\x72\xa7\x86\x71\x52\x21\x50\xd2\x87\xbe\xf0\xee\x80\x23\x89\xc4\x98\x8b\x1a\x9b\xba\xa1\xee\xe8\x73\xba\xa3\xa4\x3b\xc2\xac\xae\x77\x94\x4d\xd9\xfd\x72\x5a\xbc\x3d\xaa\x91\xd6\x84\xf5\x3f\x73\x14\xa4\x07\xc6\x42\x60\x4e\x21\xa2\x24\x37\x70\x47\x32\x6f\x3e\x89\xb0\xa1\xa9\x2d\xc7\xfb\x14\x4b\x75\x84\x19\x27\x60\xc7\x58\x4a\x50\x66\x42\x96\xfb\xfb\x3f\x1d\x63\x52\x0e\x98\x35\xeb\x2a\xbf\x61\xe3\x63\xd0\x3b\x20\x99\x00\x3f\xf0\x31\x6d\x16\x94\x80\x7a\xe2\xa9\x79\x18\x89\x38\xf0\x2c\x7d\x66\x3e\x82\x67\x83\x37\xf4\x93\x75\x3f\x84\x8e\xe7\x69\xad\x08\x0d\xfd\xa1\x9e\x59\x35\xd3\x99\x26\xf9\x5b\x39\xfd\xc7\x86\x47\xc6\xa4\x0d\xed\x28\xd4\x1d\xe0\x04\x0f\x25\x4f\x17\xc7\x00\x48\x83\x47\x8e\xa4\x37\x78\x36\x40\x26\xbe\x85\x10\x1b\xa2\x26\x4a\x84\x79\x47\xdd\xe8\x01\x8c\x46\x22\x34\x4f\x71\x83\x79\x73\x45\x93\xc8\x6a\xbe\xd9\xdf\x37\xb7\x40\x69\xac\x22\x31\x2a\xa0\xd8\xbe\x2f\xdc\xcf\x39\x6c\x8f\x9d\x5b\x2b\x05\x09\x2a\x1b\xde\xe6\x06\x5d\x9f\x39\x11\x09\x54\xa3\x5c\xff\xaa\x8d\x32\x73\x0e\x38\xb1\x12\x0b\x5b\x22\xe7\x2d\xed\x28\x74\x95\xc7\x6f\x1e\x1b\x56\x79\x7b\x17\x9d\xb3\xde\x88\xa9\xde\xb4\xc8\x7a\x33\xa7\xeb\x4c\x9e\xc0\xf4\x84\xd5\x78\x3a\xbb\x6a\xee\x27\x6d\x19\x3a\xee\xb4\xfd\xa8\x86\xa6\x83\x91\xe3\xf1\x58\x1e\xc1\x4b\xdd\x57\x10\x00\x06\x83\x5c\x14\x4b\xc8\x90\x09\x42\x41\x0a\x9f\x7b\xf0\x8c\x1d\xd2\x4f\x3e\x30\x0c\x06\x19\x5f\x6c\x43\x74\x98\x6b\xf2\x78\x51\xe2\xcd\xca\x0d\x97\xf3\xae\x26\x99\xa4\xa9\xe6\xf5\x3e\x3a\x59\xa7\xa8\x74\x3e\x1e\xe8\x14\x8b\x8a\xd6\x4b\xff\xd9\xd7\x8b\xb2\xbc\x6e\xf6\x9b\xd7\x2f\x5e\xd4\x8b\x13\xd0\x0b\xc2\xb5\x09\xe9\x7e\x4b\x04\x64\x57\x2f\xa1\x2d\xde\x91\xd3\x5f\x85\x0f\xab\xf6\xe0\x60\xf9\xcd\xc5\x4f\x78\xa2\x4e\x1e\x52\x0d\xe8\x71\x72\x7e\x7e\xf2\x10\x24\x79\x74\xbc\x40\x77\x90\x90\x25\x54\x99\x87\x25\x30\x7f\xc9\xb2\xe2\x55\x18\x3d\x2f\x01\x58\xd2\x77\xfa\xca\xa5\x9a\x7b\xe1\xb2\x34\x2d\x7d\x24\x93\x03\xcd\x2c\x76\xcf\xda\xd1\x0e\xde\x9b\x24\xc1\x39\xe8\x0e\x12\xd0\xad\xc3\xd4\xd6\xc7\xcc\x95\x6e\xdf\x2e\x10\x6c\x3b\x14\x30\x66\x4d\x63\xd0\x3a\x38\xa4\x66\xe0\x81\x2f\x62\x83\xaa\xb9\xc9\xd3\xde\x47\xc6\xc3\x34\xd8\x9e\x9f\x9f\xa7\x41\xdb\x63\xae\x88\xf4\xb3\xbc\xe9\xb1\x22\x77\x90\x78\x41\xc7\x88\x5c\xbc\xef\x0b\xdf\x2b\x0e\xf8\x6e\x1c\x49\xe2\x1e\x0a\x9e\x74\xcc\x0a\x11\x1e\x68\xa6\x69\x3d\xb2\x90\x18\x5e\x93\x62\x9a\x9f\x7e\xf8\x8a\xd1\x73\x8c\x3c\x9d\x90\x2b\xe4\xff\x17\x2b\x4c\x16\x2f\x5f\xfd\xc2\x3c\xa7\x20\xcf\x2f\xcd\x48\xbb\xb5\x97\x8f\x92\x02\x60\xd6\x39\xab\xfa\x30\x2d\x25\xcb\x7b\xba\x10\xd2\xdf\x73\x36\x41\xed\xa7\x37\x13\xd6\x3f\x69\x3f\xa9\x38\x85\xb0\x5e\x88\xc5\xc5\x11\x79\x4d\x92\xca\xbc\xd4\x29\xc8\x13\xbb\x5d\xfc\x6d\x76\xb1\x54\x91\x08\x86\x4f\xe7\xda\xdf\x57\xdf\xaf\xd1\xd7\x6a\xb2\x30\x9d\xbf\xd1\x3b\xa9\x24\x7a\xff\x03\x40\x2c\x28\x2b\xd2\x91\xe9\x8d\x91\xc5\x57\x83\x3b\x68\xfe\x7f\x40\x33\x41\xdf\x8f\x05\xd7\x96\x66\xf0\x3b\xe9\x47\x4f\xfa\xe4\xb2\xc8\x6f\xf7\xdc\xa8\x5a\x7d\xc7\xe9\x89\x8d\x59\xbd\x17\x8b\x52\xc6\xfc\x0e\x41\x92\x30\xb6\x0e\x2d\x0b\x77\xd0\xb6\x05\x32\xf7\x7a\xf9\xde\x7b\x72\xdf\x29\x80\xb6\x0e\x20\x5d\xe5\x90\x5b\x73\xe0\x20\x77\xfa\x34\x7e\x46\xe5\xd7\x8f\xd9\x4b\xab\x98\x83\x6a\xea\xe9\x9d\x7c\xef\x59\x60\xf1\x06\xe4\x13\x95\xfe\xd3\xc2\x78\xa9\xfa\xc7\xfa\x9a\x45\x54\xa7\xdf\xb3\x3a\xb3\x4b\x34\x54\xef\x6e\x9f\xd3\xbf\xae\xca\xd9\xb0\x12\xcf\xde\x6d\x2a\xdc\x4e\xbb\x02\x7e\x6b\xaa\xa4\x6d\x0c\x07\xa3\x2d\xd4\xe9\xbb\xde\xc1\xeb\x4e\x2a\xbb\x8d\xf5\xbf\x7f\x32\x9e\x5d\x08\x5d\x79\x36\xce\x5e\x34\x7d\x82\xd3\x71\xf6\xc6\xea\x0e\xa0\xbb\xf3\xf1\xee\x7c\xbc\x3b\x1f\xef\xce\xc7\xbb\xf3\xf1\xee\x7c\xbc\x3b\x1f\x7f\x47\xe7\xe3\xa5\xd9\xf4\xe2\xfb\xf4\x01\x77\x0e\x66\x24\xf3\x9e\x47\xbf\x2a\x95\xbb\x3b\x98\xb9\x0a\x36\x5f\xfc\xc3\xc3\xc3\x75\x37\x50\xf2\x57\x28\x96\xdf\xfd\x6f\xcb\x95\x8a\xed\x29\x2b\x1f\xb3\xa4\x7c\xb1\xb2\xa4\x2c\x7c\x5b\x7d\xdf\x92\x67\x6a\xce\x85\x8b\x47\xf9\x6b\x92\xd9\x10\x96\xff\x9a\xbe\xf9\xb8\xa6\xe7\x2c\x7a\x58\xf8\x9a\x47\x2f\x34\x11\xfa\x77\x9b\xbf\xf0\x5e\x0e\x27\x4b\x77\x8d\x16\x83\xc5\x49\x05\x77\xfe\x69\xf2\xb7\x91\x8f\x1c\xdf\xc9\x95\xd8\xc4\xc4\x79\x48\x3b\xa9\xd0\xcd\x73\xea\xa1\x2b\xfc\xa7\x86\x51\xfc\xff\x01\x84\xb1\x1c\x09\x94\xf8\x0f\x7c\xab\x7d\x89\x55\xfe\x4b\x88\xdf\xe2\x0b\xa5\xdf\xec\xfb\xa4\x9b\x7f\x9d\xf4\x9b\x7e\x9b\x34\xa3\xc6\x06\xfe\x9e\x7f\x81\xfd\x01\xdf\xe8\xfc\x2f\xc8\x5c\x35\xa4\x1d\x44\x00\x00")
 
 func templateDefaultTmplBytes() ([]byte, error) {
 	return bindataRead(
@@ -83,7 +83,7 @@ func templateDefaultTmpl() (*asset, error) {
 		return nil, err
 	}
 
-	info := bindataFileInfo{name: "template/default.tmpl", size: 16246, mode: os.FileMode(420), modTime: time.Unix(1483467521, 0)}
+	info := bindataFileInfo{name: "template/default.tmpl", size: 17437, mode: os.FileMode(420), modTime: time.Unix(1792157752, 0)}
 	a := &asset{bytes: bytes, info: info}
 	return a, nil
 }
@@ -0,0 +1,202 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package template
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"text/template/parse"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var limitsExceeded = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "alertmanager",
+	Name:      "template_limits_exceeded_total",
+	Help:      "The total number of template executions aborted for exceeding a limit.",
+}, []string{"limit"})
+
+var abandonedExecutions = prometheus.NewGauge(prometheus.GaugeOpts{
+	Namespace: "alertmanager",
+	Name:      "template_executions_abandoned",
+	Help:      "The number of template executions that exceeded their timeout and still run in the background.",
+})
+
+func init() {
+	prometheus.MustRegister(limitsExceeded)
+	prometheus.MustRegister(abandonedExecutions)
+}
+
+// Limits bounds the execution of templates. Zero values mean unlimited.
+type Limits struct {
+	// Timeout is the maximum time the execution of a template may take.
+	// Go templates cannot be interrupted, so an execution that exceeds the
+	// timeout is only aborted at its next write to the output. Until then
+	// it keeps running in the background, which the
+	// alertmanager_template_executions_abandoned gauge counts. Without
+	// writing, an execution can only range over its data, call functions
+	// and invoke other templates, the latter bounded by MaxDepth.
+	Timeout time.Duration
+	// MaxOutputSize is the maximum size in bytes of the output.
+	MaxOutputSize int
+	// MaxDepth is the maximum nesting depth of templates invoking other
+	// templates. It is checked before the execution, which cannot tell
+	// when a recursion ends, so templates invoking themselves, directly
+	// or indirectly, are rejected once MaxDepth is set.
+	MaxDepth int
+}
+
+// errStopped is returned by writes to the output of an aborted execution.
+var errStopped = errors.New("template execution aborted")
+
+// limitedWriter buffers the output of a template up to a maximum size.
+type limitedWriter struct {
+	mtx     sync.Mutex
+	buf     bytes.Buffer
+	max     int
+	stopped bool
+	// exceeded is set once the output exceeded the maximum size.
+	exceeded bool
+	// done is set once the execution returned, abandoned if it was
+	// stopped before.
+	done, abandoned bool
+}
+
+func (w *limitedWriter) Write(p []byte) (int, error) {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	if w.stopped {
+		return 0, errStopped
+	}
+	if w.max > 0 && w.buf.Len()+len(p) > w.max {
+		w.stopped, w.exceeded = true, true
+		return 0, fmt.Errorf("template output exceeds the maximum size of %d bytes", w.max)
+	}
+	return w.buf.Write(p)
+}
+
+// stop aborts the execution at its next write. An execution still running
+// is counted as abandoned until it returns.
+func (w *limitedWriter) stop() {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	w.stopped = true
+	if !w.done {
+		w.abandoned = true
+		abandonedExecutions.Inc()
+	}
+}
+
+// finish records that the execution returned.
+func (w *limitedWriter) finish() {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	w.done = true
+	if w.abandoned {
+		abandonedExecutions.Dec()
+	}
+}
+
+// templateDepth returns the nesting depth of the templates invoked by the
+// node, or max+1 if it exceeds max.
+func templateDepth(n parse.Node, lookup func(string) *parse.Tree, max int) int {
+	if max < 0 {
+		return 0
+	}
+	var nodes []parse.Node
+	switch n := n.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return 0
+		}
+		nodes = n.Nodes
+	case *parse.IfNode:
+		nodes = []parse.Node{n.List, n.ElseList}
+	case *parse.RangeNode:
+		nodes = []parse.Node{n.List, n.ElseList}
+	case *parse.WithNode:
+		nodes = []parse.Node{n.List, n.ElseList}
+	case *parse.TemplateNode:
+		t := lookup(n.Name)
+		if t == nil || t.Root == nil {
+			return 1
+		}
+		// Recursive templates descend until they exceed the maximum.
+		return 1 + templateDepth(t.Root, lookup, max-1)
+	}
+	depth := 0
+	for _, n := range nodes {
+		if d := templateDepth(n, lookup, max); d > depth {
+			depth = d
+		}
+		if depth > max {
+			break
+		}
+	}
+	return depth
+}
+
+// executor is implemented by text and HTML templates.
+type executor interface {
+	Execute(io.Writer, interface{}) error
+}
+
+// execute executes the template within the limits.
+func (l Limits) execute(tmpl executor, root *parse.Tree, lookup func(string) *parse.Tree, data interface{}) (string, error) {
+	if l.MaxDepth > 0 && root != nil {
+		if templateDepth(root.Root, lookup, l.MaxDepth) > l.MaxDepth {
+			limitsExceeded.WithLabelValues("depth").Inc()
+			return "", fmt.Errorf("template nesting exceeds the maximum depth of %d", l.MaxDepth)
+		}
+	}
+
+	w := &limitedWriter{max: l.MaxOutputSize}
+	exec := func() error {
+		err := tmpl.Execute(w, data)
+		if w.exceeded {
+			limitsExceeded.WithLabelValues("output_size").Inc()
+		}
+		return err
+	}
+	if l.Timeout <= 0 {
+		err := exec()
+		return w.buf.String(), err
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		err := exec()
+		w.finish()
+		done <- err
+	}()
+
+	timer := time.NewTimer(l.Timeout)
+	defer timer.Stop()
+
+	select {
+	case err := <-done:
+		return w.buf.String(), err
+	case <-timer.C:
+		w.stop()
+		limitsExceeded.WithLabelValues("timeout").Inc()
+		return "", fmt.Errorf("template execution exceeds the timeout of %s", l.Timeout)
+	}
+}
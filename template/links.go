@@ -0,0 +1,83 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package template
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Links holds the base URLs of the systems the deep link functions of
+// templates point to. Functions whose URL is not set fail.
+type Links struct {
+	// GrafanaURL is the base URL of Grafana, e.g. https://grafana.example.com.
+	GrafanaURL string
+	// LogSearchURL is the URL of a log search, in which {query} is replaced
+	// by a label selector like {job="api"}.
+	LogSearchURL string
+	// TraceURL is the URL of a trace view, in which {trace_id} is replaced
+	// by the trace ID.
+	TraceURL string
+}
+
+// grafanaLink returns a link to the Grafana dashboard with the UID, with
+// its variables set to the label values, e.g.
+// {{ grafanaLink "node-overview" (.CommonLabels | filterLabels "job|instance") }}.
+func (t *Template) grafanaLink(uid string, kv KV) (string, error) {
+	if t.Links.GrafanaURL == "" {
+		return "", errors.New("grafana URL is not configured")
+	}
+	v := url.Values{}
+	for k, lv := range kv {
+		v.Set("var-"+k, lv)
+	}
+	u := strings.TrimRight(t.Links.GrafanaURL, "/") + "/d/" + url.PathEscape(uid)
+	if len(v) > 0 {
+		u += "?" + v.Encode()
+	}
+	return u, nil
+}
+
+// logSearchLink returns a link to the log search for the logs with the
+// label values, e.g. {{ logSearchLink (.CommonLabels | filterLabels "job|instance") }}.
+func (t *Template) logSearchLink(kv KV) (string, error) {
+	if t.Links.LogSearchURL == "" {
+		return "", errors.New("log search URL is not configured")
+	}
+	names := make([]string, 0, len(kv))
+	for k := range kv {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	matchers := make([]string, 0, len(names))
+	for _, k := range names {
+		matchers = append(matchers, fmt.Sprintf("%s=%s", k, strconv.Quote(kv[k])))
+	}
+	query := "{" + strings.Join(matchers, ",") + "}"
+	return strings.Replace(t.Links.LogSearchURL, "{query}", url.QueryEscape(query), -1), nil
+}
+
+// traceLink returns a link to the trace view of the trace with the ID, e.g.
+// {{ traceLink .Annotations.trace_id }}.
+func (t *Template) traceLink(id string) (string, error) {
+	if t.Links.TraceURL == "" {
+		return "", errors.New("trace URL is not configured")
+	}
+	return strings.Replace(t.Links.TraceURL, "{trace_id}", url.QueryEscape(id), -1), nil
+}
@@ -0,0 +1,118 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package template
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// translations holds the translations of the English texts of the default
+// templates by locale. Texts without a translation are used as they are.
+var translations = map[string]map[string]string{
+	"de": {
+		"firing":          "ausgelöst",
+		"resolved":        "behoben",
+		"Firing":          "Ausgelöst",
+		"Resolved":        "Behoben",
+		"Alerts Firing":   "Ausgelöste Alarme",
+		"Alerts Resolved": "Behobene Alarme",
+		"Labels":          "Labels",
+		"Annotations":     "Annotationen",
+		"Source":          "Quelle",
+		"Started":         "Beginn",
+		"1 alert for":     "1 Alarm für",
+		"%d alerts for":   "%d Alarme für",
+		"View in":         "Anzeigen in",
+		"Sent by":         "Gesendet von",
+	},
+	"es": {
+		"firing":          "activa",
+		"resolved":        "resuelta",
+		"Firing":          "Activas",
+		"Resolved":        "Resueltas",
+		"Alerts Firing":   "Alertas activas",
+		"Alerts Resolved": "Alertas resueltas",
+		"Labels":          "Etiquetas",
+		"Annotations":     "Anotaciones",
+		"Source":          "Origen",
+		"Started":         "Inicio",
+		"1 alert for":     "1 alerta para",
+		"%d alerts for":   "%d alertas para",
+		"View in":         "Ver en",
+		"Sent by":         "Enviado por",
+	},
+	"fr": {
+		"firing":          "en cours",
+		"resolved":        "résolue",
+		"Firing":          "En cours",
+		"Resolved":        "Résolues",
+		"Alerts Firing":   "Alertes en cours",
+		"Alerts Resolved": "Alertes résolues",
+		"Labels":          "Étiquettes",
+		"Annotations":     "Annotations",
+		"Source":          "Source",
+		"Started":         "Début",
+		"1 alert for":     "1 alerte pour",
+		"%d alerts for":   "%d alertes pour",
+		"View in":         "Voir dans",
+		"Sent by":         "Envoyé par",
+	},
+}
+
+// dateLayouts holds the layouts of dates by locale.
+var dateLayouts = map[string]string{
+	"en": "Jan 2, 2006 15:04:05 MST",
+	"de": "02.01.2006 15:04:05 MST",
+	"es": "02/01/2006 15:04:05 MST",
+	"fr": "02/01/2006 15:04:05 MST",
+}
+
+// localeKeys returns the keys to look up the locale, e.g. pt-BR, in maps by
+// locale in order of preference, which are the locale and its language.
+func localeKeys(locale string) []string {
+	locale = strings.Replace(locale, "_", "-", -1)
+	return []string{locale, strings.ToLower(strings.SplitN(locale, "-", 2)[0])}
+}
+
+// translate returns the translation of the English text of the default
+// templates to the locale, e.g. {{ tr .Locale "Labels" }}. Format verbs of
+// the text are replaced by the arguments like by fmt.Sprintf.
+func translate(locale, text string, args ...interface{}) string {
+	tr := text
+	for _, l := range localeKeys(locale) {
+		if v, ok := translations[l][text]; ok {
+			tr = v
+			break
+		}
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(tr, args...)
+	}
+	return tr
+}
+
+// localDate formats the time in UTC like dates are commonly written in the
+// locale, e.g. {{ .StartsAt | localDate $.Locale }}.
+func localDate(locale string, t time.Time) string {
+	layout := dateLayouts["en"]
+	for _, l := range localeKeys(locale) {
+		if v, ok := dateLayouts[l]; ok {
+			layout = v
+			break
+		}
+	}
+	return t.UTC().Format(layout)
+}
@@ -0,0 +1,198 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package template
+
+import (
+	"fmt"
+	tmplhtml "html/template"
+	"regexp"
+	"strings"
+)
+
+// The converters handle the subset of markdown commonly used in alert
+// annotations: paragraphs, headings, lists, block quotes, code blocks and
+// spans, links, and bold, italic and struck through text. Anything else is
+// passed through as text.
+
+var (
+	mdCodeSpan   = regexp.MustCompile("`([^`]+)`")
+	mdLink       = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+	mdBold       = regexp.MustCompile(`\*\*(\S(?:.*?\S)?)\*\*|__(\S(?:.*?\S)?)__`)
+	mdItalic     = regexp.MustCompile(`\*(\S(?:[^*]*?\S)?)\*|(^|[^\w])_(\S(?:[^_]*?\S)?)_([^\w]|$)`)
+	mdStrike     = regexp.MustCompile(`~~(\S(?:.*?\S)?)~~`)
+	mdHeading    = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*$`)
+	mdBullet     = regexp.MustCompile(`^(\s*)[-*+]\s+(.*)$`)
+	mdNumbered   = regexp.MustCompile(`^(\s*)(\d+)[.)]\s+(.*)$`)
+	mdQuote      = regexp.MustCompile(`^>\s?(.*)$`)
+	mdSafeScheme = regexp.MustCompile(`^(?i)(https?|mailto):`)
+)
+
+// mdInline converts the inline markup of text outside of code spans with
+// the given function and code spans with code. The text is escaped by esc
+// before being converted.
+func mdInline(s string, esc, inline, code func(string) string) string {
+	var (
+		b    strings.Builder
+		last = 0
+	)
+	for _, m := range mdCodeSpan.FindAllStringSubmatchIndex(s, -1) {
+		b.WriteString(inline(esc(s[last:m[0]])))
+		b.WriteString(code(esc(s[m[2]:m[3]])))
+		last = m[1]
+	}
+	b.WriteString(inline(esc(s[last:])))
+	return b.String()
+}
+
+// slackEscape escapes the characters Slack uses for control sequences.
+func slackEscape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
+
+func slackInline(s string) string {
+	return mdInline(s, slackEscape, func(s string) string {
+		s = mdLink.ReplaceAllString(s, "<$2|$1>")
+		// Bold text is marked with a placeholder, so its asterisks are not
+		// taken for italic text.
+		s = mdBold.ReplaceAllString(s, "\x00${1}${2}\x00")
+		s = mdItalic.ReplaceAllString(s, "${2}_${1}${3}_${4}")
+		s = mdStrike.ReplaceAllString(s, "~$1~")
+		return strings.Replace(s, "\x00", "*", -1)
+	}, func(s string) string {
+		return "`" + s + "`"
+	})
+}
+
+// markdownToSlack converts markdown to the mrkdwn format of Slack messages.
+func markdownToSlack(s string) string {
+	var (
+		lines  = strings.Split(s, "\n")
+		inCode = false
+	)
+	for i, l := range lines {
+		if strings.HasPrefix(strings.TrimSpace(l), "```") {
+			inCode = !inCode
+			lines[i] = "```"
+			continue
+		}
+		if inCode {
+			lines[i] = slackEscape(l)
+			continue
+		}
+		if m := mdHeading.FindStringSubmatch(l); m != nil {
+			lines[i] = "*" + slackInline(m[2]) + "*"
+		} else if m := mdBullet.FindStringSubmatch(l); m != nil {
+			lines[i] = m[1] + "• " + slackInline(m[2])
+		} else if m := mdQuote.FindStringSubmatch(l); m != nil {
+			lines[i] = ">" + slackInline(m[1])
+		} else {
+			lines[i] = slackInline(l)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func htmlInline(s string) string {
+	return mdInline(s, tmplhtml.HTMLEscapeString, func(s string) string {
+		s = mdLink.ReplaceAllStringFunc(s, func(l string) string {
+			m := mdLink.FindStringSubmatch(l)
+			// The URL is already escaped, but links must not run scripts.
+			if !mdSafeScheme.MatchString(m[2]) {
+				return m[1]
+			}
+			return fmt.Sprintf(`<a href="%s">%s</a>`, m[2], m[1])
+		})
+		s = mdBold.ReplaceAllString(s, "<strong>${1}${2}</strong>")
+		s = mdItalic.ReplaceAllString(s, "${2}<em>${1}${3}</em>${4}")
+		return mdStrike.ReplaceAllString(s, "<del>$1</del>")
+	}, func(s string) string {
+		return "<code>" + s + "</code>"
+	})
+}
+
+// markdownToHTML converts markdown to HTML, e.g. for email and Hipchat
+// notifications. All text is escaped, so the result is safe to embed.
+func markdownToHTML(s string) tmplhtml.HTML {
+	var (
+		b     strings.Builder
+		para  []string
+		quote []string
+		list  string
+		code  = false
+	)
+	flush := func() {
+		if len(para) > 0 {
+			b.WriteString("<p>" + strings.Join(para, "\n") + "</p>\n")
+			para = nil
+		}
+		if len(quote) > 0 {
+			b.WriteString("<blockquote>" + strings.Join(quote, "\n") + "</blockquote>\n")
+			quote = nil
+		}
+		if list != "" {
+			b.WriteString("</" + list + ">\n")
+			list = ""
+		}
+	}
+	item := func(typ, text string) {
+		if list != typ {
+			flush()
+			b.WriteString("<" + typ + ">\n")
+			list = typ
+		}
+		b.WriteString("<li>" + htmlInline(text) + "</li>\n")
+	}
+
+	for _, l := range strings.Split(s, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(l), "```") {
+			if code {
+				b.WriteString("</code></pre>\n")
+			} else {
+				flush()
+				b.WriteString("<pre><code>")
+			}
+			code = !code
+			continue
+		}
+		if code {
+			b.WriteString(tmplhtml.HTMLEscapeString(l) + "\n")
+			continue
+		}
+		if m := mdHeading.FindStringSubmatch(l); m != nil {
+			flush()
+			fmt.Fprintf(&b, "<h%d>%s</h%d>\n", len(m[1]), htmlInline(m[2]), len(m[1]))
+		} else if m := mdBullet.FindStringSubmatch(l); m != nil {
+			item("ul", m[2])
+		} else if m := mdNumbered.FindStringSubmatch(l); m != nil {
+			item("ol", m[3])
+		} else if m := mdQuote.FindStringSubmatch(l); m != nil {
+			if len(quote) == 0 {
+				flush()
+			}
+			quote = append(quote, htmlInline(m[1]))
+		} else if strings.TrimSpace(l) == "" {
+			flush()
+		} else {
+			if len(para) == 0 {
+				flush()
+			}
+			para = append(para, htmlInline(l))
+		}
+	}
+	if code {
+		b.WriteString("</code></pre>\n")
+	}
+	flush()
+	return tmplhtml.HTML(strings.TrimSuffix(b.String(), "\n"))
+}
@@ -0,0 +1,172 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package template
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+)
+
+var (
+	reloadSuccess = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "alertmanager",
+		Name:      "template_last_reload_successful",
+		Help:      "Whether the last reload of changed template files was successful.",
+	})
+	reloadSuccessTime = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "alertmanager",
+		Name:      "template_last_reload_success_timestamp_seconds",
+		Help:      "Timestamp of the last successful reload of changed template files.",
+	})
+)
+
+func init() {
+	// The templates parsed on configuration reloads are valid.
+	reloadSuccess.Set(1)
+
+	prometheus.MustRegister(reloadSuccess)
+	prometheus.MustRegister(reloadSuccessTime)
+}
+
+// filesState returns a string that changes whenever a file matched by the
+// globs is added, removed or modified.
+func filesState(paths []string) (string, error) {
+	var files []string
+	for _, tp := range paths {
+		p, err := filepath.Glob(tp)
+		if err != nil {
+			return "", err
+		}
+		files = append(files, p...)
+	}
+	sort.Strings(files)
+
+	state := make([]string, 0, len(files))
+	for _, f := range files {
+		fi, err := os.Stat(f)
+		if err != nil {
+			return "", err
+		}
+		state = append(state, fmt.Sprintf("%s:%d:%d", f, fi.Size(), fi.ModTime().UnixNano()))
+	}
+	return strings.Join(state, "\n"), nil
+}
+
+// Reload parses the template files again and replaces the templates if all
+// of them are valid. Otherwise the previous templates remain in use and the
+// error is returned.
+func (t *Template) Reload() error {
+	files, err := filesState(t.paths)
+	if err != nil {
+		return err
+	}
+	text, html, err := t.parse()
+
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	// A broken file is not parsed again until it changes.
+	t.files = files
+	if err != nil {
+		return err
+	}
+	t.text, t.html = text, html
+	return nil
+}
+
+// changed returns whether the template files changed since they were
+// parsed last.
+func (t *Template) changed() (bool, error) {
+	files, err := filesState(t.paths)
+	if err != nil {
+		return false, err
+	}
+	t.mtx.RLock()
+	defer t.mtx.RUnlock()
+
+	return files != t.files, nil
+}
+
+// Watcher reloads the template files of a Template when they change, so
+// edits take effect without reloading the configuration.
+type Watcher struct {
+	t        *Template
+	interval time.Duration
+
+	mtx   sync.Mutex
+	stopc chan struct{}
+}
+
+// NewWatcher returns a new Watcher checking the template files of t for
+// changes at the interval.
+func NewWatcher(t *Template, interval time.Duration) *Watcher {
+	return &Watcher{t: t, interval: interval}
+}
+
+// Run checks the template files for changes until the watcher is stopped.
+func (w *Watcher) Run() {
+	w.mtx.Lock()
+	w.stopc = make(chan struct{})
+	stopc := w.stopc
+	w.mtx.Unlock()
+
+	t := time.NewTicker(w.interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-stopc:
+			return
+		case <-t.C:
+		}
+		changed, err := w.t.changed()
+		if err != nil {
+			log.Errorf("Checking template files for changes failed: %s", err)
+			continue
+		}
+		if !changed {
+			continue
+		}
+		log.Infof("Reloading changed template files")
+		if err := w.t.Reload(); err != nil {
+			log.Errorf("Reloading template files failed, keeping the previous templates: %s", err)
+			reloadSuccess.Set(0)
+			continue
+		}
+		reloadSuccess.Set(1)
+		reloadSuccessTime.Set(float64(time.Now().Unix()))
+	}
+}
+
+// Stop the watcher.
+func (w *Watcher) Stop() {
+	if w == nil {
+		return
+	}
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	if w.stopc != nil {
+		close(w.stopc)
+		w.stopc = nil
+	}
+}
@@ -14,70 +14,143 @@
 package template
 
 import (
-	"bytes"
+	"errors"
 	"net/url"
 	"path/filepath"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	tmplhtml "html/template"
 	tmpltext "text/template"
+	"text/template/parse"
 
 	"github.com/prometheus/common/model"
 
+	"github.com/prometheus/alertmanager/silence/link"
 	"github.com/prometheus/alertmanager/template/internal/deftmpl"
 	"github.com/prometheus/alertmanager/types"
 )
 
 // Template bundles a text and a html template instance.
 type Template struct {
+	// The templates are replaced when the template files are reloaded.
+	mtx  sync.RWMutex
 	text *tmpltext.Template
 	html *tmplhtml.Template
 
+	// The path globs of the template files and the state of the matched
+	// files when they were parsed.
+	paths []string
+	files string
+
 	ExternalURL *url.URL
+	// SilenceLinks signs the links returned by the silenceURL function.
+	// The function fails if it is not set.
+	SilenceLinks *link.Signer
+	// Links configures the deep link functions.
+	Links Links
+	// MaxAlerts limits the number of alerts in the data for the receiver
+	// with the given name. Receivers without an entry are not limited.
+	MaxAlerts map[string]int
+	// Locale is the locale of the data of receivers without an entry in
+	// Locales, which holds the locale by receiver name. The default
+	// templates are translated to the locale of the data.
+	Locale  string
+	Locales map[string]string
+	// Limits bounds the execution of templates.
+	Limits Limits
 }
 
 // FromGlobs calls ParseGlob on all path globs provided and returns the
 // resulting Template.
 func FromGlobs(paths ...string) (*Template, error) {
-	t := &Template{
-		text: tmpltext.New("").Option("missingkey=zero"),
-		html: tmplhtml.New("").Option("missingkey=zero"),
-	}
+	t := &Template{paths: paths}
+
 	var err error
+	if t.files, err = filesState(paths); err != nil {
+		return nil, err
+	}
+	if t.text, t.html, err = t.parse(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
 
-	t.text = t.text.Funcs(tmpltext.FuncMap(DefaultFuncs))
-	t.html = t.html.Funcs(tmplhtml.FuncMap(DefaultFuncs))
+// parse parses the default templates and the template files.
+func (t *Template) parse() (*tmpltext.Template, *tmplhtml.Template, error) {
+	text := tmpltext.New("").Option("missingkey=zero")
+	html := tmplhtml.New("").Option("missingkey=zero")
+
+	text = text.Funcs(tmpltext.FuncMap(DefaultFuncs))
+	html = html.Funcs(tmplhtml.FuncMap(DefaultFuncs))
+
+	// Functions that depend on the template's configuration.
+	funcs := FuncMap{
+		"silenceURL":    t.silenceURL,
+		"grafanaLink":   t.grafanaLink,
+		"logSearchLink": t.logSearchLink,
+		"traceLink":     t.traceLink,
+	}
+	text = text.Funcs(tmpltext.FuncMap(funcs))
+	html = html.Funcs(tmplhtml.FuncMap(funcs))
 
 	b, err := deftmpl.Asset("template/default.tmpl")
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	if t.text, err = t.text.Parse(string(b)); err != nil {
-		return nil, err
+	if text, err = text.Parse(string(b)); err != nil {
+		return nil, nil, err
 	}
-	if t.html, err = t.html.Parse(string(b)); err != nil {
-		return nil, err
+	if html, err = html.Parse(string(b)); err != nil {
+		return nil, nil, err
 	}
 
-	for _, tp := range paths {
+	for _, tp := range t.paths {
 		// ParseGlob in the template packages errors if not at least one file is
 		// matched. We want to allow empty matches that may be populated later on.
 		p, err := filepath.Glob(tp)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		if len(p) > 0 {
-			if t.text, err = t.text.ParseGlob(tp); err != nil {
-				return nil, err
+			if text, err = text.ParseGlob(tp); err != nil {
+				return nil, nil, err
 			}
-			if t.html, err = t.html.ParseGlob(tp); err != nil {
-				return nil, err
+			if html, err = html.ParseGlob(tp); err != nil {
+				return nil, nil, err
 			}
 		}
 	}
-	return t, nil
+	return text, html, nil
+}
+
+// silenceURL returns a signed link that creates a silence for the alerts of
+// the notified group once confirmed. The silence matches the group labels or,
+// if there are none, the common labels.
+func (t *Template) silenceURL(data *Data) (string, error) {
+	if t.SilenceLinks == nil || t.ExternalURL == nil {
+		return "", errors.New("silence links are not configured")
+	}
+	kv := data.GroupLabels
+	if len(kv) == 0 {
+		kv = data.CommonLabels
+	}
+	lset := make(model.LabelSet, len(kv))
+	for k, v := range kv {
+		lset[model.LabelName(k)] = model.LabelValue(v)
+	}
+	return t.SilenceLinks.URL(t.ExternalURL, lset)
+}
+
+// Defined returns true if a template with the given name was defined by the
+// default templates or the template files.
+func (t *Template) Defined(name string) bool {
+	t.mtx.RLock()
+	defer t.mtx.RUnlock()
+
+	return t.text.Lookup(name) != nil
 }
 
 // ExecuteTextString needs a meaningful doc comment (TODO(fabxc)).
@@ -85,7 +158,9 @@ func (t *Template) ExecuteTextString(text string, data interface{}) (string, err
 	if text == "" {
 		return "", nil
 	}
+	t.mtx.RLock()
 	tmpl, err := t.text.Clone()
+	t.mtx.RUnlock()
 	if err != nil {
 		return "", err
 	}
@@ -93,9 +168,12 @@ func (t *Template) ExecuteTextString(text string, data interface{}) (string, err
 	if err != nil {
 		return "", err
 	}
-	var buf bytes.Buffer
-	err = tmpl.Execute(&buf, data)
-	return buf.String(), err
+	return t.Limits.execute(tmpl, tmpl.Tree, func(name string) *parse.Tree {
+		if tmpl := tmpl.Lookup(name); tmpl != nil {
+			return tmpl.Tree
+		}
+		return nil
+	}, data)
 }
 
 // ExecuteHTMLString needs a meaningful doc comment (TODO(fabxc)).
@@ -103,7 +181,9 @@ func (t *Template) ExecuteHTMLString(html string, data interface{}) (string, err
 	if html == "" {
 		return "", nil
 	}
+	t.mtx.RLock()
 	tmpl, err := t.html.Clone()
+	t.mtx.RUnlock()
 	if err != nil {
 		return "", err
 	}
@@ -111,9 +191,12 @@ func (t *Template) ExecuteHTMLString(html string, data interface{}) (string, err
 	if err != nil {
 		return "", err
 	}
-	var buf bytes.Buffer
-	err = tmpl.Execute(&buf, data)
-	return buf.String(), err
+	return t.Limits.execute(tmpl, tmpl.Tree, func(name string) *parse.Tree {
+		if tmpl := tmpl.Lookup(name); tmpl != nil {
+			return tmpl.Tree
+		}
+		return nil
+	}, data)
 }
 
 type FuncMap map[string]interface{}
@@ -130,6 +213,24 @@ var DefaultFuncs = FuncMap{
 	"safeHtml": func(text string) tmplhtml.HTML {
 		return tmplhtml.HTML(text)
 	},
+	"humanize":           humanize,
+	"humanize1024":       humanize1024,
+	"humanizePercentage": humanizePercentage,
+	"humanizeDuration":   humanizeDuration,
+	"since":              since,
+	"dateFormat":         dateFormat,
+	"toJSON":             toJSON,
+	"toPrettyJSON":       toPrettyJSON,
+	"fromJSON":           fromJSON,
+	"reReplaceAll":       reReplaceAll,
+	"match":              match,
+	"stringSlice":        stringSlice,
+	"filterLabels":       filterLabels,
+	"excludeLabels":      excludeLabels,
+	"markdownToSlack":    markdownToSlack,
+	"markdownToHTML":     markdownToHTML,
+	"tr":                 translate,
+	"localDate":          localDate,
 }
 
 // Pair is a key/value string pair.
@@ -158,6 +259,29 @@ func (ps Pairs) Values() []string {
 	return vs
 }
 
+// SortedByValue returns a copy of the pairs sorted by value and, for equal
+// values, by name.
+func (ps Pairs) SortedByValue() Pairs {
+	res := append(Pairs(nil), ps...)
+	sort.SliceStable(res, func(i, j int) bool {
+		if res[i].Value != res[j].Value {
+			return res[i].Value < res[j].Value
+		}
+		return res[i].Name < res[j].Name
+	})
+	return res
+}
+
+// Join formats the pairs as name, separator and value each, joined by sep,
+// e.g. {{ .Labels.SortedPairs.Join "=" ", " }} returns "alertname=A, job=b".
+func (ps Pairs) Join(kvSep, sep string) string {
+	s := make([]string, 0, len(ps))
+	for _, p := range ps {
+		s = append(s, p.Name+kvSep+p.Value)
+	}
+	return strings.Join(s, sep)
+}
+
 // KV is a set of key/value string pairs.
 type KV map[string]string
 
@@ -200,6 +324,17 @@ func (kv KV) Remove(keys []string) KV {
 	return res
 }
 
+// Only returns a copy of the key/value set with only the given keys.
+func (kv KV) Only(keys []string) KV {
+	res := KV{}
+	for _, k := range keys {
+		if v, ok := kv[k]; ok {
+			res[k] = v
+		}
+	}
+	return res
+}
+
 // Names returns the names of the label names in the LabelSet.
 func (kv KV) Names() []string {
 	return kv.SortedPairs().Names()
@@ -224,6 +359,19 @@ type Data struct {
 	CommonAnnotations KV `json:"commonAnnotations"`
 
 	ExternalURL string `json:"externalURL"`
+
+	// TruncatedAlerts is the number of alerts of the group that were left
+	// out of Alerts because of the receiver's limit.
+	TruncatedAlerts int `json:"truncatedAlerts"`
+
+	// Locale is the locale of the receiver, e.g. de. It is empty for English.
+	Locale string `json:"locale,omitempty"`
+}
+
+// TotalAlerts returns the number of alerts of the group including the ones
+// left out of Alerts.
+func (d *Data) TotalAlerts() int {
+	return len(d.Alerts) + d.TruncatedAlerts
 }
 
 // Alert holds one alert for notification templates.
@@ -234,6 +382,25 @@ type Alert struct {
 	StartsAt     time.Time `json:"startsAt"`
 	EndsAt       time.Time `json:"endsAt"`
 	GeneratorURL string    `json:"generatorURL"`
+	// SoftSilenced is true if the alert is matched by a soft silence.
+	SoftSilenced bool `json:"softSilenced"`
+	// Flapping is true if the alert repeatedly changed between firing and
+	// resolved recently.
+	Flapping bool `json:"flapping"`
+	// Comments attached to the alert, oldest first.
+	Comments []Comment `json:"comments,omitempty"`
+	// AssignedTo is the person the alert is assigned to, if any.
+	AssignedTo string `json:"assignedTo,omitempty"`
+	// Locale is the locale of the data, for templates given only alerts
+	// like __text_alert_list.
+	Locale string `json:"-"`
+}
+
+// Comment holds a comment about an alert for notification templates.
+type Comment struct {
+	Author    string    `json:"author"`
+	Comment   string    `json:"comment"`
+	CreatedAt time.Time `json:"createdAt"`
 }
 
 // Alerts is a list of Alert objects.
@@ -271,11 +438,15 @@ func (t *Template) Data(recv string, groupLabels model.LabelSet, alerts ...*type
 		CommonLabels:      KV{},
 		CommonAnnotations: KV{},
 		ExternalURL:       t.ExternalURL.String(),
+		Locale:            t.Locale,
+	}
+	if l, ok := t.Locales[data.Receiver]; ok {
+		data.Locale = l
 	}
 
 	// The call to types.Alert is necessary to correctly resolve the internal
 	// representation to the user representation.
-	for _, a := range types.Alerts(alerts...) {
+	for i, a := range types.Alerts(alerts...) {
 		alert := Alert{
 			Status:       string(a.Status()),
 			Labels:       make(KV, len(a.Labels)),
@@ -283,8 +454,19 @@ func (t *Template) Data(recv string, groupLabels model.LabelSet, alerts ...*type
 			StartsAt:     a.StartsAt,
 			EndsAt:       a.EndsAt,
 			GeneratorURL: a.GeneratorURL,
+			SoftSilenced: alerts[i].SoftSilenced,
+			Flapping:     alerts[i].Flapping,
+			AssignedTo:   alerts[i].AssignedTo,
+			Locale:       data.Locale,
+		}
+		for _, c := range alerts[i].Comments {
+			alert.Comments = append(alert.Comments, Comment{
+				Author:    c.Author,
+				Comment:   c.Comment,
+				CreatedAt: c.CreatedAt,
+			})
 		}
-		for k, v := range a.Labels {
+		for k, v := range renderedLabels(alerts[i]) {
 			alert.Labels[string(k)] = string(v)
 		}
 		for k, v := range a.Annotations {
@@ -293,18 +475,25 @@ func (t *Template) Data(recv string, groupLabels model.LabelSet, alerts ...*type
 		data.Alerts = append(data.Alerts, alert)
 	}
 
+	if max := t.MaxAlerts[data.Receiver]; max > 0 && len(data.Alerts) > max {
+		as := append(data.Alerts.Firing(), data.Alerts.Resolved()...)
+		data.Alerts = as[:max]
+		data.TruncatedAlerts = len(as) - max
+	}
+
 	for k, v := range groupLabels {
 		data.GroupLabels[string(k)] = string(v)
 	}
 
 	if len(alerts) >= 1 {
 		var (
-			commonLabels      = alerts[0].Labels.Clone()
+			commonLabels      = renderedLabels(alerts[0]).Clone()
 			commonAnnotations = alerts[0].Annotations.Clone()
 		)
 		for _, a := range alerts[1:] {
+			lset := renderedLabels(a)
 			for ln, lv := range commonLabels {
-				if a.Labels[ln] != lv {
+				if lset[ln] != lv {
 					delete(commonLabels, ln)
 				}
 			}
@@ -324,3 +513,14 @@ func (t *Template) Data(recv string, groupLabels model.LabelSet, alerts ...*type
 
 	return data
 }
+
+// renderedLabels returns the labels of the alert as shown in notifications,
+// which carry the SoftSilencedLabel if a soft silence matches the alert.
+func renderedLabels(a *types.Alert) model.LabelSet {
+	if !a.SoftSilenced {
+		return a.Labels
+	}
+	lset := a.Labels.Clone()
+	lset[types.SoftSilencedLabel] = "true"
+	return lset
+}
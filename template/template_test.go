@@ -0,0 +1,420 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package template
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+
+	"github.com/prometheus/alertmanager/types"
+)
+
+func TestDataTruncatesAlerts(t *testing.T) {
+	tmpl, err := FromGlobs()
+	require.NoError(t, err)
+	tmpl.ExternalURL, _ = url.Parse("http://localhost:9093")
+	tmpl.MaxAlerts = map[string]int{"team": 3}
+
+	now := time.Now()
+
+	var alerts []*types.Alert
+	for i := 0; i < 5; i++ {
+		a := &types.Alert{
+			Alert: model.Alert{
+				Labels:   model.LabelSet{"instance": model.LabelValue(fmt.Sprint(i))},
+				StartsAt: now.Add(-time.Hour),
+			},
+		}
+		// The first alerts are resolved and must be left out first.
+		if i < 2 {
+			a.EndsAt = now.Add(-time.Minute)
+		}
+		alerts = append(alerts, a)
+	}
+
+	data := tmpl.Data("team/slack/0", model.LabelSet{}, alerts...)
+	require.Len(t, data.Alerts, 3)
+	require.Len(t, data.Alerts.Firing(), 3)
+	require.Equal(t, 2, data.TruncatedAlerts)
+	require.Equal(t, 5, data.TotalAlerts())
+
+	s, err := tmpl.ExecuteTextString(`showing {{ len .Alerts }} of {{ .TotalAlerts }}`, data)
+	require.NoError(t, err)
+	require.Equal(t, "showing 3 of 5", s)
+
+	// Other receivers are not limited.
+	data = tmpl.Data("other", model.LabelSet{}, alerts...)
+	require.Len(t, data.Alerts, 5)
+	require.Equal(t, 0, data.TruncatedAlerts)
+}
+
+func TestDataSoftSilenced(t *testing.T) {
+	tmpl, err := FromGlobs()
+	require.NoError(t, err)
+	tmpl.ExternalURL, _ = url.Parse("http://localhost:9093")
+
+	alerts := []*types.Alert{
+		{Alert: model.Alert{Labels: model.LabelSet{"job": "api", "instance": "a"}}, SoftSilenced: true},
+		{Alert: model.Alert{Labels: model.LabelSet{"job": "api", "instance": "b"}}, SoftSilenced: true},
+	}
+	data := tmpl.Data("team", model.LabelSet{}, alerts...)
+	require.Equal(t, "true", data.Alerts[0].Labels[types.SoftSilencedLabel])
+	require.True(t, data.Alerts[0].SoftSilenced)
+	require.Equal(t, KV{"job": "api", types.SoftSilencedLabel: "true"}, data.CommonLabels)
+
+	// The flag is only added to the rendered labels.
+	_, ok := alerts[0].Labels[types.SoftSilencedLabel]
+	require.False(t, ok)
+
+	alerts[1].SoftSilenced = false
+	data = tmpl.Data("team", model.LabelSet{}, alerts...)
+	require.Equal(t, KV{"job": "api"}, data.CommonLabels)
+	_, ok = data.Alerts[1].Labels[types.SoftSilencedLabel]
+	require.False(t, ok)
+}
+
+func TestHumanizeFuncs(t *testing.T) {
+	tmpl, err := FromGlobs()
+	require.NoError(t, err)
+
+	startsAt := time.Date(2016, 1, 1, 12, 0, 0, 0, time.UTC)
+	for in, exp := range map[string]string{
+		`{{ humanize 1234567 }}`:                                   "1.235M",
+		`{{ humanize 0.0025 }}`:                                    "2.5m",
+		`{{ humanize "42" }}`:                                      "42",
+		`{{ humanize1024 1048576 }}`:                               "1Mi",
+		`{{ humanizePercentage 0.1234 }}`:                          "12.34%",
+		`{{ humanizeDuration 11520 }}`:                             "3h12m",
+		`{{ humanizeDuration 183600 }}`:                            "2d3h",
+		`{{ humanizeDuration 3600 }}`:                              "1h",
+		`{{ humanizeDuration "1.5" }}`:                             "1.5s",
+		`{{ humanizeDuration 0.25 }}`:                              "250ms",
+		`{{ .StartsAt | dateFormat "2006-01-02 15:04 MST" "" }}`:   "2016-01-01 12:00 UTC",
+		`{{ .StartsAt | dateFormat "15:04 MST" "Europe/Berlin" }}`: "13:00 CET",
+	} {
+		s, err := tmpl.ExecuteTextString(in, Alert{StartsAt: startsAt})
+		require.NoError(t, err, in)
+		require.Equal(t, exp, s, in)
+	}
+
+	s, err := tmpl.ExecuteTextString(`{{ .StartsAt | since | humanizeDuration }}`, Alert{StartsAt: time.Now().Add(-90 * time.Minute)})
+	require.NoError(t, err)
+	require.Equal(t, "1h30m", s)
+
+	_, err = tmpl.ExecuteTextString(`{{ humanize "high" }}`, nil)
+	require.Error(t, err)
+	_, err = tmpl.ExecuteTextString(`{{ .StartsAt | dateFormat "15:04" "Nowhere/Atlantis" }}`, Alert{})
+	require.Error(t, err)
+}
+
+func TestJSONFuncs(t *testing.T) {
+	tmpl, err := FromGlobs()
+	require.NoError(t, err)
+
+	data := Alert{
+		Labels:      KV{"alertname": "HighLatency"},
+		Annotations: KV{"description": "Latency is \"high\" & rising\nsee https://example.com/?a=1&b=2"},
+	}
+	s, err := tmpl.ExecuteTextString(`{"text": {{ .Annotations.description | toJSON }}}`, data)
+	require.NoError(t, err)
+	require.Equal(t, `{"text": "Latency is \"high\" & rising\nsee https://example.com/?a=1&b=2"}`, s)
+
+	s, err = tmpl.ExecuteTextString(`{{ toPrettyJSON .Labels }}`, data)
+	require.NoError(t, err)
+	require.Equal(t, "{\n  \"alertname\": \"HighLatency\"\n}", s)
+
+	s, err = tmpl.ExecuteTextString(`{{ with fromJSON "{\"runbook\": {\"url\": \"http://runbooks/latency\"}}" }}{{ .runbook.url }}{{ end }}`, nil)
+	require.NoError(t, err)
+	require.Equal(t, "http://runbooks/latency", s)
+
+	_, err = tmpl.ExecuteTextString(`{{ fromJSON "{" }}`, nil)
+	require.Error(t, err)
+}
+
+func TestLabelFuncs(t *testing.T) {
+	tmpl, err := FromGlobs()
+	require.NoError(t, err)
+
+	data := Alert{
+		Labels: KV{
+			"alertname":         "PodCrashLooping",
+			"namespace":         "prod",
+			"pod":               "api-7d9f8b6c5-x2x4z",
+			"pod_template_hash": "7d9f8b6c5",
+			"severity":          "critical",
+		},
+	}
+	for in, exp := range map[string]string{
+		`{{ reReplaceAll "-[a-z0-9]+-[a-z0-9]+$" "" .Labels.pod }}`:                                                "api",
+		`{{ reReplaceAll "(\\w+)-.*" "deployment $1" .Labels.pod }}`:                                               "deployment api",
+		`{{ if match "^Pod" .Labels.alertname }}pod{{ end }}`:                                                      "pod",
+		`{{ (.Labels.Remove (stringSlice "pod" "pod_template_hash")).Names }}`:                                     "[alertname namespace severity]",
+		`{{ (.Labels.Only (stringSlice "namespace" "missing")).SortedPairs.Join "=" "," }}`:                        "namespace=prod",
+		`{{ (.Labels | excludeLabels "pod.*|severity").SortedPairs.Join "=" ", " }}`:                               "alertname=PodCrashLooping, namespace=prod",
+		`{{ (.Labels | filterLabels "pod").Values }}`:                                                              "[api-7d9f8b6c5-x2x4z]",
+		`{{ range (.Labels | filterLabels "namespace|severity").SortedPairs.SortedByValue }}{{ .Name }} {{ end }}`: "severity namespace ",
+	} {
+		s, err := tmpl.ExecuteTextString(in, data)
+		require.NoError(t, err, in)
+		require.Equal(t, exp, s, in)
+	}
+
+	_, err = tmpl.ExecuteTextString(`{{ match "(" "a" }}`, nil)
+	require.Error(t, err)
+}
+
+func TestMarkdown(t *testing.T) {
+	md := "# Disk full\n" +
+		"The **root** volume of _db-1_ is *almost* full, see [the runbook](https://runbooks/disk?a=1&b=2).\n" +
+		"Keep `snake_case` and ~~old~~ <values>.\n" +
+		"\n" +
+		"- free space\n" +
+		"- grow the volume\n" +
+		"\n" +
+		"1. check\n" +
+		"> quoted\n" +
+		"```\n" +
+		"df -h <dir>\n" +
+		"```"
+
+	require.Equal(t, "*Disk full*\n"+
+		"The *root* volume of _db-1_ is _almost_ full, see <https://runbooks/disk?a=1&amp;b=2|the runbook>.\n"+
+		"Keep `snake_case` and ~old~ &lt;values&gt;.\n"+
+		"\n"+
+		"• free space\n"+
+		"• grow the volume\n"+
+		"\n"+
+		"1. check\n"+
+		">quoted\n"+
+		"```\n"+
+		"df -h &lt;dir&gt;\n"+
+		"```", markdownToSlack(md))
+
+	require.Equal(t, "<h1>Disk full</h1>\n"+
+		"<p>The <strong>root</strong> volume of <em>db-1</em> is <em>almost</em> full, see <a href=\"https://runbooks/disk?a=1&amp;b=2\">the runbook</a>.\n"+
+		"Keep <code>snake_case</code> and <del>old</del> &lt;values&gt;.</p>\n"+
+		"<ul>\n<li>free space</li>\n<li>grow the volume</li>\n</ul>\n"+
+		"<ol>\n<li>check</li>\n</ol>\n"+
+		"<blockquote>quoted</blockquote>\n"+
+		"<pre><code>df -h &lt;dir&gt;\n</code></pre>", string(markdownToHTML(md)))
+
+	// Links must not run scripts.
+	require.Equal(t, "<p>click</p>", string(markdownToHTML("[click](javascript:void)")))
+
+	tmpl, err := FromGlobs()
+	require.NoError(t, err)
+	s, err := tmpl.ExecuteHTMLString(`<div>{{ .Annotations.description | markdownToHTML }}</div>`, Alert{Annotations: KV{"description": "**high**"}})
+	require.NoError(t, err)
+	require.Equal(t, "<div><p><strong>high</strong></p></div>", s)
+}
+
+func TestReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "templates")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "custom.tmpl")
+	write := func(text string) {
+		require.NoError(t, ioutil.WriteFile(file, []byte(text), 0644))
+		// Ensure the change is detected on file systems with a coarse
+		// modification time.
+		mtime := time.Now().Add(time.Duration(len(text)) * time.Second)
+		require.NoError(t, os.Chtimes(file, mtime, mtime))
+	}
+	write(`{{ define "title" }}old{{ end }}`)
+
+	tmpl, err := FromGlobs(filepath.Join(dir, "*.tmpl"))
+	require.NoError(t, err)
+
+	changed, err := tmpl.changed()
+	require.NoError(t, err)
+	require.False(t, changed)
+
+	// Invalid templates are rejected and the previous ones kept.
+	write(`{{ define "title" }}{{ .Broken {{ end }}`)
+	changed, err = tmpl.changed()
+	require.NoError(t, err)
+	require.True(t, changed)
+	require.Error(t, tmpl.Reload())
+
+	s, err := tmpl.ExecuteTextString(`{{ template "title" . }}`, nil)
+	require.NoError(t, err)
+	require.Equal(t, "old", s)
+
+	changed, err = tmpl.changed()
+	require.NoError(t, err)
+	require.False(t, changed)
+
+	write(`{{ define "title" }}new{{ end }}{{ define "body" }}body{{ end }}`)
+	require.NoError(t, tmpl.Reload())
+
+	s, err = tmpl.ExecuteTextString(`{{ template "title" . }}`, nil)
+	require.NoError(t, err)
+	require.Equal(t, "new", s)
+	require.True(t, tmpl.Defined("body"))
+}
+
+func TestLocale(t *testing.T) {
+	tmpl, err := FromGlobs()
+	require.NoError(t, err)
+	tmpl.ExternalURL, _ = url.Parse("http://localhost:9093")
+	tmpl.Locale = "de"
+	tmpl.Locales = map[string]string{"team": "fr-CA", "ops": ""}
+
+	alerts := []*types.Alert{{
+		Alert: model.Alert{
+			Labels:   model.LabelSet{"alertname": "DiskFull"},
+			StartsAt: time.Date(2017, 3, 14, 9, 30, 0, 0, time.UTC),
+		},
+	}}
+	lset := model.LabelSet{"alertname": "DiskFull"}
+
+	for _, c := range []struct {
+		receiver, subject, list string
+	}{
+		{receiver: "other", subject: "[AUSGELÖST:1] DiskFull ", list: "Labels:\n - alertname = DiskFull\nAnnotationen:\nQuelle: \n"},
+		{receiver: "team", subject: "[EN COURS:1] DiskFull ", list: "Étiquettes:\n - alertname = DiskFull\nAnnotations:\nSource: \n"},
+		{receiver: "ops", subject: "[FIRING:1] DiskFull ", list: "Labels:\n - alertname = DiskFull\nAnnotations:\nSource: \n"},
+	} {
+		data := tmpl.Data(c.receiver, lset, alerts...)
+
+		s, err := tmpl.ExecuteTextString(`{{ template "__subject" . }}`, data)
+		require.NoError(t, err)
+		require.Equal(t, c.subject, s)
+
+		s, err = tmpl.ExecuteTextString(`{{ template "__text_alert_list" .Alerts }}`, data)
+		require.NoError(t, err)
+		require.Equal(t, c.list, s)
+	}
+
+	require.Equal(t, "3 Alarme für", translate("de-AT", "%d alerts for", 3))
+	require.Equal(t, "Unknown", translate("de", "Unknown"))
+	require.Equal(t, "14.03.2017 09:30:00 UTC", localDate("de_DE", alerts[0].StartsAt))
+	require.Equal(t, "Mar 14, 2017 09:30:00 UTC", localDate("xx", alerts[0].StartsAt))
+
+	s, err := tmpl.ExecuteHTMLString(`{{ template "email.default.html" . }}`, tmpl.Data("other", lset, alerts...))
+	require.NoError(t, err)
+	require.Contains(t, s, "1 Alarm für")
+	require.Contains(t, s, "Beginn</strong> 14.03.2017 09:30:00 UTC")
+}
+
+// blockingData blocks executions calling Wait until unblock is closed.
+type blockingData struct {
+	unblock chan struct{}
+}
+
+func (d blockingData) Wait() string {
+	<-d.unblock
+	return "x"
+}
+
+type slowData struct {
+	List []int
+}
+
+func (slowData) Slow() string {
+	time.Sleep(10 * time.Millisecond)
+	return "x"
+}
+
+func TestLimits(t *testing.T) {
+	tmpl, err := FromGlobs()
+	require.NoError(t, err)
+
+	defs := `{{ define "a" }}{{ template "b" . }}{{ end }}{{ define "b" }}b{{ end }}` +
+		`{{ define "loop" }}{{ template "loop" . }}{{ end }}`
+
+	tmpl.Limits = Limits{MaxDepth: 2}
+	s, err := tmpl.ExecuteTextString(defs+`{{ template "a" . }}`, nil)
+	require.NoError(t, err)
+	require.Equal(t, "b", s)
+
+	tmpl.Limits = Limits{MaxDepth: 1}
+	_, err = tmpl.ExecuteTextString(defs+`{{ template "a" . }}`, nil)
+	require.EqualError(t, err, "template nesting exceeds the maximum depth of 1")
+
+	tmpl.Limits = Limits{MaxDepth: 100}
+	_, err = tmpl.ExecuteHTMLString(defs+`{{ if true }}{{ template "loop" . }}{{ end }}`, nil)
+	require.EqualError(t, err, "template nesting exceeds the maximum depth of 100")
+
+	tmpl.Limits = Limits{MaxOutputSize: 10}
+	s, err = tmpl.ExecuteTextString(`{{ range .List }}{{ . }}{{ end }}`, map[string][]string{"List": {"abcd", "efgh"}})
+	require.NoError(t, err)
+	require.Equal(t, "abcdefgh", s)
+	_, err = tmpl.ExecuteTextString(`{{ range .List }}{{ . }}{{ end }}`, map[string][]string{"List": {"abcd", "efgh", "ijkl"}})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "template output exceeds the maximum size of 10 bytes")
+
+	// Executions exceeding the timeout count as abandoned until they
+	// return, which writing executions do at their next write.
+	abandoned := func(exp float64) {
+		var m dto.Metric
+		for i := 0; i < 100; i++ {
+			require.NoError(t, abandonedExecutions.Write(&m))
+			if m.GetGauge().GetValue() == exp {
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		require.Equal(t, exp, m.GetGauge().GetValue())
+	}
+
+	tmpl.Limits = Limits{Timeout: 50 * time.Millisecond}
+	start := time.Now()
+	_, err = tmpl.ExecuteTextString(`{{ range .List }}{{ $.Slow }}{{ end }}`, slowData{List: make([]int, 100)})
+	require.EqualError(t, err, "template execution exceeds the timeout of 50ms")
+	require.True(t, time.Since(start) < time.Second)
+	abandoned(0)
+
+	data := blockingData{unblock: make(chan struct{})}
+	_, err = tmpl.ExecuteTextString(`{{ .Wait }}`, data)
+	require.EqualError(t, err, "template execution exceeds the timeout of 50ms")
+	abandoned(1)
+	close(data.unblock)
+	abandoned(0)
+}
+
+func TestLinks(t *testing.T) {
+	tmpl, err := FromGlobs()
+	require.NoError(t, err)
+
+	data := map[string]KV{"Labels": {"job": "node", "instance": "db-1:9100"}}
+	_, err = tmpl.ExecuteTextString(`{{ grafanaLink "node-overview" .Labels }}`, data)
+	require.Error(t, err)
+
+	tmpl.Links = Links{
+		GrafanaURL:   "https://grafana.example.com/",
+		LogSearchURL: "https://logs.example.com/search?q={query}&range=1h",
+		TraceURL:     "https://traces.example.com/trace/{trace_id}",
+	}
+	for in, exp := range map[string]string{
+		`{{ grafanaLink "node-overview" .Labels }}`:                   "https://grafana.example.com/d/node-overview?var-instance=db-1%3A9100&var-job=node",
+		`{{ grafanaLink "home" (.Labels | filterLabels "none") }}`:    "https://grafana.example.com/d/home",
+		`{{ logSearchLink (.Labels | filterLabels "job|instance") }}`: "https://logs.example.com/search?q=%7Binstance%3D%22db-1%3A9100%22%2Cjob%3D%22node%22%7D&range=1h",
+		`{{ traceLink "4bf92f3577b34da6" }}`:                          "https://traces.example.com/trace/4bf92f3577b34da6",
+	} {
+		s, err := tmpl.ExecuteTextString(in, data)
+		require.NoError(t, err)
+		require.Equal(t, exp, s, in)
+	}
+}
@@ -0,0 +1,110 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package topology
+
+import (
+	"fmt"
+	"sort"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Graph is a dependency graph of services or hosts.
+type Graph struct {
+	// The nodes each node directly or indirectly depends on.
+	upstream map[string][]string
+}
+
+// graphFile is the format of a graph file. As JSON is a subset of YAML,
+// graphs may be written in either.
+type graphFile struct {
+	Dependencies map[string][]string `yaml:"dependencies"`
+}
+
+// ParseGraph parses a graph file.
+func ParseGraph(b []byte) (*Graph, error) {
+	var f graphFile
+	if err := yaml.Unmarshal(b, &f); err != nil {
+		return nil, err
+	}
+	return NewGraph(f.Dependencies)
+}
+
+// NewGraph returns a graph in which each node depends on the given nodes.
+// It fails if the dependencies contain a cycle, as the alerts of the nodes
+// in the cycle would inhibit each other.
+func NewGraph(deps map[string][]string) (*Graph, error) {
+	g := &Graph{upstream: map[string][]string{}}
+
+	const (
+		visiting = iota + 1
+		visited
+	)
+	state := map[string]int{}
+
+	// visit computes the upstream nodes of n by a depth-first search.
+	var visit func(n string, path []string) error
+	visit = func(n string, path []string) error {
+		switch state[n] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf("dependency cycle %v", append(path, n))
+		}
+		state[n] = visiting
+
+		up := map[string]struct{}{}
+		for _, d := range deps[n] {
+			if err := visit(d, append(path, n)); err != nil {
+				return err
+			}
+			up[d] = struct{}{}
+			for _, u := range g.upstream[d] {
+				up[u] = struct{}{}
+			}
+		}
+		if len(up) > 0 {
+			res := make([]string, 0, len(up))
+			for u := range up {
+				res = append(res, u)
+			}
+			sort.Strings(res)
+			g.upstream[n] = res
+		}
+		state[n] = visited
+		return nil
+	}
+
+	nodes := make([]string, 0, len(deps))
+	for n := range deps {
+		nodes = append(nodes, n)
+	}
+	// Visit nodes in a fixed order to report the same cycle every time.
+	sort.Strings(nodes)
+
+	for _, n := range nodes {
+		if err := visit(n, nil); err != nil {
+			return nil, err
+		}
+	}
+	return g, nil
+}
+
+// Upstream returns the nodes the given node directly or indirectly depends on.
+func (g *Graph) Upstream(node string) []string {
+	if g == nil {
+		return nil
+	}
+	return g.upstream[node]
+}
@@ -0,0 +1,234 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package topology inhibits alerts along a dependency graph of services or
+// hosts. Alerts of a node are inhibited while an alert of a node it depends
+// on is firing.
+package topology
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/common/log"
+	"github.com/prometheus/common/model"
+
+	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/provider"
+	"github.com/prometheus/alertmanager/types"
+)
+
+// Inhibitor mutes the alerts of nodes whose upstream dependencies have
+// firing alerts.
+type Inhibitor struct {
+	conf   *config.TopologyConfig
+	alerts provider.Alerts
+	marker types.Marker
+	client *http.Client
+
+	sourceMatchers types.Matchers
+	targetMatchers types.Matchers
+
+	mtx   sync.RWMutex
+	graph *Graph
+	// Cache of source alerts by the node they belong to.
+	scache map[model.LabelValue]map[model.Fingerprint]*types.Alert
+	stopc  chan struct{}
+}
+
+// New returns a new Inhibitor. If the graph is loaded from a file or URL, it
+// is empty until Run is called.
+func New(conf *config.TopologyConfig, ap provider.Alerts, mk types.Marker) (*Inhibitor, error) {
+	ih := &Inhibitor{
+		conf:   conf,
+		alerts: ap,
+		marker: mk,
+		client: &http.Client{Timeout: 30 * time.Second},
+		scache: map[model.LabelValue]map[model.Fingerprint]*types.Alert{},
+	}
+	for ln, lv := range conf.SourceMatch {
+		ih.sourceMatchers = append(ih.sourceMatchers, types.NewMatcher(model.LabelName(ln), lv))
+	}
+	for ln, lv := range conf.SourceMatchRE {
+		ih.sourceMatchers = append(ih.sourceMatchers, types.NewRegexMatcher(model.LabelName(ln), lv.Regexp))
+	}
+	for ln, lv := range conf.TargetMatch {
+		ih.targetMatchers = append(ih.targetMatchers, types.NewMatcher(model.LabelName(ln), lv))
+	}
+	for ln, lv := range conf.TargetMatchRE {
+		ih.targetMatchers = append(ih.targetMatchers, types.NewRegexMatcher(model.LabelName(ln), lv.Regexp))
+	}
+
+	if len(conf.Dependencies) > 0 {
+		g, err := NewGraph(conf.Dependencies)
+		if err != nil {
+			return nil, err
+		}
+		ih.graph = g
+	}
+	return ih, nil
+}
+
+// load reads the graph from the configured file or URL.
+func (ih *Inhibitor) load() (*Graph, error) {
+	if ih.conf.File != "" {
+		b, err := ioutil.ReadFile(ih.conf.File)
+		if err != nil {
+			return nil, err
+		}
+		return ParseGraph(b)
+	}
+	resp, err := ih.client.Get(ih.conf.URL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return nil, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return ParseGraph(b)
+}
+
+// refresh reloads the graph. The previous graph is kept on failure.
+func (ih *Inhibitor) refresh() {
+	g, err := ih.load()
+	if err != nil {
+		log.Errorf("Loading topology failed: %s", err)
+		return
+	}
+	ih.mtx.Lock()
+	ih.graph = g
+	ih.mtx.Unlock()
+}
+
+// Run the Inhibitor's background processing.
+func (ih *Inhibitor) Run() {
+	ih.mtx.Lock()
+	ih.stopc = make(chan struct{})
+	stopc := ih.stopc
+	ih.mtx.Unlock()
+
+	var refreshc <-chan time.Time
+	if len(ih.conf.Dependencies) == 0 {
+		ih.refresh()
+
+		t := time.NewTicker(time.Duration(ih.conf.RefreshInterval))
+		defer t.Stop()
+		refreshc = t.C
+	}
+
+	gc := time.NewTicker(15 * time.Minute)
+	defer gc.Stop()
+
+	it := ih.alerts.Subscribe()
+	defer it.Close()
+
+	for {
+		select {
+		case <-stopc:
+			return
+		case <-refreshc:
+			ih.refresh()
+		case <-gc.C:
+			ih.gc()
+		case a := <-it.Next():
+			if err := it.Err(); err != nil {
+				log.Errorf("Error iterating alerts: %s", err)
+				continue
+			}
+			if a.Resolved() {
+				// As alerts can also time out without an update, we never
+				// handle new resolved alerts but invalidate the cache on read.
+				continue
+			}
+			ih.set(a)
+		}
+	}
+}
+
+// Stop the Inhibitor's background processing.
+func (ih *Inhibitor) Stop() {
+	if ih == nil {
+		return
+	}
+	ih.mtx.Lock()
+	defer ih.mtx.Unlock()
+
+	if ih.stopc != nil {
+		close(ih.stopc)
+		ih.stopc = nil
+	}
+}
+
+// set adds the alert to the source cache if it is a source alert of a node.
+func (ih *Inhibitor) set(a *types.Alert) {
+	node, ok := a.Labels[ih.conf.Label]
+	if !ok || !ih.sourceMatchers.Match(a.Labels) {
+		return
+	}
+	ih.mtx.Lock()
+	defer ih.mtx.Unlock()
+
+	if ih.scache[node] == nil {
+		ih.scache[node] = map[model.Fingerprint]*types.Alert{}
+	}
+	ih.scache[node][a.Fingerprint()] = a
+}
+
+// gc clears out resolved alerts from the source cache.
+func (ih *Inhibitor) gc() {
+	ih.mtx.Lock()
+	defer ih.mtx.Unlock()
+
+	for node, as := range ih.scache {
+		for fp, a := range as {
+			if a.Resolved() {
+				delete(as, fp)
+			}
+		}
+		if len(as) == 0 {
+			delete(ih.scache, node)
+		}
+	}
+}
+
+// Mutes implements the types.Muter interface. Alerts are only marked as
+// inhibited if they are muted. They are never unmarked, which is left to
+// the regular inhibitor.
+func (ih *Inhibitor) Mutes(lset model.LabelSet) bool {
+	node, ok := lset[ih.conf.Label]
+	if !ok || !ih.targetMatchers.Match(lset) {
+		return false
+	}
+	ih.mtx.RLock()
+	defer ih.mtx.RUnlock()
+
+	for _, up := range ih.graph.Upstream(string(node)) {
+		for _, a := range ih.scache[model.LabelValue(up)] {
+			// The cache might be stale and contain resolved alerts.
+			if !a.Resolved() {
+				ih.marker.SetInhibited(lset.Fingerprint(), true)
+				return true
+			}
+		}
+	}
+	return false
+}
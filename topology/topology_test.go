@@ -0,0 +1,128 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package topology
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+
+	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/types"
+)
+
+func TestParseGraph(t *testing.T) {
+	g, err := ParseGraph([]byte(`
+dependencies:
+  web: [api]
+  api: [db, cache]
+  worker: [db]
+`))
+	require.NoError(t, err)
+
+	require.Equal(t, []string{"api", "cache", "db"}, g.Upstream("web"))
+	require.Equal(t, []string{"cache", "db"}, g.Upstream("api"))
+	require.Equal(t, []string{"db"}, g.Upstream("worker"))
+	require.Empty(t, g.Upstream("db"))
+	require.Empty(t, g.Upstream("unknown"))
+
+	// JSON is valid YAML.
+	g, err = ParseGraph([]byte(`{"dependencies": {"api": ["db"]}}`))
+	require.NoError(t, err)
+	require.Equal(t, []string{"db"}, g.Upstream("api"))
+
+	_, err = ParseGraph([]byte(`
+dependencies:
+  a: [b]
+  b: [c]
+  c: [a]
+`))
+	require.Error(t, err)
+
+	_, err = ParseGraph([]byte(`dependencies: [a]`))
+	require.Error(t, err)
+}
+
+func TestInhibitorMutes(t *testing.T) {
+	now := time.Now()
+
+	ih, err := New(&config.TopologyConfig{
+		Dependencies: map[string][]string{
+			"web": {"api"},
+			"api": {"db"},
+		},
+		Label:       "service",
+		SourceMatch: map[string]string{"severity": "critical"},
+	}, nil, types.NewMarker())
+	require.NoError(t, err)
+
+	newAlert := func(lset model.LabelSet, end time.Time) *types.Alert {
+		return &types.Alert{
+			Alert: model.Alert{
+				Labels:   lset,
+				StartsAt: now.Add(-time.Minute),
+				EndsAt:   end,
+			},
+		}
+	}
+	ih.set(newAlert(model.LabelSet{"service": "db", "severity": "critical"}, now.Add(time.Hour)))
+	// Does not match the source matchers.
+	ih.set(newAlert(model.LabelSet{"service": "web", "severity": "warning"}, now.Add(time.Hour)))
+
+	cases := []struct {
+		lset  model.LabelSet
+		muted bool
+	}{
+		{lset: model.LabelSet{"service": "api", "alertname": "HighLatency"}, muted: true},
+		{lset: model.LabelSet{"service": "web", "alertname": "HighLatency"}, muted: true},
+		{lset: model.LabelSet{"service": "db", "alertname": "HighLatency"}, muted: false},
+		{lset: model.LabelSet{"alertname": "HighLatency"}, muted: false},
+	}
+	for i, c := range cases {
+		require.Equal(t, c.muted, ih.Mutes(c.lset), "case %d", i)
+	}
+	require.True(t, ih.marker.Inhibited(cases[0].lset.Fingerprint()))
+
+	// Resolved source alerts do not inhibit.
+	ih.set(newAlert(model.LabelSet{"service": "db", "severity": "critical"}, now.Add(-time.Second)))
+	require.False(t, ih.Mutes(cases[0].lset))
+
+	ih.gc()
+	require.Empty(t, ih.scache)
+}
+
+func TestInhibitorLoad(t *testing.T) {
+	graph := `{"dependencies": {"api": ["db"]}}`
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, graph)
+	}))
+	defer ts.Close()
+
+	ih, err := New(&config.TopologyConfig{URL: ts.URL, Label: "service"}, nil, types.NewMarker())
+	require.NoError(t, err)
+	require.Empty(t, ih.graph.Upstream("api"))
+
+	ih.refresh()
+	require.Equal(t, []string{"db"}, ih.graph.Upstream("api"))
+
+	// The previous graph is kept if loading fails.
+	graph = `{"dependencies": {"a": ["a"]}}`
+	ih.refresh()
+	require.Equal(t, []string{"db"}, ih.graph.Upstream("api"))
+}
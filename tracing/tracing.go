@@ -0,0 +1,410 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package tracing records spans of the notification pipeline and exports
+// them to an OpenTelemetry collector via OTLP over HTTP.
+//
+// Trace context is propagated in W3C traceparent headers. Spans are only
+// recorded once a tracer was installed with SetTracer; until then all
+// functions are no-ops.
+package tracing
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	mrand "math/rand"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+	"golang.org/x/net/context"
+	"golang.org/x/net/context/ctxhttp"
+)
+
+// SpanKind describes the relationship of a span to its parent and children
+// as defined by OpenTelemetry.
+type SpanKind int
+
+// The kinds of spans.
+const (
+	KindInternal SpanKind = 1
+	KindServer   SpanKind = 2
+	KindClient   SpanKind = 3
+)
+
+// SpanContext identifies a span across process boundaries.
+type SpanContext struct {
+	TraceID [16]byte
+	SpanID  [8]byte
+	Sampled bool
+}
+
+// IsValid returns whether the span context identifies a span.
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID != [16]byte{} && sc.SpanID != [8]byte{}
+}
+
+// TraceParent returns the span context as W3C traceparent header value. It
+// returns an empty string if the span context is invalid.
+func (sc SpanContext) TraceParent() string {
+	if !sc.IsValid() {
+		return ""
+	}
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return fmt.Sprintf("00-%x-%x-%s", sc.TraceID, sc.SpanID, flags)
+}
+
+// ParseTraceParent parses a W3C traceparent header value.
+func ParseTraceParent(s string) (SpanContext, bool) {
+	var sc SpanContext
+	parts := strings.Split(strings.TrimSpace(s), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" {
+		return sc, false
+	}
+	tid, err := hex.DecodeString(parts[1])
+	if err != nil || len(tid) != len(sc.TraceID) {
+		return sc, false
+	}
+	sid, err := hex.DecodeString(parts[2])
+	if err != nil || len(sid) != len(sc.SpanID) {
+		return sc, false
+	}
+	flags, err := hex.DecodeString(parts[3])
+	if err != nil || len(flags) != 1 {
+		return sc, false
+	}
+	copy(sc.TraceID[:], tid)
+	copy(sc.SpanID[:], sid)
+	sc.Sampled = flags[0]&1 == 1
+	return sc, sc.IsValid()
+}
+
+const traceParentHeader = "traceparent"
+
+// Inject sets the traceparent header for the span of the context, if any.
+func Inject(ctx context.Context, h http.Header) {
+	if sc := SpanFromContext(ctx).Context(); sc.IsValid() {
+		h.Set(traceParentHeader, sc.TraceParent())
+	}
+}
+
+// Extract returns a context holding the remote span context of the
+// traceparent header, if any, as parent of spans started from it.
+func Extract(ctx context.Context, h http.Header) context.Context {
+	sc, ok := ParseTraceParent(h.Get(traceParentHeader))
+	if !ok {
+		return ctx
+	}
+	return context.WithValue(ctx, spanKey, &Span{sc: sc})
+}
+
+type ctxKey int
+
+const spanKey ctxKey = iota
+
+// SpanFromContext returns the span of the context or nil.
+func SpanFromContext(ctx context.Context) *Span {
+	s, _ := ctx.Value(spanKey).(*Span)
+	return s
+}
+
+// Options configures a new Tracer.
+type Options struct {
+	// The URL spans are posted to in the OTLP/HTTP JSON encoding, e.g.
+	// http://localhost:4318/v1/traces.
+	Endpoint string
+	// The service name spans are attributed to.
+	ServiceName string
+	// The ratio of traces started by the Alertmanager that are sampled.
+	// Traces continued from a remote parent follow its sampling decision.
+	SampleRatio float64
+	// The interval at which recorded spans are exported.
+	Interval time.Duration
+	// The maximum number of spans buffered between exports. Further spans
+	// are dropped.
+	MaxSpans int
+
+	Client  *http.Client
+	Logger  log.Logger
+	Metrics prometheus.Registerer
+}
+
+// Tracer records spans and exports them.
+type Tracer struct {
+	o      Options
+	logger log.Logger
+
+	mtx   sync.Mutex
+	rand  *mrand.Rand
+	spans []*Span
+
+	exported prometheus.Counter
+	dropped  prometheus.Counter
+	failed   prometheus.Counter
+}
+
+// New returns a new Tracer.
+func New(o Options) *Tracer {
+	if o.Client == nil {
+		o.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+	if o.Interval <= 0 {
+		o.Interval = 5 * time.Second
+	}
+	if o.MaxSpans <= 0 {
+		o.MaxSpans = 4096
+	}
+	var seed [8]byte
+	rand.Read(seed[:])
+
+	t := &Tracer{
+		o:      o,
+		logger: log.NewNopLogger(),
+		rand:   mrand.New(mrand.NewSource(int64(binary.LittleEndian.Uint64(seed[:])))),
+		exported: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "alertmanager_tracing_spans_exported_total",
+			Help: "The total number of spans exported to the tracing backend.",
+		}),
+		dropped: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "alertmanager_tracing_spans_dropped_total",
+			Help: "The total number of spans dropped because the export buffer was full.",
+		}),
+		failed: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "alertmanager_tracing_export_failures_total",
+			Help: "The total number of failed exports of spans.",
+		}),
+	}
+	if o.Logger != nil {
+		t.logger = o.Logger
+	}
+	if o.Metrics != nil {
+		o.Metrics.MustRegister(t.exported, t.dropped, t.failed)
+	}
+	return t
+}
+
+var (
+	globalMtx sync.RWMutex
+	global    *Tracer
+)
+
+// SetTracer installs the tracer recording the spans started by Start.
+func SetTracer(t *Tracer) {
+	globalMtx.Lock()
+	defer globalMtx.Unlock()
+
+	global = t
+}
+
+func tracer() *Tracer {
+	globalMtx.RLock()
+	defer globalMtx.RUnlock()
+
+	return global
+}
+
+// Start starts a span with the span of the context as parent and returns a
+// context holding the new span. It returns a nil span, whose methods do
+// nothing, if no tracer is installed.
+func Start(ctx context.Context, name string, kind SpanKind) (context.Context, *Span) {
+	t := tracer()
+	if t == nil {
+		return ctx, nil
+	}
+	s := &Span{
+		tracer: t,
+		name:   name,
+		kind:   kind,
+		start:  time.Now(),
+	}
+
+	t.mtx.Lock()
+	if p := SpanFromContext(ctx); p != nil && p.sc.IsValid() {
+		s.sc.TraceID = p.sc.TraceID
+		s.sc.Sampled = p.sc.Sampled
+		s.parent = p.sc.SpanID
+	} else {
+		t.rand.Read(s.sc.TraceID[:])
+		s.sc.Sampled = t.rand.Float64() < t.o.SampleRatio
+	}
+	t.rand.Read(s.sc.SpanID[:])
+	t.mtx.Unlock()
+
+	return context.WithValue(ctx, spanKey, s), s
+}
+
+// Span is an operation within a trace.
+type Span struct {
+	tracer *Tracer
+	sc     SpanContext
+	parent [8]byte
+	name   string
+	kind   SpanKind
+	start  time.Time
+
+	mtx   sync.Mutex
+	end   time.Time
+	attrs []attribute
+	links []SpanContext
+	err   string
+}
+
+type attribute struct {
+	key   string
+	value interface{}
+}
+
+// Context returns the span context of the span.
+func (s *Span) Context() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.sc
+}
+
+// SetAttribute sets an attribute of the span. Values other than strings,
+// booleans and numbers are recorded as strings.
+func (s *Span) SetAttribute(key string, value interface{}) {
+	if s == nil || s.tracer == nil || !s.sc.Sampled {
+		return
+	}
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.attrs = append(s.attrs, attribute{key: key, value: value})
+}
+
+// AddLink links the span to another span, e.g. one of the requests that
+// sent an alert the span processes.
+func (s *Span) AddLink(sc SpanContext) {
+	if s == nil || s.tracer == nil || !s.sc.Sampled || !sc.IsValid() {
+		return
+	}
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.links = append(s.links, sc)
+}
+
+// SetError marks the span as failed with the error, if it is not nil.
+func (s *Span) SetError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.err = err.Error()
+}
+
+// End ends the span and queues it for export if it is sampled.
+func (s *Span) End() {
+	if s == nil || s.tracer == nil {
+		return
+	}
+	s.mtx.Lock()
+	if !s.end.IsZero() {
+		s.mtx.Unlock()
+		return
+	}
+	s.end = time.Now()
+	s.mtx.Unlock()
+
+	if s.sc.Sampled {
+		s.tracer.add(s)
+	}
+}
+
+func (t *Tracer) add(s *Span) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	if len(t.spans) >= t.o.MaxSpans {
+		t.dropped.Inc()
+		return
+	}
+	t.spans = append(t.spans, s)
+}
+
+// Run exports the recorded spans at the interval until the stop channel is
+// closed, after which the remaining spans are exported.
+func (t *Tracer) Run(stopc <-chan struct{}) {
+	tick := time.NewTicker(t.o.Interval)
+	defer tick.Stop()
+
+	for {
+		select {
+		case <-stopc:
+			t.flush()
+			return
+		case <-tick.C:
+			t.flush()
+		}
+	}
+}
+
+func (t *Tracer) flush() {
+	t.mtx.Lock()
+	spans := t.spans
+	t.spans = nil
+	t.mtx.Unlock()
+
+	if len(spans) == 0 {
+		return
+	}
+	if err := t.export(spans); err != nil {
+		t.failed.Inc()
+		t.logger.With("err", err).With("spans", len(spans)).Warn("Exporting spans failed")
+		return
+	}
+	t.exported.Add(float64(len(spans)))
+}
+
+func (t *Tracer) export(spans []*Span) error {
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(t.encode(spans)); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), t.o.Interval)
+	defer cancel()
+
+	resp, err := ctxhttp.Post(ctx, t.o.Client, t.o.Endpoint, "application/json", &buf)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("unexpected status code %v", resp.StatusCode)
+	}
+	return nil
+}
+
+// ContextWithSpan returns a context holding the span, e.g. to restore the
+// parent span after a child span ended.
+func ContextWithSpan(ctx context.Context, s *Span) context.Context {
+	if SpanFromContext(ctx) == s {
+		return ctx
+	}
+	return context.WithValue(ctx, spanKey, s)
+}
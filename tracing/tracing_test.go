@@ -0,0 +1,123 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracing
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+func TestTraceParent(t *testing.T) {
+	const tp = "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"
+
+	sc, ok := ParseTraceParent(tp)
+	require.True(t, ok)
+	require.True(t, sc.Sampled)
+	require.Equal(t, tp, sc.TraceParent())
+
+	for _, s := range []string{
+		"",
+		"00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331",
+		"ff-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01",
+		"00-00000000000000000000000000000000-b7ad6b7169203331-01",
+		"00-0af7651916cd43dd8448eb211c80319c-xxxx6b7169203331-01",
+	} {
+		_, ok := ParseTraceParent(s)
+		require.False(t, ok, s)
+	}
+
+	h := http.Header{}
+	h.Set("traceparent", tp)
+	ctx := Extract(context.Background(), h)
+
+	out := http.Header{}
+	Inject(ctx, out)
+	require.Equal(t, tp, out.Get("traceparent"))
+}
+
+func TestNoTracer(t *testing.T) {
+	SetTracer(nil)
+
+	ctx, span := Start(context.Background(), "test", KindInternal)
+	require.Nil(t, span)
+	require.Nil(t, SpanFromContext(ctx))
+
+	// Methods of nil spans must not panic.
+	span.SetAttribute("key", "value")
+	span.SetError(errors.New("failed"))
+	span.End()
+	require.False(t, span.Context().IsValid())
+}
+
+func TestExport(t *testing.T) {
+	reqs := make(chan otlpRequest, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req otlpRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		reqs <- req
+	}))
+	defer srv.Close()
+
+	tracer := New(Options{
+		Endpoint:    srv.URL,
+		ServiceName: "test",
+		SampleRatio: 1,
+	})
+	SetTracer(tracer)
+	defer SetTracer(nil)
+
+	ctx, root := Start(context.Background(), "root", KindInternal)
+	root.SetAttribute("alerts", 2)
+
+	remote, _ := ParseTraceParent("00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+	root.AddLink(remote)
+
+	_, child := Start(ctx, "child", KindClient)
+	child.SetError(errors.New("failed"))
+	child.End()
+	root.End()
+
+	stopc := make(chan struct{})
+	close(stopc)
+	tracer.Run(stopc)
+
+	req := <-reqs
+	require.Len(t, req.ResourceSpans, 1)
+	rs := req.ResourceSpans[0]
+	require.Equal(t, "test", *rs.Resource.Attributes[0].Value.StringValue)
+
+	spans := rs.ScopeSpans[0].Spans
+	require.Len(t, spans, 2)
+
+	c, r := spans[0], spans[1]
+	require.Equal(t, "child", c.Name)
+	require.Equal(t, KindClient, c.Kind)
+	require.Equal(t, r.TraceID, c.TraceID)
+	require.Equal(t, r.SpanID, c.ParentSpanID)
+	require.Equal(t, &otlpStatus{Code: otlpStatusError, Message: "failed"}, c.Status)
+
+	require.Equal(t, "root", r.Name)
+	require.Empty(t, r.ParentSpanID)
+	require.Equal(t, "2", *r.Attributes[0].Value.IntValue)
+	require.Equal(t, []otlpLink{{
+		TraceID: "0af7651916cd43dd8448eb211c80319c",
+		SpanID:  "b7ad6b7169203331",
+	}}, r.Links)
+}
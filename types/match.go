@@ -22,10 +22,13 @@ import (
 )
 
 // Matcher defines a matching rule for the value of a given label.
+// If IsAnnotation is set, the matcher applies to the annotation of
+// the given name instead.
 type Matcher struct {
-	Name    string `json:"name"`
-	Value   string `json:"value"`
-	IsRegex bool   `json:"isRegex"`
+	Name         string `json:"name"`
+	Value        string `json:"value"`
+	IsRegex      bool   `json:"isRegex"`
+	IsAnnotation bool   `json:"isAnnotation"`
 
 	regex *regexp.Regexp
 }
@@ -43,10 +46,14 @@ func (m *Matcher) Init() error {
 }
 
 func (m *Matcher) String() string {
+	name := m.Name
+	if m.IsAnnotation {
+		name = "annotations." + name
+	}
 	if m.IsRegex {
-		return fmt.Sprintf("<RegexMatcher %s:%q>", m.Name, m.Value)
+		return fmt.Sprintf("<RegexMatcher %s:%q>", name, m.Value)
 	}
-	return fmt.Sprintf("<Matcher %s:%q>", m.Name, m.Value)
+	return fmt.Sprintf("<Matcher %s:%q>", name, m.Value)
 }
 
 // Match checks whether the label of the matcher has the specified
@@ -113,7 +120,10 @@ func (ms Matchers) Less(i, j int) bool {
 	if ms[i].Value < ms[j].Value {
 		return true
 	}
-	return !ms[i].IsRegex && ms[j].IsRegex
+	if ms[i].IsRegex != ms[j].IsRegex {
+		return !ms[i].IsRegex
+	}
+	return !ms[i].IsAnnotation && ms[j].IsAnnotation
 }
 
 // Equal returns whether both Matchers are equal.
@@ -130,8 +140,19 @@ func (ms Matchers) Equal(o Matchers) bool {
 }
 
 // Match checks whether all matchers are fulfilled against the given label set.
+// Annotation matchers are checked against an empty set of annotations.
 func (ms Matchers) Match(lset model.LabelSet) bool {
+	return ms.MatchAlert(lset, nil)
+}
+
+// MatchAlert checks whether all matchers are fulfilled against the given
+// labels and annotations of an alert.
+func (ms Matchers) MatchAlert(labels, annotations model.LabelSet) bool {
 	for _, m := range ms {
+		lset := labels
+		if m.IsAnnotation {
+			lset = annotations
+		}
 		if !m.Match(lset) {
 			return false
 		}
@@ -159,7 +180,11 @@ func (ms Matchers) Fingerprint() model.Fingerprint {
 	lset := make(model.LabelSet, 3*len(ms))
 
 	for _, m := range ms {
-		lset[model.LabelName(fmt.Sprintf("%s-%s-%v", m.Name, m.Value, m.IsRegex))] = ""
+		name := m.Name
+		if m.IsAnnotation {
+			name = "annotations." + name
+		}
+		lset[model.LabelName(fmt.Sprintf("%s-%s-%v", name, m.Value, m.IsRegex))] = ""
 	}
 
 	return lset.Fingerprint()
@@ -23,14 +23,16 @@ import (
 	"github.com/prometheus/common/model"
 )
 
-// Marker helps to mark alerts as silenced and/or inhibited.
+// Marker helps to mark alerts as silenced, inhibited and/or flapping.
 // All methods are goroutine-safe.
 type Marker interface {
 	SetInhibited(alert model.Fingerprint, b bool)
 	SetSilenced(alert model.Fingerprint, sil ...string)
+	SetFlapping(alert model.Fingerprint, b bool)
 
 	Silenced(alert model.Fingerprint) (string, bool)
 	Inhibited(alert model.Fingerprint) bool
+	Flapping(alert model.Fingerprint) bool
 }
 
 // NewMarker returns an instance of a Marker implementation.
@@ -38,12 +40,14 @@ func NewMarker() Marker {
 	return &memMarker{
 		inhibited: map[model.Fingerprint]struct{}{},
 		silenced:  map[model.Fingerprint]string{},
+		flapping:  map[model.Fingerprint]struct{}{},
 	}
 }
 
 type memMarker struct {
 	inhibited map[model.Fingerprint]struct{}
 	silenced  map[model.Fingerprint]string
+	flapping  map[model.Fingerprint]struct{}
 
 	mtx sync.RWMutex
 }
@@ -56,6 +60,14 @@ func (m *memMarker) Inhibited(alert model.Fingerprint) bool {
 	return ok
 }
 
+func (m *memMarker) Flapping(alert model.Fingerprint) bool {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+
+	_, ok := m.flapping[alert]
+	return ok
+}
+
 func (m *memMarker) Silenced(alert model.Fingerprint) (string, bool) {
 	m.mtx.RLock()
 	defer m.mtx.RUnlock()
@@ -75,6 +87,17 @@ func (m *memMarker) SetInhibited(alert model.Fingerprint, b bool) {
 	}
 }
 
+func (m *memMarker) SetFlapping(alert model.Fingerprint, b bool) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	if !b {
+		delete(m.flapping, alert)
+	} else {
+		m.flapping[alert] = struct{}{}
+	}
+}
+
 func (m *memMarker) SetSilenced(alert model.Fingerprint, sil ...string) {
 	m.mtx.Lock()
 	defer m.mtx.Unlock()
@@ -141,8 +164,35 @@ type Alert struct {
 	Timeout      bool
 	WasSilenced  bool `json:"-"`
 	WasInhibited bool `json:"-"`
+	SoftSilenced bool `json:"-"`
+	Flapping     bool `json:"-"`
+	Acked        bool `json:"-"`
+	// Comments attached to the alert.
+	Comments []*Comment `json:"-"`
+	// The person the alert is assigned to.
+	AssignedTo string `json:"-"`
+	// The W3C traceparent of the traced request that last sent the alert,
+	// which notifications of the alert are linked to.
+	TraceParent string `json:"-"`
+	// The state of the alert as reported by each of its sources, ordered
+	// by name. It is empty if the sources of the alert are unknown.
+	Sources []*AlertSource `json:"sources,omitempty"`
 }
 
+// AlertSource is the state of an alert as reported by one of the clients
+// sending it, e.g. one of several Prometheus servers.
+type AlertSource struct {
+	Name      string    `json:"name"`
+	EndsAt    time.Time `json:"endsAt"`
+	Timeout   bool      `json:"timeout"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// SoftSilencedLabel is added to the labels of alerts matched by a soft
+// silence when notifications are rendered. It is not part of the alert's
+// labels, which identify it.
+const SoftSilencedLabel = "soft_silenced"
+
 // AlertSlice is a sortable slice of Alerts.
 type AlertSlice []*Alert
 
@@ -182,6 +232,22 @@ func (a *Alert) Merge(o *Alert) *Alert {
 		res.StartsAt = a.StartsAt
 	}
 
+	// If the sources of both alerts are known, the alert only ends once
+	// all of its sources resolved it or timed out.
+	if len(a.Sources) > 0 && len(o.Sources) > 0 {
+		res.Sources = mergeSources(a.Sources, o.Sources)
+
+		last := res.Sources[0]
+		for _, s := range res.Sources[1:] {
+			if s.EndsAt.After(last.EndsAt) {
+				last = s
+			}
+		}
+		res.EndsAt, res.Timeout = last.EndsAt, last.Timeout
+
+		return &res
+	}
+
 	// A non-timeout resolved timestamp always rules.
 	// The latest explicit resolved timestamp wins.
 	if a.EndsAt.After(o.EndsAt) && !a.Timeout {
@@ -191,6 +257,32 @@ func (a *Alert) Merge(o *Alert) *Alert {
 	return &res
 }
 
+// mergeSources returns the union of both sources ordered by name. For sources
+// contained in both, the latest update wins.
+func mergeSources(a, b []*AlertSource) []*AlertSource {
+	srcs := make(map[string]*AlertSource, len(a)+len(b))
+	for _, s := range a {
+		srcs[s.Name] = s
+	}
+	for _, s := range b {
+		if prev, ok := srcs[s.Name]; !ok || !s.UpdatedAt.Before(prev.UpdatedAt) {
+			srcs[s.Name] = s
+		}
+	}
+	res := make(alertSources, 0, len(srcs))
+	for _, s := range srcs {
+		res = append(res, s)
+	}
+	sort.Sort(res)
+	return res
+}
+
+type alertSources []*AlertSource
+
+func (s alertSources) Len() int           { return len(s) }
+func (s alertSources) Less(i, j int) bool { return s[i].Name < s[j].Name }
+func (s alertSources) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
 // A Muter determines whether a given label set is muted.
 type Muter interface {
 	Mutes(model.LabelSet) bool
@@ -202,6 +294,60 @@ type MuteFunc func(model.LabelSet) bool
 // Mutes implements the Muter interface.
 func (f MuteFunc) Mutes(lset model.LabelSet) bool { return f(lset) }
 
+// A Comment is a note attached to the alerts that have all of its labels and
+// started firing before it was created.
+type Comment struct {
+	// A unique identifier across all connected instances.
+	ID string `json:"id"`
+	// The labels of the alert or group the comment is about.
+	Labels    model.LabelSet `json:"labels"`
+	Author    string         `json:"author"`
+	Comment   string         `json:"comment"`
+	CreatedAt time.Time      `json:"createdAt"`
+}
+
+// Applies returns whether the comment is about an alert with the given labels
+// that started firing at the given time.
+func (c *Comment) Applies(lset model.LabelSet, startsAt time.Time) bool {
+	if startsAt.After(c.CreatedAt) {
+		return false
+	}
+	for ln, lv := range c.Labels {
+		if lset[ln] != lv {
+			return false
+		}
+	}
+	return true
+}
+
+// An Assignment assigns the alerts that have all of its labels and started
+// firing before it was last updated to a person.
+type Assignment struct {
+	// The fingerprint of the labels. There is at most one assignment for a
+	// label set.
+	ID string `json:"id"`
+	// The labels of the alert or group that is assigned.
+	Labels model.LabelSet `json:"labels"`
+	// Assignee is empty if the alerts were unassigned.
+	Assignee   string    `json:"assignee"`
+	AssignedBy string    `json:"assignedBy"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+}
+
+// Applies returns whether the assignment applies to an alert with the given
+// labels that started firing at the given time.
+func (a *Assignment) Applies(lset model.LabelSet, startsAt time.Time) bool {
+	if a.Assignee == "" || startsAt.After(a.UpdatedAt) {
+		return false
+	}
+	for ln, lv := range a.Labels {
+		if lset[ln] != lv {
+			return false
+		}
+	}
+	return true
+}
+
 // A Silence determines whether a given label set is muted.
 type Silence struct {
 	// A unique identifier across all connected instances.
@@ -209,6 +355,9 @@ type Silence struct {
 	// A set of matchers determining if a label set is affect
 	// by the silence.
 	Matchers Matchers `json:"matchers"`
+	// If set, the silence only affects alerts of the aggregation group
+	// with the given key, as reported by the alert groups API.
+	GroupKey string `json:"groupKey,omitempty"`
 
 	// Time range of the silence.
 	//
@@ -229,6 +378,20 @@ type Silence struct {
 	CreatedBy string `json:"createdBy"`
 	Comment   string `json:"comment,omitempty"`
 
+	// Broad silences may require the approval of a second user before
+	// they take effect.
+	ApprovalRequired bool   `json:"approvalRequired,omitempty"`
+	ApprovedBy       string `json:"approvedBy,omitempty"`
+
+	// Soft silences do not suppress notifications. Notifications for
+	// alerts they match carry the SoftSilencedLabel instead.
+	Soft bool `json:"soft,omitempty"`
+
+	// The number of alerts an active silence currently mutes and the
+	// last time it muted any alert.
+	MutedAlerts   int        `json:"mutedAlerts"`
+	LastMatchedAt *time.Time `json:"lastMatchedAt,omitempty"`
+
 	// timeFunc provides the time against which to evaluate
 	// the silence. Used for test injection.
 	now func() time.Time
@@ -239,8 +402,8 @@ func (s *Silence) Validate() error {
 	if s.ID == "" {
 		return fmt.Errorf("ID missing")
 	}
-	if len(s.Matchers) == 0 {
-		return fmt.Errorf("at least one matcher required")
+	if len(s.Matchers) == 0 && s.GroupKey == "" {
+		return fmt.Errorf("at least one matcher or a group key required")
 	}
 	for _, m := range s.Matchers {
 		if err := m.Validate(); err != nil {
@@ -61,3 +61,57 @@ func TestAlertMerge(t *testing.T) {
 		}
 	}
 }
+
+func TestAlertMergeSources(t *testing.T) {
+	now := time.Now()
+
+	firing := &Alert{
+		Alert: model.Alert{
+			StartsAt: now.Add(-time.Minute),
+			EndsAt:   now.Add(5 * time.Minute),
+		},
+		UpdatedAt: now,
+		Timeout:   true,
+		Sources: []*AlertSource{
+			{Name: "prom-b", EndsAt: now.Add(5 * time.Minute), Timeout: true, UpdatedAt: now},
+		},
+	}
+	// The other server restarted and explicitly resolved the alert.
+	resolved := &Alert{
+		Alert: model.Alert{
+			StartsAt: now.Add(-time.Minute),
+			EndsAt:   now.Add(time.Second),
+		},
+		UpdatedAt: now.Add(time.Second),
+		Sources: []*AlertSource{
+			{Name: "prom-a", EndsAt: now.Add(time.Second), UpdatedAt: now.Add(time.Second)},
+		},
+	}
+
+	res := firing.Merge(resolved)
+	if !res.EndsAt.Equal(now.Add(5*time.Minute)) || !res.Timeout {
+		t.Errorf("expected alert to keep firing until all sources resolved but got end %s", res.EndsAt)
+	}
+	if len(res.Sources) != 2 || res.Sources[0].Name != "prom-a" || res.Sources[1].Name != "prom-b" {
+		t.Errorf("unexpected sources %v", res.Sources)
+	}
+
+	// Once the second source resolved the alert, it is resolved.
+	second := &Alert{
+		Alert: model.Alert{
+			StartsAt: now.Add(-time.Minute),
+			EndsAt:   now.Add(2 * time.Second),
+		},
+		UpdatedAt: now.Add(2 * time.Second),
+		Sources: []*AlertSource{
+			{Name: "prom-b", EndsAt: now.Add(2 * time.Second), UpdatedAt: now.Add(2 * time.Second)},
+		},
+	}
+	res = res.Merge(second)
+	if !res.EndsAt.Equal(now.Add(2*time.Second)) || res.Timeout {
+		t.Errorf("expected alert to be resolved by the last source but got end %s", res.EndsAt)
+	}
+	if len(firing.Sources) != 1 {
+		t.Errorf("merging must not modify the sources of the merged alerts")
+	}
+}
@@ -44,8 +44,10 @@ func serveAsset(w http.ResponseWriter, req *http.Request, fp string) {
 	http.ServeContent(w, req, info.Name(), info.ModTime(), bytes.NewReader(file))
 }
 
-// Register registers handlers to serve files for the web interface.
-func Register(r *route.Router, reloadCh chan<- struct{}) {
+// Register registers handlers to serve files for the web interface and the
+// health endpoints. The ready function returns an error as long as the
+// Alertmanager is not ready to serve requests.
+func Register(r *route.Router, reloadCh chan<- struct{}, ready func() error) {
 	ihf := prometheus.InstrumentHandlerFunc
 
 	r.Get("/app/*filepath", ihf("app_files",
@@ -72,6 +74,17 @@ func Register(r *route.Router, reloadCh chan<- struct{}) {
 		reloadCh <- struct{}{}
 	})
 
+	r.Get("/-/healthy", func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte("OK"))
+	})
+	r.Get("/-/ready", func(w http.ResponseWriter, req *http.Request) {
+		if err := ready(); err != nil {
+			http.Error(w, "Not ready: "+err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("OK"))
+	})
+
 	r.Get("/debug/*subpath", http.DefaultServeMux.ServeHTTP)
 	r.Post("/debug/*subpath", http.DefaultServeMux.ServeHTTP)
 }